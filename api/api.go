@@ -1,68 +1,281 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
 	"net/url"
-	"sync"
+	"regexp"
+	"strconv"
 	"time"
 
+	"github.com/GeoffreyDick/gogarin/clock"
+	"github.com/GeoffreyDick/gogarin/lib"
 	m "github.com/GeoffreyDick/gogarin/model"
 	resty "github.com/go-resty/resty/v2"
+	"golang.org/x/sync/singleflight"
 )
 
 /*
-🐌 Throttle
+💻 Client
 */
-type Throttle struct {
-	MaxRequestsPerSecond int
-	LastRequestTime      time.Time
-	Mutex                sync.Mutex
-}
 
-func NewThrottle(maxRequestsPerSecond int) *Throttle {
-	return &Throttle{
-		MaxRequestsPerSecond: maxRequestsPerSecond,
-		LastRequestTime:      time.Now(),
-	}
+// ClientAPI is the set of spacetraders.io operations used by the bots.
+// It is implemented by Client and by DryRunClient.
+type ClientAPI interface {
+	GetStatus() (*m.Status, error)
+	GetMyAgent() (*m.Agent, error)
+	GetMyAgentEvents() (*[]m.AgentEvent, error)
+	GetMyContracts() (*[]m.Contract, error)
+	AcceptContract(contractId string) (*AcceptContractResponse, error)
+	DeliverContract(contractId string, shipSymbol string, tradeSymbol string, units int) (*DeliverContractResponse, error)
+	FulfillContract(contractId string) (*FulfillContractResponse, error)
+	GetMyShips() (*[]m.Ship, error)
+	GetShip(shipSymbol string) (*m.Ship, error)
+	GetShipCooldown(shipSymbol string) (*m.Cooldown, error)
+	GetShipNav(shipSymbol string) (*m.ShipNav, error)
+	NavigateShip(shipSymbol string, waypointSymbol string) (*NavigateShipResponse, error)
+	OrbitShip(shipSymbol string) (*m.ShipNav, error)
+	DockShip(shipSymbol string) (*m.ShipNav, error)
+	CreateSurvey(shipSymbol string) (*CreateSurveyResponse, error)
+	ExtractResources(shipSymbol string, surveys ...m.Survey) (*ExtractResourcesResponse, error)
+	JettisonCargo(shipSymbol string, cargoSymbol m.TradeGood, units int) (*m.ShipCargo, error)
+	JumpShip(shipSymbol string, systemSymbol string) (*m.ShipNav, error)
+	SellCargo(shipSymbol string, cargoSymbol string, units int) (*SellCargoResponse, error)
+	PurchaseCargo(shipSymbol string, cargoSymbol string, units int) (*PurchaseCargoResponse, error)
+	PurchaseShip(shipType string, waypointSymbol string) (*PurchaseShipResponse, error)
+	RefuelShip(shipSymbol string, units int) (*RefuelShipResponse, error)
+	GetRepairShip(shipSymbol string) (*m.RepairTransaction, error)
+	RepairShip(shipSymbol string) (*RepairShipResponse, error)
+	CreateChart(shipSymbol string) (*CreateChartResponse, error)
+	ListSystems() (*[]m.System, error)
+	GetSystem(systemSymbol string) (*m.System, error)
+	ListWaypoints(systemSymbol string) (*[]m.Waypoint, error)
+	GetWaypoint(systemSymbol string, waypointSymbol string) (*m.Waypoint, error)
+	GetWaypoints(systemSymbol string, symbols []string) (map[string]m.Waypoint, error)
+	GetMarket(systemSymbol string, waypointSymbol string) (*m.Market, error)
+	GetShipyard(systemSymbol string, waypointSymbol string) (*m.Shipyard, error)
+	GetJumpGate(systemSymbol string, waypointSymbol string) (*m.JumpGate, error)
+	GetSupplyChain() (*m.SupplyChain, error)
+	GetWaypointAt(waypointSymbol string) (*m.Waypoint, error)
+	GetMarketAt(waypointSymbol string) (*m.Market, error)
+	GetShipyardAt(waypointSymbol string) (*m.Shipyard, error)
+	GetJumpGateAt(waypointSymbol string) (*m.JumpGate, error)
 }
 
-func (t *Throttle) Wait() {
-	t.Mutex.Lock()
-	defer t.Mutex.Unlock()
+type Client struct {
+	r           *resty.Client
+	t           *Throttle
+	priority    Priority
+	subsystem   string
+	pageWorkers int
+	inflight    *singleflight.Group
+	waypoints   *waypointCache
+	// clock stamps when cooldowns were fetched, so they are measured against the same clock that reads them.
+	clock clock.Clock
+}
 
-	timeSinceLastRequest := time.Since(t.LastRequestTime)
+// Option configures a Client.
+type Option func(*Client)
 
-	timeToWait := time.Duration(float64(time.Second) / float64(t.MaxRequestsPerSecond))
+// WithRateLimit sets the maximum number of requests per second the Client sends.
+func WithRateLimit(maxRequestsPerSecond int) Option {
+	return func(c *Client) {
+		c.t.MaxRequestsPerSecond = maxRequestsPerSecond
+	}
+}
 
-	if timeSinceLastRequest < timeToWait {
-		time.Sleep(timeToWait - timeSinceLastRequest)
+// WithSubsystemCap limits the requests counted against a subsystem to a share of the Client's request budget.
+// Over its cap, the subsystem's requests wait while other subsystems' proceed.
+func WithSubsystemCap(subsystem string, share float64) Option {
+	return func(c *Client) {
+		c.t.SetCap(subsystem, share)
 	}
+}
 
-	t.LastRequestTime = time.Now()
+// WithClock spaces the Client's requests and stamps fetched cooldowns by c instead of the wall clock.
+func WithClock(c clock.Clock) Option {
+	return func(client *Client) {
+		client.t.SetClock(c)
+		client.clock = c
+	}
 }
 
-/*
-💻 Client
-*/
-type Client struct {
-	r *resty.Client
-	t *Throttle
+// WithBaseURL points the Client at another SpaceTraders-compatible server, such as a mockserver.Server.
+func WithBaseURL(url string) Option {
+	return func(c *Client) {
+		c.r.SetBaseURL(url)
+	}
 }
 
-func NewClient(token string) *Client {
-	bearer := "Bearer " + token
+// WithStatusObserver calls observe with the status code of every response. Requests that fail
+// without a response, such as when the server is unreachable, are observed as 503 Service Unavailable.
+func WithStatusObserver(observe func(statusCode int)) Option {
+	return func(c *Client) {
+		c.r.OnAfterResponse(func(_ *resty.Client, res *resty.Response) error {
+			observe(res.StatusCode())
+			return nil
+		})
+		c.r.OnError(func(_ *resty.Request, err error) {
+			// Responses that arrived were observed above; resty wraps transport errors in a ResponseError
+			// without a raw response.
+			var resErr *resty.ResponseError
+			if !errors.As(err, &resErr) || resErr.Response.RawResponse == nil {
+				observe(http.StatusServiceUnavailable)
+			}
+		})
+	}
+}
 
+func NewClient(token string, opts ...Option) *Client {
 	r := resty.
 		New().
 		SetBaseURL(baseURL.String()).
-		SetHeader("Authorization", bearer).
 		SetTimeout(1*time.Minute).
-		SetHeader("Accept", "application/json").
-		EnableTrace()
+		SetHeader("Accept", "application/json")
+
+	// Registration is the only call made without a token.
+	if token != "" {
+		r.SetHeader("Authorization", "Bearer "+token)
+	}
 
 	t := NewThrottle(2)
 
-	return &Client{r, t}
+	c := &Client{r: r, t: t, inflight: &singleflight.Group{}, waypoints: newWaypointCache(), clock: clock.Real}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// share performs fetch once for concurrent callers with the same key in the same throttle lane, returning the
+// response body it read for each caller to decode into a value of its own. The body is shared and must not be
+// modified. fetch runs detached from ctx, so a caller giving up does not fail the callers sharing its request;
+// ctx only bounds how long this caller waits for it.
+func (c *Client) share(ctx context.Context, key string, fetch func() ([]byte, error)) ([]byte, error) {
+	ch := c.inflight.DoChan(c.lane()+" "+key, func() (interface{}, error) {
+		return fetch()
+	})
+
+	select {
+	case result := <-ch:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return result.Val.([]byte), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// WithPriority returns a handle on the Client whose requests wait in the given lane of the shared throttle.
+// Use High for interactive and administrative calls so they are not starved by fleet traffic.
+func (c *Client) WithPriority(priority Priority) *Client {
+	handle := *c
+	handle.priority = priority
+
+	return &handle
+}
+
+// WithSubsystem returns a handle on the Client whose requests count against a subsystem's share of the
+// shared throttle's budget. Requests of a Client without one count against SubsystemMissions.
+func (c *Client) WithSubsystem(subsystem string) *Client {
+	handle := *c
+	handle.subsystem = subsystem
+
+	return &handle
+}
+
+// Usage returns how much of the request budget each subsystem has used.
+func (c *Client) Usage() []SubsystemUsage {
+	return c.t.Usage()
+}
+
+// Pauser is a client whose requests can be held, such as Client and a DryRunClient wrapping one.
+type Pauser interface {
+	// Pause holds requests, other than interactive ones, until Resume. Requests already sent complete.
+	Pause()
+	// Resume sends the held requests.
+	Resume()
+}
+
+// Pause holds every request of the Client and its handles, other than interactive ones, until Resume.
+func (c *Client) Pause() {
+	c.t.Pause()
+}
+
+// Resume sends the requests held since Pause.
+func (c *Client) Resume() {
+	c.t.Resume()
+}
+
+// Tag returns a handle on c whose requests count against subsystem, when c supports request budgets.
+// Other clients are returned as they are.
+func Tag(c ClientAPI, subsystem string) ClientAPI {
+	if client, ok := c.(*Client); ok {
+		return client.WithSubsystem(subsystem)
+	}
+
+	return c
+}
+
+// lane names the throttle lane and subsystem the Client's requests wait in. A request waits for a slot as the
+// caller that sent it, under its priority and its subsystem's cap, so callers waiting otherwise must not share
+// it.
+func (c *Client) lane() string {
+	return strconv.Itoa(int(c.priority)) + "/" + c.subsystem
+}
+
+// wait blocks until the throttle grants the Client's lane a request slot.
+func (c *Client) wait() {
+	c.t.WaitFor(c.priority, c.subsystem)
+}
+
+// get performs an idempotent GET and decodes its data, checking it with check, if given (see expect).
+// Concurrent calls for the same URL share a single request; each caller decodes its own copy of the value.
+func get[T any](c *Client, url string, check ...func(T) string) (*T, error) {
+	body, err := c.share(context.Background(), url, func() ([]byte, error) {
+		c.wait()
+
+		var resultResponse struct {
+			Data T `json:"data"`
+		}
+
+		res, err := c.r.R().
+			SetResult(&resultResponse).
+			SetError(&ErrorResponse{}).
+			Get(url)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.IsError() {
+			return nil, newAPIError(res)
+		}
+
+		for _, check := range check {
+			if err := expect(res, resultResponse.Data, check); err != nil {
+				return nil, err
+			}
+		}
+
+		return res.Body(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var resultResponse struct {
+		Data T `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resultResponse); err != nil {
+		return nil, err
+	}
+
+	return &resultResponse.Data, nil
 }
 
 /*
@@ -76,60 +289,300 @@ type ErrorResponse struct {
 	}
 }
 
+// APIError is an error response from the SpaceTraders API.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Code is the SpaceTraders error code, e.g. 4000 for a ship on cooldown.
+	Code    int
+	Message string
+	Data    interface{}
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// newAPIError builds an APIError from an error response, tolerating bodies that are not ErrorResponses.
+func newAPIError(res *resty.Response) *APIError {
+	e := &APIError{StatusCode: res.StatusCode()}
+
+	if body, ok := res.Error().(*ErrorResponse); ok {
+		e.Code = body.Error.Code
+		e.Message = body.Error.Message
+		e.Data = body.Error.Data
+	}
+
+	if e.Message == "" {
+		e.Message = res.Status()
+	}
+
+	return e
+}
+
+// IsUnauthorized reports whether err is an API error caused by a missing, invalid, or expired token.
+func IsUnauthorized(err error) bool {
+	var apiErr *APIError
+
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized
+}
+
+// IsNotFound reports whether err is an API error for a resource or endpoint the server does not have.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// CodeTradeNotAvailable is the error code of a trade in a good the market does not buy or sell.
+const CodeTradeNotAvailable = 4603
+
+// ErrNotTraded is returned by simulated clients for a trade in a good the market does not buy or sell.
+var ErrNotTraded = errors.New("market does not trade good")
+
+// IsNotTraded reports whether err is a refused trade in a good the market does not buy or sell.
+func IsNotTraded(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == CodeTradeNotAvailable
+	}
+
+	return errors.Is(err, ErrNotTraded)
+}
+
+// CodeNotInOrbit is the error code of an order that needs the ship in orbit, such as navigating, sent while it
+// is docked.
+const CodeNotInOrbit = 4236
+
+// ErrNotInOrbit is returned by simulated clients for an order that needs the ship in orbit while it is docked.
+var ErrNotInOrbit = errors.New("ship is docked and must be in orbit")
+
+// IsNotInOrbit reports whether err is a refused order that needs the ship in orbit while it is docked.
+func IsNotInOrbit(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == CodeNotInOrbit
+	}
+
+	return errors.Is(err, ErrNotInOrbit)
+}
+
+// CodeJumpNoAntimatter is the error code of a jump by a ship without the antimatter it burns.
+const CodeJumpNoAntimatter = 4212
+
+// ErrNoAntimatter is returned by simulated clients for a jump by a ship without the antimatter it burns.
+var ErrNoAntimatter = errors.New("ship has no antimatter to jump")
+
+// IsNoAntimatter reports whether err is a refused jump by a ship without the antimatter it burns.
+func IsNoAntimatter(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == CodeJumpNoAntimatter
+	}
+
+	return errors.Is(err, ErrNoAntimatter)
+}
+
+const (
+	// CodeCargoMissing is the error code of a ship's cargo not containing the good asked for.
+	CodeCargoMissing = 4218
+	// CodeCargoUnitCount is the error code of a ship's cargo holding fewer units of a good than asked for.
+	CodeCargoUnitCount = 4219
+)
+
+// IsCargoShort reports whether err is a refused sale, transfer, or jettison of a good the ship no longer holds,
+// or holds fewer units of than asked for. Cargo can change between deciding to move it and the call.
+func IsCargoShort(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == CodeCargoMissing || apiErr.Code == CodeCargoUnitCount
+	}
+
+	var shortErr *m.CargoShortError
+
+	return errors.As(err, &shortErr)
+}
+
+// ErrInvalidArgument is wrapped by the InvalidArgumentError of a Client call refused before any request is
+// made, because a required argument is blank or is not a symbol.
+var ErrInvalidArgument = errors.New("invalid argument")
+
+// InvalidArgumentError names a blank or malformed argument of a Client call. A zero-value ship or waypoint in
+// the caller would otherwise build a URL such as /my/ships//dock and fail with a confusing 404.
+type InvalidArgumentError struct {
+	Name  string
+	Value string
+}
+
+func (e *InvalidArgumentError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("%s: %s is blank", ErrInvalidArgument, e.Name)
+	}
+
+	return fmt.Sprintf("%s: %s %q is not a symbol", ErrInvalidArgument, e.Name, e.Value)
+}
+
+func (e *InvalidArgumentError) Unwrap() error {
+	return ErrInvalidArgument
+}
+
+// symbolPattern matches the symbols and IDs the API takes as arguments.
+var symbolPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// checkArgs returns an InvalidArgumentError for the first of alternating names and values that is blank or
+// not a symbol.
+func checkArgs(args ...string) error {
+	for i := 0; i+1 < len(args); i += 2 {
+		if !symbolPattern.MatchString(args[i+1]) {
+			return &InvalidArgumentError{Name: args[i], Value: args[i+1]}
+		}
+	}
+
+	return nil
+}
+
+// ErrUnexpectedResponse is wrapped by the UnexpectedResponseError of a successful response missing data it
+// cannot lack, which is what schema drift looks like: the fields decode to zero values instead of failing.
+var ErrUnexpectedResponse = errors.New("unexpected response")
+
+// UnexpectedResponseError describes a successful response missing data it cannot lack, with the start of its
+// body for debugging.
+type UnexpectedResponseError struct {
+	URL string
+	// Reason is what the response was missing.
+	Reason string
+	// Body is the response body, truncated to maxUnexpectedBody bytes.
+	Body string
+}
+
+func (e *UnexpectedResponseError) Error() string {
+	return fmt.Sprintf("%s from %s: %s; body: %s", ErrUnexpectedResponse, e.URL, e.Reason, e.Body)
+}
+
+func (e *UnexpectedResponseError) Unwrap() error {
+	return ErrUnexpectedResponse
+}
+
+// maxUnexpectedBody is how much of an unexpected response's body its error keeps.
+const maxUnexpectedBody = 512
+
+// expect returns an UnexpectedResponseError if check finds the decoded data of a successful response is
+// missing something, which check returns a description of; it returns "" for data that is whole.
+func expect[T any](res *resty.Response, data T, check func(T) string) error {
+	reason := check(data)
+	if reason == "" {
+		return nil
+	}
+
+	body := string(res.Body())
+	if len(body) > maxUnexpectedBody {
+		body = body[:maxUnexpectedBody] + "..."
+	}
+
+	return &UnexpectedResponseError{URL: res.Request.URL, Reason: reason, Body: body}
+}
+
+// IsUnexpectedResponse reports whether err is a successful response missing data it cannot lack.
+func IsUnexpectedResponse(err error) bool {
+	return errors.Is(err, ErrUnexpectedResponse)
+}
+
 var baseURL = url.URL{
 	Scheme: "https",
 	Host:   "api.spacetraders.io",
 	Path:   "/v2",
 }
 
-func (c *Client) GetMyAgent() (*m.Agent, error) {
-	c.t.Wait()
+// GetStatus returns the server's status, including the date of the last reset. It does not require a valid token.
+func (c *Client) GetStatus() (*m.Status, error) {
+	c.wait()
 
-	var resultResponse struct {
-		Data m.Agent `json:"data"`
-	}
+	var resultResponse m.Status
 
-	url := "/my/agent"
+	url := "/"
 
 	res, err := c.r.R().
 		SetResult(&resultResponse).
-		SetError(ErrorResponse{}).
+		SetError(&ErrorResponse{}).
 		Get(url)
 	if err != nil {
 		return nil, err
 	}
 
 	if res.IsError() {
-		return nil, errors.New(res.Error().(*ErrorResponse).Error.Message)
+		return nil, newAPIError(res)
 	}
 
-	return &resultResponse.Data, nil
+	return &resultResponse, nil
 }
 
-func (c *Client) GetMyContracts() (*[]m.Contract, error) {
-	c.t.Wait()
+type RegisterResponse struct {
+	Agent    m.Agent    `json:"agent"`
+	Contract m.Contract `json:"contract"`
+	Faction  m.Faction  `json:"faction"`
+	Ship     m.Ship     `json:"ship"`
+	Token    string     `json:"token"`
+}
+
+// Register creates a new agent, returning its token. The Client does not need a token to register.
+func (c *Client) Register(symbol string, faction string) (*RegisterResponse, error) {
+	if err := checkArgs("symbol", symbol, "faction", faction); err != nil {
+		return nil, err
+	}
+
+	c.wait()
 
 	var resultResponse struct {
-		Data []m.Contract `json:"data"`
+		Data RegisterResponse `json:"data"`
 	}
 
-	url := "/my/contracts"
+	url := "/register"
+
+	body := struct {
+		Symbol  string `json:"symbol"`
+		Faction string `json:"faction"`
+	}{symbol, faction}
 
 	res, err := c.r.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(body).
 		SetResult(&resultResponse).
-		SetError(ErrorResponse{}).
-		Get(url)
+		SetError(&ErrorResponse{}).
+		Post(url)
 	if err != nil {
 		return nil, err
 	}
 
 	if res.IsError() {
-		return nil, errors.New(res.Error().(*ErrorResponse).Error.Message)
+		return nil, newAPIError(res)
 	}
 
 	return &resultResponse.Data, nil
 }
 
+func (c *Client) GetMyAgent() (*m.Agent, error) {
+	return get(c, "/my/agent", func(agent m.Agent) string {
+		if agent.Symbol == "" {
+			return "agent has no symbol"
+		}
+		return ""
+	})
+}
+
+// GetMyAgentEvents returns recent events for the agent, such as contract offers and faction reputation changes.
+func (c *Client) GetMyAgentEvents() (*[]m.AgentEvent, error) {
+	return get[[]m.AgentEvent](c, "/my/agent/events")
+}
+
+func (c *Client) GetMyContracts() (*[]m.Contract, error) {
+	items, err := listAll[m.Contract](c, "/my/contracts")
+	if err != nil {
+		return nil, err
+	}
+
+	return &items, nil
+}
+
 type AcceptContractResponse struct {
 	Agent    m.Agent    `json:"agent"`
 	Contract m.Contract `json:"contract"`
@@ -137,7 +590,11 @@ type AcceptContractResponse struct {
 
 // AcceptContract accepts a contract.
 func (c *Client) AcceptContract(contractId string) (*AcceptContractResponse, error) {
-	c.t.Wait()
+	if err := checkArgs("contractId", contractId); err != nil {
+		return nil, err
+	}
+
+	c.wait()
 
 	var resultResponse struct {
 		Data AcceptContractResponse `json:"data"`
@@ -155,38 +612,116 @@ func (c *Client) AcceptContract(contractId string) (*AcceptContractResponse, err
 	}
 
 	if res.IsError() {
-		return nil, errors.New(res.Error().(*ErrorResponse).Error.Message)
+		return nil, newAPIError(res)
 	}
 
 	return &resultResponse.Data, nil
 }
 
-func (c *Client) GetMyShips() (*[]m.Ship, error) {
-	c.t.Wait()
+type DeliverContractResponse struct {
+	Contract m.Contract  `json:"contract"`
+	Cargo    m.ShipCargo `json:"cargo"`
+}
+
+// DeliverContract delivers cargo from a ship docked at the delivery destination to a contract.
+func (c *Client) DeliverContract(contractId string, shipSymbol string, tradeSymbol string, units int) (*DeliverContractResponse, error) {
+	if err := checkArgs("contractId", contractId, "shipSymbol", shipSymbol, "tradeSymbol", tradeSymbol); err != nil {
+		return nil, err
+	}
+
+	c.wait()
 
 	var resultResponse struct {
-		Data []m.Ship `json:"data"`
+		Data DeliverContractResponse `json:"data"`
 	}
 
-	url := "/my/ships"
+	url := "/my/contracts/" + contractId + "/deliver"
+
+	res, err := c.r.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(map[string]interface{}{
+			"shipSymbol":  shipSymbol,
+			"tradeSymbol": tradeSymbol,
+			"units":       units,
+		}).
+		SetResult(&resultResponse).
+		SetError(ErrorResponse{}).
+		Post(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.IsError() {
+		return nil, newAPIError(res)
+	}
+
+	return &resultResponse.Data, nil
+}
+
+type FulfillContractResponse struct {
+	Agent    m.Agent    `json:"agent"`
+	Contract m.Contract `json:"contract"`
+}
+
+// FulfillContract fulfills a contract whose deliveries are complete, paying its onFulfilled payment.
+func (c *Client) FulfillContract(contractId string) (*FulfillContractResponse, error) {
+	if err := checkArgs("contractId", contractId); err != nil {
+		return nil, err
+	}
+
+	c.wait()
+
+	var resultResponse struct {
+		Data FulfillContractResponse `json:"data"`
+	}
+
+	url := "/my/contracts/" + contractId + "/fulfill"
 
 	res, err := c.r.R().
+		SetHeader("Content-Type", "application/json").
 		SetResult(&resultResponse).
 		SetError(ErrorResponse{}).
-		Get(url)
+		Post(url)
 	if err != nil {
 		return nil, err
 	}
 
 	if res.IsError() {
-		return nil, errors.New(res.Error().(*ErrorResponse).Error.Message)
+		return nil, newAPIError(res)
 	}
 
 	return &resultResponse.Data, nil
 }
 
+func (c *Client) GetMyShips() (*[]m.Ship, error) {
+	items, err := listAll[m.Ship](c, "/my/ships")
+	if err != nil {
+		return nil, err
+	}
+
+	return &items, nil
+}
+
+// GetShip retrieves the details of a ship.
+func (c *Client) GetShip(shipSymbol string) (*m.Ship, error) {
+	if err := checkArgs("shipSymbol", shipSymbol); err != nil {
+		return nil, err
+	}
+
+	return get(c, "/my/ships/"+shipSymbol, func(ship m.Ship) string {
+		if ship.Symbol == "" {
+			return "ship has no symbol"
+		}
+		return ""
+	})
+}
+
 func (c *Client) GetShipCooldown(shipSymbol string) (*m.Cooldown, error) {
-	c.t.Wait()
+	if err := checkArgs("shipSymbol", shipSymbol); err != nil {
+		return nil, err
+	}
+
+	c.wait()
 
 	var resultResponse struct {
 		Data m.Cooldown `json:"data"`
@@ -196,18 +731,62 @@ func (c *Client) GetShipCooldown(shipSymbol string) (*m.Cooldown, error) {
 
 	res, err := c.r.R().
 		SetResult(&resultResponse).
+		SetError(&ErrorResponse{}).
+		Get(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.IsError() {
+		return nil, newAPIError(res)
+	}
+
+	resultResponse.Data.FetchedAt = c.clock.Now()
+
+	return &resultResponse.Data, nil
+}
+
+// GetShipNav fetches a ship's nav. The server moves a ship out of IN_TRANSIT only when it is next read.
+func (c *Client) GetShipNav(shipSymbol string) (*m.ShipNav, error) {
+	if err := checkArgs("shipSymbol", shipSymbol); err != nil {
+		return nil, err
+	}
+
+	c.wait()
+
+	var resultResponse struct {
+		Data m.ShipNav `json:"data"`
+	}
+
+	url := "/my/ships/" + shipSymbol + "/nav"
+
+	res, err := c.r.R().
+		SetResult(&resultResponse).
+		SetError(&ErrorResponse{}).
 		Get(url)
 	if err != nil {
 		return nil, err
 	}
 
 	if res.IsError() {
-		return nil, errors.New(res.Error().(*ErrorResponse).Error.Message)
+		return nil, newAPIError(res)
+	}
+
+	if err := expect(res, resultResponse.Data, navPresent); err != nil {
+		return nil, err
 	}
 
 	return &resultResponse.Data, nil
 }
 
+// navPresent checks that a ship's nav says where the ship is and what it is doing.
+func navPresent(nav m.ShipNav) string {
+	if nav.WaypointSymbol == "" || nav.Status == "" {
+		return "nav has no waypoint or status"
+	}
+	return ""
+}
+
 type NavigateShipResponse struct {
 	Fuel m.ShipFuel `json:"fuel"`
 	Nav  m.ShipNav  `json:"nav"`
@@ -219,7 +798,11 @@ type NavigateShipResponse struct {
 //
 // To travel between systems, see the ship's warp or jump actions.
 func (c *Client) NavigateShip(shipSymbol string, waypointSymbol string) (*NavigateShipResponse, error) {
-	c.t.Wait()
+	if err := checkArgs("shipSymbol", shipSymbol, "waypointSymbol", waypointSymbol); err != nil {
+		return nil, err
+	}
+
+	c.wait()
 
 	var resultResponse struct {
 		Data NavigateShipResponse `json:"data"`
@@ -242,18 +825,32 @@ func (c *Client) NavigateShip(shipSymbol string, waypointSymbol string) (*Naviga
 	}
 
 	if res.IsError() {
-		message := res.Error().(*ErrorResponse).Error.Message
-		return nil, errors.New(message)
+		return nil, newAPIError(res)
+	}
+
+	if err := expect(res, resultResponse.Data, func(data NavigateShipResponse) string {
+		if data.Nav.Route.Arrival.IsZero() {
+			return "route has no arrival time"
+		}
+		return navPresent(data.Nav)
+	}); err != nil {
+		return nil, err
 	}
 
 	return &resultResponse.Data, nil
 }
 
 func (c *Client) OrbitShip(shipSymbol string) (*m.ShipNav, error) {
-	c.t.Wait()
+	if err := checkArgs("shipSymbol", shipSymbol); err != nil {
+		return nil, err
+	}
+
+	c.wait()
 
 	var resultResponse struct {
-		Data m.ShipNav `json:"data"`
+		Data struct {
+			Nav m.ShipNav `json:"nav"`
+		} `json:"data"`
 	}
 
 	url := "/my/ships/" + shipSymbol + "/orbit"
@@ -261,23 +858,34 @@ func (c *Client) OrbitShip(shipSymbol string) (*m.ShipNav, error) {
 	res, err := c.r.R().
 		SetHeader("Content-Type", "application/json").
 		SetResult(&resultResponse).
+		SetError(&ErrorResponse{}).
 		Post(url)
 	if err != nil {
 		return nil, err
 	}
 
 	if res.IsError() {
-		return nil, errors.New(res.Error().(*ErrorResponse).Error.Message)
+		return nil, newAPIError(res)
 	}
 
-	return &resultResponse.Data, nil
+	if err := expect(res, resultResponse.Data.Nav, navPresent); err != nil {
+		return nil, err
+	}
+
+	return &resultResponse.Data.Nav, nil
 }
 
 func (c *Client) DockShip(shipSymbol string) (*m.ShipNav, error) {
-	c.t.Wait()
+	if err := checkArgs("shipSymbol", shipSymbol); err != nil {
+		return nil, err
+	}
+
+	c.wait()
 
 	var resultResponse struct {
-		Data m.ShipNav `json:"data"`
+		Data struct {
+			Nav m.ShipNav `json:"nav"`
+		} `json:"data"`
 	}
 
 	url := "/my/ships/" + shipSymbol + "/dock"
@@ -285,16 +893,21 @@ func (c *Client) DockShip(shipSymbol string) (*m.ShipNav, error) {
 	res, err := c.r.R().
 		SetHeader("Content-Type", "application/json").
 		SetResult(&resultResponse).
+		SetError(&ErrorResponse{}).
 		Post(url)
 	if err != nil {
 		return nil, err
 	}
 
 	if res.IsError() {
-		return nil, errors.New(res.Error().(*ErrorResponse).Error.Message)
+		return nil, newAPIError(res)
 	}
 
-	return &resultResponse.Data, nil
+	if err := expect(res, resultResponse.Data.Nav, navPresent); err != nil {
+		return nil, err
+	}
+
+	return &resultResponse.Data.Nav, nil
 }
 
 type CreateSurveyResponse struct {
@@ -303,7 +916,11 @@ type CreateSurveyResponse struct {
 }
 
 func (c *Client) CreateSurvey(shipSymbol string) (*CreateSurveyResponse, error) {
-	c.t.Wait()
+	if err := checkArgs("shipSymbol", shipSymbol); err != nil {
+		return nil, err
+	}
+
+	c.wait()
 
 	var resultResponse struct {
 		Data CreateSurveyResponse `json:"data"`
@@ -321,9 +938,11 @@ func (c *Client) CreateSurvey(shipSymbol string) (*CreateSurveyResponse, error)
 	}
 
 	if res.IsError() {
-		return nil, errors.New(res.Error().(*ErrorResponse).Error.Message)
+		return nil, newAPIError(res)
 	}
 
+	resultResponse.Data.Cooldown.FetchedAt = c.clock.Now()
+
 	return &resultResponse.Data, nil
 }
 
@@ -335,7 +954,11 @@ type ExtractResourcesResponse struct {
 
 // Extract resources from the waypoint into your ship. Send an optional survey as the payload to target specific yields.
 func (c *Client) ExtractResources(shipSymbol string, surveys ...m.Survey) (*ExtractResourcesResponse, error) {
-	c.t.Wait()
+	if err := checkArgs("shipSymbol", shipSymbol); err != nil {
+		return nil, err
+	}
+
+	c.wait()
 
 	var resultResponse struct {
 		Data ExtractResourcesResponse `json:"data"`
@@ -363,18 +986,26 @@ func (c *Client) ExtractResources(shipSymbol string, surveys ...m.Survey) (*Extr
 	}
 
 	if res.IsError() {
-		return nil, errors.New(res.Error().(*ErrorResponse).Error.Message)
+		return nil, newAPIError(res)
 	}
 
+	resultResponse.Data.Cooldown.FetchedAt = c.clock.Now()
+
 	return &resultResponse.Data, nil
 }
 
 // Jettison cargo from your ship's cargo hold.
 func (c *Client) JettisonCargo(shipSymbol string, cargoSymbol m.TradeGood, units int) (*m.ShipCargo, error) {
-	c.t.Wait()
+	if err := checkArgs("shipSymbol", shipSymbol, "cargoSymbol", cargoSymbol.Symbol); err != nil {
+		return nil, err
+	}
+
+	c.wait()
 
 	var resultResponse struct {
-		Data m.ShipCargo `json:"data"`
+		Data struct {
+			Cargo m.ShipCargo `json:"cargo"`
+		} `json:"data"`
 	}
 
 	url := "/my/ships/" + shipSymbol + "/jettison"
@@ -382,28 +1013,35 @@ func (c *Client) JettisonCargo(shipSymbol string, cargoSymbol m.TradeGood, units
 	res, err := c.r.R().
 		SetHeader("Content-Type", "application/json").
 		SetBody(map[string]interface{}{
-			"symbol": cargoSymbol,
+			"symbol": cargoSymbol.Symbol,
 			"units":  units,
 		}).
 		SetResult(&resultResponse).
+		SetError(ErrorResponse{}).
 		Post(url)
 	if err != nil {
 		return nil, err
 	}
 
 	if res.IsError() {
-		return nil, errors.New(res.Error().(*ErrorResponse).Error.Message)
+		return nil, newAPIError(res)
 	}
 
-	return &resultResponse.Data, nil
+	return &resultResponse.Data.Cargo, nil
 }
 
 // Jump your ship instantly to a target system. Unlike other forms of navigation, jumping requires a unit of antimatter.
 func (c *Client) JumpShip(shipSymbol string, systemSymbol string) (*m.ShipNav, error) {
-	c.t.Wait()
+	if err := checkArgs("shipSymbol", shipSymbol, "systemSymbol", systemSymbol); err != nil {
+		return nil, err
+	}
+
+	c.wait()
 
 	var resultResponse struct {
-		Data m.ShipNav `json:"data"`
+		Data struct {
+			Nav m.ShipNav `json:"nav"`
+		} `json:"data"`
 	}
 
 	url := "/my/ships/" + shipSymbol + "/jump"
@@ -414,16 +1052,17 @@ func (c *Client) JumpShip(shipSymbol string, systemSymbol string) (*m.ShipNav, e
 			"systemSymbol": systemSymbol,
 		}).
 		SetResult(&resultResponse).
+		SetError(&ErrorResponse{}).
 		Post(url)
 	if err != nil {
 		return nil, err
 	}
 
 	if res.IsError() {
-		return nil, errors.New(res.Error().(*ErrorResponse).Error.Message)
+		return nil, newAPIError(res)
 	}
 
-	return &resultResponse.Data, nil
+	return &resultResponse.Data.Nav, nil
 }
 
 type SellCargoResponse struct {
@@ -433,7 +1072,11 @@ type SellCargoResponse struct {
 }
 
 func (c *Client) SellCargo(shipSymbol string, cargoSymbol string, units int) (*SellCargoResponse, error) {
-	c.t.Wait()
+	if err := checkArgs("shipSymbol", shipSymbol, "cargoSymbol", cargoSymbol); err != nil {
+		return nil, err
+	}
+
+	c.wait()
 
 	var resultResponse struct {
 		Data SellCargoResponse `json:"data"`
@@ -455,194 +1098,385 @@ func (c *Client) SellCargo(shipSymbol string, cargoSymbol string, units int) (*S
 	}
 
 	if res.IsError() {
-		return nil, errors.New(res.Error().(*ErrorResponse).Error.Message)
+		return nil, newAPIError(res)
 	}
 
 	return &resultResponse.Data, nil
 }
 
-/*
-🌌 Systems
-*/
+type PurchaseCargoResponse struct {
+	Agent       m.Agent             `json:"agent"`
+	Cargo       m.ShipCargo         `json:"cargo"`
+	Transaction m.MarketTransaction `json:"transaction"`
+}
 
-// ListSystems returns a list of all systems.
-func (c *Client) ListSystems() (*[]m.System, error) {
-	c.t.Wait()
+// PurchaseCargo buys units of a trade good at the marketplace the ship is docked at.
+func (c *Client) PurchaseCargo(shipSymbol string, cargoSymbol string, units int) (*PurchaseCargoResponse, error) {
+	if err := checkArgs("shipSymbol", shipSymbol, "cargoSymbol", cargoSymbol); err != nil {
+		return nil, err
+	}
+
+	c.wait()
 
 	var resultResponse struct {
-		Data []m.System `json:"data"`
+		Data PurchaseCargoResponse `json:"data"`
 	}
 
-	url := "/systems"
+	url := "/my/ships/" + shipSymbol + "/purchase"
 
 	res, err := c.r.R().
 		SetHeader("Content-Type", "application/json").
+		SetBody(map[string]interface{}{
+			"symbol": cargoSymbol,
+			"units":  units,
+		}).
 		SetResult(&resultResponse).
 		SetError(ErrorResponse{}).
-		Get(url)
+		Post(url)
 	if err != nil {
 		return nil, err
 	}
 
 	if res.IsError() {
-		return nil, errors.New(res.Error().(ErrorResponse).Error.Message)
+		return nil, newAPIError(res)
 	}
 
 	return &resultResponse.Data, nil
 }
 
-// GetSystem gets the details of a system.
-func (c *Client) GetSystem(systemSymbol string) (*m.System, error) {
-	c.t.Wait()
+type PurchaseShipResponse struct {
+	Agent       m.Agent               `json:"agent"`
+	Ship        m.Ship                `json:"ship"`
+	Transaction m.ShipyardTransaction `json:"transaction"`
+}
+
+// PurchaseShip buys a ship of a type at the shipyard at a waypoint. One of the agent's ships must be there.
+func (c *Client) PurchaseShip(shipType string, waypointSymbol string) (*PurchaseShipResponse, error) {
+	if err := checkArgs("shipType", shipType, "waypointSymbol", waypointSymbol); err != nil {
+		return nil, err
+	}
+
+	c.wait()
 
 	var resultResponse struct {
-		Data m.System `json:"data"`
+		Data PurchaseShipResponse `json:"data"`
 	}
 
-	url := "/systems/" + systemSymbol
+	url := "/my/ships"
 
 	res, err := c.r.R().
 		SetHeader("Content-Type", "application/json").
+		SetBody(map[string]interface{}{
+			"shipType":       shipType,
+			"waypointSymbol": waypointSymbol,
+		}).
 		SetResult(&resultResponse).
 		SetError(ErrorResponse{}).
-		Get(url)
+		Post(url)
 	if err != nil {
 		return nil, err
 	}
 
 	if res.IsError() {
-		return nil, errors.New(res.Error().(ErrorResponse).Error.Message)
+		return nil, newAPIError(res)
 	}
 
 	return &resultResponse.Data, nil
 }
 
-// ListWaypoints fetches all of the waypoints for a given system. System must be charted or a ship must be present to return waypoint details.
-func (c *Client) ListWaypoints(systemSymbol string) (*[]m.Waypoint, error) {
-	c.t.Wait()
+type RefuelShipResponse struct {
+	Agent       m.Agent             `json:"agent"`
+	Fuel        m.ShipFuel          `json:"fuel"`
+	Transaction m.MarketTransaction `json:"transaction"`
+}
+
+// RefuelShip buys units of fuel for a docked ship at the marketplace it is docked at. Units are ship fuel,
+// not market units; zero or less fills the tank.
+func (c *Client) RefuelShip(shipSymbol string, units int) (*RefuelShipResponse, error) {
+	if err := checkArgs("shipSymbol", shipSymbol); err != nil {
+		return nil, err
+	}
+
+	c.wait()
 
 	var resultResponse struct {
-		Data []m.Waypoint `json:"data"`
+		Data RefuelShipResponse `json:"data"`
 	}
 
-	url := "/systems/" + systemSymbol + "/waypoints"
+	url := "/my/ships/" + shipSymbol + "/refuel"
+
+	body := map[string]interface{}{}
+	if units > 0 {
+		body["units"] = units
+	}
 
 	res, err := c.r.R().
 		SetHeader("Content-Type", "application/json").
+		SetBody(body).
 		SetResult(&resultResponse).
 		SetError(ErrorResponse{}).
-		Get(url)
+		Post(url)
 	if err != nil {
 		return nil, err
 	}
 
 	if res.IsError() {
-		return nil, errors.New(res.Error().(ErrorResponse).Error.Message)
+		return nil, newAPIError(res)
 	}
 
 	return &resultResponse.Data, nil
 }
 
-// GetWaypoint views the details of a waypoint.
-func (c *Client) GetWaypoint(systemSymbol string, waypointSymbol string) (*m.Waypoint, error) {
-	c.t.Wait()
+// GetRepairShip quotes what repairing a ship at the shipyard it is docked at would cost, without repairing it.
+func (c *Client) GetRepairShip(shipSymbol string) (*m.RepairTransaction, error) {
+	if err := checkArgs("shipSymbol", shipSymbol); err != nil {
+		return nil, err
+	}
+
+	c.wait()
 
 	var resultResponse struct {
-		Data m.Waypoint `json:"data"`
+		Data struct {
+			Transaction m.RepairTransaction `json:"transaction"`
+		} `json:"data"`
 	}
 
-	url := "/systems/" + systemSymbol + "/waypoints/" + waypointSymbol
+	url := "/my/ships/" + shipSymbol + "/repair"
 
 	res, err := c.r.R().
-		SetHeader("Content-Type", "application/json").
 		SetResult(&resultResponse).
-		SetError(ErrorResponse{}).
+		SetError(&ErrorResponse{}).
 		Get(url)
 	if err != nil {
 		return nil, err
 	}
 
 	if res.IsError() {
-		return nil, errors.New(res.Error().(*ErrorResponse).Error.Message)
+		return nil, newAPIError(res)
 	}
 
-	return &resultResponse.Data, nil
+	return &resultResponse.Data.Transaction, nil
 }
 
-// GetMarket: Retrieve imports, exports and exchange data from a marketplace. Imports can be sold, exports can be purchased, and exchange goods can be purchased or sold. Send a ship to the waypoint to access trade good prices and recent transactions.
-func (c *Client) GetMarket(systemSymbol string, waypointSymbol string) (*m.Market, error) {
-	c.t.Wait()
+type RepairShipResponse struct {
+	Agent       m.Agent             `json:"agent"`
+	Ship        m.Ship              `json:"ship"`
+	Transaction m.RepairTransaction `json:"transaction"`
+}
+
+// RepairShip repairs a ship's frame, reactor, and engine to full condition at the shipyard it is docked at.
+func (c *Client) RepairShip(shipSymbol string) (*RepairShipResponse, error) {
+	if err := checkArgs("shipSymbol", shipSymbol); err != nil {
+		return nil, err
+	}
+
+	c.wait()
 
 	var resultResponse struct {
-		Data m.Market `json:"data"`
+		Data RepairShipResponse `json:"data"`
 	}
 
-	url := "/systems/" + systemSymbol + "/waypoints/" + waypointSymbol + "/market"
+	url := "/my/ships/" + shipSymbol + "/repair"
 
 	res, err := c.r.R().
-		SetHeader("Content-Type", "application/json").
 		SetResult(&resultResponse).
-		SetError(ErrorResponse{}).
-		Get(url)
+		SetError(&ErrorResponse{}).
+		Post(url)
 	if err != nil {
 		return nil, err
 	}
 
 	if res.IsError() {
-		return nil, errors.New(res.Error().(ErrorResponse).Error.Message)
+		return nil, newAPIError(res)
 	}
 
 	return &resultResponse.Data, nil
 }
 
-// GetShipyard: Get the shipyard for a waypoint. Send a ship to the waypoint to access ships that are currently available for purchase and recent transactions.
-func (c *Client) GetShipyard(systemSymbol string, waypointSymbol string) (*m.Shipyard, error) {
-	c.t.Wait()
+type CreateChartResponse struct {
+	Chart    m.Chart    `json:"chart"`
+	Waypoint m.Waypoint `json:"waypoint"`
+}
+
+// CreateChart charts the uncharted waypoint a ship is at.
+func (c *Client) CreateChart(shipSymbol string) (*CreateChartResponse, error) {
+	if err := checkArgs("shipSymbol", shipSymbol); err != nil {
+		return nil, err
+	}
+
+	c.wait()
 
 	var resultResponse struct {
-		Data m.Shipyard `json:"data"`
+		Data CreateChartResponse `json:"data"`
 	}
 
-	url := "/systems/" + systemSymbol + "/waypoints/" + waypointSymbol + "/shipyard"
+	url := "/my/ships/" + shipSymbol + "/chart"
 
 	res, err := c.r.R().
 		SetHeader("Content-Type", "application/json").
 		SetResult(&resultResponse).
 		SetError(ErrorResponse{}).
-		Get(url)
+		Post(url)
 	if err != nil {
 		return nil, err
 	}
 
 	if res.IsError() {
-		return nil, errors.New(res.Error().(ErrorResponse).Error.Message)
+		return nil, newAPIError(res)
 	}
+	c.waypoints.put(resultResponse.Data.Waypoint)
 
 	return &resultResponse.Data, nil
 }
 
+/*
+🌌 Systems
+*/
+
+// ListSystems returns a list of all systems.
+func (c *Client) ListSystems() (*[]m.System, error) {
+	items, err := listAll[m.System](c, "/systems")
+	if err != nil {
+		return nil, err
+	}
+
+	return &items, nil
+}
+
+// GetSystem gets the details of a system.
+func (c *Client) GetSystem(systemSymbol string) (*m.System, error) {
+	if err := checkArgs("systemSymbol", systemSymbol); err != nil {
+		return nil, err
+	}
+
+	return get(c, "/systems/"+systemSymbol, func(system m.System) string {
+		if system.Symbol == "" {
+			return "system has no symbol"
+		}
+		return ""
+	})
+}
+
+// ListWaypoints fetches all of the waypoints for a given system. System must be charted or a ship must be present to return waypoint details.
+func (c *Client) ListWaypoints(systemSymbol string) (*[]m.Waypoint, error) {
+	if err := checkArgs("systemSymbol", systemSymbol); err != nil {
+		return nil, err
+	}
+
+	items, err := listAll[m.Waypoint](c, "/systems/"+systemSymbol+"/waypoints")
+	if err != nil {
+		return nil, err
+	}
+	c.waypoints.put(items...)
+
+	return &items, nil
+}
+
+// GetWaypoint views the details of a waypoint.
+func (c *Client) GetWaypoint(systemSymbol string, waypointSymbol string) (*m.Waypoint, error) {
+	if err := checkArgs("systemSymbol", systemSymbol, "waypointSymbol", waypointSymbol); err != nil {
+		return nil, err
+	}
+
+	waypoint, err := get(c, "/systems/"+systemSymbol+"/waypoints/"+waypointSymbol, func(waypoint m.Waypoint) string {
+		if waypoint.Symbol == "" {
+			return "waypoint has no symbol"
+		}
+		return ""
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.waypoints.put(*waypoint)
+
+	return waypoint, nil
+}
+
+// GetMarket: Retrieve imports, exports and exchange data from a marketplace. Imports can be sold, exports can be purchased, and exchange goods can be purchased or sold. Send a ship to the waypoint to access trade good prices and recent transactions.
+func (c *Client) GetMarket(systemSymbol string, waypointSymbol string) (*m.Market, error) {
+	if err := checkArgs("systemSymbol", systemSymbol, "waypointSymbol", waypointSymbol); err != nil {
+		return nil, err
+	}
+
+	return get[m.Market](c, "/systems/"+systemSymbol+"/waypoints/"+waypointSymbol+"/market")
+}
+
+// GetShipyard: Get the shipyard for a waypoint. Send a ship to the waypoint to access ships that are currently available for purchase and recent transactions.
+func (c *Client) GetShipyard(systemSymbol string, waypointSymbol string) (*m.Shipyard, error) {
+	if err := checkArgs("systemSymbol", systemSymbol, "waypointSymbol", waypointSymbol); err != nil {
+		return nil, err
+	}
+
+	return get[m.Shipyard](c, "/systems/"+systemSymbol+"/waypoints/"+waypointSymbol+"/shipyard")
+}
+
 // GetJumpGate: Get jump gate details for a waypoint.
 func (c *Client) GetJumpGate(systemSymbol string, waypointSymbol string) (*m.JumpGate, error) {
-	c.t.Wait()
+	if err := checkArgs("systemSymbol", systemSymbol, "waypointSymbol", waypointSymbol); err != nil {
+		return nil, err
+	}
 
-	var resultResponse struct {
-		Data m.JumpGate `json:"data"`
+	return get[m.JumpGate](c, "/systems/"+systemSymbol+"/waypoints/"+waypointSymbol+"/jumpgate")
+}
+
+// GetSupplyChain gets which goods markets import to produce each export. Servers that do not offer the
+// endpoint fail with an error satisfying IsNotFound.
+func (c *Client) GetSupplyChain() (*m.SupplyChain, error) {
+	return get[m.SupplyChain](c, "/market/supply-chain")
+}
+
+// GetWaypointAt views the details of a waypoint, resolving its system from the waypoint symbol.
+func (c *Client) GetWaypointAt(waypointSymbol string) (*m.Waypoint, error) {
+	if err := checkArgs("waypointSymbol", waypointSymbol); err != nil {
+		return nil, err
+	}
+
+	systemSymbol, err := lib.SystemSymbolOf(waypointSymbol)
+	if err != nil {
+		return nil, err
 	}
 
-	url := "/systems/" + systemSymbol + "/waypoints/" + waypointSymbol + "/jumpgate"
+	return c.GetWaypoint(systemSymbol, waypointSymbol)
+}
 
-	res, err := c.r.R().
-		SetHeader("Content-Type", "application/json").
-		SetResult(&resultResponse).
-		SetError(ErrorResponse{}).
-		Get(url)
+// GetMarketAt gets the market at a waypoint, resolving its system from the waypoint symbol.
+func (c *Client) GetMarketAt(waypointSymbol string) (*m.Market, error) {
+	if err := checkArgs("waypointSymbol", waypointSymbol); err != nil {
+		return nil, err
+	}
+
+	systemSymbol, err := lib.SystemSymbolOf(waypointSymbol)
 	if err != nil {
 		return nil, err
 	}
 
-	if res.IsError() {
-		return nil, errors.New(res.Error().(ErrorResponse).Error.Message)
+	return c.GetMarket(systemSymbol, waypointSymbol)
+}
+
+// GetShipyardAt gets the shipyard at a waypoint, resolving its system from the waypoint symbol.
+func (c *Client) GetShipyardAt(waypointSymbol string) (*m.Shipyard, error) {
+	if err := checkArgs("waypointSymbol", waypointSymbol); err != nil {
+		return nil, err
 	}
 
-	return &resultResponse.Data, nil
+	systemSymbol, err := lib.SystemSymbolOf(waypointSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetShipyard(systemSymbol, waypointSymbol)
+}
+
+// GetJumpGateAt gets the jump gate at a waypoint, resolving its system from the waypoint symbol.
+func (c *Client) GetJumpGateAt(waypointSymbol string) (*m.JumpGate, error) {
+	if err := checkArgs("waypointSymbol", waypointSymbol); err != nil {
+		return nil, err
+	}
+
+	systemSymbol, err := lib.SystemSymbolOf(waypointSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetJumpGate(systemSymbol, waypointSymbol)
 }
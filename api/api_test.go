@@ -0,0 +1,805 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/clock"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+	"github.com/charmbracelet/log"
+)
+
+// newTestClient returns a Client sending its requests to handler, without throttling.
+func newTestClient(t testing.TB, handler http.Handler, opts ...Option) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return NewClient("token", append([]Option{WithBaseURL(server.URL), WithRateLimit(1000)}, opts...)...)
+}
+
+// respond returns a handler writing body with status to every request.
+func respond(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+}
+
+func TestGetMyAgentEvents(t *testing.T) {
+	body := `{"data":[
+		{"id":"evt-1","type":"CONTRACT_OFFERED","message":"New contract offered.","data":{"contractId":"c-1"},"createdAt":"2030-01-01T12:00:00Z"},
+		{"id":"evt-2","type":"REPUTATION_CHANGED","message":"Reputation with COSMIC rose.","data":null,"createdAt":"2030-01-01T12:05:00Z"}
+	]}`
+	c := newTestClient(t, respond(http.StatusOK, body))
+
+	events, err := c.GetMyAgentEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*events) != 2 {
+		t.Fatalf("got %d events, want 2", len(*events))
+	}
+	first := (*events)[0]
+	if first.ID != "evt-1" || first.Type != "CONTRACT_OFFERED" || first.Data.(map[string]interface{})["contractId"] != "c-1" {
+		t.Errorf("first event = %+v", first)
+	}
+	if want := time.Date(2030, 1, 1, 12, 5, 0, 0, time.UTC); !(*events)[1].CreatedAt.Equal(want) {
+		t.Errorf("second event created at %s, want %s", (*events)[1].CreatedAt, want)
+	}
+}
+
+func TestGetMyAgentEventsReportsAPIError(t *testing.T) {
+	c := newTestClient(t, respond(http.StatusUnauthorized, `{"error":{"message":"Token is invalid.","code":401}}`))
+
+	_, err := c.GetMyAgentEvents()
+	if err == nil || err.Error() != "Token is invalid." {
+		t.Fatalf("err = %v, want the API's message", err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Code != 401 || !IsUnauthorized(err) {
+		t.Errorf("err = %#v, want an unauthorized APIError", err)
+	}
+}
+
+func TestAPIErrorWithoutAnErrorBody(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("<html>bad gateway</html>"))
+	}))
+
+	_, err := c.GetMyAgent()
+	if err == nil || err.Error() != "502 Bad Gateway" || IsUnauthorized(err) {
+		t.Fatalf("err = %v, want the HTTP status", err)
+	}
+}
+
+func TestGetStatus(t *testing.T) {
+	c := newTestClient(t, respond(http.StatusOK, `{"status":"SpaceTraders is currently online","resetDate":"2030-01-15","stats":{"agents":1200}}`))
+
+	status, err := c.GetStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.ResetDate != "2030-01-15" || status.Stats.Agents != 1200 {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestGetSupplyChain(t *testing.T) {
+	var path string
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		respond(http.StatusOK, `{"data":{"exportToImportMap":{"IRON":["IRON_ORE"],"FAB_MATS":["IRON","QUARTZ_SAND"]}}}`)(w, r)
+	}))
+
+	chain, err := c.GetSupplyChain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/market/supply-chain" {
+		t.Errorf("requested %s", path)
+	}
+	if got := strings.Join(chain.ExportToImportMap["FAB_MATS"], ","); got != "IRON,QUARTZ_SAND" || len(chain.ExportToImportMap) != 2 {
+		t.Errorf("supply chain = %+v", chain.ExportToImportMap)
+	}
+}
+
+func TestGetSupplyChainWhereTheEndpointIsDisabled(t *testing.T) {
+	c := newTestClient(t, respond(http.StatusNotFound, `{"error":{"message":"Route not found.","code":404}}`))
+
+	if _, err := c.GetSupplyChain(); !IsNotFound(err) || IsUnauthorized(err) {
+		t.Errorf("err = %v, want a not-found APIError", err)
+	}
+}
+
+func TestRegisterSendsNoToken(t *testing.T) {
+	var authorization, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		respond(http.StatusCreated, `{"data":{"token":"new-token","agent":{"symbol":"GOGARIN"}}}`)(w, r)
+	}))
+	t.Cleanup(server.Close)
+	c := NewClient("", WithBaseURL(server.URL), WithRateLimit(1000))
+
+	res, err := c.Register("GOGARIN", "COSMIC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Token != "new-token" || res.Agent.Symbol != "GOGARIN" {
+		t.Errorf("registered %+v", res)
+	}
+	if authorization != "" {
+		t.Errorf("sent Authorization %q", authorization)
+	}
+	if body != `{"symbol":"GOGARIN","faction":"COSMIC"}` {
+		t.Errorf("sent %s", body)
+	}
+}
+
+// gate is a handler that counts its requests and holds them until released, so concurrent callers pile up on
+// the same request.
+type gate struct {
+	requests atomic.Int32
+	release  chan struct{}
+	once     sync.Once
+	body     string
+}
+
+func newGate(body string) *gate {
+	return &gate{release: make(chan struct{}), body: body}
+}
+
+// open releases the held requests and lets every later one through.
+func (g *gate) open() {
+	g.once.Do(func() { close(g.release) })
+}
+
+func (g *gate) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.requests.Add(1)
+	<-g.release
+	respond(http.StatusOK, g.body)(w, r)
+}
+
+func TestConcurrentGetWaypointSharesOneRequest(t *testing.T) {
+	g := newGate(`{"data":{"symbol":"X1-A-B2","systemSymbol":"X1-A","type":"ASTEROID_FIELD","traits":[{"symbol":"COMMON_METAL_DEPOSITS"}]}}`)
+	c := newTestClient(t, g)
+	t.Cleanup(g.open)
+
+	const callers = 20
+
+	var wg sync.WaitGroup
+	waypoints := make([]*m.Waypoint, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			waypoints[i], errs[i] = c.GetWaypoint("X1-A", "X1-A-B2")
+			if errs[i] == nil {
+				// Each caller owns its copy, so changing it must not race with the others.
+				waypoints[i].Traits[0].Name = "mine"
+				waypoints[i].Traits = append(waypoints[i].Traits, m.WaypointTrait{})
+			}
+		}(i)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	g.open()
+	wg.Wait()
+
+	if n := g.requests.Load(); n != 1 {
+		t.Fatalf("%d upstream requests for %d concurrent calls, want 1", n, callers)
+	}
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("caller %d: %s", i, err)
+		}
+		if waypoints[i].Symbol != "X1-A-B2" || len(waypoints[i].Traits) != 2 {
+			t.Fatalf("caller %d got %+v", i, waypoints[i])
+		}
+	}
+}
+
+func TestSharedPageOutlivesCancelledCaller(t *testing.T) {
+	g := newGate(`{"data":[{"symbol":"X1-A-B2"}],"meta":{"total":1,"page":1,"limit":20}}`)
+	c := newTestClient(t, g)
+	t.Cleanup(g.open)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, _, err := fetchPage[m.Waypoint](ctx, c, "/systems/X1-A/waypoints", 1)
+		first <- err
+	}()
+
+	for g.requests.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	second := make(chan error, 1)
+	var items []m.Waypoint
+	go func() {
+		var err error
+		items, _, err = fetchPage[m.Waypoint](context.Background(), c, "/systems/X1-A/waypoints", 1)
+		second <- err
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled caller: err = %v, want %v", err, context.Canceled)
+	}
+
+	g.open()
+	if err := <-second; err != nil {
+		t.Fatalf("caller sharing the cancelled caller's request: %s", err)
+	}
+	if len(items) != 1 || items[0].Symbol != "X1-A-B2" {
+		t.Fatalf("items = %+v", items)
+	}
+	if n := g.requests.Load(); n != 1 {
+		t.Fatalf("%d upstream requests, want 1", n)
+	}
+}
+
+func TestHighPriorityRequestIsNotSharedWithNormalLane(t *testing.T) {
+	g := newGate(`{"data":{"symbol":"X1-A-B2","systemSymbol":"X1-A","type":"ASTEROID_FIELD"}}`)
+	c := newTestClient(t, g)
+	t.Cleanup(g.open)
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err := c.GetWaypoint("X1-A", "X1-A-B2")
+		errs <- err
+	}()
+	for g.requests.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Joining the Normal lane's request would make the interactive call wait as long as fleet traffic does.
+	go func() {
+		_, err := c.WithPriority(High).GetWaypoint("X1-A", "X1-A-B2")
+		errs <- err
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for g.requests.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("High priority request joined the Normal lane's request instead of sending its own")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	g.open()
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRequestIsNotSharedAcrossSubsystems(t *testing.T) {
+	g := newGate(`{"data":{"symbol":"X1-A-B2","systemSymbol":"X1-A","type":"ASTEROID_FIELD"}}`)
+	c := newTestClient(t, g)
+	t.Cleanup(g.open)
+
+	// A mission request is held upstream; an interactive request for the same waypoint must not wait on it,
+	// since it would then be held to the missions subsystem's cap.
+	errs := make(chan error, 2)
+	go func() {
+		_, err := c.WithSubsystem(SubsystemMissions).GetWaypoint("X1-A", "X1-A-B2")
+		errs <- err
+	}()
+	for g.requests.Load() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	go func() {
+		_, err := c.WithSubsystem(SubsystemInteractive).GetWaypoint("X1-A", "X1-A-B2")
+		errs <- err
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for g.requests.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("interactive request joined the missions request instead of sending its own")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	g.open()
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestConcurrentMutationsAreNotShared(t *testing.T) {
+	g := newGate(`{"data":{"nav":{"waypointSymbol":"X1-A-B2","status":"DOCKED"}}}`)
+	c := newTestClient(t, g)
+	t.Cleanup(g.open)
+
+	const callers = 5
+
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.DockShip("SHIP-1"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	for g.requests.Load() < callers {
+		time.Sleep(time.Millisecond)
+	}
+	g.open()
+	wg.Wait()
+
+	if n := g.requests.Load(); n != callers {
+		t.Fatalf("%d upstream requests for %d docks, want %d", n, callers, callers)
+	}
+}
+
+func TestStatusObserverSeesEveryResponse(t *testing.T) {
+	var observed []int
+	observe := func(statusCode int) { observed = append(observed, statusCode) }
+
+	c := newTestClient(t, respond(http.StatusBadGateway, `{"error":{"message":"Bad gateway.","code":502}}`))
+	WithStatusObserver(observe)(c)
+	c.GetMyAgent()
+
+	unreachable := NewClient("token", WithBaseURL("http://127.0.0.1:1"), WithStatusObserver(observe))
+	unreachable.GetMyAgent()
+
+	if len(observed) != 2 || observed[0] != http.StatusBadGateway || observed[1] != http.StatusServiceUnavailable {
+		t.Errorf("observed %v, want [502 503]", observed)
+	}
+}
+
+func TestWaypointOnlyLookupsResolveTheSystem(t *testing.T) {
+	var paths []string
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		respond(http.StatusOK, `{"data":{"symbol":"X1-DF55-17335A"}}`)(w, r)
+	}))
+
+	c.GetWaypointAt("X1-DF55-17335A")
+	c.GetMarketAt("X1-DF55-17335A")
+	c.GetShipyardAt("X1-DF55-17335A")
+	c.GetJumpGateAt("X1-DF55-17335A")
+
+	want := []string{
+		"/systems/X1-DF55/waypoints/X1-DF55-17335A",
+		"/systems/X1-DF55/waypoints/X1-DF55-17335A/market",
+		"/systems/X1-DF55/waypoints/X1-DF55-17335A/shipyard",
+		"/systems/X1-DF55/waypoints/X1-DF55-17335A/jumpgate",
+	}
+	if strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Errorf("requested %v, want %v", paths, want)
+	}
+
+	if _, err := c.GetMarketAt("X1-DF55"); err == nil {
+		t.Error("looked up a malformed waypoint symbol")
+	}
+	if len(paths) != len(want) {
+		t.Error("sent a request for a malformed waypoint symbol")
+	}
+}
+
+func TestRefuelShip(t *testing.T) {
+	var sent []string
+	body := `{"data":{"agent":{"symbol":"GOGARIN","credits":960},"fuel":{"current":100,"capacity":100},
+		"transaction":{"shipSymbol":"GOGARIN-1","tradeSymbol":"FUEL","type":"PURCHASE","units":20,"pricePerUnit":2,"totalPrice":40}}}`
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/my/ships/GOGARIN-1/refuel" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		b, _ := io.ReadAll(r.Body)
+		sent = append(sent, string(b))
+		respond(http.StatusOK, body)(w, r)
+	}))
+
+	res, err := c.RefuelShip("GOGARIN-1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Agent.Credits != 960 || res.Fuel.Current != 100 || res.Transaction.TotalPrice != 40 {
+		t.Errorf("refuel = %+v", res)
+	}
+
+	if _, err := c.RefuelShip("GOGARIN-1", 300); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(sent, " "), `{} {"units":300}`; got != want {
+		t.Errorf("sent %s, want %s: no units fills the tank", got, want)
+	}
+}
+
+func TestPurchaseCargo(t *testing.T) {
+	var sent string
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sent = r.Method + " " + r.URL.Path + " " + string(body)
+		respond(http.StatusCreated, `{"data":{"agent":{"symbol":"GOGARIN","credits":700},
+			"cargo":{"capacity":30,"units":30,"inventory":[{"symbol":"IRON_ORE","units":30}]},
+			"transaction":{"shipSymbol":"GOGARIN-1","tradeSymbol":"IRON_ORE","type":"PURCHASE","units":30,"pricePerUnit":10,"totalPrice":300}}}`)(w, r)
+	}))
+
+	res, err := c.PurchaseCargo("GOGARIN-1", "IRON_ORE", 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Agent.Credits != 700 || res.Cargo.UnitsOf("IRON_ORE") != 30 || res.Transaction.TotalPrice != 300 {
+		t.Errorf("purchase = %+v", res)
+	}
+	if want := `POST /my/ships/GOGARIN-1/purchase {"symbol":"IRON_ORE","units":30}`; sent != want {
+		t.Errorf("request = %s, want %s", sent, want)
+	}
+
+	failing := newTestClient(t, respond(http.StatusBadRequest, `{"error":{"message":"Agent has insufficient credits.","code":4600}}`))
+	if _, err := failing.PurchaseCargo("GOGARIN-1", "IRON_ORE", 30); err == nil {
+		t.Error("purchasing without the credits succeeded")
+	}
+}
+
+func TestGetShipNav(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/my/ships/GOGARIN-1/nav" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		respond(http.StatusOK, `{"data":{"systemSymbol":"X1-DF55","waypointSymbol":"X1-DF55-A","status":"IN_ORBIT","flightMode":"CRUISE"}}`)(w, r)
+	}))
+
+	nav, err := c.GetShipNav("GOGARIN-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nav.Status != "IN_ORBIT" || nav.WaypointSymbol != "X1-DF55-A" {
+		t.Errorf("nav = %+v", nav)
+	}
+}
+
+func TestJettisonCargo(t *testing.T) {
+	var sent string
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sent = r.Method + " " + r.URL.Path + " " + string(body)
+		respond(http.StatusOK, `{"data":{"cargo":{"capacity":30,"units":4,"inventory":[{"symbol":"IRON_ORE","units":4}]}}}`)(w, r)
+	}))
+
+	cargo, err := c.JettisonCargo("GOGARIN-1", m.TradeGood{Symbol: "QUARTZ_SAND"}, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cargo.Units != 4 || cargo.UnitsOf("IRON_ORE") != 4 {
+		t.Errorf("cargo = %+v", cargo)
+	}
+	if want := `POST /my/ships/GOGARIN-1/jettison {"symbol":"QUARTZ_SAND","units":8}`; sent != want {
+		t.Errorf("request = %s, want %s", sent, want)
+	}
+}
+
+func TestIsNotTraded(t *testing.T) {
+	refused := newTestClient(t, respond(http.StatusBadRequest, `{"error":{"message":"Market does not trade QUARTZ_SAND.","code":4603}}`))
+	_, refusal := refused.SellCargo("GOGARIN-1", "QUARTZ_SAND", 8)
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"refused by the market", refusal, true},
+		{"refused by a simulated client", fmt.Errorf("QUARTZ_SAND at X1-A-1: %w", ErrNotTraded), true},
+		{"other API error", &APIError{StatusCode: http.StatusBadRequest, Code: 4600}, false},
+		{"other error", errors.New("timeout"), false},
+		{"no error", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsNotTraded(tt.err); got != tt.want {
+				t.Errorf("IsNotTraded(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateChart(t *testing.T) {
+	body := `{"data":{"chart":{"waypointSymbol":"X1-DF55-A","submittedBy":"GOGARIN"},
+		"waypoint":{"symbol":"X1-DF55-A","systemSymbol":"X1-DF55","traits":[{"symbol":"MARKETPLACE"}]}}}`
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/my/ships/GOGARIN-1/chart" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		respond(http.StatusCreated, body)(w, r)
+	}))
+
+	res, err := c.CreateChart("GOGARIN-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Chart.SubmittedBy != "GOGARIN" || res.Waypoint.Symbol != "X1-DF55-A" || !res.Waypoint.HasTrait("MARKETPLACE") {
+		t.Errorf("chart = %+v", res)
+	}
+
+	failing := newTestClient(t, respond(http.StatusBadRequest, `{"error":{"message":"Waypoint already charted.","code":4230}}`))
+	if _, err := failing.CreateChart("GOGARIN-1"); err == nil {
+		t.Error("charting a charted waypoint succeeded")
+	}
+}
+
+func TestDeliverAndFulfillContract(t *testing.T) {
+	var bodies []string
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, r.Method+" "+r.URL.Path+" "+string(body))
+
+		switch r.URL.Path {
+		case "/my/contracts/c-1/deliver":
+			respond(http.StatusOK, `{"data":{"contract":{"id":"c-1","terms":{"deliver":[{"tradeSymbol":"IRON_ORE","unitsRequired":10,"unitsFulfilled":10}]}},
+				"cargo":{"capacity":30,"units":0,"inventory":[]}}}`)(w, r)
+		case "/my/contracts/c-1/fulfill":
+			respond(http.StatusOK, `{"data":{"agent":{"symbol":"GOGARIN","credits":12000},"contract":{"id":"c-1","fulfilled":true}}}`)(w, r)
+		}
+	}))
+
+	delivered, err := c.DeliverContract("c-1", "GOGARIN-1", "IRON_ORE", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !delivered.Contract.IsDeliverComplete() || delivered.Cargo.Units != 0 {
+		t.Errorf("delivery = %+v", delivered)
+	}
+
+	fulfilled, err := c.FulfillContract("c-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fulfilled.Contract.Fulfilled || fulfilled.Agent.Credits != 12000 {
+		t.Errorf("fulfillment = %+v", fulfilled)
+	}
+
+	want := []string{
+		`POST /my/contracts/c-1/deliver {"shipSymbol":"GOGARIN-1","tradeSymbol":"IRON_ORE","units":10}`,
+		"POST /my/contracts/c-1/fulfill ",
+	}
+	if strings.Join(bodies, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests = %q, want %q", bodies, want)
+	}
+}
+
+func TestGetShipCooldownStampsClientClock(t *testing.T) {
+	// The fake clock is years away from the wall clock, as a local clock skewed against the server would be.
+	fake := clock.NewFake(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)).AutoAdvance()
+	body := `{"data":{"shipSymbol":"S-1","totalSeconds":70,"remainingSeconds":60,"expiration":"2031-01-01T00:01:00Z"}}`
+	c := newTestClient(t, respond(http.StatusOK, body), WithClock(fake))
+
+	cooldown, err := c.GetShipCooldown("S-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cooldown.FetchedAt.Equal(fake.Now()) {
+		t.Fatalf("FetchedAt = %s, want the client clock's %s", cooldown.FetchedAt, fake.Now())
+	}
+
+	fake.Advance(20 * time.Second)
+	if got := cooldown.Remaining(fake.Now()); got != 40*time.Second {
+		t.Fatalf("Remaining after 20s = %s, want 40s", got)
+	}
+}
+
+func TestBlankArgumentsMakeNoRequest(t *testing.T) {
+	var requests int32
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		t.Errorf("%s %s requested with a blank argument", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	dryRun := NewDryRunClient(client, store.NewMarketStore(), log.New(io.Discard), 1)
+
+	calls := map[string]func(c ClientAPI, blank string) error{
+		"AcceptContract":   func(c ClientAPI, s string) error { _, err := c.AcceptContract(s); return err },
+		"DeliverContract":  func(c ClientAPI, s string) error { _, err := c.DeliverContract("C1", s, "IRON_ORE", 1); return err },
+		"FulfillContract":  func(c ClientAPI, s string) error { _, err := c.FulfillContract(s); return err },
+		"GetShip":          func(c ClientAPI, s string) error { _, err := c.GetShip(s); return err },
+		"GetShipCooldown":  func(c ClientAPI, s string) error { _, err := c.GetShipCooldown(s); return err },
+		"GetShipNav":       func(c ClientAPI, s string) error { _, err := c.GetShipNav(s); return err },
+		"NavigateShip":     func(c ClientAPI, s string) error { _, err := c.NavigateShip("SHIP-1", s); return err },
+		"OrbitShip":        func(c ClientAPI, s string) error { _, err := c.OrbitShip(s); return err },
+		"DockShip":         func(c ClientAPI, s string) error { _, err := c.DockShip(s); return err },
+		"CreateSurvey":     func(c ClientAPI, s string) error { _, err := c.CreateSurvey(s); return err },
+		"ExtractResources": func(c ClientAPI, s string) error { _, err := c.ExtractResources(s); return err },
+		"JettisonCargo": func(c ClientAPI, s string) error {
+			_, err := c.JettisonCargo("SHIP-1", m.TradeGood{Symbol: s}, 1)
+			return err
+		},
+		"JumpShip":      func(c ClientAPI, s string) error { _, err := c.JumpShip("SHIP-1", s); return err },
+		"SellCargo":     func(c ClientAPI, s string) error { _, err := c.SellCargo(s, "IRON_ORE", 1); return err },
+		"PurchaseCargo": func(c ClientAPI, s string) error { _, err := c.PurchaseCargo("SHIP-1", s, 1); return err },
+		"PurchaseShip":  func(c ClientAPI, s string) error { _, err := c.PurchaseShip("SHIP_PROBE", s); return err },
+		"RefuelShip":    func(c ClientAPI, s string) error { _, err := c.RefuelShip(s, 0); return err },
+		"GetRepairShip": func(c ClientAPI, s string) error { _, err := c.GetRepairShip(s); return err },
+		"RepairShip":    func(c ClientAPI, s string) error { _, err := c.RepairShip(s); return err },
+		"CreateChart":   func(c ClientAPI, s string) error { _, err := c.CreateChart(s); return err },
+		"GetSystem":     func(c ClientAPI, s string) error { _, err := c.GetSystem(s); return err },
+		"ListWaypoints": func(c ClientAPI, s string) error { _, err := c.ListWaypoints(s); return err },
+		"GetWaypoint":   func(c ClientAPI, s string) error { _, err := c.GetWaypoint("X1-AB", s); return err },
+		"GetWaypoints": func(c ClientAPI, s string) error {
+			_, err := c.GetWaypoints(s, []string{"X1-AB-C1"})
+			return err
+		},
+		"GetMarket":     func(c ClientAPI, s string) error { _, err := c.GetMarket(s, "X1-AB-C1"); return err },
+		"GetShipyard":   func(c ClientAPI, s string) error { _, err := c.GetShipyard("X1-AB", s); return err },
+		"GetJumpGate":   func(c ClientAPI, s string) error { _, err := c.GetJumpGate(s, "X1-AB-C1"); return err },
+		"GetWaypointAt": func(c ClientAPI, s string) error { _, err := c.GetWaypointAt(s); return err },
+		"GetMarketAt":   func(c ClientAPI, s string) error { _, err := c.GetMarketAt(s); return err },
+		"GetShipyardAt": func(c ClientAPI, s string) error { _, err := c.GetShipyardAt(s); return err },
+		"GetJumpGateAt": func(c ClientAPI, s string) error { _, err := c.GetJumpGateAt(s); return err },
+	}
+
+	for name, call := range calls {
+		for _, c := range []struct {
+			kind   string
+			client ClientAPI
+		}{{"client", client}, {"dry run", dryRun}} {
+			for _, arg := range []string{"", "X1-AB/../C1"} {
+				err := call(c.client, arg)
+
+				var invalid *InvalidArgumentError
+				if !errors.Is(err, ErrInvalidArgument) || !errors.As(err, &invalid) {
+					t.Errorf("%s %s(%q): err = %v, want %v", c.kind, name, arg, err, ErrInvalidArgument)
+				}
+			}
+		}
+	}
+
+	if _, err := client.Register("", "COSMIC"); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Register(\"\"): err = %v, want %v", err, ErrInvalidArgument)
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Fatalf("%d requests made, want none", n)
+	}
+}
+
+func TestMalformedSuccessfulResponsesAreRejected(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		call func(c *Client) error
+	}{
+		{"GetMyAgent", `{"data":{}}`, func(c *Client) error { _, err := c.GetMyAgent(); return err }},
+		{"GetMyAgent renamed", `{"data":{"agentSymbol":"MOCK","credits":150000}}`, func(c *Client) error { _, err := c.GetMyAgent(); return err }},
+		{"GetShip", `{"ship":{"symbol":"SHIP-1"}}`, func(c *Client) error { _, err := c.GetShip("SHIP-1"); return err }},
+		{"GetSystem", `{"data":{"systemSymbol":"X1-AB"}}`, func(c *Client) error { _, err := c.GetSystem("X1-AB"); return err }},
+		{"GetWaypoint", `{"data":{"waypoint":{"symbol":"X1-AB-C1"}}}`, func(c *Client) error { _, err := c.GetWaypoint("X1-AB", "X1-AB-C1"); return err }},
+		{"GetShipNav", `{"data":{"systemSymbol":"X1-AB"}}`, func(c *Client) error { _, err := c.GetShipNav("SHIP-1"); return err }},
+		{"NavigateShip", `{"data":{"nav":{"waypointSymbol":"X1-AB-C1","status":"IN_TRANSIT","route":{}}}}`, func(c *Client) error {
+			_, err := c.NavigateShip("SHIP-1", "X1-AB-C1")
+			return err
+		}},
+		{"OrbitShip", `{"data":{"ship":{"nav":{"status":"IN_ORBIT"}}}}`, func(c *Client) error { _, err := c.OrbitShip("SHIP-1"); return err }},
+		{"DockShip", `{"data":null}`, func(c *Client) error { _, err := c.DockShip("SHIP-1"); return err }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestClient(t, respond(http.StatusOK, tc.body))
+
+			err := tc.call(c)
+
+			var unexpected *UnexpectedResponseError
+			if !errors.As(err, &unexpected) || !IsUnexpectedResponse(err) {
+				t.Fatalf("err = %v, want an unexpected response", err)
+			}
+			if unexpected.Reason == "" || unexpected.Body != tc.body {
+				t.Fatalf("error = %+v, want the reason and the body %s", unexpected, tc.body)
+			}
+		})
+	}
+}
+
+func TestUnexpectedResponseBodyIsTruncated(t *testing.T) {
+	body := `{"data":{"padding":"` + strings.Repeat("x", 2*maxUnexpectedBody) + `"}}`
+	c := newTestClient(t, respond(http.StatusOK, body))
+
+	_, err := c.GetMyAgent()
+
+	var unexpected *UnexpectedResponseError
+	if !errors.As(err, &unexpected) {
+		t.Fatalf("err = %v, want an unexpected response", err)
+	}
+	if want := body[:maxUnexpectedBody] + "..."; unexpected.Body != want {
+		t.Fatalf("body kept is %d bytes, want the first %d and an ellipsis", len(unexpected.Body), maxUnexpectedBody)
+	}
+}
+
+func TestWellFormedResponsesPassChecks(t *testing.T) {
+	arrival := `"route":{"arrival":"2030-01-01T00:05:00Z","departureTime":"2030-01-01T00:00:00Z"}`
+	nav := `{"systemSymbol":"X1-AB","waypointSymbol":"X1-AB-C1","status":"IN_ORBIT",` + arrival + `}`
+	cases := []struct {
+		name string
+		body string
+		call func(c *Client) error
+	}{
+		{"GetMyAgent", `{"data":{"symbol":"MOCK","credits":0}}`, func(c *Client) error { _, err := c.GetMyAgent(); return err }},
+		{"GetShipNav", `{"data":` + nav + `}`, func(c *Client) error { _, err := c.GetShipNav("SHIP-1"); return err }},
+		{"NavigateShip", `{"data":{"nav":` + nav + `}}`, func(c *Client) error { _, err := c.NavigateShip("SHIP-1", "X1-AB-C1"); return err }},
+		{"OrbitShip", `{"data":{"nav":` + nav + `}}`, func(c *Client) error { _, err := c.OrbitShip("SHIP-1"); return err }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestClient(t, respond(http.StatusOK, tc.body))
+
+			if err := tc.call(c); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestShipNavErrorsKeepTheirCode(t *testing.T) {
+	body := `{"error":{"message":"Ship MOCK-1 is missing the antimatter required to jump.","code":4212}}`
+	calls := map[string]func(c *Client) error{
+		"JumpShip":   func(c *Client) error { _, err := c.JumpShip("MOCK-1", "X1-CD"); return err },
+		"OrbitShip":  func(c *Client) error { _, err := c.OrbitShip("MOCK-1"); return err },
+		"DockShip":   func(c *Client) error { _, err := c.DockShip("MOCK-1"); return err },
+		"GetShipNav": func(c *Client) error { _, err := c.GetShipNav("MOCK-1"); return err },
+	}
+
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(t, respond(http.StatusBadRequest, body))
+
+			err := call(c)
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.Code != CodeJumpNoAntimatter || apiErr.Message != "Ship MOCK-1 is missing the antimatter required to jump." {
+				t.Fatalf("err = %#v, want the response's code and message", err)
+			}
+		})
+	}
+}
+
+func TestIsNoAntimatterMatchesTheErrorCode(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"code", &APIError{Code: CodeJumpNoAntimatter, Message: "Jump refused."}, true},
+		{"message only", &APIError{Code: 4000, Message: "Ship is on cooldown after buying antimatter."}, false},
+		{"simulated", fmt.Errorf("cannot jump: %w", ErrNoAntimatter), true},
+		{"other", errors.New("no antimatter here"), false},
+	}
+
+	for _, tc := range cases {
+		if got := IsNoAntimatter(tc.err); got != tc.want {
+			t.Errorf("%s: IsNoAntimatter(%v) = %t, want %t", tc.name, tc.err, got, tc.want)
+		}
+	}
+}
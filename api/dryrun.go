@@ -0,0 +1,1107 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/clock"
+	"github.com/GeoffreyDick/gogarin/lib"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+	"github.com/charmbracelet/log"
+)
+
+/*
+🧪 DryRunClient
+*/
+
+// extractionCooldown is the simulated reactor cooldown after an extraction or survey.
+const extractionCooldown = 70 * time.Second
+
+// simulatedYields are the trade goods a simulated extraction may produce.
+var simulatedYields = []string{"IRON_ORE", "COPPER_ORE", "ALUMINUM_ORE", "QUARTZ_SAND", "SILICON_CRYSTALS", "ICE_WATER"}
+
+// DryRunClient passes read requests through to a real client but intercepts every mutating request,
+// logging its would-be payload and answering with a plausible synthetic response.
+// Ship state is simulated locally, and arrivals and cooldowns run on a clock accelerated by speed.
+type DryRunClient struct {
+	inner   ClientAPI
+	markets *store.MarketStore
+	logger  *log.Logger
+	speed   float64
+	clock   clock.Clock
+	// rand draws simulated extraction yields.
+	rand *lib.Rand
+
+	mu       sync.Mutex
+	agent    *m.Agent
+	ships    map[string]*m.Ship
+	cooldown map[string]*m.Cooldown
+	accepted map[string]bool
+	// delivered holds the simulated units delivered to each contract, by trade symbol.
+	delivered map[string]map[string]int
+	fulfilled map[string]bool
+	// bought holds the symbols of simulated ship purchases, oldest first. The API knows nothing of them.
+	bought []string
+}
+
+// NewDryRunClient creates a DryRunClient wrapping inner. Sell prices are taken from markets.
+func NewDryRunClient(inner ClientAPI, markets *store.MarketStore, logger *log.Logger, speed float64) *DryRunClient {
+	if speed <= 0 {
+		speed = 1
+	}
+
+	return &DryRunClient{
+		inner:     inner,
+		markets:   markets,
+		logger:    logger,
+		speed:     speed,
+		clock:     clock.Real,
+		ships:     make(map[string]*m.Ship),
+		cooldown:  make(map[string]*m.Cooldown),
+		accepted:  make(map[string]bool),
+		delivered: make(map[string]map[string]int),
+		fulfilled: make(map[string]bool),
+	}
+}
+
+// SetRand draws the simulated extraction yields from r instead of an unseeded source, so a seeded dry run
+// repeats them. It must be called before the DryRunClient is used.
+func (d *DryRunClient) SetRand(r *lib.Rand) {
+	d.rand = r
+}
+
+// SetClock runs simulated arrivals and cooldowns on c instead of the wall clock. It must be called before
+// the DryRunClient is used.
+func (d *DryRunClient) SetClock(c clock.Clock) {
+	d.clock = c
+}
+
+// Pause holds the inner client's requests, if it supports it. Simulated actions are not held.
+func (d *DryRunClient) Pause() {
+	if pauser, ok := d.inner.(Pauser); ok {
+		pauser.Pause()
+	}
+}
+
+// Resume sends the inner client's held requests.
+func (d *DryRunClient) Resume() {
+	if pauser, ok := d.inner.(Pauser); ok {
+		pauser.Resume()
+	}
+}
+
+// simulated scales a real game duration onto the accelerated clock.
+func (d *DryRunClient) simulated(duration time.Duration) time.Duration {
+	return time.Duration(float64(duration) / d.speed)
+}
+
+// loadShip returns the simulated state of a ship, loading it from the API on first use.
+// The caller must hold d.mu.
+func (d *DryRunClient) loadShip(shipSymbol string) (*m.Ship, error) {
+	ship, ok := d.ships[shipSymbol]
+	if !ok {
+		fetched, err := d.inner.GetShip(shipSymbol)
+		if err != nil {
+			return nil, err
+		}
+		ship = fetched
+		d.ships[shipSymbol] = ship
+	}
+
+	// The server updates nav status lazily on arrival; so does the simulation.
+	if ship.Nav.Status == "IN_TRANSIT" && !ship.Nav.Route.Arrival.After(d.clock.Now()) {
+		ship.Nav.Status = "IN_ORBIT"
+	}
+
+	return ship, nil
+}
+
+// loadAgent returns the simulated agent, loading it from the API on first use.
+// The caller must hold d.mu.
+func (d *DryRunClient) loadAgent() (*m.Agent, error) {
+	if d.agent == nil {
+		agent, err := d.inner.GetMyAgent()
+		if err != nil {
+			return nil, err
+		}
+		d.agent = agent
+	}
+
+	return d.agent, nil
+}
+
+func (d *DryRunClient) GetMyAgent() (*m.Agent, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	agent, err := d.loadAgent()
+	if err != nil {
+		return nil, err
+	}
+
+	a := *agent
+
+	return &a, nil
+}
+
+func (d *DryRunClient) GetStatus() (*m.Status, error) {
+	return d.inner.GetStatus()
+}
+
+func (d *DryRunClient) GetMyAgentEvents() (*[]m.AgentEvent, error) {
+	return d.inner.GetMyAgentEvents()
+}
+
+func (d *DryRunClient) GetMyContracts() (*[]m.Contract, error) {
+	contracts, err := d.inner.GetMyContracts()
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i := range *contracts {
+		d.simulateContract(&(*contracts)[i])
+	}
+
+	return contracts, nil
+}
+
+// simulateContract applies simulated acceptance, deliveries, and fulfillment to a contract.
+// The caller must hold d.mu.
+func (d *DryRunClient) simulateContract(contract *m.Contract) {
+	if d.accepted[contract.ID] {
+		contract.Accepted = true
+	}
+
+	if d.fulfilled[contract.ID] {
+		contract.Fulfilled = true
+	}
+
+	for i := range contract.Terms.Deliver {
+		good := &contract.Terms.Deliver[i]
+		good.UnitsFulfilled += d.delivered[contract.ID][good.TradeSymbol]
+		if good.UnitsFulfilled > good.UnitsRequired {
+			good.UnitsFulfilled = good.UnitsRequired
+		}
+	}
+}
+
+// findContract returns the simulated state of a contract.
+func (d *DryRunClient) findContract(contractId string) (*m.Contract, error) {
+	contracts, err := d.GetMyContracts()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, contract := range *contracts {
+		if contract.ID == contractId {
+			return &contract, nil
+		}
+	}
+
+	return nil, errors.New("contract not found: " + contractId)
+}
+
+func (d *DryRunClient) DeliverContract(contractId string, shipSymbol string, tradeSymbol string, units int) (*DeliverContractResponse, error) {
+	if err := checkArgs("contractId", contractId, "shipSymbol", shipSymbol, "tradeSymbol", tradeSymbol); err != nil {
+		return nil, err
+	}
+
+	d.logger.Info("🧪 Intercepted DeliverContract.", "contract", contractId, "ship", shipSymbol, "symbol", tradeSymbol, "units", units)
+
+	contract, err := d.findContract(contractId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !contract.Accepted {
+		return nil, errors.New("contract has not been accepted")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ship, err := d.loadShip(shipSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if ship.Nav.Status != "DOCKED" {
+		return nil, errors.New("ship must be docked to deliver cargo")
+	}
+
+	remaining, ok := contract.RemainingUnits()[tradeSymbol]
+	if !ok {
+		return nil, errors.New("contract does not require " + tradeSymbol)
+	}
+
+	if units > remaining {
+		return nil, errors.New("delivery exceeds the units the contract requires")
+	}
+
+	for _, good := range contract.Terms.Deliver {
+		if good.TradeSymbol == tradeSymbol && good.DestinationSymbol != ship.Nav.WaypointSymbol {
+			return nil, errors.New("ship is not at the delivery destination " + good.DestinationSymbol)
+		}
+	}
+
+	if err := ship.Cargo.Remove(tradeSymbol, units); err != nil {
+		return nil, err
+	}
+
+	if d.delivered[contractId] == nil {
+		d.delivered[contractId] = make(map[string]int)
+	}
+	d.delivered[contractId][tradeSymbol] += units
+
+	for i := range contract.Terms.Deliver {
+		if good := &contract.Terms.Deliver[i]; good.TradeSymbol == tradeSymbol {
+			good.UnitsFulfilled += units
+		}
+	}
+
+	return &DeliverContractResponse{Contract: *contract, Cargo: copyShip(ship).Cargo}, nil
+}
+
+func (d *DryRunClient) FulfillContract(contractId string) (*FulfillContractResponse, error) {
+	if err := checkArgs("contractId", contractId); err != nil {
+		return nil, err
+	}
+
+	d.logger.Info("🧪 Intercepted FulfillContract.", "contract", contractId)
+
+	contract, err := d.findContract(contractId)
+	if err != nil {
+		return nil, err
+	}
+
+	if contract.Fulfilled {
+		return nil, errors.New("contract has already been fulfilled")
+	}
+
+	if !contract.IsDeliverComplete() {
+		return nil, errors.New("contract deliveries are not complete")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	agent, err := d.loadAgent()
+	if err != nil {
+		return nil, err
+	}
+
+	d.fulfilled[contractId] = true
+	contract.Fulfilled = true
+	agent.Credits += m.Credits(contract.Terms.Payment.OnFulfilled)
+
+	return &FulfillContractResponse{Agent: *agent, Contract: *contract}, nil
+}
+
+func (d *DryRunClient) AcceptContract(contractId string) (*AcceptContractResponse, error) {
+	if err := checkArgs("contractId", contractId); err != nil {
+		return nil, err
+	}
+
+	d.logger.Info("🧪 Intercepted AcceptContract.", "contract", contractId)
+
+	contracts, err := d.inner.GetMyContracts()
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	agent, err := d.loadAgent()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, contract := range *contracts {
+		if contract.ID != contractId {
+			continue
+		}
+
+		if contract.Accepted || d.accepted[contractId] {
+			return nil, errors.New("contract has already been accepted")
+		}
+
+		d.accepted[contractId] = true
+		contract.Accepted = true
+		agent.Credits += m.Credits(contract.Terms.Payment.OnAccepted)
+
+		return &AcceptContractResponse{Agent: *agent, Contract: contract}, nil
+	}
+
+	return nil, errors.New("contract not found: " + contractId)
+}
+
+func (d *DryRunClient) GetMyShips() (*[]m.Ship, error) {
+	ships, err := d.inner.GetMyShips()
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i := range *ships {
+		symbol := (*ships)[i].Symbol
+		if _, ok := d.ships[symbol]; !ok {
+			ship := (*ships)[i]
+			d.ships[symbol] = &ship
+		}
+
+		ship, _ := d.loadShip(symbol)
+		(*ships)[i] = *copyShip(ship)
+	}
+
+	for _, symbol := range d.bought {
+		*ships = append(*ships, *copyShip(d.ships[symbol]))
+	}
+
+	return ships, nil
+}
+
+func (d *DryRunClient) GetShip(shipSymbol string) (*m.Ship, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ship, err := d.loadShip(shipSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyShip(ship), nil
+}
+
+// copyShip returns a copy of a simulated ship whose cargo inventory can be mutated independently.
+func copyShip(ship *m.Ship) *m.Ship {
+	s := *ship
+	s.Cargo.Inventory = append([]m.ShipCargoItem(nil), ship.Cargo.Inventory...)
+
+	return &s
+}
+
+func (d *DryRunClient) GetShipCooldown(shipSymbol string) (*m.Cooldown, error) {
+	d.mu.Lock()
+	cooldown, ok := d.cooldown[shipSymbol]
+	d.mu.Unlock()
+
+	if ok {
+		c := *cooldown
+		return &c, nil
+	}
+
+	return d.inner.GetShipCooldown(shipSymbol)
+}
+
+func (d *DryRunClient) GetShipNav(shipSymbol string) (*m.ShipNav, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ship, err := d.loadShip(shipSymbol)
+	if err != nil {
+		return nil, err
+	}
+	nav := ship.Nav
+
+	return &nav, nil
+}
+
+func (d *DryRunClient) NavigateShip(shipSymbol string, waypointSymbol string) (*NavigateShipResponse, error) {
+	if err := checkArgs("shipSymbol", shipSymbol, "waypointSymbol", waypointSymbol); err != nil {
+		return nil, err
+	}
+
+	d.logger.Info("🧪 Intercepted NavigateShip.", "ship", shipSymbol, "waypointSymbol", waypointSymbol)
+
+	d.mu.Lock()
+	ship, err := d.loadShip(shipSymbol)
+	if err != nil {
+		d.mu.Unlock()
+		return nil, err
+	}
+	systemSymbol, originSymbol, status := ship.Nav.SystemSymbol, ship.Nav.WaypointSymbol, ship.Nav.Status
+	d.mu.Unlock()
+
+	switch status {
+	case "DOCKED":
+		return nil, fmt.Errorf("cannot navigate: %w", ErrNotInOrbit)
+	case "IN_TRANSIT":
+		return nil, errors.New("ship is currently in transit")
+	}
+
+	origin, err := d.inner.GetWaypoint(systemSymbol, originSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	destination, err := d.inner.GetWaypoint(systemSymbol, waypointSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	distance := lib.WaypointDistance(origin, destination)
+	fuel := lib.FuelCost(distance, ship.Nav.FlightMode)
+	if ship.Fuel.Capacity > 0 && fuel > ship.Fuel.Current {
+		return nil, errors.New("ship does not have enough fuel to navigate")
+	}
+
+	now := d.clock.Now()
+	travel := d.simulated(lib.TravelTime(distance, ship.Engine.Speed, ship.Nav.FlightMode))
+
+	if ship.Fuel.Capacity > 0 {
+		ship.Fuel.Current -= fuel
+		ship.Fuel.Consumed.Amount = fuel
+		ship.Fuel.Consumed.Timestamp = m.OptionalTime{Time: now}
+	}
+
+	ship.Nav.WaypointSymbol = destination.Symbol
+	ship.Nav.Status = "IN_TRANSIT"
+	ship.Nav.Route = m.ShipNavRoute{
+		Departure:     m.ShipNavRouteWaypoint{Symbol: origin.Symbol, Type: origin.Type, SystemSymbol: origin.SystemSymbol, X: origin.X, Y: origin.Y},
+		Destination:   m.ShipNavRouteWaypoint{Symbol: destination.Symbol, Type: destination.Type, SystemSymbol: destination.SystemSymbol, X: destination.X, Y: destination.Y},
+		DepartureTime: now,
+		Arrival:       now.Add(travel),
+	}
+
+	return &NavigateShipResponse{Fuel: ship.Fuel, Nav: ship.Nav}, nil
+}
+
+func (d *DryRunClient) OrbitShip(shipSymbol string) (*m.ShipNav, error) {
+	if err := checkArgs("shipSymbol", shipSymbol); err != nil {
+		return nil, err
+	}
+
+	d.logger.Info("🧪 Intercepted OrbitShip.", "ship", shipSymbol)
+
+	return d.setNavStatus(shipSymbol, "IN_ORBIT")
+}
+
+func (d *DryRunClient) DockShip(shipSymbol string) (*m.ShipNav, error) {
+	if err := checkArgs("shipSymbol", shipSymbol); err != nil {
+		return nil, err
+	}
+
+	d.logger.Info("🧪 Intercepted DockShip.", "ship", shipSymbol)
+
+	return d.setNavStatus(shipSymbol, "DOCKED")
+}
+
+// setNavStatus moves a ship that is not in transit into a new nav status.
+func (d *DryRunClient) setNavStatus(shipSymbol string, status string) (*m.ShipNav, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ship, err := d.loadShip(shipSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if ship.Nav.Status == "IN_TRANSIT" {
+		return nil, errors.New("ship is currently in transit")
+	}
+
+	ship.Nav.Status = status
+	nav := ship.Nav
+
+	return &nav, nil
+}
+
+// startCooldown puts a ship's reactor on the simulated cooldown. The caller must hold d.mu.
+func (d *DryRunClient) startCooldown(shipSymbol string) m.Cooldown {
+	now := d.clock.Now()
+	duration := d.simulated(extractionCooldown)
+	seconds := int(math.Ceil(duration.Seconds()))
+
+	cooldown := m.Cooldown{
+		ShipSymbol:       shipSymbol,
+		TotalSeconds:     seconds,
+		RemainingSeconds: seconds,
+		Expiration:       m.OptionalTime{Time: now.Add(duration)},
+		FetchedAt:        now,
+	}
+	d.cooldown[shipSymbol] = &cooldown
+
+	return cooldown
+}
+
+// checkCooldown returns an error if a ship's simulated reactor is still cooling down. The caller must hold d.mu.
+func (d *DryRunClient) checkCooldown(shipSymbol string) error {
+	if cooldown, ok := d.cooldown[shipSymbol]; ok && !cooldown.Ready(d.clock.Now()) {
+		return errors.New("ship action is still on cooldown")
+	}
+
+	return nil
+}
+
+func (d *DryRunClient) CreateSurvey(shipSymbol string) (*CreateSurveyResponse, error) {
+	if err := checkArgs("shipSymbol", shipSymbol); err != nil {
+		return nil, err
+	}
+
+	d.logger.Info("🧪 Intercepted CreateSurvey.", "ship", shipSymbol)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.checkCooldown(shipSymbol); err != nil {
+		return nil, err
+	}
+
+	return &CreateSurveyResponse{Cooldown: d.startCooldown(shipSymbol)}, nil
+}
+
+func (d *DryRunClient) ExtractResources(shipSymbol string, surveys ...m.Survey) (*ExtractResourcesResponse, error) {
+	if err := checkArgs("shipSymbol", shipSymbol); err != nil {
+		return nil, err
+	}
+
+	d.logger.Info("🧪 Intercepted ExtractResources.", "ship", shipSymbol, "surveys", len(surveys))
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ship, err := d.loadShip(shipSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if ship.Nav.Status != "IN_ORBIT" {
+		return nil, errors.New("ship must be in orbit to extract resources")
+	}
+
+	if err := d.checkCooldown(shipSymbol); err != nil {
+		return nil, err
+	}
+
+	if ship.Cargo.SpaceRemaining() == 0 {
+		return nil, errors.New("ship cargo is full")
+	}
+
+	symbol := simulatedYields[d.rand.Intn(len(simulatedYields))]
+	units := 3 + d.rand.Intn(8)
+	if units > ship.Cargo.SpaceRemaining() {
+		units = ship.Cargo.SpaceRemaining()
+	}
+
+	if err := ship.Cargo.Add(symbol, units); err != nil {
+		return nil, err
+	}
+
+	res := &ExtractResourcesResponse{
+		Cooldown: d.startCooldown(shipSymbol),
+		Cargo:    copyShip(ship).Cargo,
+	}
+	res.Extraction.ShipSymbol = shipSymbol
+	res.Extraction.Yield.Symbol = symbol
+	res.Extraction.Yield.Units = units
+
+	return res, nil
+}
+
+func (d *DryRunClient) JettisonCargo(shipSymbol string, cargoSymbol m.TradeGood, units int) (*m.ShipCargo, error) {
+	if err := checkArgs("shipSymbol", shipSymbol, "cargoSymbol", cargoSymbol.Symbol); err != nil {
+		return nil, err
+	}
+
+	d.logger.Info("🧪 Intercepted JettisonCargo.", "ship", shipSymbol, "symbol", cargoSymbol.Symbol, "units", units)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ship, err := d.loadShip(shipSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ship.Cargo.Remove(cargoSymbol.Symbol, units); err != nil {
+		return nil, err
+	}
+
+	return &copyShip(ship).Cargo, nil
+}
+
+func (d *DryRunClient) JumpShip(shipSymbol string, systemSymbol string) (*m.ShipNav, error) {
+	if err := checkArgs("shipSymbol", shipSymbol, "systemSymbol", systemSymbol); err != nil {
+		return nil, err
+	}
+
+	d.logger.Info("🧪 Intercepted JumpShip.", "ship", shipSymbol, "systemSymbol", systemSymbol)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ship, err := d.loadShip(shipSymbol)
+	if err != nil {
+		return nil, err
+	}
+	if ship.Cargo.UnitsOf("ANTIMATTER") == 0 {
+		return nil, fmt.Errorf("cannot jump: %w", ErrNoAntimatter)
+	}
+
+	return nil, errors.New("jumps are not simulated in dry run")
+}
+
+func (d *DryRunClient) SellCargo(shipSymbol string, cargoSymbol string, units int) (*SellCargoResponse, error) {
+	if err := checkArgs("shipSymbol", shipSymbol, "cargoSymbol", cargoSymbol); err != nil {
+		return nil, err
+	}
+
+	d.logger.Info("🧪 Intercepted SellCargo.", "ship", shipSymbol, "symbol", cargoSymbol, "units", units)
+
+	d.mu.Lock()
+	ship, err := d.loadShip(shipSymbol)
+	if err != nil {
+		d.mu.Unlock()
+		return nil, err
+	}
+	systemSymbol, waypointSymbol, status := ship.Nav.SystemSymbol, ship.Nav.WaypointSymbol, ship.Nav.Status
+	d.mu.Unlock()
+
+	if status != "DOCKED" {
+		return nil, errors.New("ship must be docked to sell cargo")
+	}
+
+	observation, ok := d.markets.Get(waypointSymbol)
+	if !ok || len(observation.Market.TradeGoods) == 0 {
+		// Fetching the market is a read, so it goes to the API.
+		if _, err := d.GetMarket(systemSymbol, waypointSymbol); err != nil {
+			return nil, err
+		}
+		observation, _ = d.markets.Get(waypointSymbol)
+	}
+
+	price, ok := observation.Market.SellPriceOf(cargoSymbol)
+	if !ok {
+		if observation.Market.ImportsGood(cargoSymbol) {
+			return nil, errors.New("no price data recorded for " + cargoSymbol + " at " + waypointSymbol)
+		}
+		return nil, fmt.Errorf("%s at %s: %w", cargoSymbol, waypointSymbol, ErrNotTraded)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	agent, err := d.loadAgent()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ship.Cargo.Remove(cargoSymbol, units); err != nil {
+		return nil, err
+	}
+
+	total := price * int64(units)
+	agent.Credits += m.Credits(total)
+
+	return &SellCargoResponse{
+		Agent: *agent,
+		Cargo: copyShip(ship).Cargo,
+		Transaction: m.MarketTransaction{
+			WaypointSymbol: waypointSymbol,
+			ShipSymbol:     shipSymbol,
+			TradeSymbol:    cargoSymbol,
+			Type:           "SELL",
+			Units:          units,
+			PricePerUnit:   price,
+			TotalPrice:     total,
+			Timestamp:      d.clock.Now(),
+		},
+	}, nil
+}
+
+func (d *DryRunClient) PurchaseCargo(shipSymbol string, cargoSymbol string, units int) (*PurchaseCargoResponse, error) {
+	if err := checkArgs("shipSymbol", shipSymbol, "cargoSymbol", cargoSymbol); err != nil {
+		return nil, err
+	}
+
+	d.logger.Info("🧪 Intercepted PurchaseCargo.", "ship", shipSymbol, "symbol", cargoSymbol, "units", units)
+
+	d.mu.Lock()
+	ship, err := d.loadShip(shipSymbol)
+	if err != nil {
+		d.mu.Unlock()
+		return nil, err
+	}
+	systemSymbol, waypointSymbol, status := ship.Nav.SystemSymbol, ship.Nav.WaypointSymbol, ship.Nav.Status
+	d.mu.Unlock()
+
+	if status != "DOCKED" {
+		return nil, errors.New("ship must be docked to purchase cargo")
+	}
+
+	observation, ok := d.markets.Get(waypointSymbol)
+	if !ok || len(observation.Market.TradeGoods) == 0 {
+		// Fetching the market is a read, so it goes to the API.
+		if _, err := d.GetMarket(systemSymbol, waypointSymbol); err != nil {
+			return nil, err
+		}
+		observation, _ = d.markets.Get(waypointSymbol)
+	}
+
+	price, ok := observation.Market.PurchasePriceOf(cargoSymbol)
+	if !ok || price <= 0 {
+		return nil, fmt.Errorf("%s at %s: %w", cargoSymbol, waypointSymbol, ErrNotTraded)
+	}
+
+	if volume, ok := observation.Market.TradeVolumeOf(cargoSymbol); ok && volume > 0 && units > volume {
+		return nil, fmt.Errorf("cannot purchase %d %s; the trade volume is %d", units, cargoSymbol, volume)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	agent, err := d.loadAgent()
+	if err != nil {
+		return nil, err
+	}
+
+	total := price * int64(units)
+	if int64(agent.Credits) < total {
+		return nil, fmt.Errorf("insufficient credits: %d needed, %d available", total, agent.Credits)
+	}
+
+	if err := ship.Cargo.Add(cargoSymbol, units); err != nil {
+		return nil, err
+	}
+
+	agent.Credits -= m.Credits(total)
+
+	return &PurchaseCargoResponse{
+		Agent: *agent,
+		Cargo: copyShip(ship).Cargo,
+		Transaction: m.MarketTransaction{
+			WaypointSymbol: waypointSymbol,
+			ShipSymbol:     shipSymbol,
+			TradeSymbol:    cargoSymbol,
+			Type:           "PURCHASE",
+			Units:          units,
+			PricePerUnit:   price,
+			TotalPrice:     total,
+			Timestamp:      d.clock.Now(),
+		},
+	}, nil
+}
+
+// PurchaseShip simulates buying a ship at the price the shipyard lists. The simulated ship is built from the
+// listing and joins the fleet returned by GetMyShips.
+func (d *DryRunClient) PurchaseShip(shipType string, waypointSymbol string) (*PurchaseShipResponse, error) {
+	if err := checkArgs("shipType", shipType, "waypointSymbol", waypointSymbol); err != nil {
+		return nil, err
+	}
+
+	d.logger.Info("🧪 Intercepted PurchaseShip.", "shipType", shipType, "waypoint", waypointSymbol)
+
+	ships, err := d.GetMyShips()
+	if err != nil {
+		return nil, err
+	}
+
+	var present *m.Ship
+	for i := range *ships {
+		if ship := &(*ships)[i]; ship.Nav.WaypointSymbol == waypointSymbol && ship.Nav.Status != "IN_TRANSIT" {
+			present = ship
+			break
+		}
+	}
+	if present == nil {
+		return nil, fmt.Errorf("no ship at %s to purchase from the shipyard", waypointSymbol)
+	}
+
+	// Fetching the shipyard is a read, so it goes to the API.
+	shipyard, err := d.inner.GetShipyardAt(waypointSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	var listing *m.ShipyardShip
+	for i := range shipyard.Ships {
+		if shipyard.Ships[i].Type == shipType {
+			listing = &shipyard.Ships[i]
+			break
+		}
+	}
+	if listing == nil {
+		return nil, fmt.Errorf("shipyard at %s does not sell %s", waypointSymbol, shipType)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	agent, err := d.loadAgent()
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(agent.Credits) < listing.PurchasePrice {
+		return nil, fmt.Errorf("insufficient credits: %d needed, %d available", listing.PurchasePrice, agent.Credits)
+	}
+
+	symbol := fmt.Sprintf("%s-%X", agent.Symbol, len(*ships)+1)
+	ship := listing.Build(symbol, present.Registration.FactionSymbol, present.Nav)
+	d.ships[symbol] = &ship
+	d.bought = append(d.bought, symbol)
+
+	agent.Credits -= m.Credits(listing.PurchasePrice)
+
+	return &PurchaseShipResponse{
+		Agent: *agent,
+		Ship:  *copyShip(&ship),
+		Transaction: m.ShipyardTransaction{
+			WaypointSymbol: waypointSymbol,
+			ShipSymbol:     symbol,
+			ShipType:       shipType,
+			Price:          listing.PurchasePrice,
+			AgentSymbol:    agent.Symbol,
+			Timestamp:      d.clock.Now(),
+		},
+	}, nil
+}
+
+func (d *DryRunClient) RefuelShip(shipSymbol string, units int) (*RefuelShipResponse, error) {
+	if err := checkArgs("shipSymbol", shipSymbol); err != nil {
+		return nil, err
+	}
+
+	d.logger.Info("🧪 Intercepted RefuelShip.", "ship", shipSymbol, "units", units)
+
+	d.mu.Lock()
+	ship, err := d.loadShip(shipSymbol)
+	if err != nil {
+		d.mu.Unlock()
+		return nil, err
+	}
+	systemSymbol, waypointSymbol, status := ship.Nav.SystemSymbol, ship.Nav.WaypointSymbol, ship.Nav.Status
+	d.mu.Unlock()
+
+	if status != "DOCKED" {
+		return nil, errors.New("ship must be docked to refuel")
+	}
+
+	observation, ok := d.markets.Get(waypointSymbol)
+	if !ok || len(observation.Market.TradeGoods) == 0 {
+		if _, err := d.GetMarket(systemSymbol, waypointSymbol); err != nil {
+			return nil, err
+		}
+		observation, _ = d.markets.Get(waypointSymbol)
+	}
+
+	price, ok := observation.Market.PurchasePriceOf("FUEL")
+	if !ok {
+		return nil, errors.New("market does not sell FUEL")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	agent, err := d.loadAgent()
+	if err != nil {
+		return nil, err
+	}
+
+	if space := ship.Fuel.Capacity - ship.Fuel.Current; units <= 0 || units > space {
+		units = space
+	}
+	total := price * int64(units)
+	if int64(agent.Credits) < total {
+		return nil, errors.New("insufficient credits to refuel")
+	}
+
+	agent.Credits -= m.Credits(total)
+	ship.Fuel.Current += units
+
+	return &RefuelShipResponse{
+		Agent: *agent,
+		Fuel:  ship.Fuel,
+		Transaction: m.MarketTransaction{
+			WaypointSymbol: waypointSymbol,
+			ShipSymbol:     shipSymbol,
+			TradeSymbol:    "FUEL",
+			Type:           "PURCHASE",
+			Units:          units,
+			PricePerUnit:   price,
+			TotalPrice:     total,
+			Timestamp:      d.clock.Now(),
+		},
+	}, nil
+}
+
+// GetRepairShip quotes a free repair, as simulated repairs are free.
+func (d *DryRunClient) GetRepairShip(shipSymbol string) (*m.RepairTransaction, error) {
+	if err := checkArgs("shipSymbol", shipSymbol); err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ship, err := d.loadShip(shipSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m.RepairTransaction{
+		WaypointSymbol: ship.Nav.WaypointSymbol,
+		ShipSymbol:     shipSymbol,
+		Timestamp:      d.clock.Now(),
+	}, nil
+}
+
+// RepairShip simulates repairing a docked ship at a shipyard, restoring its frame, reactor, and engine to full
+// condition. Repair prices are not observed, so simulated repairs are free.
+func (d *DryRunClient) RepairShip(shipSymbol string) (*RepairShipResponse, error) {
+	if err := checkArgs("shipSymbol", shipSymbol); err != nil {
+		return nil, err
+	}
+
+	d.logger.Info("🧪 Intercepted RepairShip.", "ship", shipSymbol)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ship, err := d.loadShip(shipSymbol)
+	if err != nil {
+		return nil, err
+	}
+	if ship.Nav.Status != "DOCKED" {
+		return nil, errors.New("ship must be docked to repair")
+	}
+
+	agent, err := d.loadAgent()
+	if err != nil {
+		return nil, err
+	}
+
+	ship.Frame.Condition = m.FullCondition
+	ship.Reactor.Condition = m.FullCondition
+	ship.Engine.Condition = m.FullCondition
+
+	return &RepairShipResponse{
+		Agent: *agent,
+		Ship:  *copyShip(ship),
+		Transaction: m.RepairTransaction{
+			WaypointSymbol: ship.Nav.WaypointSymbol,
+			ShipSymbol:     shipSymbol,
+			Timestamp:      d.clock.Now(),
+		},
+	}, nil
+}
+
+// CreateChart simulates charting by returning the waypoint the ship is at, charted by the agent.
+func (d *DryRunClient) CreateChart(shipSymbol string) (*CreateChartResponse, error) {
+	if err := checkArgs("shipSymbol", shipSymbol); err != nil {
+		return nil, err
+	}
+
+	d.logger.Info("🧪 Intercepted CreateChart.", "ship", shipSymbol)
+
+	d.mu.Lock()
+	ship, err := d.loadShip(shipSymbol)
+	if err != nil {
+		d.mu.Unlock()
+		return nil, err
+	}
+	systemSymbol, waypointSymbol := ship.Nav.SystemSymbol, ship.Nav.WaypointSymbol
+	agent, err := d.loadAgent()
+	if err != nil {
+		d.mu.Unlock()
+		return nil, err
+	}
+	agentSymbol := agent.Symbol
+	d.mu.Unlock()
+
+	waypoint, err := d.inner.GetWaypoint(systemSymbol, waypointSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	chart := m.Chart{WaypointSymbol: waypointSymbol, SubmittedBy: agentSymbol, SubmittedOn: m.OptionalTime{Time: d.clock.Now()}}
+	waypoint.Chart = chart
+	waypoint.Traits = lib.Filter(waypoint.Traits, func(trait m.WaypointTrait) bool {
+		return trait.Symbol != "UNCHARTED"
+	})
+
+	return &CreateChartResponse{Chart: chart, Waypoint: *waypoint}, nil
+}
+
+func (d *DryRunClient) ListSystems() (*[]m.System, error) {
+	return d.inner.ListSystems()
+}
+
+func (d *DryRunClient) GetSystem(systemSymbol string) (*m.System, error) {
+	return d.inner.GetSystem(systemSymbol)
+}
+
+func (d *DryRunClient) ListWaypoints(systemSymbol string) (*[]m.Waypoint, error) {
+	return d.inner.ListWaypoints(systemSymbol)
+}
+
+func (d *DryRunClient) GetWaypoint(systemSymbol string, waypointSymbol string) (*m.Waypoint, error) {
+	return d.inner.GetWaypoint(systemSymbol, waypointSymbol)
+}
+
+func (d *DryRunClient) GetWaypoints(systemSymbol string, symbols []string) (map[string]m.Waypoint, error) {
+	return d.inner.GetWaypoints(systemSymbol, symbols)
+}
+
+// GetMarket reads the market from the API and records it in the market store used for simulated sales.
+func (d *DryRunClient) GetMarket(systemSymbol string, waypointSymbol string) (*m.Market, error) {
+	market, err := d.inner.GetMarket(systemSymbol, waypointSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	d.markets.Record(*market, d.clock.Now())
+
+	return market, nil
+}
+
+func (d *DryRunClient) GetShipyard(systemSymbol string, waypointSymbol string) (*m.Shipyard, error) {
+	return d.inner.GetShipyard(systemSymbol, waypointSymbol)
+}
+
+func (d *DryRunClient) GetJumpGate(systemSymbol string, waypointSymbol string) (*m.JumpGate, error) {
+	return d.inner.GetJumpGate(systemSymbol, waypointSymbol)
+}
+
+func (d *DryRunClient) GetSupplyChain() (*m.SupplyChain, error) {
+	return d.inner.GetSupplyChain()
+}
+
+func (d *DryRunClient) GetWaypointAt(waypointSymbol string) (*m.Waypoint, error) {
+	return d.inner.GetWaypointAt(waypointSymbol)
+}
+
+// GetMarketAt reads the market at a waypoint like GetMarket, resolving its system from the waypoint symbol.
+func (d *DryRunClient) GetMarketAt(waypointSymbol string) (*m.Market, error) {
+	if err := checkArgs("waypointSymbol", waypointSymbol); err != nil {
+		return nil, err
+	}
+
+	systemSymbol, err := lib.SystemSymbolOf(waypointSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.GetMarket(systemSymbol, waypointSymbol)
+}
+
+func (d *DryRunClient) GetShipyardAt(waypointSymbol string) (*m.Shipyard, error) {
+	return d.inner.GetShipyardAt(waypointSymbol)
+}
+
+func (d *DryRunClient) GetJumpGateAt(waypointSymbol string) (*m.JumpGate, error) {
+	return d.inner.GetJumpGateAt(waypointSymbol)
+}
@@ -0,0 +1,313 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+	"github.com/charmbracelet/log"
+)
+
+// symbol is passed for every string argument. The recorded responses use it for whatever the argument names,
+// so the contract, cargo and market it is looked up in all exist.
+const symbol = "X1-DF55-A"
+
+// recordingTransport records every request and answers GETs with canned game state, refusing everything else.
+type recordingTransport struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.mu.Lock()
+	rt.requests = append(rt.requests, req.Method+" "+req.URL.Path)
+	rt.mu.Unlock()
+
+	status, body := http.StatusOK, rt.respond(req.URL.Path)
+	if req.Method != http.MethodGet {
+		status, body = http.StatusInternalServerError, `{"error":{"message":"mutating request in dry run","code":500}}`
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func (rt *recordingTransport) respond(path string) string {
+	ship := fmt.Sprintf(`{"symbol":%[1]q,"nav":{"systemSymbol":"X1-DF55","waypointSymbol":%[1]q,"status":"IN_ORBIT","flightMode":"CRUISE"},
+		"engine":{"speed":30},"fuel":{"current":80,"capacity":100},
+		"cargo":{"capacity":30,"units":10,"inventory":[{"symbol":%[1]q,"units":10}]}}`, symbol)
+
+	switch {
+	case path == "/v2/my/agent":
+		return `{"data":{"symbol":"GOGARIN","credits":1000}}`
+	case path == "/v2/my/agent/events":
+		return `{"data":[]}`
+	case path == "/v2/my/contracts":
+		return fmt.Sprintf(`{"data":[{"id":%[1]q,"terms":{"payment":{"onAccepted":500,"onFulfilled":2000},
+			"deliver":[{"tradeSymbol":%[1]q,"destinationSymbol":%[1]q,"unitsRequired":10}]}}]}`, symbol)
+	case path == "/v2/my/ships":
+		return `{"data":[` + ship + `]}`
+	case strings.HasSuffix(path, "/cooldown"):
+		return fmt.Sprintf(`{"data":{"shipSymbol":%q}}`, symbol)
+	case strings.HasPrefix(path, "/v2/my/ships/"):
+		return `{"data":` + ship + `}`
+	case strings.HasSuffix(path, "/market"):
+		return fmt.Sprintf(`{"data":{"symbol":%[1]q,"tradeGoods":[{"symbol":%[1]q,"sellPrice":40},{"symbol":"FUEL","purchasePrice":2}]}}`, symbol)
+	case path == "/v2/systems":
+		return `{"data":[]}`
+	case strings.HasSuffix(path, "/waypoints"):
+		return `{"data":[]}`
+	case strings.Contains(path, "/waypoints/"):
+		return fmt.Sprintf(`{"data":{"symbol":%q,"systemSymbol":"X1-DF55","x":3,"y":4,"traits":[{"symbol":"UNCHARTED"},{"symbol":"MARKETPLACE"}]}}`, symbol)
+	default:
+		return `{"data":{}}`
+	}
+}
+
+// argument returns a plausible value of type t for a simulated call.
+func argument(t reflect.Type) reflect.Value {
+	switch t.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(symbol).Convert(t)
+	case reflect.Int:
+		return reflect.ValueOf(1).Convert(t)
+	}
+
+	if t == reflect.TypeOf(m.TradeGood{}) {
+		return reflect.ValueOf(m.TradeGood{Symbol: symbol})
+	}
+
+	return reflect.Zero(t)
+}
+
+// TestDryRunMakesNoMutatingRequests calls every DryRunClient method, including any added later, and fails if
+// one sends anything but a GET to the API.
+func TestDryRunMakesNoMutatingRequests(t *testing.T) {
+	transport := &recordingTransport{}
+	inner := NewClient("token", WithRateLimit(1000))
+	inner.r.SetTransport(transport)
+
+	d := NewDryRunClient(inner, store.NewMarketStore(), log.New(io.Discard), 1000)
+
+	v := reflect.ValueOf(d)
+	for i := 0; i < v.NumMethod(); i++ {
+		method, name := v.Method(i), v.Type().Method(i).Name
+		// Pause sends nothing itself, but would hold every request after it.
+		if name == "Pause" {
+			continue
+		}
+
+		var args []reflect.Value
+		for j := 0; j < method.Type().NumIn(); j++ {
+			in := method.Type().In(j)
+			if method.Type().IsVariadic() && j == method.Type().NumIn()-1 {
+				break
+			}
+			args = append(args, argument(in))
+		}
+
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("%s panicked: %v", name, r)
+				}
+			}()
+			method.Call(args)
+		}()
+	}
+
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+
+	if len(transport.requests) == 0 {
+		t.Fatal("no requests reached the transport; the recorder is not wired in")
+	}
+	for _, request := range transport.requests {
+		if !strings.HasPrefix(request, http.MethodGet+" ") {
+			t.Errorf("dry run sent %s", request)
+		}
+	}
+}
+
+func TestDryRunSimulatesAShipsWork(t *testing.T) {
+	inner := NewClient("token", WithRateLimit(1000))
+	inner.r.SetTransport(&recordingTransport{})
+	d := NewDryRunClient(inner, store.NewMarketStore(), log.New(io.Discard), 1000)
+
+	if _, err := d.ExtractResources(symbol); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.ExtractResources(symbol); err == nil {
+		t.Error("second extraction ignored the simulated cooldown")
+	}
+
+	if _, err := d.DockShip(symbol); err != nil {
+		t.Fatal(err)
+	}
+	if nav, err := d.GetShipNav(symbol); err != nil || nav.Status != "DOCKED" {
+		t.Errorf("simulated nav = %+v, %v, want DOCKED", nav, err)
+	}
+	sale, err := d.SellCargo(symbol, symbol, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sale.Transaction.TotalPrice != 400 || sale.Agent.Credits != 1400 {
+		t.Errorf("sold for %d, leaving %d credits; want 400 and 1400", sale.Transaction.TotalPrice, sale.Agent.Credits)
+	}
+
+	accepted, err := d.AcceptContract(symbol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accepted.Agent.Credits != 1900 || !accepted.Contract.Accepted {
+		t.Errorf("accepting left %d credits, contract accepted %t", accepted.Agent.Credits, accepted.Contract.Accepted)
+	}
+	if _, err := d.AcceptContract(symbol); err == nil {
+		t.Error("accepted the contract twice")
+	}
+
+	contracts, err := d.GetMyContracts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(*contracts)[0].Accepted {
+		t.Error("simulated acceptance not reflected in the contract list")
+	}
+}
+
+func TestDryRunRefuelsAndCharts(t *testing.T) {
+	inner := NewClient("token", WithRateLimit(1000))
+	inner.r.SetTransport(&recordingTransport{})
+	d := NewDryRunClient(inner, store.NewMarketStore(), log.New(io.Discard), 1000)
+
+	if _, err := d.RefuelShip(symbol, 0); err == nil {
+		t.Error("refueled a ship in orbit")
+	}
+
+	if _, err := d.DockShip(symbol); err != nil {
+		t.Fatal(err)
+	}
+	refuel, err := d.RefuelShip(symbol, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refuel.Fuel.Current != 85 || refuel.Transaction.Units != 5 || refuel.Transaction.TotalPrice != 10 || refuel.Agent.Credits != 990 {
+		t.Errorf("refuel = %+v, want 5 units for 10 credits", refuel)
+	}
+	refuel, err = d.RefuelShip(symbol, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refuel.Fuel.Current != 100 || refuel.Transaction.Units != 15 || refuel.Transaction.TotalPrice != 30 || refuel.Agent.Credits != 960 {
+		t.Errorf("refuel = %+v, want 15 units for 30 credits, no more than fills the tank", refuel)
+	}
+
+	chart, err := d.CreateChart(symbol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chart.Chart.SubmittedBy != "GOGARIN" || chart.Waypoint.HasTrait("UNCHARTED") || !chart.Waypoint.HasTrait("MARKETPLACE") {
+		t.Errorf("chart = %+v, want the waypoint charted by the agent", chart)
+	}
+}
+
+func TestDryRunPurchasesCargo(t *testing.T) {
+	inner := NewClient("token", WithRateLimit(1000))
+	inner.r.SetTransport(&recordingTransport{})
+	d := NewDryRunClient(inner, store.NewMarketStore(), log.New(io.Discard), 1000)
+
+	if _, err := d.PurchaseCargo(symbol, "FUEL", 5); err == nil {
+		t.Error("purchased from a ship in orbit")
+	}
+	if _, err := d.DockShip(symbol); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.PurchaseCargo(symbol, symbol, 5); !errors.Is(err, ErrNotTraded) {
+		t.Errorf("purchasing a good the market only buys: err = %v, want ErrNotTraded", err)
+	}
+	if _, err := d.PurchaseCargo(symbol, "FUEL", 21); err == nil {
+		t.Error("purchased more than the hold has room for")
+	}
+
+	res, err := d.PurchaseCargo(symbol, "FUEL", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Agent.Credits != 990 || res.Cargo.UnitsOf("FUEL") != 5 || res.Transaction.TotalPrice != 10 {
+		t.Errorf("purchase = %+v, want 5 FUEL for 10 credits", res)
+	}
+
+	cargo, err := d.JettisonCargo(symbol, m.TradeGood{Symbol: "FUEL"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cargo.UnitsOf("FUEL") != 0 {
+		t.Errorf("cargo after jettisoning = %+v, want no FUEL", cargo)
+	}
+}
+
+func TestDryRunDeliversAndFulfillsContracts(t *testing.T) {
+	inner := NewClient("token", WithRateLimit(1000))
+	inner.r.SetTransport(&recordingTransport{})
+	d := NewDryRunClient(inner, store.NewMarketStore(), log.New(io.Discard), 1000)
+
+	if _, err := d.DeliverContract(symbol, symbol, symbol, 4); err == nil {
+		t.Error("delivered to a contract that was not accepted")
+	}
+	if _, err := d.AcceptContract(symbol); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.DeliverContract(symbol, symbol, symbol, 4); err == nil {
+		t.Error("delivered from a ship in orbit")
+	}
+	if _, err := d.DockShip(symbol); err != nil {
+		t.Fatal(err)
+	}
+
+	delivered, err := d.DeliverContract(symbol, symbol, symbol, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivered.Contract.Terms.Deliver[0].UnitsFulfilled != 4 || delivered.Cargo.UnitsOf(symbol) != 6 {
+		t.Errorf("after delivering 4: %+v, cargo %+v", delivered.Contract.Terms.Deliver, delivered.Cargo)
+	}
+	if _, err := d.FulfillContract(symbol); err == nil {
+		t.Error("fulfilled a contract with deliveries outstanding")
+	}
+	if _, err := d.DeliverContract(symbol, symbol, symbol, 7); err == nil {
+		t.Error("delivered more than the contract requires")
+	}
+	if _, err := d.DeliverContract(symbol, symbol, symbol, 6); err != nil {
+		t.Fatal(err)
+	}
+
+	fulfilled, err := d.FulfillContract(symbol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fulfilled.Contract.Fulfilled || fulfilled.Agent.Credits != 3500 {
+		t.Errorf("fulfilled = %t with %d credits, want true with 3500", fulfilled.Contract.Fulfilled, fulfilled.Agent.Credits)
+	}
+	if _, err := d.FulfillContract(symbol); err == nil {
+		t.Error("fulfilled the contract twice")
+	}
+
+	contracts, err := d.GetMyContracts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contract := (*contracts)[0]; !contract.Fulfilled || contract.Terms.Deliver[0].UnitsFulfilled != 10 {
+		t.Errorf("contract list = %+v, want the simulated deliveries and fulfillment", contract)
+	}
+}
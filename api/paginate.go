@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// pageLimit is the number of items requested per page, the maximum the API allows.
+const pageLimit = 20
+
+// Meta describes a page of a list endpoint.
+type Meta struct {
+	Total int `json:"total"`
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+}
+
+// Pages returns the number of pages needed to list every item.
+func (m Meta) Pages() int {
+	if m.Limit <= 0 {
+		return 1
+	}
+
+	return (m.Total + m.Limit - 1) / m.Limit
+}
+
+// WithConcurrentPages fetches the pages of list endpoints with up to workers concurrent requests,
+// after reading the total from the first page. Requests still share the Client's rate limit.
+func WithConcurrentPages(workers int) Option {
+	return func(c *Client) {
+		c.pageWorkers = workers
+	}
+}
+
+// page is a single page of a list endpoint.
+type page[T any] struct {
+	Data []T  `json:"data"`
+	Meta Meta `json:"meta"`
+}
+
+// fetchPage gets a single page of a list endpoint, giving up when ctx is done. Concurrent calls for the same
+// page share a single request.
+func fetchPage[T any](ctx context.Context, c *Client, url string, number int) ([]T, Meta, error) {
+	key := url + "?page=" + strconv.Itoa(number) + "&limit=" + strconv.Itoa(pageLimit)
+
+	body, err := c.share(ctx, key, func() ([]byte, error) {
+		c.wait()
+
+		res, err := c.r.R().
+			SetQueryParam("page", strconv.Itoa(number)).
+			SetQueryParam("limit", strconv.Itoa(pageLimit)).
+			SetError(&ErrorResponse{}).
+			Get(url)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.IsError() {
+			return nil, newAPIError(res)
+		}
+
+		return res.Body(), nil
+	})
+	if err != nil {
+		return nil, Meta{}, err
+	}
+
+	var result page[T]
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, Meta{}, err
+	}
+
+	return result.Data, result.Meta, nil
+}
+
+// listAll gets every page of a list endpoint, returning the items in page order.
+func listAll[T any](c *Client, url string) ([]T, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	items, meta, err := fetchPage[T](ctx, c, url, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := meta.Pages()
+	if pages <= 1 {
+		return items, nil
+	}
+
+	if c.pageWorkers <= 1 {
+		for page := 2; page <= pages; page++ {
+			pageItems, _, err := fetchPage[T](ctx, c, url, page)
+			if err != nil {
+				return nil, fmt.Errorf("page %d: %w", page, err)
+			}
+			items = append(items, pageItems...)
+		}
+
+		return items, nil
+	}
+
+	results := make([][]T, pages+1)
+	results[1] = items
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	jobs := make(chan int)
+	for i := 0; i < c.pageWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for page := range jobs {
+				pageItems, _, err := fetchPage[T](ctx, c, url, page)
+				if err != nil {
+					once.Do(func() {
+						firstErr = fmt.Errorf("page %d: %w", page, err)
+						cancel()
+					})
+					continue
+				}
+				results[page] = pageItems
+			}
+		}()
+	}
+
+	for page := 2; page <= pages; page++ {
+		select {
+		case jobs <- page:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	all := make([]T, 0, meta.Total)
+	for _, pageItems := range results[1:] {
+		all = append(all, pageItems...)
+	}
+
+	return all, nil
+}
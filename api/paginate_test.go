@@ -0,0 +1,141 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+// pagedSystems serves total systems named S000, S001, ... from /systems, one page per request,
+// after waiting latency. A request for failPage answers 500.
+type pagedSystems struct {
+	total    int
+	latency  time.Duration
+	failPage int
+	requests int32
+}
+
+func (p *pagedSystems) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&p.requests, 1)
+	time.Sleep(p.latency)
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if page == p.failPage {
+		respond(http.StatusInternalServerError, `{"error":{"message":"boom","code":500}}`)(w, r)
+		return
+	}
+
+	systems := []m.System{}
+	for i := (page - 1) * limit; i < page*limit && i < p.total; i++ {
+		systems = append(systems, m.System{Symbol: fmt.Sprintf("S%03d", i)})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": systems,
+		"meta": Meta{Total: p.total, Page: page, Limit: limit},
+	})
+}
+
+func newPagedClient(t testing.TB, p *pagedSystems, workers int) *Client {
+	c := newTestClient(t, p)
+	WithConcurrentPages(workers)(c)
+
+	return c
+}
+
+func TestMetaPages(t *testing.T) {
+	tests := []struct {
+		meta Meta
+		want int
+	}{
+		{Meta{Total: 0, Limit: 20}, 0},
+		{Meta{Total: 20, Limit: 20}, 1},
+		{Meta{Total: 21, Limit: 20}, 2},
+		{Meta{Total: 95, Limit: 20}, 5},
+		{Meta{Total: 5}, 1},
+	}
+
+	for _, tt := range tests {
+		if got := tt.meta.Pages(); got != tt.want {
+			t.Errorf("%+v.Pages() = %d, want %d", tt.meta, got, tt.want)
+		}
+	}
+}
+
+func TestListAllReturnsItemsInPageOrder(t *testing.T) {
+	for _, workers := range []int{1, 4} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			p := &pagedSystems{total: 95}
+			systems, err := newPagedClient(t, p, workers).ListSystems()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(*systems) != 95 {
+				t.Fatalf("listed %d systems, want 95", len(*systems))
+			}
+			for i, system := range *systems {
+				if want := fmt.Sprintf("S%03d", i); system.Symbol != want {
+					t.Fatalf("system %d = %s, want %s", i, system.Symbol, want)
+				}
+			}
+			if p.requests != 5 {
+				t.Errorf("made %d requests, want 5", p.requests)
+			}
+		})
+	}
+}
+
+func TestListAllFetchesPagesConcurrently(t *testing.T) {
+	latency := 50 * time.Millisecond
+
+	start := time.Now()
+	if _, err := newPagedClient(t, &pagedSystems{total: 200, latency: latency}, 9).ListSystems(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first page is fetched alone, then the other nine together.
+	if elapsed := time.Since(start); elapsed > 5*latency {
+		t.Errorf("listing 10 pages with 9 workers took %s, want about %s", elapsed, 2*latency)
+	}
+}
+
+func TestListAllReportsTheFailingPage(t *testing.T) {
+	for _, workers := range []int{1, 4} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			p := &pagedSystems{total: 200, failPage: 3, latency: 10 * time.Millisecond}
+			_, err := newPagedClient(t, p, workers).ListSystems()
+			if err == nil || !strings.HasPrefix(err.Error(), "page 3:") {
+				t.Fatalf("err = %v, want a page 3 error", err)
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+				t.Errorf("err = %v, want the wrapped API error", err)
+			}
+		})
+	}
+}
+
+func BenchmarkListSystems(b *testing.B) {
+	for _, workers := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("%d workers", workers), func(b *testing.B) {
+			c := newPagedClient(b, &pagedSystems{total: 200, latency: 5 * time.Millisecond}, workers)
+			for i := 0; i < b.N; i++ {
+				if _, err := c.ListSystems(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
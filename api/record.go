@@ -0,0 +1,203 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	resty "github.com/go-resty/resty/v2"
+)
+
+/*
+📼 Recording
+*/
+
+// Recording is a single recorded response.
+type Recording struct {
+	Method     string          `json:"method"`
+	Path       string          `json:"path"`
+	StatusCode int             `json:"statusCode"`
+	Body       json.RawMessage `json:"body,omitempty"`
+}
+
+// redacted replaces the value of every "token" field in a recorded body.
+const redacted = "REDACTED"
+
+// WithRecorder writes each response the Client receives to its own numbered JSON file in dir, as a Recording.
+// Tokens are stripped from response bodies; request headers are never recorded.
+func WithRecorder(dir string) Option {
+	return func(c *Client) {
+		rec := &recorder{dir: dir}
+		c.r.OnAfterResponse(func(_ *resty.Client, res *resty.Response) error {
+			if err := rec.record(res); err != nil {
+				// A failed recording must not fail the request it records.
+				fmt.Fprintf(os.Stderr, "recording %s %s: %s\n", res.Request.Method, res.Request.URL, err)
+			}
+			return nil
+		})
+	}
+}
+
+// recorder numbers recordings in the order their responses arrive.
+type recorder struct {
+	mu   sync.Mutex
+	dir  string
+	next int
+}
+
+func (r *recorder) record(res *resty.Response) error {
+	body, err := sanitize(res.Body())
+	if err != nil {
+		return err
+	}
+
+	var data bytes.Buffer
+	enc := json.NewEncoder(&data)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(Recording{
+		Method:     res.Request.Method,
+		Path:       res.Request.RawRequest.URL.RequestURI(),
+		StatusCode: res.StatusCode(),
+		Body:       body,
+	}); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.next++
+	n := r.next
+	r.mu.Unlock()
+
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return err
+	}
+
+	// Each recording gets its own file, so concurrent responses never interleave.
+	return os.WriteFile(filepath.Join(r.dir, fmt.Sprintf("%06d.json", n)), data.Bytes(), 0o644)
+}
+
+// sanitize replaces tokens in a JSON body. Bodies that are not JSON are recorded as JSON strings.
+func sanitize(body []byte) (json.RawMessage, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return json.Marshal(string(body))
+	}
+
+	return json.Marshal(redact(v))
+}
+
+// redact replaces the value of every "token" field, at any depth.
+func redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if strings.EqualFold(key, "token") {
+				v[key] = redacted
+				continue
+			}
+			v[key] = redact(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redact(value)
+		}
+	}
+
+	return v
+}
+
+/*
+⏯️ Playback
+*/
+
+// Playback is an http.RoundTripper serving recorded responses by method and path. Repeated requests for
+// the same method and path are served the recordings in order, repeating the last one once they run out.
+// A request with no recording fails.
+type Playback struct {
+	mu         sync.Mutex
+	recordings map[string][]Recording
+	served     map[string]int
+}
+
+// NewPlayback loads the recordings written by WithRecorder to dir.
+func NewPlayback(dir string) (*Playback, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	p := &Playback{recordings: make(map[string][]Recording), served: make(map[string]int)}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var recording Recording
+		if err := json.Unmarshal(data, &recording); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		key := playbackKey(recording.Method, recording.Path)
+		p.recordings[key] = append(p.recordings[key], recording)
+	}
+
+	return p, nil
+}
+
+// WithTransport sends the Client's requests through transport, such as a Playback.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.r.SetTransport(transport)
+	}
+}
+
+// RoundTrip serves the next recording for the request's method and path.
+func (p *Playback) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := playbackKey(req.Method, req.URL.RequestURI())
+
+	p.mu.Lock()
+	recordings, ok := p.recordings[key]
+	if !ok {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("playback: no recording for %s", key)
+	}
+
+	i := p.served[key]
+	if i >= len(recordings) {
+		i = len(recordings) - 1
+	}
+	p.served[key]++
+	p.mu.Unlock()
+
+	recording := recordings[i]
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recording.StatusCode, http.StatusText(recording.StatusCode)),
+		StatusCode:    recording.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(recording.Body)),
+		ContentLength: int64(len(recording.Body)),
+		Request:       req,
+	}, nil
+}
+
+func playbackKey(method string, path string) string {
+	return method + " " + path
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/GeoffreyDick/gogarin/mockserver"
+)
+
+// session makes the calls a short mission would, returning what the client saw.
+func session(t *testing.T, c *Client) []interface{} {
+	t.Helper()
+
+	agent, err := c.GetMyAgent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ship, err := c.GetShip("MOCK-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waypoints, err := c.ListWaypoints("X1-MK1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	market, err := c.GetMarketAt("X1-MK1-A1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, sellErr := c.SellCargo("MOCK-2", "IRON_ORE", 1)
+
+	return []interface{}{agent, ship, waypoints, market, fmt.Sprint(sellErr)}
+}
+
+func TestRecordingReplaysAMockServerSession(t *testing.T) {
+	f, err := mockserver.DefaultFixture()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := mockserver.New(f)
+	t.Cleanup(s.Close)
+
+	dir := t.TempDir()
+	live := NewClient("secret-token", WithBaseURL(s.URL), WithRateLimit(1000), WithRecorder(dir))
+	recorded := session(t, live)
+
+	// Concurrent responses each get a whole file of their own.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := live.GetShipCooldown("MOCK-2"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) < 15 {
+		t.Fatalf("%d recordings, want one per response", len(files))
+	}
+	for i, file := range files {
+		if want := fmt.Sprintf("%06d.json", i+1); filepath.Base(file) != want {
+			t.Errorf("recording %d is %s, want %s", i+1, filepath.Base(file), want)
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var recording Recording
+		if err := json.Unmarshal(data, &recording); err != nil {
+			t.Errorf("%s: %s", filepath.Base(file), err)
+		}
+		if strings.Contains(string(data), "secret-token") {
+			t.Errorf("%s contains the token", filepath.Base(file))
+		}
+	}
+
+	playback, err := NewPlayback(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := NewClient("", WithBaseURL("http://playback.invalid"), WithRateLimit(1000), WithTransport(playback))
+	if replayed := session(t, replay); !reflect.DeepEqual(replayed, recorded) {
+		t.Errorf("replayed %+v, want the recorded %+v", replayed, recorded)
+	}
+
+	if _, err := replay.GetShip("MOCK-9"); err == nil || !strings.Contains(err.Error(), "no recording for GET /my/ships/MOCK-9") {
+		t.Errorf("unrecorded request: err = %v, want a missing recording", err)
+	}
+}
+
+func TestRecordingRedactsTokens(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestClient(t, respond(http.StatusCreated, `{"data":{"token":"secret-token","agent":{"symbol":"GOGARIN"},
+		"ship":{"symbol":"GOGARIN-1","crew":{"token":"secret-token"}}}}`))
+	WithRecorder(dir)(c)
+
+	res, err := c.Register("GOGARIN", "COSMIC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Token != "secret-token" {
+		t.Errorf("token = %q, want the caller to see the real token", res.Token)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "000001.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret-token") || strings.Count(string(data), redacted) != 2 {
+		t.Errorf("recording:\n%s\nwant every token redacted", data)
+	}
+
+	playback, err := NewPlayback(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := NewClient("", WithBaseURL("http://playback.invalid"), WithTransport(playback))
+	replayed, err := replay.Register("GOGARIN", "COSMIC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Token != redacted || replayed.Agent.Symbol != "GOGARIN" || replayed.Ship.Symbol != "GOGARIN-1" {
+		t.Errorf("replayed %+v", replayed)
+	}
+}
@@ -0,0 +1,350 @@
+package api
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/clock"
+)
+
+/*
+🐌 Throttle
+*/
+
+// Priority is the lane a request waits in for a throttle slot.
+type Priority int
+
+const (
+	// Normal is the lane of mission traffic.
+	Normal Priority = iota
+	// High is the lane of interactive and administrative calls. It is served first, within its share.
+	High
+)
+
+// DefaultHighShare is the fraction of recent slots the High lane may take while Normal requests wait.
+const DefaultHighShare = 0.5
+
+// shareWindow is the number of most recent slots the High lane's share is measured over.
+const shareWindow = 10
+
+// Subsystems a request can be counted against, so no one of them can starve the others of the shared budget.
+const (
+	SubsystemMissions    = "missions"
+	SubsystemReconcile   = "reconcile"
+	SubsystemMarketPoll  = "market-poll"
+	SubsystemInteractive = "interactive"
+)
+
+// Subsystems are the subsystems requests are counted against.
+var Subsystems = []string{SubsystemMissions, SubsystemReconcile, SubsystemMarketPoll, SubsystemInteractive}
+
+// budgetWindow is the rolling window each subsystem's usage is measured over.
+const budgetWindow = 1 * time.Minute
+
+// Throttle spaces requests at most MaxRequestsPerSecond apart. Waiting requests are granted slots from two
+// lanes: High before Normal, except that High takes at most HighShare of recent slots while Normal requests wait.
+// Within and across lanes, requests of a subsystem over its cap wait while requests of other subsystems proceed.
+type Throttle struct {
+	MaxRequestsPerSecond int
+	// HighShare is the fraction of slots the High lane may take while Normal requests wait.
+	HighShare       float64
+	LastRequestTime time.Time
+	Mutex           sync.Mutex
+
+	// lanes holds the waiters of each lane, oldest first.
+	lanes [2][]waiter
+	// recent records whether each of the last shareWindow slots went to the High lane.
+	recent     [shareWindow]bool
+	next       int
+	dispatches bool
+
+	// caps are the fractions of the budget over budgetWindow each capped subsystem may use.
+	caps map[string]float64
+	// granted are the slots granted within budgetWindow, oldest first.
+	granted []grant
+	// totals are the slots granted to each subsystem since the Throttle was created.
+	totals map[string]uint64
+	// clock spaces the slots.
+	clock clock.Clock
+	// paused holds every request but interactive ones until Resume.
+	paused bool
+}
+
+// waiter is a request waiting for a slot.
+type waiter struct {
+	ready     chan struct{}
+	subsystem string
+}
+
+// grant is a slot granted to a subsystem.
+type grant struct {
+	subsystem string
+	at        time.Time
+}
+
+// SubsystemUsage is how much of the request budget a subsystem has used.
+type SubsystemUsage struct {
+	Subsystem string `json:"subsystem"`
+	// Requests is the number of requests sent within the last budget window.
+	Requests int `json:"requests"`
+	// Share is Requests as a fraction of the budget over the window.
+	Share float64 `json:"share"`
+	// Cap is the share the subsystem is limited to, or zero if it is not capped.
+	Cap float64 `json:"cap,omitempty"`
+	// Total is the number of requests sent since the Throttle was created.
+	Total uint64 `json:"total"`
+}
+
+func NewThrottle(maxRequestsPerSecond int) *Throttle {
+	return &Throttle{
+		MaxRequestsPerSecond: maxRequestsPerSecond,
+		HighShare:            DefaultHighShare,
+		LastRequestTime:      time.Now(),
+		caps:                 make(map[string]float64),
+		totals:               make(map[string]uint64),
+		clock:                clock.Real,
+	}
+}
+
+// SetClock spaces requests by c instead of the wall clock.
+func (t *Throttle) SetClock(c clock.Clock) {
+	t.Mutex.Lock()
+	defer t.Mutex.Unlock()
+
+	t.clock = c
+	t.LastRequestTime = c.Now()
+}
+
+// SetCap limits a subsystem to a share of the request budget, measured over a rolling window. Once over it,
+// the subsystem's requests wait while any other subsystem's are waiting. A share of zero removes the cap.
+func (t *Throttle) SetCap(subsystem string, share float64) {
+	t.Mutex.Lock()
+	defer t.Mutex.Unlock()
+
+	if share <= 0 {
+		delete(t.caps, subsystem)
+		return
+	}
+	t.caps[subsystem] = share
+}
+
+// Pause holds every request except those of SubsystemInteractive until Resume. Requests already granted a
+// slot are sent.
+func (t *Throttle) Pause() {
+	t.Mutex.Lock()
+	defer t.Mutex.Unlock()
+
+	t.paused = true
+}
+
+// Resume grants slots to the requests held since Pause.
+func (t *Throttle) Resume() {
+	t.Mutex.Lock()
+	defer t.Mutex.Unlock()
+
+	t.paused = false
+	if !t.dispatches && len(t.lanes[High])+len(t.lanes[Normal]) > 0 {
+		t.dispatches = true
+		go t.dispatch()
+	}
+}
+
+// Usage returns the usage of every subsystem that has sent a request or is capped, sorted by subsystem.
+func (t *Throttle) Usage() []SubsystemUsage {
+	t.Mutex.Lock()
+	defer t.Mutex.Unlock()
+
+	t.expire(t.clock.Now())
+
+	usage := make(map[string]*SubsystemUsage)
+	get := func(subsystem string) *SubsystemUsage {
+		u, ok := usage[subsystem]
+		if !ok {
+			u = &SubsystemUsage{Subsystem: subsystem, Cap: t.caps[subsystem], Total: t.totals[subsystem]}
+			usage[subsystem] = u
+		}
+		return u
+	}
+
+	for subsystem := range t.totals {
+		get(subsystem)
+	}
+	for subsystem := range t.caps {
+		get(subsystem)
+	}
+	for _, g := range t.granted {
+		get(g.subsystem).Requests++
+	}
+
+	result := make([]SubsystemUsage, 0, len(usage))
+	for _, u := range usage {
+		u.Share = float64(u.Requests) / t.budget()
+		result = append(result, *u)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Subsystem < result[j].Subsystem })
+
+	return result
+}
+
+// budget is the number of requests the Throttle can send over budgetWindow.
+func (t *Throttle) budget() float64 {
+	return float64(t.MaxRequestsPerSecond) * budgetWindow.Seconds()
+}
+
+// Wait blocks until a Normal request may be sent.
+func (t *Throttle) Wait() {
+	t.WaitPriority(Normal)
+}
+
+// WaitPriority blocks until a request in the given lane may be sent. It counts against SubsystemMissions.
+func (t *Throttle) WaitPriority(priority Priority) {
+	t.WaitFor(priority, SubsystemMissions)
+}
+
+// WaitFor blocks until a request in the given lane, counted against subsystem, may be sent.
+func (t *Throttle) WaitFor(priority Priority, subsystem string) {
+	if priority != High {
+		priority = Normal
+	}
+	if subsystem == "" {
+		subsystem = SubsystemMissions
+	}
+
+	ready := make(chan struct{})
+
+	t.Mutex.Lock()
+	t.lanes[priority] = append(t.lanes[priority], waiter{ready: ready, subsystem: subsystem})
+	if !t.dispatches {
+		t.dispatches = true
+		go t.dispatch()
+	}
+	t.Mutex.Unlock()
+
+	<-ready
+}
+
+// dispatch grants a slot to one waiter per interval until no waiters remain, or none may be served while paused.
+// The lane is chosen once the slot arrives, so High requests queued meanwhile are not passed over.
+func (t *Throttle) dispatch() {
+	interval := time.Duration(float64(time.Second) / float64(t.MaxRequestsPerSecond))
+
+	for {
+		t.Mutex.Lock()
+		wait := interval - t.clock.Now().Sub(t.LastRequestTime)
+		t.Mutex.Unlock()
+
+		if wait > 0 {
+			t.clock.Sleep(wait)
+		}
+
+		t.Mutex.Lock()
+		now := t.clock.Now()
+		lane, i, ok := t.nextWaiter(now)
+		if !ok {
+			t.dispatches = false
+			t.Mutex.Unlock()
+			return
+		}
+
+		w := t.lanes[lane][i]
+		t.lanes[lane] = append(t.lanes[lane][:i], t.lanes[lane][i+1:]...)
+		t.recent[t.next] = lane == High
+		t.next = (t.next + 1) % shareWindow
+		t.granted = append(t.granted, grant{subsystem: w.subsystem, at: now})
+		t.totals[w.subsystem]++
+		t.LastRequestTime = now
+		t.Mutex.Unlock()
+
+		close(w.ready)
+	}
+}
+
+// nextWaiter chooses the waiter to grant the next slot to, as its lane and index, reporting false when no
+// request is waiting. The oldest waiter of a subsystem within its cap goes first; waiters over their caps are
+// served only when no other request is waiting. While paused, only interactive waiters are served.
+func (t *Throttle) nextWaiter(now time.Time) (Priority, int, bool) {
+	t.expire(now)
+
+	if t.paused {
+		for _, lane := range []Priority{High, Normal} {
+			for i, w := range t.lanes[lane] {
+				if w.subsystem == SubsystemInteractive {
+					return lane, i, true
+				}
+			}
+		}
+
+		return Normal, 0, false
+	}
+
+	within := [2]int{-1, -1}
+	for lane := range t.lanes {
+		for i, w := range t.lanes[lane] {
+			if !t.overCap(w.subsystem) {
+				within[lane] = i
+				break
+			}
+		}
+	}
+
+	if lane, ok := t.nextLane(within[High] >= 0, within[Normal] >= 0); ok {
+		return lane, within[lane], true
+	}
+
+	lane, ok := t.nextLane(len(t.lanes[High]) > 0, len(t.lanes[Normal]) > 0)
+
+	return lane, 0, ok
+}
+
+// overCap checks if a subsystem has used its capped share of the budget within the window.
+func (t *Throttle) overCap(subsystem string) bool {
+	limit, ok := t.caps[subsystem]
+	if !ok {
+		return false
+	}
+
+	var used int
+	for _, g := range t.granted {
+		if g.subsystem == subsystem {
+			used++
+		}
+	}
+
+	return float64(used) >= limit*t.budget()
+}
+
+// expire forgets slots granted before the window. It must be called with Mutex held.
+func (t *Throttle) expire(now time.Time) {
+	var i int
+	for i < len(t.granted) && now.Sub(t.granted[i].at) > budgetWindow {
+		i++
+	}
+	t.granted = t.granted[i:]
+}
+
+// nextLane chooses the lane to grant the next slot to, given which lanes have a request to serve, reporting
+// false when neither has.
+func (t *Throttle) nextLane(high bool, normal bool) (Priority, bool) {
+	switch {
+	case high && normal:
+		var taken int
+		for _, wasHigh := range t.recent {
+			if wasHigh {
+				taken++
+			}
+		}
+
+		if float64(taken) < t.HighShare*shareWindow {
+			return High, true
+		}
+
+		return Normal, true
+	case high:
+		return High, true
+	case normal:
+		return Normal, true
+	default:
+		return Normal, false
+	}
+}
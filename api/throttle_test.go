@@ -0,0 +1,240 @@
+package api
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// grants queues requests on a throttle and records the order their slots are granted in.
+type grants struct {
+	t     *Throttle
+	mu    sync.Mutex
+	order []Priority
+	wg    sync.WaitGroup
+}
+
+// queue starts n requests waiting in a lane, returning once all are queued.
+func (g *grants) queue(priority Priority, n int) {
+	g.t.Mutex.Lock()
+	queued := len(g.t.lanes[priority]) + n
+	g.t.Mutex.Unlock()
+
+	for i := 0; i < n; i++ {
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			g.t.WaitPriority(priority)
+
+			g.mu.Lock()
+			g.order = append(g.order, priority)
+			g.mu.Unlock()
+		}()
+	}
+
+	for {
+		g.t.Mutex.Lock()
+		waiting := len(g.t.lanes[priority])
+		g.t.Mutex.Unlock()
+		if waiting >= queued {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// countHigh returns how many of the granted slots went to the High lane.
+func countHigh(order []Priority) int {
+	var high int
+	for _, priority := range order {
+		if priority == High {
+			high++
+		}
+	}
+	return high
+}
+
+func TestHighPriorityIsNotStarvedByNormalTraffic(t *testing.T) {
+	g := &grants{t: NewThrottle(100)}
+
+	g.queue(Normal, 20)
+	g.queue(High, 1)
+	g.wg.Wait()
+
+	for i, priority := range g.order {
+		if priority != High {
+			continue
+		}
+		// The slot being waited on when the High request queued may already belong to a Normal one.
+		if i > 1 {
+			t.Errorf("High request served in slot %d behind %d Normal ones, want within 2 slots", i+1, i)
+		}
+		return
+	}
+	t.Fatal("High request never served")
+}
+
+func TestHighPriorityKeepsToItsShare(t *testing.T) {
+	g := &grants{t: NewThrottle(100)}
+	g.t.HighShare = 0.3
+
+	g.queue(Normal, 10)
+	g.queue(High, 10)
+	g.wg.Wait()
+
+	if high := countHigh(g.order[:shareWindow]); high > 3 {
+		t.Errorf("High lane took %d of the first %d slots while Normal requests waited, want at most 3", high, shareWindow)
+	}
+
+	// The share holds High back without starving it: High slots keep coming while Normal requests wait.
+	lastNormal := len(g.order) - 1
+	for g.order[lastNormal] != Normal {
+		lastNormal--
+	}
+	if high := countHigh(g.order[:lastNormal]); high <= 3 {
+		t.Errorf("High lane took %d slots before the Normal lane drained, want more than its first window's 3", high)
+	}
+}
+
+func TestThrottleSpacesRequests(t *testing.T) {
+	g := &grants{t: NewThrottle(50)}
+
+	start := time.Now()
+	g.queue(Normal, 3)
+	g.queue(High, 2)
+	g.wg.Wait()
+
+	// Five requests at 50 per second need at least four intervals of 20ms.
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("5 requests took %s, want at least 80ms", elapsed)
+	}
+}
+
+// fill records n slots granted to subsystem just now, as if it had sent them.
+func fill(t *Throttle, subsystem string, n int) {
+	t.Mutex.Lock()
+	defer t.Mutex.Unlock()
+
+	for i := 0; i < n; i++ {
+		t.granted = append(t.granted, grant{subsystem: subsystem, at: time.Now()})
+		t.totals[subsystem]++
+	}
+}
+
+// queueFor starts n requests of subsystem waiting on th, returning once all are queued.
+func queueFor(th *Throttle, subsystem string, n int, wg *sync.WaitGroup) {
+	th.Mutex.Lock()
+	queued := len(th.lanes[Normal]) + n
+	th.Mutex.Unlock()
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			th.WaitFor(Normal, subsystem)
+		}()
+	}
+
+	for {
+		th.Mutex.Lock()
+		waiting := len(th.lanes[Normal])
+		th.Mutex.Unlock()
+		if waiting >= queued {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSaturatedSubsystemDoesNotDelayOthers(t *testing.T) {
+	tests := []struct {
+		name    string
+		cap     float64
+		delayed bool
+	}{
+		{"capped", 0.2, false},
+		// Without a cap, the mission request queues behind the market polls like any other.
+		{"uncapped", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th := NewThrottle(100)
+			th.SetCap(SubsystemMarketPoll, tt.cap)
+			// The market poller has used a fifth of the 6000-request window.
+			fill(th, SubsystemMarketPoll, 1200)
+
+			var wg sync.WaitGroup
+			queueFor(th, SubsystemMarketPoll, 20, &wg)
+
+			// 20 queued market polls take 200ms at 100 per second.
+			start := time.Now()
+			th.WaitFor(Normal, SubsystemMissions)
+			latency := time.Since(start)
+			if !tt.delayed && latency > 50*time.Millisecond {
+				t.Errorf("mission request waited %s behind a saturated market poller, want at most 50ms", latency)
+			}
+			if tt.delayed && latency < 150*time.Millisecond {
+				t.Errorf("mission request waited %s, want it behind the market polls", latency)
+			}
+
+			// The over-cap subsystem is still served once nothing else is waiting.
+			wg.Wait()
+		})
+	}
+}
+
+func TestUsageReportsEachSubsystem(t *testing.T) {
+	th := NewThrottle(100)
+	th.SetCap(SubsystemMarketPoll, 0.2)
+	th.SetCap(SubsystemReconcile, 0.5)
+	th.SetCap(SubsystemReconcile, 0)
+	fill(th, SubsystemMarketPoll, 600)
+	th.WaitFor(High, SubsystemInteractive)
+
+	// Slots granted before the window no longer count towards the share, but do towards the total.
+	th.Mutex.Lock()
+	th.granted = append([]grant{{subsystem: SubsystemInteractive, at: time.Now().Add(-2 * budgetWindow)}}, th.granted...)
+	th.totals[SubsystemInteractive]++
+	th.Mutex.Unlock()
+
+	want := []SubsystemUsage{
+		{Subsystem: SubsystemInteractive, Requests: 1, Share: 1.0 / 6000, Total: 2},
+		{Subsystem: SubsystemMarketPoll, Requests: 600, Share: 0.1, Cap: 0.2, Total: 600},
+	}
+	if got := th.Usage(); !reflect.DeepEqual(got, want) {
+		t.Errorf("usage = %+v, want %+v", got, want)
+	}
+}
+
+func TestPausedThrottleServesOnlyInteractiveRequests(t *testing.T) {
+	th := NewThrottle(100)
+	th.Pause()
+
+	var wg sync.WaitGroup
+	queueFor(th, SubsystemMissions, 3, &wg)
+	sent := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(sent)
+	}()
+
+	start := time.Now()
+	th.WaitFor(High, SubsystemInteractive)
+	if latency := time.Since(start); latency > 50*time.Millisecond {
+		t.Errorf("interactive request waited %s while paused, want at most 50ms", latency)
+	}
+
+	select {
+	case <-sent:
+		t.Fatal("mission requests were sent while paused")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	th.Resume()
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("mission requests still held after Resume")
+	}
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TokenClaims are the claims SpaceTraders embeds in an agent token.
+type TokenClaims struct {
+	Identifier string `json:"identifier"`
+	Version    string `json:"version"`
+	ResetDate  string `json:"reset_date"`
+	IssuedAt   int64  `json:"iat"`
+	Subject    string `json:"sub"`
+}
+
+// ParseToken decodes the claims of an agent token. The signature is not verified;
+// the claims are only used to explain why the server rejected a token.
+func ParseToken(token string) (*TokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token is not a JWT")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("decoding token claims: %w", err)
+	}
+
+	var claims TokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("decoding token claims: %w", err)
+	}
+
+	return &claims, nil
+}
+
+// IsStale reports whether the token was issued for a reset other than the server's current one.
+func (t *TokenClaims) IsStale(serverResetDate string) bool {
+	return t.ResetDate != "" && serverResetDate != "" && t.ResetDate != serverResetDate
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+// testToken returns an unsigned JWT carrying claims.
+func testToken(claims string) string {
+	return "eyJhbGciOiJSUzI1NiIsInR5cCI6IkpXVCJ9." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".signature"
+}
+
+func TestParseToken(t *testing.T) {
+	claims, err := ParseToken(testToken(`{"identifier":"GOGARIN","version":"v2.1.1","reset_date":"2030-01-01","iat":1893499200,"sub":"agent-token"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.Identifier != "GOGARIN" || claims.ResetDate != "2030-01-01" || claims.IssuedAt != 1893499200 || claims.Subject != "agent-token" {
+		t.Errorf("claims = %+v", claims)
+	}
+}
+
+func TestParseTokenRejectsMalformedTokens(t *testing.T) {
+	for _, token := range []string{
+		"",
+		"not-a-jwt",
+		"header.!!!.signature",
+		"header." + base64.RawURLEncoding.EncodeToString([]byte("not json")) + ".signature",
+	} {
+		if _, err := ParseToken(token); err == nil {
+			t.Errorf("ParseToken(%q) accepted it", token)
+		}
+	}
+}
+
+func TestIsStale(t *testing.T) {
+	tests := []struct {
+		token, server string
+		want          bool
+	}{
+		{"2030-01-01", "2030-01-15", true},
+		{"2030-01-15", "2030-01-15", false},
+		{"", "2030-01-15", false},
+		{"2030-01-01", "", false},
+	}
+	for _, tt := range tests {
+		claims := TokenClaims{ResetDate: tt.token}
+		if got := claims.IsStale(tt.server); got != tt.want {
+			t.Errorf("token from %q, server reset %q: IsStale = %t, want %t", tt.token, tt.server, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+// waypointWorkers is the number of concurrent requests GetWaypoints fans out to. Requests still share the
+// Client's rate limit.
+const waypointWorkers = 4
+
+/*
+📍 Waypoints
+*/
+
+// waypointCache holds the latest details the Client has fetched of each waypoint, keyed by symbol. It is
+// shared by the Client's handles.
+type waypointCache struct {
+	mu        sync.RWMutex
+	waypoints map[string]m.Waypoint
+}
+
+func newWaypointCache() *waypointCache {
+	return &waypointCache{waypoints: make(map[string]m.Waypoint)}
+}
+
+func (wc *waypointCache) get(symbol string) (m.Waypoint, bool) {
+	wc.mu.RLock()
+	defer wc.mu.RUnlock()
+
+	waypoint, ok := wc.waypoints[symbol]
+
+	return waypoint, ok
+}
+
+func (wc *waypointCache) put(waypoints ...m.Waypoint) {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	for _, waypoint := range waypoints {
+		wc.waypoints[waypoint.Symbol] = waypoint
+	}
+}
+
+// GetWaypoints views the details of several waypoints of a system, keyed by symbol. Waypoints the Client has
+// already fetched, by any of its waypoint calls, are served from its cache; the rest are fetched with bounded
+// concurrency, each once however often it is listed. Waypoints that cannot be fetched are left out of the map,
+// and their errors are joined into the error returned alongside the waypoints that could.
+func (c *Client) GetWaypoints(systemSymbol string, symbols []string) (map[string]m.Waypoint, error) {
+	if err := checkArgs("systemSymbol", systemSymbol); err != nil {
+		return nil, err
+	}
+
+	waypoints := make(map[string]m.Waypoint, len(symbols))
+	var missing []string
+	seen := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		if seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+
+		if waypoint, ok := c.waypoints.get(symbol); ok {
+			waypoints[symbol] = waypoint
+			continue
+		}
+		missing = append(missing, symbol)
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+
+	jobs := make(chan string)
+	for i := 0; i < waypointWorkers && i < len(missing); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for symbol := range jobs {
+				waypoint, err := c.GetWaypoint(systemSymbol, symbol)
+
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("waypoint %s: %w", symbol, err))
+				} else {
+					waypoints[symbol] = *waypoint
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, symbol := range missing {
+		jobs <- symbol
+	}
+	close(jobs)
+	wg.Wait()
+
+	return waypoints, errors.Join(errs...)
+}
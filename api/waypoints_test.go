@@ -0,0 +1,68 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// waypointServer serves any waypoint of system X1-A but X1-A-Z9, counting the requests made for each.
+type waypointServer struct {
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func (s *waypointServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	symbol := path.Base(r.URL.Path)
+
+	s.mu.Lock()
+	s.requests[symbol]++
+	s.mu.Unlock()
+
+	if symbol == "X1-A-Z9" {
+		respond(http.StatusNotFound, `{"error":{"message":"Waypoint not found.","code":4001}}`)(w, r)
+		return
+	}
+	respond(http.StatusOK, fmt.Sprintf(`{"data":{"symbol":%q,"systemSymbol":"X1-A","type":"PLANET"}}`, symbol))(w, r)
+}
+
+func TestGetWaypointsFetchesOnlyUncachedSymbolsOnce(t *testing.T) {
+	s := &waypointServer{requests: make(map[string]int)}
+	c := newTestClient(t, s)
+
+	if _, err := c.GetWaypoint("X1-A", "X1-A-A1"); err != nil {
+		t.Fatal(err)
+	}
+
+	waypoints, err := c.GetWaypoints("X1-A", []string{"X1-A-A1", "X1-A-B2", "X1-A-C3", "X1-A-B2", "X1-A-Z9", "X1-A-A1", "X1-A-Z9"})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 4001 || !strings.Contains(err.Error(), "X1-A-Z9") {
+		t.Fatalf("err = %v, want X1-A-Z9's 4001", err)
+	}
+	if len(waypoints) != 3 {
+		t.Fatalf("%d waypoints, want X1-A-A1, X1-A-B2, and X1-A-C3", len(waypoints))
+	}
+	for _, symbol := range []string{"X1-A-A1", "X1-A-B2", "X1-A-C3"} {
+		if waypoints[symbol].Symbol != symbol {
+			t.Errorf("waypoints[%s] = %+v", symbol, waypoints[symbol])
+		}
+	}
+
+	// The planet was cached by the first call; every other symbol is fetched once however often it is listed.
+	want := map[string]int{"X1-A-A1": 1, "X1-A-B2": 1, "X1-A-C3": 1, "X1-A-Z9": 1}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for symbol, n := range want {
+		if s.requests[symbol] != n {
+			t.Errorf("%d requests for %s, want %d", s.requests[symbol], symbol, n)
+		}
+	}
+	if len(s.requests) != len(want) {
+		t.Errorf("requests for %v, want only %v", s.requests, want)
+	}
+}
@@ -0,0 +1,858 @@
+package bot
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/boterr"
+	"github.com/GeoffreyDick/gogarin/clock"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/health"
+	"github.com/GeoffreyDick/gogarin/ledger"
+	"github.com/GeoffreyDick/gogarin/lib"
+	"github.com/GeoffreyDick/gogarin/logging"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/status"
+	"github.com/GeoffreyDick/gogarin/store"
+	"github.com/charmbracelet/log"
+)
+
+/*
+🖥️ TERMINAL_BOT
+*/
+
+// TerminalBot represents a TerminalBot instance.
+type TerminalBot struct {
+	client api.ClientAPI
+	logger *log.Logger
+}
+
+// NewTerminalBot creates a new instance of TerminalBot.
+func NewTerminalBot(c api.ClientAPI) *TerminalBot {
+	return &TerminalBot{
+		client: c,
+		logger: logging.New("🖥️ TERMINAL_BOT"),
+	}
+}
+
+// GetMyAgent verifies an agent.
+func (tb *TerminalBot) GetMyAgent() (*m.Agent, error) {
+	tb.logger.Info("Credentials received. Retrieving agent...")
+	agent, err := tb.client.GetMyAgent()
+	if err != nil {
+		return nil, err
+	}
+
+	return agent, nil
+}
+
+/*
+👽 AGENT_BOT
+*/
+
+// reconcileInterval is how often the AgentBot reconciles its view of the agent with the API.
+const reconcileInterval = 1 * time.Minute
+
+// agentTTL is how long the agent's known state is used before it is fetched again. Transactions
+// refresh it for free, so fetches are rare.
+const agentTTL = 30 * time.Second
+
+// AgentBot represents an AgentBot instance.
+type AgentBot struct {
+	client api.ClientAPI
+	// reconciler is the client tagged for the background loops' request budget, so they cannot starve missions.
+	reconciler api.ClientAPI
+	logger     *log.Logger
+	bus        *event.Bus
+	// live is the configuration, which may be reloaded while the bot runs. Read it through config.
+	live       *config.Live
+	clock      clock.Clock
+	agent      *store.AgentState
+	seenEvents map[string]bool
+	systems    *store.SystemKnowledge
+	markets    *store.MarketStore
+	// shipyards holds the shipyard prices satellites observe. A nil store records nothing.
+	shipyards *store.ShipyardStore
+	// fuelCosts learns the fuel each route actually burns. A nil store estimates by the formula.
+	fuelCosts *store.FuelCosts
+	// visits remembers what ships did at the waypoints they visited. A nil log remembers nothing.
+	visits *store.VisitLog
+	// ledger is the agent's credit movements, logged when the credits drift. A nil ledger logs none.
+	ledger *ledger.Ledger
+	// earnings rates what each ship earns per hour in the fleet table. A nil Earnings rates none.
+	earnings *ledger.Earnings
+
+	strategies *StrategySelector
+	// scheduler re-dispatches ships that report before they are ready to act.
+	scheduler *Scheduler
+	// journal records multi-step missions, so they resume after a restart.
+	journal *store.Journal
+	// rand is the run's seeded randomness.
+	rand *lib.Rand
+	// registry drops reports from duplicate ShipBots of a ship.
+	registry *Registry
+	// monitor pauses commands while the API is unavailable. A nil monitor never pauses.
+	monitor *health.Monitor
+	// pause holds commands while the fleet is paused. A nil pause never holds.
+	pause *pauseGate
+	// quotas split contract deliverables between ships.
+	quotas *Quotas
+	// targets spread ships across mining targets.
+	targets *Targets
+	// orders are the manual orders given to ships, which preempt their automatic missions.
+	orders *Orders
+	// cooldowns are the lengths of the cooldowns each ship's extractions trigger.
+	cooldowns *store.CooldownStats
+	// metadata holds ship nicknames for the fleet table. A nil store names no ship.
+	metadata *store.MetadataStore
+	// deadlines follow each accepted contract's progress towards its deadline. Only the reconcile loop
+	// touches them.
+	deadlines map[string]*deadlineWatch
+
+	// mu guards contracts, plan, writtenOff, and the ship lists below, which the reconcile loop updates
+	// while ships are commanded.
+	mu         sync.Mutex
+	contracts  []m.Contract
+	plan       DeliveryPlan
+	writtenOff map[string]bool
+	// conditions are the condition level last announced of each ship's components, keyed by ship and
+	// component, and repairs when each ship was last sent to be repaired.
+	conditions map[string]conditionLevel
+	repairs    map[string]time.Time
+	// woken are the ships with a ShipBot, and manual the ships left to manual control that have been logged.
+	woken  map[string]bool
+	manual map[string]bool
+	// wake gets a ship found by the reconcile loop, or just bought, underway on any initial missions given,
+	// once the fleet is launched.
+	wake func(ship m.Ship, initial ...Mission)
+	// home is the agent's headquarters and the key waypoints near it, once loaded.
+	home *HomeBase
+}
+
+// NewAgentBot creates a new instance of AgentBot.
+func NewAgentBot(client api.ClientAPI, agent *m.Agent, markets *store.MarketStore, strategies *StrategySelector, journal *store.Journal, bus *event.Bus, cfg *config.Config) *AgentBot {
+	return &AgentBot{
+		client:     client,
+		reconciler: api.Tag(client, api.SubsystemReconcile),
+		bus:        bus,
+		live:       config.NewLive(cfg, ""),
+		logger:     logging.New(fmt.Sprintf("👽 %s", agent.Symbol)),
+		agent:      store.NewAgentState(*agent, cfg.CreditFloor, store.WithAgentSource(client, agentTTL)),
+		seenEvents: make(map[string]bool),
+		systems:    store.NewSystemKnowledge(client),
+		markets:    markets,
+		strategies: strategies,
+		clock:      clock.Real,
+		scheduler:  NewScheduler(clock.Real),
+		journal:    journal,
+		registry:   NewRegistry(),
+		quotas:     NewQuotas(),
+		targets:    NewTargets(),
+		orders:     NewOrders(),
+		cooldowns:  store.NewCooldownStats(),
+		deadlines:  make(map[string]*deadlineWatch),
+		writtenOff: make(map[string]bool),
+		conditions: make(map[string]conditionLevel),
+		repairs:    make(map[string]time.Time),
+		woken:      make(map[string]bool),
+		manual:     make(map[string]bool),
+	}
+}
+
+// GetMyContracts retrieves the Agent's contracts, leaving out those that have expired.
+func (ab *AgentBot) GetMyContracts() (*[]m.Contract, error) {
+	return ab.currentContracts(ab.client)
+}
+
+// currentContracts retrieves the Agent's contracts through client, leaving out those that have expired.
+func (ab *AgentBot) currentContracts(client api.ClientAPI) (*[]m.Contract, error) {
+	contracts, err := client.GetMyContracts()
+	if err != nil {
+		return nil, err
+	}
+
+	now := ab.clock.Now()
+	current := lib.Filter(*contracts, func(contract m.Contract) bool {
+		return !contract.IsExpired(now)
+	})
+	if current == nil {
+		current = []m.Contract{}
+	}
+
+	return &current, nil
+}
+
+// Plan returns the deliveries the agent's contracts currently need.
+func (ab *AgentBot) Plan() DeliveryPlan {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	return ab.plan
+}
+
+// SetPlan replaces the deliveries the agent's contracts need. Ships pick it up when they next report.
+func (ab *AgentBot) SetPlan(plan DeliveryPlan) {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	ab.plan = plan
+}
+
+// Contracts returns the contracts the agent is working on.
+func (ab *AgentBot) Contracts() []m.Contract {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	return ab.contracts
+}
+
+// SetContracts replaces the contracts the agent is working on.
+func (ab *AgentBot) SetContracts(contracts []m.Contract) {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	ab.contracts = contracts
+}
+
+// Snapshot returns the agent-wide state strategies decide from.
+func (ab *AgentBot) Snapshot() AgentSnapshot {
+	return AgentSnapshot{
+		Agent:     ab.agent.Agent(),
+		Contracts: ab.Contracts(),
+		Plan:      ab.Plan(),
+		Markets:   ab.markets.All(),
+		Home:      ab.HomeBase(),
+	}
+}
+
+// PlanDeliveries plans the deliveries of the agent's live contracts.
+func (ab *AgentBot) PlanDeliveries(contracts []m.Contract) DeliveryPlan {
+	return NewDeliveryPlan(contracts, ab.clock.Now())
+}
+
+// Reconcile refreshes the AgentBot's view of the agent, and audits its credits, until done is closed.
+func (ab *AgentBot) Reconcile(done <-chan struct{}) {
+	ticker := ab.clock.NewTicker(reconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		ab.ReconcileEvents()
+		ab.ReconcileContracts()
+		ab.ReconcileShips()
+		ab.AuditCredits()
+
+		select {
+		case <-ticker.C():
+		case <-done:
+			return
+		}
+	}
+}
+
+/*
+🏭 Shipyards
+*/
+
+// requisitionShipType is the ship type the requisition protocol shops for.
+const requisitionShipType = "SHIP_MINING_DRONE"
+
+// estimatedFuelPrice is the assumed credits per unit of fuel when comparing the cost of reaching shipyards.
+const estimatedFuelPrice int64 = 100
+
+// ShipyardOffer is a shipyard's price for a ship type, with the cost of getting there from the surveying ship.
+type ShipyardOffer struct {
+	Waypoint string
+	ShipType string
+	// Price is zero when NeedsVisit is set.
+	Price    int64
+	Distance float64
+	Fuel     int
+	// NeedsVisit is set when the shipyard sells the ship type but only shows prices to a ship that is present.
+	NeedsVisit bool
+}
+
+// TotalCost returns the purchase price plus the estimated price of the fuel to reach the shipyard.
+func (o ShipyardOffer) TotalCost() int64 {
+	return o.Price + int64(o.Fuel)*estimatedFuelPrice
+}
+
+// LogValues returns the offer as alternating log keys and values.
+func (o ShipyardOffer) LogValues() []interface{} {
+	return []interface{}{"waypoint", o.Waypoint, "shipType", o.ShipType, "price", o.Price, "distance", math.Round(o.Distance), "fuel", o.Fuel, "totalCost", o.TotalCost()}
+}
+
+// RankOffers sorts offers by total cost, cheapest first. Offers that need a visit go last, nearest first.
+func RankOffers(offers []ShipyardOffer) {
+	sort.SliceStable(offers, func(i, j int) bool {
+		if offers[i].NeedsVisit != offers[j].NeedsVisit {
+			return !offers[i].NeedsVisit
+		}
+
+		if offers[i].NeedsVisit {
+			return offers[i].Distance < offers[j].Distance
+		}
+
+		return offers[i].TotalCost() < offers[j].TotalCost()
+	})
+}
+
+// SurveyShipyards returns the offers for a ship type from every shipyard in a system, ranked by RankOffers.
+// Distances are measured from the agent's command ship, or its first ship in the system.
+func (ab *AgentBot) SurveyShipyards(systemSymbol string, shipType string) ([]ShipyardOffer, error) {
+	waypoints, err := ab.systems.Waypoints(systemSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	ships, err := ab.client.GetMyShips()
+	if err != nil {
+		return nil, err
+	}
+
+	surveyor := surveyingShip(*ships, systemSymbol)
+
+	var offers []ShipyardOffer
+	for _, waypoint := range waypoints {
+		if !waypoint.HasTrait("SHIPYARD") {
+			continue
+		}
+
+		shipyard, err := ab.client.GetShipyard(systemSymbol, waypoint.Symbol)
+		if err != nil {
+			return nil, fmt.Errorf("shipyard %s: %w", waypoint.Symbol, err)
+		}
+
+		if err := ab.shipyards.Record(*shipyard, ab.clock.Now()); err != nil {
+			ab.logger.Warn("🏭 Error saving shipyard prices.", "waypoint", waypoint.Symbol, "error", err)
+		}
+
+		offer, ok := shipyardOffer(shipyard, shipType)
+		if !ok {
+			continue
+		}
+
+		if surveyor != nil {
+			from := surveyor.Nav.Route.Destination
+			offer.Distance = lib.Distance(lib.NewCoordinate(from.X, from.Y), lib.NewCoordinate(waypoint.X, waypoint.Y))
+			offer.Fuel = lib.FuelCost(offer.Distance, "CRUISE")
+			if from.Symbol == waypoint.Symbol {
+				offer.Fuel = 0
+			}
+		}
+
+		offers = append(offers, offer)
+	}
+
+	RankOffers(offers)
+
+	return offers, nil
+}
+
+// shipyardOffer returns the shipyard's offer for a ship type, and whether the shipyard sells it at all.
+func shipyardOffer(shipyard *m.Shipyard, shipType string) (ShipyardOffer, bool) {
+	offer := ShipyardOffer{Waypoint: shipyard.Symbol, ShipType: shipType}
+
+	for _, ship := range shipyard.Ships {
+		if ship.Type == shipType {
+			offer.Price = ship.PurchasePrice
+			return offer, true
+		}
+	}
+
+	for _, t := range shipyard.ShipTypes {
+		if t.Type == shipType {
+			offer.NeedsVisit = true
+			return offer, true
+		}
+	}
+
+	return offer, false
+}
+
+// surveyingShip returns the command ship if it is in the system, otherwise the first ship that is, or nil.
+func surveyingShip(ships []m.Ship, systemSymbol string) *m.Ship {
+	var surveyor *m.Ship
+
+	for i := range ships {
+		if ships[i].Nav.SystemSymbol != systemSymbol {
+			continue
+		}
+
+		if ships[i].Registration.Role == "COMMAND" {
+			return &ships[i]
+		}
+
+		if surveyor == nil {
+			surveyor = &ships[i]
+		}
+	}
+
+	return surveyor
+}
+
+// ReconcileContracts recomputes the delivery plan from the contracts whose deadlines can still be met, and
+// tracks how those contracts stand against their deadlines. The condition of the ships it fetches to do so is
+// checked too.
+func (ab *AgentBot) ReconcileContracts() {
+	contracts, err := ab.currentContracts(ab.reconciler)
+	if err != nil {
+		ab.logger.Error("📜 Error getting contracts.", "error", err)
+		return
+	}
+
+	ships, err := ab.reconciler.GetMyShips()
+	if err != nil {
+		ab.logger.Error("📜 Error getting ships.", "error", err)
+		return
+	}
+
+	feasible := ab.WriteOffInfeasible(*contracts, *ships, ab.clock.Now())
+
+	ab.SetContracts(feasible)
+	ab.SetPlan(ab.PlanDeliveries(feasible))
+	ab.quotas.Rebalance(feasible)
+	ab.InvalidateJournal(*contracts)
+	ab.TrackDeadlines(feasible, ab.clock.Now())
+
+	for _, ship := range *ships {
+		ab.CheckCondition(ship)
+	}
+}
+
+// WriteOffInfeasible returns the contracts whose remaining deliveries can still be made before the deadline,
+// given where ships are now. Each accepted contract that cannot is logged once as written off, with the
+// onAccepted payment already received and the onFulfilled payment forfeited.
+// Contracts whose delivery time cannot be estimated are kept.
+func (ab *AgentBot) WriteOffInfeasible(contracts []m.Contract, ships []m.Ship, now time.Time) []m.Contract {
+	var feasible []m.Contract
+
+	for _, contract := range contracts {
+		if !contract.Accepted || contract.Fulfilled {
+			feasible = append(feasible, contract)
+			continue
+		}
+
+		reason := "deadline passed"
+		if !contract.IsExpired(now) {
+			needed, err := lib.MinDeliveryTime(&contract, ships, ab.systems.Waypoint, now)
+			if err != nil {
+				ab.logger.Debug("📜 Could not estimate delivery time.", "id", contract.ID, "error", err)
+				feasible = append(feasible, contract)
+				continue
+			}
+
+			if !now.Add(needed).After(contract.Terms.Deadline) {
+				feasible = append(feasible, contract)
+				continue
+			}
+
+			reason = fmt.Sprintf("deliveries need at least %s but %s remain", needed, contract.Terms.Deadline.Sub(now).Round(time.Second))
+		}
+
+		ab.mu.Lock()
+		logged := ab.writtenOff[contract.ID]
+		ab.writtenOff[contract.ID] = true
+		ab.mu.Unlock()
+
+		if !logged {
+			ab.logger.Warn("📜 Writing off contract.", "id", contract.ID, "reason", reason, "sunk", contract.Terms.Payment.OnAccepted, "forfeited", contract.Terms.Payment.OnFulfilled)
+		}
+	}
+
+	return feasible
+}
+
+// ReconcileShips wakes ships that have joined the fleet since it was launched, such as ships just bought.
+// Ships left to manual control are never woken.
+func (ab *AgentBot) ReconcileShips() {
+	ab.mu.Lock()
+	wake := ab.wake
+	ab.mu.Unlock()
+
+	if wake == nil {
+		return
+	}
+
+	ships, err := ab.reconciler.GetMyShips()
+	if err != nil {
+		ab.logger.Error("🆕 Error getting ships.", "error", err)
+		return
+	}
+
+	for _, ship := range *ships {
+		if !ab.controls(ship) {
+			ab.leaveToManual(ship)
+			continue
+		}
+
+		if ab.markWoken(ship.Symbol) {
+			ab.logger.Info("🆕 New ship found. Waking...", "ship", ship.Symbol, "role", ship.Registration.Role)
+			wake(ship)
+		}
+	}
+}
+
+// setClock times the AgentBot's waits, schedules, and caches by c instead of the wall clock. It must be called
+// before the AgentBot is started.
+func (ab *AgentBot) setClock(c clock.Clock) {
+	ab.clock = c
+	ab.scheduler = NewScheduler(c)
+	ab.agent.SetClock(c)
+}
+
+// config returns the current configuration.
+func (ab *AgentBot) config() *config.Config {
+	return ab.live.Get()
+}
+
+// controls checks if the configuration lets the bot command a ship.
+func (ab *AgentBot) controls(ship m.Ship) bool {
+	return ab.config().Controls(ship.Symbol, ship.Registration.Role)
+}
+
+// leaveToManual logs, once per ship, that a ship is left to manual control.
+func (ab *AgentBot) leaveToManual(ship m.Ship) {
+	ab.mu.Lock()
+	logged := ab.manual[ship.Symbol]
+	ab.manual[ship.Symbol] = true
+	ab.mu.Unlock()
+
+	if !logged {
+		ab.logger.Info("🕹️ Leaving ship to manual control.", "ship", ship.Symbol, "role", ship.Registration.Role)
+	}
+}
+
+// markWoken records that a ship has a ShipBot, reporting false if it already had one.
+func (ab *AgentBot) markWoken(shipSymbol string) bool {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	if ab.woken[shipSymbol] {
+		return false
+	}
+	ab.woken[shipSymbol] = true
+
+	return true
+}
+
+// setWake sets how ships found by the reconcile loop, or just bought, are got underway.
+func (ab *AgentBot) setWake(wake func(ship m.Ship, initial ...Mission)) {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	ab.wake = wake
+}
+
+// ReconcileEvents logs agent events that have not been seen before, deduplicating by event ID. Only the IDs of
+// the latest events are kept, since the server returns recent events only and older ones do not come back.
+func (ab *AgentBot) ReconcileEvents() {
+	events, err := ab.reconciler.GetMyAgentEvents()
+	if err != nil {
+		ab.logger.Error("📰 Error getting agent events.", "error", err)
+		return
+	}
+
+	seen := make(map[string]bool, len(*events))
+	for _, e := range *events {
+		seen[e.ID] = true
+		if ab.seenEvents[e.ID] {
+			continue
+		}
+
+		ab.logger.Info("📰 "+e.Message, "type", e.Type, "id", e.ID, "at", e.CreatedAt)
+		ab.bus.Publish(event.Event{Type: event.AgentEventReceived, Message: e.Message, Data: e})
+	}
+	ab.seenEvents = seen
+}
+
+// Command handles a ShipBot's report, dispatching it on its next mission according to its role.
+// Reports from a duplicate ShipBot of a ship are dropped, so only one mission runs per ship at a time, as are
+// reports from ships left to manual control and from missions that timed out, whose ships were recovered.
+func (ab *AgentBot) Command(sb ShipBot, sbCh chan ShipBot) {
+	if !sb.watch.finish() {
+		sb.logger.Warn("⏱️ Timed-out mission reported in. Dropping its report.")
+		return
+	}
+	sb.watch = nil
+
+	if !ab.controls(*sb.ship) {
+		sb.logger.Warn("🕹️ Ship is under manual control. Dropping its report.")
+		return
+	}
+
+	if !ab.registry.Admit(&sb, ab.clock.Now()) {
+		sb.logger.Warn("Duplicate ShipBot reported in. Merging its state and dropping it.", "mission", sb.mission, "missionId", sb.missionID)
+		return
+	}
+
+	ab.hold(&sb)
+
+	if sb.failure != nil {
+		logFailure(&sb)
+	}
+
+	readyAt := sb.ReadyAt()
+	sb.logger.Info("Reporting in.", append(sb.ship.LogValues(), "readyAt", readyAt.Format(time.RFC3339))...)
+	sb.plan = ab.Plan()
+	if sb.mission != "" {
+		sb.Complete()
+	}
+	ab.bus.Publish(event.Event{Type: event.ShipReported, Ship: sb.ship.Symbol, Data: *sb.ship})
+	ab.CheckCondition(*sb.ship)
+
+	if wait := readyAt.Sub(ab.clock.Now()); wait > 0 {
+		dispatchAt := readyAt.Add(-ab.dispatchLead(&sb))
+		sb.logger.Info("Not ready. Scheduling next mission.", "readyAt", readyAt.Format(time.RFC3339), "wait", wait.Round(time.Second), "lead", readyAt.Sub(dispatchAt))
+		// A ship that was in transit has its nav refreshed by DispatchNext, which holds it if it has not arrived.
+		ab.scheduler.At(dispatchAt, func() { ab.DispatchNext(sb, sbCh) })
+		return
+	}
+
+	ab.DispatchNext(sb, sbCh)
+}
+
+// dispatchLeadMax caps how far ahead of its reactor cooldown's expiry a ship is dispatched.
+const dispatchLeadMax = 2 * time.Second
+
+// dispatchLead returns how far ahead of its cooldown's expiry to dispatch a ship that is only waiting on its
+// reactor, so its next mission has been decided and is itself waiting on the cooldown when it expires, and an
+// extraction fires straight after rather than after a round trip through the command loop. The lead is a
+// tenth of the ship's average cooldown, up to dispatchLeadMax. Ships in transit, and ships with no cooldowns
+// recorded, get none. Missions that need the reactor wait out the cooldown, so none acts early.
+func (ab *AgentBot) dispatchLead(sb *ShipBot) time.Duration {
+	if sb.ship.Nav.Status == "IN_TRANSIT" {
+		return 0
+	}
+
+	average, ok := ab.cooldowns.Average(sb.ship.Symbol)
+	if !ok {
+		return 0
+	}
+
+	if lead := average / 10; lead < dispatchLeadMax {
+		return lead
+	}
+
+	return dispatchLeadMax
+}
+
+// logFailure logs the error a ShipBot's last mission failed with: its whole chain, the context it was wrapped
+// in, and how the underlying error is classified. The failure is then cleared.
+func logFailure(sb *ShipBot) {
+	fields := append(boterr.Fields(sb.failure), failureClass(sb.failure)...)
+	sb.logger.Warn("Mission failure.", append(fields, "error", sb.failure)...)
+	sb.failure = nil
+}
+
+// failureClass classifies a mission's error: an API error with its status and code, a network error, or an
+// error of the bot's own.
+func failureClass(err error) []interface{} {
+	var apiErr *api.APIError
+	if errors.As(err, &apiErr) {
+		return []interface{}{"class", "api", "status", apiErr.StatusCode, "code", apiErr.Code, "cause", apiErr.Message}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return []interface{}{"class", "network", "cause", netErr.Error()}
+	}
+
+	return []interface{}{"class", "bot"}
+}
+
+// DispatchNext sends a ShipBot on the mission its role's strategy decides next.
+// A ShipBot under manual orders carries out its next order, or holds, instead. A ShipBot's first dispatch
+// resumes its journalled mission, if it has one. Planned migrations, then queued follow-up missions, go before
+// the strategy's choice. Ships still in transit are never dispatched.
+func (ab *AgentBot) DispatchNext(sb ShipBot, sbCh chan ShipBot) {
+	ab.hold(&sb)
+	if ab.holdInTransit(&sb, sbCh) {
+		return
+	}
+	sb.home = ab.HomeBase()
+	if cfg := ab.config(); sb.tunedBy != cfg {
+		sb.retune(cfg)
+	}
+	// Resumed, migrating, and follow-up missions count for the strategy the ship follows.
+	if sb.strategy == "" || sb.strategy == manualStrategy {
+		sb.strategy = ab.strategies.Name(sb.ship.Registration.Role)
+	}
+
+	if mission, ok := ab.manualOrder(&sb); ok {
+		sb.strategy = manualStrategy
+		ab.Dispatch(&sb, mission.Name)
+		sb.journalHold()
+		go mission.Run(&sb, sbCh)
+		return
+	}
+
+	if sb.resuming {
+		sb.resuming = false
+		if mission, ok := ab.ResumeMission(&sb); ok {
+			ab.Dispatch(&sb, mission.Name)
+			go mission.Run(&sb, sbCh)
+			return
+		}
+	}
+
+	if mission, ok := ab.plannedMigration(&sb); ok {
+		ab.Dispatch(&sb, mission.Name)
+		go mission.Run(&sb, sbCh)
+		return
+	}
+
+	if len(sb.queued) > 0 {
+		mission := sb.queued[0]
+		sb.queued = sb.queued[1:]
+		ab.Dispatch(&sb, mission.Name)
+		go mission.Run(&sb, sbCh)
+		return
+	}
+
+	// A ship in critical condition is repaired before it takes on new work.
+	if ab.needsRepair(*sb.ship) {
+		mission := repairMission()
+		ab.Dispatch(&sb, mission.Name)
+		go mission.Run(&sb, sbCh)
+		return
+	}
+
+	sb.strategy = ab.strategies.Name(sb.ship.Registration.Role)
+	mission := ab.strategies.For(sb.ship.Registration.Role).Decide(&sb, ab.Snapshot())
+	ab.Dispatch(&sb, mission.Name)
+	go mission.Run(&sb, sbCh)
+}
+
+// hold blocks while the fleet is paused or the API is unavailable. A ship that waited, or whose mission was
+// parked by a pause since it last reported, is re-synced, since its state may have changed meanwhile.
+func (ab *AgentBot) hold(sb *ShipBot) {
+	pauses := ab.pause.wait()
+	parked := pauses != sb.pauses
+	sb.pauses = pauses
+	if !ab.monitor.Wait() && !parked {
+		return
+	}
+
+	ship, err := ab.client.GetShip(sb.ship.Symbol)
+	if err != nil {
+		sb.logger.Error("Error re-syncing ship after waiting.", "error", err)
+		return
+	}
+	*sb.ship = *ship
+	// Modules and mounts may have been swapped meanwhile.
+	sb.capabilities = m.CapabilitiesOf(*ship)
+}
+
+// transitRecheck is how long to wait before a ship the server still reports IN_TRANSIT after its arrival
+// reports in again.
+const transitRecheck = 5 * time.Second
+
+// holdInTransit keeps a ship that is IN_TRANSIT from being dispatched, since every mission would fail with
+// "ship is currently in transit". A ship still flying reports in again on arrival. A ship whose arrival has
+// passed has its nav refreshed first, because the server updates status lazily, and is held only if the
+// server still has it in transit.
+func (ab *AgentBot) holdInTransit(sb *ShipBot, sbCh chan ShipBot) bool {
+	if sb.ship.Nav.Status != "IN_TRANSIT" {
+		return false
+	}
+
+	now := ab.clock.Now()
+	if arrival := sb.ship.Nav.Route.Arrival; arrival.After(now) {
+		sb.logger.Info("🚀 In transit. Reporting in on arrival.", "arrival", arrival.Format(time.RFC3339))
+		held := *sb
+		ab.scheduler.At(arrival, func() { ab.Command(held, sbCh) })
+		return true
+	}
+
+	nav, err := ab.client.GetShipNav(sb.ship.Symbol)
+	if err != nil {
+		sb.logger.Error("🚀 Error refreshing nav of ship in transit.", "error", err)
+		held := *sb
+		ab.scheduler.At(now.Add(transitRecheck), func() { ab.Command(held, sbCh) })
+		return true
+	}
+	sb.ship.Nav = *nav
+
+	if nav.Status == "IN_TRANSIT" {
+		recheck := nav.Route.Arrival
+		if earliest := ab.clock.Now().Add(transitRecheck); recheck.Before(earliest) {
+			recheck = earliest
+		}
+
+		sb.logger.Warn("🚀 Still in transit. Reporting in later.", "arrival", nav.Route.Arrival.Format(time.RFC3339), "recheck", recheck.Format(time.RFC3339))
+		held := *sb
+		ab.scheduler.At(recheck, func() { ab.Command(held, sbCh) })
+		return true
+	}
+
+	sb.logger.Info("🚀 Arrived while away. Nav refreshed.", "status", nav.Status, "waypoint", nav.WaypointSymbol)
+	sb.Arrive()
+
+	return false
+}
+
+// PrintFleet logs a table of the fleet.
+func (ab *AgentBot) PrintFleet(ships []m.Ship) {
+	ab.logger.Info("Fleet status:\n" + RenderFleetTable(ships, ab.metadata, ab.config(), ab.earnings.Rates(ab.clock.Now())))
+}
+
+// PrintFleetOnSignal prints the fleet from the board's latest snapshot whenever a fleet signal (SIGUSR1) is
+// received, until done is closed.
+func (ab *AgentBot) PrintFleetOnSignal(board *status.Board, done <-chan struct{}) {
+	if len(fleetSignals) == 0 {
+		return
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, fleetSignals...)
+	defer signal.Stop(sigCh)
+
+	for {
+		select {
+		case <-sigCh:
+		case <-done:
+			return
+		}
+
+		snapshot := board.Snapshot()
+
+		ships := make([]m.Ship, 0, len(snapshot.Ships))
+		for _, ss := range snapshot.Ships {
+			ships = append(ships, ss.Ship)
+		}
+
+		ab.PrintFleet(ships)
+	}
+}
+
+// Dispatch logs and publishes the start of a mission for a ShipBot.
+// The ShipBot's logger is scoped to the mission, so every line it logs carries the mission's correlation ID.
+func (ab *AgentBot) Dispatch(sb *ShipBot, mission string) {
+	sb.mission = mission
+	sb.missionID = newMissionID(ab.rand)
+	sb.missionStarted = sb.clock.Now()
+	sb.logger = sb.base.With("ship", sb.ship.Symbol, "mission", mission, "missionId", sb.missionID)
+
+	ab.logger.Info(fmt.Sprintf("%s %s", sb.ship.Registration.Role, sb.ship.Symbol), "mission", mission, "missionId", sb.missionID)
+	sb.logger.Info("Mission started.")
+	ab.watchMission(sb)
+	ab.bus.Publish(event.Event{Type: event.MissionStarted, Ship: sb.ship.Symbol, Mission: mission, MissionID: sb.missionID, Data: event.MissionStart{Strategy: sb.strategy}})
+}
+
+// newMissionID returns a short random mission correlation ID, drawn from r so a seeded run repeats them.
+func newMissionID(r *lib.Rand) string {
+	b := make([]byte, 3)
+	r.Read(b)
+
+	return hex.EncodeToString(b)
+}
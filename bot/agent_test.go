@@ -0,0 +1,681 @@
+package bot
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/clock"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/health"
+	"github.com/GeoffreyDick/gogarin/logging"
+	"github.com/GeoffreyDick/gogarin/mockserver"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+// agentBot creates an AgentBot outside any fleet, against a mock server seeded from f. All of them run on a
+// fake clock that moves forward whenever something waits on it, and that the test can advance.
+func agentBot(t *testing.T, f *mockserver.Fixture) (*AgentBot, *mockserver.Server, *clock.Fake) {
+	t.Helper()
+
+	fake := clock.NewFake(epoch).AutoAdvance()
+	server := mockserver.New(f, mockserver.WithClock(fake))
+	t.Cleanup(server.Close)
+
+	client := api.NewClient("token", api.WithBaseURL(server.URL), api.WithRateLimit(1000), api.WithClock(fake))
+
+	cfg := config.Default()
+	ab := NewAgentBot(client, &f.Agent, store.NewMarketStore(), NewStrategySelector(cfg), nil, event.NewBus(), cfg)
+	ab.setClock(fake)
+	t.Cleanup(ab.scheduler.Stop)
+
+	return ab, server, fake
+}
+
+// newShipBot creates a ShipBot for a ship of the AgentBot's, as a fleet would.
+func (ab *AgentBot) newShipBot(t *testing.T, server *mockserver.Server, symbol string) *ShipBot {
+	t.Helper()
+
+	ship, ok := server.Ship(symbol)
+	if !ok {
+		t.Fatalf("no ship %s", symbol)
+	}
+
+	sb := NewShipBot(ab.client, &ship, ab.agent, ab.systems, ab.markets, ab.bus, ab.config())
+	sb.clock = ab.clock
+
+	return sb
+}
+
+func TestReportsFromManyShipsNeverBlock(t *testing.T) {
+	const ships = 50
+	const rounds = 5
+
+	cfg := config.Default()
+	cfg.IdleInterval = time.Millisecond
+	_, c := startMock(t, ships-2)
+
+	agent, err := c.GetMyAgent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fleet, err := c.GetMyShips()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+	reports := bus.Subscribe(ships * rounds * 4)
+	ab := NewAgentBot(c, agent, store.NewMarketStore(), NewStrategySelector(cfg), nil, bus, cfg)
+
+	// Run the command loop as runFleet does, seeded with a report from every ship.
+	// Commands still running when the test ends are waited for, so none outlives the test's config.
+	sbCh := make(chan ShipBot, len(*fleet))
+	done, stopped := make(chan struct{}), make(chan struct{})
+	var commands sync.WaitGroup
+	t.Cleanup(func() {
+		close(done)
+		<-stopped
+		commands.Wait()
+	})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case sb := <-sbCh:
+				commands.Add(1)
+				go func() {
+					defer commands.Done()
+					ab.Command(sb, sbCh)
+				}()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// Command ships idle briefly between reports, so each ship reports in every few milliseconds and
+	// rounds of reports from every ship pass through the command loop at once.
+	for i := range *fleet {
+		ship := (*fleet)[i]
+		ship.Registration.Role = "COMMAND"
+		NewShipBot(c, &ship, ab.agent, ab.systems, ab.markets, bus, cfg).Report(sbCh)
+	}
+
+	counts := make(map[string]int)
+	pending := ships
+	timeout := time.After(time.Minute)
+	for pending > 0 {
+		select {
+		case e := <-reports:
+			if e.Type != event.ShipReported {
+				continue
+			}
+			if counts[e.Ship]++; counts[e.Ship] == rounds {
+				pending--
+			}
+		case <-timeout:
+			t.Fatalf("timed out with %d of %d ships short of %d reports: %v", pending, ships, rounds, counts)
+		}
+	}
+}
+
+func TestCommandResyncsShipsAfterAnOutage(t *testing.T) {
+	cfg := config.Default()
+	s, c := startMock(t, 0)
+
+	monitor := health.NewMonitor(c, health.Options{Threshold: 1, Window: time.Minute, PollInterval: 10 * time.Millisecond}, logging.New("🩺 HEALTH"))
+
+	agent, err := c.GetMyAgent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ship, err := c.GetShip("MOCK-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ship.Nav.Status = "IN_ORBIT"
+
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+	reports := bus.Subscribe(8)
+	ab := NewAgentBot(c, agent, store.NewMarketStore(), NewStrategySelector(cfg), nil, bus, cfg)
+	ab.monitor = monitor
+
+	s.SetAvailable(false)
+	monitor.Observe(http.StatusServiceUnavailable)
+	go ab.Command(*NewShipBot(c, ship, ab.agent, ab.systems, ab.markets, bus, cfg), make(chan ShipBot, 1))
+
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case e := <-reports:
+		t.Fatalf("ship reported in during the outage: %+v", e)
+	default:
+	}
+
+	s.SetAvailable(true)
+	select {
+	case e := <-reports:
+		if reported := e.Data.(m.Ship); reported.Nav.Status != "DOCKED" {
+			t.Errorf("reported nav status %s after the outage, want the server's DOCKED", reported.Nav.Status)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ship did not report in after the API recovered")
+	}
+}
+
+func TestReloadedConfigTunesTheNextDecision(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gogarin.yaml")
+	write := func(contents string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("cargoThreshold: 0.9\n")
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, c := startMock(t, 0)
+	agent, err := c.GetMyAgent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ship, err := c.GetShip("MOCK-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ship.Cargo = m.ShipCargo{Capacity: 30}
+	ship.Cargo.Add("IRON_ORE", 25)
+
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+	ab := NewAgentBot(c, agent, store.NewMarketStore(), NewStrategySelector(cfg), nil, bus, cfg)
+	ab.live = config.NewLive(cfg, path)
+	t.Cleanup(ab.scheduler.Stop)
+	sbCh := make(chan ShipBot, 1)
+
+	// dispatch sends a ShipBot for the laden ship, tuned by the startup config, on its next mission and returns
+	// the mission's name once it has run.
+	dispatch := func() string {
+		t.Helper()
+		laden := *ship
+		laden.Cargo.Inventory = append([]m.ShipCargoItem(nil), ship.Cargo.Inventory...)
+		ab.DispatchNext(*NewShipBot(c, &laden, ab.agent, ab.systems, ab.markets, bus, cfg), sbCh)
+
+		select {
+		case reported := <-sbCh:
+			return reported.mission
+		case <-time.After(5 * time.Second):
+			t.Fatal("dispatched mission never reported")
+			return ""
+		}
+	}
+
+	if got := dispatch(); got != extractMission.Name && got != miningTargetMission.Name {
+		t.Errorf("25 of 30 units under a 0.9 threshold: mission %q, want to keep mining", got)
+	}
+
+	write("cargoThreshold: 0.5\n")
+	if changed, err := ab.live.Reload(); err != nil || !reflect.DeepEqual(changed, []string{"cargoThreshold"}) {
+		t.Fatalf("reload: changed %v, err %v", changed, err)
+	}
+
+	selling := map[string]bool{nearestMarketMission.Name: true, dockMission.Name: true, sellMission.Name: true}
+	if got := dispatch(); !selling[got] {
+		t.Errorf("25 of 30 units under a reloaded 0.5 threshold: mission %q, want to go and sell", got)
+	}
+}
+
+// contractClient is a ClientAPI serving a fixed set of contracts, ships and waypoints.
+type contractClient struct {
+	api.ClientAPI
+	contracts []m.Contract
+	ships     []m.Ship
+	waypoints []m.Waypoint
+}
+
+func (c *contractClient) GetMyContracts() (*[]m.Contract, error) {
+	contracts := append([]m.Contract(nil), c.contracts...)
+	return &contracts, nil
+}
+
+func (c *contractClient) GetMyShips() (*[]m.Ship, error) {
+	ships := append([]m.Ship(nil), c.ships...)
+	return &ships, nil
+}
+
+func (c *contractClient) ListWaypoints(systemSymbol string) (*[]m.Waypoint, error) {
+	waypoints := append([]m.Waypoint(nil), c.waypoints...)
+	return &waypoints, nil
+}
+
+// GetShipCooldown reports every reactor ready.
+func (c *contractClient) GetShipCooldown(shipSymbol string) (*m.Cooldown, error) {
+	return &m.Cooldown{ShipSymbol: shipSymbol}, nil
+}
+
+// deliveryContract is an accepted contract for 10 units of a good to X1-A-2 due at deadline.
+func deliveryContract(id string, good string, deadline time.Time) m.Contract {
+	return m.Contract{ID: id, Accepted: true, Terms: m.ContractTerms{
+		Deadline: deadline,
+		Payment:  m.ContractPayment{OnAccepted: 1000, OnFulfilled: 9000},
+		Deliver:  []m.ContractDeliverGood{{TradeSymbol: good, DestinationSymbol: "X1-A-2", UnitsRequired: 10}},
+	}}
+}
+
+func TestInfeasibleContractsAreWrittenOff(t *testing.T) {
+	cfg := config.Default()
+	out := captureLogs(t)
+
+	now := time.Now()
+	var ship m.Ship
+	ship.Engine.Speed = 30
+	ship.Nav = m.ShipNav{SystemSymbol: "X1-A", WaypointSymbol: "X1-A-1", Status: "DOCKED"}
+	ship.Nav.Route.Destination = m.ShipNavRouteWaypoint{Symbol: "X1-A-1", SystemSymbol: "X1-A"}
+
+	c := &contractClient{
+		contracts: []m.Contract{
+			deliveryContract("passed", "COPPER_ORE", now.Add(-time.Hour)),
+			deliveryContract("unreachable", "ICE_WATER", now.Add(time.Minute)),
+			deliveryContract("feasible", "IRON_ORE", now.Add(time.Hour)),
+		},
+		ships: []m.Ship{ship},
+		waypoints: []m.Waypoint{
+			{Symbol: "X1-A-1", SystemSymbol: "X1-A", X: 0, Y: 0},
+			{Symbol: "X1-A-2", SystemSymbol: "X1-A", X: 100, Y: 0},
+		},
+	}
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+	ab := NewAgentBot(c, &m.Agent{Symbol: "GOGARIN"}, store.NewMarketStore(), NewStrategySelector(cfg), nil, bus, cfg)
+
+	feasible := ab.WriteOffInfeasible(c.contracts, c.ships, now)
+	if len(feasible) != 1 || feasible[0].ID != "feasible" {
+		t.Fatalf("feasible contracts = %+v, want only the one that can still be delivered", feasible)
+	}
+
+	ab.ReconcileContracts()
+	ab.ReconcileContracts()
+	if got := ab.Plan().Goods(); len(got) != 1 || got[0] != "IRON_ORE" {
+		t.Errorf("priorities = %v, want only the feasible contract's good", got)
+	}
+
+	written := map[string]string{}
+	for _, line := range logLines(t, out, "👽 GOGARIN:") {
+		if line["msg"] == "📜 Writing off contract." {
+			if _, ok := written[line["id"].(string)]; ok {
+				t.Errorf("contract %s written off more than once", line["id"])
+			}
+			written[line["id"].(string)] = line["reason"].(string)
+			if line["sunk"] != float64(1000) || line["forfeited"] != float64(9000) {
+				t.Errorf("write-off line = %v, want the sunk and forfeited payments", line)
+			}
+		}
+	}
+	if written["passed"] != "deadline passed" {
+		t.Errorf("passed contract reason = %q, want deadline passed", written["passed"])
+	}
+	if !strings.HasPrefix(written["unreachable"], "deliveries need at least 1m38s") {
+		t.Errorf("unreachable contract reason = %q, want the delivery estimate", written["unreachable"])
+	}
+	if _, ok := written["feasible"]; ok {
+		t.Error("feasible contract was written off")
+	}
+}
+
+func TestGetMyContractsLeavesOutExpiredContracts(t *testing.T) {
+	cfg := config.Default()
+
+	now := time.Now()
+	c := &contractClient{contracts: []m.Contract{
+		deliveryContract("expired", "COPPER_ORE", now.Add(-time.Hour)),
+		deliveryContract("current", "IRON_ORE", now.Add(time.Hour)),
+		{ID: "offer", Expiration: now.Add(-time.Minute)},
+	}}
+	ab := NewAgentBot(c, &m.Agent{Symbol: "GOGARIN"}, store.NewMarketStore(), NewStrategySelector(cfg), nil, nil, cfg)
+
+	contracts, err := ab.GetMyContracts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*contracts) != 1 || (*contracts)[0].ID != "current" {
+		t.Errorf("contracts = %+v, want only the current one", *contracts)
+	}
+}
+
+// eventsClient is a ClientAPI returning each of its pages of recent agent events in turn.
+type eventsClient struct {
+	contractClient
+	pages [][]m.AgentEvent
+}
+
+func (c *eventsClient) GetMyAgentEvents() (*[]m.AgentEvent, error) {
+	page := c.pages[0]
+	c.pages = c.pages[1:]
+	return &page, nil
+}
+
+func TestReconcileEventsPublishesEachEventOnceAndForgetsOldOnes(t *testing.T) {
+	cfg := config.Default()
+
+	a, b, c := m.AgentEvent{ID: "a", Message: "A"}, m.AgentEvent{ID: "b", Message: "B"}, m.AgentEvent{ID: "c", Message: "C"}
+	client := &eventsClient{pages: [][]m.AgentEvent{{a, b}, {b, c}, {c}}}
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+	received := bus.Subscribe(16)
+	ab := NewAgentBot(client, &m.Agent{Symbol: "GOGARIN"}, store.NewMarketStore(), NewStrategySelector(cfg), nil, bus, cfg)
+
+	for range client.pages {
+		ab.ReconcileEvents()
+	}
+
+	var published []string
+	for len(received) > 0 {
+		if e := <-received; e.Type == event.AgentEventReceived {
+			published = append(published, e.Message)
+		}
+	}
+	if want := []string{"A", "B", "C"}; !reflect.DeepEqual(published, want) {
+		t.Errorf("published %q, want %q", published, want)
+	}
+	if want := map[string]bool{"c": true}; !reflect.DeepEqual(ab.seenEvents, want) {
+		t.Errorf("seen events = %v, want only those in the latest response", ab.seenEvents)
+	}
+}
+
+func TestRankOffers(t *testing.T) {
+	offers := []ShipyardOffer{
+		{Waypoint: "FAR-CHEAP", Price: 70000, Fuel: 200},
+		{Waypoint: "UNPRICED-FAR", NeedsVisit: true, Distance: 90},
+		{Waypoint: "NEAR", Price: 80000, Fuel: 10},
+		{Waypoint: "UNPRICED-NEAR", NeedsVisit: true, Distance: 20},
+		{Waypoint: "HERE", Price: 85000},
+	}
+
+	RankOffers(offers)
+
+	var got []string
+	for _, offer := range offers {
+		got = append(got, offer.Waypoint)
+	}
+
+	// FAR-CHEAP costs 70,000 + 200 fuel at 100 = 90,000, so the nearer shipyards win despite their prices.
+	want := []string{"NEAR", "HERE", "FAR-CHEAP", "UNPRICED-NEAR", "UNPRICED-FAR"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ranking = %v, want %v", got, want)
+	}
+}
+
+// shipyardClient is a contractClient whose system also has shipyards.
+type shipyardClient struct {
+	contractClient
+	shipyards map[string]m.Shipyard
+}
+
+func (c *shipyardClient) GetShipyard(systemSymbol string, waypointSymbol string) (*m.Shipyard, error) {
+	shipyard := c.shipyards[waypointSymbol]
+	return &shipyard, nil
+}
+
+func TestSurveyShipyardsRanksByTotalCost(t *testing.T) {
+	cfg := config.Default()
+
+	shipyard := func(symbol string, price int64) m.Shipyard {
+		s := m.Shipyard{Symbol: symbol, ShipTypes: []m.ShipType{{Type: "SHIP_MINING_DRONE"}}}
+		if price > 0 {
+			s.Ships = []m.ShipyardShip{{Type: "SHIP_MINING_DRONE", PurchasePrice: price}}
+		}
+		return s
+	}
+	waypoint := func(symbol string, x int, traits ...string) m.Waypoint {
+		w := m.Waypoint{Symbol: symbol, SystemSymbol: "X1-A", X: x}
+		for _, trait := range traits {
+			w.Traits = append(w.Traits, m.WaypointTrait{Symbol: trait})
+		}
+		return w
+	}
+
+	var command m.Ship
+	command.Registration.Role = "COMMAND"
+	command.Nav = m.ShipNav{SystemSymbol: "X1-A", WaypointSymbol: "X1-A-1"}
+	command.Nav.Route.Destination = m.ShipNavRouteWaypoint{Symbol: "X1-A-1", SystemSymbol: "X1-A"}
+
+	c := &shipyardClient{
+		contractClient: contractClient{
+			ships: []m.Ship{command},
+			waypoints: []m.Waypoint{
+				waypoint("X1-A-1", 0, "SHIPYARD"),
+				waypoint("X1-A-2", 300, "SHIPYARD"),
+				waypoint("X1-A-3", 50, "SHIPYARD"),
+				waypoint("X1-A-4", 10, "MARKETPLACE"),
+			},
+		},
+		shipyards: map[string]m.Shipyard{
+			"X1-A-1": shipyard("X1-A-1", 85000),
+			"X1-A-2": shipyard("X1-A-2", 70000),
+			"X1-A-3": shipyard("X1-A-3", 0),
+		},
+	}
+	ab := NewAgentBot(c, &m.Agent{Symbol: "GOGARIN"}, store.NewMarketStore(), NewStrategySelector(cfg), nil, nil, cfg)
+
+	offers, err := ab.SurveyShipyards("X1-A", "SHIP_MINING_DRONE")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// X1-A-2 is cheapest but 300 fuel away, so the shipyard the command ship is at wins.
+	want := []ShipyardOffer{
+		{Waypoint: "X1-A-1", ShipType: "SHIP_MINING_DRONE", Price: 85000},
+		{Waypoint: "X1-A-2", ShipType: "SHIP_MINING_DRONE", Price: 70000, Distance: 300, Fuel: 300},
+		{Waypoint: "X1-A-3", ShipType: "SHIP_MINING_DRONE", Distance: 50, Fuel: 50, NeedsVisit: true},
+	}
+	if !reflect.DeepEqual(offers, want) {
+		t.Errorf("offers = %+v, want %+v", offers, want)
+	}
+}
+
+func TestStuckMissionTimesOutAndShipIsRecovered(t *testing.T) {
+	ab, server, fake := agentBot(t, fixture(t))
+	events := ab.bus.Subscribe(16)
+
+	woken := make(chan m.Ship, 1)
+	ab.setWake(func(ship m.Ship, initial ...Mission) { woken <- ship })
+
+	// The mission never ends on its own, as if its loop's exit condition could never hold.
+	sb := ab.newShipBot(t, server, "MOCK-2")
+	ab.Dispatch(sb, "Hang")
+	release := make(chan struct{})
+	sbCh := make(chan ShipBot, 1)
+	go func() {
+		<-release
+		sb.Report(sbCh)
+	}()
+
+	for ab.scheduler.Pending() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	fake.Advance(59 * time.Minute)
+	select {
+	case ship := <-woken:
+		t.Fatalf("%s recovered before its mission's hour was up", ship.Symbol)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	fake.Advance(2 * time.Minute)
+	select {
+	case ship := <-woken:
+		if ship.Symbol != "MOCK-2" {
+			t.Fatalf("recovered %s, want MOCK-2", ship.Symbol)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ship was not recovered from the timed-out mission")
+	}
+
+	for e := range events {
+		if e.Type != event.MissionFailed {
+			continue
+		}
+		if err, _ := e.Data.(error); e.Ship != "MOCK-2" || !errors.Is(err, errMissionTimeout) {
+			t.Fatalf("mission failure %s %q, want MOCK-2 timed out", e.Ship, e.Message)
+		}
+		break
+	}
+
+	// The stuck mission finally reports in, long after its ship was handed to a fresh ShipBot.
+	close(release)
+	if !sb.watch.TimedOut() {
+		t.Fatal("the stuck mission does not see that it timed out")
+	}
+	ab.Command(<-sbCh, sbCh)
+	if n := server.Requests("GET", "/my/ships/MOCK-2"); n != 1 {
+		t.Fatalf("%d GetShip requests, want only the re-sync on recovery", n)
+	}
+	select {
+	case ship := <-woken:
+		t.Fatalf("%s woken again by its timed-out mission's report", ship.Symbol)
+	default:
+	}
+}
+
+// drive advances a fake clock a step every millisecond of real time until the test ends.
+func drive(t *testing.T, fake *clock.Fake, step time.Duration) {
+	t.Helper()
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+				fake.Advance(step)
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		close(stop)
+		<-stopped
+	})
+}
+
+// extractions records, by a fake clock, when each extraction is requested and when the cooldown it starts
+// expires.
+type extractions struct {
+	api.ClientAPI
+	clock clock.Clock
+
+	mu          sync.Mutex
+	requested   []time.Time
+	expirations []time.Time
+}
+
+func (c *extractions) ExtractResources(shipSymbol string, surveys ...m.Survey) (*api.ExtractResourcesResponse, error) {
+	at := c.clock.Now()
+
+	res, err := c.ClientAPI.ExtractResources(shipSymbol, surveys...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requested = append(c.requested, at)
+	c.expirations = append(c.expirations, res.Cooldown.Expiration.Time)
+
+	return res, nil
+}
+
+func TestExtractionsFollowCooldownsBackToBack(t *testing.T) {
+	f := fixture(t)
+	f.Ships[1].Nav.WaypointSymbol = "X1-MK1-B2"
+	f.Ships[1].Nav.Route.Destination.Symbol = "X1-MK1-B2"
+	f.Ships[1].Cargo.Capacity = 20
+
+	ab, server, fake := agentBot(t, f)
+	sb := ab.newShipBot(t, server, "MOCK-2")
+	sb.arrival = nil
+	recorded := &extractions{ClientAPI: sb.client, clock: fake}
+	sb.client = recorded
+
+	// The ship reports in on its reactor's first cooldown, which the AgentBot has seen before.
+	res, err := recorded.ExtractResources("MOCK-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb.ship.Cargo = res.Cargo
+	sb.setCooldown(&res.Cooldown)
+	ab.cooldowns.Record("MOCK-2", res.Cooldown.TotalSeconds)
+
+	drive(t, fake, 100*time.Millisecond)
+	sbCh := make(chan ShipBot, 1)
+	ab.Command(*sb, sbCh)
+
+	select {
+	case reported := <-sbCh:
+		if reported.failure != nil {
+			t.Fatalf("mining failed: %s", reported.failure)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("mining did not report back")
+	}
+
+	recorded.mu.Lock()
+	defer recorded.mu.Unlock()
+
+	// 7, 5, then 9 units fill the 20-unit hold.
+	if n := len(recorded.requested); n != 3 {
+		t.Fatalf("%d extractions, want 3", n)
+	}
+	for i := 1; i < len(recorded.requested); i++ {
+		gap := recorded.requested[i].Sub(recorded.expirations[i-1])
+		if gap < 0 || gap >= time.Second {
+			t.Errorf("extraction %d requested %s after the cooldown before it expired, want within a second", i+1, gap)
+		}
+	}
+}
+
+func TestShipScheduledInTransitRefreshesNav(t *testing.T) {
+	ab, server, fake := agentBot(t, fixture(t))
+	sb := ab.newShipBot(t, server, "MOCK-2")
+	sb.arrival = nil
+
+	// The excavator reports in just after setting off for the asteroid field.
+	res, err := sb.client.NavigateShip("MOCK-2", "X1-MK1-B2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb.ship.Nav = res.Nav
+	started := ab.bus.Subscribe(16)
+
+	drive(t, fake, time.Second)
+	ab.Command(*sb, make(chan ShipBot, 1))
+
+	for e := range started {
+		if e.Type != event.MissionStarted {
+			continue
+		}
+		if e.Mission != extractMission.Name {
+			t.Fatalf("dispatched on %q on arrival, want %q", e.Mission, extractMission.Name)
+		}
+		break
+	}
+
+	if n := server.Requests("GET", "/my/ships/MOCK-2/nav"); n != 1 {
+		t.Fatalf("%d nav requests, want the ship's nav refreshed once it was due to arrive", n)
+	}
+}
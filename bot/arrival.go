@@ -0,0 +1,286 @@
+package bot
+
+import (
+	"errors"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/lib"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+/*
+🛬 Arrival
+*/
+
+// refuelBelow is the fraction of fuel capacity below which a ship refuels when it arrives at a marketplace.
+const refuelBelow = 0.5
+
+// errSkipped is returned by a handler that performed no action at the waypoint, so none is recorded in the
+// visit log.
+var errSkipped = errors.New("nothing to do")
+
+// ArrivalHandler runs when a ship arrives at or docks at a waypoint, before the ship reports back to the command loop.
+type ArrivalHandler struct {
+	Name string
+	// Action is what the handler does, as recorded in the visit log; empty records nothing.
+	Action string
+	Handle func(sb *ShipBot, waypoint *m.Waypoint) error
+}
+
+// defaultArrivalHandlers returns the handlers every ShipBot runs on arrival, in order.
+func defaultArrivalHandlers() []ArrivalHandler {
+	return []ArrivalHandler{
+		{Name: "record market", Action: store.ActionMarket, Handle: recordMarket},
+		{Name: "chart waypoint", Action: store.ActionChart, Handle: chartWaypoint},
+		{Name: "deliver on hand", Handle: deliverOnHand},
+		{Name: "refuel", Handle: refuel},
+	}
+}
+
+// defaultDockHandlers returns the handlers every ShipBot runs after docking, in order.
+func defaultDockHandlers() []ArrivalHandler {
+	return []ArrivalHandler{
+		{Name: "record market", Action: store.ActionMarket, Handle: recordMarket},
+		{Name: "deliver on hand", Handle: deliverOnHand},
+	}
+}
+
+// Arrive publishes that the ship arrived at its current waypoint and runs its arrival handlers in order.
+// A failing handler is logged and does not stop the handlers after it.
+func (sb *ShipBot) Arrive() {
+	waypointSymbol := sb.ship.Nav.WaypointSymbol
+	sb.bus.Publish(event.Event{
+		Type:      event.Arrived,
+		Ship:      sb.ship.Symbol,
+		Mission:   sb.mission,
+		MissionID: sb.missionID,
+		Message:   waypointSymbol,
+		Data:      event.Arrival{Ship: sb.ship.Symbol, Waypoint: waypointSymbol, At: sb.clock.Now()},
+	})
+
+	sb.runHandlers(sb.arrival)
+}
+
+// Docked runs the ShipBot's dock handlers at its current waypoint.
+func (sb *ShipBot) Docked() {
+	sb.runHandlers(sb.dock)
+}
+
+// runHandlers runs handlers in order at the ship's current waypoint, then records the visit and the outcome of
+// each action performed in the visit log. A failing handler is logged and does not stop the handlers after it.
+func (sb *ShipBot) runHandlers(handlers []ArrivalHandler) {
+	if len(handlers) == 0 {
+		return
+	}
+
+	waypointSymbol := sb.ship.Nav.WaypointSymbol
+	waypoint, err := sb.systems.Waypoint(waypointSymbol)
+	if err != nil {
+		sb.logger.Warn("🛬 Error getting waypoint. Skipping handlers.", "waypoint", waypointSymbol, "error", err)
+		return
+	}
+
+	outcomes := make(map[string]string)
+	for _, handler := range handlers {
+		err := handler.Handle(sb, waypoint)
+		switch {
+		case errors.Is(err, errSkipped):
+			continue
+		case err != nil:
+			sb.logger.Warn("🛬 Handler failed.", "handler", handler.Name, "waypoint", waypointSymbol, "error", err)
+			outcomes[handler.Action] = store.OutcomeFailed
+		default:
+			outcomes[handler.Action] = store.OutcomeDone
+		}
+	}
+	delete(outcomes, "")
+
+	if err := sb.visits.Record(waypointSymbol, sb.clock.Now(), outcomes); err != nil {
+		sb.logger.Warn("🧭 Error saving visit.", "waypoint", waypointSymbol, "error", err)
+	}
+}
+
+// recordVisit records the outcome of an action a ship performed at a waypoint outside its handlers in the visit
+// log: done, or failed with err.
+func (sb *ShipBot) recordVisit(waypointSymbol string, action string, err error) {
+	outcome := store.OutcomeDone
+	if err != nil {
+		outcome = store.OutcomeFailed
+	}
+
+	if err := sb.visits.Record(waypointSymbol, sb.clock.Now(), map[string]string{action: outcome}); err != nil {
+		sb.logger.Warn("🧭 Error saving visit.", "waypoint", waypointSymbol, "error", err)
+	}
+}
+
+// recordMarket records the market at a marketplace in the shared market store, unless the store already has
+// prices for it, or a ship recorded it, within the ShipBot's market refresh interval.
+func recordMarket(sb *ShipBot, waypoint *m.Waypoint) error {
+	if !waypoint.HasTrait("MARKETPLACE") {
+		return errSkipped
+	}
+
+	now := sb.clock.Now()
+	if sb.markets.Fresh(waypoint.Symbol, sb.marketRefresh, now) || sb.visits.Done(waypoint.Symbol, store.ActionMarket, sb.marketRefresh, now) {
+		sb.logger.Debug("📈 Market recently recorded. Skipping.", "waypoint", waypoint.Symbol)
+		return errSkipped
+	}
+
+	// Market polls are capped on their own budget, so a surveyor polling every market cannot starve navigation.
+	market, err := api.Tag(sb.client, api.SubsystemMarketPoll).GetMarketAt(waypoint.Symbol)
+	if err != nil {
+		return err
+	}
+
+	sb.markets.Record(*market, sb.clock.Now())
+	sb.logger.Debug("📈 Market recorded.", "waypoint", waypoint.Symbol, "tradeGoods", len(market.TradeGoods))
+
+	return nil
+}
+
+// chartWaypoint charts an uncharted waypoint, unless a ship already charted it and the known waypoint is stale.
+func chartWaypoint(sb *ShipBot, waypoint *m.Waypoint) error {
+	if !waypoint.HasTrait("UNCHARTED") {
+		return errSkipped
+	}
+
+	if sb.visits.Done(waypoint.Symbol, store.ActionChart, 0, sb.clock.Now()) {
+		sb.logger.Debug("🗺️ Waypoint already charted. Skipping.", "waypoint", waypoint.Symbol)
+		return errSkipped
+	}
+
+	res, err := sb.client.CreateChart(sb.ship.Symbol)
+	if err != nil {
+		return err
+	}
+
+	// Known waypoints are updated straight away, so the mission that arrived sees the chart.
+	sb.systems.Put(res.Waypoint)
+	sb.logger.Info("🗺️ Waypoint charted.", "waypoint", res.Waypoint.Symbol)
+	sb.bus.Publish(event.Event{Type: event.WaypointCharted, Ship: sb.ship.Symbol, MissionID: sb.missionID, Message: res.Waypoint.Symbol, Data: res.Waypoint})
+
+	return nil
+}
+
+// refuel buys fuel at a marketplace selling it, reserving the credits first. A ship whose mission has planned
+// its route buys only what the route needs plus its reserve, and only enough to reach a cheaper stop when fuel
+// here costs more than its ceiling; otherwise it fills the tank once it is below refuelBelow.
+func refuel(sb *ShipBot, waypoint *m.Waypoint) error {
+	fuel := sb.ship.Fuel
+	if fuel.Capacity == 0 || !waypoint.HasTrait("MARKETPLACE") {
+		return nil
+	}
+
+	legs := sb.routeLegs(waypoint)
+	if legs == nil && float64(fuel.Current) >= refuelBelow*float64(fuel.Capacity) {
+		return nil
+	}
+
+	observation, ok := sb.markets.Get(waypoint.Symbol)
+	if !ok {
+		return nil
+	}
+
+	price, ok := observation.Market.PurchasePriceOf("FUEL")
+	if !ok {
+		return nil
+	}
+
+	units := lib.RefuelUnits(lib.RefuelPlan{
+		Current:  fuel.Current,
+		Capacity: fuel.Capacity,
+		Legs:     legs,
+		Reserve:  sb.tuning.FuelReserve,
+		Price:    price,
+		Ceiling:  sb.fuelCeiling,
+	})
+	if units == 0 {
+		return nil
+	}
+
+	return sb.buyFuel(units, price)
+}
+
+// buyFuel buys units of fuel at price at the marketplace the ship is at, reserving the credits first. Zero units
+// fills the tank. The ship is docked to refuel and returned to orbit afterwards if it was in orbit.
+func (sb *ShipBot) buyFuel(units int, price int64) error {
+	reserve := units
+	if reserve == 0 {
+		reserve = sb.ship.Fuel.Capacity - sb.ship.Fuel.Current
+	}
+
+	release, err := sb.agent.ReserveCredits(price * int64(reserve))
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	wasInOrbit := sb.ship.Nav.Status == "IN_ORBIT"
+	if wasInOrbit {
+		nav, err := sb.client.DockShip(sb.ship.Symbol)
+		if err != nil {
+			return err
+		}
+		sb.ship.Nav = *nav
+	}
+
+	res, err := sb.client.RefuelShip(sb.ship.Symbol, units)
+	if err != nil {
+		return err
+	}
+
+	sb.ship.Fuel = res.Fuel
+	sb.agent.Update(res.Agent)
+	sb.tallyFuel(0, res.Transaction.TotalPrice)
+	sb.logger.Info("⛽ Refueled.", "fuel", res.Fuel.Current, "units", res.Transaction.Units, "totalPrice", res.Transaction.TotalPrice)
+	sb.bus.Publish(event.Event{Type: event.ShipRefueled, Ship: sb.ship.Symbol, MissionID: sb.missionID, Data: res.Transaction})
+	sb.bus.Publish(event.Event{Type: event.AgentUpdated, Data: res.Agent})
+
+	if wasInOrbit {
+		nav, err := sb.client.OrbitShip(sb.ship.Symbol)
+		if err != nil {
+			return err
+		}
+		sb.ship.Nav = *nav
+	}
+
+	return nil
+}
+
+// routeLegs returns the hops of the ship's planned route onwards from a waypoint, with the fuel each burns and
+// the fuel price at its destination. It returns nil if the mission has not planned a route or a waypoint on it
+// is unknown.
+func (sb *ShipBot) routeLegs(from *m.Waypoint) []lib.FuelLeg {
+	if sb.route == nil {
+		return nil
+	}
+
+	route := sb.route
+	for i, symbol := range route {
+		if symbol == from.Symbol {
+			route = route[i+1:]
+			break
+		}
+	}
+
+	legs := make([]lib.FuelLeg, 0, len(route))
+	previous := from
+	for _, symbol := range route {
+		waypoint, err := sb.systems.Waypoint(symbol)
+		if err != nil {
+			return nil
+		}
+
+		leg := lib.FuelLeg{Fuel: sb.fuelCosts.Estimate(previous, waypoint, sb.ship.Nav.FlightMode)}
+		if observation, ok := sb.markets.Get(symbol); ok {
+			leg.Price, _ = observation.Market.PurchasePriceOf("FUEL")
+		}
+
+		legs = append(legs, leg)
+		previous = waypoint
+	}
+
+	return legs
+}
@@ -0,0 +1,362 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+// arrivalClient is a contractClient that records the calls arrival handlers make, docking, refueling, and
+// charting with fixed results.
+type arrivalClient struct {
+	contractClient
+	calls  []string
+	market m.Market
+}
+
+func (c *arrivalClient) GetMarketAt(waypointSymbol string) (*m.Market, error) {
+	c.calls = append(c.calls, "market "+waypointSymbol)
+	market := c.market
+	return &market, nil
+}
+
+func (c *arrivalClient) DockShip(shipSymbol string) (*m.ShipNav, error) {
+	c.calls = append(c.calls, "dock")
+	return &m.ShipNav{WaypointSymbol: "X1-A-1", Status: "DOCKED"}, nil
+}
+
+func (c *arrivalClient) OrbitShip(shipSymbol string) (*m.ShipNav, error) {
+	c.calls = append(c.calls, "orbit")
+	return &m.ShipNav{WaypointSymbol: "X1-A-1", Status: "IN_ORBIT"}, nil
+}
+
+func (c *arrivalClient) RefuelShip(shipSymbol string, units int) (*api.RefuelShipResponse, error) {
+	c.calls = append(c.calls, fmt.Sprintf("refuel %d", units))
+	return &api.RefuelShipResponse{
+		Agent:       m.Agent{Credits: 900},
+		Fuel:        m.ShipFuel{Current: 100, Capacity: 100},
+		Transaction: m.MarketTransaction{TradeSymbol: "FUEL", Units: 60, TotalPrice: 120},
+	}, nil
+}
+
+func (c *arrivalClient) CreateChart(shipSymbol string) (*api.CreateChartResponse, error) {
+	c.calls = append(c.calls, "chart")
+	return &api.CreateChartResponse{Waypoint: m.Waypoint{Symbol: "X1-A-1", SystemSymbol: "X1-A"}}, nil
+}
+
+// arrivingShip returns a ShipBot in orbit at X1-A-1 with 40 of 100 fuel, and the bus it publishes to.
+func arrivingShip(t *testing.T, cfg *config.Config, c api.ClientAPI, credits m.Credits, traits ...string) (*ShipBot, *event.Bus) {
+	t.Helper()
+
+	waypoint := m.Waypoint{Symbol: "X1-A-1", SystemSymbol: "X1-A"}
+	for _, trait := range traits {
+		waypoint.Traits = append(waypoint.Traits, m.WaypointTrait{Symbol: trait})
+	}
+	if ac, ok := c.(*arrivalClient); ok {
+		ac.waypoints = []m.Waypoint{waypoint}
+	}
+
+	ship := m.Ship{Symbol: "GOGARIN-1"}
+	ship.Nav = m.ShipNav{SystemSymbol: "X1-A", WaypointSymbol: "X1-A-1", Status: "IN_ORBIT"}
+	ship.Fuel = m.ShipFuel{Current: 40, Capacity: 100}
+
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+
+	return NewShipBot(c, &ship, store.NewAgentState(m.Agent{Credits: credits}, 0), store.NewSystemKnowledge(c), store.NewMarketStore(), bus, cfg), bus
+}
+
+func TestArrivalHandlersRunInOrderDespiteFailures(t *testing.T) {
+	cfg := config.Default()
+	out := captureLogs(t)
+
+	c := &arrivalClient{}
+	sb, bus := arrivingShip(t, cfg, c, 1000)
+	arrivals := bus.Subscribe(1)
+
+	var ran []string
+	handler := func(name string, err error) ArrivalHandler {
+		return ArrivalHandler{Name: name, Handle: func(sb *ShipBot, waypoint *m.Waypoint) error {
+			ran = append(ran, name+" at "+waypoint.Symbol)
+			return err
+		}}
+	}
+	sb.arrival = []ArrivalHandler{handler("first", nil), handler("second", errors.New("no luck")), handler("third", nil)}
+
+	sb.Arrive()
+
+	if want := []string{"first at X1-A-1", "second at X1-A-1", "third at X1-A-1"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("handlers ran %v, want %v", ran, want)
+	}
+
+	var failed []string
+	for _, line := range logLines(t, out, "🚀 GOGARIN-1:") {
+		if line["msg"] == "🛬 Handler failed." {
+			failed = append(failed, line["handler"].(string)+": "+line["error"].(string))
+		}
+	}
+	if !reflect.DeepEqual(failed, []string{"second: no luck"}) {
+		t.Errorf("failures logged = %v, want only the second handler's", failed)
+	}
+
+	select {
+	case e := <-arrivals:
+		arrival, ok := e.Data.(event.Arrival)
+		if e.Type != event.Arrived || !ok || arrival.Ship != "GOGARIN-1" || arrival.Waypoint != "X1-A-1" || time.Since(arrival.At) > time.Minute {
+			t.Errorf("event = %+v, want an arrival at X1-A-1", e)
+		}
+	default:
+		t.Error("no arrival published")
+	}
+}
+
+func TestDefaultArrivalHandlers(t *testing.T) {
+	cfg := config.Default()
+
+	fuel := m.Market{Symbol: "X1-A-1", TradeGoods: []m.MarketTradeGood{{Symbol: "FUEL", PurchasePrice: 2}}}
+
+	tests := []struct {
+		name    string
+		traits  []string
+		credits m.Credits
+		want    []string
+		// failed is the handler expected to fail, if any.
+		failed string
+		// available is the credits left unreserved afterwards.
+		available int64
+	}{
+		{"marketplace", []string{"MARKETPLACE"}, 1000, []string{"market X1-A-1", "dock", "refuel 60", "orbit"}, "", 900},
+		{"uncharted marketplace", []string{"UNCHARTED", "MARKETPLACE"}, 1000, []string{"market X1-A-1", "chart", "dock", "refuel 60", "orbit"}, "", 900},
+		{"refuel unaffordable", []string{"MARKETPLACE"}, 100, []string{"market X1-A-1"}, "refuel", 100},
+		{"no marketplace", nil, 1000, nil, "", 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := captureLogs(t)
+			c := &arrivalClient{market: fuel}
+			sb, _ := arrivingShip(t, cfg, c, tt.credits, tt.traits...)
+
+			sb.Arrive()
+
+			if !reflect.DeepEqual(c.calls, tt.want) {
+				t.Errorf("calls = %v, want %v", c.calls, tt.want)
+			}
+			var failed []string
+			for _, line := range logLines(t, out, "🚀 GOGARIN-1:") {
+				if line["msg"] == "🛬 Handler failed." {
+					failed = append(failed, line["handler"].(string))
+				}
+			}
+			if got := strings.Join(failed, ", "); got != tt.failed {
+				t.Errorf("failed handlers = %q, want %q", got, tt.failed)
+			}
+			if available := sb.agent.Available(); available != tt.available {
+				t.Errorf("%d credits available, want %d with no reservation held", available, tt.available)
+			}
+		})
+	}
+}
+
+func TestRefuelBuysForThePlannedRoute(t *testing.T) {
+	cfg := config.Default()
+
+	tests := []struct {
+		name    string
+		route   []string
+		ceiling int64
+		want    []string
+	}{
+		{"no route fills the tank", nil, 0, []string{"dock", "refuel 990", "orbit"}},
+		{"the route and the reserve", []string{"X1-A-2", "X1-A-3"}, 0, []string{"dock", "refuel 400", "orbit"}},
+		{"the route onwards from here", []string{"X1-A-1", "X1-A-3"}, 0, []string{"dock", "refuel 400", "orbit"}},
+		{"expensive here, cheap at the next stop", []string{"X1-A-2", "X1-A-3"}, 1, []string{"dock", "refuel 300", "orbit"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &arrivalClient{market: m.Market{Symbol: "X1-A-1", TradeGoods: []m.MarketTradeGood{{Symbol: "FUEL", PurchasePrice: 2}}}}
+			sb, _ := arrivingShip(t, cfg, c, 10000, "MARKETPLACE")
+			sb.arrival = []ArrivalHandler{{Name: "refuel", Handle: refuel}}
+			sb.ship.Fuel = m.ShipFuel{Current: 10, Capacity: 1000}
+			sb.fuelCeiling = tt.ceiling
+			c.waypoints = append(c.waypoints,
+				m.Waypoint{Symbol: "X1-A-2", SystemSymbol: "X1-A", X: 150},
+				m.Waypoint{Symbol: "X1-A-3", SystemSymbol: "X1-A", X: 300},
+			)
+			sb.markets.Record(m.Market{Symbol: "X1-A-1", TradeGoods: []m.MarketTradeGood{{Symbol: "FUEL", PurchasePrice: 2}}}, time.Now())
+			sb.markets.Record(m.Market{Symbol: "X1-A-2", TradeGoods: []m.MarketTradeGood{{Symbol: "FUEL", PurchasePrice: 1}}}, time.Now())
+			if tt.route != nil {
+				sb.planRoute(tt.route...)
+			}
+
+			sb.Arrive()
+
+			if !reflect.DeepEqual(c.calls, tt.want) {
+				t.Errorf("calls = %v, want %v", c.calls, tt.want)
+			}
+		})
+	}
+}
+
+func (c *arrivalClient) SellCargo(shipSymbol string, cargoSymbol string, units int) (*api.SellCargoResponse, error) {
+	c.calls = append(c.calls, "sell "+cargoSymbol)
+	return &api.SellCargoResponse{
+		Agent:       m.Agent{Credits: 1450},
+		Cargo:       m.ShipCargo{Capacity: 30},
+		Transaction: m.MarketTransaction{TradeSymbol: cargoSymbol, Units: units, PricePerUnit: 45, TotalPrice: int64(45 * units)},
+	}, nil
+}
+
+func TestSellMissionRecordsTheMarketOncePerRefresh(t *testing.T) {
+	cfg := config.Default()
+	cfg.MarketRefresh = time.Hour
+
+	iron := m.Market{Symbol: "X1-A-1", TradeGoods: []m.MarketTradeGood{{Symbol: "IRON_ORE", SellPrice: 45}, {Symbol: "FUEL", PurchasePrice: 2}}}
+	c := &arrivalClient{market: iron}
+	sb, _ := arrivingShip(t, cfg, c, 1000, "MARKETPLACE")
+	sb.ship.Fuel.Current = 100
+	sb.ship.Cargo = m.ShipCargo{Capacity: 30, Units: 10, Inventory: []m.ShipCargoItem{{Symbol: "IRON_ORE", Units: 10}}}
+
+	// Dock and sell, as the command loop dispatches an excavator with a full hold at a marketplace, then
+	// dock again on the next visit.
+	sbCh := make(chan ShipBot, 1)
+	sb.DockShip(sbCh)
+	<-sbCh
+	sb.SellCargo(sbCh)
+	<-sbCh
+	sb.DockShip(sbCh)
+	<-sbCh
+
+	if want := []string{"dock", "market X1-A-1", "sell IRON_ORE", "dock"}; !reflect.DeepEqual(c.calls, want) {
+		t.Errorf("calls = %v, want the market recorded on the first dock only", c.calls)
+	}
+
+	observation, ok := sb.markets.Get("X1-A-1")
+	if !ok || !reflect.DeepEqual(observation.Market, iron) || time.Since(observation.ObservedAt) > time.Minute {
+		t.Errorf("recorded market = %+v, want the market at X1-A-1", observation)
+	}
+}
+
+// rememberVisits gives a ShipBot a fresh, in-memory VisitLog.
+func rememberVisits(t *testing.T, sb *ShipBot) *store.VisitLog {
+	t.Helper()
+
+	visits, err := store.OpenVisitLog("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb.visits = visits
+
+	return visits
+}
+
+func TestMarketRecordedOnceWithinRefreshAcrossVisits(t *testing.T) {
+	sb, server := shipBot(t, fixture(t), "MOCK-2")
+	visits := rememberVisits(t, sb)
+	sb.arrival = []ArrivalHandler{{Name: "record market", Action: store.ActionMarket, Handle: recordMarket}}
+	const path = "/systems/X1-MK1/waypoints/X1-MK1-A1/market"
+
+	sb.Arrive()
+	if n := server.Requests("GET", path); n != 1 {
+		t.Fatalf("%d market requests on the first visit, want 1", n)
+	}
+	if _, ok := visits.LastVisited("X1-MK1-A1"); !ok {
+		t.Fatal("first visit not recorded")
+	}
+
+	// The prices are forgotten, as after a restart, but the visit log still knows the market was just recorded.
+	sb.markets = store.NewMarketStore()
+	sb.clock.Sleep(sb.marketRefresh / 2)
+	sb.Arrive()
+	if n := server.Requests("GET", path); n != 1 {
+		t.Fatalf("%d market requests after a second visit within the refresh interval, want 1", n)
+	}
+
+	sb.markets = store.NewMarketStore()
+	sb.clock.Sleep(sb.marketRefresh)
+	sb.Arrive()
+	if n := server.Requests("GET", path); n != 2 {
+		t.Fatalf("%d market requests after a visit beyond the refresh interval, want 2", n)
+	}
+}
+
+func TestChartedWaypointNotChartedAgainFromStaleCopy(t *testing.T) {
+	f := fixture(t)
+	for i := range f.Waypoints {
+		if f.Waypoints[i].Symbol == "X1-MK1-D4" {
+			f.Waypoints[i].Traits = append(f.Waypoints[i].Traits, m.WaypointTrait{Symbol: "UNCHARTED"})
+		}
+	}
+	f.Ships[1].Nav.WaypointSymbol = "X1-MK1-D4"
+	f.Ships[1].Nav.Route.Destination.Symbol = "X1-MK1-D4"
+
+	sb, server := shipBot(t, f, "MOCK-2")
+	rememberVisits(t, sb)
+	sb.arrival = []ArrivalHandler{{Name: "chart waypoint", Action: store.ActionChart, Handle: chartWaypoint}}
+
+	known, err := sb.systems.Waypoint("X1-MK1-D4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := *known
+
+	sb.Arrive()
+	if n := server.Requests("POST", "/my/ships/MOCK-2/chart"); n != 1 {
+		t.Fatalf("%d chart requests on the first visit, want 1", n)
+	}
+
+	// Another ship's copy of the waypoint predates the chart, however long ago it was made.
+	sb.clock.Sleep(24 * time.Hour)
+	if err := chartWaypoint(sb, &stale); err != errSkipped {
+		t.Fatalf("charting the stale copy = %v, want it skipped", err)
+	}
+	if n := server.Requests("POST", "/my/ships/MOCK-2/chart"); n != 1 {
+		t.Fatalf("%d chart requests, want the waypoint charted once", n)
+	}
+}
+
+func TestSatelliteChecksShipyardOnceWithinRefreshAcrossVisits(t *testing.T) {
+	f := fixture(t)
+	f.Ships[1].Registration.Role = "SATELLITE"
+
+	sb, server := shipBot(t, f, "MOCK-2")
+	rememberVisits(t, sb)
+	const path = "/systems/X1-MK1/waypoints/X1-MK1-A1/shipyard"
+
+	check := func() {
+		t.Helper()
+
+		// Each check starts with no recorded prices, so only the visit log can gate it.
+		shipyards, err := store.OpenShipyardStore("", "", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sb.shipyards = shipyards
+		sb.watchShipyard()
+	}
+
+	check()
+	if n := server.Requests("GET", path); n != 1 {
+		t.Fatalf("%d shipyard requests on the first visit, want 1", n)
+	}
+
+	sb.clock.Sleep(sb.marketRefresh / 2)
+	check()
+	if n := server.Requests("GET", path); n != 1 {
+		t.Fatalf("%d shipyard requests after a second visit within the refresh interval, want 1", n)
+	}
+
+	sb.clock.Sleep(sb.marketRefresh)
+	check()
+	if n := server.Requests("GET", path); n != 2 {
+		t.Fatalf("%d shipyard requests after a visit beyond the refresh interval, want 2", n)
+	}
+}
@@ -0,0 +1,61 @@
+package bot
+
+import (
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/ledger"
+)
+
+/*
+🧾 Credit audit
+*/
+
+const (
+	// creditDriftThreshold is how far, in credits, the tracked credits may drift from the agent's actual balance
+	// before the drift is reported.
+	creditDriftThreshold int64 = 1000
+	// driftHistory is the most recent ledger entries logged with a drift.
+	driftHistory = 10
+)
+
+// AuditCredits compares the credits the bot tracks from transaction responses with a fresh fetch of the agent,
+// and resets them to the actual balance. A drift beyond the threshold, from a missed or misordered response or
+// from credits spent outside the bot, is logged with the ledger's recent entries and published as a
+// CreditsDrifted event.
+func (ab *AgentBot) AuditCredits() {
+	drift, ok, err := ab.agent.Audit()
+	if err != nil {
+		ab.logger.Error("🧾 Error auditing credits.", "error", err)
+		return
+	}
+	if !ok {
+		ab.logger.Debug("🧾 Credits changed during the audit. Skipping.")
+		return
+	}
+
+	ab.bus.Publish(event.Event{Type: event.AgentUpdated, Data: ab.agent.Agent()})
+
+	if amount := drift.Drift(); amount <= creditDriftThreshold && amount >= -creditDriftThreshold {
+		return
+	}
+
+	ab.logger.Warn("🧾 Credits drifted from the agent's balance. Reset to the balance.", "tracked", drift.Tracked, "actual", drift.Actual, "drift", drift.Drift())
+	for _, entry := range ab.recentEntries() {
+		ab.logger.Warn("🧾 Recent transaction.", "at", entry.At.Format("15:04:05"), "kind", entry.Kind, "ship", entry.Ship, "symbol", entry.Symbol, "units", entry.Units, "amount", entry.Amount)
+	}
+
+	ab.bus.Publish(event.Event{Type: event.CreditsDrifted, Data: drift})
+}
+
+// recentEntries returns the ledger's latest entries, oldest first, or none without a ledger.
+func (ab *AgentBot) recentEntries() []ledger.Entry {
+	if ab.ledger == nil {
+		return nil
+	}
+
+	entries := ab.ledger.Entries()
+	if len(entries) > driftHistory {
+		entries = entries[len(entries)-driftHistory:]
+	}
+
+	return entries
+}
@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/charmbracelet/log"
+)
+
+func TestAuditCreditsCorrectsDrift(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		tracked m.Credits
+		alert   bool
+	}{
+		// A sale was applied twice, so the bot believes it has more than it does.
+		{name: "drifted", tracked: 150000, alert: true},
+		{name: "within threshold", tracked: 100500},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ab, server, _ := agentBot(t, fixture(t))
+			var logged bytes.Buffer
+			ab.logger = log.New(&logged)
+			events := ab.bus.Subscribe(16)
+
+			agent := server.Agent()
+			agent.Credits = tc.tracked
+			ab.agent.Update(agent)
+
+			ab.AuditCredits()
+
+			if credits := ab.agent.Credits(); credits != server.Agent().Credits {
+				t.Fatalf("tracked %d credits after the audit, want the agent's %d", credits, server.Agent().Credits)
+			}
+
+			var drifted []m.CreditDrift
+			for drained := false; !drained; {
+				select {
+				case e := <-events:
+					if e.Type == event.CreditsDrifted {
+						drifted = append(drifted, e.Data.(m.CreditDrift))
+					}
+				default:
+					drained = true
+				}
+			}
+
+			if !tc.alert {
+				if len(drifted) != 0 || logged.Len() != 0 {
+					t.Fatalf("alerted %v and logged %q for a drift within the threshold", drifted, logged.String())
+				}
+				return
+			}
+
+			want := m.CreditDrift{Tracked: tc.tracked, Actual: server.Agent().Credits}
+			if len(drifted) != 1 || drifted[0] != want {
+				t.Fatalf("CreditsDrifted events %+v, want one of %+v", drifted, want)
+			}
+			if !strings.HasPrefix(logged.String(), "WARN") || !strings.Contains(logged.String(), "drift=-50000") {
+				t.Fatalf("logged %q, want a warning of the -50000 drift", logged.String())
+			}
+		})
+	}
+}
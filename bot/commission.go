@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"errors"
+	"math"
+
+	"github.com/GeoffreyDick/gogarin/lib"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+/*
+🆕 Commission
+*/
+
+// errNoUnclaimedMarketplace is returned when every marketplace in a satellite's system is claimed.
+var errNoUnclaimedMarketplace = errors.New("every marketplace has a satellite")
+
+// commission gets a ship just bought underway on its role's initial missions, without waiting for the
+// reconcile loop to find it. Ships left to manual control, and ships bought before the fleet is launched,
+// are left to the reconcile loop.
+func (ab *AgentBot) commission(ship m.Ship) {
+	ab.mu.Lock()
+	wake := ab.wake
+	ab.mu.Unlock()
+
+	if wake == nil {
+		return
+	}
+
+	if !ab.controls(ship) {
+		ab.leaveToManual(ship)
+		return
+	}
+
+	if !ab.markWoken(ship.Symbol) {
+		return
+	}
+
+	initial := ab.initialMissions(ship)
+
+	names := make([]string, 0, len(initial))
+	for _, mission := range initial {
+		names = append(names, mission.Name)
+	}
+	ab.logger.Info("🆕 Ship commissioned. Waking...", "ship", ship.Symbol, "role", ship.Registration.Role, "missions", names)
+
+	wake(ship, initial...)
+}
+
+// initialMissions returns the missions a ship just bought starts with, by role: excavators head for the best
+// mining target, haulers wait at the asteroid field for cargo, and satellites station themselves at a
+// marketplace no other satellite has claimed. Other roles go straight to their strategy.
+func (ab *AgentBot) initialMissions(ship m.Ship) []Mission {
+	switch ship.Registration.Role {
+	case "EXCAVATOR":
+		return []Mission{miningTargetMission}
+	case "HAULER":
+		return []Mission{miningTargetMission, idleMission}
+	case "SATELLITE":
+		marketplace, err := ab.unclaimedMarketplace(ship)
+		if err != nil {
+			ab.logger.Warn("🆕 No unclaimed marketplace. Claiming the nearest.", "ship", ship.Symbol, "error", err)
+			return []Mission{nearestMarketMission}
+		}
+		return []Mission{navigateMission(marketplace)}
+	}
+
+	return nil
+}
+
+// unclaimedMarketplace returns the marketplace in a ship's system nearest to it that no other satellite is at
+// or flying to.
+func (ab *AgentBot) unclaimedMarketplace(ship m.Ship) (string, error) {
+	waypoints, err := ab.systems.Waypoints(ship.Nav.SystemSymbol)
+	if err != nil {
+		return "", err
+	}
+
+	ships, err := ab.reconciler.GetMyShips()
+	if err != nil {
+		return "", err
+	}
+
+	claimed := make(map[string]bool)
+	for _, other := range *ships {
+		if other.Symbol != ship.Symbol && other.Registration.Role == "SATELLITE" {
+			claimed[other.Nav.Route.Destination.Symbol] = true
+		}
+	}
+
+	from := lib.NewCoordinate(ship.Nav.Route.Destination.X, ship.Nav.Route.Destination.Y)
+
+	nearest, distance := "", math.Inf(1)
+	for _, waypoint := range waypoints {
+		if !waypoint.HasTrait("MARKETPLACE") || claimed[waypoint.Symbol] {
+			continue
+		}
+
+		if d := lib.Distance(from, lib.NewCoordinate(waypoint.X, waypoint.Y)); d < distance {
+			nearest, distance = waypoint.Symbol, d
+		}
+	}
+
+	if nearest == "" {
+		return "", errNoUnclaimedMarketplace
+	}
+
+	return nearest, nil
+}
@@ -0,0 +1,196 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/boterr"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/lib"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+/*
+🔧 Condition
+*/
+
+// repairRetry is how long a ship that was sent to be repaired, and is still critical, waits before it is sent
+// again, so a ship that cannot be repaired goes back to work in between.
+const repairRetry = 1 * time.Hour
+
+// errNoShipyard is returned when a ship's system has no shipyard to be repaired at.
+var errNoShipyard = errors.New("no shipyard in the system")
+
+// conditionLevel is how far a ship's component has dropped through the condition thresholds.
+type conditionLevel int
+
+const (
+	conditionSound conditionLevel = iota
+	conditionWarn
+	conditionCritical
+)
+
+// conditionLevelOf returns the level of a component's condition against the thresholds, with the threshold
+// it dropped below. A zero threshold is never crossed.
+func conditionLevelOf(condition int, thresholds config.ConditionConfig) (conditionLevel, int) {
+	switch {
+	case condition < thresholds.Critical:
+		return conditionCritical, thresholds.Critical
+	case condition < thresholds.Warn:
+		return conditionWarn, thresholds.Warn
+	default:
+		return conditionSound, 0
+	}
+}
+
+// CheckCondition compares a ship's frame, reactor, and engine condition with the thresholds. A
+// ConditionDegraded event is published each time a component drops into a worse level, not each time it is
+// seen there; a component found repaired back above a threshold is announced again if it drops below it.
+func (ab *AgentBot) CheckCondition(ship m.Ship) {
+	thresholds := ab.config().Condition
+
+	for _, c := range ship.Conditions() {
+		level, threshold := conditionLevelOf(c.Condition, thresholds)
+
+		key := ship.Symbol + "/" + c.Component
+		ab.mu.Lock()
+		previous := ab.conditions[key]
+		ab.conditions[key] = level
+		ab.mu.Unlock()
+
+		if level <= previous {
+			continue
+		}
+
+		degraded := m.ConditionDegraded{Ship: ship.Symbol, Component: c.Component, Condition: c.Condition, Level: m.ConditionWarn, Threshold: threshold}
+		args := []interface{}{"ship", ship.Symbol, "component", c.Component, "condition", c.Condition, "threshold", threshold}
+		if level == conditionCritical {
+			degraded.Level = m.ConditionCritical
+			ab.logger.Error("🔧 Ship condition critical.", args...)
+		} else {
+			ab.logger.Warn("🔧 Ship condition degraded.", args...)
+		}
+		ab.bus.Publish(event.Event{Type: event.ConditionDegraded, Ship: ship.Symbol, Message: c.Component, Data: degraded})
+	}
+}
+
+// needsRepair checks if a ship has a component below the critical threshold and was not sent to be repaired
+// within repairRetry. A ship that does is marked as sent.
+func (ab *AgentBot) needsRepair(ship m.Ship) bool {
+	thresholds := ab.config().Condition
+
+	critical := false
+	for _, c := range ship.Conditions() {
+		if level, _ := conditionLevelOf(c.Condition, thresholds); level == conditionCritical {
+			critical = true
+			break
+		}
+	}
+	if !critical {
+		return false
+	}
+
+	now := ab.clock.Now()
+
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	if sent, ok := ab.repairs[ship.Symbol]; ok && now.Sub(sent) < repairRetry {
+		return false
+	}
+	ab.repairs[ship.Symbol] = now
+
+	return true
+}
+
+// repairMission sends a ship to be repaired.
+func repairMission() Mission {
+	return Mission{
+		Name: "Repair",
+		Run:  func(sb *ShipBot, sbCh chan ShipBot) { sb.Repair(sbCh) },
+	}
+}
+
+// Repair flies the ship to the nearest shipyard in its system and has its frame, reactor, and engine repaired,
+// reserving the quoted price first.
+func (sb *ShipBot) Repair(sbCh chan ShipBot) {
+	defer sb.Report(sbCh)
+
+	shipyard, err := sb.nearestShipyard()
+	if err != nil {
+		sb.logger.Error("🔧 Error finding a shipyard to repair at.", "error", err)
+		sb.Fail(boterr.Wrap(err, "system", sb.ship.Nav.SystemSymbol))
+		return
+	}
+
+	sb.logger.Info("🔧 Flying to shipyard for repairs...", "waypoint", shipyard)
+	if err := sb.NavigateShip(shipyard); err != nil {
+		sb.Fail(err)
+		return
+	}
+
+	if err := sb.dockIfNeeded(); err != nil {
+		sb.Fail(boterr.Wrap(err, "waypoint", shipyard))
+		return
+	}
+
+	estimate, err := sb.client.GetRepairShip(sb.ship.Symbol)
+	if err != nil {
+		sb.logger.Error("🔧 Error pricing repairs.", "waypoint", shipyard, "error", err)
+		sb.Fail(boterr.Wrap(err, "waypoint", shipyard))
+		return
+	}
+
+	release, err := sb.agent.ReserveCredits(estimate.TotalPrice)
+	if err != nil {
+		sb.logger.Warn("🔧 Cannot afford repairs.", "waypoint", shipyard, "totalPrice", estimate.TotalPrice, "error", err)
+		sb.Fail(boterr.Wrap(err, "waypoint", shipyard))
+		return
+	}
+	defer release()
+
+	res, err := sb.client.RepairShip(sb.ship.Symbol)
+	if err != nil {
+		sb.logger.Error("🔧 Error repairing ship.", "waypoint", shipyard, "error", err)
+		sb.Fail(boterr.Wrap(err, "waypoint", shipyard))
+		return
+	}
+
+	sb.ship.Frame = res.Ship.Frame
+	sb.ship.Reactor = res.Ship.Reactor
+	sb.ship.Engine = res.Ship.Engine
+	sb.agent.Update(res.Agent)
+	sb.logger.Info("🔧 Ship repaired.", "frame", sb.ship.Frame.Condition, "reactor", sb.ship.Reactor.Condition,
+		"engine", sb.ship.Engine.Condition, "totalPrice", res.Transaction.TotalPrice)
+	sb.bus.Publish(event.Event{Type: event.ShipRepaired, Ship: sb.ship.Symbol, MissionID: sb.missionID, Data: res.Transaction})
+	sb.bus.Publish(event.Event{Type: event.AgentUpdated, Data: res.Agent})
+}
+
+// nearestShipyard returns the shipyard in the ship's system nearest to it.
+func (sb *ShipBot) nearestShipyard() (string, error) {
+	waypoints, err := sb.systems.Waypoints(sb.ship.Nav.SystemSymbol)
+	if err != nil {
+		return "", err
+	}
+
+	current, err := sb.systems.Waypoint(sb.ship.Nav.WaypointSymbol)
+	if err != nil {
+		return "", err
+	}
+
+	shipyards := lib.Filter(waypoints, func(waypoint m.Waypoint) bool {
+		return waypoint.HasTrait("SHIPYARD")
+	})
+	if len(shipyards) == 0 {
+		return "", errNoShipyard
+	}
+
+	nearest, err := lib.NearestWaypoint(current, &shipyards)
+	if err != nil {
+		return "", fmt.Errorf("finding nearest shipyard: %w", err)
+	}
+
+	return nearest.Symbol, nil
+}
@@ -0,0 +1,186 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+// degradations drains the ConditionDegraded events published so far.
+func degradations(events <-chan event.Event) []m.ConditionDegraded {
+	var degraded []m.ConditionDegraded
+	for {
+		select {
+		case e := <-events:
+			if e.Type == event.ConditionDegraded {
+				degraded = append(degraded, e.Data.(m.ConditionDegraded))
+			}
+		default:
+			return degraded
+		}
+	}
+}
+
+func TestConditionAlertsOncePerCrossing(t *testing.T) {
+	f := fixture(t)
+	ab, _, _ := agentBot(t, f)
+	events := ab.bus.Subscribe(64)
+	ship := f.Ships[1]
+
+	// The frame wears down through the warning threshold of 50 and the critical one of 25, seen at every step.
+	var levels []string
+	for _, condition := range []int{90, 60, 50, 49, 45, 30, 25, 24, 10, 10, 5} {
+		ship.Frame.Condition = condition
+		ab.CheckCondition(ship)
+		for _, degraded := range degradations(events) {
+			if degraded.Ship != "MOCK-2" || degraded.Component != m.ComponentFrame || degraded.Condition != condition {
+				t.Fatalf("degraded %+v at condition %d, want MOCK-2's frame", degraded, condition)
+			}
+			levels = append(levels, degraded.Level)
+		}
+	}
+	if len(levels) != 2 || levels[0] != m.ConditionWarn || levels[1] != m.ConditionCritical {
+		t.Fatalf("alerts %v, want one warning and one critical alert", levels)
+	}
+
+	// Repaired, the frame alerts again once it wears back down, and the engine alerts on its own account.
+	ship.Frame.Condition = m.FullCondition
+	ab.CheckCondition(ship)
+	if degraded := degradations(events); len(degraded) != 0 {
+		t.Fatalf("alerts %+v on repair, want none", degraded)
+	}
+	ship.Frame.Condition = 40
+	ship.Engine.Condition = 20
+	ab.CheckCondition(ship)
+	ab.CheckCondition(ship)
+	degraded := degradations(events)
+	if len(degraded) != 2 {
+		t.Fatalf("alerts %+v, want the frame's warning and the engine's critical alert once each", degraded)
+	}
+	for _, d := range degraded {
+		switch {
+		case d.Component == m.ComponentFrame && d.Level == m.ConditionWarn && d.Threshold == 50:
+		case d.Component == m.ComponentEngine && d.Level == m.ConditionCritical && d.Threshold == 25:
+		default:
+			t.Fatalf("unexpected alert %+v", d)
+		}
+	}
+}
+
+func TestZeroConditionThresholdIsNeverCrossed(t *testing.T) {
+	thresholds := config.ConditionConfig{Warn: 50}
+
+	if level, _ := conditionLevelOf(0, thresholds); level != conditionWarn {
+		t.Fatalf("level at 0 with no critical threshold = %d, want a warning", level)
+	}
+	if level, _ := conditionLevelOf(0, config.ConditionConfig{}); level != conditionSound {
+		t.Fatalf("level at 0 with no thresholds = %d, want sound", level)
+	}
+}
+
+func TestCriticalShipIsRepairedBeforeNewWork(t *testing.T) {
+	ab, server, fake := agentBot(t, fixture(t))
+	sb := ab.newShipBot(t, server, "MOCK-2")
+	sb.arrival = nil
+	sb.ship.Engine.Condition = 20
+	started := ab.bus.Subscribe(64)
+
+	ab.DispatchNext(*sb, make(chan ShipBot, 1))
+	if mission := startedMission(t, started, "MOCK-2"); mission != "Repair" {
+		t.Fatalf("critical ship sent on %q, want to be repaired", mission)
+	}
+
+	// Still critical, the ship goes back to work until an hour has passed since it was sent.
+	if ab.needsRepair(*sb.ship) {
+		t.Fatal("ship sent to be repaired again straight away")
+	}
+	fake.Advance(repairRetry)
+	if !ab.needsRepair(*sb.ship) {
+		t.Fatal("ship still critical after an hour was not sent to be repaired again")
+	}
+
+	sb.ship.Engine.Condition = 30
+	fake.Advance(repairRetry)
+	if ab.needsRepair(*sb.ship) {
+		t.Fatal("ship above the critical threshold sent to be repaired")
+	}
+}
+
+// repairClient quotes and performs repairs at a fixed price, which the mock server does not offer.
+type repairClient struct {
+	api.ClientAPI
+	price   int64
+	repairs int
+}
+
+func (c *repairClient) GetRepairShip(shipSymbol string) (*m.RepairTransaction, error) {
+	return &m.RepairTransaction{ShipSymbol: shipSymbol, TotalPrice: c.price}, nil
+}
+
+func (c *repairClient) RepairShip(shipSymbol string) (*api.RepairShipResponse, error) {
+	c.repairs++
+
+	ship, err := c.GetShip(shipSymbol)
+	if err != nil {
+		return nil, err
+	}
+	agent, err := c.GetMyAgent()
+	if err != nil {
+		return nil, err
+	}
+	ship.Frame.Condition = m.FullCondition
+	ship.Reactor.Condition = m.FullCondition
+	ship.Engine.Condition = m.FullCondition
+
+	return &api.RepairShipResponse{Agent: *agent, Ship: *ship, Transaction: m.RepairTransaction{ShipSymbol: shipSymbol, TotalPrice: c.price}}, nil
+}
+
+func TestRepairReservesTheQuotedPrice(t *testing.T) {
+	tests := []struct {
+		name    string
+		price   int64
+		repairs int
+	}{
+		{"affordable", 500, 1},
+		{"insufficient credits", 5000, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := fixture(t)
+			f.Agent.Credits = 1000
+			ab, server, _ := agentBot(t, f)
+			sb := ab.newShipBot(t, server, "MOCK-2")
+			sb.arrival = nil
+			sb.ship.Engine.Condition = 20
+			client := &repairClient{ClientAPI: sb.client, price: tt.price}
+			sb.client = client
+			available := ab.agent.Available()
+
+			sbCh := make(chan ShipBot, 1)
+			sb.Repair(sbCh)
+			reported := <-sbCh
+
+			if client.repairs != tt.repairs {
+				t.Fatalf("repaired %d times, want %d", client.repairs, tt.repairs)
+			}
+			if tt.repairs == 0 {
+				if !errors.Is(reported.failure, store.ErrInsufficientCredits) {
+					t.Fatalf("failure = %v, want %v", reported.failure, store.ErrInsufficientCredits)
+				}
+			} else if reported.failure != nil {
+				t.Fatalf("repair failed: %s", reported.failure)
+			} else if reported.ship.Engine.Condition != m.FullCondition {
+				t.Fatalf("engine condition %d after repair, want %d", reported.ship.Engine.Condition, m.FullCondition)
+			}
+			if got := ab.agent.Available(); got != available {
+				t.Fatalf("available credits %d after the mission, want the reservation released back to %d", got, available)
+			}
+		})
+	}
+}
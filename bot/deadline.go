@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+/*
+⏳ Deadlines
+*/
+
+const (
+	// deadlineWarn is the time left before an undelivered contract's deadline from which it is logged as a
+	// warning.
+	deadlineWarn = 6 * time.Hour
+	// deadlineError is the time left before an undelivered contract's deadline from which it is logged as an
+	// error.
+	deadlineError = 1 * time.Hour
+)
+
+// deadlineUrgency is how close an undelivered contract is to its deadline.
+type deadlineUrgency int
+
+const (
+	urgencyNone deadlineUrgency = iota
+	urgencyWarn
+	urgencyError
+)
+
+// deadlineWatch is what the AgentBot has seen of an accepted contract's progress.
+type deadlineWatch struct {
+	// since is when the contract was first tracked, and delivered the units fulfilled then, so the delivery
+	// rate counts only deliveries the bot has seen happen.
+	since     time.Time
+	delivered int
+	// urgency is the highest urgency already announced.
+	urgency deadlineUrgency
+}
+
+// TrackDeadlines logs how each accepted, undelivered contract stands against its deadline, escalating to a
+// warning within 6 hours of it and to an error within the hour. A DeadlineApproaching event is published
+// each time a contract crosses into a more urgent level, and the progress of every tracked contract is
+// published as a ContractProgressUpdated event.
+func (ab *AgentBot) TrackDeadlines(contracts []m.Contract, now time.Time) []m.ContractProgress {
+	var progress []m.ContractProgress
+	tracked := make(map[string]bool)
+
+	for _, contract := range contracts {
+		if !contract.Accepted || contract.Fulfilled || contract.IsExpired(now) {
+			continue
+		}
+		tracked[contract.ID] = true
+
+		delivered := deliveredUnits(contract)
+		watch, ok := ab.deadlines[contract.ID]
+		if !ok {
+			watch = &deadlineWatch{since: now, delivered: delivered}
+			ab.deadlines[contract.ID] = watch
+		}
+
+		p := m.ContractProgress{
+			ContractID: contract.ID,
+			Deadline:   contract.Terms.Deadline,
+			Remaining:  contract.Terms.Deadline.Sub(now),
+			Completion: contract.Completion(),
+		}
+		p.OnTrack = watch.onTrack(contract, delivered, p.Remaining, now)
+		progress = append(progress, p)
+
+		urgency := urgencyOf(p)
+		args := []interface{}{"contract", p.ContractID, "remaining", p.Remaining.Round(time.Minute),
+			"completion", fmt.Sprintf("%.0f%%", p.Completion*100), "onTrack", p.OnTrack}
+		switch urgency {
+		case urgencyError:
+			ab.logger.Error("⏳ Contract deadline imminent.", args...)
+		case urgencyWarn:
+			ab.logger.Warn("⏳ Contract deadline approaching.", args...)
+		default:
+			ab.logger.Info("⏳ Contract deadline.", args...)
+		}
+
+		if urgency > watch.urgency {
+			watch.urgency = urgency
+			ab.bus.Publish(event.Event{Type: event.DeadlineApproaching, Message: p.ContractID, Data: p})
+		}
+	}
+
+	for id := range ab.deadlines {
+		if !tracked[id] {
+			delete(ab.deadlines, id)
+		}
+	}
+
+	ab.bus.Publish(event.Event{Type: event.ContractProgressUpdated, Data: progress})
+
+	return progress
+}
+
+// urgencyOf returns how urgent a contract's deadline is. A delivered contract has no urgency.
+func urgencyOf(p m.ContractProgress) deadlineUrgency {
+	switch {
+	case p.Completion >= 1:
+		return urgencyNone
+	case p.Remaining < deadlineError:
+		return urgencyError
+	case p.Remaining < deadlineWarn:
+		return urgencyWarn
+	default:
+		return urgencyNone
+	}
+}
+
+// onTrack checks if the rate at which a contract's units have been delivered since it was first tracked
+// finishes the contract before its deadline. Until a delivery is seen there is no rate, and the contract is
+// on track while its deadline is more than 6 hours away.
+func (w *deadlineWatch) onTrack(contract m.Contract, delivered int, remaining time.Duration, now time.Time) bool {
+	left := requiredUnits(contract) - delivered
+	switch {
+	case left <= 0:
+		return true
+	case remaining <= 0:
+		return false
+	}
+
+	units := delivered - w.delivered
+	elapsed := now.Sub(w.since)
+	if units <= 0 || elapsed <= 0 {
+		return remaining > deadlineWarn
+	}
+
+	perUnit := elapsed / time.Duration(units)
+
+	return time.Duration(left)*perUnit <= remaining
+}
+
+// deliveredUnits returns the units delivered to a contract across its deliverables.
+func deliveredUnits(contract m.Contract) int {
+	var units int
+	for _, good := range contract.Terms.Deliver {
+		units += good.UnitsFulfilled
+	}
+
+	return units
+}
+
+// requiredUnits returns the units a contract requires across its deliverables.
+func requiredUnits(contract m.Contract) int {
+	var units int
+	for _, good := range contract.Terms.Deliver {
+		units += good.UnitsRequired
+	}
+
+	return units
+}
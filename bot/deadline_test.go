@@ -0,0 +1,76 @@
+package bot
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/charmbracelet/log"
+)
+
+func TestTrackDeadlinesEscalatesOncePerThreshold(t *testing.T) {
+	ab, _, fake := agentBot(t, fixture(t))
+	var logged bytes.Buffer
+	ab.logger = log.New(&logged)
+	events := ab.bus.Subscribe(64)
+
+	contract := m.Contract{
+		ID:       "contract-1",
+		Accepted: true,
+		Terms: m.ContractTerms{
+			Deadline: epoch.Add(8 * time.Hour),
+			Deliver:  []m.ContractDeliverGood{{TradeSymbol: "IRON_ORE", UnitsRequired: 100, UnitsFulfilled: 20}},
+		},
+	}
+
+	for _, tc := range []struct {
+		name    string
+		advance time.Duration
+		level   string
+		// announced is how many DeadlineApproaching events the reconcile publishes.
+		announced int
+	}{
+		{name: "8h left", level: "INFO"},
+		{name: "5h left", advance: 3 * time.Hour, level: "WARN", announced: 1},
+		{name: "4h left", advance: time.Hour, level: "WARN"},
+		{name: "30m left", advance: 3*time.Hour + 30*time.Minute, level: "ERRO", announced: 1},
+		{name: "10m left", advance: 20 * time.Minute, level: "ERRO"},
+	} {
+		fake.Advance(tc.advance)
+		logged.Reset()
+
+		progress := ab.TrackDeadlines([]m.Contract{contract}, fake.Now())
+		if len(progress) != 1 || progress[0].Remaining != contract.Terms.Deadline.Sub(fake.Now()) {
+			t.Fatalf("%s: progress %+v", tc.name, progress)
+		}
+		if !strings.HasPrefix(logged.String(), tc.level) {
+			t.Errorf("%s: logged %q, want %s", tc.name, logged.String(), tc.level)
+		}
+
+		var announced int
+		for drained := false; !drained; {
+			select {
+			case e := <-events:
+				if e.Type == event.DeadlineApproaching {
+					announced++
+				}
+			default:
+				drained = true
+			}
+		}
+		if announced != tc.announced {
+			t.Errorf("%s: %d DeadlineApproaching events, want %d", tc.name, announced, tc.announced)
+		}
+	}
+
+	// Delivering the rest ends the countdown, however close the deadline.
+	contract.Terms.Deliver[0].UnitsFulfilled = 100
+	logged.Reset()
+	ab.TrackDeadlines([]m.Contract{contract}, fake.Now())
+	if !strings.HasPrefix(logged.String(), "INFO") {
+		t.Errorf("delivered: logged %q, want INFO", logged.String())
+	}
+}
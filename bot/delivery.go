@@ -0,0 +1,437 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/boterr"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/lib"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+/*
+🗺️ Delivery plan
+*/
+
+// PlannedDelivery is one contract deliverable the agent still has to deliver, and where it goes.
+type PlannedDelivery struct {
+	ContractID  string `json:"contractId"`
+	TradeSymbol string `json:"tradeSymbol"`
+	Destination string `json:"destination"`
+	Required    int    `json:"required"`
+	Fulfilled   int    `json:"fulfilled"`
+	// Accepted is whether the contract has been accepted. Goods for contracts that are not are still mined,
+	// but cannot be delivered yet.
+	Accepted bool `json:"accepted"`
+	// UnitPayment is what the contract pays per unit delivered, across all its deliverables.
+	UnitPayment int64 `json:"unitPayment"`
+}
+
+// Remaining returns the units still to be delivered.
+func (d PlannedDelivery) Remaining() int {
+	return d.Required - d.Fulfilled
+}
+
+// DeliveryPlan is every deliverable the agent's live contracts still need, in the order of the contracts
+// and their terms. Unlike a list of goods, it keeps where each good is delivered to, so a contract sending
+// one good to one waypoint and another good elsewhere is delivered leg by leg.
+type DeliveryPlan []PlannedDelivery
+
+// NewDeliveryPlan plans the deliverables of contracts that are neither fulfilled nor expired at now.
+func NewDeliveryPlan(contracts []m.Contract, now time.Time) DeliveryPlan {
+	var plan DeliveryPlan
+
+	for _, contract := range contracts {
+		if contract.Fulfilled || contract.IsExpired(now) {
+			continue
+		}
+
+		for _, good := range contract.Terms.Deliver {
+			if good.UnitsFulfilled >= good.UnitsRequired {
+				continue
+			}
+
+			plan = append(plan, PlannedDelivery{
+				ContractID:  contract.ID,
+				TradeSymbol: good.TradeSymbol,
+				Destination: good.DestinationSymbol,
+				Required:    good.UnitsRequired,
+				Fulfilled:   good.UnitsFulfilled,
+				Accepted:    contract.Accepted,
+				UnitPayment: unitPayment(contract),
+			})
+		}
+	}
+
+	return plan
+}
+
+// Goods returns the trade goods the plan needs, in plan order.
+func (p DeliveryPlan) Goods() []string {
+	var goods []string
+
+	for _, delivery := range p {
+		if !lib.Contains(goods, delivery.TradeSymbol) {
+			goods = append(goods, delivery.TradeSymbol)
+		}
+	}
+
+	return goods
+}
+
+// Needs checks if any deliverable of the plan is the trade good symbol.
+func (p DeliveryPlan) Needs(symbol string) bool {
+	for _, delivery := range p {
+		if delivery.TradeSymbol == symbol {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String lists the plan's deliverables as units of a good to a destination.
+func (p DeliveryPlan) String() string {
+	legs := make([]string, 0, len(p))
+	for _, delivery := range p {
+		legs = append(legs, fmt.Sprintf("%d %s to %s", delivery.Remaining(), delivery.TradeSymbol, delivery.Destination))
+	}
+
+	return strings.Join(legs, ", ")
+}
+
+// Deliveries returns the deliveries cargo can make to the plan's accepted contracts. A good needed by several
+// deliverables is allocated to them in plan order, each up to what it still requires.
+func (p DeliveryPlan) Deliveries(cargo m.ShipCargo) []Delivery {
+	allocated := make(map[string]int)
+
+	var deliveries []Delivery
+	for _, planned := range p {
+		if !planned.Accepted {
+			continue
+		}
+
+		units := cargo.UnitsOf(planned.TradeSymbol) - allocated[planned.TradeSymbol]
+		if units > planned.Remaining() {
+			units = planned.Remaining()
+		}
+		if units <= 0 {
+			continue
+		}
+		allocated[planned.TradeSymbol] += units
+
+		deliveries = append(deliveries, Delivery{
+			ContractID:  planned.ContractID,
+			TradeSymbol: planned.TradeSymbol,
+			Destination: planned.Destination,
+			Units:       units,
+			Remaining:   planned.Remaining(),
+		})
+	}
+
+	return deliveries
+}
+
+// deliveryRoute returns the deliveries a ship makes on the trip delivering its quota: the quota's own, then
+// every other good aboard that the plan sends somewhere and the ship can claim a quota for, so goods for
+// other deliverables ride along instead of being sold.
+func (sb *ShipBot) deliveryRoute(first Delivery, plan DeliveryPlan, contracts []m.Contract) []Delivery {
+	route := []Delivery{first}
+
+	for _, delivery := range plan.Deliveries(sb.ship.Cargo) {
+		if delivery.ContractID == first.ContractID && delivery.TradeSymbol == first.TradeSymbol {
+			continue
+		}
+
+		good, ok := deliverGood(contracts, delivery.ContractID, delivery.TradeSymbol)
+		if !ok {
+			continue
+		}
+
+		// Units of the quota's own good that go to the quota are not aboard for another deliverable.
+		if delivery.TradeSymbol == first.TradeSymbol {
+			delivery.Units -= first.Units
+			if delivery.Units <= 0 {
+				continue
+			}
+		}
+
+		quota, ok := sb.quotas.Claim(delivery.ContractID, good, sb.ship.Symbol, delivery.Units, sb.distanceTo(delivery.Destination))
+		if !ok {
+			continue
+		}
+		delivery.Units = quota.Units
+
+		route = append(route, delivery)
+	}
+
+	return route
+}
+
+// deliverGood returns a contract's deliverable of a trade good.
+func deliverGood(contracts []m.Contract, contractID string, tradeSymbol string) (m.ContractDeliverGood, bool) {
+	for _, contract := range contracts {
+		if contract.ID != contractID {
+			continue
+		}
+
+		for _, good := range contract.Terms.Deliver {
+			if good.TradeSymbol == tradeSymbol {
+				return good, true
+			}
+		}
+	}
+
+	return m.ContractDeliverGood{}, false
+}
+
+// deliverRouteMission delivers cargo to several destinations in one trip.
+func deliverRouteMission(deliveries []Delivery) Mission {
+	var goods, destinations []string
+	for _, delivery := range deliveries {
+		if !lib.Contains(goods, delivery.TradeSymbol) {
+			goods = append(goods, delivery.TradeSymbol)
+		}
+		if !lib.Contains(destinations, delivery.Destination) {
+			destinations = append(destinations, delivery.Destination)
+		}
+	}
+
+	return Mission{
+		Name: fmt.Sprintf("%s %s to %s", deliverMissionPrefix, strings.Join(goods, ", "), strings.Join(destinations, ", ")),
+		Run:  func(sb *ShipBot, sbCh chan ShipBot) { sb.DeliverRoute(deliveries, sbCh) },
+	}
+}
+
+// deliveryStops groups deliveries by destination, ordering the destinations by the route optimizer from the
+// ship's waypoint. Destinations whose coordinates are unknown keep the order they were given in.
+func (sb *ShipBot) deliveryStops(deliveries []Delivery) [][]Delivery {
+	var destinations []string
+	byDestination := make(map[string][]Delivery)
+	for _, delivery := range deliveries {
+		if _, ok := byDestination[delivery.Destination]; !ok {
+			destinations = append(destinations, delivery.Destination)
+		}
+		byDestination[delivery.Destination] = append(byDestination[delivery.Destination], delivery)
+	}
+
+	if start, err := sb.systems.Waypoint(sb.ship.Nav.WaypointSymbol); err == nil {
+		waypoints := make([]m.Waypoint, 0, len(destinations))
+		for _, destination := range destinations {
+			waypoint, err := sb.systems.Waypoint(destination)
+			if err != nil {
+				waypoints = nil
+				break
+			}
+			waypoints = append(waypoints, *waypoint)
+		}
+
+		if waypoints != nil {
+			destinations = destinations[:0]
+			for _, waypoint := range lib.PlanTour(start, waypoints) {
+				destinations = append(destinations, waypoint.Symbol)
+			}
+		}
+	}
+
+	stops := make([][]Delivery, 0, len(destinations))
+	for _, destination := range destinations {
+		stops = append(stops, byDestination[destination])
+	}
+
+	return stops
+}
+
+// DeliverRoute delivers cargo to several contract destinations in one trip, visiting them in the order the
+// route optimizer gives and delivering every good bound for a destination while docked there. Contracts
+// whose deliveries complete are fulfilled on the way.
+func (sb *ShipBot) DeliverRoute(deliveries []Delivery, sbCh chan ShipBot) {
+	stops := sb.deliveryStops(deliveries)
+
+	destinations := make([]string, 0, len(stops))
+	for _, stop := range stops {
+		destinations = append(destinations, stop[0].Destination)
+	}
+	sb.planRoute(destinations...)
+
+	var ordered []Delivery
+	for _, stop := range stops {
+		ordered = append(ordered, stop...)
+	}
+	sb.preview(sb.deliveryPlan(ordered))
+
+	for _, stop := range stops {
+		for _, delivery := range stop {
+			sb.journalBegin(journalDeliver, stepDelivering, delivery.ContractID, delivery)
+
+			contract, err := sb.deliver(delivery)
+			if err != nil {
+				sb.quotas.Release(sb.ship.Symbol)
+				sb.Abandon(err)
+				sb.Report(sbCh)
+				return
+			}
+
+			if contract.IsDeliverComplete() {
+				sb.journalStep(stepFulfilling, nil)
+				if err := sb.fulfill(delivery.ContractID); err != nil {
+					sb.Abandon(err)
+					sb.Report(sbCh)
+					return
+				}
+			}
+
+			sb.journalEnd()
+		}
+	}
+
+	sb.Report(sbCh)
+}
+
+// deliver takes contract goods to their destination and delivers them, returning the contract as the
+// delivery left it.
+func (sb *ShipBot) deliver(delivery Delivery) (*m.Contract, error) {
+	err := sb.NavigateShip(delivery.Destination)
+	if sb.ship.Nav.WaypointSymbol != delivery.Destination {
+		return nil, notReached(delivery.Destination, err)
+	}
+
+	if err := sb.dockIfNeeded(); err != nil {
+		sb.logger.Error("🚀 Error docking ship.", "error", err)
+		return nil, boterr.Wrap(err, "waypoint", delivery.Destination)
+	}
+
+	res, err := sb.client.DeliverContract(delivery.ContractID, sb.ship.Symbol, delivery.TradeSymbol, delivery.Units)
+	if err != nil {
+		sb.logger.Error("📜 Error delivering contract goods.", "contract", delivery.ContractID, "error", err)
+		return nil, boterr.Wrap(err, "contract", delivery.ContractID, "good", delivery.TradeSymbol, "units", delivery.Units, "waypoint", delivery.Destination)
+	}
+
+	sb.quotas.Delivered(sb.ship.Symbol, res.Contract, delivery.TradeSymbol, delivery.Units)
+	sb.tallyDelivery(res.Contract, delivery.Units)
+	sb.ship.Cargo = res.Cargo
+	sb.logger.Info("📜 Contract goods delivered.", "contract", delivery.ContractID, "symbol", delivery.TradeSymbol, "units", delivery.Units, "destination", delivery.Destination)
+	sb.bus.Publish(event.Event{Type: event.CargoDelivered, Ship: sb.ship.Symbol, Mission: sb.mission, MissionID: sb.missionID, Message: delivery.ContractID, Data: m.ShipCargoItem{Symbol: delivery.TradeSymbol, Units: delivery.Units}})
+
+	return &res.Contract, nil
+}
+
+// deliverOnHand delivers the cargo aboard that accepted contracts need at the waypoint, so a ship passing a
+// destination on another mission credits what it carries instead of carrying it past. Each delivery is claimed
+// as a quota first, so units other ships have set out to deliver are not delivered twice, and contracts the
+// deliveries complete are fulfilled. A ship in orbit docks to deliver and returns to orbit afterwards.
+// Delivery missions deliver on their own, so their ships are skipped.
+func deliverOnHand(sb *ShipBot, waypoint *m.Waypoint) error {
+	if strings.HasPrefix(sb.mission, deliverMissionPrefix) {
+		return nil
+	}
+
+	var deliveries []Delivery
+	for _, delivery := range sb.plan.Deliveries(sb.ship.Cargo) {
+		if delivery.Destination != waypoint.Symbol {
+			continue
+		}
+
+		if quota, ok := sb.claimOnHand(delivery); ok {
+			delivery.Units = quota
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	wasInOrbit := sb.ship.Nav.Status == "IN_ORBIT"
+	if wasInOrbit {
+		nav, err := sb.client.DockShip(sb.ship.Symbol)
+		if err != nil {
+			return err
+		}
+		sb.ship.Nav = *nav
+	}
+
+	for _, delivery := range deliveries {
+		sb.logger.Info("📜 Delivering cargo on hand.", "contract", delivery.ContractID, "symbol", delivery.TradeSymbol, "units", delivery.Units)
+
+		contract, err := sb.deliver(delivery)
+		if err != nil {
+			return err
+		}
+		sb.plan = sb.plan.delivered(delivery)
+
+		if contract.IsDeliverComplete() {
+			if err := sb.fulfill(delivery.ContractID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if wasInOrbit {
+		nav, err := sb.client.OrbitShip(sb.ship.Symbol)
+		if err != nil {
+			return err
+		}
+		sb.ship.Nav = *nav
+	}
+
+	return nil
+}
+
+// claimOnHand claims the units of a delivery the ship has on hand as part of its quota on the deliverable,
+// keeping any larger quota the ship already holds on it. It returns the units the ship may deliver now.
+func (sb *ShipBot) claimOnHand(delivery Delivery) (int, bool) {
+	want := delivery.Units
+	for _, quota := range sb.quotas.All() {
+		if quota.Ship == sb.ship.Symbol && quota.ContractID == delivery.ContractID && quota.TradeSymbol == delivery.TradeSymbol && quota.Units > want {
+			want = quota.Units
+		}
+	}
+
+	planned, ok := sb.plan.find(delivery)
+	if !ok {
+		return 0, false
+	}
+
+	good := m.ContractDeliverGood{
+		TradeSymbol:       planned.TradeSymbol,
+		DestinationSymbol: planned.Destination,
+		UnitsRequired:     planned.Required,
+		UnitsFulfilled:    planned.Fulfilled,
+	}
+	quota, ok := sb.quotas.Claim(delivery.ContractID, good, sb.ship.Symbol, want, 0)
+	if !ok {
+		return 0, false
+	}
+
+	if quota.Units < delivery.Units {
+		return quota.Units, true
+	}
+
+	return delivery.Units, true
+}
+
+// find returns the planned deliverable a delivery is made to.
+func (p DeliveryPlan) find(delivery Delivery) (PlannedDelivery, bool) {
+	for _, planned := range p {
+		if planned.ContractID == delivery.ContractID && planned.TradeSymbol == delivery.TradeSymbol && planned.Destination == delivery.Destination {
+			return planned, true
+		}
+	}
+
+	return PlannedDelivery{}, false
+}
+
+// delivered returns a copy of the plan with a delivery's units fulfilled.
+func (p DeliveryPlan) delivered(delivery Delivery) DeliveryPlan {
+	plan := append(DeliveryPlan(nil), p...)
+	for i := range plan {
+		if plan[i].ContractID == delivery.ContractID && plan[i].TradeSymbol == delivery.TradeSymbol && plan[i].Destination == delivery.Destination {
+			plan[i].Fulfilled += delivery.Units
+			break
+		}
+	}
+
+	return plan
+}
@@ -0,0 +1,120 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+func TestDeliverRouteCompletesBothLegs(t *testing.T) {
+	f := fixture(t)
+	f.Contracts[0].Accepted = true
+	f.Contracts[0].Terms.Deliver = []m.ContractDeliverGood{
+		{TradeSymbol: "IRON_ORE", DestinationSymbol: "X1-MK1-A1", UnitsRequired: 10},
+		{TradeSymbol: "QUARTZ_SAND", DestinationSymbol: "X1-MK1-C3", UnitsRequired: 5},
+	}
+	f.Ships[1].Nav.WaypointSymbol = "X1-MK1-B2"
+	f.Ships[1].Nav.Route.Destination.Symbol = "X1-MK1-B2"
+	f.Ships[1].Cargo = m.ShipCargo{Capacity: 30, Units: 17, Inventory: []m.ShipCargoItem{
+		{Symbol: "IRON_ORE", Units: 12},
+		{Symbol: "QUARTZ_SAND", Units: 5},
+	}}
+
+	sb, server := shipBot(t, f, "MOCK-2")
+	sb.arrival = nil
+	delivered := sb.bus.Subscribe(8)
+
+	plan := NewDeliveryPlan(server.Contracts(), epoch)
+	deliveries := plan.Deliveries(sb.ship.Cargo)
+	want := []Delivery{
+		{ContractID: "mock-contract-1", TradeSymbol: "IRON_ORE", Destination: "X1-MK1-A1", Units: 10, Remaining: 10},
+		{ContractID: "mock-contract-1", TradeSymbol: "QUARTZ_SAND", Destination: "X1-MK1-C3", Units: 5, Remaining: 5},
+	}
+	if !reflect.DeepEqual(deliveries, want) {
+		t.Fatalf("deliveries = %+v, want %+v", deliveries, want)
+	}
+
+	sbCh := make(chan ShipBot, 1)
+	sb.DeliverRoute(deliveries, sbCh)
+	if reported := <-sbCh; reported.failure != nil {
+		t.Fatalf("delivery route failed: %s", reported.failure)
+	}
+
+	// The moon is nearer the asteroid field than the planet, so its leg goes first.
+	var legs []string
+	for len(legs) < 2 {
+		if e := <-delivered; e.Type == event.CargoDelivered {
+			item := e.Data.(m.ShipCargoItem)
+			legs = append(legs, item.Symbol)
+			if item.Symbol == "IRON_ORE" && item.Units != 10 || item.Symbol == "QUARTZ_SAND" && item.Units != 5 {
+				t.Fatalf("delivered %d %s", item.Units, item.Symbol)
+			}
+		}
+	}
+	if want := []string{"QUARTZ_SAND", "IRON_ORE"}; !reflect.DeepEqual(legs, want) {
+		t.Fatalf("legs delivered %v, want %v", legs, want)
+	}
+
+	contract := server.Contracts()[0]
+	if !contract.Fulfilled {
+		t.Fatal("contract not fulfilled after both legs")
+	}
+	for _, good := range contract.Terms.Deliver {
+		if good.UnitsFulfilled != good.UnitsRequired {
+			t.Fatalf("%d of %d %s delivered", good.UnitsFulfilled, good.UnitsRequired, good.TradeSymbol)
+		}
+	}
+	if ship, _ := server.Ship("MOCK-2"); ship.Cargo.UnitsOf("IRON_ORE") != 2 || ship.Cargo.UnitsOf("QUARTZ_SAND") != 0 {
+		t.Fatalf("cargo left %+v, want the 2 spare IRON_ORE", ship.Cargo.Inventory)
+	}
+}
+
+func TestSellTripDeliversCargoOnHandFirst(t *testing.T) {
+	f := fixture(t)
+	f.Contracts[0].Accepted = true
+	f.Contracts[0].Terms.Deliver[0].UnitsRequired = 10
+	f.Ships[1].Nav.WaypointSymbol = "X1-MK1-B2"
+	f.Ships[1].Nav.Route.Destination.Symbol = "X1-MK1-B2"
+	f.Ships[1].Cargo = m.ShipCargo{Capacity: 30, Units: 30, Inventory: []m.ShipCargoItem{{Symbol: "IRON_ORE", Units: 30}}}
+
+	sb, server := shipBot(t, f, "MOCK-2")
+	sb.plan = NewDeliveryPlan(server.Contracts(), epoch)
+	events := sb.bus.Subscribe(64)
+
+	// The ore is bound for the planet's market, which is also where the contract wants some of it.
+	trip := sellTripMission("X1-MK1-A1", []string{"IRON_ORE"})
+	sb.mission = trip.Name
+	sbCh := make(chan ShipBot, 1)
+	trip.Run(sb, sbCh)
+	if reported := <-sbCh; reported.failure != nil {
+		t.Fatalf("sell trip failed: %s", reported.failure)
+	}
+
+	var order []event.Type
+	for drained := false; !drained; {
+		select {
+		case e := <-events:
+			switch e.Type {
+			case event.CargoDelivered, event.CargoSold:
+				order = append(order, e.Type)
+			}
+		default:
+			drained = true
+		}
+	}
+	if want := []event.Type{event.CargoDelivered, event.CargoSold}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("events %v, want the delivery before the sale", order)
+	}
+
+	if contract := server.Contracts()[0]; !contract.Fulfilled || contract.Terms.Deliver[0].UnitsFulfilled != 10 {
+		t.Fatalf("contract %+v, want its 10 units delivered and fulfilled", contract)
+	}
+	if n := server.Requests("POST", "/my/ships/MOCK-2/sell"); n != 1 {
+		t.Fatalf("%d sales, want the rest sold in one", n)
+	}
+	if ship, _ := server.Ship("MOCK-2"); ship.Cargo.Units != 0 {
+		t.Fatalf("%d units left in the hold, want none", ship.Cargo.Units)
+	}
+}
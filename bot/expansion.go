@@ -0,0 +1,450 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/boterr"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/lib"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+/*
+🌌 Expansion
+*/
+
+// Weights of the waypoints that make a system worth moving to. Mining targets matter most, since the
+// migrated ships mine; marketplaces sell their yield; shipyards let the fleet grow there.
+const (
+	miningTargetWeight = 3
+	marketplaceWeight  = 2
+	shipyardWeight     = 1
+)
+
+// errNoExpansion is returned by EvaluateExpansion when no neighbouring system is worth moving to.
+var errNoExpansion = errors.New("no neighbouring system beats the home system")
+
+// SystemScore rates a system by the known waypoints that make it worth mining in.
+type SystemScore struct {
+	System        string
+	MiningTargets int
+	Marketplaces  int
+	Shipyards     int
+}
+
+// Score weighs the system's waypoints into a single value.
+func (s SystemScore) Score() int {
+	return s.MiningTargets*miningTargetWeight + s.Marketplaces*marketplaceWeight + s.Shipyards*shipyardWeight
+}
+
+// scoreSystem counts the mining targets, marketplaces, and shipyards among a system's known waypoints.
+// Uncharted waypoints have no traits, so only their types count.
+func scoreSystem(systemSymbol string, waypoints []m.Waypoint, miningTypes []string) SystemScore {
+	score := SystemScore{System: systemSymbol}
+	for _, waypoint := range waypoints {
+		if lib.IsMiningTarget(waypoint, miningTypes...) {
+			score.MiningTargets++
+		}
+		if waypoint.HasTrait("MARKETPLACE") {
+			score.Marketplaces++
+		}
+		if waypoint.HasTrait("SHIPYARD") {
+			score.Shipyards++
+		}
+	}
+
+	return score
+}
+
+// bestNeighbour returns the highest-scoring candidate if it beats home by margin, as a fraction of home's score.
+func bestNeighbour(home SystemScore, candidates []SystemScore, margin float64) (SystemScore, bool) {
+	var best SystemScore
+	for _, candidate := range candidates {
+		if candidate.Score() > best.Score() {
+			best = candidate
+		}
+	}
+
+	if best.System == "" || float64(best.Score()) <= float64(home.Score())*(1+margin) {
+		return SystemScore{}, false
+	}
+
+	return best, true
+}
+
+// ExpansionPlan moves some of the home system's excavators, and a satellite to map the way, to a neighbouring
+// system through the home system's jump gate.
+type ExpansionPlan struct {
+	From SystemScore
+	To   SystemScore
+	// Gate is the home system's jump gate.
+	Gate string
+	// Excavators are the ships that move to mine in the new system.
+	Excavators []string
+	// Surveyor is the satellite that explores the new system, or empty if the fleet has none to spare.
+	Surveyor string
+}
+
+func (p ExpansionPlan) LogValues() []interface{} {
+	return []interface{}{
+		"from", p.From.System,
+		"fromScore", p.From.Score(),
+		"to", p.To.System,
+		"toScore", p.To.Score(),
+		"gate", p.Gate,
+		"excavators", p.Excavators,
+		"surveyor", p.Surveyor,
+	}
+}
+
+// selectMigrants picks share of the excavators in a system, at least one but never all of them, and its first
+// satellite. Ships for which busy returns true are left out. Ships are taken in symbol order, so repeated
+// evaluations pick the same ones.
+func selectMigrants(ships []m.Ship, systemSymbol string, share float64, busy func(shipSymbol string) bool) (excavators []string, surveyor string) {
+	var candidates []string
+	for _, ship := range ships {
+		if ship.Nav.SystemSymbol != systemSymbol || busy(ship.Symbol) {
+			continue
+		}
+
+		switch ship.Registration.Role {
+		case "EXCAVATOR":
+			candidates = append(candidates, ship.Symbol)
+		case "SATELLITE":
+			if surveyor == "" || ship.Symbol < surveyor {
+				surveyor = ship.Symbol
+			}
+		}
+	}
+
+	if len(candidates) < 2 {
+		return nil, ""
+	}
+
+	sort.Strings(candidates)
+
+	n := int(math.Round(share * float64(len(candidates))))
+	if n < 1 {
+		n = 1
+	}
+	if n >= len(candidates) {
+		n = len(candidates) - 1
+	}
+
+	return candidates[:n], surveyor
+}
+
+// EvaluateExpansion scores the systems connected to the home system's jump gate against the home system, and
+// plans to move part of the fleet to the best of them if it scores better by the configured margin.
+// Neighbours whose waypoints cannot be listed are skipped.
+func (ab *AgentBot) EvaluateExpansion(ships []m.Ship) (*ExpansionPlan, error) {
+	home := ab.HomeBase()
+	if home == nil {
+		return nil, errors.New("home base not loaded")
+	}
+	cfg := ab.config()
+
+	waypoints, err := ab.systems.Waypoints(home.System)
+	if err != nil {
+		return nil, err
+	}
+
+	gates := lib.Filter(waypoints, func(waypoint m.Waypoint) bool { return waypoint.IsType("JUMP_GATE") })
+	if len(gates) == 0 {
+		return nil, fmt.Errorf("no jump gate in %s", home.System)
+	}
+
+	gate, err := ab.reconciler.GetJumpGateAt(gates[0].Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]SystemScore, 0, len(gate.ConnectedSystems))
+	for _, system := range gate.ConnectedSystems {
+		neighbours, err := ab.systems.Waypoints(system.Symbol)
+		if err != nil {
+			ab.logger.Warn("🌌 Error listing neighbouring system.", "system", system.Symbol, "error", err)
+			continue
+		}
+		candidates = append(candidates, scoreSystem(system.Symbol, neighbours, cfg.MiningTargetTypes))
+	}
+
+	from := scoreSystem(home.System, waypoints, cfg.MiningTargetTypes)
+	to, ok := bestNeighbour(from, candidates, cfg.Expansion.Margin)
+	if !ok {
+		return nil, errNoExpansion
+	}
+
+	excavators, surveyor := selectMigrants(ships, home.System, cfg.Expansion.Share, func(shipSymbol string) bool {
+		_, busy := ab.journal.Get(shipSymbol)
+		return busy
+	})
+	if len(excavators) == 0 {
+		return nil, errors.New("not enough idle excavators to split the fleet")
+	}
+
+	return &ExpansionPlan{
+		From:       from,
+		To:         to,
+		Gate:       gates[0].Symbol,
+		Excavators: excavators,
+		Surveyor:   surveyor,
+	}, nil
+}
+
+// PlanExpansion journals a migration for every ship in the plan. Each ship starts it at its next dispatch,
+// and resumes it after a restart like any other journalled mission.
+func (ab *AgentBot) PlanExpansion(plan *ExpansionPlan) error {
+	begin := func(shipSymbol string, explore bool) error {
+		raw, err := json.Marshal(migrateIntent{System: plan.To.System, Gate: plan.Gate, Explore: explore})
+		if err != nil {
+			return err
+		}
+
+		return ab.journal.Begin(store.JournalEntry{
+			Ship:      shipSymbol,
+			Kind:      journalMigrate,
+			MissionID: newMissionID(ab.rand),
+			Step:      stepPlanned,
+			Intent:    raw,
+		})
+	}
+
+	for _, shipSymbol := range plan.Excavators {
+		if err := begin(shipSymbol, false); err != nil {
+			return err
+		}
+	}
+
+	if plan.Surveyor != "" {
+		if err := begin(plan.Surveyor, true); err != nil {
+			return err
+		}
+	}
+
+	ab.logger.Info("🌌 Expansion planned.", plan.LogValues()...)
+
+	return nil
+}
+
+// Expand evaluates expansion every interval until done is closed, planning at most one migration at a time.
+// No migration is planned while any ship is outside the home system, so the fleet expands one system at a time.
+func (ab *AgentBot) Expand(interval time.Duration, done <-chan struct{}) {
+	ticker := ab.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+		case <-done:
+			return
+		}
+
+		home := ab.HomeBase()
+		if home == nil {
+			continue
+		}
+
+		ships, err := ab.reconciler.GetMyShips()
+		if err != nil {
+			ab.logger.Warn("🌌 Error getting ships.", "error", err)
+			continue
+		}
+
+		controlled := lib.Filter(*ships, ab.controls)
+		if expanded(controlled, home.System) {
+			continue
+		}
+
+		plan, err := ab.EvaluateExpansion(controlled)
+		if err != nil {
+			ab.logger.Debug("🌌 No expansion.", "reason", err)
+			continue
+		}
+
+		if err := ab.PlanExpansion(plan); err != nil {
+			ab.logger.Warn("📓 Error writing journal.", "error", err)
+		}
+	}
+}
+
+// expanded checks if any ship is outside the home system.
+func expanded(ships []m.Ship, homeSystem string) bool {
+	for _, ship := range ships {
+		if ship.Nav.SystemSymbol != homeSystem {
+			return true
+		}
+	}
+
+	return false
+}
+
+// plannedMigration returns the migration journalled for a ShipBot that has not started it yet.
+func (ab *AgentBot) plannedMigration(sb *ShipBot) (Mission, bool) {
+	entry, ok := sb.journal.Get(sb.ship.Symbol)
+	if !ok || entry.Kind != journalMigrate || entry.Step != stepPlanned {
+		return Mission{}, false
+	}
+
+	return ab.ResumeMission(sb)
+}
+
+/*
+🛸 Migration
+*/
+
+// migrateIntent is the journalled intent of a migration to another system.
+type migrateIntent struct {
+	System string `json:"system"`
+	// Gate is the jump gate the ship jumps from.
+	Gate string `json:"gate"`
+	// Explore sends the ship on to explore the system once it arrives.
+	Explore bool `json:"explore,omitempty"`
+}
+
+// migrateMission moves a ship to another system.
+func migrateMission(intent migrateIntent) Mission {
+	return Mission{
+		Name: fmt.Sprintf("Migrate to %s", intent.System),
+		Run:  func(sb *ShipBot, sbCh chan ShipBot) { sb.Migrate(intent, sbCh) },
+	}
+}
+
+// Migrate flies to the jump gate and jumps to the intent's system. A surveyor goes on to explore the system;
+// other ships report in, and their strategy picks their work in the new system.
+func (sb *ShipBot) Migrate(intent migrateIntent, sbCh chan ShipBot) {
+	if sb.ship.Nav.SystemSymbol != intent.System {
+		sb.journalStep(stepJumping, nil)
+
+		if err := sb.jump(intent.Gate, intent.System); err != nil {
+			sb.logger.Error("🛸 Error jumping.", "gate", intent.Gate, "system", intent.System, "error", err)
+			sb.Abandon(boterr.Wrap(err, "gate", intent.Gate, "system", intent.System))
+			sb.Report(sbCh)
+			return
+		}
+
+		sb.logger.Info("🛸 Arrived in new system.", "system", intent.System)
+	}
+
+	if intent.Explore {
+		sb.ExploreSystem(sbCh)
+		return
+	}
+
+	sb.journalEnd()
+	sb.Report(sbCh)
+}
+
+// jump buys any antimatter the ship is short of, navigates to a jump gate, and jumps to a connected system. A
+// jump refused for want of antimatter buys it and is tried once more.
+func (sb *ShipBot) jump(gate string, systemSymbol string) error {
+	if err := sb.ensureAntimatter(jumpHops, 0); err != nil {
+		return err
+	}
+
+	return sb.jumpFrom(gate, systemSymbol, true)
+}
+
+// jumpFrom navigates to a jump gate and jumps to a connected system, buying antimatter and trying again if retry
+// is set and the jump is refused for want of it.
+func (sb *ShipBot) jumpFrom(gate string, systemSymbol string, retry bool) error {
+	sb.planRoute(gate)
+	err := sb.NavigateShip(gate)
+	if sb.ship.Nav.WaypointSymbol != gate {
+		return notReached(gate, err)
+	}
+
+	if err := sb.EnsureOrbit(); err != nil {
+		return err
+	}
+
+	sb.WaitUntilCooldown()
+	nav, err := sb.client.JumpShip(sb.ship.Symbol, systemSymbol)
+	if retry && api.IsNoAntimatter(err) {
+		sb.logger.Warn("⚛️ Jump refused for want of antimatter. Buying more...", "gate", gate, "error", err)
+
+		// The ship holds less than it was thought to, so buy a jump's worth on top of what it holds.
+		if err := sb.ensureAntimatter(jumpHops, sb.ship.Cargo.UnitsOf(antimatter)); err != nil {
+			return err
+		}
+		return sb.jumpFrom(gate, systemSymbol, false)
+	}
+	if err != nil {
+		return err
+	}
+
+	sb.ship.Nav = *nav
+	sb.bus.Publish(event.Event{Type: event.ShipNavigated, Ship: sb.ship.Symbol, Mission: sb.mission, MissionID: sb.missionID, Message: systemSymbol, Data: *nav})
+
+	return nil
+}
+
+/*
+⚛️ Antimatter
+*/
+
+// antimatter is the good a ship burns to jump.
+const antimatter = "ANTIMATTER"
+
+const (
+	// antimatterPerJump is the antimatter a jump burns.
+	antimatterPerJump = 1
+	// jumpHops is the jumps a migration makes, since it only moves to systems connected to the ship's own.
+	jumpHops = 1
+)
+
+// antimatterNeeded returns the antimatter a route of hops jumps burns.
+func antimatterNeeded(hops int) int {
+	return hops * antimatterPerJump
+}
+
+// antimatterShort returns how many units of antimatter a ship is short of for hops jumps, counting extra units
+// beyond what it holds as burnt already.
+func antimatterShort(cargo m.ShipCargo, hops int, extra int) int {
+	return antimatterNeeded(hops) + extra - cargo.UnitsOf(antimatter)
+}
+
+// ensureAntimatter buys the antimatter the ship is short of for hops jumps, plus extra units, at the cheapest
+// market in its system that sells it, before the ship departs.
+func (sb *ShipBot) ensureAntimatter(hops int, extra int) error {
+	short := antimatterShort(sb.ship.Cargo, hops, extra)
+	if short <= 0 {
+		return nil
+	}
+	if space := sb.ship.Cargo.SpaceRemaining(); short > space {
+		return fmt.Errorf("%d %s needed to jump, but room for %d", short, antimatter, space)
+	}
+
+	source, ok := sb.antimatterMarket()
+	if !ok {
+		return fmt.Errorf("%d %s needed to jump, but no known market in %s sells it", short, antimatter, sb.ship.Nav.SystemSymbol)
+	}
+
+	sb.logger.Info("⚛️ Buying antimatter to jump...", "units", short, "market", source, "hops", hops)
+
+	return sb.procure(antimatter, short, source)
+}
+
+// antimatterMarket returns the recorded market in the ship's system that sells antimatter cheapest.
+func (sb *ShipBot) antimatterMarket() (string, bool) {
+	var best string
+	var lowest int64
+	for _, observation := range sb.markets.All() {
+		if systemSymbol, err := lib.SystemSymbolOf(observation.Market.Symbol); err != nil || systemSymbol != sb.ship.Nav.SystemSymbol {
+			continue
+		}
+
+		if price, ok := observation.Market.PurchasePriceOf(antimatter); ok && (best == "" || price < lowest) {
+			best, lowest = observation.Market.Symbol, price
+		}
+	}
+
+	return best, best != ""
+}
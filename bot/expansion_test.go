@@ -0,0 +1,403 @@
+package bot
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/mockserver"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+// expansionClient is a contractClient serving several systems, the home system's jump gate, and jumps.
+type expansionClient struct {
+	contractClient
+	systems map[string][]m.Waypoint
+	gate    m.JumpGate
+	jumps   []string
+}
+
+func (c *expansionClient) ListWaypoints(systemSymbol string) (*[]m.Waypoint, error) {
+	waypoints, ok := c.systems[systemSymbol]
+	if !ok {
+		return nil, errors.New("system not found")
+	}
+
+	return &waypoints, nil
+}
+
+func (c *expansionClient) GetJumpGateAt(waypointSymbol string) (*m.JumpGate, error) {
+	gate := c.gate
+	return &gate, nil
+}
+
+func (c *expansionClient) JumpShip(shipSymbol string, systemSymbol string) (*m.ShipNav, error) {
+	c.jumps = append(c.jumps, shipSymbol+" "+systemSymbol)
+	return &m.ShipNav{SystemSymbol: systemSymbol, WaypointSymbol: systemSymbol + "-9", Status: "IN_ORBIT"}, nil
+}
+
+// expansionWaypoint is a waypoint of a system with traits.
+func expansionWaypoint(system string, symbol string, typ string, traits ...string) m.Waypoint {
+	waypoint := m.Waypoint{Symbol: symbol, SystemSymbol: system, Type: typ}
+	for _, trait := range traits {
+		waypoint.Traits = append(waypoint.Traits, m.WaypointTrait{Symbol: trait})
+	}
+
+	return waypoint
+}
+
+// newExpansionClient serves the home system X1-H, scoring 6, whose gate connects to X1-N, scoring 10, X1-P,
+// scoring 5, and X1-Q, whose waypoints cannot be listed.
+func newExpansionClient() *expansionClient {
+	return &expansionClient{
+		systems: map[string][]m.Waypoint{
+			"X1-H": {
+				expansionWaypoint("X1-H", "X1-H-1", "PLANET", "MARKETPLACE", "SHIPYARD"),
+				expansionWaypoint("X1-H", "X1-H-2", "ASTEROID"),
+				expansionWaypoint("X1-H", "X1-H-9", "JUMP_GATE"),
+			},
+			"X1-N": {
+				expansionWaypoint("X1-N", "X1-N-1", "ASTEROID", "MARKETPLACE"),
+				expansionWaypoint("X1-N", "X1-N-2", "ENGINEERED_ASTEROID"),
+				expansionWaypoint("X1-N", "X1-N-3", "MOON", "MARKETPLACE"),
+				expansionWaypoint("X1-N", "X1-N-9", "JUMP_GATE"),
+			},
+			"X1-P": {
+				expansionWaypoint("X1-P", "X1-P-1", "ASTEROID_FIELD", "MARKETPLACE"),
+			},
+		},
+		gate: m.JumpGate{ConnectedSystems: []m.ConnectedSystem{{Symbol: "X1-P"}, {Symbol: "X1-N"}, {Symbol: "X1-Q"}}},
+	}
+}
+
+// expansionShip is a ship of a role idle at a waypoint.
+func expansionShip(symbol string, role string, waypoint string, system string) m.Ship {
+	ship := m.Ship{Symbol: symbol}
+	ship.Registration.Role = role
+	ship.Nav = m.ShipNav{SystemSymbol: system, WaypointSymbol: waypoint, Status: "IN_ORBIT"}
+
+	return ship
+}
+
+// expansionFleet is five excavators and two satellites at home, and an excavator already elsewhere.
+func expansionFleet() []m.Ship {
+	return []m.Ship{
+		expansionShip("GOGARIN-5", "EXCAVATOR", "X1-H-2", "X1-H"),
+		expansionShip("GOGARIN-2", "EXCAVATOR", "X1-H-2", "X1-H"),
+		expansionShip("GOGARIN-4", "EXCAVATOR", "X1-H-2", "X1-H"),
+		expansionShip("GOGARIN-3", "EXCAVATOR", "X1-H-2", "X1-H"),
+		expansionShip("GOGARIN-6", "EXCAVATOR", "X1-H-2", "X1-H"),
+		expansionShip("GOGARIN-8", "SATELLITE", "X1-H-1", "X1-H"),
+		expansionShip("GOGARIN-7", "SATELLITE", "X1-H-1", "X1-H"),
+		expansionShip("GOGARIN-9", "EXCAVATOR", "X1-P-1", "X1-P"),
+	}
+}
+
+// expansionAgent returns an AgentBot headquartered in X1-H with its home base loaded, journalling to j.
+func expansionAgent(t *testing.T, cfg *config.Config, c *expansionClient, j *store.Journal) *AgentBot {
+	t.Helper()
+
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+
+	ab := NewAgentBot(c, &m.Agent{Symbol: "GOGARIN", Headquarters: "X1-H-1"}, store.NewMarketStore(), NewStrategySelector(cfg), j, bus, cfg)
+	t.Cleanup(ab.scheduler.Stop)
+	if err := ab.LoadHomeBase(); err != nil {
+		t.Fatal(err)
+	}
+
+	return ab
+}
+
+func TestScoreSystem(t *testing.T) {
+	c := newExpansionClient()
+
+	tests := []struct {
+		system string
+		types  []string
+		want   SystemScore
+		score  int
+	}{
+		{"X1-H", nil, SystemScore{System: "X1-H", MiningTargets: 1, Marketplaces: 1, Shipyards: 1}, 6},
+		{"X1-N", nil, SystemScore{System: "X1-N", MiningTargets: 2, Marketplaces: 2}, 10},
+		{"X1-N", []string{"ASTEROID"}, SystemScore{System: "X1-N", MiningTargets: 1, Marketplaces: 2}, 7},
+		{"X1-P", nil, SystemScore{System: "X1-P", MiningTargets: 1, Marketplaces: 1}, 5},
+	}
+	for _, tt := range tests {
+		got := scoreSystem(tt.system, c.systems[tt.system], tt.types)
+		if got != tt.want || got.Score() != tt.score {
+			t.Errorf("scoreSystem(%s, %v) = %+v scoring %d, want %+v scoring %d", tt.system, tt.types, got, got.Score(), tt.want, tt.score)
+		}
+	}
+}
+
+func TestBestNeighbour(t *testing.T) {
+	home := SystemScore{System: "X1-H", MiningTargets: 2} // 6
+	rich := SystemScore{System: "X1-N", MiningTargets: 3} // 9
+	poor := SystemScore{System: "X1-P", Marketplaces: 1}  // 2
+
+	tests := []struct {
+		name       string
+		candidates []SystemScore
+		margin     float64
+		want       string
+	}{
+		{"beats home by the margin", []SystemScore{poor, rich}, 0.4, "X1-N"},
+		{"not by enough", []SystemScore{poor, rich}, 0.5, ""},
+		{"no neighbours", nil, 0, ""},
+		{"only poorer neighbours", []SystemScore{poor}, 0, ""},
+	}
+	for _, tt := range tests {
+		best, ok := bestNeighbour(home, tt.candidates, tt.margin)
+		if best.System != tt.want || ok != (tt.want != "") {
+			t.Errorf("%s: best = %q, %t, want %q", tt.name, best.System, ok, tt.want)
+		}
+	}
+}
+
+func TestSelectMigrants(t *testing.T) {
+	idle := func(string) bool { return false }
+
+	tests := []struct {
+		name       string
+		share      float64
+		busy       func(string) bool
+		excavators []string
+		surveyor   string
+	}{
+		{"half of them rounded, in symbol order", 0.5, idle, []string{"GOGARIN-2", "GOGARIN-3", "GOGARIN-4"}, "GOGARIN-7"},
+		{"at least one", 0.01, idle, []string{"GOGARIN-2"}, "GOGARIN-7"},
+		{"never all of them", 1, idle, []string{"GOGARIN-2", "GOGARIN-3", "GOGARIN-4", "GOGARIN-5"}, "GOGARIN-7"},
+		{"busy ships stay", 0.5, func(s string) bool { return s == "GOGARIN-2" || s == "GOGARIN-7" }, []string{"GOGARIN-3", "GOGARIN-4"}, "GOGARIN-8"},
+		{"one idle excavator is not split", 0.5, func(s string) bool { return s != "GOGARIN-2" }, nil, ""},
+	}
+	for _, tt := range tests {
+		excavators, surveyor := selectMigrants(expansionFleet(), "X1-H", tt.share, tt.busy)
+		if !reflect.DeepEqual(excavators, tt.excavators) || surveyor != tt.surveyor {
+			t.Errorf("%s: migrants = %v with surveyor %q, want %v with %q", tt.name, excavators, surveyor, tt.excavators, tt.surveyor)
+		}
+	}
+}
+
+func TestEvaluateExpansionPicksTheRicherNeighbour(t *testing.T) {
+	cfg := config.Default()
+	j, err := store.OpenJournal("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// GOGARIN-2 is in the middle of a trade.
+	if err := j.Begin(store.JournalEntry{Ship: "GOGARIN-2", Kind: journalTrade, Step: stepHauling}); err != nil {
+		t.Fatal(err)
+	}
+
+	ab := expansionAgent(t, cfg, newExpansionClient(), j)
+
+	plan, err := ab.EvaluateExpansion(expansionFleet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.From.System != "X1-H" || plan.To.System != "X1-N" || plan.Gate != "X1-H-9" {
+		t.Errorf("plan from %s to %s through %s, want X1-H to X1-N through X1-H-9", plan.From.System, plan.To.System, plan.Gate)
+	}
+	if want := []string{"GOGARIN-3", "GOGARIN-4"}; !reflect.DeepEqual(plan.Excavators, want) || plan.Surveyor != "GOGARIN-7" {
+		t.Errorf("migrants = %v with surveyor %q, want %v with GOGARIN-7", plan.Excavators, plan.Surveyor, want)
+	}
+
+	cfg.Expansion.Margin = 1
+	if _, err := ab.EvaluateExpansion(expansionFleet()); !errors.Is(err, errNoExpansion) {
+		t.Errorf("with a margin no neighbour beats: err = %v, want errNoExpansion", err)
+	}
+}
+
+func TestPlannedMigrationJumpsAndSurvivesRestart(t *testing.T) {
+	cfg := config.Default()
+	c := newExpansionClient()
+	j, err := store.OpenJournal("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ab := expansionAgent(t, cfg, c, j)
+
+	plan, err := ab.EvaluateExpansion(expansionFleet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ab.PlanExpansion(plan); err != nil {
+		t.Fatal(err)
+	}
+	for _, symbol := range []string{"GOGARIN-3", "GOGARIN-4", "GOGARIN-7"} {
+		if entry, ok := j.Get(symbol); !ok || entry.Kind != journalMigrate || entry.Step != stepPlanned {
+			t.Errorf("%s journal entry = %+v, want a planned migration", symbol, entry)
+		}
+	}
+
+	// An excavator left at the gate by its last mission, with the antimatter to jump, sets off on its next
+	// dispatch.
+	ship := expansionShip("GOGARIN-3", "EXCAVATOR", "X1-H-9", "X1-H")
+	ship.Cargo = m.ShipCargo{Capacity: 30, Units: 1, Inventory: []m.ShipCargoItem{{Symbol: "ANTIMATTER", Units: 1}}}
+	sb := NewShipBot(c, &ship, ab.agent, ab.systems, ab.markets, ab.bus, cfg)
+	sb.journal = j
+	sb.arrival = nil
+
+	mission, ok := ab.plannedMigration(sb)
+	if !ok || mission.Name != "Migrate to X1-N" {
+		t.Fatalf("planned migration = %q, %t, want Migrate to X1-N", mission.Name, ok)
+	}
+	sbCh := make(chan ShipBot, 1)
+	mission.Run(sb, sbCh)
+	reported := <-sbCh
+
+	if reported.ship.Nav.SystemSymbol != "X1-N" || !reflect.DeepEqual(c.jumps, []string{"GOGARIN-3 X1-N"}) {
+		t.Errorf("ship in %s after jumps %v, want one jump to X1-N", reported.ship.Nav.SystemSymbol, c.jumps)
+	}
+	if _, ok := j.Get("GOGARIN-3"); ok {
+		t.Error("finished migration still journalled")
+	}
+
+	// An excavator that jumped before a restart does not jump again when its migration resumes.
+	if err := j.Step("GOGARIN-4", stepJumping, nil); err != nil {
+		t.Fatal(err)
+	}
+	arrived := expansionShip("GOGARIN-4", "EXCAVATOR", "X1-N-9", "X1-N")
+	sb = NewShipBot(c, &arrived, ab.agent, ab.systems, ab.markets, ab.bus, cfg)
+	sb.journal = j
+
+	if _, ok := ab.plannedMigration(sb); ok {
+		t.Error("a migration underway was dispatched as planned")
+	}
+	entry, _ := j.Get("GOGARIN-4")
+	resumed, err := ab.resumable(sb, entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumed.Run(sb, sbCh)
+	<-sbCh
+	if len(c.jumps) != 1 {
+		t.Errorf("jumps %v, want no jump for a ship already in the new system", c.jumps)
+	}
+	if _, ok := j.Get("GOGARIN-4"); ok {
+		t.Error("resumed migration still journalled")
+	}
+}
+
+// antimatterFixture returns the default fixture with the planet's market selling antimatter, and the
+// excavator holding held units of it.
+func antimatterFixture(t *testing.T, held int) *mockserver.Fixture {
+	t.Helper()
+
+	f := fixture(t)
+	f.Markets[0].TradeGoods = append(f.Markets[0].TradeGoods, m.MarketTradeGood{
+		Symbol: "ANTIMATTER", Type: "EXCHANGE", TradeVolume: 10, Supply: m.SupplyModerate, PurchasePrice: 9000, SellPrice: 8000,
+	})
+	if held > 0 {
+		f.Ships[1].Cargo.Inventory = append(f.Ships[1].Cargo.Inventory, m.ShipCargoItem{Symbol: "ANTIMATTER", Units: held})
+		f.Ships[1].Cargo.Units += held
+	}
+
+	return f
+}
+
+// jumpGate stands in for a jump gate, refusing a set number of jumps for want of antimatter before letting the
+// ship through.
+type jumpGate struct {
+	api.ClientAPI
+	refusals int
+
+	jumps int
+}
+
+func (c *jumpGate) JumpShip(shipSymbol string, systemSymbol string) (*m.ShipNav, error) {
+	if c.jumps++; c.jumps <= c.refusals {
+		return nil, &api.APIError{StatusCode: http.StatusBadRequest, Code: api.CodeJumpNoAntimatter, Message: "Ship is missing antimatter."}
+	}
+
+	return &m.ShipNav{SystemSymbol: systemSymbol, WaypointSymbol: systemSymbol + "-I9", Status: "IN_ORBIT"}, nil
+}
+
+func TestTwoJumpRouteBuysAntimatterBeforeDeparting(t *testing.T) {
+	f := antimatterFixture(t, 0)
+	sb, server := shipBot(t, f, "MOCK-2")
+	sb.arrival = nil
+	sb.markets.Record(f.Markets[0], sb.clock.Now())
+
+	if err := sb.ensureAntimatter(2, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := server.Requests("POST", "/my/ships/MOCK-2/purchase"); n != 1 {
+		t.Fatalf("%d purchases, want 1", n)
+	}
+	if held := sb.ship.Cargo.UnitsOf("ANTIMATTER"); held != 2 {
+		t.Fatalf("%d ANTIMATTER held, want 2 for two jumps", held)
+	}
+
+	// With enough aboard, nothing more is bought.
+	if err := sb.ensureAntimatter(2, 0); err != nil {
+		t.Fatal(err)
+	}
+	if n := server.Requests("POST", "/my/ships/MOCK-2/purchase"); n != 1 {
+		t.Fatalf("%d purchases, want none for a ship holding enough", n)
+	}
+}
+
+func TestAntimatterShortWithoutSellerFails(t *testing.T) {
+	sb, server := shipBot(t, fixture(t), "MOCK-2")
+	sb.arrival = nil
+
+	if err := sb.ensureAntimatter(1, 0); err == nil {
+		t.Fatal("no error for a ship short of antimatter with no market selling it")
+	}
+	if n := server.Requests("POST", "/my/ships/MOCK-2/purchase"); n != 0 {
+		t.Fatalf("%d purchases, want none", n)
+	}
+}
+
+func TestJumpRefusedForAntimatterBuysOnceAndRetries(t *testing.T) {
+	f := antimatterFixture(t, 1)
+	sb, server := shipBot(t, f, "MOCK-2")
+	sb.arrival = nil
+	sb.markets.Record(f.Markets[0], sb.clock.Now())
+	gate := &jumpGate{ClientAPI: sb.client, refusals: 1}
+	sb.client = gate
+
+	// The ship holds the unit a jump burns, so it sets off without buying, and is refused at the gate.
+	if err := sb.jump("X1-MK1-A1", "X1-CD"); err != nil {
+		t.Fatal(err)
+	}
+
+	if gate.jumps != 2 {
+		t.Fatalf("%d jumps, want the refused one and its retry", gate.jumps)
+	}
+	if n := server.Requests("POST", "/my/ships/MOCK-2/purchase"); n != 1 {
+		t.Fatalf("%d purchases, want 1 after the refusal", n)
+	}
+	if sb.ship.Nav.SystemSymbol != "X1-CD" {
+		t.Fatalf("ship in %s after jumping, want X1-CD", sb.ship.Nav.SystemSymbol)
+	}
+}
+
+func TestJumpRefusedTwiceGivesUp(t *testing.T) {
+	f := antimatterFixture(t, 1)
+	sb, server := shipBot(t, f, "MOCK-2")
+	sb.arrival = nil
+	sb.markets.Record(f.Markets[0], sb.clock.Now())
+	gate := &jumpGate{ClientAPI: sb.client, refusals: 2}
+	sb.client = gate
+
+	err := sb.jump("X1-MK1-A1", "X1-CD")
+
+	if !api.IsNoAntimatter(err) {
+		t.Fatalf("err = %v, want the second refusal", err)
+	}
+	if gate.jumps != 2 {
+		t.Fatalf("%d jumps, want the purchase path taken only once", gate.jumps)
+	}
+	if n := server.Requests("POST", "/my/ships/MOCK-2/purchase"); n != 1 {
+		t.Fatalf("%d purchases, want 1", n)
+	}
+}
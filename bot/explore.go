@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"errors"
+
+	"github.com/GeoffreyDick/gogarin/boterr"
+	"github.com/GeoffreyDick/gogarin/lib"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+/*
+🔭 Exploration
+*/
+
+// errNothingToExplore is returned when every waypoint in the ship's system is charted.
+var errNothingToExplore = errors.New("no uncharted waypoints")
+
+// exploreIntent is the journalled intent of an exploration mission: the waypoints left to visit, in order.
+type exploreIntent struct {
+	Remaining []string `json:"remaining"`
+}
+
+// ExploreSystem visits the uncharted waypoints of the ship's system in the order of a planned tour, charting
+// each on arrival. It stops once the explore budget of waypoints is spent or the next leg would burn into
+// the ship's fuel reserve.
+func (sb *ShipBot) ExploreSystem(sbCh chan ShipBot) {
+	current, err := sb.systems.Waypoint(sb.ship.Nav.WaypointSymbol)
+	if err != nil {
+		sb.logger.Error("🔭 Error getting current waypoint.", "error", err)
+		sb.Fail(boterr.Wrap(err, "waypoint", sb.ship.Nav.WaypointSymbol))
+		sb.Report(sbCh)
+		return
+	}
+
+	uncharted, err := sb.unchartedWaypoints()
+	if err == nil && len(uncharted) == 0 {
+		err = errNothingToExplore
+	}
+	if err != nil {
+		sb.logger.Warn("🔭 Error planning exploration.", "error", err)
+		sb.Fail(boterr.Wrap(err, "system", sb.ship.Nav.SystemSymbol))
+		sb.Report(sbCh)
+		return
+	}
+
+	tour := lib.PlanTour(current, uncharted)
+	if sb.exploreBudget > 0 && len(tour) > sb.exploreBudget {
+		tour = tour[:sb.exploreBudget]
+	}
+
+	remaining := make([]string, 0, len(tour))
+	for _, waypoint := range tour {
+		remaining = append(remaining, waypoint.Symbol)
+	}
+
+	sb.logger.Info("🔭 Exploration planned.", "system", sb.ship.Nav.SystemSymbol, "uncharted", len(uncharted), "visiting", len(remaining))
+	sb.journalBegin(journalExplore, stepExploring, "", exploreIntent{Remaining: remaining})
+
+	sb.ResumeExploration(remaining, sbCh)
+}
+
+// ResumeExploration visits the given waypoints in order, skipping any that have been charted since the
+// tour was planned, and charts each on arrival.
+func (sb *ShipBot) ResumeExploration(remaining []string, sbCh chan ShipBot) {
+	var charted int
+
+	for len(remaining) > 0 {
+		waypoint, err := sb.systems.Waypoint(remaining[0])
+		if err != nil {
+			sb.logger.Error("🔭 Error getting waypoint.", "waypoint", remaining[0], "error", err)
+			sb.Abandon(boterr.Wrap(err, "waypoint", remaining[0]))
+			sb.Report(sbCh)
+			return
+		}
+
+		if !waypoint.HasTrait("UNCHARTED") {
+			sb.logger.Debug("🔭 Already charted. Skipping.", "waypoint", waypoint.Symbol)
+			remaining = remaining[1:]
+			continue
+		}
+
+		if !sb.canReach(waypoint) {
+			sb.logger.Info("🔭 Fuel reserve reached. Ending exploration.", "waypoint", waypoint.Symbol, "fuel", sb.ship.Fuel.Current)
+			break
+		}
+
+		sb.planRoute(remaining...)
+		err = sb.NavigateShip(waypoint.Symbol)
+		if sb.ship.Nav.WaypointSymbol != waypoint.Symbol {
+			err := notReached(waypoint.Symbol, err)
+			sb.logger.Error("🔭 Error reaching waypoint.", "error", err)
+			sb.Abandon(err)
+			sb.Report(sbCh)
+			return
+		}
+
+		// Arrival charts the waypoint; a failed chart is logged there and retried by a later tour.
+		if discovered, err := sb.systems.Waypoint(waypoint.Symbol); err == nil && !discovered.HasTrait("UNCHARTED") {
+			charted++
+			sb.recordDiscoveries(discovered)
+		}
+
+		remaining = remaining[1:]
+		sb.journalStep(stepExploring, exploreIntent{Remaining: remaining})
+	}
+
+	sb.logger.Info("🔭 Exploration complete.", "charted", charted, "unvisited", len(remaining))
+	sb.journalEnd()
+	sb.Report(sbCh)
+}
+
+// unchartedWaypoints returns the waypoints of the ship's system no one has charted yet.
+func (sb *ShipBot) unchartedWaypoints() ([]m.Waypoint, error) {
+	waypoints, err := sb.systems.Waypoints(sb.ship.Nav.SystemSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	return lib.Filter(waypoints, func(waypoint m.Waypoint) bool {
+		return waypoint.HasTrait("UNCHARTED")
+	}), nil
+}
+
+// canReach checks if the ship can cruise to a waypoint without burning into its fuel reserve.
+// Ships without a fuel tank, such as probes, can always reach it.
+func (sb *ShipBot) canReach(waypoint *m.Waypoint) bool {
+	fuel := sb.ship.Fuel
+	if fuel.Capacity == 0 {
+		return true
+	}
+
+	current, err := sb.systems.Waypoint(sb.ship.Nav.WaypointSymbol)
+	if err != nil {
+		return false
+	}
+
+	reserve := int(sb.tuning.FuelReserve * float64(fuel.Capacity))
+
+	return fuel.Current-sb.fuelCosts.Estimate(current, waypoint, "CRUISE") >= reserve
+}
+
+// recordDiscoveries logs the marketplace or shipyard a charted waypoint turned out to have, and records
+// a newly found market.
+func (sb *ShipBot) recordDiscoveries(waypoint *m.Waypoint) {
+	if waypoint.HasTrait("MARKETPLACE") {
+		sb.logger.Info("🔭 Marketplace discovered.", "waypoint", waypoint.Symbol)
+		if err := recordMarket(sb, waypoint); err != nil {
+			sb.logger.Warn("📈 Error recording market.", "waypoint", waypoint.Symbol, "error", err)
+		}
+	}
+
+	if waypoint.HasTrait("SHIPYARD") {
+		sb.logger.Info("🔭 Shipyard discovered.", "waypoint", waypoint.Symbol)
+	}
+}
@@ -0,0 +1,188 @@
+package bot
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/mockserver"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+// explorer returns a ShipBot for the mock server's command ship, in orbit at X1-MK1-A1, in a system where
+// the moon X1-MK1-C3 and the gas giant X1-MK1-D4 are uncharted and the asteroid field X1-MK1-B2 is charted.
+func explorer(t *testing.T, cfg *config.Config) (*ShipBot, *mockserver.Server) {
+	t.Helper()
+
+	f, err := mockserver.DefaultFixture()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range f.Waypoints {
+		switch f.Waypoints[i].Symbol {
+		case "X1-MK1-C3", "X1-MK1-D4":
+			f.Waypoints[i].Traits = append(f.Waypoints[i].Traits, m.WaypointTrait{Symbol: "UNCHARTED"})
+		}
+	}
+
+	s := mockserver.New(f, mockserver.WithTimeScale(1000))
+	t.Cleanup(s.Close)
+	c := api.NewClient("token", api.WithBaseURL(s.URL), api.WithRateLimit(1000))
+
+	ship, err := c.GetShip("MOCK-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nav, err := c.OrbitShip("MOCK-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ship.Nav = *nav
+
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+	sb := NewShipBot(c, ship, store.NewAgentState(f.Agent, 0), store.NewSystemKnowledge(c), store.NewMarketStore(), bus, cfg)
+
+	return sb, s
+}
+
+// charted reports whether the ShipBot knows a waypoint to be charted.
+func charted(t *testing.T, sb *ShipBot, symbol string) bool {
+	t.Helper()
+
+	waypoint, err := sb.systems.Waypoint(symbol)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return !waypoint.HasTrait("UNCHARTED")
+}
+
+func TestExploreSystemChartsTheUnchartedWaypoints(t *testing.T) {
+	tests := []struct {
+		name    string
+		budget  int
+		fuel    int
+		charted []string
+		at      string
+	}{
+		{"whole system", 0, 1200, []string{"X1-MK1-C3", "X1-MK1-D4"}, "X1-MK1-D4"},
+		{"budget of one", 1, 1200, []string{"X1-MK1-C3"}, "X1-MK1-C3"},
+		{"fuel reserve reached", 0, 20, nil, "X1-MK1-A1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.ExploreBudget = tt.budget
+			sb, s := explorer(t, cfg)
+			sb.ship.Fuel.Current = tt.fuel
+
+			sbCh := make(chan ShipBot, 1)
+			sb.ExploreSystem(sbCh)
+			reported := <-sbCh
+
+			if reported.ship.Nav.WaypointSymbol != tt.at {
+				t.Errorf("ended at %s, want %s", reported.ship.Nav.WaypointSymbol, tt.at)
+			}
+			if got := s.Requests("POST", "/my/ships/MOCK-1/navigate"); got != len(tt.charted) {
+				t.Errorf("%d navigations, want one to each of %v", got, tt.charted)
+			}
+			if got := s.Requests("POST", "/my/ships/MOCK-1/chart"); got != len(tt.charted) {
+				t.Errorf("%d charts, want %d", got, len(tt.charted))
+			}
+			for _, symbol := range []string{"X1-MK1-C3", "X1-MK1-D4"} {
+				want := false
+				for _, c := range tt.charted {
+					want = want || c == symbol
+				}
+				if got := charted(t, sb, symbol); got != want {
+					t.Errorf("%s charted = %t, want %t", symbol, got, want)
+				}
+			}
+
+			// The moon turned out to have a marketplace.
+			_, recorded := sb.markets.Get("X1-MK1-C3")
+			if want := len(tt.charted) > 0; recorded != want {
+				t.Errorf("X1-MK1-C3 market recorded = %t, want %t", recorded, want)
+			}
+		})
+	}
+}
+
+func TestExploreSystemFailsOnceEverythingIsCharted(t *testing.T) {
+	sb, s := explorer(t, config.Default())
+	for _, symbol := range []string{"X1-MK1-C3", "X1-MK1-D4"} {
+		waypoint, err := sb.systems.Waypoint(symbol)
+		if err != nil {
+			t.Fatal(err)
+		}
+		waypoint.Traits = nil
+		sb.systems.Put(*waypoint)
+	}
+
+	if mission := (ExploringStrategy{}).Decide(sb, AgentSnapshot{}); mission.Name != idleMission.Name {
+		t.Errorf("exploring strategy decided %q in a charted system, want to idle", mission.Name)
+	}
+
+	sbCh := make(chan ShipBot, 1)
+	sb.ExploreSystem(sbCh)
+	<-sbCh
+	if s.Requests("POST", "/my/ships/MOCK-1/navigate") != 0 {
+		t.Error("navigated with nothing to explore")
+	}
+}
+
+func TestResumeExplorationSkipsChartedWaypoints(t *testing.T) {
+	sb, s := explorer(t, config.Default())
+	journal, err := store.OpenJournal("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb.journal = journal
+
+	if mission := (ExploringStrategy{}).Decide(sb, AgentSnapshot{}); mission.Name != exploreMission.Name {
+		t.Errorf("exploring strategy decided %q with waypoints uncharted, want %q", mission.Name, exploreMission.Name)
+	}
+
+	// The last run planned the asteroid field, charted since, and the gas giant.
+	intent, err := json.Marshal(exploreIntent{Remaining: []string{"X1-MK1-B2", "X1-MK1-D4"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := store.JournalEntry{Ship: "MOCK-1", Kind: journalExplore, Step: stepExploring, Intent: intent}
+	if err := journal.Begin(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	ab := &AgentBot{journal: journal}
+	mission, err := ab.resumable(sb, entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sbCh := make(chan ShipBot, 1)
+	mission.Run(sb, sbCh)
+	reported := <-sbCh
+
+	if reported.ship.Nav.WaypointSymbol != "X1-MK1-D4" {
+		t.Errorf("ended at %s, want X1-MK1-D4", reported.ship.Nav.WaypointSymbol)
+	}
+	if got := s.Requests("POST", "/my/ships/MOCK-1/navigate"); got != 1 {
+		t.Errorf("%d navigations, want only the one to X1-MK1-D4", got)
+	}
+	if !charted(t, sb, "X1-MK1-D4") || charted(t, sb, "X1-MK1-C3") {
+		t.Error("want only X1-MK1-D4 charted")
+	}
+	if _, ok := journal.Get("MOCK-1"); ok {
+		t.Error("finished exploration still journalled")
+	}
+
+	// Nothing left to visit is not resumed.
+	empty, _ := json.Marshal(exploreIntent{})
+	if _, err := ab.resumable(sb, store.JournalEntry{Ship: "MOCK-1", Kind: journalExplore, Intent: empty}); err == nil {
+		t.Error("resumed an exploration with nothing left to visit")
+	}
+}
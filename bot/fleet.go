@@ -0,0 +1,842 @@
+// Package bot runs an agent's fleet. Fleet, in fleet.go, wires the client, stores, and event bus into the bots
+// and is the entry point for embedding gogarin. AgentBot, in agent.go, owns the agent and its contracts and runs
+// the command loop that dispatches missions. ShipBot, in ship.go, owns a ship and runs the missions dispatched
+// to it. The three share one package because each calls into the others: missions take orders from and report
+// back to the AgentBot, and the AgentBot creates the ShipBots it dispatches.
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/clock"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/health"
+	"github.com/GeoffreyDick/gogarin/ledger"
+	"github.com/GeoffreyDick/gogarin/lib"
+	"github.com/GeoffreyDick/gogarin/logging"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/status"
+	"github.com/GeoffreyDick/gogarin/store"
+	"github.com/charmbracelet/log"
+)
+
+// ErrStopped is returned by Start once the Fleet has been stopped. A stopped Fleet cannot be restarted.
+var ErrStopped = errors.New("fleet stopped")
+
+// ErrNotStarted is returned by Order before the Fleet has started.
+var ErrNotStarted = errors.New("fleet not started")
+
+// FleetSnapshot is a point-in-time deep copy of the fleet, safe to use from any goroutine.
+type FleetSnapshot struct {
+	Agent      m.Agent             `json:"agent"`
+	Ships      []status.ShipStatus `json:"ships"`
+	Contracts  []m.Contract        `json:"contracts"`
+	Priorities []string            `json:"priorities"`
+	Deliveries DeliveryPlan        `json:"deliveries"`
+	// Cooldowns are the rolling average extraction cooldown of each ship, in seconds.
+	Cooldowns map[string]float64 `json:"cooldowns"`
+	TakenAt   time.Time          `json:"takenAt"`
+}
+
+/*
+🛰️ Fleet
+*/
+
+// Fleet runs an agent's AgentBot and ShipBots. It is the entry point for embedding gogarin in another program.
+type Fleet struct {
+	client     api.ClientAPI
+	live       *config.Live
+	bus        *event.Bus
+	board      *status.Board
+	markets    *store.MarketStore
+	shipyards  *store.ShipyardStore
+	fuelCosts  *store.FuelCosts
+	visits     *store.VisitLog
+	ledger     *ledger.Ledger
+	earnings   *ledger.Earnings
+	strategies *StrategySelector
+	journal    *store.Journal
+	state      *store.StateFile
+	metadata   *store.MetadataStore
+	monitor    *health.Monitor
+	clock      clock.Clock
+	rand       *lib.Rand
+	pause      *pauseGate
+	logger     *log.Logger
+	signals    bool
+	reloads    bool
+	pauses     bool
+
+	events     <-chan event.Event
+	eventsOnce sync.Once
+
+	mu      sync.Mutex
+	ab      *AgentBot
+	started bool
+	stopped bool
+	done    chan struct{}
+}
+
+// Option configures a Fleet.
+type Option func(*Fleet)
+
+// WithConfig sets the configuration the Fleet's bots are tuned by. It defaults to config.Default().
+func WithConfig(cfg *config.Config) Option {
+	return func(f *Fleet) {
+		f.live = config.NewLive(cfg, "")
+	}
+}
+
+// WithLiveConfig tunes the Fleet's bots by live, so a reloaded configuration takes effect on each ship's next
+// dispatch.
+func WithLiveConfig(live *config.Live) Option {
+	return func(f *Fleet) {
+		f.live = live
+	}
+}
+
+// WithBus publishes the Fleet's activity to bus instead of a bus of its own.
+func WithBus(bus *event.Bus) Option {
+	return func(f *Fleet) {
+		f.bus = bus
+	}
+}
+
+// WithBoard snapshots the fleet from board, which must already follow the Fleet's bus, instead of a board of its own.
+func WithBoard(board *status.Board) Option {
+	return func(f *Fleet) {
+		f.board = board
+	}
+}
+
+// WithMarkets shares a MarketStore with the Fleet, e.g. one a DryRunClient also reads.
+func WithMarkets(markets *store.MarketStore) Option {
+	return func(f *Fleet) {
+		f.markets = markets
+	}
+}
+
+// WithShipyards records the shipyard prices satellites observe in shipyards, which times ship purchases.
+// Without one, prices are not recorded and no purchase is timed.
+func WithShipyards(shipyards *store.ShipyardStore) Option {
+	return func(f *Fleet) {
+		f.shipyards = shipyards
+	}
+}
+
+// WithFuelCosts learns the fuel each route actually burns in fuelCosts, which then estimates trips in place
+// of the formula. Without one, trips are estimated by the formula alone.
+func WithFuelCosts(fuelCosts *store.FuelCosts) Option {
+	return func(f *Fleet) {
+		f.fuelCosts = fuelCosts
+	}
+}
+
+// WithVisits remembers what ships did at the waypoints they visit in visits, so they skip markets, charts, and
+// shipyards handled recently. Without one, only the market and shipyard stores' freshness is checked.
+func WithVisits(visits *store.VisitLog) Option {
+	return func(f *Fleet) {
+		f.visits = visits
+	}
+}
+
+// WithStrategies shares a StrategySelector with the Fleet, so strategies can be switched while it runs.
+func WithStrategies(strategies *StrategySelector) Option {
+	return func(f *Fleet) {
+		f.strategies = strategies
+	}
+}
+
+// WithJournal resumes and records multi-step missions in journal. Without one, missions are not journalled.
+func WithJournal(journal *store.Journal) Option {
+	return func(f *Fleet) {
+		f.journal = journal
+	}
+}
+
+// WithState trusts the journal as it stands when state shows the previous run shut down cleanly. Otherwise, and
+// without one, journalled missions are checked against the API's ships and contracts before any resumes.
+func WithState(state *store.StateFile) Option {
+	return func(f *Fleet) {
+		f.state = state
+	}
+}
+
+// WithEarnings shows what each ship earns per hour, as rated by earnings, in the fleet table.
+func WithEarnings(earnings *ledger.Earnings) Option {
+	return func(f *Fleet) {
+		f.earnings = earnings
+	}
+}
+
+// WithLedger logs the recent entries of ledger when the tracked credits are found to have drifted.
+func WithLedger(ledger *ledger.Ledger) Option {
+	return func(f *Fleet) {
+		f.ledger = ledger
+	}
+}
+
+// WithMetadata labels ships in logs, fleet tables, and snapshots with their nicknames in metadata.
+// A board given with WithBoard must be given the same metadata.
+func WithMetadata(metadata *store.MetadataStore) Option {
+	return func(f *Fleet) {
+		f.metadata = metadata
+	}
+}
+
+// WithMonitor pauses commands while monitor reports the API unavailable.
+func WithMonitor(monitor *health.Monitor) Option {
+	return func(f *Fleet) {
+		f.monitor = monitor
+	}
+}
+
+// WithClock times the Fleet's waits, cooldowns, schedules, and caches by c instead of the wall clock, e.g. a
+// clock.Fake for deterministic tests. The client's throttle keeps its own clock; see api.WithClock.
+func WithClock(c clock.Clock) Option {
+	return func(f *Fleet) {
+		f.clock = c
+	}
+}
+
+// WithRand draws the Fleet's randomness, such as mission IDs, from r, so a run can be repeated from r's seed.
+// Without one, the Fleet draws from a Rand with a seed of its own, which it logs at startup.
+func WithRand(r *lib.Rand) Option {
+	return func(f *Fleet) {
+		f.rand = r
+	}
+}
+
+// WithFleetSignal prints the fleet table whenever the process receives a fleet signal (SIGUSR1).
+func WithFleetSignal() Option {
+	return func(f *Fleet) {
+		f.signals = true
+	}
+}
+
+// WithReloadSignal reloads the configuration whenever the process receives a reload signal (SIGHUP).
+func WithReloadSignal() Option {
+	return func(f *Fleet) {
+		f.reloads = true
+	}
+}
+
+// WithPauseSignal pauses or resumes the Fleet whenever the process receives a pause signal (SIGUSR2).
+func WithPauseSignal() Option {
+	return func(f *Fleet) {
+		f.pauses = true
+	}
+}
+
+// New creates a Fleet for the agent client authenticates as. Nothing runs until Start.
+func New(client api.ClientAPI, opts ...Option) *Fleet {
+	f := &Fleet{client: client, pause: newPauseGate(), logger: logging.New("🛰️ FLEET"), done: make(chan struct{})}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	if f.live == nil {
+		f.live = config.NewLive(config.Default(), "")
+	}
+	if f.clock == nil {
+		f.clock = clock.Real
+	}
+	f.journal.SetClock(f.clock)
+	f.shipyards.SetClock(f.clock)
+	if f.rand == nil {
+		f.rand = lib.NewRand(lib.NewSeed())
+	}
+	if f.bus == nil {
+		f.bus = event.NewBus()
+	}
+	if f.board == nil {
+		f.board = status.NewBoard()
+		f.board.SetMetadata(f.metadata)
+		go f.board.Follow(f.bus.Subscribe(256))
+	}
+	if f.markets == nil {
+		f.markets = store.NewMarketStore()
+	}
+	if f.strategies == nil {
+		f.strategies = NewStrategySelector(f.live.Get())
+	}
+
+	return f
+}
+
+// Start verifies the agent, accepts its contracts, and gets every ship underway. It returns once the fleet
+// is running; the fleet runs until ctx is done or Stop is called. Starting a running Fleet does nothing.
+func (f *Fleet) Start(ctx context.Context) error {
+	f.mu.Lock()
+	switch {
+	case f.stopped:
+		f.mu.Unlock()
+		return ErrStopped
+	case f.started:
+		f.mu.Unlock()
+		return nil
+	}
+	f.started = true
+	f.mu.Unlock()
+
+	ab, ships, err := f.prepare()
+
+	f.mu.Lock()
+	switch {
+	case err != nil:
+		// A failed start can be retried.
+		f.started = false
+	case f.stopped:
+		err = ErrStopped
+	default:
+		f.ab = ab
+	}
+	f.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			f.Stop()
+		case <-f.done:
+		}
+	}()
+
+	f.launch(ab, ships)
+
+	return nil
+}
+
+// Stop stops the command loop and every pending dispatch. Missions already underway finish, but their ships
+// are not dispatched again. Stopping a stopped Fleet does nothing.
+func (f *Fleet) Stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.stopped {
+		return
+	}
+	f.stopped = true
+	close(f.done)
+
+	if f.ab != nil {
+		f.ab.scheduler.Stop()
+	}
+}
+
+// Done is closed once the Fleet is stopped.
+func (f *Fleet) Done() <-chan struct{} {
+	return f.done
+}
+
+// Pause stops dispatching missions: ships reporting in wait until Resume, and missions underway park at their
+// next API request when the client can hold requests (see api.Pauser). Caches and journalled missions are kept,
+// and a paused Fleet stays paused across configuration reloads. Pausing a paused Fleet does nothing.
+func (f *Fleet) Pause() {
+	if !f.pause.pause() {
+		return
+	}
+
+	if pauser, ok := f.client.(api.Pauser); ok {
+		pauser.Pause()
+	}
+	f.logger.Warn("⏸️ Fleet paused. Missions park at their next request.")
+	f.bus.Publish(event.Event{Type: event.FleetPaused, Message: "fleet paused"})
+}
+
+// Resume picks a paused Fleet back up. Each ship is re-synced from the API before its next mission.
+// Resuming a running Fleet does nothing.
+func (f *Fleet) Resume() {
+	if !f.pause.Paused() {
+		return
+	}
+
+	if pauser, ok := f.client.(api.Pauser); ok {
+		pauser.Resume()
+	}
+	if f.pause.resume() {
+		f.logger.Info("▶️ Fleet resumed.")
+		f.bus.Publish(event.Event{Type: event.FleetResumed, Message: "fleet resumed"})
+	}
+}
+
+// Paused reports whether the Fleet is paused.
+func (f *Fleet) Paused() bool {
+	return f.pause.Paused()
+}
+
+// Order gives a ship a manual order, checked against the ship's latest reported state. Any order but Resume
+// takes the ship off automatic missions once its current mission ends: it carries out its orders in turn, then
+// holds, until a Resume order. A holding ship stays held across restarts.
+func (f *Fleet) Order(shipSymbol string, order m.ManualOrder) error {
+	f.mu.Lock()
+	ab := f.ab
+	f.mu.Unlock()
+
+	if ab == nil {
+		return ErrNotStarted
+	}
+
+	shipSymbol, err := lib.NormalizeSymbol(shipSymbol)
+	if err != nil {
+		return err
+	}
+
+	for _, ss := range f.board.Snapshot().Ships {
+		if ss.Ship.Symbol != shipSymbol {
+			continue
+		}
+		if !ab.controls(ss.Ship) {
+			return fmt.Errorf("ship %s is left to manual control", shipSymbol)
+		}
+
+		return ab.Order(ss.Ship, order)
+	}
+
+	return fmt.Errorf("unknown ship %s", shipSymbol)
+}
+
+// pauseOnSignal pauses or resumes the Fleet whenever a pause signal (SIGUSR2) is received, until the Fleet
+// is stopped.
+func (f *Fleet) pauseOnSignal() {
+	if len(pauseSignals) == 0 {
+		return
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, pauseSignals...)
+	defer signal.Stop(sigCh)
+
+	for {
+		select {
+		case <-sigCh:
+		case <-f.done:
+			return
+		}
+
+		if f.Paused() {
+			f.Resume()
+		} else {
+			f.Pause()
+		}
+	}
+}
+
+// Reload re-reads the configuration, returning the settings that changed. Ships pick up the new settings at
+// their next dispatch; a changed strategy or role override replaces any set while the Fleet runs. An invalid
+// configuration is rejected and the current one kept. Restart-only settings are ignored; see config.Config.
+func (f *Fleet) Reload() ([]string, error) {
+	changed, err := f.live.Reload()
+	if err != nil || len(changed) == 0 {
+		return nil, err
+	}
+
+	cfg := f.live.Get()
+	if lib.Contains(changed, "strategy") || lib.Contains(changed, "roles") {
+		f.strategies.Configure(cfg)
+	}
+
+	f.mu.Lock()
+	ab := f.ab
+	f.mu.Unlock()
+
+	if ab != nil && lib.Contains(changed, "creditFloor") {
+		ab.agent.SetFloor(cfg.CreditFloor)
+	}
+
+	return changed, nil
+}
+
+// reloadOnSignal reloads the configuration whenever a reload signal (SIGHUP) is received, until the Fleet
+// is stopped.
+func (f *Fleet) reloadOnSignal(ab *AgentBot) {
+	if len(reloadSignals) == 0 {
+		return
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, reloadSignals...)
+	defer signal.Stop(sigCh)
+
+	for {
+		select {
+		case <-sigCh:
+		case <-f.done:
+			return
+		}
+
+		changed, err := f.Reload()
+		switch {
+		case err != nil:
+			ab.logger.Error("🔄 Rejected new configuration. Keeping the current one.", "error", err)
+		case len(changed) == 0:
+			ab.logger.Info("🔄 Configuration unchanged.")
+		default:
+			ab.logger.Info("🔄 Configuration reloaded.", "changed", changed)
+		}
+	}
+}
+
+// Events returns a channel receiving the Fleet's events. Every call returns the same channel. Events are
+// dropped while its buffer is full, so a slow reader never holds up the fleet.
+func (f *Fleet) Events() <-chan event.Event {
+	f.eventsOnce.Do(func() {
+		f.events = f.bus.Subscribe(256)
+	})
+
+	return f.events
+}
+
+// Snapshot returns a deep copy of the fleet's latest known state.
+func (f *Fleet) Snapshot() FleetSnapshot {
+	board := f.board.Snapshot()
+
+	snapshot := FleetSnapshot{
+		Agent:     board.Agent,
+		Ships:     make([]status.ShipStatus, 0, len(board.Ships)),
+		Contracts: make([]m.Contract, 0, len(board.Contracts)),
+		TakenAt:   board.TakenAt,
+	}
+
+	for _, s := range board.Ships {
+		s.Ship = s.Ship.Clone()
+		snapshot.Ships = append(snapshot.Ships, s)
+	}
+	for _, contract := range board.Contracts {
+		snapshot.Contracts = append(snapshot.Contracts, contract.Clone())
+	}
+
+	f.mu.Lock()
+	ab := f.ab
+	f.mu.Unlock()
+
+	if ab != nil {
+		plan := ab.Plan()
+		snapshot.Priorities = plan.Goods()
+		snapshot.Deliveries = append(DeliveryPlan(nil), plan...)
+
+		snapshot.Cooldowns = make(map[string]float64)
+		for ship, average := range ab.cooldowns.Averages() {
+			snapshot.Cooldowns[ship] = average.Seconds()
+		}
+	}
+
+	return snapshot
+}
+
+// prepare verifies the agent, accepts open contracts, plans deliveries, and lists the fleet.
+func (f *Fleet) prepare() (*AgentBot, []m.Ship, error) {
+	// Pass --seed with the logged seed to repeat the run's random choices.
+	f.logger.Info("🎲 Random seed.", "seed", f.rand.Seed())
+
+	// TerminalBot actions.
+	tb := NewTerminalBot(f.client)
+
+	// Get agent, verify, and welcome.
+	agent, err := tb.GetMyAgent()
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting agent: %w", err)
+	}
+	tb.logger.Infof("Agent verified. Welcome %s", agent.Symbol)
+	f.bus.Publish(event.Event{Type: event.AgentUpdated, Data: *agent})
+
+	// AgentBot actions.
+	ab := NewAgentBot(f.client, agent, f.markets, f.strategies, f.journal, f.bus, f.live.Get())
+	ab.live = f.live
+	ab.setClock(f.clock)
+	ab.rand = f.rand
+	ab.monitor = f.monitor
+	ab.pause = f.pause
+	ab.metadata = f.metadata
+	ab.shipyards = f.shipyards
+	ab.fuelCosts = f.fuelCosts
+	ab.visits = f.visits
+	ab.ledger = f.ledger
+	ab.earnings = f.earnings
+	go ab.systems.Follow(f.bus.Subscribe(64))
+	go ab.agent.Follow(f.bus.Subscribe(64))
+
+	// Resolve home base.
+	if err := ab.LoadHomeBase(); err != nil {
+		ab.logger.Warn("🏠 Error resolving home base.", "headquarters", agent.Headquarters, "error", err)
+	}
+	go ab.FollowHomeBase(f.bus.Subscribe(64))
+
+	// Summarize the home system from the waypoints the home base was resolved from.
+	if err := ab.SummarizeHomeSystem(); err != nil {
+		ab.logger.Warn("🗺️ Error summarizing home system.", "headquarters", agent.Headquarters, "error", err)
+	}
+
+	// Get contracts.
+	ab.logger.Info("Getting contracts...")
+	contracts, err := ab.GetMyContracts()
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting contracts: %w", err)
+	}
+	ab.logger.Info("Contracts retrieved.", "count", len(*contracts))
+	f.bus.Publish(event.Event{Type: event.ContractsUpdated, Data: *contracts})
+
+	for _, contract := range *contracts {
+		if err := contract.Validate(); err != nil {
+			ab.logger.Warn("Contract failed validation.", "error", err)
+		}
+	}
+
+	// Accept contracts if not already accepted.
+	for _, contract := range *contracts {
+		if !contract.Accepted && !contract.IsExpired(ab.clock.Now()) {
+			ab.logger.Info("Found new contract. Accepting...", "id", contract.ID)
+			contract, err := f.client.AcceptContract(contract.ID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("accepting contract: %w", err)
+			}
+			ab.logger.Info("Contract accepted.", contract.Contract.LogValues()...)
+			f.bus.Publish(event.Event{Type: event.ContractAccepted, Message: contract.Contract.ID, Data: contract.Contract})
+			f.bus.Publish(event.Event{Type: event.AgentUpdated, Data: contract.Agent})
+		}
+	}
+
+	// Plan deliveries.
+	plan := ab.PlanDeliveries(*contracts)
+	ab.logger.Info("Deliveries planned.", "deliveries", plan.String())
+	ab.SetPlan(plan)
+
+	// Get fleet.
+	ab.logger.Info("Waking fleet...")
+	ships, err := f.client.GetMyShips()
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting ships: %w", err)
+	}
+
+	for _, ship := range *ships {
+		if err := ship.Validate(); err != nil {
+			ab.logger.Warn("Ship failed validation.", "error", err)
+		}
+	}
+
+	// A run that did not shut down cleanly may have left the journal behind what ships and contracts did since.
+	if !f.state.Clean() {
+		ab.ReconcileJournal(*ships, *contracts)
+	}
+
+	ab.PrintFleet(*ships)
+
+	return ab, *ships, nil
+}
+
+// shipyardSweepInterval is how often the shipyard store is swept of ship types no shipyard lists any more.
+const shipyardSweepInterval = 10 * time.Minute
+
+// launch starts the reconcile and command loops, sends the command ship on its requisition mission, and
+// gets the fleet underway. It does not wait for the requisition mission.
+func (f *Fleet) launch(ab *AgentBot, ships []m.Ship) {
+	if f.signals {
+		go ab.PrintFleetOnSignal(f.board, f.done)
+	}
+	if f.reloads {
+		go f.reloadOnSignal(ab)
+	}
+	if f.pauses {
+		go f.pauseOnSignal()
+	}
+
+	// sbCh contains a ShipBot for each ship in the fleet.
+	// ShipBots sent to sbCh will be processed by the command loop.
+	// It holds a report from every ship, so missions can report while the loop is busy.
+	sbCh := make(chan ShipBot, len(ships))
+
+	// Start reconcile loop.
+	go ab.Reconcile(f.done)
+
+	if interval := f.live.Get().Expansion.Interval; interval > 0 {
+		go ab.Expand(interval, f.done)
+	}
+
+	if interval := f.live.Get().Purchase.Interval; interval > 0 && f.shipyards != nil {
+		go ab.Requisition(interval, f.done)
+	}
+
+	// Ship types no shipyard lists any more are swept from the shipyard store while the fleet runs.
+	sweep, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-f.done
+		cancel()
+	}()
+	go f.shipyards.SweepEvery(sweep, shipyardSweepInterval)
+
+	// Start ShipBot command loop.
+	// Each report is handled on its own goroutine, so a slow dispatch never blocks other ships.
+	go func() {
+		ab.logger.Info("Starting command loop...")
+		for {
+			select {
+			case sb := <-sbCh:
+				go ab.Command(sb, sbCh)
+			case <-f.done:
+				ab.logger.Info("Command loop stopped.")
+				return
+			}
+		}
+	}()
+
+	// Ships left to manual control are never commanded.
+	var controlled []m.Ship
+	for _, ship := range ships {
+		if !ab.controls(ship) {
+			ab.leaveToManual(ship)
+			continue
+		}
+		controlled = append(controlled, ship)
+	}
+
+	// Ships bought later are woken as soon as they are bought, or by the reconcile loop.
+	for _, ship := range controlled {
+		ab.markWoken(ship.Symbol)
+	}
+	ab.setWake(func(ship m.Ship, initial ...Mission) { go f.wake(ab, ship, sbCh, initial...) })
+
+	// The command ship goes on its requisition mission while the rest of the fleet gets underway, and joins
+	// it once the mission is over.
+	if len(controlled) > 0 {
+		ab.logger.Info("Sending command ship on requisition mission...")
+
+		ship := controlled[0]
+		controlled = controlled[1:]
+
+		sb := NewShipBot(f.client, &ship, ab.agent, ab.systems, ab.markets, f.bus, ab.config())
+		sb.label(f.metadata.Label(ship.Symbol))
+		sb.clock = ab.clock
+		sb.shipyards = ab.shipyards
+		sb.fuelCosts = ab.fuelCosts
+		sb.visits = ab.visits
+		sb.cooldowns = ab.cooldowns
+
+		go func() {
+			sb.InitiateRequisitionProtocol(ab)
+			f.wake(ab, *sb.ship, sbCh)
+		}()
+	}
+
+	// Get fleet underway.
+	go wakeAll(controlled, func(ship m.Ship) *ShipBot { return f.prepareWake(ab, ship) }, sbCh)
+}
+
+// wakeWorkers is the most ships woken at once when the fleet starts.
+const wakeWorkers = 3
+
+// wakeAll wakes every ship once, preparing its ShipBot with prepare at most wakeWorkers at a time. ShipBots are
+// prepared concurrently but report to the command loop in order of ship symbol, so the fleet is dispatched in
+// the same order on every start.
+func wakeAll(ships []m.Ship, prepare func(ship m.Ship) *ShipBot, sbCh chan ShipBot) {
+	ships = append([]m.Ship(nil), ships...)
+	sort.Slice(ships, func(i, j int) bool { return ships[i].Symbol < ships[j].Symbol })
+
+	woken := make([]chan *ShipBot, len(ships))
+	for i := range woken {
+		woken[i] = make(chan *ShipBot, 1)
+	}
+
+	queue := make(chan int)
+	for w := 0; w < wakeWorkers; w++ {
+		go func() {
+			for i := range queue {
+				woken[i] <- prepare(ships[i])
+			}
+		}()
+	}
+
+	go func() {
+		defer close(queue)
+		for i := range ships {
+			queue <- i
+		}
+	}()
+
+	for _, ch := range woken {
+		sb := <-ch
+		sb.Report(sbCh)
+	}
+}
+
+// wake creates a ShipBot for a ship and reports it to the command loop, resuming its journalled mission.
+// Initial missions are dispatched ahead of the strategy's choice. Waking makes no API request.
+func (f *Fleet) wake(ab *AgentBot, ship m.Ship, sbCh chan ShipBot, initial ...Mission) {
+	f.prepareWake(ab, ship, initial...).Report(sbCh)
+}
+
+// prepareWake creates the ShipBot a woken ship reports in with.
+func (f *Fleet) prepareWake(ab *AgentBot, ship m.Ship, initial ...Mission) *ShipBot {
+	sb := NewShipBot(f.client, &ship, ab.agent, ab.systems, ab.markets, f.bus, ab.config())
+	sb.label(f.metadata.Label(ship.Symbol))
+	sb.clock = ab.clock
+	sb.journal = ab.journal
+	sb.quotas = ab.quotas
+	sb.targets = ab.targets
+	sb.shipyards = ab.shipyards
+	sb.fuelCosts = ab.fuelCosts
+	sb.visits = ab.visits
+	sb.cooldowns = ab.cooldowns
+	sb.resuming = true
+	sb.queued = initial
+
+	// The reactor cooldown is fetched by the first mission that waits on it, not here, so waking a fleet
+	// costs no request per ship.
+	return sb
+}
+
+// RenderFleetTable renders an aligned table of ships' symbol, nickname, role, frame, status, waypoint, cargo,
+// fuel, and credits earned per hour, with nicknames from metadata and earnings from rates. Ships without a
+// nickname, fuel, cargo capacity, or rate show "-" in those columns, and ships the configuration leaves to
+// manual control show MANUAL as their status.
+func RenderFleetTable(ships []m.Ship, metadata *store.MetadataStore, cfg *config.Config, rates ledger.Rates) string {
+	var b strings.Builder
+
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SYMBOL\tNAME\tROLE\tFRAME\tSTATUS\tWAYPOINT\tCARGO\tFUEL\tCR/H")
+	for _, ship := range ships {
+		nickname := metadata.Nickname(ship.Symbol)
+		if nickname == "" {
+			nickname = "-"
+		}
+
+		status := ship.Nav.Status
+		if !cfg.Controls(ship.Symbol, ship.Registration.Role) {
+			status = "MANUAL"
+		}
+
+		perHour := "-"
+		if rate, ok := rates.Ship(ship.Symbol); ok && rate.Rated() {
+			perHour = fmt.Sprintf("%.0f", rate.PerHour)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			ship.Symbol, nickname, ship.Registration.Role, ship.Frame.Name, status, ship.Nav.WaypointSymbol,
+			capacity(ship.Cargo.Units, ship.Cargo.Capacity), capacity(ship.Fuel.Current, ship.Fuel.Capacity), perHour)
+	}
+	tw.Flush()
+
+	return b.String()
+}
+
+// capacity formats a current/capacity pair, or "-" when there is no capacity.
+func capacity(current int, capacity int) string {
+	if capacity <= 0 {
+		return "-"
+	}
+
+	return fmt.Sprintf("%d/%d", current, capacity)
+}
@@ -0,0 +1,834 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/clock"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/lib"
+	"github.com/GeoffreyDick/gogarin/logging"
+	"github.com/GeoffreyDick/gogarin/mockserver"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+	"github.com/GeoffreyDick/gogarin/telemetry"
+)
+
+// startFleet starts a Fleet against a mock server, stopping it when the test ends.
+func startFleet(t *testing.T) *Fleet {
+	t.Helper()
+
+	_, c := startMock(t, 0)
+
+	cfg := config.Default()
+	cfg.IdleInterval = time.Millisecond
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+
+	f := New(c, WithConfig(cfg), WithBus(bus))
+	t.Cleanup(f.Stop)
+	if err := f.Start(context.Background()); err != nil {
+		t.Fatalf("starting fleet: %s", err)
+	}
+
+	return f
+}
+
+// epoch is when the simulated sessions of the tests start.
+var epoch = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// step is how far the fake clock of a simulation moves each millisecond of real time.
+const step = 5 * time.Second
+
+// simulation runs a Fleet against a mock server, both on a fake clock that is advanced a step at a time while
+// the fleet runs, and records the events the fleet publishes.
+type simulation struct {
+	server *mockserver.Server
+	client *api.Client
+	clock  *clock.Fake
+	fleet  *Fleet
+
+	mu     sync.Mutex
+	events []event.Event
+}
+
+// simulate starts a Fleet, configured by opts, against a mock server seeded from f. The fleet and server are
+// stopped when the test ends.
+func simulate(t *testing.T, f *mockserver.Fixture, opts ...Option) *simulation {
+	t.Helper()
+
+	fake := clock.NewFake(epoch)
+	server := mockserver.New(f, mockserver.WithClock(fake))
+	client := api.NewClient("token", api.WithBaseURL(server.URL), api.WithRateLimit(1000), api.WithClock(fake))
+	bus := event.NewBus()
+
+	s := &simulation{server: server, client: client, clock: fake}
+	s.fleet = New(client, append([]Option{WithClock(fake), WithBus(bus), WithRand(lib.NewRand(1))}, opts...)...)
+
+	recorded := make(chan struct{})
+	events := bus.Subscribe(1024)
+	go func() {
+		defer close(recorded)
+		for e := range events {
+			s.mu.Lock()
+			s.events = append(s.events, e)
+			s.mu.Unlock()
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	running := make(chan struct{})
+	go func() {
+		defer close(running)
+		for ctx.Err() == nil {
+			fake.Advance(step)
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	t.Cleanup(func() {
+		s.fleet.Stop()
+		cancel()
+		<-running
+		server.Close()
+		bus.Close()
+		<-recorded
+	})
+
+	if err := s.fleet.Start(ctx); err != nil {
+		t.Fatalf("starting fleet: %s", err)
+	}
+
+	return s
+}
+
+// fixture returns the mock server's default universe.
+func fixture(t *testing.T) *mockserver.Fixture {
+	t.Helper()
+
+	f, err := mockserver.DefaultFixture()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+// Events returns the events of a type the fleet has published so far.
+func (s *simulation) Events(typ event.Type) []event.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []event.Event
+	for _, e := range s.events {
+		if e.Type == typ {
+			events = append(events, e)
+		}
+	}
+
+	return events
+}
+
+// waitFor polls cond until it holds, failing the test if it does not within timeout of real time.
+func (s *simulation) waitFor(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out after %s waiting for %s", timeout, what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestFleetStartAndStopAreIdempotent(t *testing.T) {
+	f := startFleet(t)
+
+	if err := f.Start(context.Background()); err != nil {
+		t.Errorf("starting a running fleet: %s", err)
+	}
+
+	f.Stop()
+	f.Stop()
+	select {
+	case <-f.Done():
+	default:
+		t.Fatal("Done not closed after Stop")
+	}
+
+	if err := f.Start(context.Background()); !errors.Is(err, ErrStopped) {
+		t.Errorf("starting a stopped fleet: err = %v, want ErrStopped", err)
+	}
+}
+
+func TestFleetStopsWithItsContext(t *testing.T) {
+	_, c := startMock(t, 0)
+	f := New(c)
+	t.Cleanup(f.Stop)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := f.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	select {
+	case <-f.Done():
+	case <-time.After(time.Second):
+		t.Fatal("fleet still running after its context was cancelled")
+	}
+}
+
+func TestFleetSnapshotIsADeepCopy(t *testing.T) {
+	f := startFleet(t)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if s := f.Snapshot(); len(s.Ships) > 0 && len(s.Contracts) > 0 && len(s.Priorities) > 0 && len(s.Deliveries) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no ships, contracts, priorities and deliveries in the snapshot: %+v", f.Snapshot())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Readers snapshot the fleet, and scribble over their copies, while it runs.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				s := f.Snapshot()
+				for k := range s.Ships {
+					s.Ships[k].Ship.Symbol = "SCRIBBLED"
+					for l := range s.Ships[k].Ship.Cargo.Inventory {
+						s.Ships[k].Ship.Cargo.Inventory[l].Symbol = "SCRIBBLED"
+					}
+				}
+				for k := range s.Contracts {
+					s.Contracts[k].ID = "SCRIBBLED"
+					for l := range s.Contracts[k].Terms.Deliver {
+						s.Contracts[k].Terms.Deliver[l].TradeSymbol = "SCRIBBLED"
+					}
+				}
+				for k := range s.Priorities {
+					s.Priorities[k] = "SCRIBBLED"
+				}
+				for k := range s.Deliveries {
+					s.Deliveries[k].TradeSymbol = "SCRIBBLED"
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	s := f.Snapshot()
+	for _, ship := range s.Ships {
+		if ship.Ship.Symbol == "SCRIBBLED" {
+			t.Errorf("ship changed through an earlier snapshot")
+		}
+		for _, item := range ship.Ship.Cargo.Inventory {
+			if item.Symbol == "SCRIBBLED" {
+				t.Errorf("ship %s cargo changed through an earlier snapshot", ship.Ship.Symbol)
+			}
+		}
+	}
+	for _, contract := range s.Contracts {
+		if contract.ID == "SCRIBBLED" {
+			t.Errorf("contract changed through an earlier snapshot")
+		}
+		for _, deliver := range contract.Terms.Deliver {
+			if deliver.TradeSymbol == "SCRIBBLED" {
+				t.Errorf("contract %s terms changed through an earlier snapshot", contract.ID)
+			}
+		}
+	}
+	for _, good := range s.Priorities {
+		if good == "SCRIBBLED" {
+			t.Errorf("priorities %v changed through an earlier snapshot", s.Priorities)
+		}
+	}
+	for _, delivery := range s.Deliveries {
+		if delivery.TradeSymbol == "SCRIBBLED" {
+			t.Errorf("deliveries %v changed through an earlier snapshot", s.Deliveries)
+		}
+	}
+}
+
+func TestFleetLogsShipsByNickname(t *testing.T) {
+	var out lockedBuffer
+	logs, err := logging.NewFactory(&out, logging.Options{Level: "info", Format: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := logging.Default()
+	t.Cleanup(func() { logging.SetDefault(previous) })
+	logging.SetDefault(logs)
+
+	_, c := startMock(t, 0)
+
+	metadata, err := store.OpenMetadataStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := metadata.Rename("MOCK-2", "Rockhound"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	cfg.IdleInterval = time.Millisecond
+	f := New(c, WithConfig(cfg), WithMetadata(metadata))
+	t.Cleanup(f.Stop)
+	if err := f.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var named bool
+		for _, s := range f.Snapshot().Ships {
+			named = named || (s.Ship.Symbol == "MOCK-2" && s.Nickname == "Rockhound" && s.Mission != "")
+		}
+		if named {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("MOCK-2 not named in the fleet snapshot: %+v", f.Snapshot().Ships)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	logged := out.snapshot()
+	if len(logLines(t, logged, `🚀 MOCK-2 "Rockhound":`)) == 0 {
+		t.Errorf("no logs prefixed with the nickname:\n%s", logged)
+	}
+}
+
+// shipMutations are the mock server's endpoints that change a ship.
+var shipMutations = []string{"navigate", "dock", "orbit", "extract", "sell", "jettison", "purchase", "chart"}
+
+// mutations counts the requests made to change a ship.
+func mutations(s *mockserver.Server, shipSymbol string) int {
+	var n int
+	for _, action := range shipMutations {
+		n += s.Requests("POST", "/my/ships/"+shipSymbol+"/"+action)
+	}
+	return n
+}
+
+func TestFleetNeverCommandsShipsLeftToManualControl(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(cfg *config.Config)
+		commanded bool
+	}{
+		{"no filter", func(cfg *config.Config) {}, true},
+		{"ignored ship", func(cfg *config.Config) { cfg.IgnoredShips = []string{"MOCK-2"} }, false},
+		{"controlled ships", func(cfg *config.Config) { cfg.ControlledShips = []string{"MOCK-1"} }, false},
+		{"ignored role", func(cfg *config.Config) {
+			cfg.Roles = map[string]config.RoleConfig{"EXCAVATOR": {Ignored: true}}
+		}, false},
+		{"controlled despite its role", func(cfg *config.Config) {
+			cfg.ControlledShips = []string{"MOCK-1", "MOCK-2"}
+			cfg.Roles = map[string]config.RoleConfig{"EXCAVATOR": {Ignored: true}}
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, c := startMock(t, 0)
+
+			cfg := config.Default()
+			cfg.IdleInterval = time.Millisecond
+			tt.configure(cfg)
+			f := New(c, WithConfig(cfg))
+			t.Cleanup(f.Stop)
+			if err := f.Start(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			// Wait for the fleet to be launched, then have the reconcile loop find MOCK-2 again, as it would a
+			// ship just bought.
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				f.mu.Lock()
+				ab := f.ab
+				f.mu.Unlock()
+				if ab != nil {
+					ab.mu.Lock()
+					launched := ab.wake != nil
+					ab.mu.Unlock()
+					if launched {
+						ab.ReconcileShips()
+						break
+					}
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("fleet not launched")
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			// A commanded excavator is soon mining; one left to manual control never is.
+			wait := time.Now().Add(200 * time.Millisecond)
+			if tt.commanded {
+				wait = time.Now().Add(5 * time.Second)
+			}
+			for mutations(s, "MOCK-2") == 0 && time.Now().Before(wait) {
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			if got := mutations(s, "MOCK-2") > 0; got != tt.commanded {
+				t.Errorf("MOCK-2 commanded = %t, want %t", got, tt.commanded)
+			}
+			if !tt.commanded {
+				for _, ship := range f.Snapshot().Ships {
+					if ship.Ship.Symbol == "MOCK-2" && ship.Mission != "" {
+						t.Errorf("MOCK-2 given mission %q", ship.Mission)
+					}
+				}
+			}
+		})
+	}
+}
+
+// lockedBuffer is a log destination safe for the fleet's ships to write to at once.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+// snapshot copies what has been written so far, as ships may still be logging.
+func (b *lockedBuffer) snapshot() *bytes.Buffer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return bytes.NewBuffer(append([]byte(nil), b.buf.Bytes()...))
+}
+
+func TestFleetReloadSwitchesStrategiesAndRejectsInvalidFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gogarin.yaml")
+	write := func(contents string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("strategy: mining\n")
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, c := startMock(t, 0)
+	f := New(c, WithLiveConfig(config.NewLive(cfg, path)))
+	t.Cleanup(f.Stop)
+
+	write("strategy: trading\nroles:\n  EXCAVATOR:\n    strategy: contract\n")
+	changed, err := f.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"strategy", "roles"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+	if fallback, byRole := f.strategies.Strategies(); fallback != "trading" || byRole["EXCAVATOR"] != "contract" {
+		t.Errorf("strategies = %s, %v after reload, want trading with EXCAVATOR on contract", fallback, byRole)
+	}
+
+	write("strategy: gambling\n")
+	if _, err := f.Reload(); err == nil {
+		t.Error("reloaded an unknown strategy")
+	}
+	if fallback, _ := f.strategies.Strategies(); fallback != "trading" || f.live.Get().Strategy != "trading" {
+		t.Errorf("strategy = %s, config %s after a rejected reload, want trading", fallback, f.live.Get().Strategy)
+	}
+}
+
+func TestFleetCompletesMineSellCycleOnFakeClock(t *testing.T) {
+	started := time.Now()
+	s := simulate(t, fixture(t))
+
+	// The excavator travels to the asteroid field, mines through several reactor cooldowns until its hold is
+	// full, and sells at a market: well over ten minutes of game time.
+	s.waitFor(t, 10*time.Second, "a mine-sell cycle", func() bool {
+		for _, e := range s.Events(event.CargoSold) {
+			if e.Ship == "MOCK-2" {
+				return true
+			}
+		}
+		return false
+	})
+
+	if elapsed := time.Since(started); elapsed > time.Second && !raceEnabled {
+		t.Errorf("mine-sell cycle took %s of real time, want under a second", elapsed)
+	}
+	if simulated := s.clock.Now().Sub(epoch); simulated < 5*time.Minute {
+		t.Errorf("mine-sell cycle took %s of game time, want several cooldowns", simulated)
+	}
+}
+
+func TestPausedFleetMakesNoMutatingCallsUntilResumed(t *testing.T) {
+	s := simulate(t, fixture(t))
+
+	// Pause mid-cycle, once the excavator is underway.
+	s.waitFor(t, 10*time.Second, "the excavator to get underway", func() bool {
+		return mutations(s.server, "MOCK-2") >= 2
+	})
+	s.fleet.Pause()
+
+	// A request already sent completes; nothing after it is sent, however much game time passes.
+	time.Sleep(50 * time.Millisecond)
+	if !s.fleet.Paused() || len(s.Events(event.FleetPaused)) != 1 {
+		t.Fatalf("paused %t with %d pause events, want paused with one", s.fleet.Paused(), len(s.Events(event.FleetPaused)))
+	}
+	parked := map[string]int{"MOCK-1": mutations(s.server, "MOCK-1"), "MOCK-2": mutations(s.server, "MOCK-2")}
+	resyncs := s.server.Requests("GET", "/my/ships/MOCK-2")
+	paused := s.clock.Now()
+	time.Sleep(200 * time.Millisecond)
+	if _, err := s.fleet.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	if !s.fleet.Paused() {
+		t.Error("a configuration reload resumed the fleet")
+	}
+	for ship, n := range parked {
+		if now := mutations(s.server, ship); now != n {
+			t.Errorf("%s made %d mutating calls over %s of game time paused", ship, now-n, s.clock.Now().Sub(paused))
+		}
+	}
+
+	s.fleet.Resume()
+	s.waitFor(t, 10*time.Second, "the excavator to carry on", func() bool {
+		return mutations(s.server, "MOCK-2") > parked["MOCK-2"] && len(s.Events(event.FleetResumed)) > 0
+	})
+	if s.fleet.Paused() || len(s.Events(event.FleetResumed)) != 1 {
+		t.Errorf("paused %t with %d resume events, want running with one", s.fleet.Paused(), len(s.Events(event.FleetResumed)))
+	}
+
+	// The excavator's parked mission carries on, and its state is re-synced before its next mission.
+	s.waitFor(t, 10*time.Second, "the excavator to be re-synced", func() bool {
+		return s.server.Requests("GET", "/my/ships/MOCK-2") > resyncs
+	})
+}
+
+func TestStartDoesNotWaitForRequisition(t *testing.T) {
+	f := fixture(t)
+
+	// The only shipyard is a long way out, so the command ship's requisition mission takes over an hour of
+	// game time.
+	for i := range f.Waypoints {
+		var traits []m.WaypointTrait
+		for _, trait := range f.Waypoints[i].Traits {
+			if trait.Symbol != "SHIPYARD" {
+				traits = append(traits, trait)
+			}
+		}
+		if f.Waypoints[i].Symbol == "X1-MK1-D4" {
+			f.Waypoints[i].X, f.Waypoints[i].Y = -4000, 3000
+			traits = append(traits, m.WaypointTrait{Symbol: "SHIPYARD"})
+		}
+		f.Waypoints[i].Traits = traits
+	}
+	f.Shipyards[0].Symbol = "X1-MK1-D4"
+	f.Ships[0].Fuel.Current, f.Ships[0].Fuel.Capacity = 20000, 20000
+
+	s := simulate(t, f)
+
+	if n := s.server.Requests("POST", "/my/ships"); n != 0 {
+		t.Fatalf("%d ships purchased before Start returned, want it not to wait for the requisition mission", n)
+	}
+
+	s.waitFor(t, 30*time.Second, "the excavator to mine while the command ship is away", func() bool {
+		for _, e := range s.Events(event.ResourcesExtracted) {
+			if e.Ship == "MOCK-2" {
+				return len(s.Events(event.ShipPurchased)) == 0
+			}
+		}
+		return false
+	})
+	s.waitFor(t, 30*time.Second, "a ship purchase", func() bool {
+		return len(s.Events(event.ShipPurchased)) > 0
+	})
+}
+
+func TestPurchasedShipMinesInSameSession(t *testing.T) {
+	s := simulate(t, fixture(t))
+
+	var bought string
+	s.waitFor(t, 10*time.Second, "a ship purchase", func() bool {
+		if purchases := s.Events(event.ShipPurchased); len(purchases) > 0 {
+			bought = purchases[0].Ship
+			return true
+		}
+		return false
+	})
+
+	s.waitFor(t, 10*time.Second, fmt.Sprintf("%s to mine", bought), func() bool {
+		for _, e := range s.Events(event.ResourcesExtracted) {
+			if e.Ship == bought {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// telemetryOf replays the events a simulation has published through a telemetry file and returns the records
+// of a ship, without their times. Ships race the clock the simulation advances, so when they act moves from
+// run to run even when what they do does not.
+func telemetryOf(t *testing.T, s *simulation, ship string) []telemetry.Record {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "telemetry.jsonl")
+	w, err := telemetry.Open(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	for _, e := range s.events {
+		if e.Ship == ship {
+			if err := w.Write(e); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	s.mu.Unlock()
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	records, err := telemetry.Read(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range records {
+		records[i].At = time.Time{}
+		records[i].Data = timeless(t, records[i].Data)
+	}
+
+	return records
+}
+
+// timeless drops every timestamp from a record's data.
+func timeless(t *testing.T, data json.RawMessage) json.RawMessage {
+	t.Helper()
+
+	if len(data) == 0 {
+		return data
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	var strip func(v interface{}) interface{}
+	strip = func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for key, value := range v {
+				if s, ok := value.(string); ok {
+					if _, err := time.Parse(time.RFC3339, s); err == nil {
+						delete(v, key)
+						continue
+					}
+				}
+				v[key] = strip(value)
+			}
+		case []interface{}:
+			for i := range v {
+				v[i] = strip(v[i])
+			}
+		}
+		return v
+	}
+
+	stripped, err := json.Marshal(strip(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return stripped
+}
+
+func TestSameSeedReplaysSameTelemetry(t *testing.T) {
+	// The excavator flies alone: the agent cannot afford another ship, so nothing races it for the seed.
+	run := func(seed int64) []telemetry.Record {
+		f := fixture(t)
+		f.Ships = f.Ships[1:]
+		f.Agent.Credits = 1000
+
+		s := simulate(t, f, WithRand(lib.NewRand(seed)))
+
+		const records = 20
+		var stream []telemetry.Record
+		s.waitFor(t, 10*time.Second, fmt.Sprintf("%d telemetry records", records), func() bool {
+			stream = telemetryOf(t, s, "MOCK-2")
+			return len(stream) >= records
+		})
+
+		return stream[:records]
+	}
+
+	first, replay := run(42), run(42)
+	if !reflect.DeepEqual(first, replay) {
+		t.Fatalf("telemetry of two runs seeded 42 differs:\n%s", diffRecords(first, replay))
+	}
+
+	other := run(43)
+	if reflect.DeepEqual(first, other) {
+		t.Fatal("telemetry of runs seeded 42 and 43 is the same, want different mission IDs")
+	}
+}
+
+// diffRecords lists the records that differ between two telemetry streams of the same length.
+func diffRecords(a, b []telemetry.Record) string {
+	var diff strings.Builder
+	for i := range a {
+		if !reflect.DeepEqual(a[i], b[i]) {
+			fmt.Fprintf(&diff, "%d:\n  %+v\n  %+v\n", i, a[i], b[i])
+		}
+	}
+
+	return diff.String()
+}
+
+func TestWakeUpFetchesNoCooldowns(t *testing.T) {
+	const ships = 15
+
+	f := fixture(t)
+	for i := len(f.Ships); i < ships; i++ {
+		ship := f.Ships[1]
+		ship.Symbol = fmt.Sprintf("MOCK-%d", i+1)
+		ship.Registration.Name = ship.Symbol
+		f.Ships = append(f.Ships, ship)
+	}
+
+	// The fleet's clock never moves, so every ship wakes and sets off on its first flight, but none arrives
+	// anywhere to mine. The client throttles by the wall clock, so requests still go out.
+	fake := clock.NewFake(epoch)
+	server := mockserver.New(f, mockserver.WithClock(fake))
+	t.Cleanup(server.Close)
+	client := api.NewClient("token", api.WithBaseURL(server.URL), api.WithRateLimit(1000))
+
+	bus := event.NewBus()
+	navigated := bus.Subscribe(1024)
+	fleet := New(client, WithClock(fake), WithBus(bus), WithRand(lib.NewRand(1)))
+	t.Cleanup(fleet.Stop)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := fleet.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	excavators := make(map[string]bool)
+	timeout := time.After(10 * time.Second)
+	for len(excavators) < ships-1 {
+		select {
+		case e := <-navigated:
+			if e.Type == event.ShipNavigated && e.Ship != "MOCK-1" {
+				excavators[e.Ship] = true
+			}
+		case <-timeout:
+			t.Fatalf("%d of %d excavators set off", len(excavators), ships-1)
+		}
+	}
+
+	var fetched int
+	for i := 1; i <= ships+1; i++ {
+		fetched += server.Requests("GET", fmt.Sprintf("/my/ships/MOCK-%d/cooldown", i))
+	}
+	if fetched != 0 {
+		t.Fatalf("%d cooldowns fetched waking %d ships, want none", fetched, ships)
+	}
+}
+
+func TestWakeAllWakesEachShipOnceInSymbolOrder(t *testing.T) {
+	const ships = 30
+
+	var fleet []m.Ship
+	for i := ships; i > 0; i-- {
+		fleet = append(fleet, m.Ship{Symbol: fmt.Sprintf("MOCK-%02d", i)})
+	}
+
+	var (
+		mu             sync.Mutex
+		prepared       = make(map[string]int)
+		inFlight, peak int
+	)
+	prepare := func(ship m.Ship) *ShipBot {
+		mu.Lock()
+		prepared[ship.Symbol]++
+		if inFlight++; inFlight > peak {
+			peak = inFlight
+		}
+		mu.Unlock()
+
+		// Hold the worker long enough for the others to pile in.
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		return &ShipBot{ship: &ship, clock: clock.Real}
+	}
+
+	sbCh := make(chan ShipBot)
+	go wakeAll(fleet, prepare, sbCh)
+
+	for i := 1; i <= ships; i++ {
+		select {
+		case sb := <-sbCh:
+			if want := fmt.Sprintf("MOCK-%02d", i); sb.ship.Symbol != want {
+				t.Fatalf("report %d from %s, want %s", i, sb.ship.Symbol, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%d of %d ships reported", i-1, ships)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for symbol, n := range prepared {
+		if n != 1 {
+			t.Errorf("%s prepared %d times, want once", symbol, n)
+		}
+	}
+	if len(prepared) != ships {
+		t.Errorf("%d ships prepared, want %d", len(prepared), ships)
+	}
+	if peak > wakeWorkers {
+		t.Errorf("%d ships prepared at once, want at most %d", peak, wakeWorkers)
+	}
+}
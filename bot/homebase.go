@@ -0,0 +1,148 @@
+package bot
+
+import (
+	"errors"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/lib"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+/*
+🏠 HomeBase
+*/
+
+// HomeBase caches the agent's headquarters and the nearest key waypoints in its system.
+// A waypoint the system does not have is nil.
+type HomeBase struct {
+	System       string
+	Headquarters m.Waypoint
+	Shipyard     *m.Waypoint
+	Marketplace  *m.Waypoint
+	FuelStation  *m.Waypoint
+}
+
+func (h HomeBase) LogValues() []interface{} {
+	return []interface{}{
+		"system", h.System,
+		"headquarters", h.Headquarters.Symbol,
+		"shipyard", symbolOf(h.Shipyard),
+		"marketplace", symbolOf(h.Marketplace),
+		"fuelStation", symbolOf(h.FuelStation),
+	}
+}
+
+// symbolOf returns a waypoint's symbol, or "none" for no waypoint.
+func symbolOf(waypoint *m.Waypoint) string {
+	if waypoint == nil {
+		return "none"
+	}
+
+	return waypoint.Symbol
+}
+
+// buildHomeBase finds the headquarters among the waypoints of its system, and the shipyard, marketplace,
+// and fuel station nearest to it.
+func buildHomeBase(headquarters string, waypoints []m.Waypoint) (*HomeBase, error) {
+	systemSymbol, err := lib.SystemSymbolOf(headquarters)
+	if err != nil {
+		return nil, err
+	}
+
+	hq := lib.Filter(waypoints, func(waypoint m.Waypoint) bool {
+		return waypoint.Symbol == headquarters
+	})
+	if len(hq) == 0 {
+		return nil, errors.New("headquarters not found in its system")
+	}
+
+	nearest := func(match func(waypoint m.Waypoint) bool) *m.Waypoint {
+		candidates := lib.Filter(waypoints, match)
+		waypoint, err := lib.NearestWaypoint(&hq[0], &candidates)
+		if err != nil {
+			return nil
+		}
+		return waypoint
+	}
+
+	return &HomeBase{
+		System:       systemSymbol,
+		Headquarters: hq[0],
+		Shipyard:     nearest(func(waypoint m.Waypoint) bool { return waypoint.HasTrait("SHIPYARD") }),
+		Marketplace:  nearest(func(waypoint m.Waypoint) bool { return waypoint.HasTrait("MARKETPLACE") }),
+		FuelStation:  nearest(func(waypoint m.Waypoint) bool { return waypoint.IsType("FUEL_STATION") }),
+	}, nil
+}
+
+// HomeBase returns the agent's cached home base, or nil before it is loaded.
+func (ab *AgentBot) HomeBase() *HomeBase {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	return ab.home
+}
+
+// LoadHomeBase builds the home base from the known waypoints of the headquarters system, fetching them
+// the first time.
+func (ab *AgentBot) LoadHomeBase() error {
+	headquarters := ab.agent.Agent().Headquarters
+
+	systemSymbol, err := lib.SystemSymbolOf(headquarters)
+	if err != nil {
+		return err
+	}
+
+	waypoints, err := ab.systems.Waypoints(systemSymbol)
+	if err != nil {
+		return err
+	}
+
+	home, err := buildHomeBase(headquarters, waypoints)
+	if err != nil {
+		return err
+	}
+
+	ab.mu.Lock()
+	ab.home = home
+	ab.mu.Unlock()
+
+	ab.logger.Info("🏠 Home base resolved.", home.LogValues()...)
+
+	return nil
+}
+
+// RefreshHomeBase fetches the waypoints of the headquarters system again and rebuilds the home base.
+func (ab *AgentBot) RefreshHomeBase() error {
+	systemSymbol, err := lib.SystemSymbolOf(ab.agent.Agent().Headquarters)
+	if err != nil {
+		return err
+	}
+
+	if err := ab.systems.Refresh(systemSymbol); err != nil {
+		return err
+	}
+
+	return ab.LoadHomeBase()
+}
+
+// FollowHomeBase rebuilds the home base whenever a waypoint in the headquarters system is charted, until
+// the channel is closed.
+func (ab *AgentBot) FollowHomeBase(events <-chan event.Event) {
+	for e := range events {
+		waypoint, ok := e.Data.(m.Waypoint)
+		if e.Type != event.WaypointCharted || !ok {
+			continue
+		}
+
+		home := ab.HomeBase()
+		if home == nil || waypoint.SystemSymbol != home.System {
+			continue
+		}
+
+		// The charted waypoint may not have reached SystemKnowledge yet; putting it again is harmless.
+		ab.systems.Put(waypoint)
+		if err := ab.LoadHomeBase(); err != nil {
+			ab.logger.Warn("🏠 Error rebuilding home base.", "error", err)
+		}
+	}
+}
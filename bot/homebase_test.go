@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/mockserver"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+// homeWaypoints returns the fixture system with a second shipyard and two fuel stations, the farther of each
+// listed last.
+func homeWaypoints(t *testing.T) []m.Waypoint {
+	t.Helper()
+
+	f, err := mockserver.DefaultFixture()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return append(f.Waypoints,
+		m.Waypoint{Symbol: "X1-MK1-G7", SystemSymbol: "X1-MK1", Type: "FUEL_STATION", X: 30},
+		m.Waypoint{Symbol: "X1-MK1-F6", SystemSymbol: "X1-MK1", Type: "PLANET", X: 50, Y: 50, Traits: []m.WaypointTrait{{Symbol: "SHIPYARD"}}},
+		m.Waypoint{Symbol: "X1-MK1-H8", SystemSymbol: "X1-MK1", Type: "FUEL_STATION", X: 60},
+	)
+}
+
+func TestBuildHomeBase(t *testing.T) {
+	waypoints := homeWaypoints(t)
+
+	tests := []struct {
+		name         string
+		headquarters string
+		waypoints    []m.Waypoint
+		want         []string
+	}{
+		{"headquarters at the starter market and shipyard", "X1-MK1-A1", waypoints, []string{"X1-MK1-A1", "X1-MK1-A1", "X1-MK1-A1", "X1-MK1-G7"}},
+		{"headquarters without traits", "X1-MK1-D4", waypoints, []string{"X1-MK1-D4", "X1-MK1-A1", "X1-MK1-A1", "X1-MK1-G7"}},
+		{"no fuel station", "X1-MK1-C3", waypoints[:4], []string{"X1-MK1-C3", "X1-MK1-A1", "X1-MK1-C3", "none"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home, err := buildHomeBase(tt.headquarters, tt.waypoints)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{home.Headquarters.Symbol, symbolOf(home.Shipyard), symbolOf(home.Marketplace), symbolOf(home.FuelStation)}
+			for i, kind := range []string{"headquarters", "shipyard", "marketplace", "fuel station"} {
+				if got[i] != tt.want[i] {
+					t.Errorf("%s = %s, want %s", kind, got[i], tt.want[i])
+				}
+			}
+			if home.System != "X1-MK1" {
+				t.Errorf("system = %s, want X1-MK1", home.System)
+			}
+		})
+	}
+
+	if _, err := buildHomeBase("X1-MK1-Z9", waypoints); err == nil {
+		t.Error("built a home base around a headquarters its system does not list")
+	}
+	if _, err := buildHomeBase("nowhere", waypoints); err == nil {
+		t.Error("built a home base around a malformed headquarters")
+	}
+}
+
+func TestHomeBaseFollowsCharting(t *testing.T) {
+	cfg := config.Default()
+
+	c := &contractClient{waypoints: homeWaypoints(t)}
+	ab := NewAgentBot(c, &m.Agent{Symbol: "MOCK", Headquarters: "X1-MK1-A1"}, store.NewMarketStore(), NewStrategySelector(cfg), nil, nil, cfg)
+
+	if ab.HomeBase() != nil {
+		t.Fatal("home base known before it was loaded")
+	}
+	if err := ab.LoadHomeBase(); err != nil {
+		t.Fatal(err)
+	}
+	if home := ab.HomeBase(); symbolOf(home.FuelStation) != "X1-MK1-G7" || ab.Snapshot().Home != home {
+		t.Fatalf("home base = %+v, want X1-MK1-G7 as its fuel station in every view", home)
+	}
+
+	// Charting a nearer fuel station in the home system moves the home base to it; one elsewhere does not.
+	events := make(chan event.Event, 2)
+	events <- event.Event{Type: event.WaypointCharted, Data: m.Waypoint{Symbol: "X1-ZZ9-A1", SystemSymbol: "X1-ZZ9", Type: "FUEL_STATION"}}
+	events <- event.Event{Type: event.WaypointCharted, Data: m.Waypoint{Symbol: "X1-MK1-J9", SystemSymbol: "X1-MK1", Type: "FUEL_STATION", X: 3}}
+	close(events)
+	ab.FollowHomeBase(events)
+
+	if home := ab.HomeBase(); symbolOf(home.FuelStation) != "X1-MK1-J9" {
+		t.Errorf("fuel station after charting = %s, want X1-MK1-J9", symbolOf(home.FuelStation))
+	}
+
+	// Refreshing lists the system again.
+	c.waypoints = append(homeWaypoints(t), m.Waypoint{Symbol: "X1-MK1-K1", SystemSymbol: "X1-MK1", Type: "MOON", X: 1, Traits: []m.WaypointTrait{{Symbol: "SHIPYARD"}}})
+	c.waypoints[0].Traits = nil
+	if err := ab.RefreshHomeBase(); err != nil {
+		t.Fatal(err)
+	}
+	if home := ab.HomeBase(); symbolOf(home.Shipyard) != "X1-MK1-K1" || symbolOf(home.FuelStation) != "X1-MK1-G7" {
+		t.Errorf("after refreshing: shipyard %s and fuel station %s, want X1-MK1-K1 and X1-MK1-G7", symbolOf(home.Shipyard), symbolOf(home.FuelStation))
+	}
+}
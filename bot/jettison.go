@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/config"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+/*
+🗑️ Jettison
+*/
+
+// CargoEstimate is what cargo is worth at the best price recorded at any market, in any system.
+type CargoEstimate struct {
+	Symbol string
+	Units  int
+	// Price is the best recorded sell price, or zero if no recorded market buys the good.
+	Price int64
+	// Market is the market paying Price, or empty if none does.
+	Market string
+	// ObservedAt is when Price was recorded or, with no price, when the freshest market was. It is zero if no
+	// market is recorded.
+	ObservedAt time.Time
+}
+
+// Value returns the estimated value of the cargo.
+func (e CargoEstimate) Value() int64 {
+	return e.Price * int64(e.Units)
+}
+
+func (e CargoEstimate) LogValues() []interface{} {
+	return []interface{}{
+		"symbol", e.Symbol,
+		"units", e.Units,
+		"estimatedValue", e.Value(),
+		"bestMarket", e.Market,
+	}
+}
+
+// estimateCargo values units of a good at the best sell price recorded across every market.
+func estimateCargo(markets []store.MarketObservation, symbol string, units int) CargoEstimate {
+	estimate := CargoEstimate{Symbol: symbol, Units: units}
+
+	var freshest time.Time
+	for _, observation := range markets {
+		if observation.ObservedAt.After(freshest) {
+			freshest = observation.ObservedAt
+		}
+
+		if price, ok := observation.Market.SellPriceOf(symbol); ok && price > estimate.Price {
+			estimate.Price = price
+			estimate.Market = observation.Market.Symbol
+			estimate.ObservedAt = observation.ObservedAt
+		}
+	}
+
+	if estimate.Market == "" {
+		estimate.ObservedAt = freshest
+	}
+
+	return estimate
+}
+
+// jettisonDecision decides whether cargo no known buyer in the system takes may be jettisoned, and why.
+// Contract and priority goods are always held. Other cargo is jettisoned only when its estimate comes from
+// market data no older than FreshAge and is worth less than MaxValue, so a stale or missing price never
+// dumps valuable cargo.
+func jettisonDecision(estimate CargoEstimate, priority bool, guard config.JettisonConfig, now time.Time) (bool, string) {
+	switch {
+	case priority:
+		return false, "contract or priority good"
+	case estimate.ObservedAt.IsZero() || now.Sub(estimate.ObservedAt) > guard.FreshAge:
+		return false, "market data too old to value it"
+	case estimate.Value() >= guard.MaxValue:
+		return false, fmt.Sprintf("worth an estimated %d credits", estimate.Value())
+	}
+
+	return true, fmt.Sprintf("worth an estimated %d credits", estimate.Value())
+}
+
+// jettisonUnsellable jettisons a good no known buyer in the system takes, if jettisonDecision allows it,
+// reporting whether it was jettisoned and why. Held cargo is logged as unsellable; in a dry run, jettisons are
+// only logged.
+func (sb *ShipBot) jettisonUnsellable(good m.ShipCargoItem, priority bool, policy string) (bool, string) {
+	estimate := estimateCargo(sb.markets.All(), good.Symbol, good.Units)
+
+	ok, reason := jettisonDecision(estimate, priority, sb.jettison, sb.clock.Now())
+	reason += "; " + policy
+	if !ok {
+		sb.logger.Warn("📦 Holding unsellable cargo.", append(estimate.LogValues(), "reason", reason)...)
+		return false, reason
+	}
+
+	if sb.jettison.DryRun {
+		sb.logger.Warn("🗑️ Would jettison cargo. Dry run, holding it.", append(estimate.LogValues(), "reason", reason)...)
+		return false, "jettison dry run"
+	}
+
+	cargo, err := sb.client.JettisonCargo(sb.ship.Symbol, m.TradeGood{Symbol: good.Symbol}, good.Units)
+	if err != nil {
+		sb.logger.Error("🗑️ Error jettisoning cargo. Retaining it.", "symbol", good.Symbol, "error", err)
+		return false, "jettison failed"
+	}
+
+	sb.ship.Cargo = *cargo
+	sb.logger.Warn("🗑️ Cargo jettisoned.", append(estimate.LogValues(), "reason", reason)...)
+
+	return true, reason
+}
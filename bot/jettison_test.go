@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+func TestEstimateCargoUsesTheBestPriceInAnySystem(t *testing.T) {
+	now := time.Now()
+	markets := []store.MarketObservation{
+		tradeMarket("X1-A-1", time.Hour, "QUARTZ_SAND 12 10"),
+		tradeMarket("X1-B-2", 2*time.Hour, "QUARTZ_SAND 40 30"),
+		tradeMarket("X1-A-3", time.Minute, "ICE_WATER 25 20"),
+	}
+
+	estimate := estimateCargo(markets, "QUARTZ_SAND", 8)
+	if estimate.Market != "X1-B-2" || estimate.Value() != 240 || estimate.ObservedAt.After(now.Add(-90*time.Minute)) {
+		t.Errorf("estimate = %+v, want 8 units at X1-B-2's 30 credits, observed two hours ago", estimate)
+	}
+
+	// With no buyer, the estimate is as fresh as the freshest market.
+	estimate = estimateCargo(markets, "COPPER_ORE", 6)
+	if estimate.Market != "" || estimate.Value() != 0 || now.Sub(estimate.ObservedAt) > 2*time.Minute {
+		t.Errorf("estimate = %+v, want nothing, observed a minute ago", estimate)
+	}
+
+	if estimate := estimateCargo(nil, "COPPER_ORE", 6); !estimate.ObservedAt.IsZero() {
+		t.Errorf("estimate without markets = %+v, want no observation", estimate)
+	}
+}
+
+func TestJettisonDecision(t *testing.T) {
+	now := time.Now()
+	guard := config.JettisonConfig{MaxValue: 1000, FreshAge: 30 * time.Minute}
+
+	tests := []struct {
+		name     string
+		estimate CargoEstimate
+		priority bool
+		want     bool
+		reason   string
+	}{
+		{"cheap and fresh", CargoEstimate{Units: 10, Price: 50, Market: "X1-A-1", ObservedAt: now.Add(-time.Minute)}, false, true, "worth an estimated 500 credits"},
+		{"no buyer anywhere", CargoEstimate{Units: 10, ObservedAt: now.Add(-time.Minute)}, false, true, "worth an estimated 0 credits"},
+		{"at the threshold", CargoEstimate{Units: 10, Price: 100, Market: "X1-A-1", ObservedAt: now.Add(-time.Minute)}, false, false, "worth an estimated 1000 credits"},
+		{"above the threshold", CargoEstimate{Units: 40, Price: 300, Market: "X1-B-2", ObservedAt: now.Add(-time.Minute)}, false, false, "worth an estimated 12000 credits"},
+		{"stale price", CargoEstimate{Units: 10, Price: 1, Market: "X1-A-1", ObservedAt: now.Add(-time.Hour)}, false, false, "market data too old to value it"},
+		{"no market data", CargoEstimate{Units: 10}, false, false, "market data too old to value it"},
+		{"priority good", CargoEstimate{Units: 10, ObservedAt: now.Add(-time.Minute)}, true, false, "contract or priority good"},
+	}
+	for _, tt := range tests {
+		if got, reason := jettisonDecision(tt.estimate, tt.priority, guard, now); got != tt.want || reason != tt.reason {
+			t.Errorf("%s: jettisonDecision = %t, %q; want %t, %q", tt.name, got, reason, tt.want, tt.reason)
+		}
+	}
+}
+
+func TestJettisonDryRunHoldsCargo(t *testing.T) {
+	cfg := config.Default()
+	cfg.UnsellablePolicy = "jettison"
+	cfg.Jettison.DryRun = true
+	out := captureLogs(t)
+
+	c := newRefusingClient()
+	sb := refusingShip(t, cfg, c)
+
+	sbCh := make(chan ShipBot, 1)
+	sb.SellCargo(sbCh)
+	<-sbCh
+
+	for _, call := range c.calls {
+		if call == "jettison 8 QUARTZ_SAND" || call == "jettison 6 COPPER_ORE" {
+			t.Errorf("jettisoned in a dry run: %s", call)
+		}
+	}
+	for _, symbol := range []string{"QUARTZ_SAND", "COPPER_ORE"} {
+		if !sb.retained[symbol] || sb.ship.Cargo.UnitsOf(symbol) == 0 {
+			t.Errorf("%s not held in a dry run", symbol)
+		}
+	}
+
+	would := make(map[string]bool)
+	for _, line := range logLines(t, out, "🚀 GOGARIN-1:") {
+		if line["msg"] == "🗑️ Would jettison cargo. Dry run, holding it." {
+			would[line["symbol"].(string)] = true
+		}
+	}
+	if !would["QUARTZ_SAND"] || !would["COPPER_ORE"] {
+		t.Errorf("dry run logged %v, want both unsellable goods", would)
+	}
+}
@@ -0,0 +1,290 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+/*
+📓 Journal
+*/
+
+// Kinds of journalled missions.
+const (
+	journalDeliver = "deliver"
+	journalTrade   = "trade"
+	journalExplore = "explore"
+	journalMigrate = "migrate"
+	journalHold    = "hold"
+)
+
+// Steps of journalled missions.
+const (
+	stepDelivering = "delivering"
+	stepFulfilling = "fulfilling"
+	stepBuying     = "buying"
+	stepHauling    = "hauling"
+	stepExploring  = "exploring"
+	stepPlanned    = "planned"
+	stepJumping    = "jumping"
+	stepHolding    = "holding"
+)
+
+// journalBegin records that the ShipBot started a multi-step mission with an intent to resume it from.
+func (sb *ShipBot) journalBegin(kind string, step string, contractID string, intent interface{}) {
+	raw, err := json.Marshal(intent)
+	if err == nil {
+		err = sb.journal.Begin(store.JournalEntry{
+			Ship:       sb.ship.Symbol,
+			Kind:       kind,
+			MissionID:  sb.missionID,
+			Step:       step,
+			ContractID: contractID,
+			Intent:     raw,
+		})
+	}
+
+	if err != nil {
+		sb.logger.Warn("📓 Error writing journal.", "error", err)
+	}
+}
+
+// journalStep records that the ShipBot's mission reached a step. A nil intent keeps the recorded one.
+func (sb *ShipBot) journalStep(step string, intent interface{}) {
+	var raw json.RawMessage
+	if intent != nil {
+		var err error
+		if raw, err = json.Marshal(intent); err != nil {
+			sb.logger.Warn("📓 Error writing journal.", "error", err)
+			return
+		}
+	}
+
+	if err := sb.journal.Step(sb.ship.Symbol, step, raw); err != nil {
+		sb.logger.Warn("📓 Error writing journal.", "error", err)
+	}
+}
+
+// journalEnd clears the ShipBot's journalled mission.
+func (sb *ShipBot) journalEnd() {
+	if err := sb.journal.End(sb.ship.Symbol); err != nil {
+		sb.logger.Warn("📓 Error writing journal.", "error", err)
+	}
+}
+
+// Abandon fails the ShipBot's mission and clears it from the journal, so it is not resumed.
+func (sb *ShipBot) Abandon(err error) {
+	sb.journalEnd()
+	sb.Fail(err)
+}
+
+// ResumeMission returns the mission that picks up a ShipBot's unfinished journalled mission from its last step.
+// Entries that can no longer be resumed, because the contract is gone or the goods are no longer aboard,
+// are cleared.
+func (ab *AgentBot) ResumeMission(sb *ShipBot) (Mission, bool) {
+	entry, ok := sb.journal.Get(sb.ship.Symbol)
+	if !ok {
+		return Mission{}, false
+	}
+
+	mission, err := ab.resumable(sb, entry)
+	if err != nil {
+		sb.logger.Warn("📓 Journalled mission cannot be resumed. Discarding.", "kind", entry.Kind, "step", entry.Step, "reason", err)
+		sb.journalEnd()
+		return Mission{}, false
+	}
+
+	sb.logger.Info("📓 Resuming journalled mission.", "kind", entry.Kind, "step", entry.Step, "missionId", entry.MissionID)
+
+	return mission, true
+}
+
+// resumable returns the mission resuming a journal entry, or why it cannot be resumed.
+func (ab *AgentBot) resumable(sb *ShipBot, entry store.JournalEntry) (Mission, error) {
+	switch entry.Kind {
+	case journalDeliver:
+		var delivery Delivery
+		if err := json.Unmarshal(entry.Intent, &delivery); err != nil {
+			return Mission{}, err
+		}
+
+		contract, err := ab.findContract(entry.ContractID)
+		if err != nil {
+			return Mission{}, err
+		}
+
+		if entry.Step == stepFulfilling || contract.IsDeliverComplete() {
+			return Mission{
+				Name: fmt.Sprintf("Fulfill %s", contract.ID),
+				Run:  func(sb *ShipBot, sbCh chan ShipBot) { sb.FulfillContract(contract.ID, sbCh) },
+			}, nil
+		}
+
+		next, ok := nextDelivery(sb.ship.Cargo, []m.Contract{*contract})
+		if !ok {
+			return Mission{}, fmt.Errorf("no %s aboard for contract %s", delivery.TradeSymbol, contract.ID)
+		}
+
+		return deliverMission(next), nil
+	case journalTrade:
+		var intent tradeIntent
+		if err := json.Unmarshal(entry.Intent, &intent); err != nil {
+			return Mission{}, err
+		}
+
+		// A trade interrupted while buying resumes with whatever was bought.
+		units := sb.ship.Cargo.UnitsOf(intent.Route.Good)
+		if units == 0 {
+			return Mission{}, fmt.Errorf("no %s aboard", intent.Route.Good)
+		}
+
+		return Mission{
+			Name: fmt.Sprintf("Resume trade %s to %s", intent.Route.Good, intent.Route.Destination),
+			Run:  func(sb *ShipBot, sbCh chan ShipBot) { sb.ResumeTrade(intent.Route, units, sbCh) },
+		}, nil
+	case journalExplore:
+		var intent exploreIntent
+		if err := json.Unmarshal(entry.Intent, &intent); err != nil {
+			return Mission{}, err
+		}
+
+		if len(intent.Remaining) == 0 {
+			return Mission{}, errNothingToExplore
+		}
+
+		return Mission{
+			Name: fmt.Sprintf("Resume exploring %d waypoints", len(intent.Remaining)),
+			Run:  func(sb *ShipBot, sbCh chan ShipBot) { sb.ResumeExploration(intent.Remaining, sbCh) },
+		}, nil
+	case journalMigrate:
+		var intent migrateIntent
+		if err := json.Unmarshal(entry.Intent, &intent); err != nil {
+			return Mission{}, err
+		}
+
+		return migrateMission(intent), nil
+	case journalHold:
+		ab.orders.Place(sb.ship.Symbol, m.ManualOrder{Kind: m.OrderHold})
+		order, changed, _ := ab.orders.Next(sb.ship.Symbol)
+
+		return orderMission(order, changed), nil
+	default:
+		return Mission{}, fmt.Errorf("unknown mission kind %q", entry.Kind)
+	}
+}
+
+// findContract looks up one of the agent's current, unfulfilled contracts.
+func (ab *AgentBot) findContract(id string) (*m.Contract, error) {
+	contracts, err := ab.GetMyContracts()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, contract := range *contracts {
+		if contract.ID == id && !contract.Fulfilled {
+			return &contract, nil
+		}
+	}
+
+	return nil, fmt.Errorf("contract %s no longer exists", id)
+}
+
+// ReconcileJournal checks journalled missions against the API's ships and contracts after a run that did not
+// shut down cleanly, clearing those a crash left stale: of ships no longer in the fleet, on contracts that are
+// gone, or whose goods are no longer aboard.
+func (ab *AgentBot) ReconcileJournal(ships []m.Ship, contracts []m.Contract) {
+	entries := ab.journal.Entries()
+	if len(entries) == 0 {
+		return
+	}
+
+	ab.logger.Warn("📓 Previous run did not shut down cleanly. Checking journalled missions.", "entries", len(entries))
+
+	ab.InvalidateJournal(contracts)
+
+	fleet := make(map[string]m.Ship, len(ships))
+	for _, ship := range ships {
+		fleet[ship.Symbol] = ship
+	}
+
+	for _, entry := range ab.journal.Entries() {
+		ship, ok := fleet[entry.Ship]
+		if !ok {
+			ab.discardJournalled(entry, errors.New("ship no longer in the fleet"))
+			continue
+		}
+
+		if err := stale(ship, entry); err != nil {
+			ab.discardJournalled(entry, err)
+		}
+	}
+}
+
+// stale returns why a journal entry no longer matches its ship, or nil if it still may.
+func stale(ship m.Ship, entry store.JournalEntry) error {
+	switch entry.Kind {
+	case journalDeliver:
+		if entry.Step == stepFulfilling {
+			return nil
+		}
+
+		var delivery Delivery
+		if err := json.Unmarshal(entry.Intent, &delivery); err != nil {
+			return err
+		}
+		if ship.Cargo.UnitsOf(delivery.TradeSymbol) == 0 {
+			return fmt.Errorf("no %s aboard", delivery.TradeSymbol)
+		}
+	case journalTrade:
+		var intent tradeIntent
+		if err := json.Unmarshal(entry.Intent, &intent); err != nil {
+			return err
+		}
+		if ship.Cargo.UnitsOf(intent.Route.Good) == 0 {
+			return fmt.Errorf("no %s aboard", intent.Route.Good)
+		}
+	case journalExplore:
+		var intent exploreIntent
+		if err := json.Unmarshal(entry.Intent, &intent); err != nil {
+			return err
+		}
+		if len(intent.Remaining) == 0 {
+			return errNothingToExplore
+		}
+	}
+
+	return nil
+}
+
+// discardJournalled clears a journal entry that can no longer be resumed.
+func (ab *AgentBot) discardJournalled(entry store.JournalEntry, reason error) {
+	ab.logger.Info("📓 Journalled mission is stale. Discarding.", "ship", entry.Ship, "kind", entry.Kind, "step", entry.Step, "reason", reason)
+	if err := ab.journal.End(entry.Ship); err != nil {
+		ab.logger.Warn("📓 Error writing journal.", "error", err)
+	}
+}
+
+// InvalidateJournal clears journalled missions working on contracts that are no longer among contracts.
+func (ab *AgentBot) InvalidateJournal(contracts []m.Contract) {
+	current := make(map[string]bool, len(contracts))
+	for _, contract := range contracts {
+		if !contract.Fulfilled {
+			current[contract.ID] = true
+		}
+	}
+
+	for _, entry := range ab.journal.Entries() {
+		if entry.ContractID == "" || current[entry.ContractID] {
+			continue
+		}
+
+		ab.logger.Info("📓 Contract gone. Discarding journalled mission.", "ship", entry.Ship, "contract", entry.ContractID, "step", entry.Step)
+		if err := ab.journal.End(entry.Ship); err != nil {
+			ab.logger.Warn("📓 Error writing journal.", "error", err)
+		}
+	}
+}
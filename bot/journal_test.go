@@ -0,0 +1,391 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+// errKilled stands in for the process dying part-way through a mission.
+var errKilled = errors.New("killed")
+
+// journalClient is a tradeClient that also takes contract deliveries, and dies on the call named by kill.
+type journalClient struct {
+	tradeClient
+	kill string
+}
+
+func (c *journalClient) record(call string) {
+	if call == c.kill {
+		panic(errKilled)
+	}
+	c.calls = append(c.calls, call)
+}
+
+func (c *journalClient) DockShip(shipSymbol string) (*m.ShipNav, error) {
+	c.record("dock")
+	c.nav.Status = "DOCKED"
+	nav := c.nav
+	return &nav, nil
+}
+
+func (c *journalClient) NavigateShip(shipSymbol string, waypointSymbol string) (*api.NavigateShipResponse, error) {
+	c.record("navigate " + waypointSymbol)
+	c.nav.WaypointSymbol = waypointSymbol
+	c.nav.Route.Destination.Symbol = waypointSymbol
+	return &api.NavigateShipResponse{Nav: c.nav, Fuel: m.ShipFuel{Current: 100, Capacity: 100}}, nil
+}
+
+func (c *journalClient) DeliverContract(contractID string, shipSymbol string, tradeSymbol string, units int) (*api.DeliverContractResponse, error) {
+	c.record(fmt.Sprintf("deliver %d %s", units, tradeSymbol))
+	c.cargo.Remove(tradeSymbol, units)
+	deliver := &c.contracts[0].Terms.Deliver[0]
+	deliver.UnitsFulfilled += units
+	return &api.DeliverContractResponse{Contract: c.contracts[0], Cargo: c.cargo}, nil
+}
+
+func (c *journalClient) FulfillContract(contractID string) (*api.FulfillContractResponse, error) {
+	c.record("fulfill " + contractID)
+	c.contracts[0].Fulfilled = true
+	return &api.FulfillContractResponse{Agent: m.Agent{Credits: 12000}, Contract: c.contracts[0]}, nil
+}
+
+// newJournalClient is a journalClient for a hauler at X1-A-1 carrying cargo, with an accepted contract for
+// 10 IRON_ORE delivered to X1-A-3.
+func newJournalClient(kill string, cargo ...m.ShipCargoItem) *journalClient {
+	c := &journalClient{kill: kill}
+	c.waypoints = tradeWaypoints
+	c.contracts = []m.Contract{contractFor("IRON_ORE", 10, 0)}
+	c.nav = m.ShipNav{SystemSymbol: "X1-A", WaypointSymbol: "X1-A-1", Status: "IN_ORBIT"}
+	c.cargo = m.ShipCargo{Capacity: 30}
+	for _, item := range cargo {
+		c.cargo.Add(item.Symbol, item.Units)
+	}
+	c.markets = map[string]m.Market{
+		"X1-A-1": tradeMarket("X1-A-1", 0, "IRON_ORE 10 8", "FUEL 2 1").Market,
+		"X1-A-3": tradeMarket("X1-A-3", 0, "IRON_ORE 60 50").Market,
+	}
+	return c
+}
+
+// journalledShip returns the hauler of c as the server has it, journalling to j, and an AgentBot commanding it.
+func journalledShip(t *testing.T, cfg *config.Config, c *journalClient, j *store.Journal, markets *store.MarketStore) (*AgentBot, *ShipBot) {
+	t.Helper()
+
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+
+	ab := NewAgentBot(c, &m.Agent{Symbol: "GOGARIN", Credits: 10000}, markets, NewStrategySelector(cfg), j, bus, cfg)
+	t.Cleanup(ab.scheduler.Stop)
+
+	ship := m.Ship{Symbol: "GOGARIN-1", Nav: c.nav, Cargo: c.cargo}
+	ship.Registration.Role = "HAULER"
+	sb := NewShipBot(c, &ship, ab.agent, ab.systems, ab.markets, bus, cfg)
+	sb.journal = j
+	sb.arrival, sb.dock = nil, nil
+
+	return ab, sb
+}
+
+// kill runs a mission until the client dies under it.
+func kill(t *testing.T, mission func()) {
+	t.Helper()
+
+	defer func() {
+		if r := recover(); r != errKilled {
+			panic(r)
+		}
+	}()
+
+	mission()
+	t.Fatal("mission finished without being killed")
+}
+
+// restart opens the journal at path again and dispatches the hauler of c as a fresh start does, returning the
+// ShipBot once its first mission reports.
+func restart(t *testing.T, cfg *config.Config, c *journalClient, path string, markets *store.MarketStore) (ShipBot, *store.Journal) {
+	t.Helper()
+
+	j, err := store.OpenJournal(path, "2030-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.kill, c.calls = "", nil
+	ab, sb := journalledShip(t, cfg, c, j, markets)
+	sb.resuming = true
+
+	sbCh := make(chan ShipBot, 1)
+	ab.DispatchNext(*sb, sbCh)
+
+	select {
+	case reported := <-sbCh:
+		return reported, j
+	case <-time.After(time.Second):
+		t.Fatal("resumed mission never reported")
+		return ShipBot{}, nil
+	}
+}
+
+func TestInterruptedMissionsResumeFromTheirLastStep(t *testing.T) {
+	ore := m.ShipCargoItem{Symbol: "IRON_ORE", Units: 10}
+	delivery := Delivery{ContractID: "c-1", TradeSymbol: "IRON_ORE", Destination: "X1-A-3", Units: 10, Remaining: 10}
+
+	tests := []struct {
+		name    string
+		client  *journalClient
+		mission func(sb *ShipBot, sbCh chan ShipBot)
+		step    string
+		resumed string
+		calls   []string
+	}{
+		{
+			name:    "delivery killed before docking",
+			client:  newJournalClient("dock", ore),
+			mission: func(sb *ShipBot, sbCh chan ShipBot) { sb.DeliverContract(delivery, sbCh) },
+			step:    stepDelivering,
+			resumed: "Deliver 10 IRON_ORE to X1-A-3",
+			calls:   []string{"dock", "deliver 10 IRON_ORE", "fulfill c-1"},
+		},
+		{
+			name:    "delivery killed before fulfilling",
+			client:  newJournalClient("fulfill c-1", ore),
+			mission: func(sb *ShipBot, sbCh chan ShipBot) { sb.DeliverContract(delivery, sbCh) },
+			step:    stepFulfilling,
+			resumed: "Fulfill c-1",
+			calls:   []string{"fulfill c-1"},
+		},
+		{
+			name:    "trade killed on the way to sell",
+			client:  newJournalClient("navigate X1-A-3"),
+			mission: (*ShipBot).RunTradeMission,
+			step:    stepHauling,
+			resumed: "Resume trade IRON_ORE to X1-A-3",
+			calls:   []string{"navigate X1-A-3", "dock", "market X1-A-3", "sell 30 IRON_ORE at X1-A-3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			path := filepath.Join(t.TempDir(), "journal.json")
+
+			markets := store.NewMarketStore()
+			markets.Record(tt.client.markets["X1-A-1"], time.Now())
+			markets.Record(tt.client.markets["X1-A-3"], time.Now())
+
+			j, err := store.OpenJournal(path, "2030-01-01")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, sb := journalledShip(t, cfg, tt.client, j, markets)
+			kill(t, func() { tt.mission(sb, make(chan ShipBot, 1)) })
+
+			if entry, ok := j.Get("GOGARIN-1"); !ok || entry.Step != tt.step {
+				t.Fatalf("journal after the kill = %+v, want step %s", entry, tt.step)
+			}
+
+			reported, resumed := restart(t, cfg, tt.client, path, markets)
+
+			if reported.mission != tt.resumed {
+				t.Errorf("first mission after the restart = %q, want %q", reported.mission, tt.resumed)
+			}
+			if !reflect.DeepEqual(tt.client.calls, tt.calls) {
+				t.Errorf("calls after the restart = %q, want %q", tt.client.calls, tt.calls)
+			}
+			if entries := resumed.Entries(); len(entries) != 0 {
+				t.Errorf("journal after the resumed mission = %+v, want it cleared", entries)
+			}
+		})
+	}
+}
+
+func TestUnresumableMissionsAreDiscarded(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry func(c *journalClient) (kind string, step string, contractID string, intent interface{})
+	}{
+		{"contract gone", func(c *journalClient) (string, string, string, interface{}) {
+			c.contracts = nil
+			return journalDeliver, stepDelivering, "c-1", Delivery{ContractID: "c-1", TradeSymbol: "IRON_ORE", Destination: "X1-A-3", Units: 10}
+		}},
+		{"contract fulfilled", func(c *journalClient) (string, string, string, interface{}) {
+			c.contracts[0].Fulfilled = true
+			return journalDeliver, stepFulfilling, "c-1", Delivery{ContractID: "c-1", TradeSymbol: "IRON_ORE", Destination: "X1-A-3", Units: 10}
+		}},
+		{"delivery goods gone", func(c *journalClient) (string, string, string, interface{}) {
+			c.cargo = m.ShipCargo{Capacity: 30}
+			return journalDeliver, stepDelivering, "c-1", Delivery{ContractID: "c-1", TradeSymbol: "IRON_ORE", Destination: "X1-A-3", Units: 10}
+		}},
+		{"trade goods gone", func(c *journalClient) (string, string, string, interface{}) {
+			c.cargo = m.ShipCargo{Capacity: 30}
+			return journalTrade, stepHauling, "", tradeIntent{Route: TradeRoute{Good: "IRON_ORE", Destination: "X1-A-3"}, Units: 10}
+		}},
+		{"unknown kind", func(c *journalClient) (string, string, string, interface{}) {
+			return "smuggle", stepHauling, "", struct{}{}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.IdleInterval = time.Millisecond
+			path := filepath.Join(t.TempDir(), "journal.json")
+
+			c := newJournalClient("", m.ShipCargoItem{Symbol: "IRON_ORE", Units: 10})
+			j, err := store.OpenJournal(path, "2030-01-01")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, sb := journalledShip(t, cfg, c, j, store.NewMarketStore())
+			sb.journalBegin(tt.entry(c))
+
+			// The strategy decides instead; the hauler at X1-A-1 sells or idles rather than resuming.
+			reported, resumed := restart(t, cfg, c, path, store.NewMarketStore())
+			if reported.mission == "" || reported.mission == "Fulfill c-1" || reported.mission == "Deliver 10 IRON_ORE to X1-A-3" {
+				t.Errorf("first mission after the restart = %q, want the strategy's", reported.mission)
+			}
+			if entries := resumed.Entries(); len(entries) != 0 {
+				t.Errorf("journal = %+v, want the entry discarded", entries)
+			}
+		})
+	}
+}
+
+func TestInvalidateJournalDropsMissionsOnGoneContracts(t *testing.T) {
+	cfg := config.Default()
+
+	j, err := store.OpenJournal("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range []store.JournalEntry{
+		{Ship: "GOGARIN-1", Kind: journalDeliver, ContractID: "c-1"},
+		{Ship: "GOGARIN-2", Kind: journalDeliver, ContractID: "c-2"},
+		{Ship: "GOGARIN-3", Kind: journalDeliver, ContractID: "c-3"},
+		{Ship: "GOGARIN-4", Kind: journalTrade},
+	} {
+		if err := j.Begin(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ab := NewAgentBot(&contractClient{}, &m.Agent{Symbol: "GOGARIN"}, store.NewMarketStore(), NewStrategySelector(cfg), j, nil, cfg)
+	fulfilled := contractFor("IRON_ORE", 10, 10)
+	fulfilled.ID, fulfilled.Fulfilled = "c-3", true
+	ab.InvalidateJournal([]m.Contract{contractFor("IRON_ORE", 10, 0), fulfilled})
+
+	var ships []string
+	for _, entry := range j.Entries() {
+		ships = append(ships, entry.Ship)
+	}
+	if want := []string{"GOGARIN-1", "GOGARIN-4"}; !reflect.DeepEqual(ships, want) {
+		t.Errorf("journalled ships = %v, want %v", ships, want)
+	}
+}
+
+// journalled records a ship's unfinished mission in a journal, as the ship's last run left it.
+func journalled(t *testing.T, journal *store.Journal, ship, kind, step, contractID string, intent interface{}) {
+	t.Helper()
+
+	raw, err := json.Marshal(intent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.Begin(store.JournalEntry{Ship: ship, Kind: kind, Step: step, ContractID: contractID, Intent: raw}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// previousRun returns the state file a previous run left, having shut down cleanly or crashed.
+func previousRun(t *testing.T, clean bool) *store.StateFile {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	previous, err := store.OpenStateFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := previous.MarkRunning(epoch); err != nil {
+		t.Fatal(err)
+	}
+	if clean {
+		if err := previous.Close(store.Snapshot{Clean: true, At: epoch, Outcome: "stopped"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	state, err := store.OpenStateFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return state
+}
+
+func TestReconcileJournalDiscardsWhatACrashLeftStale(t *testing.T) {
+	f := fixture(t)
+	ab, _, _ := agentBot(t, f)
+	journal, err := store.OpenJournal("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ab.journal = journal
+
+	// Since the crash, MOCK-2 sold its ore, and MOCK-3 still hauls its own.
+	hauler := f.Ships[1]
+	hauler.Symbol = "MOCK-3"
+	hauler.Cargo = m.ShipCargo{Capacity: 30, Units: 12, Inventory: []m.ShipCargoItem{{Symbol: "IRON_ORE", Units: 12}}}
+	ships := []m.Ship{f.Ships[0], f.Ships[1], hauler}
+	contracts := []m.Contract{f.Contracts[0]}
+
+	ore := tradeIntent{Route: TradeRoute{Good: "IRON_ORE", Source: "X1-MK1-A1", Destination: "X1-MK1-C3"}}
+	journalled(t, journal, "MOCK-1", journalDeliver, stepFulfilling, "mock-contract-1", Delivery{ContractID: "mock-contract-1", TradeSymbol: "IRON_ORE"})
+	journalled(t, journal, "MOCK-2", journalTrade, stepHauling, "", ore)
+	journalled(t, journal, "MOCK-3", journalTrade, stepHauling, "", ore)
+	journalled(t, journal, "MOCK-4", journalExplore, stepExploring, "", exploreIntent{Remaining: []string{"X1-MK1-D4"}})
+	journalled(t, journal, "MOCK-5", journalDeliver, stepDelivering, "gone-contract", Delivery{ContractID: "gone-contract", TradeSymbol: "IRON_ORE"})
+
+	ab.ReconcileJournal(ships, contracts)
+
+	var kept []string
+	for _, entry := range journal.Entries() {
+		kept = append(kept, entry.Ship)
+	}
+	if len(kept) != 2 || kept[0] != "MOCK-1" || kept[1] != "MOCK-3" {
+		t.Fatalf("journal kept %v after the crash, want only MOCK-1's fulfilment and MOCK-3's haul", kept)
+	}
+}
+
+func TestFleetChecksTheJournalOnlyAfterAnUncleanShutdown(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		clean bool
+	}{
+		{"crashed", false},
+		{"stopped", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			journal, err := store.OpenJournal("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The ship was sold, or scrapped, while the bot was down.
+			journalled(t, journal, "GONE-1", journalExplore, stepExploring, "", exploreIntent{Remaining: []string{"X1-MK1-D4"}})
+
+			simulate(t, fixture(t), WithJournal(journal), WithState(previousRun(t, tc.clean)))
+
+			if _, kept := journal.Get("GONE-1"); kept != tc.clean {
+				t.Fatalf("journal entry of a ship gone from the fleet kept = %t, want %t", kept, tc.clean)
+			}
+		})
+	}
+}
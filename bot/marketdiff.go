@@ -0,0 +1,180 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+/*
+🔍 Market diff
+*/
+
+// GoodDiff compares a trade good as stored with the market's live listing of it. Stored or Live is nil when
+// the good is listed on only one side.
+type GoodDiff struct {
+	Symbol string             `json:"symbol"`
+	Stored *m.MarketTradeGood `json:"stored,omitempty"`
+	Live   *m.MarketTradeGood `json:"live,omitempty"`
+	// Changed names the fields that differ: supply, purchasePrice, sellPrice, tradeVolume, activity, or type.
+	Changed []string `json:"changed,omitempty"`
+	// Drift is the larger relative change of the good's purchase and sell prices, as a fraction of the stored
+	// price. A good listed on only one side has drifted by 1.
+	Drift float64 `json:"drift"`
+}
+
+// MarketDiff compares the bot's stored observation of a market with a live fetch of it.
+type MarketDiff struct {
+	Symbol     string    `json:"symbol"`
+	ObservedAt time.Time `json:"observedAt"`
+	FetchedAt  time.Time `json:"fetchedAt"`
+	// Staleness is how old the stored observation was when the market was fetched.
+	Staleness time.Duration `json:"staleness"`
+	Goods     []GoodDiff    `json:"goods"`
+	// Drift is the largest drift of any good.
+	Drift float64 `json:"drift"`
+}
+
+// DiffMarkets compares a market as observed at observedAt with the same market fetched live at fetchedAt.
+// Goods are sorted by symbol.
+func DiffMarkets(stored m.Market, live m.Market, observedAt time.Time, fetchedAt time.Time) MarketDiff {
+	diff := MarketDiff{
+		Symbol:     live.Symbol,
+		ObservedAt: observedAt,
+		FetchedAt:  fetchedAt,
+		Staleness:  fetchedAt.Sub(observedAt),
+	}
+
+	goods := make(map[string]*GoodDiff)
+	for i := range stored.TradeGoods {
+		good := stored.TradeGoods[i]
+		goods[good.Symbol] = &GoodDiff{Symbol: good.Symbol, Stored: &good}
+	}
+	for i := range live.TradeGoods {
+		good := live.TradeGoods[i]
+		if goods[good.Symbol] == nil {
+			goods[good.Symbol] = &GoodDiff{Symbol: good.Symbol}
+		}
+		goods[good.Symbol].Live = &good
+	}
+
+	for _, good := range goods {
+		good.Changed, good.Drift = diffGood(good.Stored, good.Live)
+		if good.Drift > diff.Drift {
+			diff.Drift = good.Drift
+		}
+		diff.Goods = append(diff.Goods, *good)
+	}
+
+	sort.Slice(diff.Goods, func(i, j int) bool { return diff.Goods[i].Symbol < diff.Goods[j].Symbol })
+
+	return diff
+}
+
+// diffGood returns the fields that differ between a stored and a live good, and how far its prices drifted.
+func diffGood(stored *m.MarketTradeGood, live *m.MarketTradeGood) ([]string, float64) {
+	if stored == nil || live == nil {
+		return nil, 1
+	}
+
+	var changed []string
+	for _, field := range []struct {
+		name         string
+		stored, live interface{}
+	}{
+		{"type", stored.Type, live.Type},
+		{"supply", stored.Supply, live.Supply},
+		{"activity", stored.Activity, live.Activity},
+		{"tradeVolume", stored.TradeVolume, live.TradeVolume},
+		{"purchasePrice", stored.PurchasePrice, live.PurchasePrice},
+		{"sellPrice", stored.SellPrice, live.SellPrice},
+	} {
+		if field.stored != field.live {
+			changed = append(changed, field.name)
+		}
+	}
+
+	drift := priceDrift(stored.PurchasePrice, live.PurchasePrice)
+	if sell := priceDrift(stored.SellPrice, live.SellPrice); sell > drift {
+		drift = sell
+	}
+
+	return changed, drift
+}
+
+// priceDrift returns the change from a stored price to a live one as a fraction of the stored price. A price
+// that appears where none was stored has drifted by 1.
+func priceDrift(stored int64, live int64) float64 {
+	switch {
+	case stored == live:
+		return 0
+	case stored == 0:
+		return 1
+	}
+
+	change := float64(live-stored) / float64(stored)
+	if change < 0 {
+		return -change
+	}
+
+	return change
+}
+
+// RenderMarketDiff renders a market diff as a table, showing changed fields as "stored -> live" and marking
+// changed goods with an asterisk.
+func RenderMarketDiff(diff MarketDiff) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Market %s: observed %s, %s before the live fetch. Largest price drift %.1f%%.\n",
+		diff.Symbol, diff.ObservedAt.Format(time.RFC3339), diff.Staleness.Round(time.Second), diff.Drift*100)
+
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tSYMBOL\tTYPE\tSUPPLY\tACTIVITY\tVOLUME\tPURCHASE\tSELL\tDRIFT")
+	for _, good := range diff.Goods {
+		marker := ""
+		if good.Stored == nil || good.Live == nil || len(good.Changed) > 0 {
+			marker = "*"
+		}
+
+		var stored, live m.MarketTradeGood
+		if good.Stored != nil {
+			stored = *good.Stored
+		}
+		if good.Live != nil {
+			live = *good.Live
+		}
+
+		cell := func(s interface{}, l interface{}) string {
+			switch {
+			case good.Stored == nil:
+				return "- -> " + diffValue(l)
+			case good.Live == nil:
+				return diffValue(s) + " -> -"
+			case s != l:
+				return diffValue(s) + " -> " + diffValue(l)
+			}
+			return diffValue(s)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%.1f%%\n", marker, good.Symbol,
+			cell(stored.Type, live.Type), cell(stored.Supply, live.Supply), cell(stored.Activity, live.Activity),
+			cell(stored.TradeVolume, live.TradeVolume), cell(stored.PurchasePrice, live.PurchasePrice),
+			cell(stored.SellPrice, live.SellPrice), good.Drift*100)
+	}
+	tw.Flush()
+
+	return b.String()
+}
+
+// diffValue formats a field of a market diff, showing an empty field as "-".
+func diffValue(v interface{}) string {
+	if s := fmt.Sprint(v); s != "" {
+		return s
+	}
+
+	return "-"
+}
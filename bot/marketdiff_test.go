@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+func TestDiffMarkets(t *testing.T) {
+	stored := m.Market{Symbol: "X1-MK1-C3", TradeGoods: []m.MarketTradeGood{
+		{Symbol: "IRON_ORE", Type: "IMPORT", TradeVolume: 60, Supply: "SCARCE", Activity: "WEAK", PurchasePrice: 60, SellPrice: 50},
+		{Symbol: "QUARTZ_SAND", Type: "IMPORT", TradeVolume: 40, Supply: "MODERATE", PurchasePrice: 30, SellPrice: 25},
+		{Symbol: "FUEL", Type: "EXCHANGE", TradeVolume: 100, Supply: "ABUNDANT", PurchasePrice: 72, SellPrice: 68},
+	}}
+	live := m.Market{Symbol: "X1-MK1-C3", TradeGoods: []m.MarketTradeGood{
+		// Sales crashed the ore price and flipped its supply.
+		{Symbol: "IRON_ORE", Type: "IMPORT", TradeVolume: 60, Supply: "ABUNDANT", Activity: "WEAK", PurchasePrice: 40, SellPrice: 35},
+		{Symbol: "QUARTZ_SAND", Type: "IMPORT", TradeVolume: 40, Supply: "MODERATE", PurchasePrice: 30, SellPrice: 25},
+		{Symbol: "ICE_WATER", Type: "EXPORT", TradeVolume: 80, Supply: "HIGH", Activity: "STRONG", PurchasePrice: 12, SellPrice: 10},
+	}}
+	observedAt := epoch
+	fetchedAt := epoch.Add(95 * time.Minute)
+
+	diff := DiffMarkets(stored, live, observedAt, fetchedAt)
+
+	if diff.Staleness != 95*time.Minute {
+		t.Fatalf("staleness = %s, want 1h35m", diff.Staleness)
+	}
+	if diff.Drift != 1 {
+		t.Fatalf("drift = %g, want 1 for goods listed on only one side", diff.Drift)
+	}
+
+	want := map[string]struct {
+		changed []string
+		drift   float64
+	}{
+		"FUEL":        {nil, 1},
+		"ICE_WATER":   {nil, 1},
+		"IRON_ORE":    {[]string{"supply", "purchasePrice", "sellPrice"}, 1.0 / 3},
+		"QUARTZ_SAND": {nil, 0},
+	}
+	var symbols []string
+	for _, good := range diff.Goods {
+		symbols = append(symbols, good.Symbol)
+
+		w := want[good.Symbol]
+		if !reflect.DeepEqual(good.Changed, w.changed) {
+			t.Errorf("%s changed %v, want %v", good.Symbol, good.Changed, w.changed)
+		}
+		if math.Abs(good.Drift-w.drift) > 1e-9 {
+			t.Errorf("%s drift %g, want %g", good.Symbol, good.Drift, w.drift)
+		}
+	}
+	if want := []string{"FUEL", "ICE_WATER", "IRON_ORE", "QUARTZ_SAND"}; !reflect.DeepEqual(symbols, want) {
+		t.Fatalf("goods %v, want %v", symbols, want)
+	}
+
+	golden(t, "market_diff.golden", RenderMarketDiff(diff))
+}
+
+func TestDiffUnchangedMarket(t *testing.T) {
+	market := m.Market{Symbol: "X1-MK1-A1", TradeGoods: []m.MarketTradeGood{
+		{Symbol: "IRON_ORE", Supply: "MODERATE", PurchasePrice: 60, SellPrice: 50},
+	}}
+
+	diff := DiffMarkets(market, market, epoch, epoch.Add(time.Minute))
+	if diff.Drift != 0 || len(diff.Goods[0].Changed) != 0 {
+		t.Fatalf("diff of a market with itself = %+v, want no drift or changes", diff)
+	}
+}
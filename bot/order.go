@@ -0,0 +1,285 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/GeoffreyDick/gogarin/boterr"
+	"github.com/GeoffreyDick/gogarin/lib"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+/*
+🕹️ Manual orders
+*/
+
+// manualStrategy is what the missions of a ship under manual orders are attributed to.
+const manualStrategy = "manual"
+
+// Orders holds the manual orders given to ships. A ship given any order but Resume is under manual orders: it
+// carries out its pending orders in turn, then holds, and is not sent on automatic missions until it is
+// resumed. Methods are safe to call on a nil Orders, which puts no ship under manual orders.
+type Orders struct {
+	mu    sync.Mutex
+	ships map[string]*shipOrders
+}
+
+// shipOrders are the manual orders of a ship.
+type shipOrders struct {
+	pending []m.ManualOrder
+	// changed is closed, and replaced, whenever the ship is given an order, so a holding ship wakes.
+	changed chan struct{}
+}
+
+// NewOrders creates an empty Orders.
+func NewOrders() *Orders {
+	return &Orders{ships: make(map[string]*shipOrders)}
+}
+
+// Place puts a ship under manual orders, queueing order after any it has pending. A Hold order queues nothing.
+func (o *Orders) Place(ship string, order m.ManualOrder) {
+	if o == nil {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	orders, ok := o.ships[ship]
+	if !ok {
+		orders = &shipOrders{changed: make(chan struct{})}
+		o.ships[ship] = orders
+	}
+	if order.Kind != m.OrderHold {
+		orders.pending = append(orders.pending, order)
+	}
+
+	close(orders.changed)
+	orders.changed = make(chan struct{})
+}
+
+// Release returns a ship to automatic missions, dropping its pending orders. It reports whether the ship was
+// under manual orders.
+func (o *Orders) Release(ship string) bool {
+	if o == nil {
+		return false
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	orders, ok := o.ships[ship]
+	if !ok {
+		return false
+	}
+	delete(o.ships, ship)
+	close(orders.changed)
+
+	return true
+}
+
+// Next takes a ship's next pending order, or a Hold order once none is pending, with a channel closed when
+// the ship is next given an order. It reports false if the ship is not under manual orders.
+func (o *Orders) Next(ship string) (m.ManualOrder, <-chan struct{}, bool) {
+	if o == nil {
+		return m.ManualOrder{}, nil, false
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	orders, ok := o.ships[ship]
+	if !ok {
+		return m.ManualOrder{}, nil, false
+	}
+	if len(orders.pending) == 0 {
+		return m.ManualOrder{Kind: m.OrderHold}, orders.changed, true
+	}
+
+	order := orders.pending[0]
+	orders.pending = orders.pending[1:]
+
+	return order, orders.changed, true
+}
+
+// Order gives a ship a manual order, once it is checked against the ship's capabilities and its current state.
+// The order is carried out the next time the ship reports in; a mission underway is not interrupted.
+func (ab *AgentBot) Order(ship m.Ship, order m.ManualOrder) error {
+	order, err := normalizeOrder(order)
+	if err != nil {
+		return err
+	}
+	if err := ab.validateOrder(ship, order); err != nil {
+		return err
+	}
+
+	if order.Kind == m.OrderResume {
+		if !ab.orders.Release(ship.Symbol) {
+			return fmt.Errorf("ship %s is not under manual orders", ship.Symbol)
+		}
+		if entry, ok := ab.journal.Get(ship.Symbol); ok && entry.Kind == journalHold {
+			if err := ab.journal.End(ship.Symbol); err != nil {
+				ab.logger.Warn("📓 Error writing journal.", "error", err)
+			}
+		}
+
+		ab.logger.Info("🕹️ Ship resumed automatic missions.", "ship", ship.Symbol)
+		return nil
+	}
+
+	ab.orders.Place(ship.Symbol, order)
+	ab.logger.Info("🕹️ Manual order given.", "ship", ship.Symbol, "order", order.String())
+
+	return nil
+}
+
+// normalizeOrder upper-cases an order's kind and symbols as the user may have typed them, rejecting malformed
+// symbols.
+func normalizeOrder(order m.ManualOrder) (m.ManualOrder, error) {
+	order.Kind = strings.ToUpper(strings.TrimSpace(order.Kind))
+
+	var err error
+	if order.Waypoint != "" {
+		if order.Waypoint, err = lib.NormalizeWaypointSymbol(order.Waypoint); err != nil {
+			return order, err
+		}
+	}
+	if order.Good != "" {
+		if order.Good, err = lib.NormalizeSymbol(order.Good); err != nil {
+			return order, err
+		}
+	}
+
+	return order, nil
+}
+
+// validateOrder checks that a ship can carry out an order.
+func (ab *AgentBot) validateOrder(ship m.Ship, order m.ManualOrder) error {
+	switch order.Kind {
+	case m.OrderNavigate:
+		if order.Waypoint == "" {
+			return errors.New("a navigate order needs a waypoint")
+		}
+
+		system, err := lib.SystemSymbolOf(order.Waypoint)
+		if err != nil {
+			return err
+		}
+		if system != ship.Nav.SystemSymbol {
+			return fmt.Errorf("waypoint %s is not in %s, the system ship %s is in", order.Waypoint, ship.Nav.SystemSymbol, ship.Symbol)
+		}
+		if _, err := ab.systems.Waypoint(order.Waypoint); err != nil {
+			return fmt.Errorf("waypoint %s: %w", order.Waypoint, err)
+		}
+	case m.OrderSell:
+		if order.Good == "" {
+			return errors.New("a sell order needs a good")
+		}
+		if order.Units < 0 {
+			return fmt.Errorf("cannot sell %d units", order.Units)
+		}
+		if m.CapabilitiesOf(ship).CargoCapacity == 0 {
+			return fmt.Errorf("ship %s has no cargo hold", ship.Symbol)
+		}
+
+		held := ship.Cargo.UnitsOf(order.Good)
+		if held == 0 {
+			return fmt.Errorf("ship %s has no %s aboard", ship.Symbol, order.Good)
+		}
+		if order.Units > held {
+			return fmt.Errorf("ship %s has only %d %s aboard", ship.Symbol, held, order.Good)
+		}
+	case m.OrderDock, m.OrderOrbit, m.OrderHold, m.OrderResume:
+	default:
+		return fmt.Errorf("unknown order kind %q", order.Kind)
+	}
+
+	return nil
+}
+
+// manualOrder returns the mission carrying out a ShipBot's next manual order, if it is under manual orders.
+// Queued follow-up missions are dropped, since the ship may be elsewhere once it is resumed.
+func (ab *AgentBot) manualOrder(sb *ShipBot) (Mission, bool) {
+	order, changed, ok := ab.orders.Next(sb.ship.Symbol)
+	if !ok {
+		return Mission{}, false
+	}
+	sb.queued = nil
+
+	return orderMission(order, changed), true
+}
+
+// orderMission returns the mission carrying out a manual order. A holding ship waits until changed is closed.
+func orderMission(order m.ManualOrder, changed <-chan struct{}) Mission {
+	mission := Mission{Name: "Order: " + order.String()}
+
+	switch order.Kind {
+	case m.OrderNavigate:
+		mission.Run = func(sb *ShipBot, sbCh chan ShipBot) { sb.NavigateTo(order.Waypoint, sbCh) }
+	case m.OrderDock:
+		mission.Run = (*ShipBot).DockShip
+	case m.OrderOrbit:
+		mission.Run = (*ShipBot).OrbitShip
+	case m.OrderSell:
+		mission.Run = func(sb *ShipBot, sbCh chan ShipBot) { sb.SellGood(order.Good, order.Units, sbCh) }
+	default:
+		mission.Run = func(sb *ShipBot, sbCh chan ShipBot) { sb.Hold(changed, sbCh) }
+	}
+
+	return mission
+}
+
+// journalHold records that the ShipBot is under manual orders, so it holds again after a restart.
+func (sb *ShipBot) journalHold() {
+	if entry, ok := sb.journal.Get(sb.ship.Symbol); ok && entry.Kind == journalHold {
+		return
+	}
+
+	sb.journalBegin(journalHold, stepHolding, "", nil)
+}
+
+// Hold keeps the ship where it is until it is given another manual order, reporting in every idle interval
+// meanwhile.
+func (sb *ShipBot) Hold(changed <-chan struct{}, sbCh chan ShipBot) {
+	sb.logger.Info("🕹️ Holding for orders...")
+	sb.watch.expect(sb.clock.Now().Add(sb.tuning.IdleInterval))
+
+	select {
+	case <-changed:
+	case <-sb.clock.After(sb.tuning.IdleInterval):
+	}
+
+	sb.Report(sbCh)
+}
+
+// SellGood docks and sells units of a good at the market the ship is at, or all of it aboard for zero units.
+// Unlike a trade's sale, it never moves on to another market when the price drops.
+func (sb *ShipBot) SellGood(symbol string, units int, sbCh chan ShipBot) {
+	if err := sb.dockIfNeeded(); err != nil {
+		sb.Fail(boterr.Wrap(err, "waypoint", sb.ship.Nav.WaypointSymbol))
+		sb.Report(sbCh)
+		return
+	}
+
+	if held := sb.ship.Cargo.UnitsOf(symbol); units == 0 || units > held {
+		units = held
+	}
+	if units == 0 {
+		sb.Fail(fmt.Errorf("no %s aboard", symbol))
+		sb.Report(sbCh)
+		return
+	}
+
+	drop := sb.sellPriceDrop
+	sb.sellPriceDrop = 0
+	err := sb.sellTradeGood(symbol, units)
+	sb.sellPriceDrop = drop
+
+	if err != nil {
+		sb.Fail(boterr.Wrap(err, "waypoint", sb.ship.Nav.WaypointSymbol, "good", symbol))
+	}
+
+	sb.Report(sbCh)
+}
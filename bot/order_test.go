@@ -0,0 +1,152 @@
+package bot
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+// startedMission waits for a ship to be dispatched, and returns the mission it was sent on.
+func startedMission(t *testing.T, events <-chan event.Event, ship string) string {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e.Type == event.MissionStarted && e.Ship == ship {
+				return e.Mission
+			}
+		case <-timeout:
+			t.Fatalf("%s was not dispatched", ship)
+		}
+	}
+}
+
+func TestHeldShipSkipsAutomaticDispatchUntilResumed(t *testing.T) {
+	ab, server, _ := agentBot(t, fixture(t))
+	sb := ab.newShipBot(t, server, "MOCK-2")
+	sb.arrival = nil
+	started := ab.bus.Subscribe(64)
+
+	// dispatch dispatches a reported ShipBot and returns the mission it was sent on.
+	dispatch := func(sb ShipBot, sbCh chan ShipBot) string {
+		t.Helper()
+
+		ab.DispatchNext(sb, sbCh)
+		return startedMission(t, started, "MOCK-2")
+	}
+	// report waits for the ShipBot's mission to report in.
+	report := func(sbCh chan ShipBot) ShipBot {
+		t.Helper()
+
+		select {
+		case reported := <-sbCh:
+			return reported
+		case <-time.After(5 * time.Second):
+			t.Fatal("held ship did not report in")
+			return ShipBot{}
+		}
+	}
+
+	if err := ab.Order(*sb.ship, m.ManualOrder{Kind: "hold"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sbCh := make(chan ShipBot, 1)
+	if mission := dispatch(*sb, sbCh); mission != "Order: hold" {
+		t.Fatalf("held ship sent on %q, want it holding", mission)
+	}
+
+	// Another order wakes the holding ship, which is dispatched on its orders again, not automatically.
+	if err := ab.Order(*sb.ship, m.ManualOrder{Kind: m.OrderHold}); err != nil {
+		t.Fatal(err)
+	}
+	if mission := dispatch(report(sbCh), sbCh); mission != "Order: hold" {
+		t.Fatalf("held ship sent on %q after reporting in, want it still holding", mission)
+	}
+
+	if err := ab.Order(*sb.ship, m.ManualOrder{Kind: m.OrderResume}); err != nil {
+		t.Fatal(err)
+	}
+	if mission := dispatch(report(sbCh), sbCh); strings.HasPrefix(mission, "Order:") {
+		t.Fatalf("resumed ship sent on %q, want an automatic mission", mission)
+	}
+
+	if err := ab.Order(*sb.ship, m.ManualOrder{Kind: m.OrderResume}); err == nil {
+		t.Fatal("resuming a ship not under orders succeeded")
+	}
+}
+
+func TestOrdersAreValidated(t *testing.T) {
+	ab, server, _ := agentBot(t, fixture(t))
+	excavator, _ := server.Ship("MOCK-2")
+
+	for _, tc := range []struct {
+		name  string
+		order m.ManualOrder
+	}{
+		{name: "unknown kind", order: m.ManualOrder{Kind: "JUMP"}},
+		{name: "navigate nowhere", order: m.ManualOrder{Kind: m.OrderNavigate}},
+		{name: "navigate out of system", order: m.ManualOrder{Kind: m.OrderNavigate, Waypoint: "X1-ZZ9-A1"}},
+		{name: "navigate to unknown waypoint", order: m.ManualOrder{Kind: m.OrderNavigate, Waypoint: "X1-MK1-Z9"}},
+		{name: "sell what is not aboard", order: m.ManualOrder{Kind: m.OrderSell, Good: "IRON_ORE"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := ab.Order(excavator, tc.order); err == nil {
+				t.Fatalf("order %+v accepted", tc.order)
+			}
+		})
+	}
+
+	if err := ab.Order(excavator, m.ManualOrder{Kind: "navigate", Waypoint: "x1-mk1-b2"}); err != nil {
+		t.Fatalf("navigating to a lower-cased waypoint: %s", err)
+	}
+}
+
+func TestHoldSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.json")
+
+	// start starts the bot afresh on the journal, and returns the ShipBot the excavator wakes as.
+	start := func() (*AgentBot, *ShipBot) {
+		journal, err := store.OpenJournal(path, "2030-01-01")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ab, server, _ := agentBot(t, fixture(t))
+		ab.journal = journal
+		sb := ab.newShipBot(t, server, "MOCK-2")
+		sb.arrival = nil
+		sb.journal = journal
+		sb.resuming = true
+
+		return ab, sb
+	}
+
+	ab, sb := start()
+	if err := ab.Order(*sb.ship, m.ManualOrder{Kind: m.OrderHold}); err != nil {
+		t.Fatal(err)
+	}
+	started := ab.bus.Subscribe(16)
+	ab.DispatchNext(*sb, make(chan ShipBot, 1))
+	startedMission(t, started, "MOCK-2")
+
+	restarted, sb := start()
+	started = restarted.bus.Subscribe(16)
+	restarted.DispatchNext(*sb, make(chan ShipBot, 1))
+	if mission := startedMission(t, started, "MOCK-2"); mission != "Order: hold" {
+		t.Fatalf("held ship sent on %q after a restart, want it still holding", mission)
+	}
+	if err := restarted.Order(*sb.ship, m.ManualOrder{Kind: m.OrderResume}); err != nil {
+		t.Fatalf("resuming the ship after a restart: %s", err)
+	}
+	if _, ok := restarted.journal.Get("MOCK-2"); ok {
+		t.Fatal("journal still holds the resumed ship")
+	}
+}
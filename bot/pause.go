@@ -0,0 +1,92 @@
+package bot
+
+import "sync"
+
+/*
+⏸️ Pause
+*/
+
+// pauseGate holds ships reporting in while the fleet is paused. Methods are safe to call on a nil pauseGate,
+// which never pauses.
+type pauseGate struct {
+	mu sync.Mutex
+	// resumed is closed while the fleet runs, and replaced by an open channel while it is paused.
+	resumed chan struct{}
+	// pauses counts the times the fleet has been paused.
+	pauses int
+}
+
+// newPauseGate creates a pauseGate that is not paused.
+func newPauseGate() *pauseGate {
+	resumed := make(chan struct{})
+	close(resumed)
+
+	return &pauseGate{resumed: resumed}
+}
+
+// pause holds ships until resume, reporting false if the gate was already paused.
+func (g *pauseGate) pause() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.paused() {
+		return false
+	}
+	g.resumed = make(chan struct{})
+	g.pauses++
+
+	return true
+}
+
+// resume releases the held ships, reporting false if the gate was not paused.
+func (g *pauseGate) resume() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.paused() {
+		return false
+	}
+	close(g.resumed)
+
+	return true
+}
+
+// Paused reports whether ships are held.
+func (g *pauseGate) Paused() bool {
+	if g == nil {
+		return false
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.paused()
+}
+
+// paused reports whether ships are held. It must be called with mu held.
+func (g *pauseGate) paused() bool {
+	select {
+	case <-g.resumed:
+		return false
+	default:
+		return true
+	}
+}
+
+// wait blocks while the gate is paused, returning the number of times it has been paused.
+func (g *pauseGate) wait() int {
+	if g == nil {
+		return 0
+	}
+
+	g.mu.Lock()
+	resumed := g.resumed
+	g.mu.Unlock()
+
+	<-resumed
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.pauses
+}
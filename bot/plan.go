@@ -0,0 +1,192 @@
+package bot
+
+import (
+	"strings"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/lib"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+/*
+📋 Plans
+*/
+
+// planTally is the plan a mission previewed and what the mission has actually done so far.
+type planTally struct {
+	planned m.Plan
+	actual  m.Plan
+	started time.Time
+}
+
+// preview logs the plan of the mission about to be carried out, and starts tallying what the mission actually
+// does so it can be compared with the plan when the mission ends.
+func (sb *ShipBot) preview(plan m.Plan) {
+	sb.tally = &planTally{
+		planned: plan,
+		actual:  m.Plan{Kind: plan.Kind, Good: plan.Good, Source: plan.Source, Destination: plan.Destination},
+		started: sb.clock.Now(),
+	}
+
+	sb.logger.Info("📋 Mission planned: "+plan.Describe(), plan.LogValues()...)
+}
+
+// settlePlan logs how the mission's plan compared with what the mission actually did, and publishes the outcome
+// for the ledger's accuracy stats. Missions without a plan settle nothing.
+func (sb *ShipBot) settlePlan() {
+	if sb.tally == nil {
+		return
+	}
+
+	outcome := m.PlanOutcome{Planned: sb.tally.planned, Actual: sb.tally.actual}
+	outcome.Actual.Travel = sb.clock.Now().Sub(sb.tally.started)
+	sb.tally = nil
+
+	delta := outcome.Delta()
+	sb.logger.Info("📋 Mission plan settled.", "kind", outcome.Planned.Kind, "plannedProfit", outcome.Planned.Profit(), "actualProfit", outcome.Actual.Profit(),
+		"profitDelta", delta.Profit(), "unitsDelta", delta.Units, "fuelDelta", delta.Fuel, "travelDelta", delta.Travel.Round(time.Second))
+	sb.bus.Publish(event.Event{Type: event.PlanSettled, Ship: sb.ship.Symbol, Mission: sb.mission, MissionID: sb.missionID, Message: outcome.Planned.Kind, Data: outcome})
+}
+
+// tallyPurchase adds a purchase to the mission's actuals. Goods procured for a contract are credited at what the
+// contract pays for them, which is fixed by its terms.
+func (sb *ShipBot) tallyPurchase(transaction m.MarketTransaction) {
+	if sb.tally == nil || transaction.TradeSymbol != sb.tally.planned.Good {
+		return
+	}
+
+	actual := &sb.tally.actual
+	actual.Units += transaction.Units
+	actual.Cost += transaction.TotalPrice
+	if planned := sb.tally.planned; planned.Kind == m.PlanProcurement && planned.Units > 0 {
+		actual.Revenue += planned.Revenue * int64(transaction.Units) / int64(planned.Units)
+	}
+}
+
+// tallySale adds a sale to a trade mission's actuals.
+func (sb *ShipBot) tallySale(transaction m.MarketTransaction) {
+	if sb.tally == nil || sb.tally.planned.Kind != m.PlanTrade || transaction.TradeSymbol != sb.tally.planned.Good {
+		return
+	}
+
+	sb.tally.actual.Revenue += transaction.TotalPrice
+}
+
+// tallyDelivery adds a delivery to a delivery mission's actuals, credited at what the contract pays per unit.
+func (sb *ShipBot) tallyDelivery(contract m.Contract, units int) {
+	if sb.tally == nil || sb.tally.planned.Kind != m.PlanDelivery {
+		return
+	}
+
+	sb.tally.actual.Units += units
+	sb.tally.actual.Revenue += unitPayment(contract) * int64(units)
+}
+
+// tallyFuel adds fuel burned, and the price of fuel bought, to the mission's actuals.
+func (sb *ShipBot) tallyFuel(burned int, price int64) {
+	if sb.tally == nil {
+		return
+	}
+
+	sb.tally.actual.Fuel += burned
+	sb.tally.actual.FuelCost += price
+}
+
+// legEstimate estimates the fuel the ship burns, and the time it takes, travelling from its waypoint through
+// waypoints in turn at its flight mode. Fuel is the learned cost of routes already learned. Legs to or from
+// unknown waypoints are left out.
+func (sb *ShipBot) legEstimate(waypoints ...string) (int, time.Duration) {
+	var fuel int
+	var travel time.Duration
+
+	from := sb.ship.Nav.WaypointSymbol
+	for _, symbol := range waypoints {
+		origin, err := sb.systems.Waypoint(from)
+		from = symbol
+		if err != nil {
+			continue
+		}
+		destination, err := sb.systems.Waypoint(symbol)
+		if err != nil || origin.Symbol == destination.Symbol {
+			continue
+		}
+
+		fuel += sb.fuelCosts.Estimate(origin, destination, sb.ship.Nav.FlightMode)
+		travel += lib.TravelTime(lib.WaypointDistance(origin, destination), sb.ship.Engine.Speed, sb.ship.Nav.FlightMode)
+	}
+
+	return fuel, travel
+}
+
+// tradePlan is the plan of a trade route: buying a hold of the good at its source and selling it at the
+// destination.
+func (sb *ShipBot) tradePlan(route TradeRoute) m.Plan {
+	fuel, travel := sb.legEstimate(route.Source, route.Destination)
+
+	return m.Plan{
+		Kind:        m.PlanTrade,
+		Good:        route.Good,
+		Units:       route.Units,
+		Source:      route.Source,
+		Destination: route.Destination,
+		Cost:        route.PurchasePrice * int64(route.Units),
+		Revenue:     route.SellPrice * int64(route.Units),
+		Fuel:        fuel,
+		FuelCost:    route.FuelCost,
+		Travel:      travel,
+	}
+}
+
+// procurementPlan is the plan of buying units of a quota's good at a market, to deliver for what the contract
+// pays per unit.
+func (sb *ShipBot) procurementPlan(quota Quota, source string, units int) m.Plan {
+	fuel, travel := sb.legEstimate(source, quota.Destination)
+
+	plan := m.Plan{
+		Kind:        m.PlanProcurement,
+		Good:        quota.TradeSymbol,
+		Units:       units,
+		Source:      source,
+		Destination: quota.Destination,
+		Fuel:        fuel,
+		FuelCost:    int64(fuel) * estimatedFuelPrice,
+		Travel:      travel,
+	}
+	if observation, ok := sb.markets.Get(source); ok {
+		price, _ := observation.Market.PurchasePriceOf(quota.TradeSymbol)
+		plan.Cost = price * int64(units)
+	}
+	if planned, ok := sb.plan.find(Delivery{ContractID: quota.ContractID, TradeSymbol: quota.TradeSymbol, Destination: quota.Destination}); ok {
+		plan.Revenue = planned.UnitPayment * int64(units)
+	}
+
+	return plan
+}
+
+// deliveryPlan is the plan of hauling deliveries to their destinations, in the order given, for what their
+// contracts pay per unit.
+func (sb *ShipBot) deliveryPlan(deliveries []Delivery) m.Plan {
+	var goods, destinations []string
+	plan := m.Plan{Kind: m.PlanDelivery}
+	for _, delivery := range deliveries {
+		if !lib.Contains(goods, delivery.TradeSymbol) {
+			goods = append(goods, delivery.TradeSymbol)
+		}
+		if !lib.Contains(destinations, delivery.Destination) {
+			destinations = append(destinations, delivery.Destination)
+		}
+
+		plan.Units += delivery.Units
+		if planned, ok := sb.plan.find(delivery); ok {
+			plan.Revenue += planned.UnitPayment * int64(delivery.Units)
+		}
+	}
+
+	plan.Good = strings.Join(goods, ", ")
+	plan.Destination = strings.Join(destinations, ", ")
+	plan.Fuel, plan.Travel = sb.legEstimate(destinations...)
+	plan.FuelCost = int64(plan.Fuel) * estimatedFuelPrice
+
+	return plan
+}
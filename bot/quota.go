@@ -0,0 +1,445 @@
+package bot
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/GeoffreyDick/gogarin/boterr"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/lib"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+/*
+📦 Quotas
+*/
+
+// Quota is the part of a contract deliverable a ship has claimed: units it has set out to procure and deliver.
+type Quota struct {
+	ContractID  string `json:"contractId"`
+	TradeSymbol string `json:"tradeSymbol"`
+	Destination string `json:"destination"`
+	Ship        string `json:"ship"`
+	Units       int    `json:"units"`
+	// Distance is how far the ship was from the destination when it claimed the quota. Nearer ships keep their
+	// quotas when a deliverable has more claimed than it still requires.
+	Distance float64 `json:"distance"`
+}
+
+func (q Quota) LogValues() []interface{} {
+	return []interface{}{
+		"contract", q.ContractID,
+		"symbol", q.TradeSymbol,
+		"destination", q.Destination,
+		"units", q.Units,
+	}
+}
+
+// deliverable identifies one good of a contract.
+type deliverable struct {
+	contractID  string
+	tradeSymbol string
+}
+
+// Quotas splits contract deliverables between ships, tracking the units each ship has claimed but not yet
+// delivered so no two ships procure the same units. Methods are safe to call on a nil Quotas, which tracks
+// nothing and grants every claim up to what the contract still requires.
+type Quotas struct {
+	mu     sync.Mutex
+	claims []Quota
+	// fulfilled are the latest known units fulfilled of each deliverable, which may be ahead of the contracts
+	// a ship decides from.
+	fulfilled map[deliverable]int
+}
+
+// NewQuotas creates an empty Quotas.
+func NewQuotas() *Quotas {
+	return &Quotas{fulfilled: make(map[deliverable]int)}
+}
+
+// Claim claims up to want units of a contract deliverable for a ship at distance from its destination,
+// replacing any claim the ship had on it. Units already claimed by other ships are not granted again.
+// It reports false, dropping the ship's claim, if nothing is left to claim.
+func (q *Quotas) Claim(contractID string, good m.ContractDeliverGood, ship string, want int, distance float64) (Quota, bool) {
+	quota := Quota{
+		ContractID:  contractID,
+		TradeSymbol: good.TradeSymbol,
+		Destination: good.DestinationSymbol,
+		Ship:        ship,
+		Distance:    distance,
+	}
+
+	if q == nil {
+		quota.Units = want
+		if remaining := good.UnitsRequired - good.UnitsFulfilled; remaining < want {
+			quota.Units = remaining
+		}
+		return quota, quota.Units > 0
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	key := deliverable{contractID, good.TradeSymbol}
+	q.observe(key, good.UnitsFulfilled)
+
+	available := good.UnitsRequired - q.fulfilled[key]
+	for _, claim := range q.claims {
+		if claim.ContractID == contractID && claim.TradeSymbol == good.TradeSymbol && claim.Ship != ship {
+			available -= claim.Units
+		}
+	}
+
+	quota.Units = want
+	if available < want {
+		quota.Units = available
+	}
+
+	q.drop(func(claim Quota) bool {
+		return claim.Ship == ship && claim.ContractID == contractID && claim.TradeSymbol == good.TradeSymbol
+	})
+	if quota.Units <= 0 {
+		return Quota{}, false
+	}
+
+	q.claims = append(q.claims, quota)
+
+	return quota, true
+}
+
+// Delivered records that a ship delivered units of its quota, and the contract as the delivery left it.
+func (q *Quotas) Delivered(ship string, contract m.Contract, tradeSymbol string, units int) {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, claim := range q.claims {
+		if claim.Ship == ship && claim.ContractID == contract.ID && claim.TradeSymbol == tradeSymbol {
+			q.claims[i].Units -= units
+		}
+	}
+
+	q.rebalance(contract)
+}
+
+// Release drops every quota of a ship, so the units it claimed go to the next ships to claim them.
+func (q *Quotas) Release(ship string) {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.drop(func(claim Quota) bool { return claim.Ship == ship })
+}
+
+// Rebalance fits the quotas to the contracts as they stand. Quotas on contracts no longer among them are
+// dropped, and where deliveries by any ship leave less required than is claimed, the farthest ships' quotas
+// are cut first.
+func (q *Quotas) Rebalance(contracts []m.Contract) {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	current := make(map[string]bool, len(contracts))
+	for _, contract := range contracts {
+		current[contract.ID] = true
+	}
+	q.drop(func(claim Quota) bool { return !current[claim.ContractID] })
+
+	for key := range q.fulfilled {
+		if !current[key.contractID] {
+			delete(q.fulfilled, key)
+		}
+	}
+
+	for _, contract := range contracts {
+		q.rebalance(contract)
+	}
+}
+
+// All returns every quota, sorted by contract, good, and ship.
+func (q *Quotas) All() []Quota {
+	if q == nil {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	all := append([]Quota{}, q.claims...)
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].ContractID != all[j].ContractID {
+			return all[i].ContractID < all[j].ContractID
+		}
+		if all[i].TradeSymbol != all[j].TradeSymbol {
+			return all[i].TradeSymbol < all[j].TradeSymbol
+		}
+		return all[i].Ship < all[j].Ship
+	})
+
+	return all
+}
+
+// rebalance cuts the quotas on a contract's deliverables to what each still requires. It must be called with
+// mu held.
+func (q *Quotas) rebalance(contract m.Contract) {
+	for _, good := range contract.Terms.Deliver {
+		key := deliverable{contract.ID, good.TradeSymbol}
+		q.observe(key, good.UnitsFulfilled)
+
+		var indexes []int
+		for i, claim := range q.claims {
+			if claim.ContractID == contract.ID && claim.TradeSymbol == good.TradeSymbol {
+				indexes = append(indexes, i)
+			}
+		}
+
+		candidates := make([]Quota, len(indexes))
+		for i, index := range indexes {
+			candidates[i] = q.claims[index]
+		}
+
+		split := splitDeliverable(good.UnitsRequired-q.fulfilled[key], candidates)
+		for i, index := range indexes {
+			q.claims[index].Units = split[i]
+		}
+	}
+
+	q.drop(func(claim Quota) bool { return claim.Units <= 0 })
+}
+
+// observe records units fulfilled of a deliverable, keeping the highest seen, since deliveries only add to it.
+// It must be called with mu held.
+func (q *Quotas) observe(key deliverable, fulfilled int) {
+	if fulfilled > q.fulfilled[key] {
+		q.fulfilled[key] = fulfilled
+	}
+}
+
+// drop removes the quotas matching f. It must be called with mu held.
+func (q *Quotas) drop(f func(claim Quota) bool) {
+	kept := q.claims[:0]
+	for _, claim := range q.claims {
+		if !f(claim) {
+			kept = append(kept, claim)
+		}
+	}
+	q.claims = kept
+}
+
+// splitDeliverable shares the units a deliverable still requires between the ships claiming it, returning
+// each claim's units in the order given. Ships nearest the destination are served first, each up to what it
+// claimed; the rest get what is left, if anything.
+func splitDeliverable(remaining int, claims []Quota) []int {
+	order := make([]int, len(claims))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if claims[order[a]].Distance != claims[order[b]].Distance {
+			return claims[order[a]].Distance < claims[order[b]].Distance
+		}
+		return claims[order[a]].Ship < claims[order[b]].Ship
+	})
+
+	split := make([]int, len(claims))
+	for _, i := range order {
+		units := claims[i].Units
+		if units > remaining {
+			units = remaining
+		}
+		if units < 0 {
+			units = 0
+		}
+
+		split[i] = units
+		remaining -= units
+	}
+
+	return split
+}
+
+/*
+🛒 Procurement
+*/
+
+// claimQuota claims the ship's share of the first contract deliverable it can take on: up to a hold's worth of
+// what the contract still requires and no other ship has claimed. A quota the ship already holds comes first.
+// It returns the quota and the contract it is on.
+func (sb *ShipBot) claimQuota(contracts []m.Contract) (Quota, m.Contract, bool) {
+	type candidate struct {
+		contract m.Contract
+		good     m.ContractDeliverGood
+	}
+
+	var candidates []candidate
+	for _, contract := range contracts {
+		if !contract.Accepted || contract.Fulfilled {
+			continue
+		}
+
+		for _, good := range contract.Terms.Deliver {
+			if good.UnitsRequired > good.UnitsFulfilled {
+				candidates = append(candidates, candidate{contract, good})
+			}
+		}
+	}
+
+	held := make(map[deliverable]bool)
+	for _, quota := range sb.quotas.All() {
+		if quota.Ship == sb.ship.Symbol {
+			held[deliverable{quota.ContractID, quota.TradeSymbol}] = true
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return held[deliverable{candidates[i].contract.ID, candidates[i].good.TradeSymbol}] &&
+			!held[deliverable{candidates[j].contract.ID, candidates[j].good.TradeSymbol}]
+	})
+
+	for _, c := range candidates {
+		if quota, ok := sb.quotas.Claim(c.contract.ID, c.good, sb.ship.Symbol, sb.ship.Cargo.Capacity, sb.distanceTo(c.good.DestinationSymbol)); ok {
+			return quota, c.contract, true
+		}
+	}
+
+	return Quota{}, m.Contract{}, false
+}
+
+// unitPayment returns what a contract pays per unit delivered, across all its deliverables.
+func unitPayment(contract m.Contract) int64 {
+	var units int
+	for _, good := range contract.Terms.Deliver {
+		units += good.UnitsRequired
+	}
+
+	if units == 0 {
+		return 0
+	}
+
+	return contract.TotalPayment() / int64(units)
+}
+
+// distanceTo returns the distance from the ship to a waypoint, or math.MaxFloat64 if either waypoint is unknown.
+func (sb *ShipBot) distanceTo(waypointSymbol string) float64 {
+	current, err := sb.systems.Waypoint(sb.ship.Nav.WaypointSymbol)
+	if err != nil {
+		return math.MaxFloat64
+	}
+
+	waypoint, err := sb.systems.Waypoint(waypointSymbol)
+	if err != nil {
+		return math.MaxFloat64
+	}
+
+	return lib.WaypointDistance(current, waypoint)
+}
+
+// procurementSource returns the fresh market in the ship's system selling a good most cheaply, and its price.
+func (sb *ShipBot) procurementSource(markets []store.MarketObservation, symbol string) (string, int64, bool) {
+	now := sb.clock.Now()
+
+	var source string
+	var best int64
+	for _, observation := range markets {
+		if now.Sub(observation.ObservedAt) > sb.tradeMaxPriceAge {
+			continue
+		}
+		if systemSymbol, err := lib.SystemSymbolOf(observation.Market.Symbol); err != nil || systemSymbol != sb.ship.Nav.SystemSymbol {
+			continue
+		}
+
+		if price, ok := observation.Market.PurchasePriceOf(symbol); ok && (source == "" || price < best) {
+			source, best = observation.Market.Symbol, price
+		}
+	}
+
+	return source, best, source != ""
+}
+
+// procureMission buys the rest of a quota at a market.
+func procureMission(quota Quota, source string) Mission {
+	return Mission{
+		Name: fmt.Sprintf("Procure %d %s at %s", quota.Units, quota.TradeSymbol, source),
+		Run:  func(sb *ShipBot, sbCh chan ShipBot) { sb.ProcureContractGoods(quota, source, sbCh) },
+	}
+}
+
+// ProcureContractGoods buys the units of a quota not yet aboard at a market, holding a credit reservation for
+// the purchase until it is done. The ship's quotas are released if it fails, so other ships can take them on.
+func (sb *ShipBot) ProcureContractGoods(quota Quota, source string, sbCh chan ShipBot) {
+	units := quota.Units - sb.ship.Cargo.UnitsOf(quota.TradeSymbol)
+	if space := sb.ship.Cargo.SpaceRemaining(); units > space {
+		units = space
+	}
+	if units > 0 {
+		sb.preview(sb.procurementPlan(quota, source, units))
+	}
+
+	if err := sb.procure(quota.TradeSymbol, units, source); err != nil {
+		sb.logger.Error("🛒 Error procuring contract goods.", append(quota.LogValues(), "source", source, "error", err)...)
+		sb.quotas.Release(sb.ship.Symbol)
+		sb.Fail(boterr.Wrap(err, "contract", quota.ContractID, "good", quota.TradeSymbol, "source", source))
+		sb.Report(sbCh)
+		return
+	}
+
+	sb.Report(sbCh)
+}
+
+// procure travels to a market and buys units of a good there, in lots no larger than the market's trade volume.
+func (sb *ShipBot) procure(symbol string, units int, source string) error {
+	if units <= 0 {
+		return nil
+	}
+
+	observation, ok := sb.markets.Get(source)
+	if !ok {
+		return fmt.Errorf("no recorded market at %s", source)
+	}
+	price, ok := observation.Market.PurchasePriceOf(symbol)
+	if !ok {
+		return fmt.Errorf("%s does not sell %s", source, symbol)
+	}
+
+	release, err := sb.agent.ReserveCredits(price * int64(units))
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	sb.planRoute(source)
+	if err := sb.travelAndDock(source); err != nil {
+		return err
+	}
+
+	var bought int
+	for bought < units {
+		res, err := sb.client.PurchaseCargo(sb.ship.Symbol, symbol, sb.tradeLot(source, symbol, units-bought))
+		if err != nil {
+			return err
+		}
+
+		bought += res.Transaction.Units
+		sb.ship.Cargo = res.Cargo
+		sb.agent.Update(res.Agent)
+		sb.tallyPurchase(res.Transaction)
+
+		sb.bus.Publish(event.Event{Type: event.CargoPurchased, Ship: sb.ship.Symbol, Mission: sb.mission, MissionID: sb.missionID, Message: fmt.Sprintf("%d %s for %d", res.Transaction.Units, res.Transaction.TradeSymbol, res.Transaction.TotalPrice), Data: res.Transaction})
+		sb.bus.Publish(event.Event{Type: event.AgentUpdated, Data: res.Agent})
+	}
+
+	sb.logger.Info("🛒 Goods procured.", "symbol", symbol, "units", bought, "source", source, "credits", sb.agent.Credits())
+
+	return nil
+}
@@ -0,0 +1,296 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+// oreContract is an accepted contract for units of IRON_ORE delivered to X1-A-3, paying 100 credits a unit.
+func oreContract(units int, fulfilled int) m.Contract {
+	return m.Contract{ID: "c-1", Accepted: true, Terms: m.ContractTerms{
+		Payment: m.ContractPayment{OnFulfilled: int64(units) * 100},
+		Deliver: []m.ContractDeliverGood{{TradeSymbol: "IRON_ORE", DestinationSymbol: "X1-A-3", UnitsRequired: units, UnitsFulfilled: fulfilled}},
+	}}
+}
+
+// quotaSummary describes quotas as "SHIP UNITS".
+func quotaSummary(quotas []Quota) []string {
+	var summary []string
+	for _, q := range quotas {
+		summary = append(summary, fmt.Sprintf("%s %d", q.Ship, q.Units))
+	}
+	return summary
+}
+
+func TestQuotasSplitADeliverable(t *testing.T) {
+	q := NewQuotas()
+	good := oreContract(800, 0).Terms.Deliver[0]
+
+	if quota, ok := q.Claim("c-1", good, "GOGARIN-2", 500, 40); !ok || quota.Units != 500 {
+		t.Fatalf("first claim = %+v, %t, want 500 units", quota, ok)
+	}
+	if quota, ok := q.Claim("c-1", good, "GOGARIN-3", 500, 10); !ok || quota.Units != 300 {
+		t.Fatalf("second claim = %+v, %t, want the 300 units left", quota, ok)
+	}
+	if quota, ok := q.Claim("c-1", good, "GOGARIN-4", 500, 5); ok {
+		t.Fatalf("third claim = %+v, want nothing left to claim", quota)
+	}
+
+	// Claiming again replaces the ship's claim rather than adding to it.
+	if quota, ok := q.Claim("c-1", good, "GOGARIN-2", 200, 40); !ok || quota.Units != 200 {
+		t.Fatalf("reclaim = %+v, %t, want 200 units", quota, ok)
+	}
+	if got, want := quotaSummary(q.All()), []string{"GOGARIN-2 200", "GOGARIN-3 300"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("quotas = %q, want %q", got, want)
+	}
+
+	// A released ship's units go to the next ship to claim.
+	q.Release("GOGARIN-2")
+	if quota, ok := q.Claim("c-1", good, "GOGARIN-4", 500, 5); !ok || quota.Units != 500 {
+		t.Errorf("claim after release = %+v, %t, want 500 units", quota, ok)
+	}
+}
+
+func TestQuotasRebalanceAsTheContractAdvances(t *testing.T) {
+	q := NewQuotas()
+	good := oreContract(100, 0).Terms.Deliver[0]
+	q.Claim("c-1", good, "GOGARIN-2", 50, 40)
+	q.Claim("c-1", good, "GOGARIN-3", 50, 10)
+
+	// Another ship delivered 30 units, so 70 are still required: the nearer ship keeps its 50.
+	q.Rebalance([]m.Contract{oreContract(100, 30)})
+	if got, want := quotaSummary(q.All()), []string{"GOGARIN-2 20", "GOGARIN-3 50"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after a delivery elsewhere: quotas = %q, want %q", got, want)
+	}
+
+	// A stale contract cannot undo what has been fulfilled.
+	q.Rebalance([]m.Contract{oreContract(100, 0)})
+	if got, want := quotaSummary(q.All()), []string{"GOGARIN-2 20", "GOGARIN-3 50"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after a stale contract: quotas = %q, want %q", got, want)
+	}
+
+	// GOGARIN-3 delivers its 50 units, leaving GOGARIN-2's 20 still to come.
+	q.Delivered("GOGARIN-3", oreContract(100, 80), "IRON_ORE", 50)
+	if got, want := quotaSummary(q.All()), []string{"GOGARIN-2 20"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after delivering: quotas = %q, want %q", got, want)
+	}
+
+	// A contract no longer held drops its quotas.
+	q.Rebalance(nil)
+	if all := q.All(); len(all) != 0 {
+		t.Errorf("quotas on a dropped contract = %+v", all)
+	}
+
+	var none *Quotas
+	if quota, ok := none.Claim("c-1", good, "GOGARIN-2", 500, 0); !ok || quota.Units != 100 {
+		t.Errorf("nil Quotas claim = %+v, %t, want what the contract requires", quota, ok)
+	}
+}
+
+// quotaClient is a ClientAPI for ships at tradeWaypoints buying IRON_ORE at X1-A-1 and delivering it to a
+// contract at X1-A-3. Navigation arrives at once. Deliveries beyond what the contract requires are rejected.
+type quotaClient struct {
+	contractClient
+	mu       sync.Mutex
+	contract m.Contract
+	ships    map[string]*m.Ship
+	// failPurchases is the number of purchases to fail before any succeed.
+	failPurchases int
+	purchased     int
+	rejected      int
+}
+
+func (c *quotaClient) OrbitShip(shipSymbol string) (*m.ShipNav, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ship := c.ships[shipSymbol]
+	ship.Nav.Status = "IN_ORBIT"
+	nav := ship.Nav
+	return &nav, nil
+}
+
+func (c *quotaClient) DockShip(shipSymbol string) (*m.ShipNav, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ship := c.ships[shipSymbol]
+	ship.Nav.Status = "DOCKED"
+	nav := ship.Nav
+	return &nav, nil
+}
+
+func (c *quotaClient) NavigateShip(shipSymbol string, waypointSymbol string) (*api.NavigateShipResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ship := c.ships[shipSymbol]
+	ship.Nav.WaypointSymbol = waypointSymbol
+	ship.Nav.Route.Destination.Symbol = waypointSymbol
+	return &api.NavigateShipResponse{Nav: ship.Nav, Fuel: m.ShipFuel{Current: 100, Capacity: 100}}, nil
+}
+
+func (c *quotaClient) PurchaseCargo(shipSymbol string, cargoSymbol string, units int) (*api.PurchaseCargoResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.failPurchases > 0 {
+		c.failPurchases--
+		return nil, errors.New("market closed")
+	}
+
+	ship := c.ships[shipSymbol]
+	if err := ship.Cargo.Add(cargoSymbol, units); err != nil {
+		return nil, err
+	}
+	c.purchased += units
+
+	return &api.PurchaseCargoResponse{
+		Agent:       m.Agent{Credits: 100000},
+		Cargo:       copyCargo(ship.Cargo),
+		Transaction: m.MarketTransaction{TradeSymbol: cargoSymbol, Units: units, PricePerUnit: 10, TotalPrice: 10 * int64(units)},
+	}, nil
+}
+
+func (c *quotaClient) DeliverContract(contractID string, shipSymbol string, tradeSymbol string, units int) (*api.DeliverContractResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	good := &c.contract.Terms.Deliver[0]
+	if good.UnitsFulfilled+units > good.UnitsRequired {
+		c.rejected++
+		return nil, fmt.Errorf("delivering %d units would exceed the %d required", units, good.UnitsRequired-good.UnitsFulfilled)
+	}
+
+	ship := c.ships[shipSymbol]
+	if err := ship.Cargo.Remove(tradeSymbol, units); err != nil {
+		return nil, err
+	}
+	good.UnitsFulfilled += units
+
+	return &api.DeliverContractResponse{Contract: c.snapshot()[0], Cargo: copyCargo(ship.Cargo)}, nil
+}
+
+func (c *quotaClient) FulfillContract(contractID string) (*api.FulfillContractResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.contract.Fulfilled = true
+	return &api.FulfillContractResponse{Agent: m.Agent{Credits: 100000}, Contract: c.snapshot()[0]}, nil
+}
+
+// copyCargo copies cargo so the server and ship do not share an inventory.
+func copyCargo(cargo m.ShipCargo) m.ShipCargo {
+	cargo.Inventory = append([]m.ShipCargoItem(nil), cargo.Inventory...)
+	return cargo
+}
+
+// current returns the contract as the server has it.
+func (c *quotaClient) current() []m.Contract {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.snapshot()
+}
+
+// snapshot copies the contract. It must be called with mu held.
+func (c *quotaClient) snapshot() []m.Contract {
+	contract := c.contract
+	contract.Terms.Deliver = append([]m.ContractDeliverGood(nil), contract.Terms.Deliver...)
+	return []m.Contract{contract}
+}
+
+func TestTwoShipsDeliverOneDeliverableWithoutOvershoot(t *testing.T) {
+	tests := []struct {
+		name          string
+		failPurchases int
+	}{
+		{"both legs succeed", 0},
+		// The first ship to buy fails its leg, so its quota goes back to be claimed again.
+		{"a failed leg", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+
+			c := &quotaClient{
+				contractClient: contractClient{waypoints: tradeWaypoints},
+				contract:       oreContract(50, 0),
+				ships:          make(map[string]*m.Ship),
+				failPurchases:  tt.failPurchases,
+			}
+
+			markets := store.NewMarketStore()
+			source := tradeMarket("X1-A-1", 0, "IRON_ORE 10 8", "FUEL 2 1")
+			markets.Record(source.Market, time.Now())
+
+			bus := event.NewBus()
+			t.Cleanup(bus.Close)
+			agent := store.NewAgentState(m.Agent{Credits: 100000}, 0)
+			systems := store.NewSystemKnowledge(c)
+			quotas := NewQuotas()
+
+			var ships []*ShipBot
+			for _, start := range []struct{ symbol, waypoint string }{{"GOGARIN-2", "X1-A-1"}, {"GOGARIN-3", "X1-A-4"}} {
+				ship := m.Ship{Symbol: start.symbol}
+				ship.Registration.Role = "HAULER"
+				ship.Nav = m.ShipNav{SystemSymbol: "X1-A", WaypointSymbol: start.waypoint, Status: "DOCKED"}
+				ship.Cargo = m.ShipCargo{Capacity: 30}
+				ship.Fuel = m.ShipFuel{Current: 100, Capacity: 100}
+				server := ship
+				server.Cargo = copyCargo(ship.Cargo)
+				c.ships[ship.Symbol] = &server
+
+				sb := NewShipBot(c, &ship, agent, systems, markets, bus, cfg)
+				sb.arrival, sb.dock = nil, nil
+				sb.quotas = quotas
+				ships = append(ships, sb)
+			}
+
+			// Both ships decide before either runs, as they would when reporting in together.
+			for round := 0; round < 10 && !c.current()[0].Fulfilled; round++ {
+				missions := make([]Mission, len(ships))
+				for i, sb := range ships {
+					missions[i] = (ContractStrategy{}).Decide(sb, AgentSnapshot{Contracts: c.current(), Markets: markets.All()})
+				}
+				for i, sb := range ships {
+					if name := missions[i].Name; !strings.HasPrefix(name, "Procure") && !strings.HasPrefix(name, deliverMissionPrefix) {
+						continue
+					}
+
+					sbCh := make(chan ShipBot, 1)
+					missions[i].Run(sb, sbCh)
+					reported := <-sbCh
+					*sb = reported
+				}
+			}
+
+			contract := c.current()[0]
+			if !contract.Fulfilled || contract.Terms.Deliver[0].UnitsFulfilled != 50 {
+				t.Errorf("contract = %+v, want all 50 units delivered and fulfilled", contract.Terms.Deliver[0])
+			}
+			if c.purchased != 50 {
+				t.Errorf("bought %d units for a 50-unit deliverable", c.purchased)
+			}
+			if c.rejected != 0 {
+				t.Errorf("%d deliveries rejected for exceeding the contract", c.rejected)
+			}
+			for _, sb := range ships {
+				if units := sb.ship.Cargo.UnitsOf("IRON_ORE"); units != 0 {
+					t.Errorf("%s left holding %d IRON_ORE", sb.ship.Symbol, units)
+				}
+			}
+		})
+	}
+}
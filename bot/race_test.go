@@ -0,0 +1,6 @@
+//go:build race
+
+package bot
+
+// raceEnabled relaxes timing assertions under the race detector, which slows the bots several times over.
+const raceEnabled = true
@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"sync/atomic"
+	"time"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+/*
+📇 Registry
+*/
+
+// instances numbers ShipBots as they are created, so copies of one ShipBot can be told from a duplicate.
+var instances uint64
+
+// newInstance returns a ShipBot instance number that has not been used.
+func newInstance() uint64 {
+	return atomic.AddUint64(&instances, 1)
+}
+
+// Registry tracks the canonical ShipBot of each ship symbol in the command loop, so two ShipBots for
+// the same ship never issue conflicting orders. It also records when each ship last reported in.
+type Registry struct {
+	ships *store.Map[string, registration]
+}
+
+// registration is the canonical ShipBot of a ship, and the freshest state seen from its duplicates.
+type registration struct {
+	instance uint64
+	lastSeen time.Time
+	// ship and cooldown are the state of the last dropped duplicate, merged into the canonical ShipBot
+	// when it next reports in.
+	ship     *m.Ship
+	cooldown *m.Cooldown
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{ships: store.NewMap[string, registration]()}
+}
+
+// Admit checks a ShipBot reporting in at now. The first ShipBot to report for a ship becomes canonical, and
+// its reports are always admitted, merged with any fresher state from duplicates. A report from any other
+// ShipBot for the same ship is a duplicate: its state is kept for merging and false is returned, so it is dropped.
+func (r *Registry) Admit(sb *ShipBot, now time.Time) bool {
+	admitted := true
+	r.ships.Update(sb.ship.Symbol, func(reg registration, ok bool) (registration, bool) {
+		if !ok {
+			return registration{instance: sb.instance, lastSeen: now}, true
+		}
+
+		if reg.instance != sb.instance {
+			ship := *sb.ship
+			reg.ship, reg.cooldown = &ship, sb.cooldown
+			admitted = false
+			return reg, true
+		}
+
+		reg.lastSeen = now
+		if reg.ship != nil {
+			mergeFresher(sb, reg.ship, reg.cooldown, now)
+			reg.ship, reg.cooldown = nil, nil
+		}
+		return reg, true
+	})
+
+	return admitted
+}
+
+// Retire forgets a ship's canonical ShipBot, so the next ShipBot to report for it becomes canonical.
+func (r *Registry) Retire(shipSymbol string) {
+	r.ships.Delete(shipSymbol)
+}
+
+// LastSeen returns when a ship's canonical ShipBot last reported in.
+func (r *Registry) LastSeen(shipSymbol string) (time.Time, bool) {
+	reg, ok := r.ships.Get(shipSymbol)
+
+	return reg.lastSeen, ok
+}
+
+// mergeFresher takes a duplicate's nav and cargo into sb if the duplicate departed on its route later, since
+// cargo changes only between flights, and its cooldown if it expires later.
+func mergeFresher(sb *ShipBot, ship *m.Ship, cooldown *m.Cooldown, now time.Time) {
+	if ship.Nav.Route.DepartureTime.After(sb.ship.Nav.Route.DepartureTime) {
+		sb.ship.Nav = ship.Nav
+		sb.ship.Cargo = ship.Cargo
+	}
+
+	if cooldown != nil && (sb.cooldown == nil || cooldown.Remaining(now) > sb.cooldown.Remaining(now)) {
+		sb.setCooldown(cooldown)
+	}
+}
@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+// registered returns a new ShipBot for a ship that departed on its route at departed.
+func registered(symbol string, departed time.Time) *ShipBot {
+	ship := m.Ship{Symbol: symbol}
+	ship.Nav.Route.DepartureTime = departed
+
+	return &ShipBot{ship: &ship, instance: newInstance()}
+}
+
+func TestRegistryDropsDuplicatesAndMergesTheirState(t *testing.T) {
+	epoch := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := NewRegistry()
+
+	canonical := registered("GOGARIN-1", epoch)
+	if !r.Admit(canonical, epoch) {
+		t.Fatal("the first ShipBot to report was not admitted")
+	}
+	// A copy of the canonical ShipBot is the same instance.
+	again := *canonical
+	if !r.Admit(&again, epoch.Add(time.Minute)) {
+		t.Fatal("a copy of the canonical ShipBot was dropped")
+	}
+
+	// A duplicate has since set off again, and its reactor cools down for longer.
+	duplicate := registered("GOGARIN-1", epoch.Add(2*time.Minute))
+	duplicate.ship.Nav.WaypointSymbol = "X1-A-2"
+	duplicate.ship.Cargo.Units = 12
+	duplicate.cooldown = &m.Cooldown{ShipSymbol: "GOGARIN-1", Expiration: m.OptionalTime{Time: epoch.Add(time.Hour)}}
+	if r.Admit(duplicate, epoch.Add(3*time.Minute)) {
+		t.Fatal("a duplicate ShipBot was admitted")
+	}
+	if seen, _ := r.LastSeen("GOGARIN-1"); !seen.Equal(epoch.Add(time.Minute)) {
+		t.Fatalf("last seen %s, want when the canonical ShipBot last reported, not the duplicate", seen)
+	}
+
+	if !r.Admit(canonical, epoch.Add(4*time.Minute)) {
+		t.Fatal("the canonical ShipBot was dropped after a duplicate reported")
+	}
+	if canonical.ship.Nav.WaypointSymbol != "X1-A-2" || canonical.ship.Cargo.Units != 12 {
+		t.Fatalf("canonical ShipBot at %s with %d units, want the duplicate's fresher nav and cargo",
+			canonical.ship.Nav.WaypointSymbol, canonical.ship.Cargo.Units)
+	}
+	if canonical.cooldown != duplicate.cooldown {
+		t.Fatal("the canonical ShipBot did not take the duplicate's longer cooldown")
+	}
+
+	// A stale duplicate's state is not merged.
+	stale := registered("GOGARIN-1", epoch)
+	stale.ship.Nav.WaypointSymbol = "X1-A-1"
+	r.Admit(stale, epoch.Add(5*time.Minute))
+	if r.Admit(canonical, epoch.Add(6*time.Minute)); canonical.ship.Nav.WaypointSymbol != "X1-A-2" {
+		t.Fatalf("canonical ShipBot at %s, want its own fresher nav kept", canonical.ship.Nav.WaypointSymbol)
+	}
+}
+
+func TestRegistryRetireLetsTheNextShipBotBecomeCanonical(t *testing.T) {
+	epoch := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := NewRegistry()
+
+	stuck := registered("GOGARIN-1", epoch)
+	r.Admit(stuck, epoch)
+	r.Retire("GOGARIN-1")
+	if _, ok := r.LastSeen("GOGARIN-1"); ok {
+		t.Fatal("a retired ship is still seen")
+	}
+
+	fresh := registered("GOGARIN-1", epoch)
+	if !r.Admit(fresh, epoch.Add(time.Hour)) {
+		t.Fatal("the ShipBot recovering a retired ship was dropped")
+	}
+	if r.Admit(stuck, epoch.Add(2*time.Hour)) {
+		t.Fatal("the retired ShipBot was admitted after its ship was recovered")
+	}
+}
+
+func TestCommandRunsOneMissionPerShip(t *testing.T) {
+	cfg := config.Default()
+
+	c := &contractClient{waypoints: strategyWaypoints}
+	bus := event.NewBus()
+	events := bus.Subscribe(64)
+
+	ab := NewAgentBot(c, &m.Agent{Symbol: "GOGARIN"}, store.NewMarketStore(), NewStrategySelector(cfg), nil, bus, cfg)
+	t.Cleanup(ab.scheduler.Stop)
+
+	// Two ShipBots for each ship report in, as when a ship is injected while its first ShipBot is still running.
+	sbCh := make(chan ShipBot, 8)
+	for _, symbol := range []string{"GOGARIN-1", "GOGARIN-2"} {
+		for i := 0; i < 2; i++ {
+			ship := m.Ship{Symbol: symbol}
+			ship.Registration.Role = "COMMAND"
+			ship.Nav = m.ShipNav{SystemSymbol: "X1-A", WaypointSymbol: "X1-A-1", Status: "DOCKED"}
+			ab.Command(*NewShipBot(c, &ship, ab.agent, ab.systems, ab.markets, bus, cfg), sbCh)
+		}
+	}
+
+	bus.Close()
+	started := make(map[string]int)
+	for e := range events {
+		if e.Type == event.MissionStarted {
+			started[e.Ship]++
+		}
+	}
+	if started["GOGARIN-1"] != 1 || started["GOGARIN-2"] != 1 {
+		t.Errorf("missions started per ship = %v, want one each", started)
+	}
+}
@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"sync"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/clock"
+)
+
+/*
+⏰ Scheduler
+*/
+
+// Scheduler runs tasks at a later time, each on its own timer, so nothing sleeps while it waits.
+type Scheduler struct {
+	mu      sync.Mutex
+	clock   clock.Clock
+	timers  map[clock.Timer]struct{}
+	stopped bool
+}
+
+// NewScheduler creates a Scheduler with no pending tasks, timing tasks by c.
+func NewScheduler(c clock.Clock) *Scheduler {
+	return &Scheduler{clock: c, timers: make(map[clock.Timer]struct{})}
+}
+
+// At runs task on its own goroutine at t, or straight away if t has passed.
+// Tasks scheduled after Stop never run.
+func (s *Scheduler) At(t time.Time, task func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return
+	}
+
+	var timer clock.Timer
+	timer = s.clock.AfterFunc(t.Sub(s.clock.Now()), func() {
+		s.mu.Lock()
+		delete(s.timers, timer)
+		s.mu.Unlock()
+
+		task()
+	})
+	s.timers[timer] = struct{}{}
+}
+
+// Pending returns the number of tasks waiting to run.
+func (s *Scheduler) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.timers)
+}
+
+// Stop cancels every pending task.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopped = true
+	for timer := range s.timers {
+		timer.Stop()
+	}
+	s.timers = make(map[clock.Timer]struct{})
+}
@@ -0,0 +1,261 @@
+package bot
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/clock"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+func TestReadyAt(t *testing.T) {
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	soon, later := now.Add(time.Minute), now.Add(time.Hour)
+
+	cooling := func(expiration time.Time) *m.Cooldown {
+		return &m.Cooldown{Expiration: m.OptionalTime{Time: expiration}}
+	}
+
+	tests := []struct {
+		name     string
+		status   string
+		arrival  time.Time
+		cooldown *m.Cooldown
+		want     time.Time
+	}{
+		{"neither in transit nor cooling down", "IN_ORBIT", now.Add(-time.Hour), nil, now},
+		{"in transit", "IN_TRANSIT", later, nil, later},
+		{"cooling down", "IN_ORBIT", now.Add(-time.Hour), cooling(soon), soon},
+		{"arriving after the cooldown", "IN_TRANSIT", later, cooling(soon), later},
+		{"cooling down after arrival", "IN_TRANSIT", soon, cooling(later), later},
+		{"arrived but not yet marked", "IN_TRANSIT", now.Add(-time.Minute), nil, now},
+		{"cooldown expired", "DOCKED", time.Time{}, cooling(now.Add(-time.Minute)), now},
+		{"orbiting with a stale arrival ahead", "IN_ORBIT", later, nil, now},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ship := m.Ship{Symbol: "GOGARIN-1"}
+			ship.Nav = m.ShipNav{Status: tt.status, Route: m.ShipNavRoute{Arrival: tt.arrival}}
+			sb := ShipBot{ship: &ship, cooldown: tt.cooldown}
+
+			if got := sb.readyAt(now); !got.Equal(tt.want) {
+				t.Errorf("readyAt = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedulerRunsTasksAtTheirTime(t *testing.T) {
+	fake := clock.NewFake(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := NewScheduler(fake)
+	t.Cleanup(s.Stop)
+
+	start := fake.Now()
+	ran := make(chan time.Time, 2)
+	s.At(start.Add(time.Hour), func() { ran <- fake.Now() })
+	s.At(start.Add(-time.Minute), func() { ran <- fake.Now() })
+
+	if at := <-ran; !at.Equal(start) {
+		t.Errorf("overdue task ran at %s, want straight away", at)
+	}
+	select {
+	case at := <-ran:
+		t.Fatalf("task due in an hour ran at %s", at)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	fake.Advance(time.Hour)
+	if at := <-ran; !at.Equal(start.Add(time.Hour)) {
+		t.Errorf("task ran at %s, want %s", at, start.Add(time.Hour))
+	}
+
+	if pending := s.Pending(); pending != 0 {
+		t.Errorf("%d tasks pending after all ran", pending)
+	}
+}
+
+func TestSchedulerStopCancelsPendingTasks(t *testing.T) {
+	s := NewScheduler(clock.Real)
+
+	var ran atomic.Int32
+	s.At(time.Now().Add(20*time.Millisecond), func() { ran.Add(1) })
+	if pending := s.Pending(); pending != 1 {
+		t.Fatalf("%d tasks pending, want 1", pending)
+	}
+
+	s.Stop()
+	s.At(time.Now(), func() { ran.Add(1) })
+
+	time.Sleep(50 * time.Millisecond)
+	if n := ran.Load(); n != 0 {
+		t.Errorf("%d tasks ran after Stop", n)
+	}
+	if pending := s.Pending(); pending != 0 {
+		t.Errorf("%d tasks pending after Stop", pending)
+	}
+}
+
+func TestCommandSchedulesShipsThatAreNotReady(t *testing.T) {
+	cfg := config.Default()
+	cfg.IdleInterval = time.Millisecond
+
+	c := &contractClient{waypoints: strategyWaypoints}
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+	events := bus.Subscribe(16)
+
+	ab := NewAgentBot(c, &m.Agent{Symbol: "GOGARIN"}, store.NewMarketStore(), NewStrategySelector(cfg), nil, bus, cfg)
+	t.Cleanup(ab.scheduler.Stop)
+
+	ship := m.Ship{Symbol: "GOGARIN-1"}
+	ship.Registration.Role = "COMMAND"
+	ship.Nav = m.ShipNav{SystemSymbol: "X1-A", WaypointSymbol: "X1-A-1", Status: "IN_ORBIT"}
+	sb := NewShipBot(c, &ship, ab.agent, ab.systems, ab.markets, bus, cfg)
+	readyAt := time.Now().Add(100 * time.Millisecond)
+	sb.cooldown = &m.Cooldown{Expiration: m.OptionalTime{Time: readyAt}}
+
+	ab.Command(*sb, make(chan ShipBot, 1))
+
+	if pending := ab.scheduler.Pending(); pending != 1 {
+		t.Fatalf("%d dispatches scheduled, want 1", pending)
+	}
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e.Type != event.MissionStarted {
+				continue
+			}
+			if now := time.Now(); now.Before(readyAt) {
+				t.Errorf("mission started %s before the ship was ready", readyAt.Sub(now))
+			}
+			return
+		case <-timeout:
+			t.Fatal("scheduled mission never started")
+		}
+	}
+}
+
+// transitClient is a contractClient whose server has the nav of a ship as nav, or fails to read it with err.
+type transitClient struct {
+	contractClient
+	nav      m.ShipNav
+	err      error
+	navCalls atomic.Int32
+}
+
+func (c *transitClient) GetShipNav(shipSymbol string) (*m.ShipNav, error) {
+	c.navCalls.Add(1)
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	nav := c.nav
+	return &nav, nil
+}
+
+// transitShip returns an AgentBot commanding c and a COMMAND ship it has in transit to X1-A-1, arriving at arrival.
+func transitShip(t *testing.T, cfg *config.Config, c *transitClient, arrival time.Time) (*AgentBot, *ShipBot) {
+	t.Helper()
+
+	c.waypoints = strategyWaypoints
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+
+	ab := NewAgentBot(c, &m.Agent{Symbol: "GOGARIN"}, store.NewMarketStore(), NewStrategySelector(cfg), nil, bus, cfg)
+	t.Cleanup(ab.scheduler.Stop)
+
+	ship := m.Ship{Symbol: "GOGARIN-1"}
+	ship.Registration.Role = "COMMAND"
+	ship.Nav = m.ShipNav{SystemSymbol: "X1-A", WaypointSymbol: "X1-A-1", Status: "IN_TRANSIT", Route: m.ShipNavRoute{Arrival: arrival}}
+	sb := NewShipBot(c, &ship, ab.agent, ab.systems, ab.markets, bus, cfg)
+	sb.arrival = nil
+
+	return ab, sb
+}
+
+func TestHoldInTransit(t *testing.T) {
+	now := time.Now()
+	orbiting := m.ShipNav{SystemSymbol: "X1-A", WaypointSymbol: "X1-A-1", Status: "IN_ORBIT"}
+	flying := m.ShipNav{SystemSymbol: "X1-A", WaypointSymbol: "X1-A-1", Status: "IN_TRANSIT", Route: m.ShipNavRoute{Arrival: now.Add(time.Second)}}
+
+	tests := []struct {
+		name     string
+		arrival  time.Time
+		server   m.ShipNav
+		err      error
+		held     bool
+		navCalls int32
+		status   string
+	}{
+		{"arriving later", now.Add(time.Hour), orbiting, nil, true, 0, "IN_TRANSIT"},
+		{"arrived with a stale status", now.Add(-time.Minute), orbiting, nil, false, 1, "IN_ORBIT"},
+		{"still in transit on the server", now.Add(-time.Minute), flying, nil, true, 1, "IN_TRANSIT"},
+		{"nav refresh failing", now.Add(-time.Minute), orbiting, errors.New("timeout"), true, 1, "IN_TRANSIT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+
+			c := &transitClient{nav: tt.server, err: tt.err}
+			ab, sb := transitShip(t, cfg, c, tt.arrival)
+			sbCh := make(chan ShipBot, 1)
+
+			if held := ab.holdInTransit(sb, sbCh); held != tt.held {
+				t.Errorf("held = %t, want %t", held, tt.held)
+			}
+			if n := c.navCalls.Load(); n != tt.navCalls {
+				t.Errorf("%d nav refreshes, want %d", n, tt.navCalls)
+			}
+			if sb.ship.Nav.Status != tt.status {
+				t.Errorf("status = %s, want %s", sb.ship.Nav.Status, tt.status)
+			}
+
+			// A held ship reports in again later; one let through is the caller's to dispatch.
+			want := 0
+			if tt.held {
+				want = 1
+			}
+			if pending := ab.scheduler.Pending(); pending != want {
+				t.Errorf("%d report-ins scheduled, want %d", pending, want)
+			}
+		})
+	}
+}
+
+func TestShipInTransitIsDispatchedOnArrival(t *testing.T) {
+	cfg := config.Default()
+	cfg.IdleInterval = time.Millisecond
+
+	arrival := time.Now().Add(100 * time.Millisecond)
+	c := &transitClient{nav: m.ShipNav{SystemSymbol: "X1-A", WaypointSymbol: "X1-A-1", Status: "IN_ORBIT"}}
+	ab, sb := transitShip(t, cfg, c, arrival)
+	events := sb.bus.Subscribe(16)
+
+	ab.DispatchNext(*sb, make(chan ShipBot, 1))
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e.Type != event.MissionStarted {
+				continue
+			}
+			if now := time.Now(); now.Before(arrival) {
+				t.Errorf("mission started %s before the ship arrived", arrival.Sub(now))
+			}
+			if n := c.navCalls.Load(); n != 1 {
+				t.Errorf("%d nav refreshes, want 1 on arrival", n)
+			}
+			return
+		case <-timeout:
+			t.Fatal("ship in transit never dispatched")
+		}
+	}
+}
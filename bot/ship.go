@@ -0,0 +1,1155 @@
+package bot
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/boterr"
+	"github.com/GeoffreyDick/gogarin/clock"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/lib"
+	"github.com/GeoffreyDick/gogarin/logging"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+	"github.com/charmbracelet/log"
+)
+
+/*
+🚀 SHIP_BOT
+*/
+
+// ShipBot represents a ShipBot instance.
+type ShipBot struct {
+	client         api.ClientAPI
+	logger         *log.Logger
+	bus            *event.Bus
+	tuning         config.RoleTuning
+	agent          *store.AgentState
+	contracts      *[]m.Contract
+	plan           DeliveryPlan
+	ship           *m.Ship
+	capabilities   m.ShipCapabilities
+	cooldown       *m.Cooldown
+	cooldownSynced bool
+	mission        string
+	missionID      string
+	// strategy is the strategy the ship's missions are attributed to, or manualStrategy under manual orders.
+	strategy       string
+	missionStarted time.Time
+	// watch times the current mission out if it runs past its deadline.
+	watch   *missionWatch
+	systems *store.SystemKnowledge
+	markets *store.MarketStore
+	// arrival runs, in order, whenever the ship arrives at a waypoint.
+	arrival []ArrivalHandler
+	// dock runs, in order, whenever the ship docks.
+	dock []ArrivalHandler
+	// marketRefresh is how old a recorded market must be before the ship records it again.
+	marketRefresh time.Duration
+	// tradeMaxPriceAge is the oldest market observation the ship plans trades from.
+	tradeMaxPriceAge time.Duration
+	// tradeMargin is the profit, after estimated fuel, a trade must exceed.
+	tradeMargin int64
+	// sellPriceDrop is how far below the recorded price a sale may realize before the rest of the cargo is
+	// re-scored; zero disables the check.
+	sellPriceDrop float64
+	// journal records the ship's multi-step missions.
+	journal *store.Journal
+	// resuming is set until the ship's first dispatch, which resumes its journalled mission.
+	resuming bool
+	// reserved are goods the ship needs and never sells.
+	reserved map[string]bool
+	// retained are goods no known market buys that the unsellable policy chose to keep.
+	retained map[string]bool
+	// quotas are the contract deliverables the fleet's ships have claimed.
+	quotas *Quotas
+	// shipyards records the prices of shipyards the ship watches. A nil store records nothing.
+	shipyards *store.ShipyardStore
+	// fuelCosts learns the fuel each route actually burns. A nil store estimates by the formula.
+	fuelCosts *store.FuelCosts
+	// visits remembers what ships did at the waypoints they visited. A nil log remembers nothing.
+	visits *store.VisitLog
+	// cooldowns records the lengths of the cooldowns the ship's extractions trigger. A nil store records nothing.
+	cooldowns *store.CooldownStats
+	// home is the agent's home base as of the ship's last dispatch, or nil if it is not loaded.
+	home *HomeBase
+	// route are the waypoints the current mission still plans to visit, in order, or nil if it has not said.
+	route []string
+	// fuelStations finds where the ship can buy fuel.
+	fuelStations *store.FuelStations
+	// fuelCeiling is the fuel price above which the ship buys only enough to reach a cheaper stop on its route.
+	fuelCeiling int64
+	// exploreBudget is the most waypoints an exploration mission visits; zero means no limit.
+	exploreBudget int
+	// miningTypes are the waypoint types the ship mines at; empty means m.MiningTargetTypes.
+	miningTypes []string
+	// maxPerTarget is the most ships sent to the same mining target; zero means no limit.
+	maxPerTarget int
+	// targets spread the fleet's ships across mining targets.
+	targets *Targets
+	// unsellable is the policy for goods no known market buys: auto, retain, or jettison.
+	unsellable string
+	// jettison guards the unsellable policy's jettisons.
+	jettison config.JettisonConfig
+	// tunedBy is the configuration the ship's tuning and settings were read from.
+	tunedBy *config.Config
+	// clock times the ship's waits.
+	clock clock.Clock
+	// pauses is the number of fleet pauses the ship's state has been re-synced after.
+	pauses int
+	// queued are follow-up missions dispatched ahead of the strategy's choice, oldest first.
+	queued []Mission
+	// tally is the plan the current mission previewed and what it has done so far, or nil if it previewed none.
+	tally *planTally
+	// failure is the error the last mission failed with, wrapped in its context, until the command loop has
+	// logged it.
+	failure error
+	// instance tells this ShipBot, and copies of it, from a duplicate ShipBot of the same ship.
+	instance uint64
+	// base is the ship's logger; logger is scoped to the current mission.
+	base *log.Logger
+}
+
+// NavigateToNearestWaypointOfType: Navigate to nearest waypoint of type.
+// Only types and coordinates matter, so the system's layout is enough and no waypoints are listed.
+func (sb *ShipBot) NavigateToNearestWaypointOfType(waypointType string, sbCh chan ShipBot) {
+	sb.logger.Info("Navigating to nearest waypoint of type...", "waypointType", waypointType)
+
+	// Get nearest waypoint of type.
+	waypoints, err := sb.systems.Layout(sb.ship.Nav.SystemSymbol)
+	if err != nil {
+		sb.logger.Error("🚀 Error getting system.", "error", err)
+		sb.Fail(boterr.Wrap(err, "system", sb.ship.Nav.SystemSymbol))
+		sb.Report(sbCh)
+		return
+	}
+
+	filteredWaypoints := lib.Filter(waypoints, func(waypoint m.Waypoint) bool {
+		return waypoint.IsType(waypointType)
+	})
+
+	current := lib.Filter(waypoints, func(waypoint m.Waypoint) bool {
+		return waypoint.Symbol == sb.ship.Nav.WaypointSymbol
+	})
+	if len(current) == 0 {
+		err := fmt.Errorf("waypoint %s not found in system %s", sb.ship.Nav.WaypointSymbol, sb.ship.Nav.SystemSymbol)
+		sb.logger.Error("🚀 Error getting current waypoint.", "error", err)
+		sb.Fail(err)
+		sb.Report(sbCh)
+		return
+	}
+	currentWaypoint := current[0]
+
+	nearestWaypoint, err := lib.NearestWaypoint(&currentWaypoint, &filteredWaypoints)
+	if err != nil {
+		sb.logger.Error("🚀 Error getting nearest waypoint.", "error", err)
+		sb.Fail(boterr.Wrap(err, "from", currentWaypoint.Symbol))
+		sb.Report(sbCh)
+		return
+	}
+
+	// Navigate to waypoint.
+	sb.logger.Infof("🚀 Navigating to nearest %s...", waypointType)
+
+	res, err := sb.navigate(nearestWaypoint.Symbol)
+	if err != nil {
+		sb.logger.Error("🚀 Error navigating to waypoint.", "error", err)
+		sb.Fail(boterr.Wrap(err, "waypoint", nearestWaypoint.Symbol))
+		sb.Report(sbCh)
+		return
+	}
+
+	sb.logger.Info("🚀 Navigation successful! Waiting until arrival...", "eta", res.Nav.Route.Arrival)
+
+	// Wait until arrival.
+	sb.WaitUntilArrival()
+
+	// Send sb to sbCh.
+	sb.Report(sbCh)
+}
+
+// NavigateToMiningTarget navigates to the asteroid field whose deposits best suit the priority goods,
+// preferring a slightly farther field with the right deposits over a nearer one without. Fields the most
+// ships per target are already heading to or mining at are passed over for the next best.
+func (sb *ShipBot) NavigateToMiningTarget(sbCh chan ShipBot) {
+	sb.logger.Info("Choosing mining target...", "priorities", sb.plan.Goods())
+
+	waypoints, err := sb.systems.Waypoints(sb.ship.Nav.SystemSymbol)
+	if err != nil {
+		sb.logger.Error("🚀 Error getting system.", "error", err)
+		sb.Fail(boterr.Wrap(err, "system", sb.ship.Nav.SystemSymbol))
+		sb.Report(sbCh)
+		return
+	}
+
+	current, err := sb.systems.Waypoint(sb.ship.Nav.WaypointSymbol)
+	if err != nil {
+		sb.logger.Error("🚀 Error getting current waypoint.", "error", err)
+		sb.Fail(boterr.Wrap(err, "waypoint", sb.ship.Nav.WaypointSymbol))
+		sb.Report(sbCh)
+		return
+	}
+
+	fields := lib.Filter(waypoints, func(waypoint m.Waypoint) bool {
+		return lib.IsMiningTarget(waypoint, sb.miningTypes...)
+	})
+
+	ranked := lib.RankMiningTargets(current, fields, sb.plan.Goods())
+	target := sb.targets.Claim(sb.ship.Symbol, ranked, sb.maxPerTarget)
+	if target == nil {
+		sb.logger.Error("🚀 Error choosing mining target.", "error", lib.ErrNoMiningTargets)
+		sb.Fail(boterr.Wrap(lib.ErrNoMiningTargets, "system", sb.ship.Nav.SystemSymbol, "priorities", strings.Join(sb.plan.Goods(), ",")))
+		sb.Report(sbCh)
+		return
+	}
+
+	reason := lib.MiningReason(target, sb.plan.Goods())
+	if target.Symbol != ranked[0].Symbol {
+		reason = fmt.Sprintf("%s already has %d ships; %s", ranked[0].Symbol, sb.maxPerTarget, reason)
+	}
+	sb.logger.Info("⛏ Mining target chosen.", "waypoint", target.Symbol, "reason", reason, "distance", math.Round(lib.WaypointDistance(current, target)))
+
+	if err := sb.NavigateShip(target.Symbol); err != nil {
+		sb.Fail(err)
+	}
+
+	sb.Report(sbCh)
+}
+
+// NavigateToNearestWaypointWithTrait: Navigate to nearest waypoint with trait.
+func (sb *ShipBot) NavigateToNearestWaypointWithTrait(trait string, sbCh chan ShipBot) {
+	sb.logger.Info("Navigating to nearest waypoint with trait...", "trait", trait)
+
+	// Get nearest waypoint with trait.
+	waypoints, err := sb.systems.Waypoints(sb.ship.Nav.SystemSymbol)
+	if err != nil {
+		sb.logger.Error("🚀 Error getting waypoints.", "error", err)
+		sb.Fail(boterr.Wrap(err, "system", sb.ship.Nav.SystemSymbol))
+		sb.Report(sbCh)
+		return
+	}
+
+	filteredWaypoints := lib.Filter(waypoints, func(waypoint m.Waypoint) bool {
+		return waypoint.HasTrait(trait)
+	})
+
+	current := lib.Filter(waypoints, func(waypoint m.Waypoint) bool {
+		return waypoint.Symbol == sb.ship.Nav.WaypointSymbol
+	})
+	if len(current) == 0 {
+		err := fmt.Errorf("waypoint %s not found in system %s", sb.ship.Nav.WaypointSymbol, sb.ship.Nav.SystemSymbol)
+		sb.logger.Error("🚀 Error getting current waypoint.", "error", err)
+		sb.Fail(err)
+		sb.Report(sbCh)
+		return
+	}
+	currentWaypoint := current[0]
+
+	nearestWaypoint, err := lib.NearestWaypoint(&currentWaypoint, &filteredWaypoints)
+	if err != nil {
+		sb.logger.Error("🚀 Error getting nearest waypoint.", "error", err)
+		sb.Fail(boterr.Wrap(err, "from", currentWaypoint.Symbol))
+		sb.Report(sbCh)
+		return
+	}
+
+	// Navigate to waypoint.
+	sb.logger.Infof("🚀 Navigating to nearest waypoint with %s...", trait)
+
+	res, err := sb.navigate(nearestWaypoint.Symbol)
+	if err != nil {
+		sb.logger.Error("🚀 Error navigating to waypoint.", "error", err)
+		sb.Fail(boterr.Wrap(err, "waypoint", nearestWaypoint.Symbol))
+		sb.Report(sbCh)
+		return
+	}
+
+	sb.logger.Info("🚀 Navigation successful! Waiting until arrival...", "eta", res.Nav.Route.Arrival)
+
+	// Wait until arrival.
+	sb.WaitUntilArrival()
+
+	// Send sb to sbCh.
+	sb.Report(sbCh)
+}
+
+// defaultReservedGoods are never sold, since ships need them to jump.
+var defaultReservedGoods = []string{antimatter}
+
+// NewShipBot creates a new instance of ShipBot.
+func NewShipBot(client api.ClientAPI, ship *m.Ship, agent *store.AgentState, systems *store.SystemKnowledge, markets *store.MarketStore, bus *event.Bus, cfg *config.Config) *ShipBot {
+	logger := logging.ForShip(fmt.Sprintf("🚀 %s", ship.Symbol), ship.Symbol)
+
+	sb := &ShipBot{
+		instance:     newInstance(),
+		client:       client,
+		systems:      systems,
+		bus:          bus,
+		logger:       logger,
+		base:         logger,
+		retained:     make(map[string]bool),
+		ship:         ship,
+		agent:        agent,
+		markets:      markets,
+		arrival:      defaultArrivalHandlers(),
+		dock:         defaultDockHandlers(),
+		fuelStations: store.NewFuelStations(systems, markets),
+		clock:        clock.Real,
+		capabilities: m.CapabilitiesOf(*ship),
+	}
+	sb.retune(cfg)
+
+	return sb
+}
+
+// retune reads the ship's tuning and settings from cfg. The AgentBot retunes a ShipBot before dispatching it
+// whenever the configuration was reloaded, so a mission never sees its settings change while it runs.
+func (sb *ShipBot) retune(cfg *config.Config) {
+	reserved := make(map[string]bool)
+	for _, good := range append(defaultReservedGoods, cfg.ReservedGoods...) {
+		reserved[good] = true
+	}
+
+	sb.tunedBy = cfg
+	sb.tuning = cfg.ForRole(sb.ship.Registration.Role)
+	sb.reserved = reserved
+	sb.marketRefresh = cfg.MarketRefresh
+	sb.tradeMaxPriceAge = cfg.TradeMaxPriceAge
+	sb.tradeMargin = cfg.TradeMargin
+	sb.sellPriceDrop = cfg.SellPriceDrop
+	sb.unsellable = cfg.UnsellablePolicy
+	sb.jettison = cfg.Jettison
+	sb.miningTypes = cfg.MiningTargetTypes
+	sb.maxPerTarget = cfg.MaxShipsPerTarget
+	sb.exploreBudget = cfg.ExploreBudget
+	sb.fuelCeiling = cfg.FuelPriceCeiling
+}
+
+// label names the ship in its log prefix, e.g. by its symbol and nickname.
+func (sb *ShipBot) label(name string) {
+	logger := logging.ForShip(fmt.Sprintf("🚀 %s", name), sb.ship.Symbol)
+	sb.logger, sb.base = logger, logger
+}
+
+// DockShip: Dock ship at waypoint.
+func (sb *ShipBot) DockShip(sbCh chan ShipBot) {
+	sb.logger.Info("Docking ship...")
+	nav, err := sb.client.DockShip(sb.ship.Symbol)
+	if err != nil {
+		sb.logger.Error("🚀 Error docking ship.", "error", err)
+		sb.Fail(boterr.Wrap(err, "waypoint", sb.ship.Nav.WaypointSymbol))
+		sb.Report(sbCh)
+		return
+	}
+
+	sb.ship.Nav = *nav
+	sb.Docked()
+
+	sb.Report(sbCh)
+}
+
+// OrbitShip puts the ship into orbit, unless it is already in orbit.
+func (sb *ShipBot) OrbitShip(sbCh chan ShipBot) {
+	if err := sb.EnsureOrbit(); err != nil {
+		sb.Fail(boterr.Wrap(err, "waypoint", sb.ship.Nav.WaypointSymbol))
+	}
+
+	sb.Report(sbCh)
+}
+
+// dockIfNeeded docks the ship unless it is already docked, running its dock handlers.
+func (sb *ShipBot) dockIfNeeded() error {
+	if sb.ship.Nav.Status == "DOCKED" {
+		return nil
+	}
+
+	nav, err := sb.client.DockShip(sb.ship.Symbol)
+	if err != nil {
+		return err
+	}
+
+	sb.ship.Nav = *nav
+	sb.Docked()
+
+	return nil
+}
+
+// ReadyAt returns when the ship can next act: the later of its arrival, if it is in transit, and the expiry
+// of its reactor cooldown, if one is active. A ship that is ready now returns the current time.
+func (sb *ShipBot) ReadyAt() time.Time {
+	return sb.readyAt(sb.clock.Now())
+}
+
+// readyAt returns when the ship can next act, as seen at now.
+func (sb *ShipBot) readyAt(now time.Time) time.Time {
+	ready := now
+
+	if arrival := sb.ship.Nav.Route.Arrival; sb.ship.Nav.Status == "IN_TRANSIT" && arrival.After(ready) {
+		ready = arrival
+	}
+
+	if sb.cooldown != nil {
+		if expiry := now.Add(sb.cooldown.Remaining(now)); expiry.After(ready) {
+			ready = expiry
+		}
+	}
+
+	return ready
+}
+
+// WaitUntilArrival: Wait until ship arrives at its destination.
+func (sb *ShipBot) WaitUntilArrival() {
+	if sb.ship.Nav.Route.Arrival.Before(sb.clock.Now()) {
+		sb.logger.Info("Not in transit. Skipping wait.")
+		return
+	}
+
+	sb.logger.Info("In transit. Waiting until arrival...", "arrival", sb.ship.Nav.Route.Arrival)
+	sb.watch.expect(sb.ship.Nav.Route.Arrival)
+	sb.clock.Sleep(sb.ship.Nav.Route.Arrival.Sub(sb.clock.Now()))
+
+	sb.ship.Nav.Status = "IN_ORBIT"
+	sb.Arrive()
+}
+
+// WaitUntilCooldown: Wait until ship's cooldown expires, fetching the cooldown first if it is not synced.
+func (sb *ShipBot) WaitUntilCooldown() {
+	sb.syncCooldown()
+
+	if sb.cooldown == nil || sb.cooldown.Ready(sb.clock.Now()) {
+		sb.logger.Info("⚛ Reactor ready. Skipping wait.")
+		return
+	}
+
+	remaining := sb.cooldown.Remaining(sb.clock.Now())
+	sb.logger.Info("⚛ Reactor cooldown active. Waiting...", "remaining", remaining)
+	sb.clock.Sleep(remaining)
+}
+
+// IsAtWaypointOfType checks if the ship is at a waypoint of a given type, returning a boolean.
+func (sb *ShipBot) IsAtWaypointOfType(waypointType string) bool {
+	waypoint, err := sb.systems.Waypoint(sb.ship.Nav.WaypointSymbol)
+	if err != nil {
+		sb.logger.Error("Error getting waypoint.", "error", err)
+		return false
+	}
+
+	return waypoint.IsType(waypointType)
+}
+
+// IsAtMiningTarget checks if the ship is at a waypoint it can mine, returning a boolean.
+func (sb *ShipBot) IsAtMiningTarget() bool {
+	waypoint, err := sb.systems.Waypoint(sb.ship.Nav.WaypointSymbol)
+	if err != nil {
+		sb.logger.Error("Error getting waypoint.", "error", err)
+		return false
+	}
+
+	return lib.IsMiningTarget(*waypoint, sb.miningTypes...)
+}
+
+// IsAtWaypointWithTrait checks if the ship is at a waypoint with a given trait, returning a boolean.
+func (sb *ShipBot) IsAtWaypointWithTrait(traitSymbol string) bool {
+	waypoint, err := sb.systems.Waypoint(sb.ship.Nav.WaypointSymbol)
+	if err != nil {
+		sb.logger.Error("Error getting waypoint.", "error", err)
+		return false
+	}
+
+	return waypoint.HasTrait(traitSymbol)
+}
+
+// Capabilities returns what the ship's modules and mounts let it do, as worked out when the ShipBot was
+// created or the ship last re-synced.
+func (sb *ShipBot) Capabilities() m.ShipCapabilities {
+	return sb.capabilities
+}
+
+// CanMine checks if the ship has a mining laser to extract resources with.
+func (sb *ShipBot) CanMine() bool {
+	return sb.capabilities.CanMine
+}
+
+// CanHaulCargo checks if the ship has a hold. Probes and satellites have none.
+func (sb *ShipBot) CanHaulCargo() bool {
+	return sb.capabilities.CargoCapacity > 0
+}
+
+// CanSurvey checks if the ship has a surveyor to survey mining targets with.
+func (sb *ShipBot) CanSurvey() bool {
+	return sb.capabilities.CanSurvey
+}
+
+// IsCargoAtThreshold checks if the ship's cargo has reached the role's configured sell threshold, returning a boolean.
+// A ship without a hold never reaches it.
+func (sb *ShipBot) IsCargoAtThreshold() bool {
+	if sb.ship.Cargo.Capacity == 0 {
+		return false
+	}
+
+	if sb.ship.Cargo.IsFull() {
+		return true
+	}
+
+	return float64(sb.ship.Cargo.Units) >= sb.tuning.CargoThreshold*float64(sb.ship.Cargo.Capacity)
+}
+
+// Idle waits for the role's idle interval before reporting back to the command loop. A satellite parked at a
+// shipyard records its prices first.
+func (sb *ShipBot) Idle(sbCh chan ShipBot) {
+	sb.watchShipyard()
+
+	sb.logger.Info("💤 Idling...", "interval", sb.tuning.IdleInterval)
+	sb.watch.expect(sb.clock.Now().Add(sb.tuning.IdleInterval))
+	sb.clock.Sleep(sb.tuning.IdleInterval)
+
+	sb.Report(sbCh)
+}
+
+// Fail publishes that the ShipBot's current mission failed with err, wrapped with the ship and mission, and
+// keeps it for the command loop to log.
+func (sb *ShipBot) Fail(err error) {
+	sb.logger.Warn("Mission ended.", "outcome", "failed", "duration", sb.clock.Now().Sub(sb.missionStarted).Round(time.Second), "error", err)
+
+	err = boterr.Wrap(err, "ship", sb.ship.Symbol, "mission", sb.mission, "missionId", sb.missionID)
+	sb.bus.Publish(event.Event{Type: event.MissionFailed, Ship: sb.ship.Symbol, Mission: sb.mission, MissionID: sb.missionID, Message: err.Error(), Data: err})
+	sb.failure = err
+	sb.endMission()
+}
+
+// Complete logs and publishes that the ShipBot's current mission completed.
+func (sb *ShipBot) Complete() {
+	sb.logger.Info("Mission ended.", "outcome", "completed", "duration", sb.clock.Now().Sub(sb.missionStarted).Round(time.Second))
+	sb.bus.Publish(event.Event{Type: event.MissionCompleted, Ship: sb.ship.Symbol, Mission: sb.mission, MissionID: sb.missionID})
+	sb.settlePlan()
+	sb.endMission()
+}
+
+// endMission clears the ShipBot's mission and its mission-scoped logger.
+func (sb *ShipBot) endMission() {
+	sb.mission = ""
+	sb.missionID = ""
+	sb.missionStarted = time.Time{}
+	sb.route = nil
+	sb.tally = nil
+	sb.logger = sb.base
+}
+
+// planRoute records the waypoints the current mission still plans to visit, so refuelling buys only the fuel
+// they need.
+func (sb *ShipBot) planRoute(waypoints ...string) {
+	sb.route = append([]string{}, waypoints...)
+}
+
+// reportTimeout is how long a ShipBot waits to report before warning that the command loop is stuck.
+const reportTimeout = 30 * time.Second
+
+// Report hands the ShipBot back to the command loop, warning if the loop does not accept it in time.
+func (sb *ShipBot) Report(sbCh chan<- ShipBot) {
+	select {
+	case sbCh <- *sb:
+		return
+	case <-sb.clock.After(reportTimeout):
+		sb.logger.Warn("Command loop has not accepted report. Still waiting...", "waited", reportTimeout)
+	}
+
+	sbCh <- *sb
+}
+
+// HasStatus checks if the ship has a given status, returning a boolean.
+func (sb *ShipBot) HasStatus(status string) bool {
+	return sb.ship.Nav.Status == status
+}
+
+// SellCargo sells every sellable good at the market the ship is docked at, in lots no larger than its trade
+// volume. A good the market does not trade is sent on a follow-up sell trip to another known buyer, or else
+// retained or jettisoned by the unsellable policy. A good whose sale price drops by more than the sell price
+// drop has the rest of it sent on a sell trip to a market that then scores higher, if there is one.
+func (sb *ShipBot) SellCargo(sbCh chan ShipBot) {
+	sold := make(map[string]int)
+	var lots int
+	var credits int64
+
+	refused := make(map[string]bool)
+	trips := make(map[string][]string)
+	// resynced are the goods a sale was refused for because the cargo changed since it was last seen.
+	resynced := make(map[string]bool)
+
+	// Each sale returns the updated cargo, so the next good is always chosen from current inventory.
+	for {
+		if sb.watch.TimedOut() {
+			sb.logger.Warn("Mission timed out. Stopping...")
+			return
+		}
+
+		good, ok := sb.nextSellable(refused)
+		if !ok {
+			break
+		}
+
+		if sb.plan.Needs(good.Symbol) {
+			sb.logger.Debug("💲 Selling priority cargo...", "type", good.Symbol, "units", good.Units)
+		} else {
+			sb.logger.Debug("💲 Selling non-priority cargo...", "type", good.Symbol, "units", good.Units)
+		}
+
+		lot := sb.tradeLot(sb.ship.Nav.WaypointSymbol, good.Symbol, good.Units)
+		recorded := sb.recordedSellPrice(good.Symbol)
+
+		res, err := sb.client.SellCargo(sb.ship.Symbol, good.Symbol, lot)
+		if api.IsNotTraded(err) {
+			refused[good.Symbol] = true
+			if buyer, ok := sb.resolveUnsellable(good); ok {
+				trips[buyer] = append(trips[buyer], good.Symbol)
+			}
+			continue
+		}
+		if api.IsCargoShort(err) && !resynced[good.Symbol] {
+			resynced[good.Symbol] = true
+			if syncErr := sb.syncCargo(); syncErr == nil {
+				sb.logger.Warn("📦 Cargo changed before sale. Selling what remains...", "symbol", good.Symbol, "units", lot, "held", sb.ship.Cargo.UnitsOf(good.Symbol))
+				continue
+			}
+		}
+		if err != nil {
+			sb.logger.Error("💲 Error selling cargo. Returning to agent...", "error", err)
+			sb.Fail(boterr.Wrap(err, "waypoint", sb.ship.Nav.WaypointSymbol, "good", good.Symbol, "units", lot))
+			break
+		}
+
+		sb.logger.Debug("💲 Cargo sold.", "type", res.Transaction.TradeSymbol, "units", res.Transaction.Units, "unitPrice", res.Transaction.PricePerUnit, "totalPrice", res.Transaction.TotalPrice)
+		sold[res.Transaction.TradeSymbol] += res.Transaction.Units
+		lots++
+		credits += res.Transaction.TotalPrice
+
+		sb.ship.Cargo = res.Cargo
+		sb.agent.Update(res.Agent)
+
+		sb.bus.Publish(event.Event{Type: event.CargoSold, Ship: sb.ship.Symbol, MissionID: sb.missionID, Message: fmt.Sprintf("%d %s for %d", res.Transaction.Units, res.Transaction.TradeSymbol, res.Transaction.TotalPrice), Data: res.Transaction})
+		sb.bus.Publish(event.Event{Type: event.AgentUpdated, Data: res.Agent})
+
+		// A sale that crashed the price sends the rest of the good to a market that now scores higher.
+		if held := sb.ship.Cargo.UnitsOf(good.Symbol); held > 0 && sb.priceDropped(recorded, res.Transaction.PricePerUnit) {
+			if buyer, ok := sb.rerouteSale(good.Symbol); ok {
+				supply, _ := sb.markets.Supply(sb.ship.Nav.WaypointSymbol, good.Symbol)
+				sb.logger.Warn("💲 Sale price dropped. Queued sell trip for the rest.", "symbol", good.Symbol, "recorded", recorded, "realized", res.Transaction.PricePerUnit, "supply", supply, "units", held, "buyer", buyer)
+				refused[good.Symbol] = true
+				trips[buyer] = append(trips[buyer], good.Symbol)
+			}
+		}
+	}
+
+	if lots > 0 {
+		sb.logger.Info("💲 Cargo sold.", "lots", lots, "sold", soldSummary(sold), "totalPrice", credits, "credits", sb.agent.Credits())
+	}
+	sb.logger.Info("📦 Cargo status updated.", "cargoStatus", fmt.Sprintf("%d/%d", sb.ship.Cargo.Units, sb.ship.Cargo.Capacity))
+
+	buyers := make([]string, 0, len(trips))
+	for buyer := range trips {
+		buyers = append(buyers, buyer)
+	}
+	sort.Strings(buyers)
+
+	for _, buyer := range buyers {
+		sb.queued = append(sb.queued, sellTripMission(buyer, trips[buyer]))
+	}
+
+	sb.Report(sbCh)
+}
+
+// nextSellable returns the first cargo item that has units and is neither reserved, retained, nor skipped.
+func (sb *ShipBot) nextSellable(skip map[string]bool) (m.ShipCargoItem, bool) {
+	for _, good := range sb.ship.Cargo.Inventory {
+		if good.Units > 0 && !sb.reserved[good.Symbol] && !sb.retained[good.Symbol] && !skip[good.Symbol] {
+			return good, true
+		}
+	}
+
+	return m.ShipCargoItem{}, false
+}
+
+// syncCargo refreshes the ship's cargo from the API, after a sale was refused for cargo the ship no longer
+// holds, or holds fewer units of, because another mission moved it since the cargo was last seen.
+func (sb *ShipBot) syncCargo() error {
+	ship, err := sb.client.GetShip(sb.ship.Symbol)
+	if err != nil {
+		return err
+	}
+	sb.ship.Cargo = ship.Cargo
+
+	return nil
+}
+
+// resolveUnsellable decides what to do with a good the local market refused, logging the decision and its reason.
+// It returns the waypoint of another known buyer in the system, if there is one, for a follow-up sell trip.
+// Otherwise the good is retained or jettisoned by the unsellable policy; auto retains contract and priority goods.
+// Jettisons are guarded by jettisonDecision, so goods valued from stale data or above the threshold are retained.
+func (sb *ShipBot) resolveUnsellable(good m.ShipCargoItem) (string, bool) {
+	if buyer, ok := sb.buyerFor(good.Symbol); ok {
+		sb.logger.Info("💲 Good not traded here. Queued sell trip.", "symbol", good.Symbol, "units", good.Units, "buyer", buyer, "reason", "known buyer in system")
+		return buyer, true
+	}
+
+	reason := "policy retain"
+	switch sb.unsellable {
+	case "jettison":
+		var jettisoned bool
+		if jettisoned, reason = sb.jettisonUnsellable(good, false, "policy jettison; no known buyer"); jettisoned {
+			return "", false
+		}
+	case "auto":
+		var jettisoned bool
+		if jettisoned, reason = sb.jettisonUnsellable(good, sb.plan.Needs(good.Symbol), "no known buyer"); jettisoned {
+			return "", false
+		}
+	}
+
+	sb.retained[good.Symbol] = true
+	sb.logger.Info("📦 Cargo retained.", "symbol", good.Symbol, "units", good.Units, "reason", reason)
+
+	return "", false
+}
+
+// buyerFor returns another known market in the ship's system that buys a good, preferring the highest
+// recorded sell price, then any market that imports it.
+func (sb *ShipBot) buyerFor(symbol string) (string, bool) {
+	var best, importer string
+	var bestPrice int64
+
+	for _, observation := range sb.markets.All() {
+		market := observation.Market
+		if market.Symbol == sb.ship.Nav.WaypointSymbol {
+			continue
+		}
+		if systemSymbol, err := lib.SystemSymbolOf(market.Symbol); err != nil || systemSymbol != sb.ship.Nav.SystemSymbol {
+			continue
+		}
+
+		if price, ok := market.SellPriceOf(symbol); ok && price > bestPrice {
+			best, bestPrice = market.Symbol, price
+		} else if importer == "" && market.ImportsGood(symbol) {
+			importer = market.Symbol
+		}
+	}
+
+	if best == "" {
+		best = importer
+	}
+
+	return best, best != ""
+}
+
+// sellTripMission takes the ship to a market that buys goods the last market refused, and sells there.
+func sellTripMission(waypointSymbol string, goods []string) Mission {
+	return Mission{
+		Name: fmt.Sprintf("Sell %s at %s", strings.Join(goods, ", "), waypointSymbol),
+		Run: func(sb *ShipBot, sbCh chan ShipBot) {
+			if err := sb.travelAndDock(waypointSymbol); err != nil {
+				sb.logger.Error("💲 Error reaching buyer.", "waypoint", waypointSymbol, "error", err)
+				sb.Fail(boterr.Wrap(err, "waypoint", waypointSymbol))
+				sb.Report(sbCh)
+				return
+			}
+
+			sb.SellCargo(sbCh)
+		},
+	}
+}
+
+// soldSummary formats the units sold of each good, e.g. "ICE_WATER=12 IRON_ORE=30".
+func soldSummary(sold map[string]int) string {
+	goods := make([]string, 0, len(sold))
+	for good, units := range sold {
+		goods = append(goods, fmt.Sprintf("%s=%d", good, units))
+	}
+	sort.Strings(goods)
+
+	return strings.Join(goods, " ")
+}
+
+func (sb *ShipBot) ExtractResources(sbCh chan ShipBot) {
+	for attempt := 1; ; attempt++ {
+		if sb.watch.TimedOut() {
+			sb.logger.Warn("Mission timed out. Stopping...")
+			return
+		}
+
+		if !sb.IsCargoAtThreshold() {
+			sb.WaitUntilCooldown()
+
+			res, err := sb.client.ExtractResources(sb.ship.Symbol)
+			if err != nil {
+				sb.logger.Error(err)
+				sb.Fail(boterr.Wrap(err, "waypoint", sb.ship.Nav.WaypointSymbol, "attempt", attempt))
+				sb.logger.Info("Mission failed. Reporting to agent...")
+				break
+			}
+			sb.logger.Info("⛏ Resources extracted.", "type", res.Extraction.Yield.Symbol, "units", res.Extraction.Yield.Units)
+			sb.bus.Publish(event.Event{Type: event.ResourcesExtracted, Ship: sb.ship.Symbol, MissionID: sb.missionID, Message: fmt.Sprintf("%d %s", res.Extraction.Yield.Units, res.Extraction.Yield.Symbol), Data: res.Extraction})
+
+			// Update cargo
+			sb.ship.Cargo = res.Cargo
+			sb.logger.Info("📦 Cargo status updated.", "cargoStatus", fmt.Sprintf("%d/%d", res.Cargo.Units, res.Cargo.Capacity))
+
+			// Update cooldown
+			sb.setCooldown(&res.Cooldown)
+			sb.cooldowns.Record(sb.ship.Symbol, res.Cooldown.TotalSeconds)
+		} else {
+			sb.logger.Info("📦 Cargo full. Reporting to agent...")
+			break
+		}
+	}
+
+	sb.Report(sbCh)
+}
+
+// syncCooldown fetches the ship's reactor cooldown the first time a mission needs it. Ships are woken without
+// it, so until then the cooldown is unknown and the ship counts as ready. Actions that trigger a cooldown
+// return it, so once synced it is never fetched again.
+func (sb *ShipBot) syncCooldown() {
+	if sb.cooldownSynced {
+		return
+	}
+
+	sb.logger.Info("⚛ Checking reactor...")
+	cooldown, err := sb.GetShipCooldown()
+	if err != nil {
+		sb.logger.Error("⚛ Error getting ship cooldown.", "error", err)
+		return
+	}
+	sb.setCooldown(cooldown)
+}
+
+// setCooldown caches the ship's reactor cooldown, as fetched or returned by an action. A nil cooldown means
+// the reactor is ready.
+func (sb *ShipBot) setCooldown(cooldown *m.Cooldown) {
+	sb.cooldown = cooldown
+	sb.cooldownSynced = true
+}
+
+func (sb *ShipBot) GetShipCooldown() (*m.Cooldown, error) {
+	cooldown, err := sb.client.GetShipCooldown(sb.ship.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	return cooldown, nil
+}
+
+// InitiateRequisitionProtocol sends a command ship to compare the shipyards in its system, visiting those
+// that only show prices to a ship that is present, and buys a ship at the cheapest offer if the agent can
+// afford it. The new ship is got underway on its role's initial missions straight away.
+func (sb *ShipBot) InitiateRequisitionProtocol(ab *AgentBot) {
+	sb.logger.Info("Initiating requisition protocol...")
+
+	// Survey shipyards in current system
+	sb.logger.Info("🔎 Surveying shipyards in current system...", "system", sb.ship.Nav.SystemSymbol, "shipType", requisitionShipType)
+	offers, err := ab.SurveyShipyards(sb.ship.Nav.SystemSymbol, requisitionShipType)
+	if err != nil {
+		sb.logger.Error("🔎 Error surveying shipyards in current system.", "error", err)
+		return
+	}
+
+	if len(offers) == 0 {
+		sb.logger.Warn("🔎 No shipyard in the system sells the ship type.", "shipType", requisitionShipType)
+		return
+	}
+
+	visited := false
+	for _, offer := range offers {
+		if !offer.NeedsVisit {
+			continue
+		}
+
+		// Travel to shipyard
+		sb.logger.Info("🚀 Traveling to shipyard for prices...", "waypoint", offer.Waypoint)
+		if err := sb.NavigateShip(offer.Waypoint); err != nil {
+			sb.logger.Warn("🔎 Could not reach shipyard. Leaving it unpriced.", "waypoint", offer.Waypoint, "error", err)
+			continue
+		}
+		visited = true
+
+		if _, err := ab.client.GetShipyardAt(offer.Waypoint); err != nil {
+			sb.logger.Error("🔎 Error getting shipyard.", "waypoint", offer.Waypoint, "error", err)
+		}
+	}
+
+	if visited {
+		if offers, err = ab.SurveyShipyards(sb.ship.Nav.SystemSymbol, requisitionShipType); err != nil {
+			sb.logger.Error("🔎 Error surveying shipyards in current system.", "error", err)
+			return
+		}
+	}
+
+	best := offers[0]
+	if best.NeedsVisit {
+		sb.logger.Warn("🔎 No shipyard prices available.", "shipType", requisitionShipType)
+		return
+	}
+
+	sb.logger.Info("🔎 Cheapest shipyard found.", best.LogValues()...)
+
+	release, err := ab.agent.ReserveCredits(best.TotalCost())
+	if err != nil {
+		sb.logger.Warn("💰 Cannot afford a ship yet.", "totalCost", best.TotalCost(), "available", ab.agent.Available())
+		return
+	}
+	defer release()
+
+	_, travel := sb.legEstimate(best.Waypoint)
+	sb.preview(m.Plan{
+		Kind:     m.PlanRequisition,
+		Good:     requisitionShipType,
+		Units:    1,
+		Source:   best.Waypoint,
+		Cost:     best.Price,
+		Fuel:     best.Fuel,
+		FuelCost: int64(best.Fuel) * estimatedFuelPrice,
+		Travel:   travel,
+	})
+
+	// Shipyards only sell to an agent with a ship present.
+	if err := sb.NavigateShip(best.Waypoint); err != nil {
+		sb.logger.Warn("🔎 Could not reach shipyard. Purchase abandoned.", "waypoint", best.Waypoint, "error", err)
+		return
+	}
+
+	purchase, err := ab.buyShip(requisitionShipType, best.Waypoint)
+	if err != nil {
+		sb.logger.Error("🚀 Error purchasing ship.", "waypoint", best.Waypoint, "shipType", requisitionShipType, "error", err)
+		sb.tally = nil
+		return
+	}
+
+	sb.tally.actual.Units++
+	sb.tally.actual.Cost += purchase.Transaction.Price
+	sb.settlePlan()
+}
+
+// NavigateShip sends a ship to a waypoint and waits until it arrives. Errors are logged, and returned with
+// the waypoint so missions that need the ship there can say why it is not.
+func (sb *ShipBot) NavigateShip(waypointSymbol string) error {
+	// Check if ship is already at waypoint
+	if sb.ship.Nav.WaypointSymbol == waypointSymbol && sb.ship.Nav.Route.Arrival.Before(sb.clock.Now()) {
+		sb.logger.Info("🚀 Already at waypoint. Navigation skipped.", "waypoint", waypointSymbol)
+		return nil
+	}
+
+	// Check if ship is already traveling to waypoint
+	if sb.ship.Nav.Route.Arrival.After(sb.clock.Now()) {
+		if sb.ship.Nav.Route.Destination.Symbol == waypointSymbol {
+			sb.logger.Info("🚀 Already traveling to waypoint. Navigation skipped.", "waypoint", waypointSymbol)
+			sb.WaitUntilArrival()
+			return nil
+		}
+
+		sb.WaitUntilArrival()
+	}
+
+	if err := sb.ensureRefuge(waypointSymbol); err != nil {
+		sb.logger.Error("⛽ Refusing trip.", "waypoint", waypointSymbol, "error", err)
+		return boterr.Wrap(err, "waypoint", waypointSymbol)
+	}
+
+	if _, err := sb.navigate(waypointSymbol); err != nil {
+		sb.logger.Error("🚀 Error traveling to waypoint.", "waypoint", waypointSymbol, "error", err)
+		return boterr.Wrap(err, "waypoint", waypointSymbol)
+	}
+
+	sb.WaitUntilArrival()
+
+	return nil
+}
+
+// navigate sends the ship to a waypoint without waiting for it to arrive, orbiting it first if it is docked.
+// A navigation refused because the ship is not in orbit, when its local status was stale, is retried once
+// after orbiting. If orbiting fails, its error is returned.
+func (sb *ShipBot) navigate(waypointSymbol string) (*api.NavigateShipResponse, error) {
+	if err := sb.EnsureOrbit(); err != nil {
+		return nil, err
+	}
+
+	res, err := sb.client.NavigateShip(sb.ship.Symbol, waypointSymbol)
+	if api.IsNotInOrbit(err) {
+		sb.logger.Warn("🚀 Ship was docked. Orbiting and retrying...", "waypoint", waypointSymbol)
+		sb.ship.Nav.Status = "DOCKED"
+		if err := sb.EnsureOrbit(); err != nil {
+			return nil, err
+		}
+		res, err = sb.client.NavigateShip(sb.ship.Symbol, waypointSymbol)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sb.ship.Fuel = res.Fuel
+	sb.ship.Nav = res.Nav
+	sb.targets.Leave(sb.ship.Symbol, waypointSymbol)
+	sb.tallyFuel(res.Fuel.Consumed.Amount, 0)
+	if sb.ship.Fuel.Capacity > 0 {
+		if err := sb.fuelCosts.Record(res.Nav.Route.Departure.Symbol, res.Nav.Route.Destination.Symbol, res.Nav.FlightMode, res.Fuel.Consumed.Amount); err != nil {
+			sb.logger.Warn("⛽ Error saving fuel costs.", "error", err)
+		}
+	}
+	sb.bus.Publish(event.Event{Type: event.ShipNavigated, Ship: sb.ship.Symbol, Mission: sb.mission, MissionID: sb.missionID, Message: waypointSymbol, Data: res.Nav})
+
+	return res, nil
+}
+
+// EnsureOrbit puts the ship in orbit if it is docked, updating its nav.
+func (sb *ShipBot) EnsureOrbit() error {
+	if sb.ship.Nav.Status != "DOCKED" {
+		return nil
+	}
+
+	nav, err := sb.client.OrbitShip(sb.ship.Symbol)
+	if err != nil {
+		sb.logger.Error("🚀 Error orbiting ship.", "error", err)
+		return err
+	}
+	sb.ship.Nav = *nav
+
+	return nil
+}
+
+// ensureRefuge checks that after flying to a waypoint, the ship would have the fuel to reach a fuel station in
+// its system, or to fly back, in its flight mode. If not, it fills the tank first where fuel is sold, and
+// returns an error if even a full tank would strand it. Ships without a fuel tank, and trips to or from
+// waypoints that are not known, are not checked.
+func (sb *ShipBot) ensureRefuge(waypointSymbol string) error {
+	if sb.ship.Fuel.Capacity == 0 {
+		return nil
+	}
+
+	origin, err := sb.systems.Waypoint(sb.ship.Nav.WaypointSymbol)
+	if err != nil {
+		return nil
+	}
+	destination, err := sb.systems.Waypoint(waypointSymbol)
+	if err != nil {
+		return nil
+	}
+
+	stations, err := sb.fuelStations.In(destination.SystemSymbol)
+	if err != nil {
+		return nil
+	}
+	refuges := append(stations, *origin)
+
+	mode := sb.ship.Nav.FlightMode
+	cost := sb.fuelCosts.Estimate(origin, destination, mode)
+	if lib.CanReachRefuge(destination, sb.ship.Fuel.Current-cost, mode, refuges) {
+		return nil
+	}
+
+	if !lib.CanReachRefuge(destination, sb.ship.Fuel.Capacity-cost, mode, refuges) {
+		return fmt.Errorf("%s would strand the ship: even a full tank leaves too little fuel to reach a fuel station or return", waypointSymbol)
+	}
+
+	if !sb.fuelStations.SellsFuel(*origin) {
+		return fmt.Errorf("%s would strand the ship with %d fuel left, and no fuel is sold here to top up", waypointSymbol, sb.ship.Fuel.Current-cost)
+	}
+
+	var price int64
+	if observation, ok := sb.markets.Get(origin.Symbol); ok {
+		price, _ = observation.Market.PurchasePriceOf("FUEL")
+	}
+
+	sb.logger.Info("⛽ Topping up before the trip, so the ship can reach fuel from the destination.", "waypoint", waypointSymbol, "fuel", sb.ship.Fuel.Current, "cost", cost)
+	if err := sb.buyFuel(0, price); err != nil {
+		return fmt.Errorf("topping up for %s: %w", waypointSymbol, err)
+	}
+
+	return nil
+}
+
+// notReached is the error of a ship that did not reach a waypoint, wrapping why if navigation failed.
+func notReached(waypointSymbol string, err error) error {
+	if err != nil {
+		return fmt.Errorf("did not reach %s: %w", waypointSymbol, err)
+	}
+
+	return fmt.Errorf("did not reach %s", waypointSymbol)
+}
+
+// NavigateTo navigates to a waypoint, reporting back once the ship arrives.
+func (sb *ShipBot) NavigateTo(waypointSymbol string, sbCh chan ShipBot) {
+	err := sb.NavigateShip(waypointSymbol)
+
+	if sb.ship.Nav.WaypointSymbol != waypointSymbol {
+		sb.Fail(notReached(waypointSymbol, err))
+	}
+
+	sb.Report(sbCh)
+}
+
+// DeliverContract takes contract goods to their destination and delivers them, fulfilling the contract
+// once every deliverable is complete.
+func (sb *ShipBot) DeliverContract(delivery Delivery, sbCh chan ShipBot) {
+	sb.journalBegin(journalDeliver, stepDelivering, delivery.ContractID, delivery)
+	sb.preview(sb.deliveryPlan([]Delivery{delivery}))
+
+	sb.planRoute(delivery.Destination)
+	contract, err := sb.deliver(delivery)
+	if err != nil {
+		sb.quotas.Release(sb.ship.Symbol)
+		sb.Abandon(err)
+		sb.Report(sbCh)
+		return
+	}
+
+	if !contract.IsDeliverComplete() {
+		sb.journalEnd()
+		sb.Report(sbCh)
+		return
+	}
+
+	sb.journalStep(stepFulfilling, nil)
+	sb.FulfillContract(delivery.ContractID, sbCh)
+}
+
+// FulfillContract fulfills a contract whose deliveries are complete.
+func (sb *ShipBot) FulfillContract(contractID string, sbCh chan ShipBot) {
+	if err := sb.fulfill(contractID); err != nil {
+		sb.Abandon(err)
+		sb.Report(sbCh)
+		return
+	}
+
+	sb.journalEnd()
+	sb.Report(sbCh)
+}
+
+// fulfill fulfills a contract whose deliveries are complete, crediting the agent.
+func (sb *ShipBot) fulfill(contractID string) error {
+	fulfilled, err := sb.client.FulfillContract(contractID)
+	if err != nil {
+		sb.logger.Error("📜 Error fulfilling contract.", "contract", contractID, "error", err)
+		return boterr.Wrap(err, "contract", contractID)
+	}
+
+	sb.agent.Update(fulfilled.Agent)
+	sb.logger.Info("📜 Contract fulfilled.", fulfilled.Contract.LogValues()...)
+	sb.bus.Publish(event.Event{Type: event.ContractFulfilled, Ship: sb.ship.Symbol, Mission: sb.mission, MissionID: sb.missionID, Message: contractID, Data: fulfilled.Contract})
+	sb.bus.Publish(event.Event{Type: event.AgentUpdated, Data: fulfilled.Agent})
+
+	return nil
+}
+
+func (sb *ShipBot) FindWaypointsByTrait(systemSymbol, trait string) (*[]m.Waypoint, error) {
+	waypoints, err := sb.systems.Waypoints(systemSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	waypointsWithTrait := lib.Filter(waypoints, func(w m.Waypoint) bool {
+		return w.HasTrait(trait)
+	})
+
+	return &waypointsWithTrait, nil
+}
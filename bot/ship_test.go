@@ -0,0 +1,992 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/boterr"
+	"github.com/GeoffreyDick/gogarin/clock"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/logging"
+	"github.com/GeoffreyDick/gogarin/mockserver"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+// startMock boots a mock server from the default fixture with extra copies of its excavator,
+// returning the server and a client pointed at it.
+func startMock(t *testing.T, excavators int) (*mockserver.Server, *api.Client) {
+	t.Helper()
+
+	f, err := mockserver.DefaultFixture()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	excavator := f.Ships[1]
+	for i := 0; i < excavators; i++ {
+		ship := excavator
+		ship.Symbol = fmt.Sprintf("%s-%d", excavator.Symbol, i+1)
+		f.Ships = append(f.Ships, ship)
+	}
+
+	s := mockserver.New(f, mockserver.WithTimeScale(1000))
+	t.Cleanup(s.Close)
+
+	return s, api.NewClient("token", api.WithBaseURL(s.URL), api.WithRateLimit(1000))
+}
+
+// shipBot creates a ShipBot for a fixture ship outside any fleet, against a mock server seeded from f. Both
+// run on a fake clock that moves forward whenever the ShipBot waits.
+func shipBot(t *testing.T, f *mockserver.Fixture, shipSymbol string) (*ShipBot, *mockserver.Server) {
+	t.Helper()
+
+	fake := clock.NewFake(epoch).AutoAdvance()
+	server := mockserver.New(f, mockserver.WithClock(fake))
+	t.Cleanup(server.Close)
+
+	client := api.NewClient("token", api.WithBaseURL(server.URL), api.WithRateLimit(1000), api.WithClock(fake))
+
+	ship, err := client.GetShip(shipSymbol)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	agent := store.NewAgentState(f.Agent, 0)
+	sb := NewShipBot(client, ship, agent, store.NewSystemKnowledge(client), store.NewMarketStore(), event.NewBus(), config.Default())
+	sb.clock = fake
+
+	return sb, server
+}
+
+func TestShipsShareOneWaypointListing(t *testing.T) {
+	cfg := config.Default()
+	s, c := startMock(t, 5)
+
+	agent, err := c.GetMyAgent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ships, err := c.GetMyShips()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bus := event.NewBus()
+	ab := NewAgentBot(c, agent, store.NewMarketStore(), NewStrategySelector(cfg), nil, bus, cfg)
+	sbCh := make(chan ShipBot, len(*ships))
+
+	n := 0
+	for i := range *ships {
+		ship := (*ships)[i]
+		if ship.Nav.Status != "IN_ORBIT" {
+			continue
+		}
+
+		n++
+		go NewShipBot(c, &ship, ab.agent, ab.systems, ab.markets, bus, cfg).NavigateToNearestWaypointOfType("ASTEROID_FIELD", sbCh)
+	}
+
+	for i := 0; i < n; i++ {
+		sb := <-sbCh
+		if sb.ship.Nav.WaypointSymbol != "X1-MK1-B2" {
+			t.Errorf("%s navigated to %s, want X1-MK1-B2", sb.ship.Symbol, sb.ship.Nav.WaypointSymbol)
+		}
+	}
+
+	if n != 6 {
+		t.Fatalf("navigated %d ships, want 6", n)
+	}
+	if got := s.Requests("GET", "/systems/X1-MK1/waypoints"); got != 1 {
+		t.Errorf("ListWaypoints made %d requests for %d ships, want 1", got, n)
+	}
+}
+
+// captureLogs sends the bots' logs to the returned buffer as JSON lines for the duration of the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var out bytes.Buffer
+	f, err := logging.NewFactory(&out, logging.Options{Level: "info", Format: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := logging.Default()
+	t.Cleanup(func() { logging.SetDefault(previous) })
+	logging.SetDefault(f)
+
+	return &out
+}
+
+// logLines decodes JSON log lines, keeping those with prefix.
+func logLines(t *testing.T, out *bytes.Buffer, prefix string) []map[string]interface{} {
+	t.Helper()
+
+	var lines []map[string]interface{}
+	for _, raw := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if raw == "" {
+			continue
+		}
+
+		var line map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &line); err != nil {
+			t.Fatalf("not a JSON line: %s\n%s", err, raw)
+		}
+		if line["prefix"] == prefix {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+func TestMissionLinesCarryTheCorrelationID(t *testing.T) {
+	cfg := config.Default()
+	out := captureLogs(t)
+	_, c := startMock(t, 0)
+
+	agent, err := c.GetMyAgent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ship, err := c.GetShip("MOCK-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+	events := bus.Subscribe(16)
+	ab := NewAgentBot(c, agent, store.NewMarketStore(), NewStrategySelector(cfg), nil, bus, cfg)
+	sb := NewShipBot(c, ship, ab.agent, ab.systems, ab.markets, bus, cfg)
+
+	ab.Dispatch(sb, "Navigate to nearest asteroid field")
+	id := sb.missionID
+	if len(id) != 6 {
+		t.Fatalf("mission ID %q, want 6 hex digits", id)
+	}
+
+	sb.NavigateToNearestWaypointOfType("ASTEROID_FIELD", make(chan ShipBot, 1))
+	sb.Complete()
+	sb.logger.Info("Between missions.")
+
+	lines := logLines(t, out, "🚀 MOCK-2:")
+	if len(lines) < 4 {
+		t.Fatalf("logged %d ship lines, want the mission's and one after it", len(lines))
+	}
+	mission, after := lines[:len(lines)-1], lines[len(lines)-1]
+	for _, line := range mission {
+		if line["missionId"] != id || line["ship"] != "MOCK-2" || line["mission"] != "Navigate to nearest asteroid field" {
+			t.Errorf("mission line without its correlation fields: %v", line)
+		}
+	}
+	if first := mission[0]; first["msg"] != "Mission started." {
+		t.Errorf("first mission line = %v, want the start", first)
+	}
+	if last := mission[len(mission)-1]; last["msg"] != "Mission ended." || last["outcome"] != "completed" || last["duration"] == nil {
+		t.Errorf("last mission line = %v, want the end with outcome and duration", last)
+	}
+	if _, ok := after["missionId"]; ok {
+		t.Errorf("line after the mission still carries its ID: %v", after)
+	}
+
+	bus.Close()
+	for e := range events {
+		if e.Ship == "MOCK-2" && e.MissionID != id {
+			t.Errorf("%s event has mission ID %q, want %q", e.Type, e.MissionID, id)
+		}
+	}
+}
+
+// sellClient is a ClientAPI market that buys every good at 10 credits a unit from its copy of a ship's cargo.
+type sellClient struct {
+	api.ClientAPI
+	cargo   m.ShipCargo
+	credits m.Credits
+	sales   []string
+}
+
+func (c *sellClient) SellCargo(shipSymbol string, cargoSymbol string, units int) (*api.SellCargoResponse, error) {
+	c.sales = append(c.sales, fmt.Sprintf("%d %s", units, cargoSymbol))
+	if err := c.cargo.Remove(cargoSymbol, units); err != nil {
+		return nil, &api.APIError{StatusCode: http.StatusBadRequest, Code: 4219, Message: err.Error()}
+	}
+	c.credits += m.Credits(10 * units)
+
+	res := &api.SellCargoResponse{Agent: m.Agent{Credits: c.credits}, Cargo: c.cargo}
+	res.Cargo.Inventory = append([]m.ShipCargoItem(nil), c.cargo.Inventory...)
+	res.Transaction = m.MarketTransaction{TradeSymbol: cargoSymbol, Units: units, PricePerUnit: 10, TotalPrice: int64(10 * units)}
+
+	return res, nil
+}
+
+func TestReportWarnsWhileTheCommandLoopIsStuck(t *testing.T) {
+	out := captureLogs(t)
+	fake := clock.NewFake(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	sb := strategyShip(t, config.Default(), "COMMAND", "X1-A-1", "DOCKED")
+	sb.clock = fake
+
+	sbCh := make(chan ShipBot)
+	go sb.Report(sbCh)
+
+	for fake.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	fake.Advance(reportTimeout)
+	<-sbCh
+
+	warned := 0
+	for _, line := range logLines(t, out, "🚀 GOGARIN-2:") {
+		if line["msg"] == "Command loop has not accepted report. Still waiting..." {
+			warned++
+		}
+	}
+	if warned != 1 {
+		t.Errorf("warned %d times after %s, want once", warned, reportTimeout)
+	}
+}
+
+func TestSellCargoKeepsReservedGoodsAboard(t *testing.T) {
+	cfg := config.Default()
+	cfg.ReservedGoods = []string{"FUEL"}
+
+	ship := m.Ship{Symbol: "GOGARIN-2"}
+	ship.Cargo = m.ShipCargo{Capacity: 40, Units: 29, Inventory: []m.ShipCargoItem{
+		{Symbol: "IRON_ORE", Units: 12},
+		{Symbol: "ANTIMATTER", Units: 2},
+		{Symbol: "QUARTZ_SAND", Units: 0},
+		{Symbol: "FUEL", Units: 5},
+		{Symbol: "ICE_WATER", Units: 10},
+	}}
+
+	c := &sellClient{cargo: ship.Cargo}
+	c.cargo.Inventory = append([]m.ShipCargoItem(nil), ship.Cargo.Inventory...)
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+	sb := NewShipBot(c, &ship, store.NewAgentState(m.Agent{}, 0), nil, store.NewMarketStore(), bus, cfg)
+
+	sbCh := make(chan ShipBot, 1)
+	sb.SellCargo(sbCh)
+	<-sbCh
+
+	if got := strings.Join(c.sales, ", "); got != "12 IRON_ORE, 10 ICE_WATER" {
+		t.Errorf("sold %s, want each unreserved good with units once", got)
+	}
+	if ship.Cargo.UnitsOf("ANTIMATTER") != 2 || ship.Cargo.UnitsOf("FUEL") != 5 {
+		t.Errorf("cargo after selling = %+v, want the reserved goods aboard", ship.Cargo.Inventory)
+	}
+	if ship.Cargo.Units != 7 || sb.agent.Credits() != 220 {
+		t.Errorf("after selling: %d units aboard and %d credits, want 7 and 220", ship.Cargo.Units, sb.agent.Credits())
+	}
+}
+
+// refusingClient is a tradeClient whose markets refuse goods they do not trade, and that jettisons cargo.
+type refusingClient struct {
+	tradeClient
+	jettisonErr error
+}
+
+func (c *refusingClient) SellCargo(shipSymbol string, cargoSymbol string, units int) (*api.SellCargoResponse, error) {
+	market := c.markets[c.nav.WaypointSymbol]
+	if _, ok := market.SellPriceOf(cargoSymbol); !ok {
+		c.calls = append(c.calls, fmt.Sprintf("refused %s at %s", cargoSymbol, c.nav.WaypointSymbol))
+		return nil, &api.APIError{StatusCode: http.StatusBadRequest, Code: api.CodeTradeNotAvailable, Message: "not traded"}
+	}
+
+	res, err := c.tradeClient.SellCargo(shipSymbol, cargoSymbol, units)
+	if err == nil {
+		res.Cargo.Inventory = append([]m.ShipCargoItem(nil), res.Cargo.Inventory...)
+	}
+	return res, err
+}
+
+func (c *refusingClient) JettisonCargo(shipSymbol string, cargoSymbol m.TradeGood, units int) (*m.ShipCargo, error) {
+	if c.jettisonErr != nil {
+		return nil, c.jettisonErr
+	}
+
+	c.calls = append(c.calls, fmt.Sprintf("jettison %d %s", units, cargoSymbol.Symbol))
+	c.cargo.Remove(cargoSymbol.Symbol, units)
+	cargo := c.cargo
+	cargo.Inventory = append([]m.ShipCargoItem(nil), c.cargo.Inventory...)
+	return &cargo, nil
+}
+
+// newRefusingClient is a refusingClient for a ship docked at X1-A-1, which buys only IRON_ORE, with a mixed
+// hold: ICE_WATER has a known buyer at X1-A-3, and nothing in the system buys QUARTZ_SAND or COPPER_ORE.
+func newRefusingClient() *refusingClient {
+	c := &refusingClient{}
+	c.waypoints = tradeWaypoints
+	c.nav = m.ShipNav{SystemSymbol: "X1-A", WaypointSymbol: "X1-A-1", Status: "DOCKED"}
+	c.cargo = m.ShipCargo{Capacity: 40}
+	for _, good := range []string{"IRON_ORE 10", "ICE_WATER 5", "QUARTZ_SAND 8", "COPPER_ORE 6"} {
+		var item m.ShipCargoItem
+		fmt.Sscan(good, &item.Symbol, &item.Units)
+		c.cargo.Add(item.Symbol, item.Units)
+	}
+	c.markets = map[string]m.Market{
+		"X1-A-1": tradeMarket("X1-A-1", 0, "IRON_ORE 10 8").Market,
+		"X1-A-3": tradeMarket("X1-A-3", 0, "ICE_WATER 25 20").Market,
+	}
+	return c
+}
+
+// refusingShip returns a ShipBot for the ship of c that knows the markets of c, and one in another system
+// buying QUARTZ_SAND.
+func refusingShip(t *testing.T, cfg *config.Config, c *refusingClient) *ShipBot {
+	t.Helper()
+
+	markets := store.NewMarketStore()
+	for _, market := range c.markets {
+		markets.Record(market, time.Now())
+	}
+	elsewhere := tradeMarket("X1-B-2", 0, "QUARTZ_SAND 40 30")
+	markets.Record(elsewhere.Market, elsewhere.ObservedAt)
+
+	ship := m.Ship{Symbol: "GOGARIN-1", Nav: c.nav, Cargo: c.cargo}
+	ship.Cargo.Inventory = append([]m.ShipCargoItem(nil), c.cargo.Inventory...)
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+	sb := NewShipBot(c, &ship, store.NewAgentState(m.Agent{}, 0), store.NewSystemKnowledge(c), markets, bus, cfg)
+	sb.arrival, sb.dock = nil, nil
+	sb.plan = DeliveryPlan{{TradeSymbol: "COPPER_ORE"}}
+
+	return sb
+}
+
+func TestSellCargoResolvesGoodsTheMarketRefuses(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      string
+		jettisonErr error
+		calls       []string
+		retained    []string
+		reasons     map[string]string
+	}{
+		{
+			name:     "auto",
+			policy:   "auto",
+			calls:    []string{"refused QUARTZ_SAND at X1-A-1", "jettison 8 QUARTZ_SAND", "refused COPPER_ORE at X1-A-1"},
+			retained: []string{"COPPER_ORE"},
+			reasons:  map[string]string{"ICE_WATER": "known buyer in system", "QUARTZ_SAND": "worth an estimated 240 credits; no known buyer", "COPPER_ORE": "contract or priority good; no known buyer"},
+		},
+		{
+			name:     "retain",
+			policy:   "retain",
+			calls:    []string{"refused QUARTZ_SAND at X1-A-1", "refused COPPER_ORE at X1-A-1"},
+			retained: []string{"COPPER_ORE", "QUARTZ_SAND"},
+			reasons:  map[string]string{"ICE_WATER": "known buyer in system", "QUARTZ_SAND": "policy retain", "COPPER_ORE": "policy retain"},
+		},
+		{
+			name:    "jettison",
+			policy:  "jettison",
+			calls:   []string{"refused QUARTZ_SAND at X1-A-1", "jettison 8 QUARTZ_SAND", "refused COPPER_ORE at X1-A-1", "jettison 6 COPPER_ORE"},
+			reasons: map[string]string{"ICE_WATER": "known buyer in system", "QUARTZ_SAND": "worth an estimated 240 credits; policy jettison; no known buyer", "COPPER_ORE": "worth an estimated 0 credits; policy jettison; no known buyer"},
+		},
+		{
+			name:        "jettison failing",
+			policy:      "jettison",
+			jettisonErr: errors.New("jettison refused"),
+			calls:       []string{"refused QUARTZ_SAND at X1-A-1", "refused COPPER_ORE at X1-A-1"},
+			retained:    []string{"COPPER_ORE", "QUARTZ_SAND"},
+			reasons:     map[string]string{"ICE_WATER": "known buyer in system", "QUARTZ_SAND": "jettison failed", "COPPER_ORE": "jettison failed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.UnsellablePolicy = tt.policy
+			out := captureLogs(t)
+
+			c := newRefusingClient()
+			c.jettisonErr = tt.jettisonErr
+			sb := refusingShip(t, cfg, c)
+
+			sbCh := make(chan ShipBot, 1)
+			sb.SellCargo(sbCh)
+			<-sbCh
+
+			want := append([]string{"sell 10 IRON_ORE at X1-A-1", "refused ICE_WATER at X1-A-1"}, tt.calls...)
+			if !reflect.DeepEqual(c.calls, want) {
+				t.Errorf("calls = %q, want %q", c.calls, want)
+			}
+
+			var retained []string
+			for symbol := range sb.retained {
+				retained = append(retained, symbol)
+			}
+			sort.Strings(retained)
+			if !reflect.DeepEqual(retained, tt.retained) {
+				t.Errorf("retained %q, want %q", retained, tt.retained)
+			}
+			for _, symbol := range tt.retained {
+				if sb.ship.Cargo.UnitsOf(symbol) == 0 {
+					t.Errorf("retained %s is no longer aboard", symbol)
+				}
+			}
+
+			if len(sb.queued) != 1 || sb.queued[0].Name != "Sell ICE_WATER at X1-A-3" {
+				t.Errorf("queued %+v, want one sell trip to X1-A-3", sb.queued)
+			}
+
+			reasons := make(map[string]string)
+			for _, line := range logLines(t, out, "🚀 GOGARIN-1:") {
+				switch line["msg"] {
+				case "💲 Good not traded here. Queued sell trip.", "🗑️ Cargo jettisoned.", "📦 Cargo retained.":
+					reasons[line["symbol"].(string)] = line["reason"].(string)
+				}
+			}
+			if !reflect.DeepEqual(reasons, tt.reasons) {
+				t.Errorf("logged decisions = %v, want %v", reasons, tt.reasons)
+			}
+		})
+	}
+}
+
+func TestQueuedSellTripGoesBeforeTheStrategy(t *testing.T) {
+	cfg := config.Default()
+
+	c := newRefusingClient()
+	sb := refusingShip(t, cfg, c)
+
+	sbCh := make(chan ShipBot, 1)
+	sb.SellCargo(sbCh)
+	reported := <-sbCh
+
+	ab := NewAgentBot(c, &m.Agent{Symbol: "GOGARIN"}, sb.markets, NewStrategySelector(cfg), nil, sb.bus, cfg)
+	t.Cleanup(ab.scheduler.Stop)
+
+	c.calls = nil
+	ab.DispatchNext(reported, sbCh)
+	reported = <-sbCh
+
+	if reported.mission != "Sell ICE_WATER at X1-A-3" {
+		t.Errorf("dispatched %q, want the queued sell trip", reported.mission)
+	}
+	if want := []string{"orbit", "navigate X1-A-3", "dock", "sell 5 ICE_WATER at X1-A-3"}; !reflect.DeepEqual(c.calls, want) {
+		t.Errorf("calls = %q, want %q", c.calls, want)
+	}
+	if len(reported.queued) != 0 || reported.ship.Cargo.UnitsOf("ICE_WATER") != 0 {
+		t.Errorf("after the trip: queued %+v and %d ICE_WATER aboard, want neither", reported.queued, reported.ship.Cargo.UnitsOf("ICE_WATER"))
+	}
+}
+
+// layoutClient is a tradeClient that also serves its system's waypoint listing, recording both kinds of read.
+type layoutClient struct {
+	tradeClient
+}
+
+func (c *layoutClient) GetSystem(systemSymbol string) (*m.System, error) {
+	c.calls = append(c.calls, "system "+systemSymbol)
+	system := m.System{Symbol: systemSymbol}
+	for _, waypoint := range c.waypoints {
+		system.Waypoints = append(system.Waypoints, m.SystemWaypoint{Symbol: waypoint.Symbol, Type: waypoint.Type, X: waypoint.X, Y: waypoint.Y})
+	}
+	return &system, nil
+}
+
+func (c *layoutClient) ListWaypoints(systemSymbol string) (*[]m.Waypoint, error) {
+	c.calls = append(c.calls, "list "+systemSymbol)
+	return c.tradeClient.ListWaypoints(systemSymbol)
+}
+
+// layoutShip returns a ShipBot in orbit at waypointSymbol, commanded through c.
+func layoutShip(t *testing.T, cfg *config.Config, c *layoutClient, waypointSymbol string) *ShipBot {
+	t.Helper()
+
+	c.waypoints = tradeWaypoints
+	c.nav = m.ShipNav{SystemSymbol: "X1-A", WaypointSymbol: waypointSymbol, Status: "IN_ORBIT"}
+
+	ship := m.Ship{Symbol: "GOGARIN-1", Nav: c.nav}
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+	sb := NewShipBot(c, &ship, store.NewAgentState(m.Agent{}, 0), store.NewSystemKnowledge(c), store.NewMarketStore(), bus, cfg)
+	sb.arrival, sb.dock = nil, nil
+
+	return sb
+}
+
+func TestNavigateByTypeUsesTheSystemLayout(t *testing.T) {
+	cfg := config.Default()
+
+	c := &layoutClient{}
+	sb := layoutShip(t, cfg, c, "X1-A-4")
+
+	sbCh := make(chan ShipBot, 1)
+	sb.NavigateToNearestWaypointOfType("PLANET", sbCh)
+
+	if reported := <-sbCh; reported.ship.Nav.WaypointSymbol != "X1-A-1" {
+		t.Errorf("navigated to %s, want the planet X1-A-1", reported.ship.Nav.WaypointSymbol)
+	}
+	if want := []string{"system X1-A", "navigate X1-A-1"}; !reflect.DeepEqual(c.calls, want) {
+		t.Errorf("calls = %q, want %q with no waypoints listed", c.calls, want)
+	}
+}
+
+func TestNavigateFromAnUnknownWaypointFails(t *testing.T) {
+	cfg := config.Default()
+
+	// The ship sits at a waypoint its system does not list, as after a stale cache or a server reset.
+	c := &layoutClient{}
+	sb := layoutShip(t, cfg, c, "X1-A-9")
+	events := sb.bus.Subscribe(16)
+	sbCh := make(chan ShipBot, 2)
+
+	sb.NavigateToNearestWaypointOfType("MOON", sbCh)
+	<-sbCh
+	sb.NavigateToNearestWaypointWithTrait("MARKETPLACE", sbCh)
+	<-sbCh
+
+	sb.bus.Close()
+	var failures int
+	for e := range events {
+		if e.Type == event.MissionFailed {
+			failures++
+		}
+	}
+	if failures != 2 {
+		t.Errorf("%d navigations failed, want both", failures)
+	}
+
+	for _, call := range c.calls {
+		if strings.HasPrefix(call, "navigate") {
+			t.Errorf("navigated from an unknown waypoint: %s", call)
+		}
+	}
+}
+
+// fuelClient is a tradeClient whose ship fills its tank when it refuels.
+type fuelClient struct {
+	tradeClient
+	fuel m.ShipFuel
+}
+
+func (c *fuelClient) RefuelShip(shipSymbol string, units int) (*api.RefuelShipResponse, error) {
+	c.calls = append(c.calls, fmt.Sprintf("refuel %d", units))
+	c.fuel.Current = c.fuel.Capacity
+	return &api.RefuelShipResponse{Agent: m.Agent{Credits: 10000}, Fuel: c.fuel}, nil
+}
+
+func TestNavigateShipChecksItCanReachFuelFromTheDestination(t *testing.T) {
+	tests := []struct {
+		name     string
+		fuel     m.ShipFuel
+		mode     string
+		fuelSold bool
+		calls    []string
+		at       string
+	}{
+		{"enough fuel to return", m.ShipFuel{Current: 600, Capacity: 700}, "CRUISE", true, []string{"orbit", "navigate X1-S-2"}, "X1-S-2"},
+		{"tops up first", m.ShipFuel{Current: 350, Capacity: 700}, "CRUISE", true, []string{"refuel 0", "orbit", "navigate X1-S-2"}, "X1-S-2"},
+		{"no fuel sold to top up", m.ShipFuel{Current: 350, Capacity: 700}, "CRUISE", false, nil, "X1-S-1"},
+		{"stranded even with a full tank", m.ShipFuel{Current: 350, Capacity: 400}, "CRUISE", true, nil, "X1-S-1"},
+		{"burn needs a bigger tank", m.ShipFuel{Current: 700, Capacity: 700}, "BURN", true, nil, "X1-S-1"},
+		{"drifting", m.ShipFuel{Current: 10, Capacity: 400}, "DRIFT", true, []string{"orbit", "navigate X1-S-2"}, "X1-S-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			out := captureLogs(t)
+
+			// A sparse system: the nearest fuel to the asteroid field is back where the ship sets out.
+			c := &fuelClient{fuel: tt.fuel}
+			c.waypoints = []m.Waypoint{
+				{Symbol: "X1-S-1", SystemSymbol: "X1-S", Type: "PLANET", Traits: []m.WaypointTrait{{Symbol: "MARKETPLACE"}}},
+				{Symbol: "X1-S-2", SystemSymbol: "X1-S", Type: "ASTEROID_FIELD", X: 300},
+			}
+			c.nav = m.ShipNav{SystemSymbol: "X1-S", WaypointSymbol: "X1-S-1", Status: "DOCKED", FlightMode: tt.mode}
+
+			markets := store.NewMarketStore()
+			if tt.fuelSold {
+				market := tradeMarket("X1-S-1", 0, "FUEL 2 1").Market
+				market.Exchange = []m.TradeGood{{Symbol: "FUEL"}}
+				markets.Record(market, time.Now())
+			}
+
+			ship := m.Ship{Symbol: "GOGARIN-1", Nav: c.nav, Fuel: tt.fuel}
+			bus := event.NewBus()
+			t.Cleanup(bus.Close)
+			sb := NewShipBot(c, &ship, store.NewAgentState(m.Agent{Credits: 10000}, 0), store.NewSystemKnowledge(c), markets, bus, cfg)
+			sb.arrival, sb.dock = nil, nil
+
+			sb.NavigateShip("X1-S-2")
+
+			if !reflect.DeepEqual(c.calls, tt.calls) {
+				t.Errorf("calls = %q, want %q", c.calls, tt.calls)
+			}
+			if sb.ship.Nav.WaypointSymbol != tt.at {
+				t.Errorf("at %s, want %s", sb.ship.Nav.WaypointSymbol, tt.at)
+			}
+
+			if tt.at == "X1-S-1" {
+				var logged bool
+				for _, line := range logLines(t, out, "🚀 GOGARIN-1:") {
+					logged = logged || (line["msg"] == "⛽ Refusing trip." && strings.Contains(line["error"].(string), "strand"))
+				}
+				if !logged {
+					t.Error("refusal not logged with its reason")
+				}
+			}
+		})
+	}
+}
+
+func TestSoldSummary(t *testing.T) {
+	if got := soldSummary(map[string]int{"IRON_ORE": 30, "ICE_WATER": 12}); got != "ICE_WATER=12 IRON_ORE=30" {
+		t.Errorf("soldSummary = %q", got)
+	}
+}
+
+func TestRequisitionLeavesUnreachableShipyardUnpriced(t *testing.T) {
+	cfg := config.Default()
+	out := captureLogs(t)
+
+	f, err := mockserver.DefaultFixture()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The only shipyard is at the moon, where no ship is present to see prices, and the command ship has
+	// no fuel to get there.
+	for i := range f.Waypoints {
+		var traits []m.WaypointTrait
+		for _, trait := range f.Waypoints[i].Traits {
+			if trait.Symbol != "SHIPYARD" {
+				traits = append(traits, trait)
+			}
+		}
+		if f.Waypoints[i].Symbol == "X1-MK1-C3" {
+			traits = append(traits, m.WaypointTrait{Symbol: "SHIPYARD"})
+		}
+		f.Waypoints[i].Traits = traits
+	}
+	f.Shipyards[0].Symbol = "X1-MK1-C3"
+	f.Ships[0].Fuel.Current = 0
+
+	s := mockserver.New(f, mockserver.WithTimeScale(1000))
+	t.Cleanup(s.Close)
+	c := api.NewClient("token", api.WithBaseURL(s.URL), api.WithRateLimit(1000))
+
+	ship, err := c.GetShip("MOCK-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+	ab := NewAgentBot(c, &m.Agent{Symbol: "MOCK"}, store.NewMarketStore(), NewStrategySelector(cfg), nil, bus, cfg)
+	sb := NewShipBot(c, ship, ab.agent, ab.systems, ab.markets, bus, cfg)
+
+	sb.InitiateRequisitionProtocol(ab)
+
+	if ship, _ := s.Ship("MOCK-1"); ship.Nav.WaypointSymbol != "X1-MK1-A1" {
+		t.Fatalf("command ship at %s, want it still at X1-MK1-A1", ship.Nav.WaypointSymbol)
+	}
+
+	var msgs []string
+	for _, line := range logLines(t, out, "🚀 MOCK-1:") {
+		msgs = append(msgs, line["msg"].(string))
+	}
+	if !reflect.DeepEqual(msgs[len(msgs)-2:], []string{"🔎 Could not reach shipyard. Leaving it unpriced.", "🔎 No shipyard prices available."}) {
+		t.Errorf("requisition log = %v, want the shipyard left unpriced", msgs)
+	}
+}
+
+func TestRequisitionAbandonsAnUnreachableShipyard(t *testing.T) {
+	cfg := config.Default()
+	out := captureLogs(t)
+
+	f, err := mockserver.DefaultFixture()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The only shipyard is at the moon, where the excavator shows its prices, but the command ship has no
+	// fuel to get there and shipyards only sell to a ship that is present.
+	for i := range f.Waypoints {
+		var traits []m.WaypointTrait
+		for _, trait := range f.Waypoints[i].Traits {
+			if trait.Symbol != "SHIPYARD" {
+				traits = append(traits, trait)
+			}
+		}
+		if f.Waypoints[i].Symbol == "X1-MK1-C3" {
+			traits = append(traits, m.WaypointTrait{Symbol: "SHIPYARD"})
+		}
+		f.Waypoints[i].Traits = traits
+	}
+	f.Shipyards[0].Symbol = "X1-MK1-C3"
+	f.Ships[0].Fuel.Current = 0
+	f.Ships[1].Nav.WaypointSymbol = "X1-MK1-C3"
+	f.Ships[1].Nav.Route.Destination.Symbol = "X1-MK1-C3"
+
+	s := mockserver.New(f, mockserver.WithTimeScale(1000))
+	t.Cleanup(s.Close)
+	c := api.NewClient("token", api.WithBaseURL(s.URL), api.WithRateLimit(1000))
+
+	ship, err := c.GetShip("MOCK-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+	ab := NewAgentBot(c, &f.Agent, store.NewMarketStore(), NewStrategySelector(cfg), nil, bus, cfg)
+	sb := NewShipBot(c, ship, ab.agent, ab.systems, ab.markets, bus, cfg)
+
+	sb.InitiateRequisitionProtocol(ab)
+
+	if n := s.Requests("POST", "/my/ships"); n != 0 {
+		t.Fatalf("%d ship purchases, want none from a shipyard the command ship never reached", n)
+	}
+	if ship, _ := s.Ship("MOCK-1"); ship.Nav.WaypointSymbol != "X1-MK1-A1" {
+		t.Fatalf("command ship at %s, want it still at X1-MK1-A1", ship.Nav.WaypointSymbol)
+	}
+
+	var msgs []string
+	for _, line := range logLines(t, out, "🚀 MOCK-1:") {
+		msgs = append(msgs, line["msg"].(string))
+	}
+	if last := msgs[len(msgs)-1]; last != "🔎 Could not reach shipyard. Purchase abandoned." {
+		t.Errorf("requisition log = %v, want the purchase abandoned", msgs)
+	}
+	if available := ab.agent.Available(); available != int64(f.Agent.Credits) {
+		t.Errorf("%d credits available after abandoning the purchase, want all %d", available, f.Agent.Credits)
+	}
+}
+
+func TestMissionFailureKeepsTheAPIError(t *testing.T) {
+	_, c := startMock(t, 0)
+	ship, err := c.GetShip("MOCK-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+
+	// The excavator orbits the planet, which has nothing to extract.
+	sb := NewShipBot(c, ship, store.NewAgentState(m.Agent{}, 0), store.NewSystemKnowledge(c), store.NewMarketStore(), bus, config.Default())
+	sb.mission = "Extract resources"
+
+	sbCh := make(chan ShipBot, 1)
+	sb.ExtractResources(sbCh)
+	reported := <-sbCh
+
+	var apiErr *api.APIError
+	if !errors.As(reported.failure, &apiErr) || apiErr.Code != 4205 {
+		t.Fatalf("failure %q, want it to reach an APIError with code 4205", reported.failure)
+	}
+	want := []interface{}{"ship", "MOCK-2", "mission", "Extract resources", "waypoint", "X1-MK1-A1", "attempt", 1}
+	if got := boterr.Fields(reported.failure); !reflect.DeepEqual(got, want) {
+		t.Errorf("failure fields = %v, want %v", got, want)
+	}
+}
+
+func TestMiningTargetSuitsThePriorities(t *testing.T) {
+	cfg := config.Default()
+	out := captureLogs(t)
+
+	f, err := mockserver.DefaultFixture()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A second mining target, farther from the excavator at X1-MK1-A1 than X1-MK1-B2 but with ice, and of a
+	// newer asteroid type.
+	ice := f.Waypoints[1]
+	ice.Symbol, ice.Type, ice.X, ice.Y = "X1-MK1-E5", "ENGINEERED_ASTEROID", -20, 10
+	ice.Traits = []m.WaypointTrait{{Symbol: "MINERAL_DEPOSITS"}}
+	f.Waypoints = append(f.Waypoints, ice)
+
+	s := mockserver.New(f, mockserver.WithTimeScale(1000))
+	t.Cleanup(s.Close)
+	c := api.NewClient("token", api.WithBaseURL(s.URL), api.WithRateLimit(1000))
+
+	ship, err := c.GetShip("MOCK-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+	sb := NewShipBot(c, ship, store.NewAgentState(m.Agent{}, 0), store.NewSystemKnowledge(c), store.NewMarketStore(), bus, cfg)
+	sb.plan = DeliveryPlan{{TradeSymbol: "ICE_WATER"}}
+
+	sbCh := make(chan ShipBot, 1)
+	sb.NavigateToMiningTarget(sbCh)
+	<-sbCh
+
+	if ship, _ := s.Ship("MOCK-2"); ship.Nav.WaypointSymbol != "X1-MK1-E5" {
+		t.Fatalf("excavator navigated to %s, want the ice field X1-MK1-E5", ship.Nav.WaypointSymbol)
+	}
+
+	var chosen []map[string]interface{}
+	for _, line := range logLines(t, out, "🚀 MOCK-2:") {
+		if line["msg"] == "⛏ Mining target chosen." {
+			chosen = append(chosen, line)
+		}
+	}
+	if len(chosen) != 1 || chosen[0]["waypoint"] != "X1-MK1-E5" || chosen[0]["reason"] != "deposits yield ICE_WATER" {
+		t.Errorf("mining target lines = %v, want one giving the reason", chosen)
+	}
+}
+
+func TestSellCargoResyncsCargoChangedMidMission(t *testing.T) {
+	f := fixture(t)
+	f.Ships[1].Nav.Status = "DOCKED"
+	f.Ships[1].Nav.WaypointSymbol = "X1-MK1-C3"
+	f.Ships[1].Nav.Route.Destination.Symbol = "X1-MK1-C3"
+	f.Ships[1].Cargo = m.ShipCargo{Capacity: 30, Units: 15, Inventory: []m.ShipCargoItem{
+		{Symbol: "QUARTZ_SAND", Units: 5},
+		{Symbol: "IRON_ORE", Units: 10},
+	}}
+
+	sb, server := shipBot(t, f, "MOCK-2")
+	sb.arrival = nil
+
+	// Since the ShipBot last saw its cargo, another mission has jettisoned the sand and six units of ore.
+	if _, err := sb.client.JettisonCargo("MOCK-2", m.TradeGood{Symbol: "QUARTZ_SAND"}, 5); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sb.client.JettisonCargo("MOCK-2", m.TradeGood{Symbol: "IRON_ORE"}, 6); err != nil {
+		t.Fatal(err)
+	}
+	sold := sb.bus.Subscribe(8)
+
+	sbCh := make(chan ShipBot, 1)
+	sb.SellCargo(sbCh)
+
+	if reported := <-sbCh; reported.failure != nil {
+		t.Fatalf("selling failed: %s", reported.failure)
+	}
+	if ship, _ := server.Ship("MOCK-2"); ship.Cargo.Units != 0 {
+		t.Fatalf("%d units left in the hold, want the remaining ore sold", ship.Cargo.Units)
+	}
+	if e := <-sold; e.Type != event.CargoSold || !strings.HasPrefix(e.Message, "4 IRON_ORE ") {
+		t.Fatalf("first event %s %q, want the 4 remaining IRON_ORE sold", e.Type, e.Message)
+	}
+}
+
+func TestNavigateShipOrbitsFirst(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		ship   string
+		status string
+		orbits int
+		tries  int
+	}{
+		{name: "docked", ship: "MOCK-1", orbits: 1, tries: 1},
+		{name: "in orbit", ship: "MOCK-2", orbits: 0, tries: 1},
+		// The ShipBot believes the ship is in orbit, but it was docked since: the refused navigation is
+		// retried once the ship is in orbit.
+		{name: "stale status", ship: "MOCK-1", status: "IN_ORBIT", orbits: 1, tries: 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sb, server := shipBot(t, fixture(t), tc.ship)
+			sb.arrival = nil
+			if tc.status != "" {
+				sb.ship.Nav.Status = tc.status
+			}
+
+			if err := sb.NavigateShip("X1-MK1-C3"); err != nil {
+				t.Fatalf("navigation failed: %s", err)
+			}
+			if ship, _ := server.Ship(tc.ship); ship.Nav.WaypointSymbol != "X1-MK1-C3" {
+				t.Fatalf("ship at %s, want X1-MK1-C3", ship.Nav.WaypointSymbol)
+			}
+			if n := server.Requests("POST", "/my/ships/"+tc.ship+"/orbit"); n != tc.orbits {
+				t.Fatalf("%d orbit requests, want %d", n, tc.orbits)
+			}
+			if n := server.Requests("POST", "/my/ships/"+tc.ship+"/navigate"); n != tc.tries {
+				t.Fatalf("%d navigate requests, want %d", n, tc.tries)
+			}
+		})
+	}
+}
+
+func TestNavigateShipSurfacesOrbitError(t *testing.T) {
+	sb, server := shipBot(t, fixture(t), "MOCK-1")
+	server.SetAvailable(false)
+
+	var apiErr *api.APIError
+	if err := sb.NavigateShip("X1-MK1-C3"); !errors.As(err, &apiErr) || apiErr.StatusCode != 503 {
+		t.Fatalf("err = %v, want the orbit request's 503", err)
+	}
+	if n := server.Requests("POST", "/my/ships/MOCK-1/navigate"); n != 0 {
+		t.Fatalf("%d navigate requests, want none after orbiting failed", n)
+	}
+}
+
+// crashingMarket crashes the price of a good at a market once a set number of lots of it have been sold there.
+type crashingMarket struct {
+	api.ClientAPI
+	server *mockserver.Server
+	after  int
+	crash  m.Market
+
+	lots int
+}
+
+func (c *crashingMarket) SellCargo(shipSymbol string, goodSymbol string, units int) (*api.SellCargoResponse, error) {
+	res, err := c.ClientAPI.SellCargo(shipSymbol, goodSymbol, units)
+	if err == nil {
+		if c.lots++; c.lots == c.after {
+			c.server.SetMarket(c.crash)
+		}
+	}
+
+	return res, err
+}
+
+func TestSellCargoReroutesRestAfterPriceCollapse(t *testing.T) {
+	f := fixture(t)
+	f.Ships[1].Nav.Status = "DOCKED"
+	f.Ships[1].Cargo = m.ShipCargo{Capacity: 30, Units: 30, Inventory: []m.ShipCargoItem{{Symbol: "IRON_ORE", Units: 30}}}
+	// The planet buys ore ten units at a time.
+	f.Markets[0].TradeGoods[0].TradeVolume = 10
+	crash := f.Markets[0]
+	crash.TradeGoods = append([]m.MarketTradeGood(nil), crash.TradeGoods...)
+	crash.TradeGoods[0].SellPrice = 20
+	crash.TradeGoods[0].Supply = m.SupplyAbundant
+
+	sb, server := shipBot(t, f, "MOCK-2")
+	sb.arrival = nil
+	for _, market := range f.Markets {
+		sb.markets.Record(market, sb.clock.Now())
+	}
+	// The first lot sells at the recorded price and floods the market, so the second sells at under half.
+	sb.client = &crashingMarket{ClientAPI: sb.client, server: server, after: 1, crash: crash}
+
+	sbCh := make(chan ShipBot, 1)
+	sb.SellCargo(sbCh)
+
+	reported := <-sbCh
+	if reported.failure != nil {
+		t.Fatalf("selling failed: %s", reported.failure)
+	}
+	if n := server.Requests("POST", "/my/ships/MOCK-2/sell"); n != 2 {
+		t.Fatalf("%d lots sold, want 2 before the collapse was noticed", n)
+	}
+	if held := reported.ship.Cargo.UnitsOf("IRON_ORE"); held != 10 {
+		t.Fatalf("%d IRON_ORE held, want the last 10 kept for another market", held)
+	}
+	if n := server.Requests("GET", "/systems/X1-MK1/waypoints/X1-MK1-A1/market"); n != 1 {
+		t.Fatalf("%d market refreshes, want 1 after the price dropped", n)
+	}
+	if supply, _ := sb.markets.Supply("X1-MK1-A1", "IRON_ORE"); supply != m.SupplyAbundant {
+		t.Fatalf("recorded supply %q, want the refreshed %q", supply, m.SupplyAbundant)
+	}
+
+	want := sellTripMission("X1-MK1-C3", []string{"IRON_ORE"}).Name
+	if len(reported.queued) != 1 || reported.queued[0].Name != want {
+		t.Fatalf("queued %v, want only %q", reported.queued, want)
+	}
+}
@@ -0,0 +1,163 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+/*
+🏭 Shipyard prices
+*/
+
+// requisitionRole is the role of requisitionShipType, counted against the fleet plan.
+const requisitionRole = "EXCAVATOR"
+
+var (
+	// errFleetPlanMet is returned when the fleet already has the ships its plan asks for.
+	errFleetPlanMet = errors.New("the fleet plan is met")
+	// errNoPriceDip is returned when no shipyard's latest price is below its rolling average.
+	errNoPriceDip = errors.New("no shipyard price is below its rolling average")
+)
+
+// watchShipyard records the prices of the shipyard a satellite is parked at, unless they were recorded, or a ship
+// checked the shipyard, within the ship's market refresh interval. Other ships, and satellites elsewhere, record
+// nothing.
+func (sb *ShipBot) watchShipyard() {
+	if sb.shipyards == nil || sb.ship.Registration.Role != "SATELLITE" || sb.ship.Nav.Status == "IN_TRANSIT" {
+		return
+	}
+
+	waypoint, err := sb.systems.Waypoint(sb.ship.Nav.WaypointSymbol)
+	if err != nil || !waypoint.HasTrait("SHIPYARD") {
+		return
+	}
+
+	now := sb.clock.Now()
+	if sb.shipyards.Fresh(waypoint.Symbol, sb.marketRefresh, now) || sb.visits.Done(waypoint.Symbol, store.ActionShipyard, sb.marketRefresh, now) {
+		return
+	}
+
+	shipyard, err := sb.client.GetShipyardAt(waypoint.Symbol)
+	sb.recordVisit(waypoint.Symbol, store.ActionShipyard, err)
+	if err != nil {
+		sb.logger.Warn("🏭 Error getting shipyard.", "waypoint", waypoint.Symbol, "error", err)
+		return
+	}
+
+	if err := sb.shipyards.Record(*shipyard, sb.clock.Now()); err != nil {
+		sb.logger.Warn("🏭 Error saving shipyard prices.", "waypoint", waypoint.Symbol, "error", err)
+		return
+	}
+	sb.logger.Debug("🏭 Shipyard prices recorded.", "waypoint", waypoint.Symbol, "ships", len(shipyard.Ships))
+}
+
+// priceDip checks if the latest of a shipyard's prices, oldest first, is at least dip below the average of the
+// earlier ones, as a fraction of that average. The average needs at least minSamples earlier prices.
+func priceDip(history []store.ShipyardPrice, dip float64, minSamples int) (average float64, ok bool) {
+	if len(history) < 2 || len(history)-1 < minSamples {
+		return 0, false
+	}
+
+	earlier := history[:len(history)-1]
+
+	var sum int64
+	for _, price := range earlier {
+		sum += price.Price
+	}
+	average = float64(sum) / float64(len(earlier))
+
+	return average, float64(history[len(history)-1].Price) <= average*(1-dip)
+}
+
+// Requisition checks the recorded shipyard prices every interval until done is closed, buying a ship when the
+// fleet is below its plan and a shipyard with one of the agent's ships present has dipped below its rolling
+// average. At most one ship is bought each interval.
+func (ab *AgentBot) Requisition(interval time.Duration, done <-chan struct{}) {
+	ticker := ab.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+		case <-done:
+			return
+		}
+
+		if err := ab.requisitionOnDip(); err != nil {
+			ab.logger.Debug("🏭 No ship purchased.", "reason", err)
+		}
+	}
+}
+
+// requisitionOnDip buys a ship at the first shipyard, by waypoint symbol, whose latest price has dipped.
+func (ab *AgentBot) requisitionOnDip() error {
+	cfg := ab.config()
+
+	ships, err := ab.reconciler.GetMyShips()
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	present := make(map[string]bool)
+	for _, ship := range *ships {
+		if ab.controls(ship) && ship.Registration.Role == requisitionRole {
+			count++
+		}
+		if ship.Nav.Status != "IN_TRANSIT" {
+			present[ship.Nav.WaypointSymbol] = true
+		}
+	}
+
+	if count >= cfg.FleetPlan[requisitionRole] {
+		return errFleetPlanMet
+	}
+
+	now := ab.clock.Now()
+	for _, waypoint := range ab.shipyards.Shipyards(requisitionShipType, now) {
+		history := ab.shipyards.History(waypoint, requisitionShipType, now)
+
+		average, ok := priceDip(history, cfg.Purchase.Dip, cfg.Purchase.MinSamples)
+		if !ok || !present[waypoint] {
+			continue
+		}
+
+		latest := history[len(history)-1].Price
+		ab.logger.Info("🏭 Shipyard price dipped.", "waypoint", waypoint, "shipType", requisitionShipType, "price", latest, "average", int64(average), "samples", len(history)-1)
+
+		release, err := ab.agent.ReserveCredits(latest)
+		if err != nil {
+			return fmt.Errorf("cannot afford %d credits: %w", latest, err)
+		}
+		defer release()
+
+		_, err = ab.buyShip(requisitionShipType, waypoint)
+		return err
+	}
+
+	return errNoPriceDip
+}
+
+// buyShip buys a ship at a shipyard one of the agent's ships is at, publishes the purchase, and commissions
+// the new ship. It returns the purchase.
+func (ab *AgentBot) buyShip(shipType string, waypointSymbol string) (*m.ShipPurchase, error) {
+	res, err := ab.client.PurchaseShip(shipType, waypointSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	ab.agent.Update(res.Agent)
+	ab.logger.Info("🚀 Ship purchased.", "ship", res.Ship.Symbol, "shipType", shipType, "waypoint", waypointSymbol, "price", res.Transaction.Price, "credits", ab.agent.Credits())
+
+	ab.bus.Publish(event.Event{Type: event.ShipPurchased, Ship: res.Ship.Symbol, Message: fmt.Sprintf("%s for %d", shipType, res.Transaction.Price), Data: m.ShipPurchase{Ship: res.Ship, Transaction: res.Transaction}})
+	ab.bus.Publish(event.Event{Type: event.AgentUpdated, Data: res.Agent})
+
+	ab.commission(res.Ship)
+
+	return &m.ShipPurchase{Ship: res.Ship, Transaction: res.Transaction}, nil
+}
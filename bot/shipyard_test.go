@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/clock"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/mockserver"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+func TestRequisitionBuysAtPriceDip(t *testing.T) {
+	f := fixture(t)
+	fake := clock.NewFake(epoch).AutoAdvance()
+	server := mockserver.New(f, mockserver.WithClock(fake))
+	t.Cleanup(server.Close)
+	client := api.NewClient("token", api.WithBaseURL(server.URL), api.WithRateLimit(1000), api.WithClock(fake))
+
+	cfg := config.Default()
+	cfg.FleetPlan = map[string]int{requisitionRole: 2}
+
+	shipyards, err := store.OpenShipyardStore("", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ab := NewAgentBot(client, &f.Agent, store.NewMarketStore(), NewStrategySelector(cfg), nil, event.NewBus(), cfg)
+	ab.clock = fake
+	ab.shipyards = shipyards
+
+	// The command ship is docked at the shipyard. Prices wander around 81,000 before dipping 8% below their
+	// average; the 4% fall before it is within the noise.
+	series := []int64{80000, 84000, 79000, 83000, 78000, 74000}
+	for i, price := range series {
+		if err := shipyards.Record(m.Shipyard{Symbol: "X1-MK1-A1", Ships: []m.ShipyardShip{{Type: requisitionShipType, PurchasePrice: price}}}, fake.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		err := ab.requisitionOnDip()
+		if i < len(series)-1 {
+			if !errors.Is(err, errNoPriceDip) {
+				t.Fatalf("price %d of %v: err = %v, want %v", price, series[:i+1], err, errNoPriceDip)
+			}
+			if n := server.Requests("POST", "/my/ships"); n != 0 {
+				t.Fatalf("price %d of %v: bought a ship before the dip", price, series[:i+1])
+			}
+		} else if err != nil {
+			t.Fatalf("price %d of %v: %s", price, series, err)
+		}
+
+		fake.Advance(time.Hour)
+	}
+
+	if n := server.Requests("POST", "/my/ships"); n != 1 {
+		t.Fatalf("%d ship purchases at the dip, want 1", n)
+	}
+	if err := ab.requisitionOnDip(); !errors.Is(err, errFleetPlanMet) {
+		t.Fatalf("err once the fleet plan is met = %v, want %v", err, errFleetPlanMet)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package bot
+
+import "os"
+
+// fleetSignals make the AgentBot print the fleet table. SIGUSR1 is not available on this platform.
+var fleetSignals []os.Signal
+
+// reloadSignals make the Fleet reload its configuration. SIGHUP is not available on this platform.
+var reloadSignals []os.Signal
+
+// pauseSignals pause or resume the Fleet. SIGUSR2 is not available on this platform.
+var pauseSignals []os.Signal
@@ -0,0 +1,17 @@
+//go:build unix
+
+package bot
+
+import (
+	"os"
+	"syscall"
+)
+
+// fleetSignals make the AgentBot print the fleet table.
+var fleetSignals = []os.Signal{syscall.SIGUSR1}
+
+// reloadSignals make the Fleet reload its configuration.
+var reloadSignals = []os.Signal{syscall.SIGHUP}
+
+// pauseSignals pause or resume the Fleet.
+var pauseSignals = []os.Signal{syscall.SIGUSR2}
@@ -0,0 +1,70 @@
+//go:build unix
+
+package bot
+
+import (
+	"bytes"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/clock"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/status"
+	"github.com/charmbracelet/log"
+)
+
+// syncBuffer is a bytes.Buffer safe for a logger writing on another goroutine.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func TestPrintFleetOnSignal(t *testing.T) {
+	// Keep SIGUSR1 from terminating the test binary before the AgentBot is listening.
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, syscall.SIGUSR1)
+	defer signal.Stop(guard)
+
+	board := status.NewBoard()
+	board.Apply(event.Event{Type: event.ShipReported, Ship: "GOGARIN-1", Data: m.Ship{Symbol: "GOGARIN-1"}})
+
+	var out syncBuffer
+	ab := &AgentBot{logger: log.New(&out), live: config.NewLive(config.Default(), ""), clock: clock.Real}
+	done := make(chan struct{})
+	defer close(done)
+	go ab.PrintFleetOnSignal(board, done)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "GOGARIN-1") {
+		if time.Now().After(deadline) {
+			t.Fatalf("no fleet table after SIGUSR1:\n%s", out.String())
+		}
+		syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if !strings.Contains(out.String(), "Fleet status:") {
+		t.Errorf("output:\n%s", out.String())
+	}
+}
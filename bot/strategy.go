@@ -0,0 +1,419 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/GeoffreyDick/gogarin/config"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+/*
+♟️ Strategy
+*/
+
+// Mission is a named task a ShipBot runs until it reports back to the command loop.
+type Mission struct {
+	Name string
+	Run  func(sb *ShipBot, sbCh chan ShipBot)
+}
+
+// AgentSnapshot is the agent-wide state a Strategy decides from.
+type AgentSnapshot struct {
+	Agent     m.Agent
+	Contracts []m.Contract
+	Plan      DeliveryPlan
+	Markets   []store.MarketObservation
+	// Home is the agent's home base, or nil if it is not loaded.
+	Home *HomeBase
+}
+
+// Strategy decides a ShipBot's next mission when it reports to the command loop.
+type Strategy interface {
+	Decide(sb *ShipBot, snapshot AgentSnapshot) Mission
+}
+
+// newStrategy returns the strategy called name, which is one of config.Strategies.
+func newStrategy(name string) (Strategy, error) {
+	switch name {
+	case "mining":
+		return MiningStrategy{}, nil
+	case "contract":
+		return ContractStrategy{}, nil
+	case "trading":
+		return TradingStrategy{}, nil
+	case "exploring":
+		return ExploringStrategy{}, nil
+	}
+
+	return nil, fmt.Errorf("unknown strategy %q; must be one of %s", name, strings.Join(config.Strategies, ", "))
+}
+
+/*
+🎛️ StrategySelector
+*/
+
+// StrategySelector holds the strategy each role follows. Changes take effect on each ship's next dispatch.
+type StrategySelector struct {
+	mu       sync.RWMutex
+	fallback string
+	byRole   map[string]string
+}
+
+// NewStrategySelector creates a StrategySelector from the configured strategy and per-role overrides.
+func NewStrategySelector(cfg *config.Config) *StrategySelector {
+	s := &StrategySelector{}
+	s.Configure(cfg)
+
+	return s
+}
+
+// Configure resets the strategies to the configured strategy and per-role overrides, discarding those set
+// with SetStrategy.
+func (s *StrategySelector) Configure(cfg *config.Config) {
+	byRole := make(map[string]string)
+	for role, override := range cfg.Roles {
+		if override.Strategy != nil {
+			byRole[role] = *override.Strategy
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fallback, s.byRole = cfg.Strategy, byRole
+}
+
+// For returns the strategy ships of a role follow.
+func (s *StrategySelector) For(role string) Strategy {
+	strategy, err := newStrategy(s.Name(role))
+	if err != nil {
+		// Names are validated when set, so this only happens for a strategy removed from the code.
+		return MiningStrategy{}
+	}
+
+	return strategy
+}
+
+// Name returns the name of the strategy ships of a role follow.
+func (s *StrategySelector) Name(role string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if name, ok := s.byRole[role]; ok {
+		return name
+	}
+
+	return s.fallback
+}
+
+// Strategies returns the strategy of ships without a role override, and the overrides by role.
+func (s *StrategySelector) Strategies() (string, map[string]string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	byRole := make(map[string]string, len(s.byRole))
+	for role, name := range s.byRole {
+		byRole[role] = name
+	}
+
+	return s.fallback, byRole
+}
+
+// SetStrategy switches the strategy of a role, or of ships without a role override when role is empty.
+func (s *StrategySelector) SetStrategy(role string, name string) error {
+	if _, err := newStrategy(name); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if role == "" {
+		s.fallback = name
+	} else {
+		s.byRole[role] = name
+	}
+
+	return nil
+}
+
+/*
+🧭 Missions
+*/
+
+var (
+	idleMission           = Mission{Name: "Idle", Run: (*ShipBot).Idle}
+	dockMission           = Mission{Name: "Dock ship", Run: (*ShipBot).DockShip}
+	sellMission           = Mission{Name: "Sell cargo", Run: (*ShipBot).SellCargo}
+	extractMission        = Mission{Name: "Extract resources", Run: (*ShipBot).ExtractResources}
+	miningTargetMission   = Mission{Name: "Navigate to mining target", Run: (*ShipBot).NavigateToMiningTarget}
+	tradeMission          = Mission{Name: "Trade", Run: (*ShipBot).RunTradeMission}
+	exploreMission        = Mission{Name: "Explore system", Run: (*ShipBot).ExploreSystem}
+	nearestMarketMission  = Mission{Name: "Navigate to nearest marketplace", Run: func(sb *ShipBot, sbCh chan ShipBot) { sb.NavigateToNearestWaypointWithTrait("MARKETPLACE", sbCh) }}
+	deliverMissionPrefix  = "Deliver"
+	navigateMissionPrefix = "Navigate to"
+)
+
+// navigateMission sends a ship to a waypoint.
+func navigateMission(waypointSymbol string) Mission {
+	return Mission{
+		Name: fmt.Sprintf("%s %s", navigateMissionPrefix, waypointSymbol),
+		Run:  func(sb *ShipBot, sbCh chan ShipBot) { sb.NavigateTo(waypointSymbol, sbCh) },
+	}
+}
+
+// deliverMission delivers cargo to a contract.
+func deliverMission(delivery Delivery) Mission {
+	return Mission{
+		Name: fmt.Sprintf("%s %d %s to %s", deliverMissionPrefix, delivery.Units, delivery.TradeSymbol, delivery.Destination),
+		Run:  func(sb *ShipBot, sbCh chan ShipBot) { sb.DeliverContract(delivery, sbCh) },
+	}
+}
+
+/*
+⛏ MiningStrategy
+*/
+
+// MiningStrategy has excavators mine and sell at the nearest marketplace. Other ships, and excavators without
+// a hold or a mining laser, idle once they have nothing left to sell.
+type MiningStrategy struct{}
+
+func (MiningStrategy) Decide(sb *ShipBot, snapshot AgentSnapshot) Mission {
+	if sb.ship.Registration.Role != "EXCAVATOR" || !sb.CanHaulCargo() {
+		return idleMission
+	}
+
+	full := sb.IsCargoAtThreshold() && !sb.ship.Cargo.IsEmpty()
+
+	switch {
+	case full && !sb.IsAtWaypointWithTrait("MARKETPLACE"):
+		return nearestMarketMission
+	case full && sb.ship.Nav.Status != "DOCKED":
+		return dockMission
+	case full:
+		return sellMission
+	case !sb.CanMine():
+		return idleMission
+	case !sb.IsAtMiningTarget():
+		return miningTargetMission
+	default:
+		return extractMission
+	}
+}
+
+/*
+📜 ContractStrategy
+*/
+
+// Delivery is cargo aboard a ship that a contract needs.
+type Delivery struct {
+	ContractID  string `json:"contractId"`
+	TradeSymbol string `json:"tradeSymbol"`
+	Destination string `json:"destination"`
+	// Units is the number of units to deliver: what is aboard, up to what the contract still requires.
+	Units int `json:"units"`
+	// Remaining is the number of units the contract still requires.
+	Remaining int `json:"remaining"`
+}
+
+// nextDelivery returns the first delivery the cargo can make to an accepted, unfulfilled contract.
+func nextDelivery(cargo m.ShipCargo, contracts []m.Contract) (Delivery, bool) {
+	for _, contract := range contracts {
+		if !contract.Accepted || contract.Fulfilled {
+			continue
+		}
+
+		for _, good := range contract.Terms.Deliver {
+			remaining := good.UnitsRequired - good.UnitsFulfilled
+			if remaining <= 0 {
+				continue
+			}
+
+			aboard := cargo.UnitsOf(good.TradeSymbol)
+			if aboard == 0 {
+				continue
+			}
+
+			units := aboard
+			if units > remaining {
+				units = remaining
+			}
+
+			return Delivery{
+				ContractID:  contract.ID,
+				TradeSymbol: good.TradeSymbol,
+				Destination: good.DestinationSymbol,
+				Units:       units,
+				Remaining:   remaining,
+			}, true
+		}
+	}
+
+	return Delivery{}, false
+}
+
+// ContractStrategy splits contract deliverables between ships by quota, each ship claiming up to a hold's worth
+// that no other ship has claimed. A ship delivers once it holds its quota or its hold is full, taking along any
+// other goods aboard that the delivery plan needs and routing each to its own destination. It buys the rest
+// of its quota where a known market sells it for less than the contract pays. Otherwise it mines like
+// MiningStrategy, selling what no contract needs.
+type ContractStrategy struct{}
+
+func (ContractStrategy) Decide(sb *ShipBot, snapshot AgentSnapshot) Mission {
+	quota, contract, ok := sb.claimQuota(snapshot.Contracts)
+	if !ok {
+		return MiningStrategy{}.Decide(sb, snapshot)
+	}
+
+	aboard := sb.ship.Cargo.UnitsOf(quota.TradeSymbol)
+	if aboard > 0 && (aboard >= quota.Units || sb.IsCargoAtThreshold()) {
+		units := aboard
+		if units > quota.Units {
+			units = quota.Units
+		}
+
+		route := sb.deliveryRoute(Delivery{
+			ContractID:  quota.ContractID,
+			TradeSymbol: quota.TradeSymbol,
+			Destination: quota.Destination,
+			Units:       units,
+			Remaining:   contract.RemainingUnits()[quota.TradeSymbol],
+		}, snapshot.Plan, snapshot.Contracts)
+		if len(route) > 1 {
+			return deliverRouteMission(route)
+		}
+
+		return deliverMission(route[0])
+	}
+
+	if source, price, ok := sb.procurementSource(snapshot.Markets, quota.TradeSymbol); ok && price <= unitPayment(contract) {
+		need := int64(quota.Units - aboard)
+		if price*need <= sb.agent.Available() {
+			return procureMission(quota, source)
+		}
+	}
+
+	if aboard == 0 {
+		// A quota is only held while the ship has its goods aboard or is buying them, so miners that may never
+		// extract the good leave it to ships that can buy it.
+		sb.quotas.Release(sb.ship.Symbol)
+	}
+
+	return MiningStrategy{}.Decide(sb, snapshot)
+}
+
+/*
+💱 TradingStrategy
+*/
+
+// TradingStrategy buys low and sells high between known markets. A ship with cargo left over first sells it
+// at the known market paying the most for it. Ships idle while no route clears the trade margin, and ships
+// without a hold always idle.
+type TradingStrategy struct{}
+
+func (TradingStrategy) Decide(sb *ShipBot, snapshot AgentSnapshot) Mission {
+	if !sb.CanHaulCargo() {
+		return idleMission
+	}
+
+	good, ok := largestLot(sb)
+	if !ok {
+		routes, err := sb.FindArbitrage(sb.ship.Nav.SystemSymbol, sb.ship.Cargo.SpaceRemaining(), sb.agent.Available())
+		if err != nil || len(routes) == 0 {
+			return idleMission
+		}
+
+		return tradeMission
+	}
+
+	market, ok := bestSellMarket(snapshot.Markets, good.Symbol, sb.ship.Nav.SystemSymbol)
+	if !ok {
+		return MiningStrategy{}.Decide(sb, snapshot)
+	}
+
+	switch {
+	case sb.ship.Nav.WaypointSymbol != market:
+		return navigateMission(market)
+	case sb.ship.Nav.Status != "DOCKED":
+		return dockMission
+	default:
+		return sellMission
+	}
+}
+
+// largestLot returns the sellable cargo item with the most units.
+func largestLot(sb *ShipBot) (m.ShipCargoItem, bool) {
+	var largest m.ShipCargoItem
+
+	for _, good := range sb.ship.Cargo.Inventory {
+		if good.Units > largest.Units && !sb.reserved[good.Symbol] {
+			largest = good
+		}
+	}
+
+	return largest, largest.Units > 0
+}
+
+// bestSellMarket returns the waypoint of the market in a system scoring highest for selling a good (see sellScore).
+func bestSellMarket(markets []store.MarketObservation, symbol string, systemSymbol string) (string, bool) {
+	var best string
+	var bestScore float64
+
+	for _, observation := range markets {
+		if !strings.HasPrefix(observation.Market.Symbol, systemSymbol+"-") {
+			continue
+		}
+
+		if score, ok := sellScore(observation.Market, symbol); ok && (best == "" || score > bestScore) {
+			best, bestScore = observation.Market.Symbol, score
+		}
+	}
+
+	return best, best != ""
+}
+
+// sellSupplyPenalty discounts the sell price of a market whose supply of a good is already high. Every lot sold
+// there pushes its supply up and its price down, so it scores below a market paying a little less whose
+// supply is still short, and sales spread across markets instead of crashing one.
+var sellSupplyPenalty = map[string]float64{
+	m.SupplyHigh:     0.85,
+	m.SupplyAbundant: 0.6,
+}
+
+// sellScore scores a market for selling a good: its recorded sell price, discounted by sellSupplyPenalty.
+// It reports false when the market has no price for the good.
+func sellScore(market m.Market, symbol string) (float64, bool) {
+	price, ok := market.SellPriceOf(symbol)
+	if !ok {
+		return 0, false
+	}
+
+	score := float64(price)
+	if supply, ok := market.SupplyOf(symbol); ok {
+		if penalty, ok := sellSupplyPenalty[supply]; ok {
+			score *= penalty
+		}
+	}
+
+	return score, true
+}
+
+/*
+🔭 ExploringStrategy
+*/
+
+// ExploringStrategy has ships chart the uncharted waypoints of their system, idling once it is fully charted.
+// It suits probes and satellites, which burn no fuel.
+type ExploringStrategy struct{}
+
+func (ExploringStrategy) Decide(sb *ShipBot, snapshot AgentSnapshot) Mission {
+	uncharted, err := sb.unchartedWaypoints()
+	if err != nil || len(uncharted) == 0 {
+		return idleMission
+	}
+
+	return exploreMission
+}
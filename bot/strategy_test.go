@@ -0,0 +1,295 @@
+package bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/ledger"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/server"
+	"github.com/GeoffreyDick/gogarin/status"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+// strategyWaypoints is a system with a marketplace planet, an asteroid field, and a marketplace moon.
+var strategyWaypoints = []m.Waypoint{
+	{Symbol: "X1-A-1", SystemSymbol: "X1-A", Type: "PLANET", Traits: []m.WaypointTrait{{Symbol: "MARKETPLACE"}}},
+	{Symbol: "X1-A-2", SystemSymbol: "X1-A", Type: "ASTEROID_FIELD", X: 10},
+	{Symbol: "X1-A-3", SystemSymbol: "X1-A", Type: "MOON", X: 20, Traits: []m.WaypointTrait{{Symbol: "MARKETPLACE"}}},
+}
+
+// strategyShip returns a ShipBot of a role at a waypoint of strategyWaypoints, holding cargo in a 30-unit hold.
+// Excavators carry a mining laser.
+func strategyShip(t *testing.T, cfg *config.Config, role string, waypointSymbol string, navStatus string, cargo ...m.ShipCargoItem) *ShipBot {
+	t.Helper()
+
+	ship := m.Ship{Symbol: "GOGARIN-2"}
+	ship.Registration.Role = role
+	if role == "EXCAVATOR" {
+		ship.Mounts = []m.ShipMount{{Symbol: "MOUNT_MINING_LASER_I"}}
+	}
+	ship.Nav = m.ShipNav{SystemSymbol: "X1-A", WaypointSymbol: waypointSymbol, Status: navStatus}
+	ship.Cargo = m.ShipCargo{Capacity: 30, Inventory: cargo}
+	for _, item := range cargo {
+		ship.Cargo.Units += item.Units
+	}
+
+	c := &contractClient{waypoints: strategyWaypoints}
+
+	return NewShipBot(c, &ship, store.NewAgentState(m.Agent{}, 0), store.NewSystemKnowledge(c), store.NewMarketStore(), nil, cfg)
+}
+
+// contractFor is an accepted contract for units of a good delivered to X1-A-3, of which fulfilled are done.
+func contractFor(good string, units int, fulfilled int) m.Contract {
+	return m.Contract{ID: "c-1", Accepted: true, Terms: m.ContractTerms{Deliver: []m.ContractDeliverGood{
+		{TradeSymbol: good, DestinationSymbol: "X1-A-3", UnitsRequired: units, UnitsFulfilled: fulfilled},
+	}}}
+}
+
+// observed records markets buying goods at prices, as "WAYPOINT GOOD PRICE".
+func observed(offers ...string) []store.MarketObservation {
+	var markets []store.MarketObservation
+	for _, offer := range offers {
+		fields := strings.Fields(offer)
+		waypoint, good := fields[0], fields[1]
+		price, _ := strconv.ParseInt(fields[2], 10, 64)
+		markets = append(markets, store.MarketObservation{
+			Market:     m.Market{Symbol: waypoint, TradeGoods: []m.MarketTradeGood{{Symbol: good, SellPrice: price}}},
+			ObservedAt: time.Now(),
+		})
+	}
+	return markets
+}
+
+func TestMiningStrategy(t *testing.T) {
+	cfg := config.Default()
+
+	ore := m.ShipCargoItem{Symbol: "IRON_ORE", Units: 30}
+	tests := []struct {
+		name string
+		sb   *ShipBot
+		want string
+	}{
+		{"command ship", strategyShip(t, cfg, "COMMAND", "X1-A-1", "DOCKED"), "Idle"},
+		{"empty away from a field", strategyShip(t, cfg, "EXCAVATOR", "X1-A-1", "IN_ORBIT"), "Navigate to mining target"},
+		{"empty at a field", strategyShip(t, cfg, "EXCAVATOR", "X1-A-2", "IN_ORBIT"), "Extract resources"},
+		{"full at a field", strategyShip(t, cfg, "EXCAVATOR", "X1-A-2", "IN_ORBIT", ore), "Navigate to nearest marketplace"},
+		{"full in orbit at a market", strategyShip(t, cfg, "EXCAVATOR", "X1-A-1", "IN_ORBIT", ore), "Dock ship"},
+		{"full docked at a market", strategyShip(t, cfg, "EXCAVATOR", "X1-A-1", "DOCKED", ore), "Sell cargo"},
+	}
+	for _, tt := range tests {
+		if got := (MiningStrategy{}).Decide(tt.sb, AgentSnapshot{}); got.Name != tt.want {
+			t.Errorf("%s: mission %q, want %q", tt.name, got.Name, tt.want)
+		}
+	}
+}
+
+func TestShipsWithoutAHoldOrLaserAreNotSentToMineOrSell(t *testing.T) {
+	cfg := config.Default()
+
+	// A probe has neither a hold nor mounts; a shuttle has a hold but no mining laser.
+	probe := func(waypointSymbol string) *ShipBot {
+		sb := strategyShip(t, cfg, "EXCAVATOR", waypointSymbol, "IN_ORBIT")
+		sb.ship.Mounts = nil
+		sb.ship.Cargo = m.ShipCargo{}
+		sb.capabilities = m.CapabilitiesOf(*sb.ship)
+		return sb
+	}
+	shuttle := func(waypointSymbol string, cargo ...m.ShipCargoItem) *ShipBot {
+		sb := strategyShip(t, cfg, "EXCAVATOR", waypointSymbol, "IN_ORBIT", cargo...)
+		sb.ship.Mounts = nil
+		sb.capabilities = m.CapabilitiesOf(*sb.ship)
+		return sb
+	}
+	if probe("X1-A-2").CanHaulCargo() || probe("X1-A-2").CanMine() || probe("X1-A-2").CanSurvey() {
+		t.Error("a probe reports a hold, a mining laser or a surveyor")
+	}
+	if sb := shuttle("X1-A-2"); !sb.CanHaulCargo() || sb.CanMine() {
+		t.Error("a shuttle does not report its hold, or reports a mining laser")
+	}
+
+	nonsense := map[string]bool{
+		extractMission.Name:       true,
+		miningTargetMission.Name:  true,
+		sellMission.Name:          true,
+		dockMission.Name:          true,
+		nearestMarketMission.Name: true,
+		tradeMission.Name:         true,
+	}
+	snapshot := AgentSnapshot{Contracts: []m.Contract{contractFor("COPPER_ORE", 10, 0)}}
+	for _, strategy := range config.Strategies {
+		cfg.Strategy = strategy
+		strategies := NewStrategySelector(cfg)
+
+		ships := map[string]*ShipBot{
+			"probe at a field":              probe("X1-A-2"),
+			"probe at a market":             probe("X1-A-1"),
+			"empty shuttle at a field":      shuttle("X1-A-2"),
+			"empty shuttle at a market":     shuttle("X1-A-1"),
+			"part-laden shuttle at a field": shuttle("X1-A-2", m.ShipCargoItem{Symbol: "IRON_ORE", Units: 5}),
+		}
+		for name, sb := range ships {
+			if got := strategies.For("EXCAVATOR").Decide(sb, snapshot); nonsense[got.Name] {
+				t.Errorf("%s %s: mission %q", strategy, name, got.Name)
+			}
+		}
+	}
+
+	// A shuttle with a full hold still sells what it carries.
+	cfg.Strategy = "mining"
+	sb := shuttle("X1-A-1", m.ShipCargoItem{Symbol: "IRON_ORE", Units: 30})
+	sb.ship.Nav.Status = "DOCKED"
+	if got := NewStrategySelector(cfg).For("EXCAVATOR").Decide(sb, AgentSnapshot{}); got.Name != sellMission.Name {
+		t.Errorf("full shuttle docked at a market: mission %q, want %q", got.Name, sellMission.Name)
+	}
+}
+
+func TestMiningStrategyMinesEveryAsteroidType(t *testing.T) {
+	tests := []struct {
+		waypointType string
+		types        []string
+		want         string
+	}{
+		{"ASTEROID_FIELD", nil, "Extract resources"},
+		{"ASTEROID", nil, "Extract resources"},
+		{"ENGINEERED_ASTEROID", nil, "Extract resources"},
+		{"ASTEROID_BASE", nil, "Extract resources"},
+		{"PLANET", nil, "Navigate to mining target"},
+		{"GAS_GIANT", []string{"GAS_GIANT"}, "Extract resources"},
+		{"ASTEROID", []string{"GAS_GIANT"}, "Navigate to mining target"},
+	}
+	for _, tt := range tests {
+		cfg := config.Default()
+		cfg.MiningTargetTypes = tt.types
+
+		sb := strategyShip(t, cfg, "EXCAVATOR", "X1-A-2", "IN_ORBIT")
+		c := &contractClient{waypoints: []m.Waypoint{{Symbol: "X1-A-2", SystemSymbol: "X1-A", Type: tt.waypointType}}}
+		sb.systems = store.NewSystemKnowledge(c)
+
+		if got := (MiningStrategy{}).Decide(sb, AgentSnapshot{}); got.Name != tt.want {
+			t.Errorf("at %s mining %v: mission %q, want %q", tt.waypointType, tt.types, got.Name, tt.want)
+		}
+	}
+}
+
+func TestContractStrategy(t *testing.T) {
+	cfg := config.Default()
+
+	tests := []struct {
+		name      string
+		cargo     []m.ShipCargoItem
+		contracts []m.Contract
+		want      string
+	}{
+		{"everything the contract needs", []m.ShipCargoItem{{Symbol: "COPPER_ORE", Units: 8}}, []m.Contract{contractFor("COPPER_ORE", 10, 4)}, "Deliver 6 COPPER_ORE to X1-A-3"},
+		{"part of what the contract needs", []m.ShipCargoItem{{Symbol: "COPPER_ORE", Units: 8}}, []m.Contract{contractFor("COPPER_ORE", 50, 0)}, "Extract resources"},
+		{"a full hold with some contract goods", []m.ShipCargoItem{{Symbol: "COPPER_ORE", Units: 8}, {Symbol: "IRON_ORE", Units: 22}}, []m.Contract{contractFor("COPPER_ORE", 50, 0)}, "Deliver 8 COPPER_ORE to X1-A-3"},
+		{"no contract goods", []m.ShipCargoItem{{Symbol: "IRON_ORE", Units: 8}}, []m.Contract{contractFor("COPPER_ORE", 10, 0)}, "Extract resources"},
+		{"a contract not accepted", []m.ShipCargoItem{{Symbol: "COPPER_ORE", Units: 8}}, []m.Contract{{Terms: contractFor("COPPER_ORE", 8, 0).Terms}}, "Extract resources"},
+		{"a contract fully delivered", []m.ShipCargoItem{{Symbol: "COPPER_ORE", Units: 8}}, []m.Contract{contractFor("COPPER_ORE", 8, 8)}, "Extract resources"},
+	}
+	for _, tt := range tests {
+		sb := strategyShip(t, cfg, "EXCAVATOR", "X1-A-2", "IN_ORBIT", tt.cargo...)
+		if got := (ContractStrategy{}).Decide(sb, AgentSnapshot{Contracts: tt.contracts}); got.Name != tt.want {
+			t.Errorf("%s: mission %q, want %q", tt.name, got.Name, tt.want)
+		}
+	}
+}
+
+func TestTradingStrategy(t *testing.T) {
+	cfg := config.Default()
+
+	ore := []m.ShipCargoItem{{Symbol: "ICE_WATER", Units: 3}, {Symbol: "IRON_ORE", Units: 12}}
+	markets := observed("X1-A-1 IRON_ORE 40", "X1-A-3 IRON_ORE 52", "X1-B-1 IRON_ORE 90", "X1-A-1 ICE_WATER 70")
+
+	tests := []struct {
+		name    string
+		sb      *ShipBot
+		markets []store.MarketObservation
+		want    string
+	}{
+		{"nothing to sell", strategyShip(t, cfg, "HAULER", "X1-A-1", "DOCKED"), markets, "Idle"},
+		{"best market elsewhere", strategyShip(t, cfg, "HAULER", "X1-A-1", "DOCKED", ore...), markets, "Navigate to X1-A-3"},
+		{"in orbit at the best market", strategyShip(t, cfg, "HAULER", "X1-A-3", "IN_ORBIT", ore...), markets, "Dock ship"},
+		{"docked at the best market", strategyShip(t, cfg, "HAULER", "X1-A-3", "DOCKED", ore...), markets, "Sell cargo"},
+		{"no known market for the good", strategyShip(t, cfg, "EXCAVATOR", "X1-A-2", "IN_ORBIT", ore...), observed("X1-A-1 ICE_WATER 70"), "Extract resources"},
+	}
+	for _, tt := range tests {
+		if got := (TradingStrategy{}).Decide(tt.sb, AgentSnapshot{Markets: tt.markets}); got.Name != tt.want {
+			t.Errorf("%s: mission %q, want %q", tt.name, got.Name, tt.want)
+		}
+	}
+
+	// Reserved goods are never traded, however many are aboard.
+	sb := strategyShip(t, cfg, "HAULER", "X1-A-1", "DOCKED", m.ShipCargoItem{Symbol: "ANTIMATTER", Units: 20}, m.ShipCargoItem{Symbol: "ICE_WATER", Units: 3})
+	if got := (TradingStrategy{}).Decide(sb, AgentSnapshot{Markets: markets}); got.Name != "Sell cargo" {
+		t.Errorf("with reserved goods aboard: mission %q, want to sell the ICE_WATER at X1-A-1", got.Name)
+	}
+
+	// An empty ship trades once a route clears the margin.
+	sb = strategyShip(t, cfg, "HAULER", "X1-A-1", "DOCKED")
+	sb.agent = store.NewAgentState(m.Agent{Credits: 10000}, 0)
+	for _, observation := range []store.MarketObservation{
+		tradeMarket("X1-A-1", time.Minute, "IRON_ORE 10 8", "FUEL 2 1"),
+		tradeMarket("X1-A-3", time.Minute, "IRON_ORE 60 50"),
+	} {
+		sb.markets.Record(observation.Market, observation.ObservedAt)
+	}
+	if got := (TradingStrategy{}).Decide(sb, AgentSnapshot{Markets: sb.markets.All()}); got.Name != "Trade" {
+		t.Errorf("with a profitable route: mission %q, want Trade", got.Name)
+	}
+}
+
+func TestStrategySelector(t *testing.T) {
+	cfg := config.Default()
+	trading := "trading"
+	cfg.Strategy = "contract"
+	cfg.Roles = map[string]config.RoleConfig{"HAULER": {Strategy: &trading}}
+
+	s := NewStrategySelector(cfg)
+	if _, ok := s.For("EXCAVATOR").(ContractStrategy); !ok {
+		t.Errorf("EXCAVATOR follows %T, want the configured ContractStrategy", s.For("EXCAVATOR"))
+	}
+	if _, ok := s.For("HAULER").(TradingStrategy); !ok {
+		t.Errorf("HAULER follows %T, want its TradingStrategy override", s.For("HAULER"))
+	}
+
+	if err := s.SetStrategy("EXCAVATOR", "hoarding"); err == nil {
+		t.Error("switched to an unknown strategy")
+	}
+	if err := s.SetStrategy("", "mining"); err != nil {
+		t.Fatal(err)
+	}
+	if fallback, byRole := s.Strategies(); fallback != "mining" || len(byRole) != 1 || byRole["HAULER"] != "trading" {
+		t.Errorf("strategies = %s %v, want mining with HAULER trading", fallback, byRole)
+	}
+}
+
+func TestStrategySwitchTakesEffectOnTheNextDispatch(t *testing.T) {
+	cfg := config.Default()
+
+	strategies := NewStrategySelector(cfg)
+	srv := server.New("", status.NewBoard(), ledger.New(0), nil, server.WithStrategies(strategies))
+
+	sb := strategyShip(t, cfg, "EXCAVATOR", "X1-A-2", "IN_ORBIT", m.ShipCargoItem{Symbol: "IRON_ORE", Units: 12})
+	snapshot := AgentSnapshot{Markets: observed("X1-A-3 IRON_ORE 52")}
+
+	if got := strategies.For("EXCAVATOR").Decide(sb, snapshot); got.Name != "Extract resources" {
+		t.Fatalf("mining: mission %q, want Extract resources", got.Name)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/strategy", strings.NewReader(`{"role":"EXCAVATOR","strategy":"trading"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("switching strategy: status %d, %s", rec.Code, rec.Body)
+	}
+
+	if got := strategies.For("EXCAVATOR").Decide(sb, snapshot); got.Name != "Navigate to X1-A-3" {
+		t.Errorf("after switching to trading: mission %q, want Navigate to X1-A-3", got.Name)
+	}
+}
@@ -0,0 +1,148 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/lib"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+/*
+🗺️ System summary
+*/
+
+// SummarizeSystem sums up the waypoints of a system by what they offer, measuring distances from the waypoint
+// from, or from the system's origin if from is not among them. sellsFuel decides which waypoints sell fuel; a
+// nil sellsFuel counts only fuel stations.
+func SummarizeSystem(systemSymbol string, from string, waypoints []m.Waypoint, sellsFuel func(m.Waypoint) bool) m.SystemSummary {
+	if sellsFuel == nil {
+		sellsFuel = func(waypoint m.Waypoint) bool { return waypoint.IsType("FUEL_STATION") }
+	}
+
+	var origin lib.Coordinate
+	for _, waypoint := range waypoints {
+		if waypoint.Symbol == from {
+			origin = lib.NewCoordinate(waypoint.X, waypoint.Y)
+		}
+	}
+
+	summary := m.SystemSummary{System: systemSymbol, From: from, Waypoints: len(waypoints)}
+	for _, waypoint := range waypoints {
+		entry := m.SummaryWaypoint{
+			Symbol:   waypoint.Symbol,
+			Type:     waypoint.Type,
+			Distance: lib.Distance(origin, lib.NewCoordinate(waypoint.X, waypoint.Y)),
+		}
+
+		if waypoint.HasTrait("MARKETPLACE") {
+			summary.Marketplaces = append(summary.Marketplaces, entry)
+		}
+		if waypoint.HasTrait("SHIPYARD") {
+			summary.Shipyards = append(summary.Shipyards, entry)
+		}
+		if waypoint.IsAnyType(m.MiningTargetTypes...) {
+			summary.Minable = append(summary.Minable, entry)
+		}
+		if sellsFuel(waypoint) {
+			summary.FuelSellers = append(summary.FuelSellers, entry)
+		}
+		if waypoint.IsType("JUMP_GATE") {
+			summary.JumpGates = append(summary.JumpGates, entry)
+		}
+		if waypoint.HasTrait("UNCHARTED") {
+			summary.Uncharted = append(summary.Uncharted, entry)
+		}
+	}
+
+	for _, kind := range [][]m.SummaryWaypoint{summary.Marketplaces, summary.Shipyards, summary.Minable, summary.FuelSellers, summary.JumpGates, summary.Uncharted} {
+		sortByDistance(kind)
+	}
+
+	return summary
+}
+
+// sortByDistance sorts waypoints nearest first, then by symbol.
+func sortByDistance(waypoints []m.SummaryWaypoint) {
+	sort.Slice(waypoints, func(i, j int) bool {
+		if waypoints[i].Distance != waypoints[j].Distance {
+			return waypoints[i].Distance < waypoints[j].Distance
+		}
+
+		return waypoints[i].Symbol < waypoints[j].Symbol
+	})
+}
+
+// RenderSystemSummary renders a system summary as an aligned table with a row per kind of waypoint, listing
+// each waypoint nearest first with its rounded distance. Kinds the system has none of show "-". Uncharted
+// waypoints are called out, since the other kinds cannot be complete until they are explored.
+func RenderSystemSummary(summary m.SystemSummary) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "System %s: %d waypoints, distances from %s.\n", summary.System, summary.Waypoints, summary.From)
+
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tCOUNT\tWAYPOINTS")
+	for _, row := range []struct {
+		kind      string
+		waypoints []m.SummaryWaypoint
+	}{
+		{"MARKETPLACES", summary.Marketplaces},
+		{"SHIPYARDS", summary.Shipyards},
+		{"MINABLE", summary.Minable},
+		{"FUEL", summary.FuelSellers},
+		{"JUMP_GATES", summary.JumpGates},
+		{"UNCHARTED", summary.Uncharted},
+	} {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", row.kind, len(row.waypoints), summaryList(row.waypoints))
+	}
+	tw.Flush()
+
+	if len(summary.Uncharted) > 0 {
+		fmt.Fprintf(&b, "%d waypoints are uncharted. Explore the system to chart them.\n", len(summary.Uncharted))
+	}
+
+	return b.String()
+}
+
+// summaryList lists waypoints as "SYMBOL (distance)", or "-" for none.
+func summaryList(waypoints []m.SummaryWaypoint) string {
+	if len(waypoints) == 0 {
+		return "-"
+	}
+
+	items := make([]string, 0, len(waypoints))
+	for _, waypoint := range waypoints {
+		items = append(items, fmt.Sprintf("%s (%.0f)", waypoint.Symbol, waypoint.Distance))
+	}
+
+	return strings.Join(items, ", ")
+}
+
+// SummarizeHomeSystem logs and publishes a summary of the headquarters system, from the waypoints the home
+// base was resolved from.
+func (ab *AgentBot) SummarizeHomeSystem() error {
+	headquarters := ab.agent.Agent().Headquarters
+
+	systemSymbol, err := lib.SystemSymbolOf(headquarters)
+	if err != nil {
+		return err
+	}
+
+	waypoints, err := ab.systems.Waypoints(systemSymbol)
+	if err != nil {
+		return err
+	}
+
+	fuel := store.NewFuelStations(ab.systems, ab.markets)
+	summary := SummarizeSystem(systemSymbol, headquarters, waypoints, fuel.SellsFuel)
+
+	ab.logger.Info("🗺️ Home system:\n" + RenderSystemSummary(summary))
+	ab.bus.Publish(event.Event{Type: event.SystemSummarized, Message: systemSymbol, Data: summary})
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the current output")
+
+// golden compares got with the golden file testdata/name, rewriting it instead with -update.
+func golden(t *testing.T, name string, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Fatalf("output differs from %s (rerun with -update to accept it):\n--- got\n%s--- want\n%s", path, got, want)
+	}
+}
+
+func TestRenderSystemSummary(t *testing.T) {
+	waypoints := append(fixture(t).Waypoints,
+		m.Waypoint{Symbol: "X1-MK1-E5", Type: "FUEL_STATION", X: 3, Y: -4, Traits: []m.WaypointTrait{{Symbol: "MARKETPLACE"}}},
+		m.Waypoint{Symbol: "X1-MK1-F6", Type: "JUMP_GATE", X: 60, Y: 80},
+		m.Waypoint{Symbol: "X1-MK1-G7", Type: "ASTEROID", X: -6, Y: 8, Traits: []m.WaypointTrait{{Symbol: "UNCHARTED"}}},
+	)
+
+	golden(t, "system_summary.golden", RenderSystemSummary(SummarizeSystem("X1-MK1", "X1-MK1-A1", waypoints, nil)))
+}
+
+func TestRenderChartedSystemSummary(t *testing.T) {
+	// Measured from a waypoint the system does not list, distances are from its origin.
+	golden(t, "system_summary_charted.golden", RenderSystemSummary(SummarizeSystem("X1-MK1", "X1-MK1-Z9", fixture(t).Waypoints, nil)))
+}
@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"sync"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+/*
+🎯 Targets
+*/
+
+// Targets spreads ships across mining targets, tracking the target each ship is heading to or mining at so a
+// crowded target can be passed over for the next-best one. A ship's claim ends when it navigates anywhere else.
+// Methods are safe to call on a nil Targets, which tracks nothing and always grants the best target.
+type Targets struct {
+	mu sync.Mutex
+	// claims are the target each ship is heading to or mining at, keyed by ship symbol.
+	claims map[string]string
+}
+
+// NewTargets creates an empty Targets.
+func NewTargets() *Targets {
+	return &Targets{claims: make(map[string]string)}
+}
+
+// Claim claims the first of ranked that fewer than max other ships have claimed for a ship, replacing any
+// target it had. If every target is full, the ship claims the first anyway. Zero max means no limit. It
+// returns nil if ranked is empty.
+func (t *Targets) Claim(ship string, ranked []m.Waypoint, max int) *m.Waypoint {
+	if len(ranked) == 0 {
+		return nil
+	}
+	if t == nil {
+		return &ranked[0]
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	chosen := &ranked[0]
+	if max > 0 {
+		for i := range ranked {
+			if t.count(ranked[i].Symbol, ship) < max {
+				chosen = &ranked[i]
+				break
+			}
+		}
+	}
+	t.claims[ship] = chosen.Symbol
+
+	return chosen
+}
+
+// Leave ends a ship's claim, unless it is on waypoint, where the ship is still heading.
+func (t *Targets) Leave(ship string, waypointSymbol string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.claims[ship] != waypointSymbol {
+		delete(t.claims, ship)
+	}
+}
+
+// Count returns the number of ships that have claimed a target.
+func (t *Targets) Count(waypointSymbol string) int {
+	if t == nil {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.count(waypointSymbol, "")
+}
+
+// count returns the number of ships other than except that have claimed a target. It must be called with mu
+// held.
+func (t *Targets) count(waypointSymbol string, except string) int {
+	var n int
+	for ship, target := range t.claims {
+		if target == waypointSymbol && ship != except {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+func TestMiningShipsSpreadAcrossFields(t *testing.T) {
+	const ships = 5
+
+	f := fixture(t)
+	// A second, slightly farther field with the same deposits as the first.
+	for _, waypoint := range f.Waypoints {
+		if waypoint.Symbol == "X1-MK1-B2" {
+			waypoint.Symbol, waypoint.X, waypoint.Y = "X1-MK1-E5", -12, 9
+			waypoint.Chart.WaypointSymbol = waypoint.Symbol
+			f.Waypoints = append(f.Waypoints, waypoint)
+			break
+		}
+	}
+	for i := len(f.Ships); i < ships+1; i++ {
+		ship := f.Ships[1]
+		ship.Symbol = fmt.Sprintf("MOCK-%d", i+1)
+		f.Ships = append(f.Ships, ship)
+	}
+
+	ab, server, _ := agentBot(t, f)
+
+	fields := make(map[string]int)
+	for i := 2; i <= ships+1; i++ {
+		sb := ab.newShipBot(t, server, fmt.Sprintf("MOCK-%d", i))
+		sb.arrival = nil
+		sb.targets = ab.targets
+
+		sbCh := make(chan ShipBot, 1)
+		sb.NavigateToMiningTarget(sbCh)
+		reported := <-sbCh
+		if reported.failure != nil {
+			t.Fatalf("%s failed to reach a mining target: %s", reported.ship.Symbol, reported.failure)
+		}
+		fields[reported.ship.Nav.WaypointSymbol]++
+	}
+
+	if want := map[string]int{"X1-MK1-B2": 3, "X1-MK1-E5": 2}; !reflect.DeepEqual(fields, want) {
+		t.Fatalf("ships per field %v, want %v", fields, want)
+	}
+	if n := ab.targets.Count("X1-MK1-B2"); n != 3 {
+		t.Fatalf("%d claims on X1-MK1-B2, want 3", n)
+	}
+}
+
+func TestTargetClaimsDecayAsShipsLeave(t *testing.T) {
+	targets := NewTargets()
+	ranked := []m.Waypoint{{Symbol: "B2"}, {Symbol: "E5"}}
+
+	for _, ship := range []string{"S-1", "S-2"} {
+		if got := targets.Claim(ship, ranked, 2); got.Symbol != "B2" {
+			t.Fatalf("%s claimed %s, want B2", ship, got.Symbol)
+		}
+	}
+	if got := targets.Claim("S-3", ranked, 2); got.Symbol != "E5" {
+		t.Fatalf("S-3 claimed %s, want E5 with B2 full", got.Symbol)
+	}
+	// A ship claiming again does not count against itself.
+	if got := targets.Claim("S-1", ranked, 2); got.Symbol != "B2" {
+		t.Fatalf("S-1 reclaimed %s, want B2", got.Symbol)
+	}
+
+	// Setting off for the claimed field keeps the claim; setting off anywhere else ends it.
+	targets.Leave("S-1", "B2")
+	targets.Leave("S-2", "X1-MK1-A1")
+	if n := targets.Count("B2"); n != 1 {
+		t.Fatalf("%d claims on B2, want S-1's alone", n)
+	}
+	if got := targets.Claim("S-4", ranked, 2); got.Symbol != "B2" {
+		t.Fatalf("S-4 claimed %s, want B2 once S-2 left", got.Symbol)
+	}
+
+	// With every field full, the best is claimed anyway.
+	targets.Claim("S-5", ranked, 2)
+	if got := targets.Claim("S-6", ranked, 2); got.Symbol != "B2" {
+		t.Fatalf("S-6 claimed %s, want B2 with every field full", got.Symbol)
+	}
+}
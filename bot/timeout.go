@@ -0,0 +1,174 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/boterr"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+/*
+⏱️ Mission timeouts
+*/
+
+// errMissionTimeout is the error a mission that runs past its deadline fails with.
+var errMissionTimeout = errors.New("mission timed out")
+
+// missionWatch is the deadline of a ShipBot's current mission, shared by every copy of the ShipBot. Methods
+// are safe to call on a nil missionWatch, which never times out.
+type missionWatch struct {
+	mu       sync.Mutex
+	deadline time.Time
+	// slack is allowed past the end of every wait the mission expects.
+	slack    time.Duration
+	finished bool
+	timedOut bool
+}
+
+// missionBudget returns how long a mission may run before its deadline is first extended, or zero if it
+// never times out. Navigation missions get the slack until their flight starts, then its arrival plus slack.
+func missionBudget(mission string, timeouts config.MissionTimeoutConfig) time.Duration {
+	switch {
+	case mission == extractMission.Name:
+		return timeouts.Extraction
+	case mission == sellMission.Name:
+		return timeouts.Sell
+	case strings.HasPrefix(mission, navigateMissionPrefix):
+		return timeouts.Slack
+	}
+
+	return timeouts.Default
+}
+
+// expect extends the deadline to the slack past until, the end of a wait the mission expects, if that is later.
+func (w *missionWatch) expect(until time.Time) {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if deadline := until.Add(w.slack); deadline.After(w.deadline) {
+		w.deadline = deadline
+	}
+}
+
+// finish marks the mission as reported in, reporting false if it had already timed out.
+func (w *missionWatch) finish() bool {
+	if w == nil {
+		return true
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timedOut {
+		return false
+	}
+	w.finished = true
+
+	return true
+}
+
+// expire times the mission out if it is still running at now and its deadline has passed. Otherwise it
+// returns the deadline to check again at, which is zero once the mission has reported in.
+func (w *missionWatch) expire(now time.Time) (time.Time, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch {
+	case w.finished:
+		return time.Time{}, false
+	case now.Before(w.deadline):
+		return w.deadline, false
+	}
+	w.timedOut = true
+
+	return w.deadline, true
+}
+
+// TimedOut reports whether the mission ran past its deadline. Missions with loops check it, so a ShipBot
+// that was timed out stops issuing orders to a ship that has been recovered.
+func (w *missionWatch) TimedOut() bool {
+	if w == nil {
+		return false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.timedOut
+}
+
+// watchMission gives a ShipBot's just dispatched mission its deadline, and schedules the check that times
+// it out if it has not reported in by then.
+func (ab *AgentBot) watchMission(sb *ShipBot) {
+	timeouts := ab.config().MissionTimeouts
+
+	sb.watch = nil
+	budget := missionBudget(sb.mission, timeouts)
+	if budget <= 0 {
+		return
+	}
+
+	watch := &missionWatch{deadline: sb.clock.Now().Add(budget), slack: timeouts.Slack}
+	sb.watch = watch
+
+	// The mission's goroutine owns the ShipBot from here, so the check works from a copy of what it needs.
+	stuck := ShipBot{ship: new(m.Ship), mission: sb.mission, missionID: sb.missionID, missionStarted: sb.missionStarted, logger: sb.logger}
+	*stuck.ship = *sb.ship
+
+	var check func()
+	check = func() {
+		deadline, expired := watch.expire(ab.clock.Now())
+		if !expired {
+			if !deadline.IsZero() {
+				ab.scheduler.At(deadline, check)
+			}
+			return
+		}
+
+		ab.recoverTimedOut(stuck)
+	}
+	ab.scheduler.At(watch.deadline, check)
+}
+
+// recoverTimedOut publishes that a mission timed out, abandons its journalled mission, and gets its ship
+// underway again on a fresh ShipBot. The timed-out ShipBot's report, if it ever makes one, is dropped.
+func (ab *AgentBot) recoverTimedOut(stuck ShipBot) {
+	duration := ab.clock.Now().Sub(stuck.missionStarted).Round(time.Second)
+	stuck.logger.Warn("Mission ended.", "outcome", "timeout", "duration", duration)
+
+	err := boterr.Wrap(fmt.Errorf("%w after %s", errMissionTimeout, duration), "ship", stuck.ship.Symbol, "mission", stuck.mission, "missionId", stuck.missionID)
+	ab.bus.Publish(event.Event{Type: event.MissionFailed, Ship: stuck.ship.Symbol, Mission: stuck.mission, MissionID: stuck.missionID, Message: err.Error(), Data: err})
+
+	if err := ab.journal.End(stuck.ship.Symbol); err != nil {
+		ab.logger.Warn("📓 Error writing journal.", "error", err)
+	}
+
+	ship := *stuck.ship
+	if fresh, err := ab.client.GetShip(ship.Symbol); err != nil {
+		stuck.logger.Error("Error re-syncing ship after timeout. Recovering it from its last known state.", "error", err)
+	} else {
+		ship = *fresh
+	}
+
+	ab.mu.Lock()
+	wake := ab.wake
+	ab.mu.Unlock()
+
+	if wake == nil {
+		return
+	}
+
+	ab.registry.Retire(ship.Symbol)
+	ab.logger.Info("⏱️ Recovering ship from timed-out mission...", "ship", ship.Symbol, "mission", stuck.mission, "missionId", stuck.missionID)
+	wake(ship)
+}
@@ -0,0 +1,448 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/boterr"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/lib"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+/*
+💱 Trade
+*/
+
+// priceCollapse is the fraction of its planned sell price below which a destination's price has collapsed,
+// sending the ship on to the next-best market.
+const priceCollapse = 0.75
+
+// errNoTradeRoute is returned when no known route clears the trade margin.
+var errNoTradeRoute = errors.New("no profitable trade route")
+
+// TradeRoute is a good bought at one market and hauled to another that pays more for it.
+type TradeRoute struct {
+	Good          string `json:"good"`
+	Source        string `json:"source"`
+	Destination   string `json:"destination"`
+	PurchasePrice int64  `json:"purchasePrice"`
+	SellPrice     int64  `json:"sellPrice"`
+	Units         int    `json:"units"`
+	// FuelCost is the estimated price of the fuel burned between the markets.
+	FuelCost int64 `json:"fuelCost"`
+	// Profit is the estimated credits earned after purchase and fuel.
+	Profit int64 `json:"profit"`
+}
+
+func (r TradeRoute) LogValues() []interface{} {
+	return []interface{}{
+		"good", r.Good,
+		"source", r.Source,
+		"destination", r.Destination,
+		"purchasePrice", r.PurchasePrice,
+		"sellPrice", r.SellPrice,
+		"units", r.Units,
+		"fuelCost", r.FuelCost,
+		"profit", r.Profit,
+	}
+}
+
+// FindArbitrage returns the routes between known markets in a system that earn more than the trade margin
+// for a hold of cargoCapacity units bought with at most credits, ordered as planArbitrage orders them.
+func (sb *ShipBot) FindArbitrage(system string, cargoCapacity int, credits int64) ([]TradeRoute, error) {
+	waypoints, err := sb.systems.Waypoints(system)
+	if err != nil {
+		return nil, err
+	}
+
+	return planArbitrage(sb.markets.All(), waypoints, sb.fuelCosts, cargoCapacity, credits, sb.tradeMaxPriceAge, sb.tradeMargin, sb.clock.Now()), nil
+}
+
+// planArbitrage pairs every good a market sells with every other market buying it, costing the fuel between
+// them by fuelCosts. Markets outside waypoints, or observed longer than maxPriceAge before now, are ignored, so
+// stale prices never plan a route. Routes buying exports and selling to importers go first.
+func planArbitrage(markets []store.MarketObservation, waypoints []m.Waypoint, fuelCosts *store.FuelCosts, cargoCapacity int, credits int64, maxPriceAge time.Duration, margin int64, now time.Time) []TradeRoute {
+	located := make(map[string]*m.Waypoint, len(waypoints))
+	for i := range waypoints {
+		located[waypoints[i].Symbol] = &waypoints[i]
+	}
+
+	var fresh []store.MarketObservation
+	for _, observation := range markets {
+		if _, ok := located[observation.Market.Symbol]; ok && now.Sub(observation.ObservedAt) <= maxPriceAge {
+			fresh = append(fresh, observation)
+		}
+	}
+
+	var routes []TradeRoute
+	var fits []int
+	for _, source := range fresh {
+		fuelPrice, ok := source.Market.PurchasePriceOf("FUEL")
+		if !ok {
+			fuelPrice = estimatedFuelPrice
+		}
+
+		for _, good := range source.Market.TradeGoods {
+			if good.PurchasePrice <= 0 {
+				continue
+			}
+
+			units := cargoCapacity
+			if affordable := credits / good.PurchasePrice; affordable < int64(units) {
+				units = int(affordable)
+			}
+			if units <= 0 {
+				continue
+			}
+
+			for _, destination := range fresh {
+				if destination.Market.Symbol == source.Market.Symbol {
+					continue
+				}
+
+				sellPrice, ok := destination.Market.SellPriceOf(good.Symbol)
+				if !ok || sellPrice <= good.PurchasePrice {
+					continue
+				}
+
+				fuel := fuelCosts.Estimate(located[source.Market.Symbol], located[destination.Market.Symbol], "CRUISE")
+				route := TradeRoute{
+					Good:          good.Symbol,
+					Source:        source.Market.Symbol,
+					Destination:   destination.Market.Symbol,
+					PurchasePrice: good.PurchasePrice,
+					SellPrice:     sellPrice,
+					Units:         units,
+					FuelCost:      int64(fuel) * fuelPrice,
+				}
+				route.Profit = int64(units)*(sellPrice-good.PurchasePrice) - route.FuelCost
+
+				if route.Profit > margin {
+					routes = append(routes, route)
+					fits = append(fits, tradeFit(&source.Market, &destination.Market, good.Symbol))
+				}
+			}
+		}
+	}
+
+	sort.Stable(byFitThenProfit{routes, fits})
+
+	return routes
+}
+
+// tradeFit rates how well a route follows the markets' supply chain: a point for buying a good its source
+// exports, and a point for selling it where the destination imports it. Such prices hold up better than
+// those of goods a market merely exchanges.
+func tradeFit(source *m.Market, destination *m.Market, symbol string) int {
+	var fit int
+	if source.TradeTypeOf(symbol) == m.TradeTypeExport {
+		fit++
+	}
+	if destination.TradeTypeOf(symbol) == m.TradeTypeImport {
+		fit++
+	}
+
+	return fit
+}
+
+// byFitThenProfit orders routes by their tradeFit, best first, then by profit, most profitable first.
+type byFitThenProfit struct {
+	routes []TradeRoute
+	fits   []int
+}
+
+func (b byFitThenProfit) Len() int { return len(b.routes) }
+
+func (b byFitThenProfit) Less(i, j int) bool {
+	if b.fits[i] != b.fits[j] {
+		return b.fits[i] > b.fits[j]
+	}
+
+	return b.routes[i].Profit > b.routes[j].Profit
+}
+
+func (b byFitThenProfit) Swap(i, j int) {
+	b.routes[i], b.routes[j] = b.routes[j], b.routes[i]
+	b.fits[i], b.fits[j] = b.fits[j], b.fits[i]
+}
+
+// RunTradeMission buys the good of the most profitable trade route at its source, hauls it to the destination
+// and sells it there. The destination price is checked again on arrival; if it has collapsed, the cargo is
+// sold at the next-best fresh market instead.
+func (sb *ShipBot) RunTradeMission(sbCh chan ShipBot) {
+	routes, err := sb.FindArbitrage(sb.ship.Nav.SystemSymbol, sb.ship.Cargo.SpaceRemaining(), sb.agent.Available())
+	if err == nil && len(routes) == 0 {
+		err = errNoTradeRoute
+	}
+	if err != nil {
+		sb.logger.Warn("💱 Error planning trade route.", "error", err)
+		sb.Fail(boterr.Wrap(err, "system", sb.ship.Nav.SystemSymbol))
+		sb.Report(sbCh)
+		return
+	}
+
+	route := routes[0]
+	sb.logger.Info("💱 Trade route planned.", route.LogValues()...)
+	sb.preview(sb.tradePlan(route))
+	sb.journalBegin(journalTrade, stepBuying, "", tradeIntent{Route: route})
+	sb.planRoute(route.Source, route.Destination)
+
+	units, err := sb.buyTradeGoods(route)
+	if err != nil {
+		sb.logger.Error("💱 Error buying trade goods.", "good", route.Good, "source", route.Source, "error", err)
+		sb.Abandon(boterr.Wrap(err, "good", route.Good, "waypoint", route.Source))
+		sb.Report(sbCh)
+		return
+	}
+
+	sb.journalStep(stepHauling, tradeIntent{Route: route, Units: units})
+	sb.ResumeTrade(route, units, sbCh)
+}
+
+// tradeIntent is the journalled intent of a trade mission. Units is set once the goods are bought.
+type tradeIntent struct {
+	Route TradeRoute `json:"route"`
+	Units int        `json:"units,omitempty"`
+}
+
+// ResumeTrade hauls units of a route's good already aboard to its destination and sells them.
+func (sb *ShipBot) ResumeTrade(route TradeRoute, units int, sbCh chan ShipBot) {
+	sb.planRoute(route.Destination)
+	if err := sb.sellTradeGoods(route, units); err != nil {
+		sb.logger.Error("💱 Error selling trade goods.", "good", route.Good, "destination", route.Destination, "error", err)
+		sb.Abandon(boterr.Wrap(err, "good", route.Good, "waypoint", route.Destination))
+		sb.Report(sbCh)
+		return
+	}
+
+	sb.journalEnd()
+	sb.Report(sbCh)
+}
+
+// buyTradeGoods buys a route's good at its source in lots no larger than the market's trade volume, holding
+// a credit reservation for the whole purchase until it is done. It returns the units bought.
+func (sb *ShipBot) buyTradeGoods(route TradeRoute) (int, error) {
+	release, err := sb.agent.ReserveCredits(route.PurchasePrice * int64(route.Units))
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	if err := sb.travelAndDock(route.Source); err != nil {
+		return 0, err
+	}
+
+	var bought int
+	for bought < route.Units {
+		lot := sb.tradeLot(route.Source, route.Good, route.Units-bought)
+
+		res, err := sb.client.PurchaseCargo(sb.ship.Symbol, route.Good, lot)
+		if err != nil {
+			if bought > 0 {
+				sb.logger.Warn("💱 Purchase stopped early.", "good", route.Good, "bought", bought, "error", err)
+				return bought, nil
+			}
+			return 0, err
+		}
+
+		bought += res.Transaction.Units
+		sb.ship.Cargo = res.Cargo
+		sb.agent.Update(res.Agent)
+		sb.tallyPurchase(res.Transaction)
+
+		sb.bus.Publish(event.Event{Type: event.CargoPurchased, Ship: sb.ship.Symbol, Mission: sb.mission, MissionID: sb.missionID, Message: fmt.Sprintf("%d %s for %d", res.Transaction.Units, res.Transaction.TradeSymbol, res.Transaction.TotalPrice), Data: res.Transaction})
+		sb.bus.Publish(event.Event{Type: event.AgentUpdated, Data: res.Agent})
+	}
+
+	sb.logger.Info("💱 Trade goods bought.", "good", route.Good, "units", bought, "credits", sb.agent.Credits())
+
+	return bought, nil
+}
+
+// sellTradeGoods hauls units of a route's good to its destination and sells them, going on to the next-best
+// fresh market if the destination's price has collapsed below priceCollapse of the planned price.
+func (sb *ShipBot) sellTradeGoods(route TradeRoute, units int) error {
+	if err := sb.travelAndDock(route.Destination); err != nil {
+		return err
+	}
+
+	price, err := sb.currentSellPrice(route.Destination, route.Good)
+	if err != nil {
+		return err
+	}
+
+	if float64(price) < priceCollapse*float64(route.SellPrice) {
+		sb.logger.Warn("💱 Destination price collapsed.", "good", route.Good, "planned", route.SellPrice, "current", price)
+
+		observation, _ := sb.markets.Get(route.Destination)
+		score, _ := sellScore(observation.Market, route.Good)
+		if next, ok := sb.nextBestMarket(route.Good, route.Destination, score); ok {
+			sb.logger.Info("💱 Selling at next-best market.", "good", route.Good, "market", next)
+			if err := sb.travelAndDock(next); err != nil {
+				return err
+			}
+		}
+	}
+
+	return sb.sellTradeGood(route.Good, units)
+}
+
+// travelAndDock navigates to a waypoint and docks there.
+func (sb *ShipBot) travelAndDock(waypointSymbol string) error {
+	err := sb.NavigateShip(waypointSymbol)
+	if sb.ship.Nav.WaypointSymbol != waypointSymbol {
+		return notReached(waypointSymbol, err)
+	}
+
+	return sb.dockIfNeeded()
+}
+
+// currentSellPrice fetches the market at a waypoint, recording it, and returns what it pays for a good.
+func (sb *ShipBot) currentSellPrice(waypointSymbol string, symbol string) (int64, error) {
+	market, err := sb.client.GetMarketAt(waypointSymbol)
+	if err != nil {
+		return 0, err
+	}
+	sb.markets.Record(*market, sb.clock.Now())
+
+	price, ok := market.SellPriceOf(symbol)
+	if !ok {
+		return 0, fmt.Errorf("%s does not buy %s", waypointSymbol, symbol)
+	}
+
+	return price, nil
+}
+
+// nextBestMarket returns the fresh market in the ship's system, other than exclude, scoring highest for selling a
+// good (see sellScore), provided it scores more than floor.
+func (sb *ShipBot) nextBestMarket(symbol string, exclude string, floor float64) (string, bool) {
+	now := sb.clock.Now()
+
+	var best string
+	for _, observation := range sb.markets.All() {
+		if observation.Market.Symbol == exclude || now.Sub(observation.ObservedAt) > sb.tradeMaxPriceAge {
+			continue
+		}
+		if systemSymbol, err := lib.SystemSymbolOf(observation.Market.Symbol); err != nil || systemSymbol != sb.ship.Nav.SystemSymbol {
+			continue
+		}
+
+		if score, ok := sellScore(observation.Market, symbol); ok && score > floor {
+			best, floor = observation.Market.Symbol, score
+		}
+	}
+
+	return best, best != ""
+}
+
+// priceDropped checks if a sale realized a price more than the ship's sell price drop below the price the
+// market was recorded at before it.
+func (sb *ShipBot) priceDropped(recorded int64, realized int64) bool {
+	return sb.sellPriceDrop > 0 && recorded > 0 && float64(realized) < (1-sb.sellPriceDrop)*float64(recorded)
+}
+
+// rerouteSale fetches the market the ship is docked at after a sale's price dropped, recording its new prices
+// and supply, and returns another fresh market in the system that now scores higher for the rest of a good.
+func (sb *ShipBot) rerouteSale(symbol string) (string, bool) {
+	here := sb.ship.Nav.WaypointSymbol
+
+	market, err := sb.client.GetMarketAt(here)
+	if err != nil {
+		sb.logger.Warn("💲 Error refreshing market after price drop. Selling on...", "waypoint", here, "error", err)
+		return "", false
+	}
+	sb.markets.Record(*market, sb.clock.Now())
+
+	score, ok := sellScore(*market, symbol)
+	if !ok {
+		return "", false
+	}
+
+	return sb.nextBestMarket(symbol, here, score)
+}
+
+// sellTradeGood sells units of a good at the market the ship is docked at, in lots no larger than its trade volume.
+func (sb *ShipBot) sellTradeGood(symbol string, units int) error {
+	var sold int
+	var credits int64
+	var resynced bool
+
+	for sold < units {
+		lot := sb.tradeLot(sb.ship.Nav.WaypointSymbol, symbol, units-sold)
+		recorded := sb.recordedSellPrice(symbol)
+
+		res, err := sb.client.SellCargo(sb.ship.Symbol, symbol, lot)
+		if api.IsCargoShort(err) && !resynced {
+			resynced = true
+			if syncErr := sb.syncCargo(); syncErr == nil {
+				// Sell the lesser of the units still wanted and the units held.
+				if held := sb.ship.Cargo.UnitsOf(symbol); held < units-sold {
+					units = sold + held
+				}
+				sb.logger.Warn("📦 Cargo changed before sale. Selling what remains...", "good", symbol, "units", units-sold)
+				continue
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		sold += res.Transaction.Units
+		credits += res.Transaction.TotalPrice
+		sb.ship.Cargo = res.Cargo
+		sb.agent.Update(res.Agent)
+		sb.tallySale(res.Transaction)
+
+		sb.bus.Publish(event.Event{Type: event.CargoSold, Ship: sb.ship.Symbol, MissionID: sb.missionID, Message: fmt.Sprintf("%d %s for %d", res.Transaction.Units, res.Transaction.TradeSymbol, res.Transaction.TotalPrice), Data: res.Transaction})
+		sb.bus.Publish(event.Event{Type: event.AgentUpdated, Data: res.Agent})
+
+		if sold < units && sb.priceDropped(recorded, res.Transaction.PricePerUnit) {
+			next, ok := sb.rerouteSale(symbol)
+			if !ok {
+				continue
+			}
+
+			supply, _ := sb.markets.Supply(sb.ship.Nav.WaypointSymbol, symbol)
+			sb.logger.Warn("💱 Sale price dropped. Selling the rest at another market.", "good", symbol, "recorded", recorded, "realized", res.Transaction.PricePerUnit, "supply", supply, "market", next, "units", units-sold)
+			if err := sb.travelAndDock(next); err != nil {
+				return err
+			}
+		}
+	}
+
+	sb.logger.Info("💲 Trade goods sold.", "good", symbol, "units", sold, "totalPrice", credits, "credits", sb.agent.Credits())
+
+	return nil
+}
+
+// recordedSellPrice returns the price the market the ship is docked at was last recorded paying for a good, or
+// zero if none is recorded.
+func (sb *ShipBot) recordedSellPrice(symbol string) int64 {
+	observation, ok := sb.markets.Get(sb.ship.Nav.WaypointSymbol)
+	if !ok {
+		return 0
+	}
+
+	price, _ := observation.Market.SellPriceOf(symbol)
+
+	return price
+}
+
+// tradeLot returns how many of the wanted units of a good fit in a single transaction at a market.
+func (sb *ShipBot) tradeLot(waypointSymbol string, symbol string, wanted int) int {
+	observation, ok := sb.markets.Get(waypointSymbol)
+	if !ok {
+		return wanted
+	}
+
+	if volume, ok := observation.Market.TradeVolumeOf(symbol); ok && volume > 0 && volume < wanted {
+		return volume
+	}
+
+	return wanted
+}
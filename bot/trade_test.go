@@ -0,0 +1,315 @@
+package bot
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+// tradeWaypoints is a system with markets at X1-A-1, X1-A-3 and X1-A-4, 20 and 30 units apart.
+var tradeWaypoints = []m.Waypoint{
+	{Symbol: "X1-A-1", SystemSymbol: "X1-A", Type: "PLANET", Traits: []m.WaypointTrait{{Symbol: "MARKETPLACE"}}},
+	{Symbol: "X1-A-3", SystemSymbol: "X1-A", Type: "MOON", X: 20, Traits: []m.WaypointTrait{{Symbol: "MARKETPLACE"}}},
+	{Symbol: "X1-A-4", SystemSymbol: "X1-A", Type: "MOON", X: 30, Traits: []m.WaypointTrait{{Symbol: "MARKETPLACE"}}},
+}
+
+// tradeMarket is a market observed age ago, trading goods given as "GOOD PURCHASE_PRICE SELL_PRICE".
+func tradeMarket(symbol string, age time.Duration, goods ...string) store.MarketObservation {
+	market := m.Market{Symbol: symbol}
+	for _, good := range goods {
+		var tradeGood m.MarketTradeGood
+		fmt.Sscan(good, &tradeGood.Symbol, &tradeGood.PurchasePrice, &tradeGood.SellPrice)
+		market.TradeGoods = append(market.TradeGoods, tradeGood)
+	}
+
+	return store.MarketObservation{Market: market, ObservedAt: time.Now().Add(-age)}
+}
+
+// routeSummary describes a route as "GOOD SOURCE->DESTINATION UNITSu FUEL_COST PROFIT".
+func routeSummary(routes []TradeRoute) []string {
+	var summary []string
+	for _, r := range routes {
+		summary = append(summary, fmt.Sprintf("%s %s->%s %du %d %d", r.Good, r.Source, r.Destination, r.Units, r.FuelCost, r.Profit))
+	}
+	return summary
+}
+
+func TestPlanArbitrage(t *testing.T) {
+	source := tradeMarket("X1-A-1", time.Minute, "IRON_ORE 10 8", "FUEL 2 1")
+
+	tests := []struct {
+		name    string
+		markets []store.MarketObservation
+		credits int64
+		margin  int64
+		want    []string
+	}{
+		{
+			// 30 units earn 40 each, less 20 fuel at 2 credits.
+			name:    "profit after fuel",
+			markets: []store.MarketObservation{source, tradeMarket("X1-A-3", time.Minute, "IRON_ORE 60 50")},
+			credits: 10000,
+			margin:  100,
+			want:    []string{"IRON_ORE X1-A-1->X1-A-3 30u 40 1160"},
+		},
+		{
+			name:    "credits limit the units",
+			markets: []store.MarketObservation{source, tradeMarket("X1-A-3", time.Minute, "IRON_ORE 60 50")},
+			credits: 155,
+			margin:  100,
+			want:    []string{"IRON_ORE X1-A-1->X1-A-3 15u 40 560"},
+		},
+		{
+			name:    "profit within the margin",
+			markets: []store.MarketObservation{source, tradeMarket("X1-A-3", time.Minute, "IRON_ORE 60 50")},
+			credits: 10000,
+			margin:  1160,
+		},
+		{
+			name:    "unaffordable",
+			markets: []store.MarketObservation{source, tradeMarket("X1-A-3", time.Minute, "IRON_ORE 60 50")},
+			credits: 9,
+		},
+		{
+			// Without a fuel price, fuel is estimated at estimatedFuelPrice, costing more than the trade earns.
+			name:    "fuel priced by estimate",
+			markets: []store.MarketObservation{tradeMarket("X1-A-1", time.Minute, "IRON_ORE 10 8"), tradeMarket("X1-A-3", time.Minute, "IRON_ORE 60 50")},
+			credits: 10000,
+		},
+		{
+			name:    "stale destination",
+			markets: []store.MarketObservation{source, tradeMarket("X1-A-3", 20*time.Minute, "IRON_ORE 60 50")},
+			credits: 10000,
+		},
+		{
+			name:    "stale source",
+			markets: []store.MarketObservation{tradeMarket("X1-A-1", 20*time.Minute, "IRON_ORE 10 8", "FUEL 2 1"), tradeMarket("X1-A-3", time.Minute, "IRON_ORE 60 50")},
+			credits: 10000,
+		},
+		{
+			name:    "market outside the system",
+			markets: []store.MarketObservation{source, tradeMarket("X1-B-3", time.Minute, "IRON_ORE 60 50")},
+			credits: 10000,
+		},
+		{
+			name:    "destination paying less",
+			markets: []store.MarketObservation{source, tradeMarket("X1-A-3", time.Minute, "IRON_ORE 12 9")},
+			credits: 10000,
+		},
+		{
+			name: "most profitable first",
+			markets: []store.MarketObservation{
+				source,
+				tradeMarket("X1-A-3", time.Minute, "IRON_ORE 40 30"),
+				tradeMarket("X1-A-4", time.Minute, "IRON_ORE 60 50"),
+			},
+			credits: 10000,
+			margin:  100,
+			want: []string{
+				"IRON_ORE X1-A-1->X1-A-4 30u 60 1140",
+				"IRON_ORE X1-A-1->X1-A-3 30u 40 560",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes := planArbitrage(tt.markets, tradeWaypoints, nil, 30, tt.credits, 15*time.Minute, tt.margin, time.Now())
+			if got := routeSummary(routes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("routes = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanArbitragePrefersExportsSoldToImporters(t *testing.T) {
+	source := tradeMarket("X1-A-1", time.Minute, "IRON_ORE 10 8", "FUEL 2 1")
+	source.Market.Exports = []m.TradeGood{{Symbol: "IRON_ORE"}}
+
+	// X1-A-3 pays less than X1-A-4 but imports the ore, where X1-A-4 only exchanges it.
+	importer := tradeMarket("X1-A-3", time.Minute, "IRON_ORE 40 30")
+	importer.Market.TradeGoods[0].Type = m.TradeTypeImport
+	exchange := tradeMarket("X1-A-4", time.Minute, "IRON_ORE 60 50")
+	exchange.Market.TradeGoods[0].Type = m.TradeTypeExchange
+
+	routes := planArbitrage([]store.MarketObservation{source, importer, exchange}, tradeWaypoints, nil, 30, 10000, 15*time.Minute, 100, time.Now())
+	want := []string{
+		"IRON_ORE X1-A-1->X1-A-3 30u 40 560",
+		"IRON_ORE X1-A-1->X1-A-4 30u 60 1140",
+	}
+	if got := routeSummary(routes); !reflect.DeepEqual(got, want) {
+		t.Errorf("routes = %q, want %q", got, want)
+	}
+
+	// Markets observed without trade good types fall back to their import listings.
+	exchange.Market.TradeGoods[0].Type = ""
+	exchange.Market.Imports = []m.TradeGood{{Symbol: "IRON_ORE"}}
+	routes = planArbitrage([]store.MarketObservation{source, importer, exchange}, tradeWaypoints, nil, 30, 10000, 15*time.Minute, 100, time.Now())
+	want = []string{
+		"IRON_ORE X1-A-1->X1-A-4 30u 60 1140",
+		"IRON_ORE X1-A-1->X1-A-3 30u 40 560",
+	}
+	if got := routeSummary(routes); !reflect.DeepEqual(got, want) {
+		t.Errorf("untyped importer: routes = %q, want %q", got, want)
+	}
+}
+
+// tradeClient is a contractClient with markets at tradeWaypoints, where navigation arrives at once and
+// trades settle at the markets' current prices.
+type tradeClient struct {
+	contractClient
+	calls   []string
+	markets map[string]m.Market
+	nav     m.ShipNav
+	cargo   m.ShipCargo
+}
+
+func (c *tradeClient) OrbitShip(shipSymbol string) (*m.ShipNav, error) {
+	c.calls = append(c.calls, "orbit")
+	c.nav.Status = "IN_ORBIT"
+	nav := c.nav
+	return &nav, nil
+}
+
+func (c *tradeClient) DockShip(shipSymbol string) (*m.ShipNav, error) {
+	c.calls = append(c.calls, "dock")
+	c.nav.Status = "DOCKED"
+	nav := c.nav
+	return &nav, nil
+}
+
+func (c *tradeClient) NavigateShip(shipSymbol string, waypointSymbol string) (*api.NavigateShipResponse, error) {
+	c.calls = append(c.calls, "navigate "+waypointSymbol)
+	c.nav.WaypointSymbol = waypointSymbol
+	c.nav.Route.Destination.Symbol = waypointSymbol
+	return &api.NavigateShipResponse{Nav: c.nav, Fuel: m.ShipFuel{Current: 100, Capacity: 100}}, nil
+}
+
+func (c *tradeClient) GetMarketAt(waypointSymbol string) (*m.Market, error) {
+	c.calls = append(c.calls, "market "+waypointSymbol)
+	market := c.markets[waypointSymbol]
+	return &market, nil
+}
+
+func (c *tradeClient) PurchaseCargo(shipSymbol string, cargoSymbol string, units int) (*api.PurchaseCargoResponse, error) {
+	market := c.markets[c.nav.WaypointSymbol]
+	price, _ := market.PurchasePriceOf(cargoSymbol)
+	c.calls = append(c.calls, fmt.Sprintf("purchase %d %s at %s", units, cargoSymbol, c.nav.WaypointSymbol))
+	c.cargo.Add(cargoSymbol, units)
+	return &api.PurchaseCargoResponse{
+		Agent:       m.Agent{Credits: m.Credits(10000 - price*int64(units))},
+		Cargo:       c.cargo,
+		Transaction: m.MarketTransaction{TradeSymbol: cargoSymbol, Units: units, PricePerUnit: price, TotalPrice: price * int64(units)},
+	}, nil
+}
+
+func (c *tradeClient) SellCargo(shipSymbol string, cargoSymbol string, units int) (*api.SellCargoResponse, error) {
+	market := c.markets[c.nav.WaypointSymbol]
+	price, _ := market.SellPriceOf(cargoSymbol)
+	c.calls = append(c.calls, fmt.Sprintf("sell %d %s at %s", units, cargoSymbol, c.nav.WaypointSymbol))
+	c.cargo.Remove(cargoSymbol, units)
+	return &api.SellCargoResponse{
+		Cargo:       c.cargo,
+		Transaction: m.MarketTransaction{TradeSymbol: cargoSymbol, Units: units, PricePerUnit: price, TotalPrice: price * int64(units)},
+	}, nil
+}
+
+func TestTradeMissionSellsElsewhereWhenTheDestinationPriceCollapsed(t *testing.T) {
+	cfg := config.Default()
+	out := captureLogs(t)
+
+	c := &tradeClient{
+		contractClient: contractClient{waypoints: tradeWaypoints},
+		nav:            m.ShipNav{SystemSymbol: "X1-A", WaypointSymbol: "X1-A-1", Status: "DOCKED"},
+		cargo:          m.ShipCargo{Capacity: 30},
+		markets: map[string]m.Market{
+			"X1-A-1": tradeMarket("X1-A-1", 0, "IRON_ORE 10 8", "FUEL 2 1").Market,
+			"X1-A-3": tradeMarket("X1-A-3", 0, "IRON_ORE 25 20").Market,
+			"X1-A-4": tradeMarket("X1-A-4", 0, "IRON_ORE 40 35").Market,
+		},
+	}
+
+	// The recorded prices make X1-A-3 the best destination, though its price has since collapsed.
+	markets := store.NewMarketStore()
+	for _, observation := range []store.MarketObservation{
+		tradeMarket("X1-A-1", time.Minute, "IRON_ORE 10 8", "FUEL 2 1"),
+		tradeMarket("X1-A-3", time.Minute, "IRON_ORE 60 50"),
+		tradeMarket("X1-A-4", time.Minute, "IRON_ORE 40 35"),
+	} {
+		markets.Record(observation.Market, observation.ObservedAt)
+	}
+
+	ship := m.Ship{Symbol: "GOGARIN-1", Nav: c.nav, Cargo: c.cargo}
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+	sb := NewShipBot(c, &ship, store.NewAgentState(m.Agent{Credits: 10000}, 0), store.NewSystemKnowledge(c), markets, bus, cfg)
+	sb.arrival, sb.dock = nil, nil
+
+	sbCh := make(chan ShipBot, 1)
+	sb.RunTradeMission(sbCh)
+	<-sbCh
+
+	want := []string{
+		"purchase 30 IRON_ORE at X1-A-1",
+		"orbit", "navigate X1-A-3", "dock", "market X1-A-3",
+		"orbit", "navigate X1-A-4", "dock",
+		"sell 30 IRON_ORE at X1-A-4",
+	}
+	if !reflect.DeepEqual(c.calls, want) {
+		t.Errorf("calls = %q, want %q", c.calls, want)
+	}
+	if ship := sb.ship; ship.Cargo.UnitsOf("IRON_ORE") != 0 {
+		t.Errorf("cargo after the mission = %+v, want none left", ship.Cargo)
+	}
+	if recorded, _ := markets.Get("X1-A-3"); recorded.Market.TradeGoods[0].SellPrice != 20 {
+		t.Errorf("recorded X1-A-3 price = %d, want the collapsed 20", recorded.Market.TradeGoods[0].SellPrice)
+	}
+
+	var messages []string
+	for _, line := range logLines(t, out, "🚀 GOGARIN-1:") {
+		if msg := line["msg"].(string); strings.HasPrefix(msg, "💱") {
+			messages = append(messages, msg)
+		}
+	}
+	if want := []string{"💱 Trade route planned.", "💱 Trade goods bought.", "💱 Destination price collapsed.", "💱 Selling at next-best market."}; !reflect.DeepEqual(messages, want) {
+		t.Errorf("trade log = %q, want %q", messages, want)
+	}
+}
+
+func TestTradeMissionFailsWithoutARoute(t *testing.T) {
+	cfg := config.Default()
+
+	c := &tradeClient{contractClient: contractClient{waypoints: tradeWaypoints}}
+	markets := store.NewMarketStore()
+	stale := tradeMarket("X1-A-3", time.Hour, "IRON_ORE 60 50")
+	markets.Record(stale.Market, stale.ObservedAt)
+
+	ship := m.Ship{Symbol: "GOGARIN-1", Nav: m.ShipNav{SystemSymbol: "X1-A", WaypointSymbol: "X1-A-1", Status: "DOCKED"}, Cargo: m.ShipCargo{Capacity: 30}}
+	bus := event.NewBus()
+	t.Cleanup(bus.Close)
+	failures := bus.Subscribe(1)
+	sb := NewShipBot(c, &ship, store.NewAgentState(m.Agent{Credits: 10000}, 0), store.NewSystemKnowledge(c), markets, bus, cfg)
+
+	sbCh := make(chan ShipBot, 1)
+	sb.RunTradeMission(sbCh)
+	<-sbCh
+
+	if len(c.calls) != 0 {
+		t.Errorf("calls = %q, want none", c.calls)
+	}
+	select {
+	case e := <-failures:
+		if e.Type != event.MissionFailed || !strings.HasSuffix(e.Message, errNoTradeRoute.Error()) {
+			t.Errorf("event = %+v, want a failure for want of a route", e)
+		}
+	default:
+		t.Error("no failure published")
+	}
+}
@@ -0,0 +1,86 @@
+// Package boterr wraps errors with the context they happened in, such as the ship, mission, and waypoint,
+// as structured fields. Wrapped errors still match errors.Is and errors.As against the errors they wrap.
+package boterr
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Error is an error with alternating key/value fields describing where it happened.
+type Error struct {
+	Err    error
+	Fields []interface{}
+}
+
+// Error returns the fields as key=value pairs, then the wrapped error's message.
+func (e *Error) Error() string {
+	var b strings.Builder
+	for i := 0; i+1 < len(e.Fields); i += 2 {
+		fmt.Fprintf(&b, "%v=%s ", e.Fields[i], quote(e.Fields[i+1]))
+	}
+
+	return strings.TrimSuffix(b.String(), " ") + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// quote formats a field value, quoting it if it is empty or contains spaces.
+func quote(value interface{}) string {
+	s := fmt.Sprint(value)
+	if s == "" || strings.ContainsAny(s, " \t\n\"") {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
+
+// Wrap adds alternating key/value fields to err, or returns nil if err is nil. Fields with an empty value
+// are left out, so call sites can pass context that is not always known.
+func Wrap(err error, fields ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+
+	var kept []interface{}
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i+1] == nil || fields[i+1] == "" {
+			continue
+		}
+		kept = append(kept, fields[i], fields[i+1])
+	}
+
+	if len(kept) == 0 {
+		return err
+	}
+
+	return &Error{Err: err, Fields: kept}
+}
+
+// Fields returns the fields of every Error in err's chain, outermost first. A key already given by an outer
+// Error is not repeated.
+func Fields(err error) []interface{} {
+	var fields []interface{}
+	seen := make(map[interface{}]bool)
+
+	for err != nil {
+		var e *Error
+		if !errors.As(err, &e) {
+			break
+		}
+
+		for i := 0; i+1 < len(e.Fields); i += 2 {
+			if !seen[e.Fields[i]] {
+				seen[e.Fields[i]] = true
+				fields = append(fields, e.Fields[i], e.Fields[i+1])
+			}
+		}
+		err = e.Err
+	}
+
+	return fields
+}
@@ -0,0 +1,62 @@
+package boterr
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/GeoffreyDick/gogarin/api"
+)
+
+func TestWrappedErrorsReachAPIError(t *testing.T) {
+	cause := &api.APIError{StatusCode: 409, Code: 4000, Message: "Ship action is still on cooldown."}
+
+	err := Wrap(fmt.Errorf("extracting: %w", Wrap(cause, "waypoint", "X1-MK1-B2", "attempt", 3)), "ship", "MOCK-2", "mission", "ExtractResources")
+
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("errors.As found no APIError in %q", err)
+	}
+	if apiErr != cause {
+		t.Fatalf("errors.As found %+v, want the wrapped %+v", apiErr, cause)
+	}
+	if !errors.Is(err, cause) {
+		t.Fatalf("errors.Is does not match the wrapped APIError in %q", err)
+	}
+
+	want := `ship=MOCK-2 mission=ExtractResources: extracting: waypoint=X1-MK1-B2 attempt=3: Ship action is still on cooldown.`
+	if err.Error() != want {
+		t.Fatalf("message = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestWrapLeavesOutEmptyFields(t *testing.T) {
+	if err := Wrap(nil, "ship", "MOCK-1"); err != nil {
+		t.Fatalf("wrapping nil = %v, want nil", err)
+	}
+	if err := Wrap(io.EOF, "ship", "", "mission", nil); err != io.EOF {
+		t.Fatalf("wrapping with only empty fields = %#v, want the error itself", err)
+	}
+
+	err := Wrap(io.EOF, "ship", "MOCK-1", "waypoint", "", "good", "IRON ORE")
+	if want := `ship=MOCK-1 good="IRON ORE": EOF`; err.Error() != want {
+		t.Fatalf("message = %q, want %q", err.Error(), want)
+	}
+	if !errors.Is(err, io.EOF) {
+		t.Fatal("errors.Is does not match the wrapped error")
+	}
+}
+
+func TestFieldsOutermostFirst(t *testing.T) {
+	err := Wrap(fmt.Errorf("selling: %w", Wrap(io.EOF, "waypoint", "X1-MK1-C3", "ship", "INNER")), "ship", "MOCK-2", "mission", "SellCargo")
+
+	want := []interface{}{"ship", "MOCK-2", "mission", "SellCargo", "waypoint", "X1-MK1-C3"}
+	if got := Fields(err); !reflect.DeepEqual(got, want) {
+		t.Fatalf("fields = %v, want %v", got, want)
+	}
+	if got := Fields(io.EOF); got != nil {
+		t.Fatalf("fields of an unwrapped error = %v, want none", got)
+	}
+}
@@ -0,0 +1,660 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/bot"
+	"github.com/GeoffreyDick/gogarin/ledger"
+	"github.com/GeoffreyDick/gogarin/lib"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/server"
+	"github.com/GeoffreyDick/gogarin/store"
+	"github.com/GeoffreyDick/gogarin/telemetry"
+)
+
+/*
+⌨️ CLI
+*/
+
+// command is a gogarin subcommand.
+type command struct {
+	usage string
+	run   func(c api.ClientAPI, args []string, w io.Writer) error
+	// offline commands do not call the API, so they run without a token.
+	offline bool
+}
+
+var commands = map[string]command{
+	"status":    {"status [--json]", statusCommand, false},
+	"ships":     {"ships [--json]", shipsCommand, false},
+	"contracts": {"contracts [--json]", contractsCommand, false},
+	"market":    {"market [--json] WAYPOINT | market diff [--json] [--http ADDR] [--max-drift F] WAYPOINT", marketCommand, false},
+	"system":    {"system [--json] SYSTEM", systemCommand, false},
+	"run":       {"run [--tui] [--dry-run] [--dry-run-speed N] [--log-level L] [--log-format text|json] [--log-only SHIP] [--http ADDR] [--metrics] [--telemetry FILE] [--auto-register] [--skip-preflight] [--seed N] [--strategy mining|contract|trading|exploring]", runCommand, false},
+	"replay":    {"replay FILE", replayCommand, true},
+	"register":  {"register --symbol SYMBOL [--faction FACTION]", registerCommand, true},
+	"ship":      {"ship rename SHIP NICKNAME | ship note SHIP NOTES", shipCommand, true},
+	"pause":     {"pause [--http ADDR]", pauseCommand, true},
+	"resume":    {"resume [--http ADDR]", resumeCommand, true},
+	"order":     {"order [--http ADDR] SHIP navigate WAYPOINT | dock | orbit | sell GOOD [UNITS] | hold | resume", orderCommand, true},
+}
+
+// execute runs the subcommand named by the first argument, defaulting to run.
+func execute(c api.ClientAPI, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		args = []string{"run"}
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage())
+	}
+
+	if !cmd.offline && cfg.Token == "" {
+		return errors.New("TOKEN not set in environment or config file")
+	}
+
+	// Only run sends fleet traffic; every other command is interactive.
+	if args[0] != "run" {
+		c = interactive(c)
+	}
+
+	return cmd.run(c, args[1:], w)
+}
+
+// usage lists the available subcommands.
+func usage() string {
+	var b strings.Builder
+	b.WriteString("usage:\n")
+	for _, name := range []string{"run", "status", "ships", "contracts", "market", "system", "replay", "register", "ship", "pause", "resume", "order"} {
+		fmt.Fprintf(&b, "  gogarin %s\n", commands[name].usage)
+	}
+
+	return b.String()
+}
+
+// parseFlags parses the flags shared by the read-only subcommands, returning the --json flag and remaining arguments.
+func parseFlags(name string, args []string) (bool, []string, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print machine-readable JSON")
+
+	if err := fs.Parse(args); err != nil {
+		return false, nil, err
+	}
+
+	return *asJSON, fs.Args(), nil
+}
+
+func runCommand(c api.ClientAPI, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	tui := fs.Bool("tui", false, "show the interactive fleet dashboard")
+	dryRun := fs.Bool("dry-run", false, "simulate mutating actions instead of sending them to the API")
+	dryRunSpeed := fs.Float64("dry-run-speed", 10, "how many times faster than real time arrivals and cooldowns elapse in a dry run")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "log level: debug, info, warn, or error")
+	fs.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "log format: text or json")
+	fs.StringVar(&cfg.LogOnly, "log-only", cfg.LogOnly, "only show Info and Debug lines from this ship symbol")
+	fs.BoolVar(&cfg.Metrics, "metrics", cfg.Metrics, "serve bot metrics at /metrics on the --http server")
+	fs.StringVar(&cfg.TelemetryPath, "telemetry", cfg.TelemetryPath, "append a JSONL record of significant actions to this file")
+	autoRegister := fs.Bool("auto-register", false, "re-register with the configured symbol and faction if the token was invalidated by a reset")
+	skipPreflight := fs.Bool("skip-preflight", false, "start without checking the token, API, fleet, and configuration first")
+	fs.StringVar(&cfg.HTTPAddr, "http", cfg.HTTPAddr, "serve fleet status as JSON on this address, e.g. :8080")
+	seed := fs.Int64("seed", 0, "seed the run's random choices, to repeat a run from the seed it logged; 0 picks a new seed")
+	fs.StringVar(&cfg.Strategy, "strategy", cfg.Strategy, "strategy ships follow unless their role overrides it: mining, contract, trading, or exploring")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	return run(c, runOptions{
+		tui:           *tui,
+		dryRun:        *dryRun,
+		dryRunSpeed:   *dryRunSpeed,
+		httpAddr:      cfg.HTTPAddr,
+		autoRegister:  *autoRegister,
+		skipPreflight: *skipPreflight,
+		seed:          *seed,
+	})
+}
+
+func statusCommand(c api.ClientAPI, args []string, w io.Writer) error {
+	asJSON, _, err := parseFlags("status", args)
+	if err != nil {
+		return err
+	}
+
+	agent, err := c.GetMyAgent()
+	if err != nil {
+		return err
+	}
+
+	contracts, err := c.GetMyContracts()
+	if err != nil {
+		return err
+	}
+
+	return renderStatus(w, agent, *contracts, asJSON)
+}
+
+func shipsCommand(c api.ClientAPI, args []string, w io.Writer) error {
+	asJSON, _, err := parseFlags("ships", args)
+	if err != nil {
+		return err
+	}
+
+	ships, err := c.GetMyShips()
+	if err != nil {
+		return err
+	}
+
+	metadata, err := store.OpenMetadataStore(cfg.MetadataPath)
+	if err != nil {
+		return err
+	}
+
+	return renderShips(w, *ships, metadata, asJSON)
+}
+
+func contractsCommand(c api.ClientAPI, args []string, w io.Writer) error {
+	asJSON, _, err := parseFlags("contracts", args)
+	if err != nil {
+		return err
+	}
+
+	contracts, err := c.GetMyContracts()
+	if err != nil {
+		return err
+	}
+
+	return renderContracts(w, *contracts, asJSON)
+}
+
+func marketCommand(c api.ClientAPI, args []string, w io.Writer) error {
+	if len(args) > 0 && args[0] == "diff" {
+		return marketDiffCommand(c, args[1:], w)
+	}
+
+	asJSON, rest, err := parseFlags("market", args)
+	if err != nil {
+		return err
+	}
+
+	if len(rest) != 1 {
+		return errors.New("usage: gogarin market [--json] WAYPOINT")
+	}
+
+	waypoint, err := lib.NormalizeWaypointSymbol(rest[0])
+	if err != nil {
+		return err
+	}
+
+	market, err := c.GetMarketAt(waypoint)
+	if err != nil {
+		return err
+	}
+
+	return renderMarket(w, market, asJSON)
+}
+
+// marketDiffCommand compares a running bot's stored observation of a market, read from its status server, with
+// the live market. It fails once any price has drifted further than --max-drift, so it can alert from cron.
+func marketDiffCommand(c api.ClientAPI, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("market diff", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print machine-readable JSON")
+	addr := fs.String("http", cfg.HTTPAddr, "address of the running bot's status server")
+	maxDrift := fs.Float64("max-drift", 0.1, "fail if any price has drifted by more than this fraction of the stored price")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 || *addr == "" {
+		return errors.New("usage: gogarin market diff [--json] --http ADDR [--max-drift F] WAYPOINT (the bot must run with --http)")
+	}
+	waypoint, err := lib.NormalizeWaypointSymbol(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	res, err := http.Get(statusServerURL(*addr) + "/api/markets/" + waypoint)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+
+	var stored store.MarketObservation
+	if err := json.NewDecoder(res.Body).Decode(&stored); err != nil {
+		return err
+	}
+
+	live, err := c.GetMarketAt(waypoint)
+	if err != nil {
+		return err
+	}
+
+	diff := bot.DiffMarkets(stored.Market, *live, stored.ObservedAt, time.Now())
+	if *asJSON {
+		err = renderJSON(w, diff)
+	} else {
+		_, err = io.WriteString(w, bot.RenderMarketDiff(diff))
+	}
+	if err != nil {
+		return err
+	}
+
+	if diff.Drift > *maxDrift {
+		return fmt.Errorf("market %s has drifted %.1f%%, over the %.1f%% allowed", waypoint, diff.Drift*100, *maxDrift*100)
+	}
+
+	return nil
+}
+
+// systemCommand sums up what the waypoints of a system offer, with distances from the agent's headquarters
+// when it is in the system. Only fuel stations are counted as fuel sellers, since markets are not fetched.
+func systemCommand(c api.ClientAPI, args []string, w io.Writer) error {
+	asJSON, rest, err := parseFlags("system", args)
+	if err != nil {
+		return err
+	}
+
+	if len(rest) != 1 {
+		return errors.New("usage: gogarin system [--json] SYSTEM")
+	}
+
+	system, err := lib.NormalizeSystemSymbol(rest[0])
+	if err != nil {
+		return err
+	}
+
+	agent, err := c.GetMyAgent()
+	if err != nil {
+		return err
+	}
+
+	waypoints, err := c.ListWaypoints(system)
+	if err != nil {
+		return err
+	}
+
+	return renderSystem(w, bot.SummarizeSystem(system, agent.Headquarters, *waypoints, nil), asJSON)
+}
+
+func registerCommand(c api.ClientAPI, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("register", flag.ContinueOnError)
+	symbol := fs.String("symbol", cfg.Symbol, "agent callsign")
+	faction := fs.String("faction", cfg.Faction, "starting faction")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *symbol == "" {
+		return errors.New("usage: gogarin register --symbol SYMBOL [--faction FACTION]")
+	}
+
+	for _, s := range []*string{symbol, faction} {
+		if *s == "" {
+			continue
+		}
+
+		normal, err := lib.NormalizeSymbol(*s)
+		if err != nil {
+			return err
+		}
+		*s = normal
+	}
+
+	if _, err := registerAgent(*symbol, *faction); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Registered %s with %s. Token saved to .env.\n", *symbol, *faction)
+
+	return nil
+}
+
+// shipCommand sets a ship's nickname or notes. They are kept locally and never sent to the API.
+func shipCommand(c api.ClientAPI, args []string, w io.Writer) error {
+	if len(args) < 2 || (args[0] != "rename" && args[0] != "note") {
+		return errors.New("usage: gogarin ship rename SHIP NICKNAME | ship note SHIP NOTES")
+	}
+
+	metadata, err := store.OpenMetadataStore(cfg.MetadataPath)
+	if err != nil {
+		return err
+	}
+
+	action, value := args[0], strings.Join(args[2:], " ")
+	symbol, err := lib.NormalizeSymbol(args[1])
+	if err != nil {
+		return err
+	}
+
+	if action == "rename" {
+		if err := metadata.Rename(symbol, value); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s is now %s.\n", symbol, metadata.Label(symbol))
+
+		return nil
+	}
+
+	if err := metadata.Annotate(symbol, value); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Notes for %s saved.\n", metadata.Label(symbol))
+
+	return nil
+}
+
+// pauseCommand pauses the fleet of a running bot through its status server.
+func pauseCommand(c api.ClientAPI, args []string, w io.Writer) error {
+	return pauseControl("pause", args, w)
+}
+
+// resumeCommand resumes the paused fleet of a running bot through its status server.
+func resumeCommand(c api.ClientAPI, args []string, w io.Writer) error {
+	return pauseControl("resume", args, w)
+}
+
+// pauseControl posts a pause or resume action to the status server and reports whether the fleet is paused.
+func pauseControl(action string, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet(action, flag.ContinueOnError)
+	addr := fs.String("http", cfg.HTTPAddr, "address of the running bot's status server")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *addr == "" {
+		return fmt.Errorf("usage: gogarin %s --http ADDR (the bot must run with --http)", action)
+	}
+
+	res, err := http.Post(statusServerURL(*addr)+"/api/"+action, "application/json", nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+
+	var state server.PauseResponse
+	if err := json.NewDecoder(res.Body).Decode(&state); err != nil {
+		return err
+	}
+
+	if state.Paused {
+		fmt.Fprintln(w, "Fleet paused.")
+	} else {
+		fmt.Fprintln(w, "Fleet running.")
+	}
+
+	return nil
+}
+
+// orderCommand gives a ship of a running bot a manual order through its status server.
+func orderCommand(c api.ClientAPI, args []string, w io.Writer) error {
+	const orderUsage = "usage: gogarin order --http ADDR SHIP navigate WAYPOINT | dock | orbit | sell GOOD [UNITS] | hold | resume (the bot must run with --http)"
+
+	fs := flag.NewFlagSet("order", flag.ContinueOnError)
+	addr := fs.String("http", cfg.HTTPAddr, "address of the running bot's status server")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() < 2 || *addr == "" {
+		return errors.New(orderUsage)
+	}
+	ship, err := lib.NormalizeSymbol(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	order := m.ManualOrder{Kind: strings.ToUpper(fs.Arg(1))}
+	rest := fs.Args()[2:]
+
+	switch order.Kind {
+	case m.OrderNavigate:
+		if len(rest) != 1 {
+			return errors.New(orderUsage)
+		}
+		if order.Waypoint, err = lib.NormalizeWaypointSymbol(rest[0]); err != nil {
+			return err
+		}
+	case m.OrderSell:
+		if len(rest) < 1 || len(rest) > 2 {
+			return errors.New(orderUsage)
+		}
+		if order.Good, err = lib.NormalizeSymbol(rest[0]); err != nil {
+			return err
+		}
+		if len(rest) == 2 {
+			units, err := strconv.Atoi(rest[1])
+			if err != nil || units <= 0 {
+				return fmt.Errorf("invalid units %q", rest[1])
+			}
+			order.Units = units
+		}
+	default:
+		if len(rest) != 0 {
+			return errors.New(orderUsage)
+		}
+	}
+
+	body, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+
+	res, err := http.Post(statusServerURL(*addr)+"/api/ships/"+ship+"/order", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+
+	var placed server.OrderResponse
+	if err := json.NewDecoder(res.Body).Decode(&placed); err != nil {
+		return err
+	}
+
+	if placed.Order.Kind == m.OrderResume {
+		fmt.Fprintf(w, "%s resumed automatic missions.\n", placed.Ship)
+	} else {
+		fmt.Fprintf(w, "%s ordered to %s.\n", placed.Ship, placed.Order)
+	}
+
+	return nil
+}
+
+// statusServerURL turns a listen address such as :8080 into the URL of the status server.
+func statusServerURL(addr string) string {
+	if strings.HasPrefix(addr, "http://") || strings.HasPrefix(addr, "https://") {
+		return strings.TrimSuffix(addr, "/")
+	}
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+
+	return "http://" + addr
+}
+
+func replayCommand(c api.ClientAPI, args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("usage: gogarin replay FILE")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	records, err := telemetry.Read(f)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", args[0], err)
+	}
+
+	return renderTimeline(w, records)
+}
+
+// renderJSON writes v to w as indented JSON.
+func renderJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
+
+// renderStatus writes the agent's credits and a contract summary to w.
+func renderStatus(w io.Writer, agent *m.Agent, contracts []m.Contract, asJSON bool) error {
+	if asJSON {
+		return renderJSON(w, struct {
+			Agent     *m.Agent     `json:"agent"`
+			Contracts []m.Contract `json:"contracts"`
+		}{agent, contracts})
+	}
+
+	offered, accepted, fulfilled := 0, 0, 0
+	for _, contract := range contracts {
+		switch {
+		case contract.Fulfilled:
+			fulfilled++
+		case contract.Accepted:
+			accepted++
+		default:
+			offered++
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "AGENT\t%s\n", agent.Symbol)
+	fmt.Fprintf(tw, "HEADQUARTERS\t%s\n", agent.Headquarters)
+	fmt.Fprintf(tw, "CREDITS\t%d\n", agent.Credits)
+	fmt.Fprintf(tw, "CONTRACTS\t%d offered, %d accepted, %d fulfilled\n", offered, accepted, fulfilled)
+
+	return tw.Flush()
+}
+
+// renderShips writes a table of ships, with their nicknames from metadata and ships left to manual control
+// marked MANUAL, to w.
+func renderShips(w io.Writer, ships []m.Ship, metadata *store.MetadataStore, asJSON bool) error {
+	if asJSON {
+		return renderJSON(w, ships)
+	}
+
+	_, err := io.WriteString(w, bot.RenderFleetTable(ships, metadata, cfg, ledger.Rates{}))
+
+	return err
+}
+
+// renderContracts writes a table of contracts to w.
+func renderContracts(w io.Writer, contracts []m.Contract, asJSON bool) error {
+	if asJSON {
+		return renderJSON(w, contracts)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTYPE\tFACTION\tSTATUS\tDELIVER\tPAYMENT\tDEADLINE")
+	for _, contract := range contracts {
+		var goods []string
+		for _, good := range contract.Terms.Deliver {
+			goods = append(goods, fmt.Sprintf("%s %d/%d", good.TradeSymbol, good.UnitsFulfilled, good.UnitsRequired))
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			contract.ID, contract.Type, contract.FactionSymbol, contract.Status(), strings.Join(goods, ", "),
+			contract.TotalPayment(), contract.Terms.Deadline.Format(time.RFC3339))
+	}
+
+	return tw.Flush()
+}
+
+// renderMarket writes a table of a market's trade goods to w.
+// Without a ship present the API omits prices, so only the traded symbols are listed.
+func renderMarket(w io.Writer, market *m.Market, asJSON bool) error {
+	if asJSON {
+		return renderJSON(w, market)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "MARKET\t%s\n\n", market.Symbol)
+
+	if len(market.TradeGoods) > 0 {
+		fmt.Fprintln(tw, "SYMBOL\tSUPPLY\tPURCHASE\tSELL\tVOLUME")
+		for _, good := range market.TradeGoods {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\n", good.Symbol, good.Supply, good.PurchasePrice, good.SellPrice, good.TradeVolume)
+		}
+
+		return tw.Flush()
+	}
+
+	fmt.Fprintln(tw, "SYMBOL\tTRADE")
+	for _, good := range market.Imports {
+		fmt.Fprintf(tw, "%s\tIMPORT\n", good.Symbol)
+	}
+	for _, good := range market.Exports {
+		fmt.Fprintf(tw, "%s\tEXPORT\n", good.Symbol)
+	}
+	for _, good := range market.Exchange {
+		fmt.Fprintf(tw, "%s\tEXCHANGE\n", good.Symbol)
+	}
+
+	return tw.Flush()
+}
+
+func renderSystem(w io.Writer, summary m.SystemSummary, asJSON bool) error {
+	if asJSON {
+		return renderJSON(w, summary)
+	}
+
+	_, err := io.WriteString(w, bot.RenderSystemSummary(summary))
+
+	return err
+}
+
+// renderTimeline writes telemetry records to w as a human-readable timeline.
+func renderTimeline(w io.Writer, records []telemetry.Record) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tSHIP\tEVENT\tMISSION\tDETAIL")
+
+	var day string
+	for _, record := range records {
+		at := record.At.Local()
+		if d := at.Format("2006-01-02"); d != day {
+			day = d
+			fmt.Fprintf(tw, "%s\t\t\t\t\n", day)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", at.Format("15:04:05"), record.Ship, record.Type, record.Mission, record.Message)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/bot"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/ledger"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/server"
+	"github.com/GeoffreyDick/gogarin/status"
+	"github.com/GeoffreyDick/gogarin/store"
+	"github.com/GeoffreyDick/gogarin/telemetry"
+)
+
+func testContracts() []m.Contract {
+	offered := m.Contract{ID: "c-offered", Type: "PROCUREMENT", FactionSymbol: "COSMIC"}
+	offered.Terms.Deadline = time.Date(2030, 1, 8, 12, 0, 0, 0, time.UTC)
+	offered.Terms.Payment.OnAccepted = 1000
+	offered.Terms.Payment.OnFulfilled = 4000
+	offered.Terms.Deliver = []m.ContractDeliverGood{{TradeSymbol: "IRON_ORE", DestinationSymbol: "X1-DF55-20250Z", UnitsRequired: 72, UnitsFulfilled: 30}}
+
+	accepted := offered
+	accepted.ID, accepted.Accepted = "c-accepted", true
+
+	fulfilled := accepted
+	fulfilled.ID, fulfilled.Fulfilled = "c-fulfilled", true
+
+	return []m.Contract{offered, accepted, fulfilled}
+}
+
+func testShips() []m.Ship {
+	var ship m.Ship
+	ship.Symbol = "GOGARIN-1"
+	ship.Registration.Role = "COMMAND"
+	ship.Frame.Name = "Frame Frigate"
+	ship.Nav.Status = "DOCKED"
+	ship.Nav.WaypointSymbol = "X1-DF55-20250Z"
+	ship.Cargo.Units, ship.Cargo.Capacity = 12, 40
+	ship.Fuel.Current, ship.Fuel.Capacity = 380, 400
+
+	return []m.Ship{ship}
+}
+
+// withConfig sets the package configuration to the defaults with token for the duration of the test.
+func withConfig(t *testing.T, token string) {
+	t.Helper()
+
+	previous := cfg
+	t.Cleanup(func() { cfg = previous })
+
+	cfg = config.Default()
+	cfg.Token = token
+}
+
+// lines splits rendered table output into its lines with runs of padding collapsed to single spaces.
+func lines(out string) []string {
+	var got []string
+	for _, line := range strings.Split(strings.TrimRight(out, "\n"), "\n") {
+		got = append(got, strings.Join(strings.Fields(line), " "))
+	}
+
+	return got
+}
+
+func assertLines(t *testing.T, out string, want ...string) {
+	t.Helper()
+
+	got := lines(out)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("output:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestRenderStatus(t *testing.T) {
+	agent := &m.Agent{Symbol: "GOGARIN", Headquarters: "X1-DF55-20250Z", Credits: 175000}
+
+	var table bytes.Buffer
+	if err := renderStatus(&table, agent, testContracts(), false); err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, table.String(),
+		"AGENT GOGARIN",
+		"HEADQUARTERS X1-DF55-20250Z",
+		"CREDITS 175000",
+		"CONTRACTS 1 offered, 1 accepted, 1 fulfilled",
+	)
+
+	var out bytes.Buffer
+	if err := renderStatus(&out, agent, testContracts(), true); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Agent     m.Agent      `json:"agent"`
+		Contracts []m.Contract `json:"contracts"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("status --json is not JSON: %s\n%s", err, out.String())
+	}
+	if decoded.Agent.Credits != 175000 || len(decoded.Contracts) != 3 {
+		t.Errorf("status --json = %+v", decoded)
+	}
+}
+
+func TestRenderShips(t *testing.T) {
+	var table bytes.Buffer
+	if err := renderShips(&table, testShips(), nil, false); err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, table.String(),
+		"SYMBOL NAME ROLE FRAME STATUS WAYPOINT CARGO FUEL CR/H",
+		"GOGARIN-1 - COMMAND Frame Frigate DOCKED X1-DF55-20250Z 12/40 380/400 -",
+	)
+
+	var out bytes.Buffer
+	if err := renderShips(&out, testShips(), nil, true); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []m.Ship
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("ships --json is not JSON: %s", err)
+	}
+	if len(decoded) != 1 || decoded[0].Fuel.Current != 380 {
+		t.Errorf("ships --json = %+v", decoded)
+	}
+}
+
+func TestRenderFleetTableDashesMissingCapacity(t *testing.T) {
+	withConfig(t, "")
+
+	var probe m.Ship
+	probe.Symbol = "GOGARIN-3"
+	probe.Registration.Role = "SATELLITE"
+	probe.Frame.Name = "Frame Probe"
+	probe.Nav.Status = "IN_ORBIT"
+	probe.Nav.WaypointSymbol = "X1-DF55-20250Z"
+
+	// The probe has earned nothing, so it has no rate either.
+	rates := ledger.Rates{Ships: []ledger.ShipRate{{Ship: "GOGARIN-1", Credits: 3000, Active: 2 * time.Hour, PerHour: 1500}}}
+	assertLines(t, bot.RenderFleetTable(append(testShips(), probe), nil, cfg, rates),
+		"SYMBOL NAME ROLE FRAME STATUS WAYPOINT CARGO FUEL CR/H",
+		"GOGARIN-1 - COMMAND Frame Frigate DOCKED X1-DF55-20250Z 12/40 380/400 1500",
+		"GOGARIN-3 - SATELLITE Frame Probe IN_ORBIT X1-DF55-20250Z - - -",
+	)
+}
+
+func TestRenderFleetTableMarksShipsLeftToManualControl(t *testing.T) {
+	withConfig(t, "")
+	cfg.IgnoredShips = []string{"GOGARIN-1"}
+
+	assertLines(t, bot.RenderFleetTable(testShips(), nil, cfg, ledger.Rates{}),
+		"SYMBOL NAME ROLE FRAME STATUS WAYPOINT CARGO FUEL CR/H",
+		"GOGARIN-1 - COMMAND Frame Frigate MANUAL X1-DF55-20250Z 12/40 380/400 -",
+	)
+}
+
+func TestShipCommandNamesShipsLocally(t *testing.T) {
+	withConfig(t, "")
+	cfg.MetadataPath = filepath.Join(t.TempDir(), "ships.json")
+
+	// A nil client fails any API call, so renaming and noting stay local.
+	var out bytes.Buffer
+	if err := execute(nil, []string{"ship", "rename", "GOGARIN-1", "Iron", "Maiden"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if err := execute(nil, []string{"ship", "note", "GOGARIN-1", "Flagship"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, out.String(),
+		`GOGARIN-1 is now GOGARIN-1 "Iron Maiden".`,
+		`Notes for GOGARIN-1 "Iron Maiden" saved.`,
+	)
+
+	metadata, err := store.OpenMetadataStore(cfg.MetadataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := metadata.Get("GOGARIN-1"); got != (store.ShipMetadata{Nickname: "Iron Maiden", Notes: "Flagship"}) {
+		t.Errorf("metadata = %+v", got)
+	}
+	assertLines(t, bot.RenderFleetTable(testShips(), metadata, cfg, ledger.Rates{}),
+		"SYMBOL NAME ROLE FRAME STATUS WAYPOINT CARGO FUEL CR/H",
+		"GOGARIN-1 Iron Maiden COMMAND Frame Frigate DOCKED X1-DF55-20250Z 12/40 380/400 -",
+	)
+
+	if err := execute(nil, []string{"ship", "paint", "GOGARIN-1"}, &out); err == nil {
+		t.Error("unknown ship action: no error")
+	}
+}
+
+func TestRenderContracts(t *testing.T) {
+	var table bytes.Buffer
+	if err := renderContracts(&table, testContracts()[1:2], false); err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, table.String(),
+		"ID TYPE FACTION STATUS DELIVER PAYMENT DEADLINE",
+		"c-accepted PROCUREMENT COSMIC accepted IRON_ORE 30/72 5000 2030-01-08T12:00:00Z",
+	)
+}
+
+func TestRenderMarket(t *testing.T) {
+	market := &m.Market{Symbol: "X1-DF55-20250Z"}
+	market.Imports = []m.TradeGood{{Symbol: "IRON_ORE"}}
+	market.Exports = []m.TradeGood{{Symbol: "IRON"}}
+	market.Exchange = []m.TradeGood{{Symbol: "FUEL"}}
+
+	var unpriced bytes.Buffer
+	if err := renderMarket(&unpriced, market, false); err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, unpriced.String(),
+		"MARKET X1-DF55-20250Z",
+		"",
+		"SYMBOL TRADE",
+		"IRON_ORE IMPORT",
+		"IRON EXPORT",
+		"FUEL EXCHANGE",
+	)
+
+	market.TradeGoods = []m.MarketTradeGood{{Symbol: "IRON_ORE", Supply: "MODERATE", PurchasePrice: 52, SellPrice: 45, TradeVolume: 100}}
+
+	var priced bytes.Buffer
+	if err := renderMarket(&priced, market, false); err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, priced.String(),
+		"MARKET X1-DF55-20250Z",
+		"",
+		"SYMBOL SUPPLY PURCHASE SELL VOLUME",
+		"IRON_ORE MODERATE 52 45 100",
+	)
+}
+
+// fleetPause is a server.PauseControl recording whether it is paused.
+type fleetPause struct {
+	paused bool
+}
+
+func (p *fleetPause) Pause()       { p.paused = true }
+func (p *fleetPause) Resume()      { p.paused = false }
+func (p *fleetPause) Paused() bool { return p.paused }
+
+func TestPauseAndResumeCommandsControlTheRunningBot(t *testing.T) {
+	withConfig(t, "")
+
+	control := &fleetPause{}
+	s := server.New("", status.NewBoard(), ledger.New(0), nil, server.WithPauseControl(control))
+	running := httptest.NewServer(s.Handler())
+	t.Cleanup(running.Close)
+
+	var out bytes.Buffer
+	if err := execute(nil, []string{"pause", "--http", running.URL}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !control.paused {
+		t.Error("gogarin pause did not pause the fleet")
+	}
+
+	cfg.HTTPAddr = strings.TrimPrefix(running.URL, "http://")
+	if err := execute(nil, []string{"resume"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if control.paused {
+		t.Error("gogarin resume did not resume the fleet")
+	}
+	assertLines(t, out.String(), "Fleet paused.", "Fleet running.")
+
+	cfg.HTTPAddr = ""
+	if err := execute(nil, []string{"pause"}, &out); err == nil || !strings.Contains(err.Error(), "--http") {
+		t.Errorf("pause without a status server: err = %v, want the usage", err)
+	}
+}
+
+func TestStatusServerURL(t *testing.T) {
+	for addr, want := range map[string]string{
+		":8080":                  "http://localhost:8080",
+		"127.0.0.1:8080":         "http://127.0.0.1:8080",
+		"http://bot.local:8080/": "http://bot.local:8080",
+	} {
+		if got := statusServerURL(addr); got != want {
+			t.Errorf("statusServerURL(%q) = %q, want %q", addr, got, want)
+		}
+	}
+}
+
+func TestExecuteRejectsBadArguments(t *testing.T) {
+	withConfig(t, "token")
+
+	if err := execute(nil, []string{"fly"}, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "usage:") {
+		t.Errorf("unknown command: err = %v, want the usage", err)
+	}
+	if err := execute(nil, []string{"market"}, &bytes.Buffer{}); err == nil {
+		t.Error("market without a waypoint: no error")
+	}
+	if err := execute(nil, []string{"market", "X1-DF55", "X1-DF55-20250Z"}, &bytes.Buffer{}); err == nil {
+		t.Error("market with a system and a waypoint: no error")
+	}
+	if err := execute(nil, []string{"ships", "--verbose"}, &bytes.Buffer{}); err == nil {
+		t.Error("unknown flag: no error")
+	}
+}
+
+func TestExecuteRequiresATokenForAPICommands(t *testing.T) {
+	withConfig(t, "")
+
+	if err := execute(nil, []string{"ships"}, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "TOKEN") {
+		t.Errorf("ships without a token: err = %v", err)
+	}
+}
+
+func TestReplayRendersATimeline(t *testing.T) {
+	withConfig(t, "")
+
+	path := filepath.Join(t.TempDir(), "telemetry.jsonl")
+	w, err := telemetry.Open(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := time.Date(2030, 1, 1, 23, 59, 0, 0, time.Local)
+	w.Write(event.Event{Type: event.MissionStarted, At: first, Ship: "GOGARIN-2", Mission: "mine", Message: "at X1-DF55-17335A"})
+	w.Write(event.Event{Type: event.CargoSold, At: first.Add(2 * time.Minute), Ship: "GOGARIN-2", Mission: "sell"})
+	w.Close()
+
+	var out bytes.Buffer
+	if err := execute(nil, []string{"replay", path}, &out); err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, out.String(),
+		"TIME SHIP EVENT MISSION DETAIL",
+		"2030-01-01",
+		"23:59:00 GOGARIN-2 MISSION_STARTED mine at X1-DF55-17335A",
+		"2030-01-02",
+		"00:01:00 GOGARIN-2 CARGO_SOLD sell",
+	)
+
+	if err := os.WriteFile(path, []byte("{broken\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := execute(nil, []string{"replay", path}, &out); err == nil {
+		t.Error("replayed a corrupt file")
+	}
+}
+
+func TestMarketCommandTakesAWaypoint(t *testing.T) {
+	withConfig(t, "token")
+	s, c := startMock(t, 0)
+
+	var out bytes.Buffer
+	if err := execute(c, []string{"market", "X1-MK1-A1"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if got := lines(out.String()); got[0] != "MARKET X1-MK1-A1" {
+		t.Errorf("output:\n%s", out.String())
+	}
+	if s.Requests("GET", "/systems/X1-MK1/waypoints/X1-MK1-A1/market") != 1 {
+		t.Error("did not get the market of the waypoint's system")
+	}
+}
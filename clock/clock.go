@@ -0,0 +1,81 @@
+// Package clock abstracts the passing of time, so waits, cooldowns, and caches can run against a Fake clock
+// that is advanced by hand, or skips ahead whenever something sleeps, instead of the wall clock.
+package clock
+
+import "time"
+
+// Clock tells the time and waits. Real is the wall clock.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// Sleep blocks for d.
+	Sleep(d time.Duration)
+	// After returns a channel receiving the time once d has passed.
+	After(d time.Duration) <-chan time.Time
+	// NewTimer creates a Timer firing once d has passed.
+	NewTimer(d time.Duration) Timer
+	// AfterFunc calls f on its own goroutine once d has passed.
+	AfterFunc(d time.Duration, f func()) Timer
+	// NewTicker creates a Ticker firing every d.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer fires once, like time.Timer.
+type Timer interface {
+	// C receives the time the Timer fired. It is nil for a Timer created by AfterFunc.
+	C() <-chan time.Time
+	// Stop prevents the Timer from firing, reporting whether it was still pending.
+	Stop() bool
+}
+
+// Ticker fires repeatedly, like time.Ticker.
+type Ticker interface {
+	// C receives the time of each tick. Ticks are dropped while a previous one is unread.
+	C() <-chan time.Time
+	// Stop turns off the Ticker.
+	Stop()
+}
+
+// Real is the wall clock, backed by the time package.
+var Real Clock = realClock{}
+
+// Or returns c, or Real if c is nil.
+func Or(c Clock) Clock {
+	if c == nil {
+		return Real
+	}
+
+	return c
+}
+
+/*
+🕰️ Real
+*/
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTimer struct{ t *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.t.C }
+func (t realTimer) Stop() bool          { return t.t.Stop() }
+
+type realTicker struct{ t *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.t.C }
+func (t realTicker) Stop()               { t.t.Stop() }
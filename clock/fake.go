@@ -0,0 +1,216 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+⏩ Fake
+*/
+
+// Fake is a Clock whose time only moves when Advance is called or, once AutoAdvance is set, when something
+// sleeps. Timers, tickers, and sleepers fire as the time they wait for is reached. It is safe for concurrent use.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	auto    bool
+	waiters []*waiter
+}
+
+// waiter is a pending timer, ticker, or sleeper.
+type waiter struct {
+	at time.Time
+	// period is the interval of a ticker, or zero for a timer.
+	period time.Duration
+	ch     chan time.Time
+	fn     func()
+}
+
+// NewFake creates a Fake clock reading start.
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start}
+}
+
+// AutoAdvance makes Sleep move the clock forward by the time slept instead of blocking, firing every timer
+// due on the way, so a run of waits and cooldowns completes at once. Timers still wait for the clock to move.
+func (f *Fake) AutoAdvance() *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.auto = true
+
+	return f
+}
+
+// Now returns the fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+// Advance moves the clock forward by d, firing every timer, ticker, and sleeper due by then in order.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.advanceTo(f.now.Add(d))
+}
+
+// Waiters returns the number of pending timers, tickers, and sleepers, so a test can wait until the code
+// under test is blocked on the clock before advancing it.
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.waiters)
+}
+
+// Sleep blocks until the clock has advanced by d, or advances it by d with AutoAdvance.
+func (f *Fake) Sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	f.mu.Lock()
+	if f.auto {
+		f.advanceTo(f.now.Add(d))
+		f.mu.Unlock()
+		return
+	}
+	f.mu.Unlock()
+
+	<-f.After(d)
+}
+
+// After returns a channel receiving the fake time once the clock has advanced by d.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// NewTimer creates a Timer firing once the clock has advanced by d.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	w := &waiter{ch: make(chan time.Time, 1)}
+	f.add(w, d)
+
+	return &fakeTimer{f, w}
+}
+
+// AfterFunc calls fn on its own goroutine once the clock has advanced by d.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	w := &waiter{fn: fn}
+	f.add(w, d)
+
+	return &fakeTimer{f, w}
+}
+
+// NewTicker creates a Ticker firing every time the clock advances by d.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+
+	w := &waiter{period: d, ch: make(chan time.Time, 1)}
+	f.add(w, d)
+
+	return &fakeTicker{f, w}
+}
+
+// add schedules w to fire after d, or fires it straight away if d is not positive.
+func (f *Fake) add(w *waiter, d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if d <= 0 {
+		f.fire(w)
+		return
+	}
+
+	w.at = f.now.Add(d)
+	f.waiters = append(f.waiters, w)
+}
+
+// remove unschedules w, reporting whether it was pending. It must be called with mu held.
+func (f *Fake) remove(w *waiter) bool {
+	for i, pending := range f.waiters {
+		if pending == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// advanceTo moves the clock to t, firing due waiters in the order they fall due. Each fires with the clock
+// reading its due time, and tickers are rescheduled for their next tick. It must be called with mu held.
+func (f *Fake) advanceTo(t time.Time) {
+	for {
+		sort.SliceStable(f.waiters, func(i, j int) bool { return f.waiters[i].at.Before(f.waiters[j].at) })
+
+		if len(f.waiters) == 0 || f.waiters[0].at.After(t) {
+			break
+		}
+
+		w := f.waiters[0]
+		if w.at.After(f.now) {
+			f.now = w.at
+		}
+		f.fire(w)
+
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			f.waiters = f.waiters[1:]
+		}
+	}
+
+	if t.After(f.now) {
+		f.now = t
+	}
+}
+
+// fire delivers the current time to w, dropping it if the previous one is unread, or calls its function.
+// It must be called with mu held.
+func (f *Fake) fire(w *waiter) {
+	if w.fn != nil {
+		go w.fn()
+		return
+	}
+
+	select {
+	case w.ch <- f.now:
+	default:
+	}
+}
+
+type fakeTimer struct {
+	f *Fake
+	w *waiter
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.w.ch }
+
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+
+	return t.f.remove(t.w)
+}
+
+type fakeTicker struct {
+	f *Fake
+	w *waiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.w.ch }
+
+func (t *fakeTicker) Stop() {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+
+	t.f.remove(t.w)
+}
@@ -0,0 +1,107 @@
+package clock
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeFiresTimersInOrderAsItAdvances(t *testing.T) {
+	f := NewFake(start)
+
+	late := f.NewTimer(2 * time.Minute)
+	early := f.NewTimer(time.Minute)
+	stopped := f.NewTimer(30 * time.Second)
+	if !stopped.Stop() {
+		t.Fatal("stopping a pending timer reported it was not pending")
+	}
+
+	var (
+		mu    sync.Mutex
+		fired []time.Time
+		done  = make(chan struct{})
+	)
+	f.AfterFunc(90*time.Second, func() {
+		mu.Lock()
+		fired = append(fired, f.Now())
+		mu.Unlock()
+		close(done)
+	})
+
+	f.Advance(time.Minute)
+	select {
+	case at := <-early.C():
+		if !at.Equal(start.Add(time.Minute)) {
+			t.Fatalf("early timer fired at %s, want %s", at, start.Add(time.Minute))
+		}
+	default:
+		t.Fatal("timer due after a minute did not fire when the clock advanced a minute")
+	}
+	select {
+	case <-late.C():
+		t.Fatal("timer due after two minutes fired after one")
+	default:
+	}
+
+	f.Advance(time.Minute)
+	if at := <-late.C(); !at.Equal(start.Add(2 * time.Minute)) {
+		t.Fatalf("late timer fired at %s, want %s", at, start.Add(2*time.Minute))
+	}
+	<-done
+	select {
+	case <-stopped.C():
+		t.Fatal("stopped timer fired")
+	default:
+	}
+	if f.Waiters() != 0 {
+		t.Fatalf("%d waiters left, want 0", f.Waiters())
+	}
+}
+
+func TestFakeTickerTicksEachPeriod(t *testing.T) {
+	f := NewFake(start)
+	ticker := f.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for i := 1; i <= 3; i++ {
+		f.Advance(10 * time.Second)
+		if at := <-ticker.C(); !at.Equal(start.Add(time.Duration(i) * 10 * time.Second)) {
+			t.Fatalf("tick %d at %s", i, at)
+		}
+	}
+}
+
+func TestFakeSleepBlocksUntilAdvanced(t *testing.T) {
+	f := NewFake(start)
+
+	woke := make(chan time.Time)
+	go func() {
+		f.Sleep(time.Hour)
+		woke <- f.Now()
+	}()
+
+	for f.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	f.Advance(time.Hour)
+
+	if at := <-woke; !at.Equal(start.Add(time.Hour)) {
+		t.Fatalf("woke at %s, want %s", at, start.Add(time.Hour))
+	}
+}
+
+func TestFakeAutoAdvanceSleepsInstantly(t *testing.T) {
+	f := NewFake(start).AutoAdvance()
+	timer := f.NewTimer(30 * time.Minute)
+
+	f.Sleep(time.Hour)
+
+	if !f.Now().Equal(start.Add(time.Hour)) {
+		t.Fatalf("now = %s after sleeping an hour, want %s", f.Now(), start.Add(time.Hour))
+	}
+	if at := <-timer.C(); !at.Equal(start.Add(30 * time.Minute)) {
+		t.Fatalf("timer passed while sleeping fired at %s, want %s", at, start.Add(30*time.Minute))
+	}
+}
@@ -0,0 +1,1019 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/lib"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is the config file read when GOGARIN_CONFIG is not set.
+const DefaultPath = "gogarin.yaml"
+
+// Config is the bot configuration.
+// Values come from defaults, then the optional config file, then environment variables.
+//
+// A running bot reloads the configuration on SIGHUP (see Live). The agent's credentials and the API client
+// (Token, Symbol, Faction, Agents, BaseURL, RateLimit, PageWorkers, RequestCaps), logging, the status server,
+// the files the bot writes, Notify, Expansion.Interval, Purchase.Interval, and Purchase.PriceTTL are only read
+// at startup; changing them requires a restart, and the reloader ignores them.
+type Config struct {
+	// Token is the agent's bearer token. Env: TOKEN.
+	Token string `yaml:"token"`
+	// Symbol is the agent callsign used when registering. Env: GOGARIN_SYMBOL.
+	Symbol string `yaml:"symbol"`
+	// Faction is the starting faction used when registering. Env: GOGARIN_FACTION.
+	Faction string `yaml:"faction"`
+	// Agents, when set, runs several agents in one process instead of the agent of Token. Each agent runs in
+	// isolation, with its own client and request budget, stores, and files (see ForAgent); they share the
+	// status server, which serves each agent's routes under /agents/NAME/, and the logs.
+	Agents []AgentConfig `yaml:"agents"`
+	// BaseURL, when set, replaces the SpaceTraders API URL, e.g. to run against a mock server. Env: GOGARIN_BASE_URL.
+	BaseURL string `yaml:"baseURL"`
+	// RateLimit is the maximum number of API requests per second. Env: GOGARIN_RATE_LIMIT.
+	RateLimit int `yaml:"rateLimit"`
+	// PageWorkers is the number of pages of a list endpoint fetched concurrently. Env: GOGARIN_PAGE_WORKERS.
+	PageWorkers int `yaml:"pageWorkers"`
+	// LogLevel is one of debug, info, warn, or error. Env: GOGARIN_LOG_LEVEL.
+	LogLevel string `yaml:"logLevel"`
+	// LogFormat is text or json. Env: GOGARIN_LOG_FORMAT.
+	LogFormat string `yaml:"logFormat"`
+	// LogOnly, when set, suppresses Info and Debug lines from ships other than this ship symbol. Env: GOGARIN_LOG_ONLY.
+	LogOnly string `yaml:"logOnly"`
+	// HTTPAddr, when set, is the address of the read-only status server. Env: GOGARIN_HTTP.
+	HTTPAddr string `yaml:"httpAddr"`
+	// Metrics serves bot metrics at /metrics on the status server. Env: GOGARIN_METRICS.
+	Metrics bool `yaml:"metrics"`
+	// TelemetryPath, when set, is the JSONL file significant actions are appended to. Env: GOGARIN_TELEMETRY.
+	TelemetryPath string `yaml:"telemetryPath"`
+	// ReportPath, when set, is the file a JSON report summing up the run is written to when the bot exits. The
+	// report is always logged. Env: GOGARIN_REPORT.
+	ReportPath string `yaml:"reportPath"`
+	// RecordDir, when set, is the directory every API response is recorded to, for building fixtures.
+	// Env: GOGARIN_RECORD.
+	RecordDir string `yaml:"recordDir"`
+	// JournalPath is the file unfinished multi-step missions are recorded to, so they resume after a restart.
+	// Empty keeps the journal in memory only. Env: GOGARIN_JOURNAL.
+	JournalPath string `yaml:"journalPath"`
+	// MetadataPath is the file ship nicknames and notes are kept in. Empty keeps them in memory only.
+	// Env: GOGARIN_SHIP_METADATA.
+	MetadataPath string `yaml:"metadataPath"`
+	// ShipyardPath is the file observed shipyard prices are kept in. Empty keeps them in memory only.
+	// Env: GOGARIN_SHIPYARDS.
+	ShipyardPath string `yaml:"shipyardPath"`
+	// FuelCostPath is the file the fuel each route actually burns is learned in, per server reset. Empty keeps
+	// it in memory only. Env: GOGARIN_FUEL_COSTS.
+	FuelCostPath string `yaml:"fuelCostPath"`
+	// VisitPath is the file what ships did at the waypoints they visited is remembered in, per server reset.
+	// Empty keeps it in memory only. Env: GOGARIN_VISITS.
+	VisitPath string `yaml:"visitPath"`
+	// StatePath is the file a final snapshot of the run's state is written to on shutdown, marking that it shut
+	// down cleanly. Without the mark, the next run checks journalled missions against the API before resuming
+	// them. Empty keeps no snapshot, so every run checks. Env: GOGARIN_STATE.
+	StatePath string `yaml:"statePath"`
+	// TelemetryMaxBytes is the size at which the telemetry file is rotated. Zero disables rotation.
+	TelemetryMaxBytes int64 `yaml:"telemetryMaxBytes"`
+	// Strategy decides each ship's missions: mining, contract, trading, or exploring. Env: GOGARIN_STRATEGY.
+	Strategy string `yaml:"strategy"`
+	// IdleInterval is how long a ship without a mission waits before reporting in again. Env: GOGARIN_IDLE_INTERVAL.
+	IdleInterval time.Duration `yaml:"idleInterval"`
+	// CargoThreshold is the fraction of cargo capacity at which a ship stops mining to sell. Env: GOGARIN_CARGO_THRESHOLD.
+	CargoThreshold float64 `yaml:"cargoThreshold"`
+	// FuelReserve is the fraction of fuel capacity a ship keeps in reserve. Env: GOGARIN_FUEL_RESERVE.
+	FuelReserve float64 `yaml:"fuelReserve"`
+	// FuelPriceCeiling is the fuel price above which a ship buys only enough fuel to reach a cheaper fuel stop
+	// on its route. Zero means no ceiling. Env: GOGARIN_FUEL_PRICE_CEILING.
+	FuelPriceCeiling int64 `yaml:"fuelPriceCeiling"`
+	// MarketRefresh is how old a recorded market must be before a ship visiting it records it again.
+	// Env: GOGARIN_MARKET_REFRESH.
+	MarketRefresh time.Duration `yaml:"marketRefresh"`
+	// TradeMaxPriceAge is the oldest market observation a trade route is planned from. Env: GOGARIN_TRADE_MAX_PRICE_AGE.
+	TradeMaxPriceAge time.Duration `yaml:"tradeMaxPriceAge"`
+	// TradeMargin is the profit, after estimated fuel, a trade route must exceed. Env: GOGARIN_TRADE_MARGIN.
+	TradeMargin int64 `yaml:"tradeMargin"`
+	// SellPriceDrop is how far below a market's recorded price, as a fraction of it, a sale may realize before
+	// the market is fetched again and the rest of the cargo re-scored against other markets. Zero disables the
+	// check. Env: GOGARIN_SELL_PRICE_DROP.
+	SellPriceDrop float64 `yaml:"sellPriceDrop"`
+	// ExploreBudget is the most waypoints an exploration mission visits. Zero means no limit.
+	// Env: GOGARIN_EXPLORE_BUDGET.
+	ExploreBudget int `yaml:"exploreBudget"`
+	// EarningsWindow is how far back the credits each ship and strategy earn per hour are rated over.
+	// Env: GOGARIN_EARNINGS_WINDOW.
+	EarningsWindow time.Duration `yaml:"earningsWindow"`
+	// EarningsSplit attributes a contract's payment to the ship that fulfilled it (seller), or splits it among
+	// the ships that delivered to it by units delivered (contributors). Env: GOGARIN_EARNINGS_SPLIT.
+	EarningsSplit string `yaml:"earningsSplit"`
+	// CreditFloor is the balance spending never takes the agent below. Env: GOGARIN_CREDIT_FLOOR.
+	CreditFloor int64 `yaml:"creditFloor"`
+	// UnsellablePolicy decides what happens to cargo no known market buys: retain keeps it, jettison dumps it,
+	// and auto keeps contract and priority goods and dumps the rest. Env: GOGARIN_UNSELLABLE_POLICY.
+	UnsellablePolicy string `yaml:"unsellablePolicy"`
+	// Jettison guards dumping cargo no known market buys.
+	Jettison JettisonConfig `yaml:"jettison"`
+	// ReservedGoods are cargo a ship never sells, in addition to ANTIMATTER.
+	ReservedGoods []string `yaml:"reservedGoods"`
+	// MiningTargetTypes, when set, replaces the waypoint types excavators mine at: ASTEROID_FIELD, ASTEROID,
+	// ENGINEERED_ASTEROID, and ASTEROID_BASE.
+	MiningTargetTypes []string `yaml:"miningTargetTypes"`
+	// MaxShipsPerTarget is the most ships sent to, or mining at, the same mining target. Further ships go to the
+	// next-best target, or to the best one if every target is full. Zero means no limit.
+	// Env: GOGARIN_MAX_SHIPS_PER_TARGET.
+	MaxShipsPerTarget int `yaml:"maxShipsPerTarget"`
+	// ControlledShips, when set, are the only ships the bot commands. Other ships are left to be flown manually.
+	// Env: GOGARIN_CONTROLLED_SHIPS, comma-separated.
+	ControlledShips []string `yaml:"controlledShips"`
+	// IgnoredShips are never commanded by the bot, so they can be flown manually. Env: GOGARIN_IGNORED_SHIPS,
+	// comma-separated.
+	IgnoredShips []string `yaml:"ignoredShips"`
+	// RequestCaps limit subsystems to a share of the request budget: missions, reconcile, market-poll, and
+	// interactive. Over its cap, a subsystem's requests wait while other subsystems' proceed.
+	RequestCaps map[string]float64 `yaml:"requestCaps"`
+	// FleetPlan is the target number of ships per role.
+	FleetPlan map[string]int `yaml:"fleetPlan"`
+	// Roles overrides the tuning values above for ships of a given role.
+	Roles map[string]RoleConfig `yaml:"roles"`
+	// Notify configures webhook notifications for significant events.
+	Notify NotifyConfig `yaml:"notify"`
+	// Expansion configures moving part of the fleet to a richer neighbouring system.
+	Expansion ExpansionConfig `yaml:"expansion"`
+	// Purchase configures timing ship purchases by the shipyard prices satellites observe.
+	Purchase PurchaseConfig `yaml:"purchase"`
+	// MissionTimeouts bounds how long each kind of mission may run.
+	MissionTimeouts MissionTimeoutConfig `yaml:"missionTimeouts"`
+	// Condition sets the frame, reactor, and engine conditions at which a ship is warned about and repaired.
+	Condition ConditionConfig `yaml:"condition"`
+}
+
+// AgentConfig is one of several agents run by one process.
+type AgentConfig struct {
+	// Name identifies the agent in logs, file names, and status routes. Letters, digits, '-', and '_' only.
+	Name string `yaml:"name"`
+	// Token is the agent's bearer token. Env: TOKEN_NAME, with the name upper-cased and '-' replaced by '_'.
+	Token string `yaml:"token"`
+	// Symbol is the agent's callsign. Empty uses the top-level symbol.
+	Symbol string `yaml:"symbol"`
+	// Faction is the agent's starting faction. Empty uses the top-level faction.
+	Faction string `yaml:"faction"`
+}
+
+// TokenEnv returns the environment variable the agent's token is read from.
+func (a AgentConfig) TokenEnv() string {
+	return "TOKEN_" + strings.ToUpper(strings.ReplaceAll(a.Name, "-", "_"))
+}
+
+// JettisonConfig guards the unsellable policy's jettisons. Cargo is valued at the best price recorded at any
+// market, and is only jettisoned when that estimate is fresh and below MaxValue; otherwise it is held.
+type JettisonConfig struct {
+	// MaxValue is the estimated value, in credits, at or above which cargo is held rather than jettisoned.
+	// Env: GOGARIN_JETTISON_MAX_VALUE.
+	MaxValue int64 `yaml:"maxValue"`
+	// FreshAge is the oldest market data a value estimate is trusted from.
+	FreshAge time.Duration `yaml:"freshAge"`
+	// DryRun logs what would be jettisoned, and holds it instead. Env: GOGARIN_JETTISON_DRY_RUN.
+	DryRun bool `yaml:"dryRun"`
+}
+
+// ExpansionConfig configures fleet expansion. Expansion is disabled when Interval is zero.
+type ExpansionConfig struct {
+	// Interval is how often the systems connected to the home system are evaluated. Env: GOGARIN_EXPANSION_INTERVAL.
+	Interval time.Duration `yaml:"interval"`
+	// Margin is how much better than the home system, as a fraction of its score, a neighbour must score.
+	Margin float64 `yaml:"margin"`
+	// Share is the fraction of the home system's excavators that move to the neighbour.
+	Share float64 `yaml:"share"`
+}
+
+// ConditionConfig sets the thresholds a ship's frame, reactor, and engine condition, out of 100, is watched
+// against. A ship is alerted about each time one of them drops below a threshold, and is sent to be repaired,
+// ahead of new work, once one is below Critical. Zero disables a threshold.
+type ConditionConfig struct {
+	// Warn is the condition below which a component is logged as a warning. Env: GOGARIN_CONDITION_WARN.
+	Warn int `yaml:"warn"`
+	// Critical is the condition below which a component is logged as an error and the ship repaired.
+	// Env: GOGARIN_CONDITION_CRITICAL.
+	Critical int `yaml:"critical"`
+}
+
+// PurchaseConfig configures buying ships while the fleet is below its plan. A ship is bought when a shipyard's
+// latest price dips below its rolling average. Timed purchases are disabled when Interval is zero.
+type PurchaseConfig struct {
+	// Interval is how often observed shipyard prices are checked for a dip. Env: GOGARIN_PURCHASE_INTERVAL.
+	Interval time.Duration `yaml:"interval"`
+	// PriceTTL is how long an observed price counts towards the rolling average.
+	PriceTTL time.Duration `yaml:"priceTTL"`
+	// Dip is how far below the rolling average, as a fraction of it, the latest price must be.
+	Dip float64 `yaml:"dip"`
+	// MinSamples is the number of earlier prices the rolling average needs before a dip is trusted.
+	MinSamples int `yaml:"minSamples"`
+}
+
+// MissionTimeoutConfig bounds how long a mission may run before it is abandoned and its ship recovered, so a
+// mission that never ends cannot hold its ship. Every flight and idle wait extends a mission's deadline to the
+// end of the wait plus Slack. A zero budget never times those missions out.
+type MissionTimeoutConfig struct {
+	// Extraction bounds extracting resources.
+	Extraction time.Duration `yaml:"extraction"`
+	// Sell bounds selling cargo.
+	Sell time.Duration `yaml:"sell"`
+	// Slack bounds a navigation mission before its flight starts, and is allowed past every wait.
+	Slack time.Duration `yaml:"slack"`
+	// Default bounds every other mission.
+	Default time.Duration `yaml:"default"`
+}
+
+// NotifyConfig configures webhook notifications. Notifications are disabled when no URL is set.
+type NotifyConfig struct {
+	// WebhookURL receives each notification as a JSON POST. Env: GOGARIN_WEBHOOK_URL.
+	WebhookURL string `yaml:"webhookURL"`
+	// DiscordURL is a Discord webhook receiving each notification as an embed. Env: GOGARIN_DISCORD_URL.
+	DiscordURL string `yaml:"discordURL"`
+	// Events enables or disables each kind of notification:
+	// contractFulfilled, shipPurchased, creditsThreshold, missionFailed, deadlineApproaching, creditDrift, and
+	// conditionDegraded.
+	Events map[string]bool `yaml:"events"`
+	// CreditThresholds are the credit balances that trigger a notification when crossed.
+	CreditThresholds []int64 `yaml:"creditThresholds"`
+	// FailureThreshold is the number of consecutive failures of a mission that triggers a notification.
+	FailureThreshold int `yaml:"failureThreshold"`
+	// RateLimit is the maximum number of notifications sent per RateWindow.
+	RateLimit int `yaml:"rateLimit"`
+	// RateWindow is the window RateLimit applies to.
+	RateWindow time.Duration `yaml:"rateWindow"`
+}
+
+// Enabled reports whether any notification sink is configured.
+func (n NotifyConfig) Enabled() bool {
+	return n.WebhookURL != "" || n.DiscordURL != ""
+}
+
+// Strategies are the names of the strategies a ship can follow.
+var Strategies = []string{"mining", "contract", "trading", "exploring"}
+
+// Subsystems are the names of the subsystems requests are counted against.
+var Subsystems = []string{"missions", "reconcile", "market-poll", "interactive"}
+
+// IsStrategy checks if name is one of Strategies.
+func IsStrategy(name string) bool {
+	for _, strategy := range Strategies {
+		if strategy == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsSubsystem checks if name is one of Subsystems.
+func IsSubsystem(name string) bool {
+	for _, subsystem := range Subsystems {
+		if subsystem == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RoleConfig overrides tuning values for a single ship role. Unset values fall back to the top-level Config.
+type RoleConfig struct {
+	Strategy       *string        `yaml:"strategy"`
+	IdleInterval   *time.Duration `yaml:"idleInterval"`
+	CargoThreshold *float64       `yaml:"cargoThreshold"`
+	FuelReserve    *float64       `yaml:"fuelReserve"`
+	// Ignored leaves ships of the role to be flown manually, unless they are listed in ControlledShips.
+	Ignored bool `yaml:"ignored"`
+}
+
+// RoleTuning is the resolved tuning for a ship role.
+type RoleTuning struct {
+	Strategy       string
+	IdleInterval   time.Duration
+	CargoThreshold float64
+	FuelReserve    float64
+}
+
+// Default returns the configuration used when neither a config file nor environment variables are set.
+func Default() *Config {
+	return &Config{
+		RateLimit:         2,
+		PageWorkers:       1,
+		LogLevel:          "info",
+		LogFormat:         "text",
+		TelemetryMaxBytes: 10 << 20,
+		JournalPath:       "gogarin.journal.json",
+		MetadataPath:      "gogarin.ships.json",
+		ShipyardPath:      "gogarin.shipyards.json",
+		FuelCostPath:      "gogarin.fuel.json",
+		VisitPath:         "gogarin.visits.json",
+		StatePath:         "gogarin.state.json",
+		Strategy:          "mining",
+		IdleInterval:      1 * time.Minute,
+		CargoThreshold:    1.0,
+		FuelReserve:       0.1,
+		MarketRefresh:     5 * time.Minute,
+		UnsellablePolicy:  "auto",
+		TradeMaxPriceAge:  15 * time.Minute,
+		TradeMargin:       1000,
+		SellPriceDrop:     0.2,
+		EarningsWindow:    1 * time.Hour,
+		EarningsSplit:     "seller",
+		ExploreBudget:     10,
+		MaxShipsPerTarget: 3,
+		FleetPlan:         map[string]int{},
+		Roles:             map[string]RoleConfig{},
+		Notify: NotifyConfig{
+			Events: map[string]bool{
+				"contractFulfilled":   true,
+				"shipPurchased":       true,
+				"creditsThreshold":    true,
+				"missionFailed":       true,
+				"deadlineApproaching": true,
+				"creditDrift":         true,
+				"conditionDegraded":   true,
+			},
+			FailureThreshold: 3,
+			RateLimit:        10,
+			RateWindow:       1 * time.Hour,
+		},
+		Jettison: JettisonConfig{
+			MaxValue: 1000,
+			FreshAge: 30 * time.Minute,
+		},
+		Expansion: ExpansionConfig{
+			Margin: 0.5,
+			Share:  0.5,
+		},
+		Purchase: PurchaseConfig{
+			PriceTTL:   24 * time.Hour,
+			Dip:        0.05,
+			MinSamples: 3,
+		},
+		MissionTimeouts: MissionTimeoutConfig{
+			Extraction: 30 * time.Minute,
+			Sell:       10 * time.Minute,
+			Slack:      5 * time.Minute,
+			Default:    1 * time.Hour,
+		},
+		Condition: ConditionConfig{
+			Warn:     50,
+			Critical: 25,
+		},
+	}
+}
+
+// Load builds the configuration from defaults, the config file at path (if it exists), and the environment.
+// An empty path means DefaultPath.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path == "" {
+		path = DefaultPath
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// applyEnv overrides configuration values with any environment variables that are set.
+func (c *Config) applyEnv() error {
+	if v, ok := os.LookupEnv("TOKEN"); ok {
+		c.Token = v
+	}
+	for i := range c.Agents {
+		if v, ok := os.LookupEnv(c.Agents[i].TokenEnv()); ok {
+			c.Agents[i].Token = v
+		}
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_SYMBOL"); ok {
+		c.Symbol = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_FACTION"); ok {
+		c.Faction = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_BASE_URL"); ok {
+		c.BaseURL = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_LOG_LEVEL"); ok {
+		c.LogLevel = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_LOG_FORMAT"); ok {
+		c.LogFormat = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_LOG_ONLY"); ok {
+		c.LogOnly = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_HTTP"); ok {
+		c.HTTPAddr = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_METRICS"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_METRICS: %w", err)
+		}
+		c.Metrics = b
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_TELEMETRY"); ok {
+		c.TelemetryPath = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_RECORD"); ok {
+		c.RecordDir = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_JOURNAL"); ok {
+		c.JournalPath = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_SHIP_METADATA"); ok {
+		c.MetadataPath = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_REPORT"); ok {
+		c.ReportPath = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_SHIPYARDS"); ok {
+		c.ShipyardPath = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_FUEL_COSTS"); ok {
+		c.FuelCostPath = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_VISITS"); ok {
+		c.VisitPath = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_STATE"); ok {
+		c.StatePath = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_CONTROLLED_SHIPS"); ok {
+		c.ControlledShips = splitList(v)
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_IGNORED_SHIPS"); ok {
+		c.IgnoredShips = splitList(v)
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_EXPANSION_INTERVAL"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_EXPANSION_INTERVAL: %w", err)
+		}
+		c.Expansion.Interval = d
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_PURCHASE_INTERVAL"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_PURCHASE_INTERVAL: %w", err)
+		}
+		c.Purchase.Interval = d
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_WEBHOOK_URL"); ok {
+		c.Notify.WebhookURL = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_DISCORD_URL"); ok {
+		c.Notify.DiscordURL = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_CONDITION_WARN"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_CONDITION_WARN: %w", err)
+		}
+		c.Condition.Warn = n
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_CONDITION_CRITICAL"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_CONDITION_CRITICAL: %w", err)
+		}
+		c.Condition.Critical = n
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_RATE_LIMIT"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_RATE_LIMIT: %w", err)
+		}
+		c.RateLimit = n
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_PAGE_WORKERS"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_PAGE_WORKERS: %w", err)
+		}
+		c.PageWorkers = n
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_STRATEGY"); ok {
+		c.Strategy = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_IDLE_INTERVAL"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_IDLE_INTERVAL: %w", err)
+		}
+		c.IdleInterval = d
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_CARGO_THRESHOLD"); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_CARGO_THRESHOLD: %w", err)
+		}
+		c.CargoThreshold = f
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_FUEL_RESERVE"); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_FUEL_RESERVE: %w", err)
+		}
+		c.FuelReserve = f
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_FUEL_PRICE_CEILING"); ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_FUEL_PRICE_CEILING: %w", err)
+		}
+		c.FuelPriceCeiling = n
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_MARKET_REFRESH"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_MARKET_REFRESH: %w", err)
+		}
+		c.MarketRefresh = d
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_TRADE_MAX_PRICE_AGE"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_TRADE_MAX_PRICE_AGE: %w", err)
+		}
+		c.TradeMaxPriceAge = d
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_TRADE_MARGIN"); ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_TRADE_MARGIN: %w", err)
+		}
+		c.TradeMargin = n
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_SELL_PRICE_DROP"); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_SELL_PRICE_DROP: %w", err)
+		}
+		c.SellPriceDrop = f
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_EARNINGS_WINDOW"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_EARNINGS_WINDOW: %w", err)
+		}
+		c.EarningsWindow = d
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_EARNINGS_SPLIT"); ok {
+		c.EarningsSplit = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_UNSELLABLE_POLICY"); ok {
+		c.UnsellablePolicy = v
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_JETTISON_MAX_VALUE"); ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_JETTISON_MAX_VALUE: %w", err)
+		}
+		c.Jettison.MaxValue = n
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_JETTISON_DRY_RUN"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_JETTISON_DRY_RUN: %w", err)
+		}
+		c.Jettison.DryRun = b
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_EXPLORE_BUDGET"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_EXPLORE_BUDGET: %w", err)
+		}
+		c.ExploreBudget = n
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_MAX_SHIPS_PER_TARGET"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_MAX_SHIPS_PER_TARGET: %w", err)
+		}
+		c.MaxShipsPerTarget = n
+	}
+
+	if v, ok := os.LookupEnv("GOGARIN_CREDIT_FLOOR"); ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("GOGARIN_CREDIT_FLOOR: %w", err)
+		}
+		c.CreditFloor = n
+	}
+
+	return nil
+}
+
+// splitList splits a comma-separated environment value, dropping empty items.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// agentName matches the names agents may have, which are used in file names and status routes.
+var agentName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Validate checks that the configuration values are usable. Symbols are normalized first (see normalizeSymbols).
+func (c *Config) Validate() error {
+	if err := c.normalizeSymbols(); err != nil {
+		return err
+	}
+
+	if c.RateLimit <= 0 {
+		return fmt.Errorf("rateLimit must be positive, got %d", c.RateLimit)
+	}
+
+	if c.PageWorkers <= 0 {
+		return fmt.Errorf("pageWorkers must be positive, got %d", c.PageWorkers)
+	}
+
+	names := make(map[string]bool, len(c.Agents))
+	for i, agent := range c.Agents {
+		if !agentName.MatchString(agent.Name) {
+			return fmt.Errorf("agents[%d].name must be letters, digits, '-', and '_', got %q", i, agent.Name)
+		}
+		if names[agent.Name] {
+			return fmt.Errorf("agents[%d].name %q is used by another agent", i, agent.Name)
+		}
+		names[agent.Name] = true
+	}
+
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("logLevel must be debug, info, warn, or error, got %q", c.LogLevel)
+	}
+
+	switch c.LogFormat {
+	case "text", "json":
+	default:
+		return fmt.Errorf("logFormat must be text or json, got %q", c.LogFormat)
+	}
+
+	if c.TelemetryMaxBytes < 0 {
+		return fmt.Errorf("telemetryMaxBytes must not be negative, got %d", c.TelemetryMaxBytes)
+	}
+
+	if c.IdleInterval <= 0 {
+		return fmt.Errorf("idleInterval must be positive, got %s", c.IdleInterval)
+	}
+
+	if c.CargoThreshold <= 0 || c.CargoThreshold > 1 {
+		return fmt.Errorf("cargoThreshold must be in (0, 1], got %g", c.CargoThreshold)
+	}
+
+	if !IsStrategy(c.Strategy) {
+		return fmt.Errorf("strategy must be one of %s, got %q", strings.Join(Strategies, ", "), c.Strategy)
+	}
+
+	for role, override := range c.Roles {
+		if override.Strategy != nil && !IsStrategy(*override.Strategy) {
+			return fmt.Errorf("roles.%s.strategy must be one of %s, got %q", role, strings.Join(Strategies, ", "), *override.Strategy)
+		}
+	}
+
+	if c.MarketRefresh < 0 {
+		return fmt.Errorf("marketRefresh must not be negative, got %s", c.MarketRefresh)
+	}
+
+	if c.TradeMaxPriceAge <= 0 {
+		return fmt.Errorf("tradeMaxPriceAge must be positive, got %s", c.TradeMaxPriceAge)
+	}
+
+	if c.TradeMargin < 0 {
+		return fmt.Errorf("tradeMargin must not be negative, got %d", c.TradeMargin)
+	}
+
+	if c.ExploreBudget < 0 {
+		return fmt.Errorf("exploreBudget must not be negative, got %d", c.ExploreBudget)
+	}
+
+	if c.MaxShipsPerTarget < 0 {
+		return fmt.Errorf("maxShipsPerTarget must not be negative, got %d", c.MaxShipsPerTarget)
+	}
+
+	if c.EarningsWindow <= 0 {
+		return fmt.Errorf("earningsWindow must be positive, got %s", c.EarningsWindow)
+	}
+
+	switch c.EarningsSplit {
+	case "seller", "contributors":
+	default:
+		return fmt.Errorf("earningsSplit must be seller or contributors, got %q", c.EarningsSplit)
+	}
+
+	switch c.UnsellablePolicy {
+	case "auto", "retain", "jettison":
+	default:
+		return fmt.Errorf("unsellablePolicy must be auto, retain, or jettison, got %q", c.UnsellablePolicy)
+	}
+
+	if c.Jettison.MaxValue < 0 {
+		return fmt.Errorf("jettison.maxValue must not be negative, got %d", c.Jettison.MaxValue)
+	}
+
+	if c.Jettison.FreshAge <= 0 {
+		return fmt.Errorf("jettison.freshAge must be positive, got %s", c.Jettison.FreshAge)
+	}
+
+	if c.CreditFloor < 0 {
+		return fmt.Errorf("creditFloor must not be negative, got %d", c.CreditFloor)
+	}
+
+	if c.FuelReserve < 0 || c.FuelReserve >= 1 {
+		return fmt.Errorf("fuelReserve must be in [0, 1), got %g", c.FuelReserve)
+	}
+
+	if c.FuelPriceCeiling < 0 {
+		return fmt.Errorf("fuelPriceCeiling must not be negative, got %d", c.FuelPriceCeiling)
+	}
+
+	for _, ship := range c.IgnoredShips {
+		if contains(c.ControlledShips, ship) {
+			return fmt.Errorf("ship %s is in both controlledShips and ignoredShips", ship)
+		}
+	}
+
+	for subsystem, share := range c.RequestCaps {
+		if !IsSubsystem(subsystem) {
+			return fmt.Errorf("requestCaps: unknown subsystem %q; must be one of %s", subsystem, strings.Join(Subsystems, ", "))
+		}
+
+		if share <= 0 || share > 1 {
+			return fmt.Errorf("requestCaps.%s must be in (0, 1], got %g", subsystem, share)
+		}
+	}
+
+	if c.Expansion.Interval < 0 {
+		return fmt.Errorf("expansion.interval must not be negative, got %s", c.Expansion.Interval)
+	}
+
+	if c.Expansion.Interval > 0 {
+		if c.Expansion.Margin < 0 {
+			return fmt.Errorf("expansion.margin must not be negative, got %g", c.Expansion.Margin)
+		}
+
+		if c.Expansion.Share <= 0 || c.Expansion.Share > 1 {
+			return fmt.Errorf("expansion.share must be in (0, 1], got %g", c.Expansion.Share)
+		}
+	}
+
+	if c.Purchase.Interval < 0 {
+		return fmt.Errorf("purchase.interval must not be negative, got %s", c.Purchase.Interval)
+	}
+
+	if c.Purchase.Interval > 0 {
+		if c.Purchase.PriceTTL <= 0 {
+			return fmt.Errorf("purchase.priceTTL must be positive, got %s", c.Purchase.PriceTTL)
+		}
+
+		if c.Purchase.Dip < 0 || c.Purchase.Dip >= 1 {
+			return fmt.Errorf("purchase.dip must be in [0, 1), got %g", c.Purchase.Dip)
+		}
+
+		if c.Purchase.MinSamples <= 0 {
+			return fmt.Errorf("purchase.minSamples must be positive, got %d", c.Purchase.MinSamples)
+		}
+	}
+
+	if c.SellPriceDrop < 0 || c.SellPriceDrop >= 1 {
+		return fmt.Errorf("sellPriceDrop must be in [0, 1), got %g", c.SellPriceDrop)
+	}
+
+	if t := c.MissionTimeouts; t.Extraction < 0 || t.Sell < 0 || t.Slack < 0 || t.Default < 0 {
+		return fmt.Errorf("missionTimeouts must not be negative, got extraction %s, sell %s, slack %s, default %s", t.Extraction, t.Sell, t.Slack, t.Default)
+	}
+
+	if cond := c.Condition; cond.Warn < 0 || cond.Warn > 100 || cond.Critical < 0 || cond.Critical > 100 {
+		return fmt.Errorf("condition thresholds must be in [0, 100], got warn %d, critical %d", cond.Warn, cond.Critical)
+	}
+
+	if cond := c.Condition; cond.Warn > 0 && cond.Critical > cond.Warn {
+		return fmt.Errorf("condition.critical must not exceed condition.warn, got critical %d, warn %d", cond.Critical, cond.Warn)
+	}
+
+	if c.Notify.Enabled() {
+		if c.Notify.FailureThreshold <= 0 {
+			return fmt.Errorf("notify.failureThreshold must be positive, got %d", c.Notify.FailureThreshold)
+		}
+
+		if c.Notify.RateLimit <= 0 || c.Notify.RateWindow <= 0 {
+			return fmt.Errorf("notify.rateLimit and notify.rateWindow must be positive, got %d per %s", c.Notify.RateLimit, c.Notify.RateWindow)
+		}
+	}
+
+	return nil
+}
+
+// normalizeSymbols upper-cases the agent, faction, ship, trade, and waypoint type symbols set in the
+// configuration, which the API only matches in upper case, and rejects malformed ones.
+func (c *Config) normalizeSymbols() error {
+	symbols := map[string]*string{
+		"symbol":  &c.Symbol,
+		"faction": &c.Faction,
+		"logOnly": &c.LogOnly,
+	}
+	for i := range c.Agents {
+		symbols[fmt.Sprintf("agents[%d].symbol", i)] = &c.Agents[i].Symbol
+		symbols[fmt.Sprintf("agents[%d].faction", i)] = &c.Agents[i].Faction
+	}
+
+	for name, value := range symbols {
+		if *value == "" {
+			continue
+		}
+
+		symbol, err := lib.NormalizeSymbol(*value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		*value = symbol
+	}
+
+	lists := map[string][]string{
+		"controlledShips":   c.ControlledShips,
+		"ignoredShips":      c.IgnoredShips,
+		"reservedGoods":     c.ReservedGoods,
+		"miningTargetTypes": c.MiningTargetTypes,
+	}
+	for name, list := range lists {
+		if err := lib.NormalizeSymbols(list); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// ForAgent returns the configuration an agent of Agents runs with: c with the agent's credentials, and with
+// each file the bot writes suffixed with the agent's name (gogarin.journal.json becomes
+// gogarin.journal.NAME.json, and recordDir gets a NAME subdirectory), so agents never share a file.
+func (c *Config) ForAgent(agent AgentConfig) *Config {
+	cfg := *c
+	cfg.Agents = nil
+	cfg.Token = agent.Token
+	if agent.Symbol != "" {
+		cfg.Symbol = agent.Symbol
+	}
+	if agent.Faction != "" {
+		cfg.Faction = agent.Faction
+	}
+
+	cfg.TelemetryPath = agentPath(c.TelemetryPath, agent.Name)
+	cfg.ReportPath = agentPath(c.ReportPath, agent.Name)
+	cfg.JournalPath = agentPath(c.JournalPath, agent.Name)
+	cfg.MetadataPath = agentPath(c.MetadataPath, agent.Name)
+	cfg.ShipyardPath = agentPath(c.ShipyardPath, agent.Name)
+	cfg.FuelCostPath = agentPath(c.FuelCostPath, agent.Name)
+	cfg.VisitPath = agentPath(c.VisitPath, agent.Name)
+	cfg.StatePath = agentPath(c.StatePath, agent.Name)
+	if c.RecordDir != "" {
+		cfg.RecordDir = filepath.Join(c.RecordDir, agent.Name)
+	}
+
+	return &cfg
+}
+
+// agentPath inserts an agent's name before the extension of path. An empty path stays empty.
+func agentPath(path string, name string) string {
+	if path == "" {
+		return ""
+	}
+
+	ext := filepath.Ext(path)
+
+	return strings.TrimSuffix(path, ext) + "." + name + ext
+}
+
+// Controls checks if the bot commands a ship. Ships in IgnoredShips never are. When ControlledShips is set,
+// only the ships in it are; otherwise every ship is, except those of roles set to be ignored.
+func (c *Config) Controls(shipSymbol string, role string) bool {
+	switch {
+	case contains(c.IgnoredShips, shipSymbol):
+		return false
+	case contains(c.ControlledShips, shipSymbol):
+		return true
+	case len(c.ControlledShips) > 0:
+		return false
+	}
+
+	return !c.Roles[role].Ignored
+}
+
+// contains checks if items includes item.
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ForRole resolves the tuning values for a ship role, applying any per-role overrides.
+func (c *Config) ForRole(role string) RoleTuning {
+	tuning := RoleTuning{
+		Strategy:       c.Strategy,
+		IdleInterval:   c.IdleInterval,
+		CargoThreshold: c.CargoThreshold,
+		FuelReserve:    c.FuelReserve,
+	}
+
+	override, ok := c.Roles[role]
+	if !ok {
+		return tuning
+	}
+
+	if override.Strategy != nil {
+		tuning.Strategy = *override.Strategy
+	}
+	if override.IdleInterval != nil {
+		tuning.IdleInterval = *override.IdleInterval
+	}
+	if override.CargoThreshold != nil {
+		tuning.CargoThreshold = *override.CargoThreshold
+	}
+	if override.FuelReserve != nil {
+		tuning.FuelReserve = *override.FuelReserve
+	}
+
+	return tuning
+}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig writes a config file into a temporary directory and returns its path.
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "gogarin.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadWithoutAFileUsesDefaults(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.RateLimit != 2 || cfg.IdleInterval != time.Minute || cfg.CargoThreshold != 1 || cfg.FuelReserve != 0.1 || cfg.MarketRefresh != 5*time.Minute || cfg.Strategy != "mining" ||
+		cfg.TradeMaxPriceAge != 15*time.Minute || cfg.TradeMargin != 1000 || cfg.UnsellablePolicy != "auto" || cfg.ExploreBudget != 10 || cfg.FuelPriceCeiling != 0 ||
+		cfg.Expansion.Interval != 0 || cfg.Expansion.Margin != 0.5 || cfg.Expansion.Share != 0.5 {
+		t.Errorf("cfg = %+v, want the defaults", cfg)
+	}
+}
+
+func TestLoadAppliesTheFileThenTheEnvironment(t *testing.T) {
+	path := writeConfig(t, `
+token: from-file
+rateLimit: 3
+idleInterval: 30s
+cargoThreshold: 0.8
+miningTargetTypes: [ASTEROID, GAS_GIANT]
+requestCaps: {market-poll: 0.2}
+fleetPlan:
+  EXCAVATOR: 4
+roles:
+  EXCAVATOR:
+    cargoThreshold: 0.9
+`)
+	t.Setenv("TOKEN", "from-env")
+	t.Setenv("GOGARIN_IDLE_INTERVAL", "45s")
+	t.Setenv("GOGARIN_LOG_FORMAT", "json")
+	t.Setenv("GOGARIN_BASE_URL", "http://localhost:8080/v2")
+	t.Setenv("GOGARIN_CREDIT_FLOOR", "25000")
+	t.Setenv("GOGARIN_STRATEGY", "contract")
+	t.Setenv("GOGARIN_TRADE_MAX_PRICE_AGE", "10m")
+	t.Setenv("GOGARIN_TRADE_MARGIN", "500")
+	t.Setenv("GOGARIN_RECORD", "recordings")
+	t.Setenv("GOGARIN_UNSELLABLE_POLICY", "jettison")
+	t.Setenv("GOGARIN_EXPLORE_BUDGET", "4")
+	t.Setenv("GOGARIN_FUEL_PRICE_CEILING", "90")
+	t.Setenv("GOGARIN_EXPANSION_INTERVAL", "10m")
+	t.Setenv("GOGARIN_SHIP_METADATA", "ships.json")
+	t.Setenv("GOGARIN_IGNORED_SHIPS", "GOGARIN-2, ,GOGARIN-3")
+	t.Setenv("GOGARIN_JETTISON_MAX_VALUE", "250")
+	t.Setenv("GOGARIN_JETTISON_DRY_RUN", "true")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Token != "from-env" || cfg.IdleInterval != 45*time.Second || cfg.LogFormat != "json" || cfg.BaseURL != "http://localhost:8080/v2" || cfg.CreditFloor != 25000 || cfg.Strategy != "contract" ||
+		cfg.TradeMaxPriceAge != 10*time.Minute || cfg.TradeMargin != 500 || cfg.RecordDir != "recordings" || cfg.UnsellablePolicy != "jettison" ||
+		cfg.ExploreBudget != 4 || cfg.FuelPriceCeiling != 90 || cfg.Expansion.Interval != 10*time.Minute ||
+		cfg.MetadataPath != "ships.json" || !reflect.DeepEqual(cfg.IgnoredShips, []string{"GOGARIN-2", "GOGARIN-3"}) ||
+		cfg.Jettison.MaxValue != 250 || !cfg.Jettison.DryRun {
+		t.Errorf("environment did not override the file: %+v", cfg)
+	}
+	if cfg.RateLimit != 3 || cfg.CargoThreshold != 0.8 || cfg.FleetPlan["EXCAVATOR"] != 4 || len(cfg.MiningTargetTypes) != 2 ||
+		cfg.RequestCaps["market-poll"] != 0.2 {
+		t.Errorf("file values not applied: %+v", cfg)
+	}
+	if cfg.FuelReserve != 0.1 || cfg.LogLevel != "info" {
+		t.Errorf("defaults not kept for unset values: %+v", cfg)
+	}
+}
+
+func TestLoadRejectsBadValues(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		want string
+	}{
+		{"unparsable file", "rateLimit: [", nil, "parsing"},
+		{"unparsable rate limit", "", map[string]string{"GOGARIN_RATE_LIMIT": "fast"}, "GOGARIN_RATE_LIMIT"},
+		{"unparsable interval", "", map[string]string{"GOGARIN_IDLE_INTERVAL": "60"}, "GOGARIN_IDLE_INTERVAL"},
+		{"unparsable threshold", "", map[string]string{"GOGARIN_CARGO_THRESHOLD": "most"}, "GOGARIN_CARGO_THRESHOLD"},
+		{"unparsable metrics switch", "", map[string]string{"GOGARIN_METRICS": "sometimes"}, "GOGARIN_METRICS"},
+		{"unparsable reserve", "", map[string]string{"GOGARIN_FUEL_RESERVE": "some"}, "GOGARIN_FUEL_RESERVE"},
+		{"unparsable credit floor", "", map[string]string{"GOGARIN_CREDIT_FLOOR": "1e6"}, "GOGARIN_CREDIT_FLOOR"},
+		{"unparsable market refresh", "", map[string]string{"GOGARIN_MARKET_REFRESH": "300"}, "GOGARIN_MARKET_REFRESH"},
+		{"unparsable trade price age", "", map[string]string{"GOGARIN_TRADE_MAX_PRICE_AGE": "900"}, "GOGARIN_TRADE_MAX_PRICE_AGE"},
+		{"unparsable trade margin", "", map[string]string{"GOGARIN_TRADE_MARGIN": "lots"}, "GOGARIN_TRADE_MARGIN"},
+		{"unparsable expansion interval", "", map[string]string{"GOGARIN_EXPANSION_INTERVAL": "600"}, "GOGARIN_EXPANSION_INTERVAL"},
+		{"unparsable fuel price ceiling", "", map[string]string{"GOGARIN_FUEL_PRICE_CEILING": "cheap"}, "GOGARIN_FUEL_PRICE_CEILING"},
+		{"unparsable explore budget", "", map[string]string{"GOGARIN_EXPLORE_BUDGET": "all"}, "GOGARIN_EXPLORE_BUDGET"},
+		{"unparsable page workers", "", map[string]string{"GOGARIN_PAGE_WORKERS": "many"}, "GOGARIN_PAGE_WORKERS"},
+		{"zero rate limit", "rateLimit: 0", nil, "rateLimit"},
+		{"zero page workers", "pageWorkers: 0", nil, "pageWorkers"},
+		{"negative telemetry size", "telemetryMaxBytes: -1", nil, "telemetryMaxBytes"},
+		{"unknown log level", "logLevel: verbose", nil, "logLevel"},
+		{"unknown log format", "", map[string]string{"GOGARIN_LOG_FORMAT": "xml"}, "logFormat"},
+		{"negative interval", "idleInterval: -1s", nil, "idleInterval"},
+		{"zero threshold", "cargoThreshold: 0", nil, "cargoThreshold"},
+		{"threshold above one", "cargoThreshold: 1.5", nil, "cargoThreshold"},
+		{"negative reserve", "fuelReserve: -0.1", nil, "fuelReserve"},
+		{"whole tank reserved", "fuelReserve: 1", nil, "fuelReserve"},
+		{"unknown strategy", "", map[string]string{"GOGARIN_STRATEGY": "gambling"}, "strategy"},
+		{"unknown role strategy", "roles: {EXCAVATOR: {strategy: hoarding}}", nil, "roles.EXCAVATOR.strategy"},
+		{"negative market refresh", "marketRefresh: -1m", nil, "marketRefresh"},
+		{"zero trade price age", "tradeMaxPriceAge: 0s", nil, "tradeMaxPriceAge"},
+		{"negative trade margin", "tradeMargin: -1", nil, "tradeMargin"},
+		{"negative expansion interval", "expansion: {interval: -1m}", nil, "expansion.interval"},
+		{"negative expansion margin", "expansion: {interval: 1m, margin: -0.1}", nil, "expansion.margin"},
+		{"no expansion share", "expansion: {interval: 1m, share: 0}", nil, "expansion.share"},
+		{"expansion share above one", "expansion: {interval: 1m, share: 1.5}", nil, "expansion.share"},
+		{"unknown request cap subsystem", "requestCaps: {polling: 0.2}", nil, "requestCaps"},
+		{"zero request cap", "requestCaps: {market-poll: 0}", nil, "requestCaps.market-poll"},
+		{"request cap above one", "requestCaps: {reconcile: 1.5}", nil, "requestCaps.reconcile"},
+		{"negative fuel price ceiling", "fuelPriceCeiling: -1", nil, "fuelPriceCeiling"},
+		{"ship both controlled and ignored", "controlledShips: [GOGARIN-1, GOGARIN-2]\nignoredShips: [GOGARIN-2]", nil, "GOGARIN-2"},
+		{"negative explore budget", "exploreBudget: -1", nil, "exploreBudget"},
+		{"unknown unsellable policy", "unsellablePolicy: sell", nil, "unsellablePolicy"},
+		{"negative credit floor", "creditFloor: -1", nil, "creditFloor"},
+		{"unparsable jettison max value", "", map[string]string{"GOGARIN_JETTISON_MAX_VALUE": "lots"}, "GOGARIN_JETTISON_MAX_VALUE"},
+		{"unparsable jettison dry run", "", map[string]string{"GOGARIN_JETTISON_DRY_RUN": "maybe"}, "GOGARIN_JETTISON_DRY_RUN"},
+		{"negative jettison max value", "jettison: {maxValue: -1}", nil, "jettison.maxValue"},
+		{"no jettison fresh age", "jettison: {freshAge: 0s}", nil, "jettison.freshAge"},
+		{"notifying on no failures", "notify: {failureThreshold: 0}", map[string]string{"GOGARIN_WEBHOOK_URL": "http://localhost/hook"}, "notify.failureThreshold"},
+		{"notifying without a rate window", "notify: {rateWindow: 0s}", map[string]string{"GOGARIN_DISCORD_URL": "http://localhost/hook"}, "notify.rateLimit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := Load(writeConfig(t, tt.file))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want one mentioning %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifyLimitsAreOnlyCheckedWithASink(t *testing.T) {
+	cfg := Default()
+	cfg.Notify.RateLimit = 0
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("notifications disabled: %s", err)
+	}
+
+	cfg.Notify.WebhookURL = "http://localhost/hook"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("notifications enabled: accepted a zero rate limit")
+	}
+}
+
+func TestValidateAcceptsTheBoundaries(t *testing.T) {
+	cfg := Default()
+	cfg.CargoThreshold = 1
+	cfg.FuelReserve = 0
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestForRole(t *testing.T) {
+	interval := 10 * time.Second
+	threshold := 0.5
+	trading := "trading"
+
+	cfg := Default()
+	cfg.Roles["SATELLITE"] = RoleConfig{IdleInterval: &interval}
+	cfg.Roles["EXCAVATOR"] = RoleConfig{CargoThreshold: &threshold, Strategy: &trading}
+
+	if got := cfg.ForRole("SATELLITE"); got.IdleInterval != interval || got.CargoThreshold != cfg.CargoThreshold {
+		t.Errorf("SATELLITE = %+v", got)
+	}
+	if got := cfg.ForRole("EXCAVATOR"); got.CargoThreshold != threshold || got.Strategy != trading || got.IdleInterval != cfg.IdleInterval {
+		t.Errorf("EXCAVATOR = %+v", got)
+	}
+	if got := cfg.ForRole("COMMAND"); got != (RoleTuning{cfg.Strategy, cfg.IdleInterval, cfg.CargoThreshold, cfg.FuelReserve}) {
+		t.Errorf("COMMAND = %+v, want the top-level values", got)
+	}
+}
+
+func TestControls(t *testing.T) {
+	tests := []struct {
+		name       string
+		controlled []string
+		ignored    []string
+		roles      map[string]RoleConfig
+		want       map[string]bool
+	}{
+		{"no filter", nil, nil, nil, map[string]bool{"GOGARIN-1": true, "GOGARIN-2": true}},
+		{"ignored ship", nil, []string{"GOGARIN-2"}, nil, map[string]bool{"GOGARIN-1": true, "GOGARIN-2": false}},
+		{"controlled ships only", []string{"GOGARIN-1"}, nil, nil, map[string]bool{"GOGARIN-1": true, "GOGARIN-2": false}},
+		{"ignored role", nil, nil, map[string]RoleConfig{"EXCAVATOR": {Ignored: true}}, map[string]bool{"GOGARIN-1": true, "GOGARIN-2": false}},
+		{"controlled ship of an ignored role", []string{"GOGARIN-1", "GOGARIN-2"}, nil, map[string]RoleConfig{"EXCAVATOR": {Ignored: true}}, map[string]bool{"GOGARIN-1": true, "GOGARIN-2": true}},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.ControlledShips, cfg.IgnoredShips, cfg.Roles = tt.controlled, tt.ignored, tt.roles
+
+		for ship, role := range map[string]string{"GOGARIN-1": "COMMAND", "GOGARIN-2": "EXCAVATOR"} {
+			if got := cfg.Controls(ship, role); got != tt.want[ship] {
+				t.Errorf("%s: Controls(%s) = %t, want %t", tt.name, ship, got, tt.want[ship])
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+🔄 Live
+*/
+
+// Live is the configuration of a running bot, which Reload replaces while it runs. Readers call Get each time
+// they need a value, and never modify the Config it returns, so they always see one consistent configuration.
+type Live struct {
+	path    string
+	current atomic.Pointer[Config]
+	// mu serializes reloads.
+	mu sync.Mutex
+}
+
+// NewLive holds cfg, reloading it from the config file at path. An empty path means DefaultPath.
+func NewLive(cfg *Config, path string) *Live {
+	l := &Live{path: path}
+	l.current.Store(cfg)
+
+	return l
+}
+
+// Get returns the current configuration.
+func (l *Live) Get() *Config {
+	return l.current.Load()
+}
+
+// Reload re-reads the config file and the environment and, if the result is valid, makes it the current
+// configuration, returning the top-level settings that changed. Restart-only settings keep their current
+// values. An invalid configuration is rejected and the current one kept.
+func (l *Live) Reload() ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	next, err := Load(l.path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := l.Get()
+	next.keepRestartOnly(current)
+
+	changed := changedSettings(current, next)
+	if len(changed) > 0 {
+		l.current.Store(next)
+	}
+
+	return changed, nil
+}
+
+// keepRestartOnly copies the settings only read at startup from current: the agent's credentials, the API
+// client, logging, the status server, the files the bot writes, notifications, the expansion interval, the
+// purchase interval and price TTL, and how earnings are rated.
+func (c *Config) keepRestartOnly(current *Config) {
+	c.Token = current.Token
+	c.Symbol = current.Symbol
+	c.Faction = current.Faction
+	c.Agents = current.Agents
+	c.BaseURL = current.BaseURL
+	c.RateLimit = current.RateLimit
+	c.PageWorkers = current.PageWorkers
+	c.RequestCaps = current.RequestCaps
+	c.LogLevel = current.LogLevel
+	c.LogFormat = current.LogFormat
+	c.LogOnly = current.LogOnly
+	c.HTTPAddr = current.HTTPAddr
+	c.Metrics = current.Metrics
+	c.TelemetryPath = current.TelemetryPath
+	c.TelemetryMaxBytes = current.TelemetryMaxBytes
+	c.ReportPath = current.ReportPath
+	c.RecordDir = current.RecordDir
+	c.JournalPath = current.JournalPath
+	c.MetadataPath = current.MetadataPath
+	c.ShipyardPath = current.ShipyardPath
+	c.FuelCostPath = current.FuelCostPath
+	c.VisitPath = current.VisitPath
+	c.StatePath = current.StatePath
+	c.Notify = current.Notify
+	c.Expansion.Interval = current.Expansion.Interval
+	c.Purchase.Interval = current.Purchase.Interval
+	c.Purchase.PriceTTL = current.Purchase.PriceTTL
+	c.EarningsWindow = current.EarningsWindow
+	c.EarningsSplit = current.EarningsSplit
+}
+
+// changedSettings returns the yaml names of the top-level settings that differ between two configurations.
+func changedSettings(a *Config, b *Config) []string {
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+
+	var changed []string
+	for i := 0; i < va.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			changed = append(changed, va.Type().Field(i).Tag.Get("yaml"))
+		}
+	}
+
+	return changed
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReload(t *testing.T) {
+	path := writeConfig(t, `
+token: at-startup
+baseURL: http://localhost:8080/v2
+cargoThreshold: 0.9
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := NewLive(cfg, path)
+
+	rewrite := func(contents string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changed, err := live.Reload()
+	if err != nil || changed != nil || live.Get() != cfg {
+		t.Errorf("reloading an unchanged file: changed %v, err %v, config replaced %t", changed, err, live.Get() != cfg)
+	}
+
+	rewrite(`
+token: edited
+baseURL: http://example.com/v2
+cargoThreshold: 0.5
+fleetPlan:
+  EXCAVATOR: 4
+`)
+	changed, err = live.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"cargoThreshold", "fleetPlan"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+	got := live.Get()
+	if got.CargoThreshold != 0.5 || got.FleetPlan["EXCAVATOR"] != 4 {
+		t.Errorf("reloaded config = %+v, want the new threshold and plan", got)
+	}
+	if got.Token != "at-startup" || got.BaseURL != "http://localhost:8080/v2" {
+		t.Errorf("restart-only settings = %q, %q, want the startup values", got.Token, got.BaseURL)
+	}
+	if cfg.CargoThreshold != 0.9 {
+		t.Errorf("reload modified the previous config: threshold %v", cfg.CargoThreshold)
+	}
+
+	rewrite("cargoThreshold: 1.5\n")
+	if _, err := live.Reload(); err == nil || !strings.Contains(err.Error(), "cargoThreshold") {
+		t.Errorf("reloading an invalid file: err %v, want a cargoThreshold error", err)
+	}
+	if live.Get() != got {
+		t.Errorf("invalid file replaced the config with %+v", live.Get())
+	}
+}
@@ -0,0 +1,170 @@
+package event
+
+import (
+	"sync"
+	"time"
+)
+
+// Type identifies the kind of an Event.
+type Type string
+
+const (
+	// ShipReported is published when a ShipBot reports to the command loop. Data is an m.Ship.
+	ShipReported Type = "SHIP_REPORTED"
+	// MissionStarted is published when a ShipBot is dispatched on a mission. Data is a MissionStart.
+	MissionStarted Type = "MISSION_STARTED"
+	// MissionCompleted is published when a ShipBot reports back from a mission.
+	MissionCompleted Type = "MISSION_COMPLETED"
+	// Arrived is published when a ship reaches a waypoint. Data is an Arrival.
+	Arrived Type = "SHIP_ARRIVED"
+	// ShipNavigated is published after a ship departs for a waypoint. Data is an m.ShipNav.
+	ShipNavigated Type = "SHIP_NAVIGATED"
+	// ResourcesExtracted is published after a successful extraction. Data is an m.Extraction.
+	ResourcesExtracted Type = "RESOURCES_EXTRACTED"
+	// CargoSold is published after a successful sale. Data is an m.MarketTransaction.
+	CargoSold Type = "CARGO_SOLD"
+	// CargoPurchased is published after a successful purchase. Data is an m.MarketTransaction.
+	CargoPurchased Type = "CARGO_PURCHASED"
+	// CargoDelivered is published after cargo is delivered to a contract. Data is an m.ShipCargoItem.
+	CargoDelivered Type = "CARGO_DELIVERED"
+	// ContractAccepted is published after a contract is accepted. Data is an m.Contract.
+	ContractAccepted Type = "CONTRACT_ACCEPTED"
+	// ContractsUpdated is published when the agent's contracts are retrieved. Data is a []m.Contract.
+	ContractsUpdated Type = "CONTRACTS_UPDATED"
+	// AgentUpdated is published whenever fresh agent data is received. Data is an m.Agent.
+	AgentUpdated Type = "AGENT_UPDATED"
+	// ContractFulfilled is published after a contract is fulfilled. Data is an m.Contract.
+	ContractFulfilled Type = "CONTRACT_FULFILLED"
+	// ContractProgressUpdated is published when the agent's contracts are reconciled, with how each accepted
+	// contract stands against its deadline. Data is a []m.ContractProgress.
+	ContractProgressUpdated Type = "CONTRACT_PROGRESS_UPDATED"
+	// DeadlineApproaching is published when an undelivered contract comes within 6 hours, then within an hour,
+	// of its deadline. Data is an m.ContractProgress.
+	DeadlineApproaching Type = "DEADLINE_APPROACHING"
+	// ShipPurchased is published after a ship is purchased. Data is an m.ShipPurchase.
+	ShipPurchased Type = "SHIP_PURCHASED"
+	// ShipRefueled is published after a ship buys fuel. Data is an m.MarketTransaction.
+	ShipRefueled Type = "SHIP_REFUELED"
+	// PlanSettled is published when a mission that previewed its plan ends, comparing the plan with what the
+	// mission actually did. Data is an m.PlanOutcome.
+	PlanSettled Type = "PLAN_SETTLED"
+	// CreditsDrifted is published when an audit finds the tracked credits drifted from the agent's actual
+	// balance, and resets them to it. Data is an m.CreditDrift.
+	CreditsDrifted Type = "CREDITS_DRIFTED"
+	// ConditionDegraded is published when a ship's frame, reactor, or engine condition drops below the warning,
+	// then the critical, threshold. Data is an m.ConditionDegraded.
+	ConditionDegraded Type = "CONDITION_DEGRADED"
+	// ShipRepaired is published when a ship is repaired at a shipyard. Data is an m.RepairTransaction.
+	ShipRepaired Type = "SHIP_REPAIRED"
+	// MissionFailed is published when a ShipBot abandons a mission because of an error. Data is the error.
+	MissionFailed Type = "MISSION_FAILED"
+	// WaypointCharted is published after a ship charts a waypoint. Data is the charted m.Waypoint.
+	WaypointCharted Type = "WAYPOINT_CHARTED"
+	// AgentEventReceived is published for each new agent event from the API. Data is an m.AgentEvent.
+	AgentEventReceived Type = "AGENT_EVENT"
+	// FleetPaused is published when the fleet is paused.
+	FleetPaused Type = "FLEET_PAUSED"
+	// FleetResumed is published when a paused fleet is resumed.
+	FleetResumed Type = "FLEET_RESUMED"
+	// SystemSummarized is published when the home system is summed up at startup. Data is an m.SystemSummary.
+	SystemSummarized Type = "SYSTEM_SUMMARIZED"
+)
+
+// Event is something significant that happened to the agent or one of its ships.
+type Event struct {
+	Type    Type
+	At      time.Time
+	Ship    string
+	Mission string
+	// MissionID correlates the events and log lines of a single mission.
+	MissionID string
+	Message   string
+	Data      interface{}
+}
+
+// MissionStart is the Data of a MissionStarted event.
+type MissionStart struct {
+	// Strategy is the strategy the ship follows, or "manual" while it is under manual orders.
+	Strategy string
+}
+
+// Arrival is the Data of an Arrived event.
+type Arrival struct {
+	Ship     string
+	Waypoint string
+	At       time.Time
+}
+
+/*
+🚌 Bus
+*/
+
+// Bus fans out published events to every subscriber.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   []chan Event
+	closed bool
+}
+
+// NewBus creates a new event Bus.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe returns a channel receiving every event published after the call.
+// Events are dropped for a subscriber whose buffer is full, so publishing never blocks bot logic.
+func (b *Bus) Subscribe(buffer int) <-chan Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, buffer)
+	if b.closed {
+		close(ch)
+		return ch
+	}
+
+	b.subs = append(b.subs, ch)
+
+	return ch
+}
+
+// Publish sends an event to all subscribers, stamping it with the current time if unset.
+// It is safe to call on a nil Bus.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return
+	}
+
+	for _, ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Close closes every subscriber channel. Events published afterwards are discarded.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+
+	for _, ch := range b.subs {
+		close(ch)
+	}
+}
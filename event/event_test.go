@@ -0,0 +1,70 @@
+package event
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPublishFansOutToEverySubscriber(t *testing.T) {
+	bus := NewBus()
+	a, b := bus.Subscribe(1), bus.Subscribe(1)
+
+	bus.Publish(Event{Type: CargoSold, Ship: "GOGARIN-1"})
+
+	for _, ch := range []<-chan Event{a, b} {
+		e := <-ch
+		if e.Type != CargoSold || e.Ship != "GOGARIN-1" {
+			t.Errorf("received %+v", e)
+		}
+		if e.At.IsZero() {
+			t.Error("event not stamped with the publish time")
+		}
+	}
+}
+
+func TestPublishKeepsAnExplicitTime(t *testing.T) {
+	bus := NewBus()
+	ch := bus.Subscribe(1)
+	at := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	bus.Publish(Event{Type: AgentUpdated, At: at})
+
+	if e := <-ch; !e.At.Equal(at) {
+		t.Errorf("At = %s, want %s", e.At, at)
+	}
+}
+
+func TestPublishDropsEventsForAFullSubscriber(t *testing.T) {
+	bus := NewBus()
+	slow := bus.Subscribe(1)
+	fast := bus.Subscribe(3)
+
+	for i := 0; i < 3; i++ {
+		bus.Publish(Event{Type: ShipReported})
+	}
+
+	if len(slow) != 1 || len(fast) != 3 {
+		t.Errorf("slow holds %d, fast holds %d; want 1 and 3", len(slow), len(fast))
+	}
+}
+
+func TestCloseClosesSubscribers(t *testing.T) {
+	bus := NewBus()
+	ch := bus.Subscribe(1)
+
+	bus.Close()
+	bus.Close()
+	bus.Publish(Event{Type: ShipReported})
+
+	if _, ok := <-ch; ok {
+		t.Error("received an event after Close")
+	}
+	if _, ok := <-bus.Subscribe(1); ok {
+		t.Error("subscription after Close is open")
+	}
+}
+
+func TestPublishOnNilBus(t *testing.T) {
+	var bus *Bus
+	bus.Publish(Event{Type: ShipReported})
+}
@@ -0,0 +1,60 @@
+// Command embed runs a gogarin fleet as a library against the in-process mock server, printing a snapshot
+// of the fleet every few seconds until interrupted.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/bot"
+	"github.com/GeoffreyDick/gogarin/mockserver"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fixture, err := mockserver.DefaultFixture()
+	if err != nil {
+		return err
+	}
+
+	srv := mockserver.New(fixture, mockserver.WithTimeScale(100))
+	defer srv.Close()
+
+	fleet := bot.New(api.NewClient("", api.WithBaseURL(srv.URL)))
+	defer fleet.Stop()
+
+	if err := fleet.Start(ctx); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(3 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-fleet.Done():
+			return nil
+		}
+
+		snapshot := fleet.Snapshot()
+		fmt.Printf("%s  %s  %d credits  %d ships  priorities %v\n",
+			snapshot.TakenAt.Format(time.TimeOnly), snapshot.Agent.Symbol, snapshot.Agent.Credits, len(snapshot.Ships), snapshot.Priorities)
+		for _, s := range snapshot.Ships {
+			fmt.Printf("  %-16s %-10s %-24s %s\n", s.Ship.Symbol, s.Ship.Nav.Status, s.Ship.Nav.WaypointSymbol, s.Mission)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package health
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/clock"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/charmbracelet/log"
+)
+
+// StatusGetter fetches the server status. It is satisfied by api.ClientAPI.
+type StatusGetter interface {
+	GetStatus() (*m.Status, error)
+}
+
+// Options configures a Monitor.
+type Options struct {
+	// Threshold is the number of unavailable responses within Window that pauses the fleet.
+	Threshold int
+	// Window is the period unavailable responses are counted over.
+	Window time.Duration
+	// PollInterval is how often the status is polled while paused.
+	PollInterval time.Duration
+	// OnReset is called, instead of resuming, when the server has been reset since the baseline was taken.
+	OnReset func(previous string, current string)
+	// Clock measures Window and paces polls. Nil means the wall clock.
+	Clock clock.Clock
+}
+
+// DefaultOptions pauses after five unavailable responses within a minute and polls every 30 seconds.
+var DefaultOptions = Options{
+	Threshold:    5,
+	Window:       1 * time.Minute,
+	PollInterval: 30 * time.Second,
+}
+
+/*
+🩺 Monitor
+*/
+
+// Monitor watches API responses for maintenance windows and resets.
+// When a burst of unavailable responses is seen it pauses mission dispatch and polls the
+// server status until the API is healthy again. A nil Monitor never pauses.
+type Monitor struct {
+	status StatusGetter
+	opts   Options
+	logger *log.Logger
+
+	mu        sync.Mutex
+	failures  []time.Time
+	resumed   chan struct{}
+	resetDate string
+}
+
+// NewMonitor creates a Monitor polling status while paused.
+func NewMonitor(status StatusGetter, opts Options, logger *log.Logger) *Monitor {
+	resumed := make(chan struct{})
+	close(resumed)
+
+	return &Monitor{
+		status:  status,
+		opts:    opts,
+		logger:  logger,
+		resumed: resumed,
+	}
+}
+
+// Baseline records the server's current reset date, against which later resets are detected.
+func (mo *Monitor) Baseline() error {
+	status, err := mo.status.GetStatus()
+	if err != nil {
+		return err
+	}
+
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+
+	mo.resetDate = status.ResetDate
+
+	return nil
+}
+
+// ResetDate returns the server reset date of the baseline, or an empty string if none was taken.
+func (mo *Monitor) ResetDate() string {
+	if mo == nil {
+		return ""
+	}
+
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+
+	return mo.resetDate
+}
+
+// Observe records the status code of an API response. It is safe to call from any goroutine.
+func (mo *Monitor) Observe(statusCode int) {
+	if mo == nil || !unavailable(statusCode) {
+		return
+	}
+
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+
+	// Failures while paused are expected; recovery is already under way.
+	if mo.paused() {
+		return
+	}
+
+	now := clock.Or(mo.opts.Clock).Now()
+	cutoff := now.Add(-mo.opts.Window)
+
+	kept := mo.failures[:0]
+	for _, at := range mo.failures {
+		if at.After(cutoff) {
+			kept = append(kept, at)
+		}
+	}
+	mo.failures = append(kept, now)
+
+	if len(mo.failures) < mo.opts.Threshold {
+		return
+	}
+
+	mo.logger.Warn("🩺 API unavailable. Pausing missions until it recovers...", "failures", len(mo.failures), "window", mo.opts.Window)
+	mo.failures = nil
+	mo.resumed = make(chan struct{})
+
+	go mo.recover(mo.resumed)
+}
+
+// Wait blocks while missions are paused, reporting whether it had to wait.
+// Ships that waited should re-sync their state before acting.
+func (mo *Monitor) Wait() bool {
+	if mo == nil {
+		return false
+	}
+
+	mo.mu.Lock()
+	resumed := mo.resumed
+	mo.mu.Unlock()
+
+	select {
+	case <-resumed:
+		return false
+	default:
+	}
+
+	<-resumed
+
+	return true
+}
+
+// Paused reports whether missions are paused.
+func (mo *Monitor) Paused() bool {
+	if mo == nil {
+		return false
+	}
+
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+
+	return mo.paused()
+}
+
+// paused reports whether missions are paused. Callers must hold mu.
+func (mo *Monitor) paused() bool {
+	select {
+	case <-mo.resumed:
+		return false
+	default:
+		return true
+	}
+}
+
+// recover polls the server status until it is healthy, then resumes missions by closing resumed.
+// If the server was reset, OnReset is called and missions stay paused.
+func (mo *Monitor) recover(resumed chan struct{}) {
+	ticker := clock.Or(mo.opts.Clock).NewTicker(mo.opts.PollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C() {
+		status, err := mo.status.GetStatus()
+		if err != nil {
+			mo.logger.Info("🩺 API still unavailable.", "error", err)
+			continue
+		}
+
+		mo.mu.Lock()
+		previous := mo.resetDate
+		mo.mu.Unlock()
+
+		if previous != "" && status.ResetDate != previous {
+			mo.logger.Error("🩺 Server was reset.", "previous", previous, "current", status.ResetDate)
+			if mo.opts.OnReset != nil {
+				mo.opts.OnReset(previous, status.ResetDate)
+			}
+			return
+		}
+
+		mo.logger.Info("🩺 API healthy. Resuming missions.", "status", status.Status)
+		close(resumed)
+
+		return
+	}
+}
+
+// unavailable reports whether a status code indicates the API is down rather than a request being wrong.
+func unavailable(statusCode int) bool {
+	switch statusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,172 @@
+package health
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/mockserver"
+	"github.com/charmbracelet/log"
+)
+
+// testOptions pause after three unavailable responses and poll quickly while paused.
+var testOptions = Options{
+	Threshold:    3,
+	Window:       time.Minute,
+	PollInterval: 10 * time.Millisecond,
+}
+
+// watch starts a mock server and a Monitor with a baseline, observing the responses of the returned client.
+func watch(t *testing.T, opts Options) (*mockserver.Server, *api.Client, *Monitor) {
+	t.Helper()
+
+	f, err := mockserver.DefaultFixture()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := mockserver.New(f)
+	t.Cleanup(s.Close)
+
+	status := api.NewClient("", api.WithBaseURL(s.URL), api.WithRateLimit(1000))
+	mo := NewMonitor(status, opts, log.New(io.Discard))
+	if err := mo.Baseline(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := api.NewClient("token", api.WithBaseURL(s.URL), api.WithRateLimit(1000), api.WithStatusObserver(mo.Observe))
+
+	return s, c, mo
+}
+
+// waitUntil polls cond until it holds, failing the test after a second.
+func waitUntil(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMonitorPausesDuringAnOutageAndResumes(t *testing.T) {
+	s, c, mo := watch(t, testOptions)
+
+	s.SetAvailable(false)
+	for i := 0; i < testOptions.Threshold-1; i++ {
+		c.GetMyAgent()
+	}
+	if mo.Paused() {
+		t.Fatalf("paused after %d failures, want %d", testOptions.Threshold-1, testOptions.Threshold)
+	}
+
+	c.GetMyAgent()
+	if !mo.Paused() {
+		t.Fatal("not paused after the threshold was reached")
+	}
+
+	waited := make(chan bool)
+	go func() { waited <- mo.Wait() }()
+
+	// The monitor keeps polling while the server is down.
+	time.Sleep(5 * testOptions.PollInterval)
+	if !mo.Paused() {
+		t.Fatal("resumed while the server was still down")
+	}
+
+	s.SetAvailable(true)
+	select {
+	case w := <-waited:
+		if !w {
+			t.Error("Wait reported not waiting during an outage")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not return after the server recovered")
+	}
+
+	if mo.Paused() || mo.Wait() {
+		t.Error("still paused after recovery")
+	}
+}
+
+func TestMonitorIgnoresOtherFailures(t *testing.T) {
+	_, _, mo := watch(t, testOptions)
+
+	for i := 0; i < 10; i++ {
+		mo.Observe(http.StatusBadRequest)
+		mo.Observe(http.StatusTooManyRequests)
+		mo.Observe(http.StatusOK)
+	}
+
+	if mo.Paused() {
+		t.Error("paused on responses that do not mean the API is down")
+	}
+}
+
+func TestMonitorForgetsFailuresOutsideTheWindow(t *testing.T) {
+	opts := testOptions
+	opts.Window = 20 * time.Millisecond
+	_, _, mo := watch(t, opts)
+
+	for i := 0; i < 5; i++ {
+		mo.Observe(http.StatusServiceUnavailable)
+		time.Sleep(15 * time.Millisecond)
+	}
+
+	if mo.Paused() {
+		t.Error("paused on failures spread wider than the window")
+	}
+}
+
+func TestMonitorReportsAResetAndStaysPaused(t *testing.T) {
+	resets := make(chan [2]string, 1)
+	opts := testOptions
+	opts.OnReset = func(previous string, current string) {
+		resets <- [2]string{previous, current}
+	}
+	s, c, mo := watch(t, opts)
+	status, err := c.GetStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := status.ResetDate
+
+	s.SetAvailable(false)
+	for i := 0; i < opts.Threshold; i++ {
+		c.GetMyAgent()
+	}
+	s.Reset("2031-01-01")
+	s.SetAvailable(true)
+
+	select {
+	case got := <-resets:
+		if got != [2]string{previous, "2031-01-01"} {
+			t.Errorf("OnReset(%q, %q), want (%q, %q)", got[0], got[1], previous, "2031-01-01")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnReset was not called")
+	}
+
+	waitUntil(t, "the monitor to stop polling", func() bool {
+		before := s.Requests("GET", "/")
+		time.Sleep(3 * opts.PollInterval)
+		return s.Requests("GET", "/") == before
+	})
+	if !mo.Paused() {
+		t.Error("resumed after a reset")
+	}
+}
+
+func TestNilMonitorNeverPauses(t *testing.T) {
+	var mo *Monitor
+
+	mo.Observe(http.StatusServiceUnavailable)
+	if mo.Paused() || mo.Wait() {
+		t.Error("a nil Monitor paused")
+	}
+}
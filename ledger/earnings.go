@@ -0,0 +1,332 @@
+package ledger
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+// Ways of attributing a contract's payment to the ships that worked on it.
+const (
+	// SplitSeller pays the ship that fulfilled the contract, like a sale pays the ship that sold.
+	SplitSeller = "seller"
+	// SplitContributors splits the payment among the ships that delivered to the contract, by units delivered.
+	SplitContributors = "contributors"
+)
+
+const (
+	// idleMission is the mission a ShipBot runs while it has nothing to do, which does not count as active time.
+	idleMission = "Idle"
+	// minActive is the active time below which a rate is not given, since a single sale would make it huge.
+	minActive = time.Minute
+)
+
+// ShipRate is what a ship earned per hour of active time within the earnings window.
+type ShipRate struct {
+	Ship     string `json:"ship"`
+	Strategy string `json:"strategy,omitempty"`
+	// Credits are the ship's income less its spend within the window.
+	Credits int64         `json:"credits"`
+	Active  time.Duration `json:"active"`
+	// PerHour is zero until the ship has been active for a minute within the window.
+	PerHour float64 `json:"perHour"`
+}
+
+// Rated reports whether the ship has been active long enough within the window for PerHour to be given.
+func (r ShipRate) Rated() bool {
+	return r.Active >= minActive
+}
+
+// StrategyRate is what the ships following a strategy earned per hour of their active time within the window.
+type StrategyRate struct {
+	Strategy string        `json:"strategy"`
+	Credits  int64         `json:"credits"`
+	Active   time.Duration `json:"active"`
+	PerHour  float64       `json:"perHour"`
+}
+
+// Rates are the earning rates of each ship and strategy, sorted by ship and strategy.
+type Rates struct {
+	Window     time.Duration  `json:"window"`
+	Ships      []ShipRate     `json:"ships"`
+	Strategies []StrategyRate `json:"strategies"`
+}
+
+// Ship returns the rate of a ship, reporting false if it has none within the window.
+func (r Rates) Ship(symbol string) (ShipRate, bool) {
+	for _, rate := range r.Ships {
+		if rate.Ship == symbol {
+			return rate, true
+		}
+	}
+
+	return ShipRate{}, false
+}
+
+// earning is credits a ship earned, or spent when negative, while following a strategy.
+type earning struct {
+	at       time.Time
+	strategy string
+	amount   int64
+}
+
+// activity is a span of time a ship spent on missions other than idling, while following a strategy. end is
+// zero while the ship is still on the mission.
+type activity struct {
+	start    time.Time
+	end      time.Time
+	strategy string
+}
+
+// shipEarnings are a ship's earnings and active time within the window.
+type shipEarnings struct {
+	strategy   string
+	earnings   []earning
+	activities []activity
+}
+
+/*
+⏱️ Earnings
+*/
+
+// Earnings attributes the credits the agent earns and spends to its ships and their strategies, and rates them
+// per hour of active time within a rolling window. Sales, purchases, fuel, and repairs count for the ship that
+// made them; a contract's payment on fulfilment counts by the split rule. Idle time does not count as active.
+// Methods are safe to call on a nil Earnings, which rates nothing.
+type Earnings struct {
+	mu     sync.Mutex
+	window time.Duration
+	split  string
+	ships  map[string]*shipEarnings
+	// delivered are the units each ship delivered to each contract not yet fulfilled, keyed by contract ID.
+	delivered map[string]map[string]int
+}
+
+// NewEarnings creates an Earnings rating the last window of activity, splitting contract payments by split.
+func NewEarnings(window time.Duration, split string) *Earnings {
+	return &Earnings{
+		window:    window,
+		split:     split,
+		ships:     make(map[string]*shipEarnings),
+		delivered: make(map[string]map[string]int),
+	}
+}
+
+// Follow attributes events until the channel is closed.
+func (r *Earnings) Follow(events <-chan event.Event) {
+	for e := range events {
+		r.Apply(e)
+	}
+}
+
+// Apply attributes a single event.
+func (r *Earnings) Apply(e event.Event) {
+	if r == nil || e.Ship == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch e.Type {
+	case event.MissionStarted:
+		s := r.ship(e.Ship)
+		if start, ok := e.Data.(event.MissionStart); ok && start.Strategy != "" {
+			s.strategy = start.Strategy
+		}
+		s.stop(e.At)
+		if e.Mission != idleMission {
+			s.activities = append(s.activities, activity{start: e.At, strategy: s.strategy})
+		}
+	case event.MissionCompleted, event.MissionFailed:
+		r.ship(e.Ship).stop(e.At)
+	case event.CargoSold:
+		if transaction, ok := e.Data.(m.MarketTransaction); ok {
+			r.earn(e.Ship, e.At, transaction.TotalPrice)
+		}
+	case event.CargoPurchased, event.ShipRefueled:
+		if transaction, ok := e.Data.(m.MarketTransaction); ok {
+			r.earn(e.Ship, e.At, -transaction.TotalPrice)
+		}
+	case event.ShipRepaired:
+		if transaction, ok := e.Data.(m.RepairTransaction); ok {
+			r.earn(e.Ship, e.At, -transaction.TotalPrice)
+		}
+	case event.CargoDelivered:
+		if item, ok := e.Data.(m.ShipCargoItem); ok && e.Message != "" {
+			if r.delivered[e.Message] == nil {
+				r.delivered[e.Message] = make(map[string]int)
+			}
+			r.delivered[e.Message][e.Ship] += item.Units
+		}
+	case event.ContractFulfilled:
+		if contract, ok := e.Data.(m.Contract); ok {
+			r.fulfilled(e.Ship, e.At, contract)
+		}
+	}
+
+	r.expire(e.At)
+}
+
+// fulfilled attributes a contract's payment on fulfilment to the ship that fulfilled it, or to the ships that
+// delivered to it by the units each delivered. It must be called with mu held.
+func (r *Earnings) fulfilled(ship string, at time.Time, contract m.Contract) {
+	payment := contract.Terms.Payment.OnFulfilled
+	delivered := r.delivered[contract.ID]
+	delete(r.delivered, contract.ID)
+
+	var total int
+	for _, units := range delivered {
+		total += units
+	}
+	if r.split != SplitContributors || total == 0 {
+		r.earn(ship, at, payment)
+		return
+	}
+
+	contributors := make([]string, 0, len(delivered))
+	for contributor := range delivered {
+		contributors = append(contributors, contributor)
+	}
+	sort.Strings(contributors)
+
+	// Shares are rounded down, and what rounding leaves goes to the fulfilling ship, so the payment adds up.
+	remaining := payment
+	for _, contributor := range contributors {
+		share := payment * int64(delivered[contributor]) / int64(total)
+		r.earn(contributor, at, share)
+		remaining -= share
+	}
+	r.earn(ship, at, remaining)
+}
+
+// earn attributes an amount to a ship and the strategy it follows. It must be called with mu held.
+func (r *Earnings) earn(ship string, at time.Time, amount int64) {
+	if amount == 0 {
+		return
+	}
+
+	s := r.ship(ship)
+	s.earnings = append(s.earnings, earning{at: at, strategy: s.strategy, amount: amount})
+}
+
+// ship returns the earnings of a ship, creating them if needed. It must be called with mu held.
+func (r *Earnings) ship(symbol string) *shipEarnings {
+	s, ok := r.ships[symbol]
+	if !ok {
+		s = &shipEarnings{}
+		r.ships[symbol] = s
+	}
+
+	return s
+}
+
+// stop ends the ship's current activity, if it has one.
+func (s *shipEarnings) stop(at time.Time) {
+	if n := len(s.activities); n > 0 && s.activities[n-1].end.IsZero() {
+		s.activities[n-1].end = at
+	}
+}
+
+// expire drops earnings and finished activities from before the window. It must be called with mu held.
+func (r *Earnings) expire(now time.Time) {
+	if r.window <= 0 {
+		return
+	}
+
+	since := now.Add(-r.window)
+	for _, s := range r.ships {
+		i := sort.Search(len(s.earnings), func(i int) bool { return s.earnings[i].at.After(since) })
+		s.earnings = s.earnings[i:]
+
+		j := 0
+		for j < len(s.activities) && !s.activities[j].end.IsZero() && s.activities[j].end.Before(since) {
+			j++
+		}
+		s.activities = s.activities[j:]
+	}
+}
+
+// Rates returns the earning rates of each ship and strategy within the window up to now.
+func (r *Earnings) Rates(now time.Time) Rates {
+	if r == nil {
+		return Rates{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	since := time.Time{}
+	if r.window > 0 {
+		since = now.Add(-r.window)
+	}
+
+	rates := Rates{Window: r.window, Ships: []ShipRate{}, Strategies: []StrategyRate{}}
+	strategies := make(map[string]*StrategyRate)
+	strategy := func(name string) *StrategyRate {
+		rate, ok := strategies[name]
+		if !ok {
+			rate = &StrategyRate{Strategy: name}
+			strategies[name] = rate
+		}
+		return rate
+	}
+
+	for symbol, s := range r.ships {
+		rate := ShipRate{Ship: symbol, Strategy: s.strategy}
+
+		for _, e := range s.earnings {
+			if e.at.After(since) && !e.at.After(now) {
+				rate.Credits += e.amount
+				if e.strategy != "" {
+					strategy(e.strategy).Credits += e.amount
+				}
+			}
+		}
+
+		for _, a := range s.activities {
+			end := a.end
+			if end.IsZero() || end.After(now) {
+				end = now
+			}
+			start := a.start
+			if start.Before(since) {
+				start = since
+			}
+			if active := end.Sub(start); active > 0 {
+				rate.Active += active
+				if a.strategy != "" {
+					strategy(a.strategy).Active += active
+				}
+			}
+		}
+
+		if rate.Credits == 0 && rate.Active == 0 {
+			continue
+		}
+		rate.PerHour = perHour(rate.Credits, rate.Active)
+		rates.Ships = append(rates.Ships, rate)
+	}
+
+	for _, rate := range strategies {
+		rate.PerHour = perHour(rate.Credits, rate.Active)
+		rates.Strategies = append(rates.Strategies, *rate)
+	}
+
+	sort.Slice(rates.Ships, func(i, j int) bool { return rates.Ships[i].Ship < rates.Ships[j].Ship })
+	sort.Slice(rates.Strategies, func(i, j int) bool { return rates.Strategies[i].Strategy < rates.Strategies[j].Strategy })
+
+	return rates
+}
+
+// perHour rates credits per hour of active time, or zero for less than minActive.
+func perHour(credits int64, active time.Duration) float64 {
+	if active < minActive {
+		return 0
+	}
+
+	return float64(credits) / active.Hours()
+}
@@ -0,0 +1,143 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+var epoch = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// script plays a half hour of work through earnings: the command ship hauls ore to a contract and fulfils it,
+// while the excavator mines, refuels, sells some ore, delivers the rest to the contract, and goes idle.
+func script(earnings *Earnings) {
+	at := func(minutes int) time.Time { return epoch.Add(time.Duration(minutes) * time.Minute) }
+	var contract m.Contract
+	contract.ID = "CONTRACT-1"
+	contract.Terms.Payment = m.ContractPayment{OnAccepted: 1500, OnFulfilled: 9000}
+
+	for _, e := range []event.Event{
+		{Type: event.MissionStarted, At: at(0), Ship: "MOCK-1", Mission: "Haul", Data: event.MissionStart{Strategy: "contract"}},
+		{Type: event.MissionStarted, At: at(0), Ship: "MOCK-2", Mission: "Extract", Data: event.MissionStart{Strategy: "mining"}},
+		{Type: event.ShipRefueled, At: at(10), Ship: "MOCK-2", Data: m.MarketTransaction{TradeSymbol: "FUEL", Units: 1, TotalPrice: 100}},
+		{Type: event.CargoSold, At: at(20), Ship: "MOCK-2", Data: m.MarketTransaction{TradeSymbol: "IRON_ORE", Units: 16, TotalPrice: 720}},
+		{Type: event.CargoDelivered, At: at(25), Ship: "MOCK-1", Message: "CONTRACT-1", Data: m.ShipCargoItem{Symbol: "IRON_ORE", Units: 30}},
+		{Type: event.CargoDelivered, At: at(25), Ship: "MOCK-2", Message: "CONTRACT-1", Data: m.ShipCargoItem{Symbol: "IRON_ORE", Units: 10}},
+		{Type: event.ContractFulfilled, At: at(30), Ship: "MOCK-1", Data: contract},
+		{Type: event.MissionCompleted, At: at(30), Ship: "MOCK-2", Mission: "Extract"},
+		{Type: event.MissionStarted, At: at(30), Ship: "MOCK-2", Mission: idleMission},
+	} {
+		earnings.Apply(e)
+	}
+}
+
+func TestEarningsPayTheSellerByDefault(t *testing.T) {
+	earnings := NewEarnings(time.Hour, SplitSeller)
+	script(earnings)
+
+	rates := earnings.Rates(epoch.Add(time.Hour))
+
+	// The command ship was paid the whole contract over an hour on its mission; the excavator's sale less its
+	// fuel over the half hour before it went idle.
+	want := []ShipRate{
+		{Ship: "MOCK-1", Strategy: "contract", Credits: 9000, Active: time.Hour, PerHour: 9000},
+		{Ship: "MOCK-2", Strategy: "mining", Credits: 620, Active: 30 * time.Minute, PerHour: 1240},
+	}
+	if len(rates.Ships) != len(want) {
+		t.Fatalf("ship rates %+v, want %+v", rates.Ships, want)
+	}
+	for i := range want {
+		if rates.Ships[i] != want[i] {
+			t.Errorf("ship rate %+v, want %+v", rates.Ships[i], want[i])
+		}
+	}
+
+	wantStrategies := []StrategyRate{
+		{Strategy: "contract", Credits: 9000, Active: time.Hour, PerHour: 9000},
+		{Strategy: "mining", Credits: 620, Active: 30 * time.Minute, PerHour: 1240},
+	}
+	if len(rates.Strategies) != len(wantStrategies) {
+		t.Fatalf("strategy rates %+v, want %+v", rates.Strategies, wantStrategies)
+	}
+	for i := range wantStrategies {
+		if rates.Strategies[i] != wantStrategies[i] {
+			t.Errorf("strategy rate %+v, want %+v", rates.Strategies[i], wantStrategies[i])
+		}
+	}
+}
+
+func TestEarningsSplitContractsByUnitsDelivered(t *testing.T) {
+	earnings := NewEarnings(time.Hour, SplitContributors)
+	script(earnings)
+
+	rates := earnings.Rates(epoch.Add(time.Hour))
+
+	// The command ship delivered 30 of the 40 units, so it is paid 6,750 and the excavator 2,250.
+	hauler, _ := rates.Ship("MOCK-1")
+	excavator, _ := rates.Ship("MOCK-2")
+	if hauler.Credits != 6750 || excavator.Credits != 620+2250 {
+		t.Fatalf("credits MOCK-1 %d, MOCK-2 %d, want 6750 and 2870", hauler.Credits, excavator.Credits)
+	}
+	if excavator.PerHour != 5740 {
+		t.Fatalf("MOCK-2 rated %.0f an hour, want 5740", excavator.PerHour)
+	}
+}
+
+func TestContributorSharesAddUpToThePayment(t *testing.T) {
+	earnings := NewEarnings(0, SplitContributors)
+	var contract m.Contract
+	contract.ID = "CONTRACT-1"
+	contract.Terms.Payment.OnFulfilled = 1000
+
+	for _, ship := range []string{"MOCK-1", "MOCK-2", "MOCK-3"} {
+		earnings.Apply(event.Event{Type: event.CargoDelivered, At: epoch, Ship: ship, Message: "CONTRACT-1", Data: m.ShipCargoItem{Units: 5}})
+	}
+	earnings.Apply(event.Event{Type: event.ContractFulfilled, At: epoch, Ship: "MOCK-3", Data: contract})
+
+	// Each third rounds down to 333, and the credit rounding leaves goes to the ship that fulfilled it.
+	want := map[string]int64{"MOCK-1": 333, "MOCK-2": 333, "MOCK-3": 334}
+	rates := earnings.Rates(epoch)
+	for ship, credits := range want {
+		if rate, _ := rates.Ship(ship); rate.Credits != credits {
+			t.Errorf("%s credited %d, want %d", ship, rate.Credits, credits)
+		}
+	}
+}
+
+func TestEarningsOutsideTheWindowAreDropped(t *testing.T) {
+	earnings := NewEarnings(time.Hour, SplitSeller)
+	script(earnings)
+	now := epoch.Add(100 * time.Minute)
+	earnings.Apply(event.Event{Type: event.MissionStarted, At: now, Ship: "MOCK-3", Mission: idleMission})
+
+	rates := earnings.Rates(now)
+
+	// The command ship is still on its mission, with its payment an hour and ten minutes old; the excavator has
+	// idled since before the window.
+	hauler, ok := rates.Ship("MOCK-1")
+	if !ok || hauler.Credits != 0 || hauler.Active != time.Hour {
+		t.Fatalf("MOCK-1 rate %+v, want an hour active and no credits", hauler)
+	}
+	if rate, ok := rates.Ship("MOCK-2"); ok {
+		t.Fatalf("MOCK-2 rated %+v, want nothing left in the window", rate)
+	}
+}
+
+func TestBriefActivityIsNotRated(t *testing.T) {
+	earnings := NewEarnings(time.Hour, SplitSeller)
+	earnings.Apply(event.Event{Type: event.MissionStarted, At: epoch, Ship: "MOCK-2", Mission: "Sell", Data: event.MissionStart{Strategy: "trading"}})
+	earnings.Apply(event.Event{Type: event.CargoSold, At: epoch.Add(10 * time.Second), Ship: "MOCK-2", Data: m.MarketTransaction{TotalPrice: 5000}})
+
+	rate, _ := earnings.Rates(epoch.Add(30 * time.Second)).Ship("MOCK-2")
+	if rate.Rated() || rate.PerHour != 0 || rate.Credits != 5000 {
+		t.Fatalf("rate %+v after half a minute, want its credits but no hourly rate", rate)
+	}
+
+	var none *Earnings
+	none.Apply(event.Event{Type: event.CargoSold, Ship: "MOCK-2", Data: m.MarketTransaction{TotalPrice: 5000}})
+	if rates := none.Rates(epoch); len(rates.Ships) != 0 {
+		t.Fatalf("nil Earnings rated %+v", rates.Ships)
+	}
+}
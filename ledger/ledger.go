@@ -0,0 +1,170 @@
+package ledger
+
+import (
+	"sync"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+// Entry kinds.
+const (
+	KindSale             = "SALE"
+	KindPurchase         = "PURCHASE"
+	KindContractAccepted = "CONTRACT_ACCEPTED"
+	KindShipPurchase     = "SHIP_PURCHASE"
+	KindRefuel           = "REFUEL"
+	KindRepair           = "REPAIR"
+)
+
+// Entry is a single credit movement. Amount is positive for income and negative for spending.
+type Entry struct {
+	At     time.Time `json:"at"`
+	Kind   string    `json:"kind"`
+	Ship   string    `json:"ship,omitempty"`
+	Symbol string    `json:"symbol,omitempty"`
+	Units  int       `json:"units,omitempty"`
+	Amount int64     `json:"amount"`
+}
+
+// Totals summarizes the entries in a Ledger.
+type Totals struct {
+	Income int64 `json:"income"`
+	Spend  int64 `json:"spend"`
+	Net    int64 `json:"net"`
+}
+
+// Accuracy compares the profit the bot's mission plans of a kind expected with the profit they realized.
+type Accuracy struct {
+	Plans         int   `json:"plans"`
+	PlannedProfit int64 `json:"plannedProfit"`
+	ActualProfit  int64 `json:"actualProfit"`
+	// AbsoluteError is the sum of how far each plan's realized profit was from its planned profit.
+	AbsoluteError int64 `json:"absoluteError"`
+}
+
+// MeanError returns the mean absolute difference between the planned and realized profit of a plan.
+func (a Accuracy) MeanError() float64 {
+	if a.Plans == 0 {
+		return 0
+	}
+
+	return float64(a.AbsoluteError) / float64(a.Plans)
+}
+
+/*
+📒 Ledger
+*/
+
+// Ledger records the agent's credit movements from the event bus.
+type Ledger struct {
+	mu      sync.RWMutex
+	entries []Entry
+	max     int
+	totals  Totals
+	// accuracy is the accuracy of every settled plan, keyed by plan kind.
+	accuracy map[string]Accuracy
+}
+
+// New creates a Ledger keeping at most max entries. Totals cover every entry ever recorded.
+func New(max int) *Ledger {
+	return &Ledger{max: max, accuracy: make(map[string]Accuracy)}
+}
+
+// Follow records entries from events until the channel is closed.
+func (l *Ledger) Follow(events <-chan event.Event) {
+	for e := range events {
+		l.Apply(e)
+	}
+}
+
+// Apply records an entry for events that move credits.
+func (l *Ledger) Apply(e event.Event) {
+	switch data := e.Data.(type) {
+	case m.MarketTransaction:
+		switch e.Type {
+		case event.CargoSold:
+			l.Record(Entry{At: e.At, Kind: KindSale, Ship: e.Ship, Symbol: data.TradeSymbol, Units: data.Units, Amount: data.TotalPrice})
+		case event.CargoPurchased:
+			l.Record(Entry{At: e.At, Kind: KindPurchase, Ship: e.Ship, Symbol: data.TradeSymbol, Units: data.Units, Amount: -data.TotalPrice})
+		case event.ShipRefueled:
+			l.Record(Entry{At: e.At, Kind: KindRefuel, Ship: e.Ship, Symbol: data.TradeSymbol, Units: data.Units, Amount: -data.TotalPrice})
+		}
+	case m.Contract:
+		if e.Type != event.ContractAccepted {
+			return
+		}
+		l.Record(Entry{At: e.At, Kind: KindContractAccepted, Symbol: data.ID, Amount: data.Terms.Payment.OnAccepted})
+	case m.RepairTransaction:
+		l.Record(Entry{At: e.At, Kind: KindRepair, Ship: data.ShipSymbol, Amount: -data.TotalPrice})
+	case m.ShipPurchase:
+		l.Record(Entry{At: e.At, Kind: KindShipPurchase, Ship: data.Ship.Symbol, Symbol: data.Transaction.ShipType, Amount: -data.Transaction.Price})
+	case m.PlanOutcome:
+		l.RecordPlan(data)
+	}
+}
+
+// RecordPlan adds a settled plan to the accuracy of its kind.
+func (l *Ledger) RecordPlan(outcome m.PlanOutcome) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	accuracy := l.accuracy[outcome.Planned.Kind]
+	accuracy.Plans++
+	accuracy.PlannedProfit += outcome.Planned.Profit()
+	accuracy.ActualProfit += outcome.Actual.Profit()
+	if delta := outcome.Delta().Profit(); delta < 0 {
+		accuracy.AbsoluteError -= delta
+	} else {
+		accuracy.AbsoluteError += delta
+	}
+	l.accuracy[outcome.Planned.Kind] = accuracy
+}
+
+// Record adds an entry to the Ledger.
+func (l *Ledger) Record(entry Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = append(l.entries, entry)
+	if l.max > 0 && len(l.entries) > l.max {
+		l.entries = l.entries[len(l.entries)-l.max:]
+	}
+
+	if entry.Amount >= 0 {
+		l.totals.Income += entry.Amount
+	} else {
+		l.totals.Spend -= entry.Amount
+	}
+	l.totals.Net += entry.Amount
+}
+
+// Entries returns a copy of the retained entries, oldest first.
+func (l *Ledger) Entries() []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return append([]Entry(nil), l.entries...)
+}
+
+// Totals returns the income, spend, and net across every recorded entry.
+func (l *Ledger) Totals() Totals {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.totals
+}
+
+// Accuracy returns the accuracy of the settled plans of each kind, keyed by plan kind.
+func (l *Ledger) Accuracy() map[string]Accuracy {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	accuracy := make(map[string]Accuracy, len(l.accuracy))
+	for kind, a := range l.accuracy {
+		accuracy[kind] = a
+	}
+
+	return accuracy
+}
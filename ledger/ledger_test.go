@@ -0,0 +1,52 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+var at = time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestApplyRecordsSalesAndAcceptedContracts(t *testing.T) {
+	l := New(0)
+
+	l.Apply(event.Event{Type: event.CargoSold, At: at, Ship: "GOGARIN-2", Data: m.MarketTransaction{TradeSymbol: "IRON_ORE", Units: 10, TotalPrice: 450}})
+
+	var contract m.Contract
+	contract.ID = "c-1"
+	contract.Terms.Payment.OnAccepted = 1000
+	l.Apply(event.Event{Type: event.ContractAccepted, At: at, Data: contract})
+
+	// Events that carry the same data without moving credits are ignored.
+	l.Apply(event.Event{Type: event.ContractsUpdated, Data: contract})
+	l.Apply(event.Event{Type: event.ShipReported, Data: m.MarketTransaction{TotalPrice: 1}})
+
+	entries := l.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("entries = %+v, want the sale and the acceptance", entries)
+	}
+	if sale := entries[0]; sale.Kind != KindSale || sale.Ship != "GOGARIN-2" || sale.Symbol != "IRON_ORE" || sale.Units != 10 || sale.Amount != 450 {
+		t.Errorf("sale = %+v", sale)
+	}
+	if accepted := entries[1]; accepted.Kind != KindContractAccepted || accepted.Symbol != "c-1" || accepted.Amount != 1000 {
+		t.Errorf("acceptance = %+v", accepted)
+	}
+}
+
+func TestTotalsOutliveTrimmedEntries(t *testing.T) {
+	l := New(2)
+
+	l.Record(Entry{Amount: 100})
+	l.Record(Entry{Amount: -30})
+	l.Record(Entry{Amount: 50})
+
+	if entries := l.Entries(); len(entries) != 2 || entries[0].Amount != -30 {
+		t.Errorf("entries = %+v, want the newest two", entries)
+	}
+	if got, want := l.Totals(), (Totals{Income: 150, Spend: 30, Net: 120}); got != want {
+		t.Errorf("totals = %+v, want %+v", got, want)
+	}
+}
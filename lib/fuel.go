@@ -0,0 +1,79 @@
+package lib
+
+import (
+	"math"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+// FuelLot is the ship fuel bought by one market unit of FUEL. Markets sell fuel in whole units only.
+const FuelLot = 100
+
+// FuelLeg is one hop of a ship's planned route.
+type FuelLeg struct {
+	// Fuel is the fuel the hop burns.
+	Fuel int
+	// Price is what fuel costs at the hop's destination, or zero if it sells none or the price is unknown.
+	Price int64
+}
+
+// RefuelPlan is what RefuelUnits needs to know about a ship at a fuel stop.
+type RefuelPlan struct {
+	Current  int
+	Capacity int
+	// Legs are the hops the ship still plans to make from the stop, in order. Nil means the route is unknown.
+	Legs []FuelLeg
+	// Reserve is the fraction of capacity kept in the tank on arrival at the end of the route.
+	Reserve float64
+	// Price is what fuel costs at the stop.
+	Price int64
+	// Ceiling is the price above which only enough fuel to reach a cheaper stop is bought. Zero means no ceiling.
+	Ceiling int64
+}
+
+// RefuelUnits returns how much fuel to buy at a stop: enough for the planned route plus the reserve, rounded
+// up to whole FuelLots and never more than the tank holds. If the stop's price is above the ceiling, only enough
+// to reach the first stop on the route at or below the ceiling is bought. With no known route, the tank is filled.
+func RefuelUnits(plan RefuelPlan) int {
+	if plan.Capacity <= 0 {
+		return 0
+	}
+
+	need := plan.Capacity
+	if plan.Legs != nil {
+		need = int(math.Ceil(plan.Reserve * float64(plan.Capacity)))
+
+		expensive := plan.Ceiling > 0 && plan.Price > plan.Ceiling
+		for _, leg := range plan.Legs {
+			need += leg.Fuel
+			if expensive && leg.Price > 0 && leg.Price <= plan.Ceiling {
+				break
+			}
+		}
+	}
+
+	space := plan.Capacity - plan.Current
+	units := need - plan.Current
+	if units <= 0 || space <= 0 {
+		return 0
+	}
+
+	units = (units + FuelLot - 1) / FuelLot * FuelLot
+	if units > space {
+		units = space
+	}
+
+	return units
+}
+
+// CanReachRefuge checks if a ship at a waypoint with fuel left could fly to one of refuges, such as fuel
+// stations or the waypoint it came from, in a flight mode. A ship already at a refuge always can.
+func CanReachRefuge(from *m.Waypoint, fuel int, flightMode string, refuges []m.Waypoint) bool {
+	for _, refuge := range refuges {
+		if refuge.Symbol == from.Symbol || FuelCost(WaypointDistance(from, &refuge), flightMode) <= fuel {
+			return true
+		}
+	}
+
+	return false
+}
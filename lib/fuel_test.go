@@ -0,0 +1,71 @@
+package lib
+
+import (
+	"testing"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+func TestRefuelUnits(t *testing.T) {
+	tests := []struct {
+		name string
+		plan RefuelPlan
+		want int
+	}{
+		{"no tank", RefuelPlan{Current: 0, Capacity: 0}, 0},
+		{"unknown route fills the tank", RefuelPlan{Current: 40, Capacity: 400}, 360},
+		{"full tank", RefuelPlan{Current: 400, Capacity: 400}, 0},
+		{"route plus reserve, in whole lots", RefuelPlan{Current: 10, Capacity: 1000, Legs: []FuelLeg{{Fuel: 30}}, Reserve: 0.1}, 200},
+		{"enough aboard", RefuelPlan{Current: 150, Capacity: 1000, Legs: []FuelLeg{{Fuel: 30}}, Reserve: 0.1}, 0},
+		{"lots capped by the tank", RefuelPlan{Current: 20, Capacity: 100, Legs: []FuelLeg{{Fuel: 60}}, Reserve: 0.1}, 80},
+		{"empty route keeps the reserve", RefuelPlan{Current: 0, Capacity: 1000, Legs: []FuelLeg{}, Reserve: 0.1}, 100},
+		{"several legs", RefuelPlan{Current: 0, Capacity: 1000, Legs: []FuelLeg{{Fuel: 150}, {Fuel: 150}}, Reserve: 0.1}, 400},
+		{"price under the ceiling buys for the route", RefuelPlan{
+			Current: 0, Capacity: 1000, Legs: []FuelLeg{{Fuel: 150, Price: 1}, {Fuel: 150}}, Reserve: 0.1, Price: 2, Ceiling: 5,
+		}, 400},
+		{"above the ceiling buys to the next cheap stop", RefuelPlan{
+			Current: 0, Capacity: 1000, Legs: []FuelLeg{{Fuel: 150, Price: 1}, {Fuel: 150}}, Reserve: 0.1, Price: 8, Ceiling: 5,
+		}, 300},
+		{"cheap stop further along", RefuelPlan{
+			Current: 0, Capacity: 1000, Legs: []FuelLeg{{Fuel: 150, Price: 9}, {Fuel: 150, Price: 5}, {Fuel: 300}}, Reserve: 0.1, Price: 8, Ceiling: 5,
+		}, 400},
+		{"no cheaper stop buys for the route", RefuelPlan{
+			Current: 0, Capacity: 1000, Legs: []FuelLeg{{Fuel: 150, Price: 9}, {Fuel: 150}}, Reserve: 0.1, Price: 8, Ceiling: 5,
+		}, 400},
+		{"no ceiling", RefuelPlan{
+			Current: 0, Capacity: 1000, Legs: []FuelLeg{{Fuel: 150, Price: 1}, {Fuel: 150}}, Reserve: 0.1, Price: 8,
+		}, 400},
+	}
+	for _, tt := range tests {
+		if got := RefuelUnits(tt.plan); got != tt.want {
+			t.Errorf("%s: RefuelUnits(%+v) = %d, want %d", tt.name, tt.plan, got, tt.want)
+		}
+	}
+}
+
+func TestCanReachRefuge(t *testing.T) {
+	field := &m.Waypoint{Symbol: "X1-A-2", X: 300}
+	refuges := []m.Waypoint{{Symbol: "X1-A-1"}, {Symbol: "X1-A-3", X: 450}}
+
+	tests := []struct {
+		name    string
+		from    *m.Waypoint
+		fuel    int
+		mode    string
+		refuges []m.Waypoint
+		want    bool
+	}{
+		{"nearest refuge in reach", field, 150, "CRUISE", refuges, true},
+		{"out of reach", field, 149, "CRUISE", refuges, false},
+		{"burn uses twice the fuel", field, 150, "BURN", refuges, false},
+		{"drift always reaches", field, 1, "DRIFT", refuges, true},
+		{"no fuel left to drift", field, 0, "DRIFT", refuges, false},
+		{"already at a refuge", &refuges[1], 0, "CRUISE", refuges, true},
+		{"no refuges", field, 1000, "CRUISE", nil, false},
+	}
+	for _, tt := range tests {
+		if got := CanReachRefuge(tt.from, tt.fuel, tt.mode, tt.refuges); got != tt.want {
+			t.Errorf("%s: CanReachRefuge = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
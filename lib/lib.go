@@ -2,9 +2,12 @@ package lib
 
 import (
 	"errors"
-	"log"
+	"fmt"
 	"math"
+	"strings"
+	"time"
 
+	"github.com/GeoffreyDick/gogarin/logging"
 	m "github.com/GeoffreyDick/gogarin/model"
 )
 
@@ -29,11 +32,27 @@ func Contains(elems []string, v string) bool {
 	return false
 }
 
+// SystemSymbolOf returns the symbol of the system a waypoint is in, which is everything before the
+// second hyphen of the waypoint symbol, e.g. X1-DF55 for X1-DF55-17335A.
+func SystemSymbolOf(waypointSymbol string) (string, error) {
+	parts := strings.SplitN(waypointSymbol, "-", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", fmt.Errorf("malformed waypoint symbol %q", waypointSymbol)
+	}
+
+	return parts[0] + "-" + parts[1], nil
+}
+
 type Coordinate struct {
 	x int
 	y int
 }
 
+// NewCoordinate creates a Coordinate from x and y.
+func NewCoordinate(x int, y int) Coordinate {
+	return Coordinate{x, y}
+}
+
 // Distance calculates the distance between two xy coordinates.
 func Distance(c1, c2 Coordinate) float64 {
 	return math.Sqrt(math.Pow(float64(c1.x-c2.x), 2) + math.Pow(float64(c1.y-c2.y), 2))
@@ -43,13 +62,16 @@ func Distance(c1, c2 Coordinate) float64 {
 func NearestWaypoint(currentWaypoint *m.Waypoint, waypoints *[]m.Waypoint) (*m.Waypoint, error) {
 	var nearestWaypoint *m.Waypoint
 	var nearestDistance float64
-	log.Printf("current waypoint: %v", currentWaypoint)
-	log.Printf("waypoints: %v", waypoints)
+	logger := logging.New("📐 LIB")
+	logger.Debug("Finding nearest waypoint...", "from", currentWaypoint.Symbol, "candidates", len(*waypoints))
 
-	for _, waypoint := range *waypoints {
-		distance := Distance(Coordinate{currentWaypoint.X, currentWaypoint.Y}, Coordinate{waypoint.X, waypoint.Y})
-		if nearestWaypoint == nil || distance < nearestDistance {
-			nearestWaypoint = &waypoint
+	// Index into the slice, so the returned pointer is to the nearest waypoint and not the loop variable.
+	for i := range *waypoints {
+		waypoint := &(*waypoints)[i]
+		distance := WaypointDistance(currentWaypoint, waypoint)
+		// Ties go to the lower symbol, so the choice does not depend on the order waypoints are listed in.
+		if nearestWaypoint == nil || distance < nearestDistance || (distance == nearestDistance && waypoint.Symbol < nearestWaypoint.Symbol) {
+			nearestWaypoint = waypoint
 			nearestDistance = distance
 		}
 	}
@@ -60,3 +82,98 @@ func NearestWaypoint(currentWaypoint *m.Waypoint, waypoints *[]m.Waypoint) (*m.W
 
 	return nearestWaypoint, nil
 }
+
+// flightModeMultipliers scale travel time by flight mode.
+var flightModeMultipliers = map[string]float64{
+	"CRUISE":  25,
+	"DRIFT":   250,
+	"BURN":    12.5,
+	"STEALTH": 30,
+}
+
+// TravelTime estimates how long a ship with a given engine speed takes to travel a distance in a flight mode.
+// Unknown flight modes are treated as CRUISE.
+func TravelTime(distance float64, engineSpeed int, flightMode string) time.Duration {
+	multiplier, ok := flightModeMultipliers[flightMode]
+	if !ok {
+		multiplier = flightModeMultipliers["CRUISE"]
+	}
+
+	if engineSpeed <= 0 {
+		engineSpeed = 1
+	}
+
+	seconds := math.Round(math.Round(math.Max(1, distance))*(multiplier/float64(engineSpeed)) + 15)
+
+	return time.Duration(seconds) * time.Second
+}
+
+// FuelCost estimates the fuel consumed travelling a distance in a flight mode.
+// Unknown flight modes are treated as CRUISE.
+func FuelCost(distance float64, flightMode string) int {
+	switch flightMode {
+	case "DRIFT":
+		return 1
+	case "BURN":
+		return int(math.Max(2, 2*math.Round(distance)))
+	default:
+		return int(math.Max(1, math.Round(distance)))
+	}
+}
+
+// WaypointDistance calculates the distance between two waypoints.
+func WaypointDistance(w1, w2 *m.Waypoint) float64 {
+	return Distance(Coordinate{w1.X, w1.Y}, Coordinate{w2.X, w2.Y})
+}
+
+// ErrNoShipInSystem is returned by MinDeliveryTime when no ship is in the system of a delivery destination.
+var ErrNoShipInSystem = errors.New("no ship in the destination system")
+
+// MinDeliveryTime estimates the least time needed to complete a contract's remaining deliveries: the longest,
+// over its destinations, of the cruise time of the ship that can reach that destination soonest. Ships in
+// transit set off from their destination once they arrive. locate looks up a destination waypoint.
+func MinDeliveryTime(contract *m.Contract, ships []m.Ship, locate func(waypointSymbol string) (*m.Waypoint, error), now time.Time) (time.Duration, error) {
+	var longest time.Duration
+
+	for _, good := range contract.Terms.Deliver {
+		if good.UnitsFulfilled >= good.UnitsRequired {
+			continue
+		}
+
+		destination, err := locate(good.DestinationSymbol)
+		if err != nil {
+			return 0, err
+		}
+
+		soonest := time.Duration(-1)
+		for _, ship := range ships {
+			if ship.Nav.SystemSymbol != destination.SystemSymbol {
+				continue
+			}
+
+			var t time.Duration
+			if arrival := ship.Nav.Route.Arrival; ship.Nav.Status == "IN_TRANSIT" && arrival.After(now) {
+				t = arrival.Sub(now)
+			}
+
+			from := ship.Nav.Route.Destination
+			if from.Symbol != destination.Symbol {
+				t += TravelTime(Distance(Coordinate{from.X, from.Y}, Coordinate{destination.X, destination.Y}), ship.Engine.Speed, "CRUISE")
+			}
+
+			if soonest < 0 || t < soonest {
+				soonest = t
+			}
+		}
+
+		if soonest < 0 {
+			return 0, fmt.Errorf("%s: %w", good.DestinationSymbol, ErrNoShipInSystem)
+		}
+
+		if soonest > longest {
+			longest = soonest
+		}
+	}
+
+	return longest, nil
+}
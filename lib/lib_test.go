@@ -0,0 +1,162 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+func TestTravelTime(t *testing.T) {
+	tests := []struct {
+		distance   float64
+		speed      int
+		flightMode string
+		want       time.Duration
+	}{
+		{100, 30, "CRUISE", 98 * time.Second},
+		{100, 30, "BURN", 57 * time.Second},
+		{100, 30, "DRIFT", 848 * time.Second},
+		{100, 30, "UNKNOWN", 98 * time.Second},
+		{0, 30, "CRUISE", 16 * time.Second},
+		{100, 0, "CRUISE", 2515 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := TravelTime(tt.distance, tt.speed, tt.flightMode); got != tt.want {
+			t.Errorf("TravelTime(%v, %d, %s) = %s, want %s", tt.distance, tt.speed, tt.flightMode, got, tt.want)
+		}
+	}
+}
+
+func TestFuelCost(t *testing.T) {
+	tests := []struct {
+		distance   float64
+		flightMode string
+		want       int
+	}{
+		{10.4, "CRUISE", 10},
+		{0, "CRUISE", 1},
+		{10.4, "BURN", 20},
+		{0, "BURN", 2},
+		{500, "DRIFT", 1},
+		{10.4, "STEALTH", 10},
+	}
+	for _, tt := range tests {
+		if got := FuelCost(tt.distance, tt.flightMode); got != tt.want {
+			t.Errorf("FuelCost(%v, %s) = %d, want %d", tt.distance, tt.flightMode, got, tt.want)
+		}
+	}
+}
+
+func TestWaypointDistance(t *testing.T) {
+	if got := WaypointDistance(&m.Waypoint{X: 0, Y: 0}, &m.Waypoint{X: 3, Y: -4}); got != 5 {
+		t.Errorf("distance = %v, want 5", got)
+	}
+}
+
+func TestNearestWaypoint(t *testing.T) {
+	from := m.Waypoint{Symbol: "X1-A-1"}
+	candidates := []m.Waypoint{
+		{Symbol: "X1-A-2", X: 20},
+		{Symbol: "X1-A-3", X: 5, Y: 5},
+		{Symbol: "X1-A-4", X: -30},
+	}
+
+	nearest, err := NearestWaypoint(&from, &candidates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nearest.Symbol != "X1-A-3" {
+		t.Errorf("nearest = %s, want X1-A-3", nearest.Symbol)
+	}
+	if nearest != &candidates[1] {
+		t.Error("nearest does not point into the candidates")
+	}
+
+	if _, err := NearestWaypoint(&from, &[]m.Waypoint{}); err == nil {
+		t.Error("found a nearest waypoint among none")
+	}
+}
+
+func TestSystemSymbolOf(t *testing.T) {
+	tests := []struct {
+		waypoint string
+		want     string
+		ok       bool
+	}{
+		{"X1-DF55-17335A", "X1-DF55", true},
+		{"X1-DF55-20250Z", "X1-DF55", true},
+		{"X1-MK1-A1", "X1-MK1", true},
+		{"X1-DF55-A-B", "X1-DF55", true},
+		{"X1-DF55", "", false},
+		{"X1", "", false},
+		{"", "", false},
+		{"X1--A1", "", false},
+		{"-DF55-A1", "", false},
+		{"X1-DF55-", "", false},
+	}
+	for _, tt := range tests {
+		got, err := SystemSymbolOf(tt.waypoint)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("SystemSymbolOf(%q) = %q, %v, want %q, ok %t", tt.waypoint, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMinDeliveryTime(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	waypoints := map[string]*m.Waypoint{
+		"X1-A-1": {Symbol: "X1-A-1", SystemSymbol: "X1-A", X: 0, Y: 0},
+		"X1-A-2": {Symbol: "X1-A-2", SystemSymbol: "X1-A", X: 100, Y: 0},
+		"X1-B-1": {Symbol: "X1-B-1", SystemSymbol: "X1-B", X: 0, Y: 0},
+	}
+	locate := func(symbol string) (*m.Waypoint, error) {
+		if w, ok := waypoints[symbol]; ok {
+			return w, nil
+		}
+		return nil, fmt.Errorf("unknown waypoint %s", symbol)
+	}
+	ship := func(at string, status string, arrival time.Time) m.Ship {
+		w := waypoints[at]
+		var s m.Ship
+		s.Engine.Speed = 30
+		s.Nav = m.ShipNav{SystemSymbol: w.SystemSymbol, WaypointSymbol: at, Status: status}
+		s.Nav.Route.Destination = m.ShipNavRouteWaypoint{Symbol: at, SystemSymbol: w.SystemSymbol, X: w.X, Y: w.Y}
+		s.Nav.Route.Arrival = arrival
+		return s
+	}
+	contract := func(deliver ...m.ContractDeliverGood) *m.Contract {
+		return &m.Contract{Terms: m.ContractTerms{Deliver: deliver}}
+	}
+	to := func(destination string, required, fulfilled int) m.ContractDeliverGood {
+		return m.ContractDeliverGood{TradeSymbol: "IRON_ORE", DestinationSymbol: destination, UnitsRequired: required, UnitsFulfilled: fulfilled}
+	}
+
+	tests := []struct {
+		name     string
+		contract *m.Contract
+		ships    []m.Ship
+		want     time.Duration
+		wantErr  error
+	}{
+		{"docked at the destination", contract(to("X1-A-2", 10, 0)), []m.Ship{ship("X1-A-2", "DOCKED", time.Time{})}, 0, nil},
+		{"cruise to the destination", contract(to("X1-A-2", 10, 0)), []m.Ship{ship("X1-A-1", "DOCKED", time.Time{})}, 98 * time.Second, nil},
+		{"in transit arrives first", contract(to("X1-A-2", 10, 0)), []m.Ship{ship("X1-A-1", "IN_TRANSIT", now.Add(time.Minute))}, time.Minute + 98*time.Second, nil},
+		{"nearest ship is used", contract(to("X1-A-2", 10, 0)), []m.Ship{ship("X1-A-1", "DOCKED", time.Time{}), ship("X1-A-2", "IN_ORBIT", time.Time{})}, 0, nil},
+		{"fulfilled deliveries are ignored", contract(to("X1-B-1", 10, 10), to("X1-A-2", 10, 0)), []m.Ship{ship("X1-A-1", "DOCKED", time.Time{})}, 98 * time.Second, nil},
+		{"nothing left to deliver", contract(to("X1-A-2", 10, 10)), nil, 0, nil},
+		{"no ship in the system", contract(to("X1-B-1", 10, 0)), []m.Ship{ship("X1-A-1", "DOCKED", time.Time{})}, 0, ErrNoShipInSystem},
+	}
+	for _, tt := range tests {
+		got, err := MinDeliveryTime(tt.contract, tt.ships, locate, now)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("%s: MinDeliveryTime = %s, %v, want %s, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	if _, err := MinDeliveryTime(contract(to("X1-C-1", 1, 0)), nil, locate, now); err == nil {
+		t.Error("MinDeliveryTime for an unknown destination succeeded, want the lookup error")
+	}
+}
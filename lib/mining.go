@@ -0,0 +1,106 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+// DepositYields maps each deposit trait of an asteroid field to the trade goods extraction there can yield.
+var DepositYields = map[string][]string{
+	"COMMON_METAL_DEPOSITS":   {"IRON_ORE", "COPPER_ORE", "ALUMINUM_ORE"},
+	"PRECIOUS_METAL_DEPOSITS": {"GOLD_ORE", "SILVER_ORE", "PLATINUM_ORE"},
+	"RARE_METAL_DEPOSITS":     {"URANITE_ORE", "MERITIUM_ORE"},
+	"MINERAL_DEPOSITS":        {"SILICON_CRYSTALS", "QUARTZ_SAND", "ICE_WATER", "AMMONIA_ICE", "PRECIOUS_STONES", "DIAMONDS"},
+}
+
+// ErrNoMiningTargets is returned when a system has no mining target to choose.
+var ErrNoMiningTargets = errors.New("no mining targets")
+
+// mismatchPenalty is how many times farther a field with the right deposits may be before a nearer field
+// without them is preferred.
+const mismatchPenalty = 3
+
+// IsMiningTarget checks if a waypoint can be mined, i.e. is of one of types, or of m.MiningTargetTypes
+// when no types are given.
+func IsMiningTarget(waypoint m.Waypoint, types ...string) bool {
+	if len(types) == 0 {
+		types = m.MiningTargetTypes
+	}
+
+	return waypoint.IsAnyType(types...)
+}
+
+// Yields returns the trade goods a waypoint's deposit traits can yield.
+func Yields(waypoint *m.Waypoint) []string {
+	var goods []string
+
+	for _, trait := range waypoint.Traits {
+		for _, good := range DepositYields[trait.Symbol] {
+			if !Contains(goods, good) {
+				goods = append(goods, good)
+			}
+		}
+	}
+
+	return goods
+}
+
+// MiningScore returns how suitable a mining target is for priority goods; lower is better. It is the distance
+// from the current waypoint, multiplied by mismatchPenalty unless the target's deposits can yield a priority
+// good. With no priorities, every target with deposits matches.
+func MiningScore(current *m.Waypoint, target *m.Waypoint, priorities []string) float64 {
+	distance := WaypointDistance(current, target)
+	if len(MatchingYields(target, priorities)) > 0 {
+		return distance
+	}
+
+	return distance * mismatchPenalty
+}
+
+// MatchingYields returns the priority goods a target's deposits can yield, or all of its yields when there are
+// no priorities.
+func MatchingYields(target *m.Waypoint, priorities []string) []string {
+	yields := Yields(target)
+	if len(priorities) == 0 {
+		return yields
+	}
+
+	return Filter(yields, func(good string) bool {
+		return Contains(priorities, good)
+	})
+}
+
+// ChooseMiningTarget returns the candidate with the best MiningScore, and the reason it was chosen.
+func ChooseMiningTarget(current *m.Waypoint, candidates []m.Waypoint, priorities []string) (*m.Waypoint, string, error) {
+	ranked := RankMiningTargets(current, candidates, priorities)
+	if len(ranked) == 0 {
+		return nil, "", ErrNoMiningTargets
+	}
+
+	return &ranked[0], MiningReason(&ranked[0], priorities), nil
+}
+
+// RankMiningTargets returns the candidates ordered by MiningScore, best first. Candidates scoring the same keep
+// their order.
+func RankMiningTargets(current *m.Waypoint, candidates []m.Waypoint, priorities []string) []m.Waypoint {
+	ranked := append([]m.Waypoint(nil), candidates...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return MiningScore(current, &ranked[i], priorities) < MiningScore(current, &ranked[j], priorities)
+	})
+
+	return ranked
+}
+
+// MiningReason explains why a target suits the priority goods: the priority goods its deposits yield, or that
+// it is merely near.
+func MiningReason(target *m.Waypoint, priorities []string) string {
+	if matching := MatchingYields(target, priorities); len(matching) > 0 {
+		return fmt.Sprintf("deposits yield %s", strings.Join(matching, ", "))
+	}
+
+	return "nearest field; none has deposits of the priority goods"
+}
@@ -0,0 +1,123 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+// field returns an asteroid field at x, y with the given traits.
+func field(symbol string, x int, y int, traits ...string) m.Waypoint {
+	w := m.Waypoint{Symbol: symbol, Type: "ASTEROID_FIELD", X: x, Y: y}
+	for _, trait := range traits {
+		w.Traits = append(w.Traits, m.WaypointTrait{Symbol: trait})
+	}
+	return w
+}
+
+func TestIsMiningTarget(t *testing.T) {
+	tests := []struct {
+		waypointType string
+		types        []string
+		want         bool
+	}{
+		{"ASTEROID_FIELD", nil, true},
+		{"ASTEROID", nil, true},
+		{"ENGINEERED_ASTEROID", nil, true},
+		{"ASTEROID_BASE", nil, true},
+		{"PLANET", nil, false},
+		{"GAS_GIANT", []string{"GAS_GIANT"}, true},
+		{"ASTEROID", []string{"GAS_GIANT"}, false},
+	}
+	for _, tt := range tests {
+		w := m.Waypoint{Symbol: "X1-A-1", Type: tt.waypointType}
+		if got := IsMiningTarget(w, tt.types...); got != tt.want {
+			t.Errorf("IsMiningTarget(%s, %v) = %t, want %t", tt.waypointType, tt.types, got, tt.want)
+		}
+	}
+}
+
+func TestYields(t *testing.T) {
+	tests := []struct {
+		traits []string
+		want   []string
+	}{
+		{[]string{"COMMON_METAL_DEPOSITS"}, []string{"IRON_ORE", "COPPER_ORE", "ALUMINUM_ORE"}},
+		{[]string{"RARE_METAL_DEPOSITS", "MARKETPLACE"}, []string{"URANITE_ORE", "MERITIUM_ORE"}},
+		{[]string{"PRECIOUS_METAL_DEPOSITS", "RARE_METAL_DEPOSITS"}, []string{"GOLD_ORE", "SILVER_ORE", "PLATINUM_ORE", "URANITE_ORE", "MERITIUM_ORE"}},
+		{[]string{"MINERAL_DEPOSITS"}, []string{"SILICON_CRYSTALS", "QUARTZ_SAND", "ICE_WATER", "AMMONIA_ICE", "PRECIOUS_STONES", "DIAMONDS"}},
+		{[]string{"STRIPPED"}, nil},
+		{nil, nil},
+	}
+	for _, tt := range tests {
+		w := field("X1-A-1", 0, 0, tt.traits...)
+		if got := Yields(&w); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Yields(%v) = %v, want %v", tt.traits, got, tt.want)
+		}
+	}
+}
+
+func TestMiningScore(t *testing.T) {
+	current := m.Waypoint{X: 0, Y: 0}
+	metals := field("METALS", 30, 40, "COMMON_METAL_DEPOSITS")
+	bare := field("BARE", 30, 40)
+
+	tests := []struct {
+		name       string
+		target     m.Waypoint
+		priorities []string
+		want       float64
+	}{
+		{"deposits of a priority", metals, []string{"ICE_WATER", "IRON_ORE"}, 50},
+		{"no deposits of a priority", metals, []string{"ICE_WATER"}, 150},
+		{"no priorities", metals, nil, 50},
+		{"no deposits", bare, nil, 150},
+	}
+	for _, tt := range tests {
+		if got := MiningScore(&current, &tt.target, tt.priorities); got != tt.want {
+			t.Errorf("%s: MiningScore = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestChooseMiningTarget(t *testing.T) {
+	current := m.Waypoint{X: 0, Y: 0}
+	candidates := []m.Waypoint{
+		field("NEAR-METALS", 10, 0, "COMMON_METAL_DEPOSITS"),
+		field("FARTHER-ICE", 25, 0, "MINERAL_DEPOSITS"),
+		field("FAR-ICE", 40, 0, "MINERAL_DEPOSITS"),
+	}
+
+	tests := []struct {
+		name       string
+		candidates []m.Waypoint
+		priorities []string
+		want       string
+		reason     string
+	}{
+		{"nearest with the deposits", candidates, []string{"IRON_ORE"}, "NEAR-METALS", "deposits yield IRON_ORE"},
+		{"slightly farther with the deposits", candidates, []string{"ICE_WATER", "QUARTZ_SAND"}, "FARTHER-ICE", "deposits yield QUARTZ_SAND, ICE_WATER"},
+		{"too far to be worth it", candidates[:1:1], []string{"ICE_WATER"}, "NEAR-METALS", "nearest field; none has deposits of the priority goods"},
+		{"no priorities", candidates, nil, "NEAR-METALS", "deposits yield IRON_ORE, COPPER_ORE, ALUMINUM_ORE"},
+	}
+	for _, tt := range tests {
+		got, reason, err := ChooseMiningTarget(&current, tt.candidates, tt.priorities)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		if got.Symbol != tt.want || reason != tt.reason {
+			t.Errorf("%s: chose %s (%s), want %s (%s)", tt.name, got.Symbol, reason, tt.want, tt.reason)
+		}
+	}
+
+	// A field with the right deposits more than mismatchPenalty times as far loses to a nearer one.
+	far := []m.Waypoint{field("NEAR-METALS", 10, 0, "COMMON_METAL_DEPOSITS"), field("DISTANT-ICE", 31, 0, "MINERAL_DEPOSITS")}
+	if got, _, _ := ChooseMiningTarget(&current, far, []string{"ICE_WATER"}); got.Symbol != "NEAR-METALS" {
+		t.Errorf("chose %s, want NEAR-METALS over a field more than %d times as far", got.Symbol, mismatchPenalty)
+	}
+
+	if _, _, err := ChooseMiningTarget(&current, nil, nil); err == nil {
+		t.Error("choosing among no candidates succeeded")
+	}
+}
@@ -0,0 +1,60 @@
+package lib
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Rand is a seeded source of randomness that is safe for concurrent use. Every component that needs
+// randomness draws from the run's Rand, so a run can be reproduced from its seed. Methods are safe to call on
+// a nil Rand, which draws from an unseeded source.
+type Rand struct {
+	mu   sync.Mutex
+	seed int64
+	r    *rand.Rand
+}
+
+// NewRand creates a Rand seeded with seed.
+func NewRand(seed int64) *Rand {
+	return &Rand{seed: seed, r: rand.New(rand.NewSource(seed))}
+}
+
+// NewSeed returns a seed for a run that was not given one.
+func NewSeed() int64 {
+	return time.Now().UnixNano()
+}
+
+// Seed returns the seed the Rand was created with, or zero for a nil Rand.
+func (r *Rand) Seed() int64 {
+	if r == nil {
+		return 0
+	}
+
+	return r.seed
+}
+
+// Intn returns a non-negative pseudo-random number in [0, n). It panics if n <= 0.
+func (r *Rand) Intn(n int) int {
+	if r == nil {
+		return rand.Intn(n)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.r.Intn(n)
+}
+
+// Read fills p with pseudo-random bytes.
+func (r *Rand) Read(p []byte) {
+	if r == nil {
+		rand.Read(p)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.r.Read(p)
+}
@@ -0,0 +1,28 @@
+package lib
+
+import (
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+// PlanTour orders waypoints into a route starting from start, always visiting the nearest unvisited
+// waypoint next. The tour is not the shortest possible, but is cheap enough to plan on every dispatch.
+func PlanTour(start *m.Waypoint, waypoints []m.Waypoint) []m.Waypoint {
+	remaining := append([]m.Waypoint(nil), waypoints...)
+	tour := make([]m.Waypoint, 0, len(remaining))
+
+	current := start
+	for len(remaining) > 0 {
+		nearest := 0
+		for i := range remaining {
+			if WaypointDistance(current, &remaining[i]) < WaypointDistance(current, &remaining[nearest]) {
+				nearest = i
+			}
+		}
+
+		tour = append(tour, remaining[nearest])
+		current = &tour[len(tour)-1]
+		remaining = append(remaining[:nearest], remaining[nearest+1:]...)
+	}
+
+	return tour
+}
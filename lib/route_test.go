@@ -0,0 +1,40 @@
+package lib
+
+import (
+	"testing"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+func TestPlanTour(t *testing.T) {
+	start := m.Waypoint{Symbol: "X1-A-1"}
+	waypoints := []m.Waypoint{
+		{Symbol: "X1-A-2", X: 30},
+		{Symbol: "X1-A-3", X: -5},
+		{Symbol: "X1-A-4", X: 10},
+		{Symbol: "X1-A-5", X: 20, Y: 1},
+	}
+
+	tour := PlanTour(&start, waypoints)
+
+	var got []string
+	for _, waypoint := range tour {
+		got = append(got, waypoint.Symbol)
+	}
+	want := []string{"X1-A-3", "X1-A-4", "X1-A-5", "X1-A-2"}
+	if len(got) != len(want) {
+		t.Fatalf("tour = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("tour = %v, want %v", got, want)
+		}
+	}
+	if waypoints[0].Symbol != "X1-A-2" || waypoints[3].Symbol != "X1-A-5" {
+		t.Errorf("planning reordered the waypoints it was given: %v", waypoints)
+	}
+
+	if tour := PlanTour(&start, nil); len(tour) != 0 {
+		t.Errorf("tour of no waypoints = %v", tour)
+	}
+}
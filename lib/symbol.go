@@ -0,0 +1,89 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+)
+
+// symbolDashes replaces the dashes that stand in for a hyphen when a symbol is pasted from a document or chat.
+var symbolDashes = strings.NewReplacer(
+	"‐", "-", // hyphen
+	"‑", "-", // non-breaking hyphen
+	"‒", "-", // figure dash
+	"–", "-", // en dash
+	"—", "-", // em dash
+	"―", "-", // horizontal bar
+	"−", "-", // minus sign
+	"﹣", "-", // small hyphen-minus
+	"－", "-", // fullwidth hyphen-minus
+)
+
+// NormalizeSymbol turns a ship, waypoint, system, trade, or faction symbol a user typed into the upper-case form
+// the API expects, e.g. " x1-df55–a1" into X1-DF55-A1. Surrounding space is trimmed and unicode dashes become
+// hyphens. Anything left that is not ASCII letters, digits, '-', and '_', or that has an empty part between
+// hyphens, is rejected with an error saying why. Symbols from the API are already normal and need no call.
+func NormalizeSymbol(symbol string) (string, error) {
+	s := symbolDashes.Replace(strings.TrimSpace(symbol))
+	if s == "" {
+		return "", fmt.Errorf("invalid symbol %q: empty", symbol)
+	}
+
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		case r == ' ' || r == '\t':
+			return "", fmt.Errorf("invalid symbol %q: contains a space", symbol)
+		default:
+			return "", fmt.Errorf("invalid symbol %q: contains %q; symbols are ASCII letters, digits, '-', and '_'", symbol, r)
+		}
+	}
+
+	if strings.HasPrefix(s, "-") || strings.HasSuffix(s, "-") || strings.Contains(s, "--") {
+		return "", fmt.Errorf("invalid symbol %q: empty part between hyphens", symbol)
+	}
+
+	return strings.ToUpper(s), nil
+}
+
+// NormalizeWaypointSymbol normalizes a waypoint symbol a user typed like NormalizeSymbol, and checks that it
+// names a system and a waypoint in it, e.g. X1-DF55-A1.
+func NormalizeWaypointSymbol(symbol string) (string, error) {
+	s, err := NormalizeSymbol(symbol)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := SystemSymbolOf(s); err != nil {
+		return "", fmt.Errorf("invalid waypoint symbol %q: want SECTOR-SYSTEM-WAYPOINT, e.g. X1-DF55-A1", symbol)
+	}
+
+	return s, nil
+}
+
+// NormalizeSystemSymbol normalizes a system symbol a user typed like NormalizeSymbol, and checks that it has a
+// sector and a system, e.g. X1-DF55.
+func NormalizeSystemSymbol(symbol string) (string, error) {
+	s, err := NormalizeSymbol(symbol)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.Count(s, "-") != 1 {
+		return "", fmt.Errorf("invalid system symbol %q: want SECTOR-SYSTEM, e.g. X1-DF55", symbol)
+	}
+
+	return s, nil
+}
+
+// NormalizeSymbols normalizes each of symbols like NormalizeSymbol, in place.
+func NormalizeSymbols(symbols []string) error {
+	for i, symbol := range symbols {
+		s, err := NormalizeSymbol(symbol)
+		if err != nil {
+			return err
+		}
+		symbols[i] = s
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeSymbol(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		// err is part of the error message, for input that is rejected.
+		err string
+	}{
+		{in: "x1-df55-a1", want: "X1-DF55-A1"},
+		{in: "  X1-Df55-A1\t\n", want: "X1-DF55-A1"},
+		{in: "iron_ore", want: "IRON_ORE"},
+		{in: "x1–df55—a1", want: "X1-DF55-A1"},
+		{in: "x1－df55−a1", want: "X1-DF55-A1"},
+		{in: "", err: "empty"},
+		{in: " \t ", err: "empty"},
+		{in: "x1 df55", err: "contains a space"},
+		{in: "x1-dƒ55", err: `contains 'ƒ'`},
+		{in: "x1-df55-a1!", err: `contains '!'`},
+		{in: "ｘ1-DF55", err: `contains 'ｘ'`},
+		{in: "x1--a1", err: "empty part between hyphens"},
+		{in: "-x1-df55", err: "empty part between hyphens"},
+		{in: "x1-df55–", err: "empty part between hyphens"},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeSymbol(tt.in)
+		switch {
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("NormalizeSymbol(%q) = %q, %v; want an error saying %q", tt.in, got, err, tt.err)
+		case tt.err == "" && (err != nil || got != tt.want):
+			t.Errorf("NormalizeSymbol(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeWaypointAndSystemSymbol(t *testing.T) {
+	if got, err := NormalizeWaypointSymbol(" x1-df55–a1 "); err != nil || got != "X1-DF55-A1" {
+		t.Errorf("NormalizeWaypointSymbol = %q, %v; want X1-DF55-A1", got, err)
+	}
+	if _, err := NormalizeWaypointSymbol("x1-df55"); err == nil || !strings.Contains(err.Error(), "SECTOR-SYSTEM-WAYPOINT") {
+		t.Errorf("NormalizeWaypointSymbol of a system: err = %v, want the expected shape", err)
+	}
+
+	if got, err := NormalizeSystemSymbol("x1-df55"); err != nil || got != "X1-DF55" {
+		t.Errorf("NormalizeSystemSymbol = %q, %v; want X1-DF55", got, err)
+	}
+	if _, err := NormalizeSystemSymbol("x1-df55-a1"); err == nil || !strings.Contains(err.Error(), "SECTOR-SYSTEM") {
+		t.Errorf("NormalizeSystemSymbol of a waypoint: err = %v, want the expected shape", err)
+	}
+}
+
+func FuzzNormalizeSymbol(f *testing.F) {
+	for _, seed := range []string{"x1-df55-a1", " X1-DF55 ", "iron_ore", "x1—df55", "x1--a1", "", "-", "ÿ", "a\x00b", "x1 df55"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, in string) {
+		got, err := NormalizeSymbol(in)
+		if err != nil {
+			if got != "" {
+				t.Fatalf("NormalizeSymbol(%q) returned %q with error %v", in, got, err)
+			}
+			if !strings.HasPrefix(err.Error(), "invalid symbol ") {
+				t.Fatalf("NormalizeSymbol(%q) error %q does not say what was invalid", in, err)
+			}
+			return
+		}
+
+		if got == "" || strings.HasPrefix(got, "-") || strings.HasSuffix(got, "-") || strings.Contains(got, "--") {
+			t.Fatalf("NormalizeSymbol(%q) = %q, which has an empty part", in, got)
+		}
+		for _, r := range got {
+			if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				t.Fatalf("NormalizeSymbol(%q) = %q, which contains %q", in, got, r)
+			}
+		}
+		if again, err := NormalizeSymbol(got); err != nil || again != got {
+			t.Fatalf("NormalizeSymbol(%q) = %q, %v; want a normal symbol unchanged", got, again, err)
+		}
+		if !strings.EqualFold(got, symbolDashes.Replace(strings.TrimSpace(in))) {
+			t.Fatalf("NormalizeSymbol(%q) = %q, which changes more than case, space, and dashes", in, got)
+		}
+	})
+}
@@ -0,0 +1,250 @@
+package logging
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/clock"
+	"github.com/charmbracelet/log"
+	"github.com/go-logfmt/logfmt"
+)
+
+// DefaultDedupWindow is how long identical warnings and errors are collapsed for by default.
+const DefaultDedupWindow = time.Minute
+
+// maxDedupStreams is the most loggers whose repeated lines are tracked at once. When more repeat, the one
+// tracked longest is summarized and dropped.
+const maxDedupStreams = 256
+
+// maxRecordSize is the longest log record the dedupHandler decodes.
+const maxRecordSize = 1 << 20
+
+/*
+🔁 Deduplication
+*/
+
+// record is a log line as its logger made it, before it is formatted for output.
+type record struct {
+	level   log.Level
+	prefix  string
+	message string
+	// keyvals are the line's fields in the order they were logged.
+	keyvals []string
+}
+
+// decodeRecord decodes a line a logger formatted as logfmt into a record. The timestamp is dropped, as the
+// record is stamped again when it is written out.
+func decodeRecord(p []byte) (record, bool) {
+	var rec record
+
+	dec := logfmt.NewDecoderSize(bytes.NewReader(p), maxRecordSize)
+	if !dec.ScanRecord() {
+		return rec, false
+	}
+	for dec.ScanKeyval() {
+		key, value := string(dec.Key()), string(dec.Value())
+		switch key {
+		case log.TimestampKey:
+		case log.LevelKey:
+			rec.level = log.ParseLevel(value)
+		case log.PrefixKey:
+			rec.prefix = strings.TrimSuffix(value, ":")
+		case log.MessageKey:
+			rec.message = value
+		default:
+			rec.keyvals = append(rec.keyvals, key, value)
+		}
+	}
+
+	return rec, dec.Err() == nil
+}
+
+// key identifies the record among its logger's records: its level, message, and fields, without the mission
+// ID, so the same error from each new mission of a ship is the same record.
+func (r record) key() string {
+	var b strings.Builder
+	b.WriteString(r.level.String())
+	b.WriteByte(0)
+	b.WriteString(r.message)
+	for i := 0; i+1 < len(r.keyvals); i += 2 {
+		if r.keyvals[i] == "missionId" {
+			continue
+		}
+		b.WriteByte(0)
+		b.WriteString(r.keyvals[i])
+		b.WriteByte('=')
+		b.WriteString(r.keyvals[i+1])
+	}
+
+	return b.String()
+}
+
+// repeat is a warning or error being collapsed: the first occurrence was written, and the latest of the
+// occurrences since is held until the window closes.
+type repeat struct {
+	key    string
+	latest record
+	count  int
+	timer  clock.Timer
+}
+
+// dedupHandler collapses identical warnings and errors logged by the same logger within a window. The
+// Factory's loggers format their records as logfmt for it, and it decodes each back into a record, compares
+// records by level, message, and fields, and formats them for output itself. The first occurrence is always
+// written; repeats are counted, and once the window closes, or the logger logs a different warning or error,
+// the latest repeat is written once with a repeated=N field. Other records pass straight through.
+type dedupHandler struct {
+	window time.Duration
+	clock  clock.Clock
+	json   bool
+
+	mu sync.Mutex
+	// out formats records as the Factory was configured to, at any level.
+	out *log.Logger
+	// streams are the records being collapsed, keyed by the prefix of the logger that logged them, and order
+	// the prefixes oldest first.
+	streams map[string]*repeat
+	order   []string
+}
+
+func newDedupHandler(out io.Writer, formatter log.Formatter, window time.Duration, c clock.Clock) *dedupHandler {
+	return &dedupHandler{
+		window: window,
+		clock:  clock.Or(c),
+		json:   formatter == log.JSONFormatter,
+		out: log.NewWithOptions(out, log.Options{
+			ReportTimestamp: true,
+			Level:           log.DebugLevel,
+			Formatter:       formatter,
+		}),
+		streams: make(map[string]*repeat),
+	}
+}
+
+// Write takes a record a logger formatted as logfmt, and writes it out unless it repeats the logger's last
+// warning or error within the window.
+func (h *dedupHandler) Write(p []byte) (int, error) {
+	rec, ok := decodeRecord(p)
+	if !ok {
+		return len(p), nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if rec.level < log.WarnLevel {
+		h.write(rec, 0)
+		return len(p), nil
+	}
+
+	key := rec.key()
+	if r, ok := h.streams[rec.prefix]; ok {
+		if r.key == key {
+			r.latest = rec
+			r.count++
+			return len(p), nil
+		}
+
+		h.flush(rec.prefix)
+	}
+
+	if len(h.order) >= maxDedupStreams {
+		h.flush(h.order[0])
+	}
+
+	stream := rec.prefix
+	r := &repeat{key: key}
+	r.timer = h.clock.AfterFunc(h.window, func() { h.expire(stream, r) })
+	h.streams[stream] = r
+	h.order = append(h.order, stream)
+	h.write(rec, 0)
+
+	return len(p), nil
+}
+
+// write formats a record for output, with the number of repeats it stands for, if any.
+func (h *dedupHandler) write(rec record, repeated int) {
+	keyvals := make([]interface{}, 0, len(rec.keyvals)+2)
+	for i := 0; i+1 < len(rec.keyvals); i += 2 {
+		keyvals = append(keyvals, rec.keyvals[i], h.value(rec.keyvals[i+1]))
+	}
+	if repeated > 0 {
+		keyvals = append(keyvals, "repeated", repeated)
+	}
+
+	h.out.SetPrefix(rec.prefix)
+	switch {
+	case rec.level <= log.DebugLevel:
+		h.out.Debug(rec.message, keyvals...)
+	case rec.level == log.InfoLevel:
+		h.out.Info(rec.message, keyvals...)
+	case rec.level == log.WarnLevel:
+		h.out.Warn(rec.message, keyvals...)
+	default:
+		// A fatal record is written as an error; its logger exits the process once it is written.
+		h.out.Error(rec.message, keyvals...)
+	}
+}
+
+// value returns a field's value for output. Logfmt carries every value as text, so for JSON output a value
+// that reads as a number or boolean is written as one again.
+func (h *dedupHandler) value(s string) interface{} {
+	if !h.json {
+		return s
+	}
+
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return f
+	}
+	if s == "true" || s == "false" {
+		return s == "true"
+	}
+
+	return s
+}
+
+// expire closes the window of a stream's repeated record, if it is still the one being collapsed.
+func (h *dedupHandler) expire(stream string, r *repeat) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.streams[stream] == r {
+		h.flush(stream)
+	}
+}
+
+// flush writes the summary of a stream's repeats, if there were any, and stops tracking the stream.
+func (h *dedupHandler) flush(stream string) {
+	r := h.streams[stream]
+	r.timer.Stop()
+	delete(h.streams, stream)
+	for i, s := range h.order {
+		if s == stream {
+			h.order = append(h.order[:i], h.order[i+1:]...)
+			break
+		}
+	}
+
+	if r.count > 0 {
+		h.write(r.latest, r.count)
+	}
+}
+
+// Flush writes the summaries of every record being collapsed, so no repeats go uncounted when the process
+// exits.
+func (h *dedupHandler) Flush() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for len(h.order) > 0 {
+		h.flush(h.order[0])
+	}
+}
@@ -0,0 +1,158 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/clock"
+)
+
+var start = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// output collects the lines a Factory writes, which summaries may write from the fake clock's goroutines.
+type output struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.buf.Write(p)
+}
+
+func (o *output) lines() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return strings.Split(strings.TrimRight(o.buf.String(), "\n"), "\n")
+}
+
+// factory returns a deduplicating Factory writing to out, on a fake clock.
+func factory(t *testing.T, format string) (*Factory, *output, *clock.Fake) {
+	t.Helper()
+
+	out := &output{}
+	fake := clock.NewFake(start)
+	f, err := NewFactory(out, Options{Level: "info", Format: format, DedupWindow: time.Minute, Clock: fake})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f, out, fake
+}
+
+func TestDedupCollapsesBurstOfIdenticalErrors(t *testing.T) {
+	f, out, fake := factory(t, "text")
+	l := f.ForShip("🚀 MOCK-1", "MOCK-1")
+
+	// Each retry runs as a new mission, so only the mission ID differs.
+	for i := 1; i <= 10; i++ {
+		l.Error("💲 Error selling cargo.", "mission", "SellCargo", "missionId", fmt.Sprintf("m-%d", i), "error", errors.New("market does not trade IRON_ORE"))
+	}
+	if got := out.lines(); len(got) != 1 || !strings.Contains(got[0], "missionId=m-1 ") {
+		t.Fatalf("wrote %q during the burst, want only the first occurrence", got)
+	}
+
+	fake.Advance(time.Minute)
+	for deadline := time.Now().Add(5 * time.Second); len(out.lines()) < 2 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+
+	got := out.lines()
+	if len(got) != 2 {
+		t.Fatalf("wrote %q, want the first occurrence and one summary", got)
+	}
+	if !strings.Contains(got[1], "ERRO 🚀 MOCK-1: 💲 Error selling cargo.") || !strings.Contains(got[1], "missionId=m-10 ") || !strings.HasSuffix(got[1], " repeated=9") {
+		t.Fatalf("summary %q, want the latest occurrence with repeated=9", got[1])
+	}
+	if !strings.Contains(got[0], `error="market does not trade IRON_ORE"`) {
+		t.Fatalf("first occurrence %q lost its error field", got[0])
+	}
+}
+
+func TestDedupKeepsDifferentRecords(t *testing.T) {
+	f, out, _ := factory(t, "text")
+	ship := f.ForShip("🚀 MOCK-1", "MOCK-1")
+	other := f.ForShip("🚀 MOCK-2", "MOCK-2")
+
+	ship.Error("Error docking.", "error", "ship not in orbit")
+	ship.Error("Error docking.", "error", "ship not in orbit")
+	other.Error("Error docking.", "error", "ship not in orbit")
+	ship.Warn("Error docking.", "error", "ship not in orbit")
+	ship.Info("Docked.")
+	ship.Info("Docked.")
+	ship.Error("Error docking.", "error", "ship in transit")
+	f.Flush()
+
+	want := []string{
+		"ERRO 🚀 MOCK-1: Error docking. error=\"ship not in orbit\"",
+		// Another logger's record, however alike, is its own.
+		"ERRO 🚀 MOCK-2: Error docking. error=\"ship not in orbit\"",
+		// A different level ends the repeats, so they are summarized before it.
+		"ERRO 🚀 MOCK-1: Error docking. error=\"ship not in orbit\" repeated=1",
+		"WARN 🚀 MOCK-1: Error docking. error=\"ship not in orbit\"",
+		// Info lines are never collapsed.
+		"INFO 🚀 MOCK-1: Docked.",
+		"INFO 🚀 MOCK-1: Docked.",
+		"ERRO 🚀 MOCK-1: Error docking. error=\"ship in transit\"",
+	}
+	got := out.lines()
+	if len(got) != len(want) {
+		t.Fatalf("wrote %d lines %q, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if !strings.HasSuffix(got[i], want[i]) {
+			t.Errorf("line %d = %q, want it to end %q", i+1, got[i], want[i])
+		}
+	}
+}
+
+func TestDedupWritesJSONWithTypedFields(t *testing.T) {
+	f, out, _ := factory(t, "json")
+	l := f.New("👽 AGENT")
+
+	l.Warn("Low credits.", "credits", 1200, "ratio", 0.25, "ok", false, "ship", "MOCK-1")
+	l.Warn("Low credits.", "credits", 1200, "ratio", 0.25, "ok", false, "ship", "MOCK-1")
+	f.Flush()
+
+	got := out.lines()
+	if len(got) != 2 {
+		t.Fatalf("wrote %q, want the first occurrence and one summary", got)
+	}
+	var summary map[string]interface{}
+	if err := json.Unmarshal([]byte(got[1]), &summary); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"lvl": "warn", "prefix": "👽 AGENT:", "msg": "Low credits.", "credits": 1200.0, "ratio": 0.25, "ok": false, "ship": "MOCK-1", "repeated": 1.0}
+	for key, value := range want {
+		if summary[key] != value {
+			t.Errorf("%s = %#v, want %#v", key, summary[key], value)
+		}
+	}
+}
+
+func TestDedupTracksBoundedStreams(t *testing.T) {
+	f, out, _ := factory(t, "text")
+
+	for i := 0; i <= maxDedupStreams; i++ {
+		l := f.New(fmt.Sprintf("🚀 S-%d", i))
+		l.Error("Stuck.")
+		l.Error("Stuck.")
+	}
+
+	if n := len(f.dedup.streams); n != maxDedupStreams {
+		t.Fatalf("%d streams tracked, want at most %d", n, maxDedupStreams)
+	}
+	// The oldest stream was summarized to make room for the newest.
+	if got := out.lines(); !strings.HasSuffix(got[maxDedupStreams], "🚀 S-0: Stuck. repeated=1") {
+		t.Fatalf("line %d = %q, want S-0's summary", maxDedupStreams+1, got[maxDedupStreams])
+	}
+}
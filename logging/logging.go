@@ -0,0 +1,124 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/clock"
+	"github.com/charmbracelet/log"
+)
+
+// Options configures the loggers created by a Factory.
+type Options struct {
+	// Level is one of debug, info, warn, or error.
+	Level string
+	// Format is text or json.
+	Format string
+	// Only, when set, suppresses Info and Debug lines from ships other than this ship symbol.
+	Only string
+	// DedupWindow is how long identical warnings and errors from the same logger are collapsed into one line
+	// and a repeat count. Zero writes every line.
+	DedupWindow time.Duration
+	// Clock times the DedupWindow. Defaults to the real clock.
+	Clock clock.Clock
+}
+
+// Factory creates the bots' loggers so they share output, level, format, and filtering.
+type Factory struct {
+	out       io.Writer
+	level     log.Level
+	formatter log.Formatter
+	only      string
+	dedup     *dedupHandler
+}
+
+// NewFactory creates a Factory writing to out.
+func NewFactory(out io.Writer, opts Options) (*Factory, error) {
+	f := &Factory{
+		out:   out,
+		level: log.ParseLevel(opts.Level),
+		only:  opts.Only,
+	}
+
+	switch opts.Format {
+	case "", "text":
+		f.formatter = log.TextFormatter
+	case "json":
+		f.formatter = log.JSONFormatter
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected text or json", opts.Format)
+	}
+
+	// Deduplicating loggers hand their records to the dedupHandler as logfmt, which keeps the fields in order
+	// and decodes losslessly, and the handler formats them for out.
+	if opts.DedupWindow > 0 {
+		f.dedup = newDedupHandler(out, f.formatter, opts.DedupWindow, opts.Clock)
+		f.out = f.dedup
+		f.formatter = log.LogfmtFormatter
+	}
+
+	return f, nil
+}
+
+// Flush writes the repeat counts of warnings and errors still being collapsed. It is a no-op without a
+// DedupWindow.
+func (f *Factory) Flush() {
+	if f.dedup != nil {
+		f.dedup.Flush()
+	}
+}
+
+// New creates a logger with a prefix.
+func (f *Factory) New(prefix string) *log.Logger {
+	return log.NewWithOptions(f.out, log.Options{
+		ReportTimestamp: true,
+		Level:           f.level,
+		Formatter:       f.formatter,
+		Prefix:          prefix,
+	})
+}
+
+// ForShip creates a logger for a ship, applying the Only filter.
+func (f *Factory) ForShip(prefix string, shipSymbol string) *log.Logger {
+	l := f.New(prefix)
+
+	if f.only != "" && f.only != shipSymbol && l.GetLevel() < log.WarnLevel {
+		l.SetLevel(log.WarnLevel)
+	}
+
+	return l
+}
+
+var (
+	mu             sync.RWMutex
+	defaultFactory = &Factory{out: os.Stderr, level: log.InfoLevel, formatter: log.TextFormatter}
+)
+
+// SetDefault replaces the Factory used by the package-level New and ForShip.
+func SetDefault(f *Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	defaultFactory = f
+}
+
+// Default returns the Factory used by the package-level New and ForShip.
+func Default() *Factory {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return defaultFactory
+}
+
+// New creates a logger with a prefix from the default Factory.
+func New(prefix string) *log.Logger {
+	return Default().New(prefix)
+}
+
+// ForShip creates a logger for a ship from the default Factory.
+func ForShip(prefix string, shipSymbol string) *log.Logger {
+	return Default().ForShip(prefix, shipSymbol)
+}
@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewFactoryRejectsUnknownFormats(t *testing.T) {
+	if _, err := NewFactory(&bytes.Buffer{}, Options{Format: "xml"}); err == nil {
+		t.Fatal("accepted the xml format")
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	var out bytes.Buffer
+	f, err := NewFactory(&out, Options{Level: "info", Format: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.New("🖥️ TERMINAL_BOT").Info("Hello.", "credits", 100)
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("not a JSON line: %s\n%s", err, out.String())
+	}
+	if line["msg"] != "Hello." || line["prefix"] != "🖥️ TERMINAL_BOT:" || line["credits"] != float64(100) {
+		t.Errorf("line = %v", line)
+	}
+}
+
+func TestLevel(t *testing.T) {
+	var out bytes.Buffer
+	f, err := NewFactory(&out, Options{Level: "warn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := f.New("TEST")
+	l.Info("quiet")
+	l.Warn("loud")
+
+	if strings.Contains(out.String(), "quiet") || !strings.Contains(out.String(), "loud") {
+		t.Errorf("output at warn level:\n%s", out.String())
+	}
+}
+
+func TestForShipOnlyFiltersOtherShips(t *testing.T) {
+	var out bytes.Buffer
+	f, err := NewFactory(&out, Options{Level: "debug", Only: "GOGARIN-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := f.ForShip("🚀 GOGARIN-1", "GOGARIN-1")
+	other.Info("other info")
+	other.Warn("other warning")
+
+	only := f.ForShip("🚀 GOGARIN-2", "GOGARIN-2")
+	only.Debug("only debug")
+
+	got := out.String()
+	if strings.Contains(got, "other info") {
+		t.Errorf("Info from a filtered ship was written:\n%s", got)
+	}
+	if !strings.Contains(got, "other warning") || !strings.Contains(got, "only debug") {
+		t.Errorf("expected lines missing:\n%s", got)
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	previous := Default()
+	t.Cleanup(func() { SetDefault(previous) })
+
+	var out bytes.Buffer
+	f, err := NewFactory(&out, Options{Level: "info"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetDefault(f)
+
+	New("TEST").Info("through the default")
+	ForShip("TEST", "GOGARIN-1").Info("ship through the default")
+
+	if !strings.Contains(out.String(), "through the default") || !strings.Contains(out.String(), "ship through the default") {
+		t.Errorf("output:\n%s", out.String())
+	}
+}
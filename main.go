@@ -1,20 +1,41 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
-	"sync"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/bot"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/health"
+	"github.com/GeoffreyDick/gogarin/ledger"
 	"github.com/GeoffreyDick/gogarin/lib"
-	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/logging"
+	"github.com/GeoffreyDick/gogarin/metrics"
+	"github.com/GeoffreyDick/gogarin/notify"
+	"github.com/GeoffreyDick/gogarin/report"
+	"github.com/GeoffreyDick/gogarin/server"
+	"github.com/GeoffreyDick/gogarin/status"
+	"github.com/GeoffreyDick/gogarin/store"
+	"github.com/GeoffreyDick/gogarin/telemetry"
+	"github.com/GeoffreyDick/gogarin/tui"
 	"github.com/charmbracelet/log"
 	"github.com/joho/godotenv"
 )
 
 var (
-	token string
+	cfg *config.Config
+	// monitor pauses missions while the API is unavailable. It is nil, and never pauses, until run starts.
+	monitor *health.Monitor
 )
 
 func init() {
@@ -23,595 +44,687 @@ func init() {
 		Prefix:          "🏗️ INIT_BOT",
 	})
 
-	// load .env file
+	// load .env file, if present
 	err := godotenv.Load(".env")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		l.Warn("Error loading .env file", "error", err)
+	}
+
+	cfg, err = config.Load(os.Getenv("GOGARIN_CONFIG"))
 	if err != nil {
-		l.Fatal("Error loading .env file")
+		l.Fatal("Error loading config", "error", err)
+	}
+}
+
+func main() {
+	if err := configureLogging(os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
-	token = os.Getenv("TOKEN")
+	c := newClient(cfg.Token)
 
-	if token == "" {
-		l.Fatal("TOKEN environment variable not set")
+	if err := execute(c, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 }
 
-func main() {
-	c := api.NewClient(token)
+// newClient creates an API client for token using the configured rate limit, request caps, page workers, base URL,
+// and recorder.
+func newClient(token string) *api.Client {
+	return clientFor(cfg, token)
+}
 
-	// TerminalBot actions.
-	tb := NewTerminalBot(c)
+// clientFor creates an API client for token using c's rate limit, request caps, page workers, base URL, and
+// recorder. Each client has a request budget of its own, as the API's limits are per token.
+func clientFor(c *config.Config, token string) *api.Client {
+	opts := []api.Option{api.WithRateLimit(c.RateLimit), api.WithConcurrentPages(c.PageWorkers)}
+	for subsystem, share := range c.RequestCaps {
+		opts = append(opts, api.WithSubsystemCap(subsystem, share))
+	}
+	if c.BaseURL != "" {
+		opts = append(opts, api.WithBaseURL(c.BaseURL))
+	}
+	if c.RecordDir != "" {
+		opts = append(opts, api.WithRecorder(c.RecordDir))
+	}
+	// monitor is read when each response arrives, so clients created before run starts report to it too.
+	opts = append(opts, api.WithStatusObserver(func(statusCode int) { monitor.Observe(statusCode) }))
 
-	// Get agent, verify, and welcome.
-	agent, err := tb.GetMyAgent()
-	if err != nil {
-		tb.logger.Fatal("Failed to get agent", "error", err)
+	return api.NewClient(token, opts...)
+}
+
+// interactive returns a handle on c whose requests are served ahead of fleet traffic and count as interactive,
+// when c supports priorities.
+func interactive(c api.ClientAPI) api.ClientAPI {
+	if client, ok := c.(*api.Client); ok {
+		return client.WithPriority(api.High).WithSubsystem(api.SubsystemInteractive)
 	}
-	tb.logger.Infof("Agent verified. Welcome %s", agent.Symbol)
 
-	// AgentBot actions.
-	ab := NewAgentBot(c, agent)
+	return c
+}
 
-	// Get contracts.
-	ab.logger.Info("Getting contracts...")
-	contracts, err := ab.GetMyContracts()
+// configureLogging points every bot logger at out, using the configured level, format, and ship filter.
+func configureLogging(out io.Writer) error {
+	f, err := logging.NewFactory(out, logging.Options{
+		Level:       cfg.LogLevel,
+		Format:      cfg.LogFormat,
+		Only:        cfg.LogOnly,
+		DedupWindow: logging.DefaultDedupWindow,
+	})
 	if err != nil {
-		tb.logger.Fatal("Failed to get contracts", "error", err)
+		return err
 	}
-	ab.logger.Info("Contracts retrieved.", "count", len(*contracts))
 
-	// Accept contracts if not already accepted.
-	for _, contract := range *contracts {
-		if !contract.Accepted {
-			ab.logger.Info("Found new contract. Accepting...", "id", contract.ID)
-			contract, err := c.AcceptContract(contract.ID)
-			if err != nil {
-				tb.logger.Fatal("Failed to accept contract", "error", err)
-			}
-			ab.logger.Info("Contract accepted.", "terms", contract.Contract.Terms)
+	logging.SetDefault(f)
+
+	return nil
+}
+
+// runOptions configures the run subcommand.
+type runOptions struct {
+	tui          bool
+	dryRun       bool
+	dryRunSpeed  float64
+	httpAddr     string
+	autoRegister bool
+	// skipPreflight starts the fleet without running the preflight checks.
+	skipPreflight bool
+	// seed seeds the run's randomness; zero picks a new seed.
+	seed int64
+}
+
+// run starts the autonomous fleet loop of the configured agent, or of each of the configured agents, optionally
+// behind the TUI dashboard and the HTTP status server.
+func run(c api.ClientAPI, opts runOptions) (err error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	if len(cfg.Agents) > 0 {
+		switch {
+		case opts.tui:
+			return errors.New("the dashboard shows a single agent; run several agents without --tui")
+		case opts.autoRegister:
+			return errors.New("--auto-register saves a single token; register each of several agents with `gogarin register`")
 		}
 	}
 
-	// Determine priorities.
-	ab.logger.Info("Determining priorities...")
-	priorities, err := ab.DeterminePriorities(contracts)
-	if err != nil {
-		tb.logger.Fatal("Failed to determine priorities", "error", err)
-	}
-	ab.logger.Info("Priorities determined.", "priorities", priorities)
-
-	// sbCh contains a ShipBot for each ship in the fleet.
-	// ShipBots sent to sbCh will be processed by the command loop.
-	sbCh := make(chan ShipBot)
-	done := make(chan bool)
-	completed := make(chan bool)
-
-	wg := sync.WaitGroup{}
-
-	// Start ShipBot command loop.
-	go func() {
-		ab.logger.Info("Starting command loop...")
-		for {
-			select {
-			case sb := <-sbCh:
-				sb.logger.Info("Reporting in.", "role", sb.ship.Registration.Role)
-				// RoleSwitch
-				switch sb.ship.Registration.Role {
-				case "COMMAND":
-					// TODO: command ship logic
-				case "EXCAVATOR":
-					if sb.IsFullOfCargo() && sb.IsAtWaypointWithTrait("MARKETPLACE") && sb.ship.Nav.Status == "DOCKED" {
-						ab.logger.Info(fmt.Sprintf("%s %s", sb.ship.Registration.Role, sb.ship.Symbol), "mission", "Sell cargo")
-						go sb.SellCargo(sbCh)
-					}
-
-					if sb.IsFullOfCargo() && sb.IsAtWaypointWithTrait("MARKETPLACE") && sb.ship.Nav.Status != "DOCKED" {
-						ab.logger.Info(fmt.Sprintf("%s %s", sb.ship.Registration.Role, sb.ship.Symbol), "mission", "Dock ship")
-						go sb.DockShip(sbCh)
-					}
-
-					if sb.IsFullOfCargo() && !sb.IsAtWaypointWithTrait("MARKETPLACE") {
-						ab.logger.Info(fmt.Sprintf("%s %s", sb.ship.Registration.Role, sb.ship.Symbol), "mission", "Navigate to nearest marketplace")
-						go sb.NavigateToNearestWaypointWithTrait("MARKETPLACE", sbCh)
-					}
-
-					if !sb.IsFullOfCargo() && sb.IsAtWaypointOfType("ASTEROID_FIELD") {
-						ab.logger.Info(fmt.Sprintf("%s %s", sb.ship.Registration.Role, sb.ship.Symbol), "mission", "Extract resources")
-						go sb.ExtractResources(sbCh)
-					}
-
-					if !sb.IsFullOfCargo() && !sb.IsAtWaypointOfType("ASTEROID_FIELD") {
-						ab.logger.Info(fmt.Sprintf("%s %s", sb.ship.Registration.Role, sb.ship.Symbol), "mission", "Navigate to nearest asteroid field")
-						go sb.NavigateToNearestWaypointOfType("ASTEROID_FIELD", sbCh)
-					}
-				}
-			case <-done:
-				fmt.Println("exiting...")
-				completed <- true
-				return
-			}
-		}
-	}()
+	var out io.Writer = os.Stderr
 
-	// Get fleet.
-	ab.logger.Info("Waking fleet...")
-	ships, err := c.GetMyShips()
-	if err != nil {
-		ab.logger.Fatal("Failed to get ships", "error", err)
+	if opts.tui {
+		// The dashboard owns the terminal, so logs go to a file instead.
+		f, err := os.OpenFile("gogarin.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
 	}
 
-	// If only one ship, InitiateRequisitionProtocol.
-	if len(*ships) > 0 {
-		ab.logger.Info("Found only one ship. Sending command ship on requisition mission...")
+	if err := configureLogging(out); err != nil {
+		return err
+	}
+	defer logging.Default().Flush()
 
-		// InitiateRequisitionProtocol.
-		ship := (*ships)[0]
-		sb := NewShipBot(c, &ship, ab.agent)
+	healthOpts := health.DefaultOptions
+	healthOpts.OnReset = func(previous string, current string) {
+		cancel(handleReset(previous, current, opts.autoRegister))
+	}
+	monitor = health.NewMonitor(newClient(""), healthOpts, logging.New("🩺 HEALTH"))
+	if err := monitor.Baseline(); err != nil {
+		logging.New("🩺 HEALTH").Warn("Failed to get server status. Resets will not be detected.", "error", err)
+	}
 
-		wg.Add(1)
+	agents, err := startAgents(ctx, c, opts)
+	if err != nil {
+		return err
+	}
+	// Registered before the agents are closed, so it runs after, once their fleets have stopped.
+	defer func() {
+		recovered := recover()
+		shutDown(agents, err, recovered)
 
-		go sb.InitiateRequisitionProtocol(&wg)
+		if recovered != nil {
+			panic(recovered)
+		}
+	}()
+	defer func() {
+		for _, a := range agents {
+			a.close()
+		}
+	}()
 
-		wg.Wait()
-	}
+	if opts.httpAddr != "" {
+		l := logging.New("🌐 HTTP")
+		srv := newStatusServer(opts.httpAddr, agents)
 
-	// Get fleet underway.
-	for i, ship := range *ships {
-		wg.Add(1)
-		defer wg.Done()
-		ship = (*ships)[i]
+		go func() {
+			l.Info("Serving status.", "addr", opts.httpAddr)
+			if err := srv.ListenAndServe(); err != nil {
+				l.Error("Status server stopped", "error", err)
+			}
+		}()
 
-		go func(ship m.Ship) {
-			// Create ShipBot.
-			sb := NewShipBot(c, &ship, ab.agent)
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
 
-			// Check if ship on cooldown
-			sb.logger.Info("⚛ Checking reactor...")
-			cooldown, err := sb.GetShipCooldown()
-			if err != nil {
-				sb.logger.Error("⚛ Error getting ship cooldown.", "error", err)
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				l.Error("Failed to shut down status server", "error", err)
+			}
+		}()
+	}
+
+	var failed int32
+	for _, a := range agents {
+		a := a
+		go func() {
+			err := a.fleet.Start(ctx)
+			switch {
+			case err == nil, ctx.Err() != nil:
+			case a.name == "":
+				cancel(fmt.Errorf("starting fleet: %w", err))
+			default:
+				// One agent failing to start leaves the others running, unless it was the last.
+				logging.New("👥 "+a.name).Error("Failed to start fleet. The other agents keep running.", "error", err)
+				if int(atomic.AddInt32(&failed, 1)) == len(agents) {
+					cancel(errors.New("no agent's fleet started"))
+				}
 			}
-			sb.cooldown = cooldown
+		}()
+	}
 
-			// Send sb to sbCh.
-			sbCh <- *sb
-		}(ship)
+	if opts.tui {
+		return tui.Run(agents[0].board)
 	}
 
-	wg.Wait()
+	<-ctx.Done()
 
-	// close(done)
-	// <-completed
-}
+	if err := context.Cause(ctx); !errors.Is(err, context.Canceled) {
+		return err
+	}
 
-/*
-🖥️ TERMINAL_BOT
-*/
+	return nil
+}
 
-// TerminalBot represents a TerminalBot instance.
-type TerminalBot struct {
-	client *api.Client
-	logger *log.Logger
+// agent is one agent's bot and everything it keeps to itself: its client and request budget, event bus,
+// stores, files, and fleet. Agents run in one process share only the logs and the status server.
+type agent struct {
+	// name is the agent's name among several, or empty when the process runs a single agent.
+	name  string
+	board *status.Board
+	fleet *bot.Fleet
+	// server serves the agent's status routes, when the status server is enabled.
+	server *server.Server
+	// session tallies what the agent does, and ledger its credit movements, for the report at exit.
+	session *report.Session
+	ledger  *ledger.Ledger
+	// state keeps the agent's final snapshot, and journal its unfinished missions to snapshot.
+	state   *store.StateFile
+	journal *store.Journal
+	// usage reports the API requests the agent's client sent, or is nil if the client does not count them.
+	usage func() []api.SubsystemUsage
+	// reportPath is the file the report is written to, or empty to only log it.
+	reportPath string
+	// closers release the agent's resources, in reverse order, when it stops.
+	closers []func()
 }
 
-// NewTerminalBot creates a new instance of TerminalBot.
-func NewTerminalBot(c *api.Client) *TerminalBot {
-	return &TerminalBot{
-		client: c,
-		logger: log.NewWithOptions(os.Stderr, log.Options{
-			ReportTimestamp: true,
-			Prefix:          "🖥️ TERMINAL_BOT",
-		}),
+// close stops the agent's fleet and releases its resources.
+func (a *agent) close() {
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		a.closers[i]()
 	}
 }
 
-// GetMyAgent verifies an agent.
-func (tb *TerminalBot) GetMyAgent() (*m.Agent, error) {
-	tb.logger.Info("Credentials received. Retrieving agent...")
-	agent, err := tb.client.GetMyAgent()
-	if err != nil {
-		return nil, err
+// report logs a report summing up the agent's run, which ended for outcome, and writes it to the report file.
+func (a *agent) report(outcome string) {
+	l := logging.New("📋 REPORT")
+	if a.name != "" {
+		l = l.With("agent", a.name)
 	}
 
-	return agent, nil
-}
+	r := a.session.Report(time.Now(), outcome, a.ledger, a.usage)
+	l.Info("Session report:\n" + r.Render())
 
-/*
-👽 AGENT_BOT
-*/
-
-// AgentBot represents an AgentBot instance.
-type AgentBot struct {
-	client     *api.Client
-	logger     *log.Logger
-	agent      *m.Agent
-	contracts  *[]m.Contract
-	priorities *[]string
+	if a.reportPath == "" {
+		return
+	}
+	if err := r.WriteFile(a.reportPath); err != nil {
+		l.Error("Failed to write report", "path", a.reportPath, "error", err)
+		return
+	}
+	l.Info("Report written.", "path", a.reportPath)
 }
 
-// NewAgentBot creates a new instance of AgentBot.
-func NewAgentBot(client *api.Client, agent *m.Agent) *AgentBot {
-	return &AgentBot{
-		client: client,
-		logger: log.NewWithOptions(os.Stderr, log.Options{
-			ReportTimestamp: true,
-			Prefix:          fmt.Sprintf("👽 %s", agent.Symbol),
-		}),
-		agent: agent,
+// shutDown reports and snapshots each agent of a run that ended with err, or with a panic when recovered is not
+// nil, once their fleets have stopped.
+func shutDown(agents []*agent, err error, recovered interface{}) {
+	outcome := "stopped"
+	switch {
+	case recovered != nil:
+		outcome = fmt.Sprintf("panic: %v", recovered)
+	case err != nil:
+		outcome = err.Error()
+	}
+
+	for _, a := range agents {
+		a.report(outcome)
+		// The state from before a server reset was purged with the other stores; a snapshot would write it back.
+		if errors.Is(err, errServerReset) {
+			continue
+		}
+		// A panic may have cut a mission short between journal writes, so only an orderly exit is clean.
+		a.snapshot(outcome, recovered == nil)
 	}
 }
 
-// GetMyContracts retrieves the Agent's contracts.
-func (ab *AgentBot) GetMyContracts() (*[]m.Contract, error) {
-	contracts, err := ab.client.GetMyContracts()
-	if err != nil {
-		return nil, err
+// snapshot writes the agent's final state, marked clean if it shut down cleanly, once its fleet has stopped and
+// its telemetry is flushed.
+func (a *agent) snapshot(outcome string, clean bool) {
+	l := logging.New("🧷 STATE")
+	if a.name != "" {
+		l = l.With("agent", a.name)
 	}
 
-	return contracts, nil
+	snapshot := store.Snapshot{
+		Clean:        clean,
+		At:           time.Now(),
+		Outcome:      outcome,
+		Journal:      a.journal.Entries(),
+		Ledger:       a.ledger.Entries(),
+		LedgerTotals: a.ledger.Totals(),
+	}
+	if err := a.state.Close(snapshot); err != nil {
+		l.Error("Failed to write final state", "error", err)
+		return
+	}
+	l.Info("Final state written.", "clean", clean)
 }
 
-// SetPriorities scrapes the agent's contracts for priority trade goods.
-func (ab *AgentBot) DeterminePriorities(contracts *[]m.Contract) (*[]string, error) {
-	var priorities []string
+// startAgents prepares the agent c authenticates as, or, when several agents are configured, each of them
+// with a client of its own. An agent that fails its token check or preflight is left out, so it does not stop
+// the others; only when every agent fails is an error returned.
+func startAgents(ctx context.Context, c api.ClientAPI, opts runOptions) ([]*agent, error) {
+	if len(cfg.Agents) == 0 {
+		a, err := newAgent(ctx, "", cfg, c, opts)
+		if err != nil {
+			return nil, err
+		}
 
-	for _, contract := range *contracts {
-		for _, good := range contract.Terms.Deliver {
-			if !lib.Contains(priorities, good.TradeSymbol) {
-				priorities = append(priorities, good.TradeSymbol)
-			}
+		return []*agent{a}, nil
+	}
+
+	var agents []*agent
+	for _, ac := range cfg.Agents {
+		acfg := cfg.ForAgent(ac)
+		l := logging.New("👥 " + ac.Name)
+
+		l.Info("Starting agent...", "symbol", acfg.Symbol)
+		a, err := newAgent(ctx, ac.Name, acfg, clientFor(acfg, acfg.Token), opts)
+		if err != nil {
+			l.Error("Agent failed to start. The other agents run without it.", "error", err)
+			continue
 		}
+
+		agents = append(agents, a)
 	}
 
-	return &priorities, nil
-}
+	if len(agents) == 0 {
+		return nil, errors.New("no agent started; fix the problems above")
+	}
 
-/*
-🚀 SHIP_BOT
-*/
-
-// ShipBot represents a ShipBot instance.
-type ShipBot struct {
-	client     *api.Client
-	logger     *log.Logger
-	agent      *m.Agent
-	contracts  *[]m.Contract
-	priorities []string
-	ship       *m.Ship
-	cooldown   *m.Cooldown
+	return agents, nil
 }
 
-// NavigateToNearestWaypointOfType: Navigate to nearest waypoint of type.
-func (sb *ShipBot) NavigateToNearestWaypointOfType(waypointType string, sbCh chan ShipBot) {
-	sb.logger.Info("Navigating to nearest waypoint of type...", "waypointType", waypointType)
+// newAgent checks an agent's token and runs its preflight, then builds its fleet from acfg. Nothing runs until
+// the fleet is started.
+func newAgent(ctx context.Context, name string, acfg *config.Config, c api.ClientAPI, opts runOptions) (_ *agent, err error) {
+	bus := event.NewBus()
+	board := status.NewBoard()
+	go board.Follow(bus.Subscribe(256))
+
+	ldg := ledger.New(500)
+	go ldg.Follow(bus.Subscribe(256))
+
+	earnings := ledger.NewEarnings(acfg.EarningsWindow, acfg.EarningsSplit)
+	go earnings.Follow(bus.Subscribe(256))
+	board.SetEarnings(earnings)
+
+	session := report.NewSession(time.Now())
+	go session.Follow(bus.Subscribe(256))
+
+	a := &agent{name: name, board: board, session: session, ledger: ldg, reportPath: acfg.ReportPath}
+	// Closing the bus last ends every follower, whichever step below fails.
+	a.closers = append(a.closers, bus.Close)
+	defer func() {
+		if err != nil {
+			a.close()
+		}
+	}()
+
+	markets := store.NewMarketStore()
+	strategies := bot.NewStrategySelector(acfg)
+	live := config.NewLive(acfg, os.Getenv("GOGARIN_CONFIG"))
 
-	// Get nearest waypoint of type.
-	waypoints, err := sb.client.ListWaypoints(sb.ship.Nav.SystemSymbol)
+	journal, err := store.OpenJournal(acfg.JournalPath, monitor.ResetDate())
 	if err != nil {
-		sb.logger.Error("🚀 Error getting system.", "error", err)
-		sbCh <- *sb
-		return
+		return nil, fmt.Errorf("opening mission journal: %w", err)
 	}
 
-	filteredWaypoints := lib.Filter(*waypoints, func(waypoint m.Waypoint) bool {
-		return waypoint.Type == waypointType
-	})
+	metadata, err := store.OpenMetadataStore(acfg.MetadataPath)
+	if err != nil {
+		return nil, fmt.Errorf("opening ship metadata: %w", err)
+	}
+	board.SetMetadata(metadata)
 
-	currentWaypoint := lib.Filter(*waypoints, func(waypoint m.Waypoint) bool {
-		return waypoint.Symbol == sb.ship.Nav.WaypointSymbol
-	})[0]
+	shipyards, err := store.OpenShipyardStore(acfg.ShipyardPath, monitor.ResetDate(), acfg.Purchase.PriceTTL)
+	if err != nil {
+		return nil, fmt.Errorf("opening shipyard prices: %w", err)
+	}
 
-	nearestWaypoint, err := lib.NearestWaypoint(&currentWaypoint, &filteredWaypoints)
+	fuelCosts, err := store.OpenFuelCosts(acfg.FuelCostPath, monitor.ResetDate())
 	if err != nil {
-		sb.logger.Error("🚀 Error getting nearest waypoint.", "error", err)
-		sbCh <- *sb
-		return
+		return nil, fmt.Errorf("opening fuel costs: %w", err)
 	}
 
-	// Navigate to waypoint.
-	sb.logger.Infof("🚀 Navigating to nearest %s...", waypointType)
+	visits, err := store.OpenVisitLog(acfg.VisitPath, monitor.ResetDate())
+	if err != nil {
+		return nil, fmt.Errorf("opening visit log: %w", err)
+	}
 
-	res, err := sb.client.NavigateShip(sb.ship.Symbol, nearestWaypoint.Symbol)
+	state, err := store.OpenStateFile(acfg.StatePath)
 	if err != nil {
-		sb.logger.Error("🚀 Error navigating to waypoint.", "error", err)
-		sbCh <- *sb
-		return
+		return nil, fmt.Errorf("opening state file: %w", err)
 	}
+	a.state, a.journal = state, journal
 
-	sb.logger.Info("🚀 Navigation successful! Waiting until arrival...", "eta", res.Nav.Route.Arrival)
-	sb.ship.Fuel = res.Fuel
-	sb.ship.Nav = res.Nav
+	if acfg.TelemetryPath != "" {
+		tw, err := telemetry.Open(acfg.TelemetryPath, acfg.TelemetryMaxBytes)
+		if err != nil {
+			return nil, err
+		}
 
-	// Wait until arrival.
-	sb.WaitUntilArrival()
+		followed := make(chan error, 1)
+		go func() { followed <- tw.Follow(bus.Subscribe(256)) }()
 
-	// Send sb to sbCh.
-	sbCh <- *sb
-}
+		a.closers = append(a.closers, func() {
+			// Closing the bus ends Follow, so every received event is written before the file is synced.
+			bus.Close()
+			if err := <-followed; err != nil {
+				logging.New("📼 TELEMETRY").Error("Failed to write telemetry", "error", err)
+			}
+			if err := tw.Close(); err != nil {
+				logging.New("📼 TELEMETRY").Error("Failed to close telemetry", "error", err)
+			}
+		})
+	}
 
-// NavigateToNearestWaypointWithTrait: Navigate to nearest waypoint with trait.
-func (sb *ShipBot) NavigateToNearestWaypointWithTrait(trait string, sbCh chan ShipBot) {
-	sb.logger.Info("Navigating to nearest waypoint with trait...", "trait", trait)
+	if acfg.Notify.Enabled() {
+		go newNotifier(acfg.Notify).Follow(bus.Subscribe(64))
+	}
 
-	// Get nearest waypoint with trait.
-	waypoints, err := sb.client.ListWaypoints(sb.ship.Nav.SystemSymbol)
+	c, err = verifyToken(c, acfg, opts.autoRegister)
 	if err != nil {
-		sb.logger.Error("🚀 Error getting waypoints.", "error", err)
+		return nil, err
 	}
 
-	filteredWaypoints := lib.Filter(*waypoints, func(waypoint m.Waypoint) bool {
-		for _, waypointTrait := range waypoint.Traits {
-			if waypointTrait.Symbol == trait {
-				return true
-			}
+	if !opts.skipPreflight {
+		admin := interactive(c)
+		results, err := preflight(admin, preflightChecks(admin, acfg))
+		renderPreflight(os.Stderr, results)
+		if err != nil {
+			return nil, err
 		}
-
-		return false
-	})
-
-	currentWaypoint := lib.Filter(*waypoints, func(waypoint m.Waypoint) bool {
-		return waypoint.Symbol == sb.ship.Nav.WaypointSymbol
-	})[0]
-
-	nearestWaypoint, err := lib.NearestWaypoint(&currentWaypoint, &filteredWaypoints)
-	if err != nil {
-		sb.logger.Error("🚀 Error getting nearest waypoint.", "error", err)
-		sbCh <- *sb
-		return
 	}
 
-	// Navigate to waypoint.
-	sb.logger.Infof("🚀 Navigating to nearest waypoint with %s...", trait)
+	var usage func() []api.SubsystemUsage
+	if client, ok := c.(*api.Client); ok {
+		usage = client.Usage
+	}
+	a.usage = usage
+
+	seed := opts.seed
+	if seed == 0 {
+		seed = lib.NewSeed()
+	}
+	random := lib.NewRand(seed)
+
+	if opts.dryRun {
+		dryRun := api.NewDryRunClient(c, markets, logging.New("🧪 DRY_RUN"), opts.dryRunSpeed)
+		dryRun.SetRand(random)
+		c = dryRun
+	}
+
+	a.fleet = bot.New(c,
+		bot.WithLiveConfig(live),
+		bot.WithBus(bus),
+		bot.WithBoard(board),
+		bot.WithMarkets(markets),
+		bot.WithStrategies(strategies),
+		bot.WithJournal(journal),
+		bot.WithState(state),
+		bot.WithMetadata(metadata),
+		bot.WithShipyards(shipyards),
+		bot.WithFuelCosts(fuelCosts),
+		bot.WithVisits(visits),
+		bot.WithLedger(ldg),
+		bot.WithEarnings(earnings),
+		bot.WithRand(random),
+		bot.WithMonitor(monitor),
+		bot.WithFleetSignal(),
+		bot.WithReloadSignal(),
+		bot.WithPauseSignal(),
+	)
+	a.closers = append(a.closers, a.fleet.Stop)
+
+	if opts.httpAddr != "" {
+		serverOpts := []server.Option{server.WithStrategies(strategies), server.WithPauseControl(a.fleet), server.WithOrders(a.fleet), server.WithMarkets(markets)}
+		if usage != nil {
+			serverOpts = append(serverOpts, server.WithRequestUsage(usage))
+		}
+		if acfg.Metrics {
+			registry := metrics.NewRegistry()
+			go metrics.NewBot(registry).Follow(bus.Subscribe(256))
+			if usage != nil {
+				go metrics.NewBudget(registry, usage).Follow(15*time.Second, ctx.Done())
+			}
+			go metrics.NewEarnings(registry, earnings.Rates).Follow(15*time.Second, ctx.Done())
+			serverOpts = append(serverOpts, server.WithMetrics(registry.Handler()))
+		}
 
-	res, err := sb.client.NavigateShip(sb.ship.Symbol, nearestWaypoint.Symbol)
-	if err != nil {
-		sb.logger.Error("🚀 Error navigating to waypoint.", "error", err)
-		sbCh <- *sb
-		return
+		a.server = server.New(opts.httpAddr, board, ldg, func() map[string]int { return live.Get().FleetPlan }, serverOpts...)
 	}
 
-	sb.logger.Info("🚀 Navigation successful! Waiting until arrival...", "eta", res.Nav.Route.Arrival)
-	sb.ship.Fuel = res.Fuel
-	sb.ship.Nav = res.Nav
-
-	// Wait until arrival.
-	sb.WaitUntilArrival()
+	// Marked last, so an agent that fails to start leaves the previous run's mark as it was.
+	if err := state.MarkRunning(time.Now()); err != nil {
+		return nil, fmt.Errorf("writing state file: %w", err)
+	}
 
-	// Send sb to sbCh.
-	sbCh <- *sb
+	return a, nil
 }
 
-// NewShipBot creates a new instance of ShipBot.
-func NewShipBot(client *api.Client, ship *m.Ship, agent *m.Agent) *ShipBot {
-	return &ShipBot{
-		client: client,
-		logger: log.NewWithOptions(os.Stderr, log.Options{
-			ReportTimestamp: true,
-			Prefix:          fmt.Sprintf("🚀 %s", ship.Symbol),
-		}),
-		ship:  ship,
-		agent: agent,
-	}
+// statusServer is the HTTP status server of one agent, or of several.
+type statusServer interface {
+	ListenAndServe() error
+	Shutdown(ctx context.Context) error
 }
 
-// DockShip: Dock ship at waypoint.
-func (sb *ShipBot) DockShip(sbCh chan ShipBot) {
-	sb.logger.Info("Docking ship...")
-	nav, err := sb.client.DockShip(sb.ship.Symbol)
-	if err != nil {
-		sb.logger.Error("🚀 Error docking ship.", "error", err)
+// newStatusServer serves the status of a single agent at the root, or of several agents each under
+// /agents/NAME/.
+func newStatusServer(addr string, agents []*agent) statusServer {
+	if len(agents) == 1 && agents[0].name == "" {
+		return agents[0].server
 	}
 
-	sb.ship.Nav = *nav
+	servers := make(map[string]*server.Server, len(agents))
+	for _, a := range agents {
+		servers[a.name] = a.server
+	}
 
-	sbCh <- *sb
+	return server.NewAgents(addr, servers)
 }
 
-// WaitUntilArrival: Wait until ship arrives at its destination.
-func (sb *ShipBot) WaitUntilArrival() {
-	if sb.ship.Nav.Route.Arrival.Before(time.Now()) {
-		sb.logger.Info("Not in transit. Skipping wait.")
-		return
-	}
+// errServerReset is wrapped by every error the bot stops with because the server was reset.
+var errServerReset = errors.New("the server was reset")
 
-	sb.logger.Info("In transit. Waiting until arrival...", "arrival", sb.ship.Nav.Route.Arrival)
-	time.Sleep(time.Until(sb.ship.Nav.Route.Arrival))
-}
+// errReregistered stops the bot after a new agent was registered following a server reset.
+var errReregistered = fmt.Errorf("%w and a new agent was registered; restart gogarin to continue", errServerReset)
 
-// WaitUntilCooldown: Wait until ship's cooldown expires.
-func (sb *ShipBot) WaitUntilCooldown() {
-	sb.logger.Info("Waiting until cooldown expires...", "cooldown", sb.cooldown.Expiration)
-	if sb.cooldown.Expiration.Before(time.Now()) {
-		sb.logger.Info("⚛ Reactor ready. Skipping wait.")
-		return
+// handleReset is called when the server resets while the bot is running. It removes the state persisted
+// before the reset, re-registers when autoRegister is set and a callsign is configured, and returns the error
+// the bot stops with.
+func handleReset(previous string, current string, autoRegister bool) error {
+	if err := purgeStores(); err != nil {
+		logging.New("🩺 HEALTH").Error("Failed to remove state from before the reset.", "error", err)
 	}
 
-	sb.logger.Info("⚛ Reactor cooldown active. Waiting...", "cooldown", sb.cooldown.Expiration)
-	time.Sleep(time.Until(sb.cooldown.Expiration))
-}
-
-// IsFullOfCargo checks if the ship is full of cargo, returning a boolean.
-func (sb *ShipBot) IsFullOfCargo() bool {
-	return sb.ship.Cargo.Units >= sb.ship.Cargo.Capacity
-}
+	if !autoRegister || cfg.Symbol == "" {
+		return fmt.Errorf("%w on %s (previously %s) and your agent no longer exists; run `gogarin register --symbol SYMBOL --faction %s` and restart", errServerReset, current, previous, cfg.Faction)
+	}
 
-// IsAtWaypointOfType checks if the ship is at a waypoint of a given type, returning a boolean.
-func (sb *ShipBot) IsAtWaypointOfType(waypointType string) bool {
-	waypoint, err := sb.client.GetWaypoint(sb.ship.Nav.SystemSymbol, sb.ship.Nav.WaypointSymbol)
-	if err != nil {
-		sb.logger.Error("Error getting waypoint.", "error", err)
+	if _, err := registerAgent(cfg.Symbol, cfg.Faction); err != nil {
+		return fmt.Errorf("%w on %s and re-registering failed: %w", errServerReset, current, err)
 	}
 
-	return waypoint.Type == waypointType
+	return errReregistered
 }
 
-// IsAtWaypointWithTrait checks if the ship is at a waypoint with a given trait, returning a boolean.
-func (sb *ShipBot) IsAtWaypointWithTrait(traitSymbol string) bool {
-	waypoint, err := sb.client.GetWaypoint(sb.ship.Nav.SystemSymbol, sb.ship.Nav.WaypointSymbol)
-	if err != nil {
-		sb.logger.Error("Error getting waypoint.", "error", err)
+// persistedStores returns the files holding state that belongs to the current server reset, of the
+// configured agent or of each of the configured agents.
+func persistedStores() []string {
+	configs := []*config.Config{cfg}
+	if len(cfg.Agents) > 0 {
+		configs = configs[:0]
+		for _, ac := range cfg.Agents {
+			configs = append(configs, cfg.ForAgent(ac))
+		}
 	}
 
-	matchingTraits := lib.Filter(waypoint.Traits, func(t m.WaypointTrait) bool {
-		return t.Symbol == traitSymbol
-	})
-
-	return len(matchingTraits) > 0
-}
+	var paths []string
+	for _, acfg := range configs {
+		paths = append(paths, acfg.JournalPath, acfg.ShipyardPath, acfg.FuelCostPath, acfg.VisitPath, acfg.StatePath)
+	}
 
-// HasStatus checks if the ship has a given status, returning a boolean.
-func (sb *ShipBot) HasStatus(status string) bool {
-	return sb.ship.Nav.Status == status
+	return paths
 }
 
-func (sb *ShipBot) SellCargo(sbCh chan ShipBot) {
-	for {
-		if sb.ship.Cargo.Units > 0 {
-			for _, good := range sb.ship.Cargo.Inventory {
-				if lib.Contains(sb.priorities, good.Symbol) {
-					sb.logger.Info("💲 Selling priority cargo...", "type", good.Symbol, "units", good.Units)
-					res, err := sb.client.SellCargo(sb.ship.Symbol, good.Symbol, good.Units)
-					if err != nil {
-						sb.logger.Error("💲 Error selling cargo.", "error", err)
-						break
-					}
-
-					sb.logger.Info("💲 Cargo sold.", "type", res.Transaction.TradeSymbol, "units", res.Transaction.Units, "unitPrice", res.Transaction.PricePerUnit, "totalPrice", res.Transaction.TotalPrice)
-
-					sb.logger.Info("📦 Cargo status updated.", "cargoStatus", fmt.Sprintf("%d/%d", res.Cargo.Units, res.Cargo.Capacity))
-					sb.ship.Cargo = res.Cargo
-
-					sb.logger.Info("💰 Agent credits updated.", "credits", res.Agent.Credits)
-					sb.agent.Credits = res.Agent.Credits
-				} else {
-					sb.logger.Info("💲 Selling non-priority cargo...", "type", good.Symbol, "units", good.Units)
-					res, err := sb.client.SellCargo(sb.ship.Symbol, good.Symbol, good.Units)
-					if err != nil {
-						sb.logger.Error("💲 Error selling cargo. Returning to agent...", "error", err)
-						break
-					}
-
-					sb.logger.Info("💲 Cargo sold.", "type", res.Transaction.TradeSymbol, "units", res.Transaction.Units, "unitPrice", res.Transaction.PricePerUnit, "totalPrice", res.Transaction.TotalPrice)
-
-					sb.logger.Info("📦 Cargo status updated.", "cargoStatus", fmt.Sprintf("%d/%d", res.Cargo.Units, res.Cargo.Capacity))
-					sb.ship.Cargo = res.Cargo
-
-					sb.logger.Info("💰 Agent credits updated.", "credits", res.Agent.Credits)
-					sb.agent.Credits = res.Agent.Credits
-				}
-			}
-		} else {
-			sb.logger.Info("📦 Cargo empty. Reporting to agent...")
-			break
+// purgeStores removes the persisted stores, so no state from before a reset is loaded after it.
+// Stores that are not configured or were never written are skipped.
+func purgeStores() error {
+	var errs []error
+	for _, path := range persistedStores() {
+		if path == "" {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
 		}
 	}
 
-	sbCh <- *sb
+	return errors.Join(errs...)
 }
 
-func (sb *ShipBot) ExtractResources(sbCh chan ShipBot) {
-	for {
-		if !sb.IsFullOfCargo() {
-			sb.WaitUntilCooldown()
-
-			res, err := sb.client.ExtractResources(sb.ship.Symbol)
-			if err != nil {
-				sb.logger.Error(err)
-				sb.logger.Info("Mission failed. Reporting to agent...")
-				break
-			}
-			sb.logger.Info("⛏ Resources extracted.", "type", res.Extraction.Yield.Symbol, "units", res.Extraction.Yield.Units)
+// verifyToken checks that the server accepts acfg's token. If it does not, it explains why and either
+// re-registers (when autoRegister is set and a callsign is configured) or returns an actionable error.
+// Failures other than an invalid token are left for the fleet startup to report.
+func verifyToken(c api.ClientAPI, acfg *config.Config, autoRegister bool) (api.ClientAPI, error) {
+	_, err := c.GetMyAgent()
+	if err == nil || !api.IsUnauthorized(err) {
+		return c, nil
+	}
 
-			// Update cargo
-			sb.ship.Cargo = res.Cargo
-			sb.logger.Info("📦 Cargo status updated.", "cargoStatus", fmt.Sprintf("%d/%d", res.Cargo.Units, res.Cargo.Capacity))
+	l := logging.New("🔑 TOKEN")
+	diagnosis := diagnoseToken(c, acfg.Token)
 
-			// Update cooldown
-			sb.cooldown = &res.Cooldown
-		} else {
-			sb.logger.Info("📦 Cargo full. Reporting to agent...")
-			break
+	if !autoRegister || acfg.Symbol == "" {
+		symbol := acfg.Symbol
+		if symbol == "" {
+			symbol = "SYMBOL"
 		}
+
+		return nil, fmt.Errorf("%s; run `gogarin register --symbol %s --faction %s`, or set a symbol and pass --auto-register", diagnosis, symbol, acfg.Faction)
 	}
 
-	sbCh <- *sb
-}
+	l.Warn(diagnosis+". Re-registering...", "symbol", acfg.Symbol, "faction", acfg.Faction)
 
-func (sb *ShipBot) GetShipCooldown() (*m.Cooldown, error) {
-	cooldown, err := sb.client.GetShipCooldown(sb.ship.Symbol)
+	token, err := registerAgent(acfg.Symbol, acfg.Faction)
 	if err != nil {
 		return nil, err
 	}
+	l.Info("Registered. Token saved to .env.", "symbol", acfg.Symbol)
 
-	return cooldown, nil
+	return newClient(token), nil
 }
 
-// InitiateRequisitionProtocol sends a command ship to search for shipyard and purchase more ships.
-func (sb *ShipBot) InitiateRequisitionProtocol(wg *sync.WaitGroup) {
-	defer wg.Done()
-
-	sb.logger.Info("Initiating requisition protocol...")
-
-	// Find shipyards in current system
-	sb.logger.Info("🔎 Finding shipyards in current system...", "system", sb.ship.Nav.SystemSymbol)
-	waypoints, err := sb.FindWaypointsByTrait(sb.ship.Nav.SystemSymbol, "SHIPYARD")
+// diagnoseToken explains why the server rejected token, comparing its reset date with the server's.
+func diagnoseToken(c api.ClientAPI, token string) string {
+	claims, err := api.ParseToken(token)
 	if err != nil {
-		sb.logger.Error("🔎 Error finding shipyards in current system.", "error", err)
+		return "your token is not a valid SpaceTraders token"
 	}
 
-	if len(*waypoints) == 0 {
-		panic("no shipyards found. Not yet handled.")
+	status, err := c.GetStatus()
+	if err != nil {
+		return "your token was rejected and the server status is unavailable"
 	}
 
-	sb.logger.Info("🔎 Shipyards found.", "count", len(*waypoints))
-
-	for _, waypoint := range *waypoints {
-		// Travel to shipyard
-		sb.logger.Info("🚀 Traveling to shipyard...", "waypoint", waypoint.Symbol)
-		sb.NavigateShip(waypoint.Symbol)
+	if claims.IsStale(status.ResetDate) {
+		return fmt.Sprintf("your token is from the previous reset (%s); the server was reset on %s", claims.ResetDate, status.ResetDate)
 	}
+
+	return "your token was rejected by the server"
 }
 
-// NavigateShip sends a ship to a waypoint.
-func (sb *ShipBot) NavigateShip(waypointSymbol string) {
-	// Check if ship is already at waypoint
-	if sb.ship.Nav.WaypointSymbol == waypointSymbol && sb.ship.Nav.Route.Arrival.Before(time.Now()) {
-		sb.logger.Info("🚀 Already at waypoint. Navigation skipped.", "waypoint", waypointSymbol)
-		return
+// registerAgent registers a new agent and saves its token to .env, returning the token.
+func registerAgent(symbol string, faction string) (string, error) {
+	res, err := newClient("").Register(symbol, faction)
+	if err != nil {
+		return "", fmt.Errorf("registering %s: %w", symbol, err)
 	}
 
-	// Check if ship is already traveling to waypoint
-	if sb.ship.Nav.Route.Arrival.After(time.Now()) {
-		if sb.ship.Nav.Route.Destination.Symbol == waypointSymbol {
-			sb.logger.Info("🚀 Already traveling to waypoint. Navigation skipped.", "waypoint", waypointSymbol)
-			sb.WaitUntilArrival()
-			return
-		}
-
-		sb.WaitUntilArrival()
+	if err := persistToken(".env", res.Token); err != nil {
+		return "", fmt.Errorf("saving token: %w", err)
 	}
 
-	_, err := sb.client.NavigateShip(sb.ship.Symbol, waypointSymbol)
-	if err != nil {
-		sb.logger.Error("🚀 Error traveling to shipyard.", "error", err)
-	}
+	cfg.Token = res.Token
+
+	return res.Token, nil
 }
 
-func (sb *ShipBot) FindWaypointsByTrait(systemSymbol, trait string) (*[]m.Waypoint, error) {
-	waypoints, err := sb.client.ListWaypoints(systemSymbol)
-	if err != nil {
-		return nil, err
+// persistToken sets TOKEN in the dotenv file at path, keeping its other variables.
+func persistToken(path string, token string) error {
+	env, err := godotenv.Read(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		env = map[string]string{}
+	} else if err != nil {
+		return err
 	}
 
-	waypointsWithTrait := lib.Filter(*waypoints, func(w m.Waypoint) bool {
-		for _, t := range w.Traits {
-			if t.Symbol == trait {
-				return true
-			}
-		}
+	env["TOKEN"] = token
 
-		return false
-	})
+	return godotenv.Write(env, path)
+}
+
+// newNotifier creates a Notifier delivering to the sinks of n.
+func newNotifier(n config.NotifyConfig) *notify.Notifier {
+	var sinks []notify.Sink
+	if n.WebhookURL != "" {
+		sinks = append(sinks, notify.NewWebhookSink(n.WebhookURL))
+	}
+	if n.DiscordURL != "" {
+		sinks = append(sinks, notify.NewDiscordSink(n.DiscordURL))
+	}
 
-	return &waypointsWithTrait, nil
+	return notify.New(notify.Options{
+		Enabled:          n.Events,
+		CreditThresholds: n.CreditThresholds,
+		FailureThreshold: n.FailureThreshold,
+		RateLimit:        n.RateLimit,
+		RateWindow:       n.RateWindow,
+	}, logging.New("🔔 NOTIFIER"), sinks...)
 }
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/ledger"
+	"github.com/GeoffreyDick/gogarin/mockserver"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/report"
+	"github.com/GeoffreyDick/gogarin/store"
+	"github.com/joho/godotenv"
+)
+
+// tokenClient is a ClientAPI answering the calls made while verifying a token.
+type tokenClient struct {
+	api.ClientAPI
+	agentErr  error
+	status    *m.Status
+	statusErr error
+}
+
+func (c *tokenClient) GetMyAgent() (*m.Agent, error) {
+	if c.agentErr != nil {
+		return nil, c.agentErr
+	}
+
+	return &m.Agent{Symbol: "GOGARIN"}, nil
+}
+
+func (c *tokenClient) GetStatus() (*m.Status, error) {
+	return c.status, c.statusErr
+}
+
+var unauthorized = &api.APIError{StatusCode: 401, Code: 401, Message: "Token is invalid."}
+
+// jwt returns an unsigned token issued for the reset on resetDate.
+func jwt(resetDate string) string {
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"identifier":"GOGARIN","reset_date":"` + resetDate + `"}`))
+
+	return "header." + claims + ".signature"
+}
+
+func TestVerifyTokenPassesAcceptedTokens(t *testing.T) {
+	withConfig(t, jwt("2030-01-15"))
+
+	for _, c := range []*tokenClient{{}, {agentErr: errors.New("connection refused")}} {
+		got, err := verifyToken(c, cfg, false)
+		if err != nil || got != api.ClientAPI(c) {
+			t.Errorf("agent error %v: verifyToken = %v, %v; want the client unchanged", c.agentErr, got, err)
+		}
+	}
+}
+
+func TestVerifyTokenExplainsARejectedToken(t *testing.T) {
+	withConfig(t, jwt("2030-01-01"))
+	cfg.Symbol, cfg.Faction = "GOGARIN", "COSMIC"
+
+	_, err := verifyToken(&tokenClient{agentErr: unauthorized, status: &m.Status{ResetDate: "2030-01-15"}}, cfg, false)
+	if err == nil {
+		t.Fatal("a rejected token was accepted")
+	}
+	for _, want := range []string{"previous reset (2030-01-01)", "reset on 2030-01-15", "gogarin register --symbol GOGARIN --faction COSMIC"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %q, want it to mention %q", err, want)
+		}
+	}
+}
+
+func TestDiagnoseToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		client *tokenClient
+		want   string
+	}{
+		{"not a token", "not-a-jwt", &tokenClient{}, "not a valid SpaceTraders token"},
+		{"status unavailable", jwt("2030-01-01"), &tokenClient{statusErr: errors.New("timeout")}, "server status is unavailable"},
+		{"previous reset", jwt("2030-01-01"), &tokenClient{status: &m.Status{ResetDate: "2030-01-15"}}, "previous reset"},
+		{"current reset", jwt("2030-01-15"), &tokenClient{status: &m.Status{ResetDate: "2030-01-15"}}, "rejected by the server"},
+	}
+	for _, tt := range tests {
+		if got := diagnoseToken(tt.client, tt.token); !strings.Contains(got, tt.want) {
+			t.Errorf("%s: diagnosis %q, want it to mention %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPersistTokenKeepsOtherVariables(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("TOKEN=old\nGOGARIN_SYMBOL=GOGARIN\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := persistToken(path, "new"); err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := godotenv.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env["TOKEN"] != "new" || env["GOGARIN_SYMBOL"] != "GOGARIN" {
+		t.Errorf(".env = %v", env)
+	}
+
+	fresh := filepath.Join(t.TempDir(), ".env")
+	if err := persistToken(fresh, "new"); err != nil {
+		t.Fatalf("without a .env: %s", err)
+	}
+}
+
+// startMock boots a mock server from the default fixture with extra copies of its excavator,
+// returning the server and a client pointed at it.
+func startMock(t *testing.T, excavators int) (*mockserver.Server, *api.Client) {
+	t.Helper()
+
+	f, err := mockserver.DefaultFixture()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	excavator := f.Ships[1]
+	for i := 0; i < excavators; i++ {
+		ship := excavator
+		ship.Symbol = fmt.Sprintf("%s-%d", excavator.Symbol, i+1)
+		f.Ships = append(f.Ships, ship)
+	}
+
+	s := mockserver.New(f, mockserver.WithTimeScale(1000))
+	t.Cleanup(s.Close)
+
+	return s, api.NewClient("token", api.WithBaseURL(s.URL), api.WithRateLimit(1000))
+}
+
+func TestHandleResetExplainsHowToContinue(t *testing.T) {
+	withConfig(t, "token")
+	cfg.Faction = "COSMIC"
+
+	err := handleReset("2030-01-01", "2030-01-15", false)
+	if err == nil || !strings.Contains(err.Error(), "reset on 2030-01-15") || !strings.Contains(err.Error(), "gogarin register --symbol SYMBOL --faction COSMIC") {
+		t.Errorf("without auto-registration: err = %v", err)
+	}
+
+	if err := handleReset("2030-01-01", "2030-01-15", true); err == nil || errors.Is(err, errReregistered) {
+		t.Errorf("auto-registering without a symbol: err = %v, want the register hint", err)
+	}
+
+	// The mock server does not implement registration, so re-registering fails.
+	f, err := mockserver.DefaultFixture()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := mockserver.New(f)
+	t.Cleanup(s.Close)
+	cfg.BaseURL = s.URL
+	cfg.Symbol = "GOGARIN"
+	if err := handleReset("2030-01-01", "2030-01-15", true); err == nil || !strings.Contains(err.Error(), "re-registering failed") {
+		t.Errorf("failed re-registration: err = %v", err)
+	}
+	if s.Requests("POST", "/register") != 1 {
+		t.Error("did not try to re-register")
+	}
+}
+
+func TestHandleResetPurgesPersistedStores(t *testing.T) {
+	withConfig(t, "token")
+	dir := t.TempDir()
+
+	cfg.JournalPath = filepath.Join(dir, "journal.json")
+	j, err := store.OpenJournal(cfg.JournalPath, "2030-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Begin(store.JournalEntry{Ship: "GOGARIN-1", Kind: "deliver", ContractID: "c-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.ShipyardPath = filepath.Join(dir, "shipyards.json")
+	shipyards, err := store.OpenShipyardStore(cfg.ShipyardPath, "2030-01-01", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shipyard := m.Shipyard{Symbol: "X1-MK1-A1", Ships: []m.ShipyardShip{{Type: "SHIP_MINING_DRONE", PurchasePrice: 80000}}}
+	if err := shipyards.Record(shipyard, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.FuelCostPath = filepath.Join(dir, "fuel.json")
+	fuelCosts, err := store.OpenFuelCosts(cfg.FuelCostPath, "2030-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fuelCosts.Record("X1-MK1-A1", "X1-MK1-B2", "CRUISE", 12); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.VisitPath = filepath.Join(dir, "visits.json")
+	visits, err := store.OpenVisitLog(cfg.VisitPath, "2030-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := visits.Record("X1-MK1-A1", time.Now(), map[string]string{"market": store.OutcomeDone}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.StatePath = filepath.Join(dir, "state.json")
+	state, err := store.OpenStateFile(cfg.StatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := state.MarkRunning(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := handleReset("2030-01-01", "2030-01-15", false); err == nil {
+		t.Fatal("reset without auto-registration did not stop the bot")
+	}
+	for name, path := range map[string]string{
+		"journal":         cfg.JournalPath,
+		"shipyard prices": cfg.ShipyardPath,
+		"fuel costs":      cfg.FuelCostPath,
+		"visit log":       cfg.VisitPath,
+		"state":           cfg.StatePath,
+	} {
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s after the reset: stat err = %v, want them removed", name, err)
+		}
+	}
+
+	// A purge with nothing to remove succeeds.
+	if err := purgeStores(); err != nil {
+		t.Errorf("purging again: %s", err)
+	}
+
+	// With several agents, each agent's own stores are purged.
+	cfg.Agents = []config.AgentConfig{{Name: "one"}, {Name: "two"}}
+	var want []string
+	for _, agent := range []string{"one", "two"} {
+		for _, name := range []string{"journal", "shipyards", "fuel", "visits", "state"} {
+			want = append(want, filepath.Join(dir, name+"."+agent+".json"))
+		}
+	}
+	if got := persistedStores(); !reflect.DeepEqual(got, want) {
+		t.Errorf("stores of several agents = %v, want %v", got, want)
+	}
+}
+
+func TestResetExitLeavesNoCleanSnapshot(t *testing.T) {
+	withConfig(t, "token")
+	dir := t.TempDir()
+
+	// runningAgent returns an agent whose run has marked the state file at path running, with a mission underway.
+	runningAgent := func(path string) *agent {
+		t.Helper()
+
+		journal, err := store.OpenJournal("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := journal.Begin(store.JournalEntry{Ship: "GOGARIN-1", Kind: "deliver", ContractID: "c-1"}); err != nil {
+			t.Fatal(err)
+		}
+		state, err := store.OpenStateFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := state.MarkRunning(time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		return &agent{session: report.NewSession(time.Now()), ledger: ledger.New(10), journal: journal, state: state}
+	}
+
+	cfg.StatePath = filepath.Join(dir, "state.json")
+	a := runningAgent(cfg.StatePath)
+	shutDown([]*agent{a}, handleReset("2030-01-01", "2030-01-15", false), nil)
+
+	if _, err := os.Stat(cfg.StatePath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("state file after a reset exit: stat err = %v, want it left purged", err)
+	}
+	reopened, err := store.OpenStateFile(cfg.StatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Clean() {
+		t.Error("the run after a reset trusts the journal from before it")
+	}
+
+	// An orderly stop is still marked clean.
+	stopped := filepath.Join(dir, "stopped.json")
+	shutDown([]*agent{runningAgent(stopped)}, nil, nil)
+	if reopened, err := store.OpenStateFile(stopped); err != nil || !reopened.Clean() {
+		t.Errorf("state after an orderly stop: clean = %t, err = %v; want clean", reopened.Clean(), err)
+	}
+}
+
+// agentServer starts a mock server for an agent whose ships are named after it, accepting only token.
+func agentServer(t *testing.T, symbol string, token string) *mockserver.Server {
+	t.Helper()
+
+	f, err := mockserver.DefaultFixture()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Agent.Symbol = symbol
+	for i := range f.Ships {
+		f.Ships[i].Symbol = strings.Replace(f.Ships[i].Symbol, "MOCK", symbol, 1)
+		f.Ships[i].Registration.Name = f.Ships[i].Symbol
+	}
+
+	server := mockserver.New(f, mockserver.WithToken(token), mockserver.WithTimeScale(100))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestAgentsRunSideBySide(t *testing.T) {
+	// One base URL serves both agents, as the real API does, each from a universe of its own.
+	servers := map[string]*mockserver.Server{
+		"Bearer token-one": agentServer(t, "ONE", "token-one"),
+		"Bearer token-two": agentServer(t, "TWO", "token-two"),
+	}
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server, ok := servers[r.Header.Get("Authorization")]
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"message":"Invalid token.","code":401}}`))
+			return
+		}
+
+		target, _ := url.Parse(server.URL)
+		httputil.NewSingleHostReverseProxy(target).ServeHTTP(w, r)
+	}))
+	t.Cleanup(gateway.Close)
+
+	withConfig(t, "")
+	dir := t.TempDir()
+	cfg.BaseURL = gateway.URL
+	cfg.RateLimit = 1000
+	for _, path := range []*string{&cfg.JournalPath, &cfg.MetadataPath, &cfg.ShipyardPath, &cfg.FuelCostPath, &cfg.VisitPath, &cfg.StatePath} {
+		*path = filepath.Join(dir, filepath.Base(*path))
+	}
+	cfg.Agents = []config.AgentConfig{
+		{Name: "one", Token: "token-one"},
+		// The third agent's token is rejected, which must not stop the other two.
+		{Name: "broken", Token: "token-expired"},
+		{Name: "two", Token: "token-two"},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	agents, err := startAgents(ctx, nil, runOptions{skipPreflight: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, a := range agents {
+			a.close()
+		}
+	}()
+
+	var names []string
+	for _, a := range agents {
+		names = append(names, a.name)
+	}
+	if strings.Join(names, ",") != "one,two" {
+		t.Fatalf("agents %v started, want one and two", names)
+	}
+
+	for _, a := range agents {
+		if err := a.fleet.Start(ctx); err != nil {
+			t.Fatalf("starting agent %s: %s", a.name, err)
+		}
+	}
+
+	for _, a := range agents {
+		prefix := strings.ToUpper(a.name) + "-"
+
+		deadline := time.Now().Add(10 * time.Second)
+		for len(a.board.Snapshot().Ships) < 2 {
+			if time.Now().After(deadline) {
+				t.Fatalf("agent %s never listed its ships", a.name)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		for _, ship := range a.board.Snapshot().Ships {
+			if !strings.HasPrefix(ship.Ship.Symbol, prefix) {
+				t.Errorf("agent %s lists ship %s of another agent", a.name, ship.Ship.Symbol)
+			}
+		}
+	}
+
+	// Each agent records the shipyard prices its command ship surveys in a file of its own.
+	for _, name := range []string{"one", "two"} {
+		path := filepath.Join(dir, "gogarin.shipyards."+name+".json")
+
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			if _, err := os.Stat(path); err == nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("agent %s has no shipyard file of its own", name)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "gogarin.shipyards.broken.json")); err == nil {
+		t.Error("the agent that failed to start recorded shipyard prices")
+	}
+}
@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+// Bot metric names.
+const (
+	Credits          = "gogarin_credits"
+	FleetSize        = "gogarin_fleet_size"
+	ExtractedUnits   = "gogarin_extracted_units_total"
+	SoldUnits        = "gogarin_sold_units_total"
+	SaleCredits      = "gogarin_sale_credits_total"
+	DeliveredUnits   = "gogarin_delivered_units_total"
+	MissionsActive   = "gogarin_missions_active"
+	MissionsFinished = "gogarin_missions_finished_total"
+	MissionDuration  = "gogarin_mission_duration_seconds"
+	ShipIdleSeconds  = "gogarin_ship_idle_seconds_total"
+	ContractUnits    = "gogarin_contract_units"
+	CreditDrifts     = "gogarin_credit_drifts_total"
+)
+
+// idleMission is the mission a ShipBot runs while it has nothing to do.
+const idleMission = "Idle"
+
+// startedMission is a mission a ship is currently running.
+type startedMission struct {
+	mission string
+	at      time.Time
+}
+
+/*
+🤖 Bot
+*/
+
+// Bot updates bot-level metrics from the event bus.
+type Bot struct {
+	r        *Registry
+	ships    map[string]bool
+	missions map[string]startedMission
+}
+
+// NewBot registers the bot metrics on r.
+func NewBot(r *Registry) *Bot {
+	r.Gauge(Credits, "Agent credits.")
+	r.Gauge(FleetSize, "Number of ships that have reported in.")
+	r.Counter(ExtractedUnits, "Units extracted, by ship and trade symbol.")
+	r.Counter(SoldUnits, "Units sold, by ship and trade symbol.")
+	r.Counter(SaleCredits, "Credits earned from sales, by ship and trade symbol.")
+	r.Counter(DeliveredUnits, "Units delivered to contracts, by ship and trade symbol.")
+	r.Gauge(MissionsActive, "Missions in progress, by mission.")
+	r.Counter(MissionsFinished, "Missions finished, by mission and result.")
+	r.Histogram(MissionDuration, "Mission duration in seconds, by mission.", DefaultBuckets)
+	r.Counter(ShipIdleSeconds, "Seconds spent idling, by ship.")
+	r.Gauge(ContractUnits, "Contract units required and fulfilled, by contract and trade symbol.")
+	r.Counter(CreditDrifts, "Audits that found the tracked credits drifted from the agent's balance.")
+
+	return &Bot{
+		r:        r,
+		ships:    map[string]bool{},
+		missions: map[string]startedMission{},
+	}
+}
+
+// Follow updates metrics from events until the channel is closed.
+func (b *Bot) Follow(events <-chan event.Event) {
+	for e := range events {
+		b.Apply(e)
+	}
+}
+
+// Apply updates metrics from a single event.
+func (b *Bot) Apply(e event.Event) {
+	switch e.Type {
+	case event.AgentUpdated:
+		if agent, ok := e.Data.(m.Agent); ok {
+			b.r.Set(Credits, float64(agent.Credits))
+		}
+	case event.ShipReported:
+		b.ships[e.Ship] = true
+		b.r.Set(FleetSize, float64(len(b.ships)))
+	case event.ResourcesExtracted:
+		if extraction, ok := e.Data.(m.Extraction); ok {
+			b.r.Add(ExtractedUnits, float64(extraction.Yield.Units), "ship", e.Ship, "symbol", extraction.Yield.Symbol)
+		}
+	case event.CargoSold:
+		if transaction, ok := e.Data.(m.MarketTransaction); ok {
+			b.r.Add(SoldUnits, float64(transaction.Units), "ship", e.Ship, "symbol", transaction.TradeSymbol)
+			b.r.Add(SaleCredits, float64(transaction.TotalPrice), "ship", e.Ship, "symbol", transaction.TradeSymbol)
+		}
+	case event.CargoDelivered:
+		if item, ok := e.Data.(m.ShipCargoItem); ok {
+			b.r.Add(DeliveredUnits, float64(item.Units), "ship", e.Ship, "symbol", item.Symbol)
+		}
+	case event.ContractsUpdated:
+		if contracts, ok := e.Data.([]m.Contract); ok {
+			for _, contract := range contracts {
+				for _, good := range contract.Terms.Deliver {
+					b.r.Set(ContractUnits, float64(good.UnitsRequired), "contract", contract.ID, "symbol", good.TradeSymbol, "kind", "required")
+					b.r.Set(ContractUnits, float64(good.UnitsFulfilled), "contract", contract.ID, "symbol", good.TradeSymbol, "kind", "fulfilled")
+				}
+			}
+		}
+	case event.CreditsDrifted:
+		b.r.Add(CreditDrifts, 1)
+	case event.MissionStarted:
+		// A ship only runs one mission at a time, so a new one ends any that was not reported.
+		b.finish(e.Ship, e.At, "abandoned")
+		b.missions[e.Ship] = startedMission{mission: e.Mission, at: e.At}
+		b.r.Add(MissionsActive, 1, "mission", e.Mission)
+	case event.MissionCompleted:
+		b.finish(e.Ship, e.At, "completed")
+	case event.MissionFailed:
+		b.finish(e.Ship, e.At, "failed")
+	}
+}
+
+// finish records the end of a ship's current mission, if it has one.
+func (b *Bot) finish(ship string, at time.Time, result string) {
+	started, ok := b.missions[ship]
+	if !ok {
+		return
+	}
+	delete(b.missions, ship)
+
+	seconds := at.Sub(started.at).Seconds()
+
+	b.r.Add(MissionsActive, -1, "mission", started.mission)
+	b.r.Add(MissionsFinished, 1, "mission", started.mission, "result", result)
+	b.r.Observe(MissionDuration, seconds, "mission", started.mission)
+
+	if started.mission == idleMission {
+		b.r.Add(ShipIdleSeconds, seconds, "ship", ship)
+	}
+}
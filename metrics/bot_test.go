@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+var at = time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestBotCountsWork(t *testing.T) {
+	r := NewRegistry()
+	b := NewBot(r)
+
+	var extraction m.Extraction
+	extraction.Yield.Symbol = "IRON_ORE"
+	extraction.Yield.Units = 7
+
+	b.Apply(event.Event{Type: event.AgentUpdated, Data: m.Agent{Credits: 175000}})
+	b.Apply(event.Event{Type: event.ShipReported, Ship: "GOGARIN-1"})
+	b.Apply(event.Event{Type: event.ShipReported, Ship: "GOGARIN-2"})
+	b.Apply(event.Event{Type: event.ShipReported, Ship: "GOGARIN-2"})
+	b.Apply(event.Event{Type: event.ResourcesExtracted, Ship: "GOGARIN-2", Data: extraction})
+	b.Apply(event.Event{Type: event.CargoSold, Ship: "GOGARIN-2", Data: m.MarketTransaction{TradeSymbol: "IRON_ORE", Units: 7, TotalPrice: 315}})
+	b.Apply(event.Event{Type: event.CargoDelivered, Ship: "GOGARIN-2", Data: m.ShipCargoItem{Symbol: "COPPER_ORE", Units: 20}})
+
+	tests := []struct {
+		name   string
+		labels []string
+		want   float64
+	}{
+		{Credits, nil, 175000},
+		{FleetSize, nil, 2},
+		{ExtractedUnits, []string{"ship", "GOGARIN-2", "symbol", "IRON_ORE"}, 7},
+		{SoldUnits, []string{"ship", "GOGARIN-2", "symbol", "IRON_ORE"}, 7},
+		{SaleCredits, []string{"ship", "GOGARIN-2", "symbol", "IRON_ORE"}, 315},
+		{DeliveredUnits, []string{"ship", "GOGARIN-2", "symbol", "COPPER_ORE"}, 20},
+	}
+	for _, tt := range tests {
+		if got := r.Value(tt.name, tt.labels...); got != tt.want {
+			t.Errorf("%s%v = %v, want %v", tt.name, tt.labels, got, tt.want)
+		}
+	}
+}
+
+func TestBotTracksContractUnits(t *testing.T) {
+	r := NewRegistry()
+	b := NewBot(r)
+
+	var contract m.Contract
+	contract.ID = "c-1"
+	contract.Terms.Deliver = []m.ContractDeliverGood{{TradeSymbol: "IRON_ORE", UnitsRequired: 72, UnitsFulfilled: 30}}
+	b.Apply(event.Event{Type: event.ContractsUpdated, Data: []m.Contract{contract}})
+
+	if got := r.Value(ContractUnits, "contract", "c-1", "symbol", "IRON_ORE", "kind", "required"); got != 72 {
+		t.Errorf("required = %v, want 72", got)
+	}
+	if got := r.Value(ContractUnits, "contract", "c-1", "symbol", "IRON_ORE", "kind", "fulfilled"); got != 30 {
+		t.Errorf("fulfilled = %v, want 30", got)
+	}
+}
+
+func TestBotTimesMissions(t *testing.T) {
+	r := NewRegistry()
+	b := NewBot(r)
+
+	start := func(ship, mission string, offset time.Duration) {
+		b.Apply(event.Event{Type: event.MissionStarted, Ship: ship, Mission: mission, At: at.Add(offset)})
+	}
+
+	start("GOGARIN-1", "Idle", 0)
+	b.Apply(event.Event{Type: event.MissionCompleted, Ship: "GOGARIN-1", At: at.Add(60 * time.Second)})
+	start("GOGARIN-2", "mine", 0)
+	start("GOGARIN-2", "sell", 10*time.Second) // abandons mine
+	b.Apply(event.Event{Type: event.MissionFailed, Ship: "GOGARIN-2", At: at.Add(40 * time.Second)})
+	b.Apply(event.Event{Type: event.MissionCompleted, Ship: "GOGARIN-3", At: at}) // never started
+
+	tests := []struct {
+		name   string
+		labels []string
+		want   float64
+	}{
+		{MissionsActive, []string{"mission", "Idle"}, 0},
+		{MissionsActive, []string{"mission", "mine"}, 0},
+		{MissionsActive, []string{"mission", "sell"}, 0},
+		{MissionsFinished, []string{"mission", "Idle", "result", "completed"}, 1},
+		{MissionsFinished, []string{"mission", "mine", "result", "abandoned"}, 1},
+		{MissionsFinished, []string{"mission", "sell", "result", "failed"}, 1},
+		{MissionDuration, []string{"mission", "sell"}, 30},
+		{ShipIdleSeconds, []string{"ship", "GOGARIN-1"}, 60},
+	}
+	for _, tt := range tests {
+		if got := r.Value(tt.name, tt.labels...); got != tt.want {
+			t.Errorf("%s%v = %v, want %v", tt.name, tt.labels, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+)
+
+// Request budget metric names.
+const (
+	APIRequests      = "gogarin_api_requests"
+	APIRequestShare  = "gogarin_api_request_share"
+	APIRequestCap    = "gogarin_api_request_cap"
+	APIRequestsTotal = "gogarin_api_requests_total"
+)
+
+/*
+🪣 Budget
+*/
+
+// Budget copies the API request budget usage of each subsystem into a Registry.
+type Budget struct {
+	r     *Registry
+	usage func() []api.SubsystemUsage
+}
+
+// NewBudget registers the request budget metrics on r, read from usage.
+func NewBudget(r *Registry, usage func() []api.SubsystemUsage) *Budget {
+	r.Gauge(APIRequests, "API requests within the budget window, by subsystem.")
+	r.Gauge(APIRequestShare, "Share of the request budget used within the budget window, by subsystem.")
+	r.Gauge(APIRequestCap, "Share of the request budget a subsystem is capped at, by subsystem.")
+	r.Counter(APIRequestsTotal, "API requests sent, by subsystem.")
+
+	return &Budget{r: r, usage: usage}
+}
+
+// Sample copies the current usage into the Registry.
+func (b *Budget) Sample() {
+	for _, u := range b.usage() {
+		b.r.Set(APIRequests, float64(u.Requests), "subsystem", u.Subsystem)
+		b.r.Set(APIRequestShare, u.Share, "subsystem", u.Subsystem)
+		b.r.Set(APIRequestCap, u.Cap, "subsystem", u.Subsystem)
+		b.r.Set(APIRequestsTotal, float64(u.Total), "subsystem", u.Subsystem)
+	}
+}
+
+// Follow samples the usage every interval until done is closed.
+func (b *Budget) Follow(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		b.Sample()
+
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+)
+
+func TestBudgetCopiesUsage(t *testing.T) {
+	r := NewRegistry()
+	usage := []api.SubsystemUsage{
+		{Subsystem: api.SubsystemMarketPoll, Requests: 600, Share: 0.1, Cap: 0.2, Total: 900},
+		{Subsystem: api.SubsystemMissions, Requests: 30, Share: 0.005, Total: 45},
+	}
+	b := NewBudget(r, func() []api.SubsystemUsage { return usage })
+
+	done := make(chan struct{})
+	close(done)
+	b.Follow(time.Hour, done)
+
+	tests := []struct {
+		name      string
+		subsystem string
+		want      float64
+	}{
+		{APIRequests, api.SubsystemMarketPoll, 600},
+		{APIRequestShare, api.SubsystemMarketPoll, 0.1},
+		{APIRequestCap, api.SubsystemMarketPoll, 0.2},
+		{APIRequestsTotal, api.SubsystemMarketPoll, 900},
+		{APIRequests, api.SubsystemMissions, 30},
+		{APIRequestCap, api.SubsystemMissions, 0},
+	}
+	for _, tt := range tests {
+		if got := r.Value(tt.name, "subsystem", tt.subsystem); got != tt.want {
+			t.Errorf("%s{subsystem=%s} = %v, want %v", tt.name, tt.subsystem, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/ledger"
+)
+
+// Earnings metric names.
+const (
+	ShipCreditsPerHour     = "gogarin_ship_credits_per_hour"
+	StrategyCreditsPerHour = "gogarin_strategy_credits_per_hour"
+)
+
+/*
+⏱️ Earnings
+*/
+
+// Earnings copies what each ship and strategy earns per hour into a Registry.
+type Earnings struct {
+	r     *Registry
+	rates func(now time.Time) ledger.Rates
+}
+
+// NewEarnings registers the earnings metrics on r, read from rates.
+func NewEarnings(r *Registry, rates func(now time.Time) ledger.Rates) *Earnings {
+	r.Gauge(ShipCreditsPerHour, "Credits earned per hour of active time within the earnings window, by ship and strategy.")
+	r.Gauge(StrategyCreditsPerHour, "Credits earned per hour of active time within the earnings window, by strategy.")
+
+	return &Earnings{r: r, rates: rates}
+}
+
+// Sample copies the current rates into the Registry.
+func (e *Earnings) Sample() {
+	rates := e.rates(time.Now())
+	for _, rate := range rates.Ships {
+		e.r.Set(ShipCreditsPerHour, rate.PerHour, "ship", rate.Ship, "strategy", rate.Strategy)
+	}
+	for _, rate := range rates.Strategies {
+		e.r.Set(StrategyCreditsPerHour, rate.PerHour, "strategy", rate.Strategy)
+	}
+}
+
+// Follow samples the rates every interval until done is closed.
+func (e *Earnings) Follow(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		e.Sample()
+
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+	}
+}
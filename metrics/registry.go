@@ -0,0 +1,231 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metric types, as named in the Prometheus text exposition format.
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// DefaultBuckets are histogram bucket upper bounds in seconds, spanning a quick dock to a long transit.
+var DefaultBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600}
+
+// family is a named metric and its labeled series.
+type family struct {
+	name    string
+	help    string
+	typ     string
+	buckets []float64
+	series  map[string]*series
+}
+
+// series is a single labeled time series.
+type series struct {
+	labels string
+	value  float64
+	counts []uint64
+	count  uint64
+}
+
+/*
+📈 Registry
+*/
+
+// Registry holds metrics and renders them in the Prometheus text exposition format.
+// A nil Registry discards every update, so instrumentation costs nothing when metrics are disabled.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]*family{}}
+}
+
+// Counter registers a counter.
+func (r *Registry) Counter(name string, help string) {
+	r.register(name, help, typeCounter, nil)
+}
+
+// Gauge registers a gauge.
+func (r *Registry) Gauge(name string, help string) {
+	r.register(name, help, typeGauge, nil)
+}
+
+// Histogram registers a histogram with the given bucket upper bounds.
+func (r *Registry) Histogram(name string, help string, buckets []float64) {
+	r.register(name, help, typeHistogram, buckets)
+}
+
+func (r *Registry) register(name string, help string, typ string, buckets []float64) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.families[name] = &family{name: name, help: help, typ: typ, buckets: buckets, series: map[string]*series{}}
+}
+
+// Set sets a gauge. Labels are alternating names and values.
+func (r *Registry) Set(name string, value float64, labels ...string) {
+	r.update(name, labels, func(f *family, s *series) {
+		s.value = value
+	})
+}
+
+// Add adds delta to a counter or gauge. Labels are alternating names and values.
+func (r *Registry) Add(name string, delta float64, labels ...string) {
+	r.update(name, labels, func(f *family, s *series) {
+		s.value += delta
+	})
+}
+
+// Observe records a histogram sample. Labels are alternating names and values.
+func (r *Registry) Observe(name string, value float64, labels ...string) {
+	r.update(name, labels, func(f *family, s *series) {
+		if s.counts == nil {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+
+		for i, bound := range f.buckets {
+			if value <= bound {
+				s.counts[i]++
+			}
+		}
+		s.count++
+		s.value += value
+	})
+}
+
+// Value returns the current value of a counter or gauge series, or a histogram's sum.
+func (r *Registry) Value(name string, labels ...string) float64 {
+	var value float64
+	r.update(name, labels, func(f *family, s *series) {
+		value = s.value
+	})
+
+	return value
+}
+
+// update applies fn to the series of a registered metric, creating it if needed. Unregistered metrics are ignored.
+func (r *Registry) update(name string, labels []string, fn func(f *family, s *series)) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.families[name]
+	if !ok {
+		return
+	}
+
+	key := formatLabels(labels)
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: key}
+		f.series[key] = s
+	}
+
+	fn(f, s)
+}
+
+// formatLabels renders alternating label names and values as {name="value",...}.
+func formatLabels(labels []string) string {
+	if len(labels) < 2 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+"="+strconv.Quote(labels[i+1]))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// withLabel adds a label to a rendered label set.
+func withLabel(labels string, name string, value string) string {
+	pair := name + "=" + strconv.Quote(value)
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+// WriteTo writes every metric in the Prometheus text exposition format, sorted by name and labels.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		f := r.families[name]
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.typ)
+
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			s := f.series[key]
+
+			if f.typ != typeHistogram {
+				fmt.Fprintf(&b, "%s%s %s\n", f.name, key, formatValue(s.value))
+				continue
+			}
+
+			for i, bound := range f.buckets {
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", f.name, withLabel(key, "le", formatValue(bound)), s.counts[i])
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", f.name, withLabel(key, "le", "+Inf"), s.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", f.name, key, formatValue(s.value))
+			fmt.Fprintf(&b, "%s_count%s %d\n", f.name, key, s.count)
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+
+	return int64(n), err
+}
+
+// formatValue renders a sample value.
+func formatValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Handler serves the Registry for scraping.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		r.WriteTo(w)
+	})
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func render(r *Registry) string {
+	var b strings.Builder
+	r.WriteTo(&b)
+
+	return b.String()
+}
+
+func TestWriteToRendersTheTextFormat(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("test_sold_total", "Units sold.")
+	r.Gauge("test_credits", "Credits.")
+
+	r.Add("test_sold_total", 10, "ship", "GOGARIN-2", "symbol", "IRON_ORE")
+	r.Add("test_sold_total", 5, "ship", "GOGARIN-2", "symbol", "IRON_ORE")
+	r.Add("test_sold_total", 3, "ship", "GOGARIN-1", "symbol", "QUARTZ_SAND")
+	r.Set("test_credits", 175000)
+	r.Set("test_credits", 1.5e6)
+	r.Add("test_unregistered", 1)
+
+	want := `# HELP test_credits Credits.
+# TYPE test_credits gauge
+test_credits 1.5e+06
+# HELP test_sold_total Units sold.
+# TYPE test_sold_total counter
+test_sold_total{ship="GOGARIN-1",symbol="QUARTZ_SAND"} 3
+test_sold_total{ship="GOGARIN-2",symbol="IRON_ORE"} 15
+`
+	if got := render(r); got != want {
+		t.Errorf("rendered:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	r.Histogram("test_duration_seconds", "Duration.", []float64{1, 10})
+
+	for _, v := range []float64{0.5, 1, 4, 30} {
+		r.Observe("test_duration_seconds", v, "mission", "mine")
+	}
+
+	want := `# HELP test_duration_seconds Duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{mission="mine",le="1"} 2
+test_duration_seconds_bucket{mission="mine",le="10"} 3
+test_duration_seconds_bucket{mission="mine",le="+Inf"} 4
+test_duration_seconds_sum{mission="mine"} 35.5
+test_duration_seconds_count{mission="mine"} 4
+`
+	if got := render(r); got != want {
+		t.Errorf("rendered:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLabelValuesAreQuoted(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("test_total", "Test.")
+	r.Add("test_total", 1, "mission", `say "hi"`)
+
+	if got := render(r); !strings.Contains(got, `test_total{mission="say \"hi\""} 1`) {
+		t.Errorf("rendered:\n%s", got)
+	}
+}
+
+func TestNilRegistryDiscardsUpdates(t *testing.T) {
+	var r *Registry
+	r.Counter("test_total", "Test.")
+	r.Add("test_total", 1)
+
+	if got := r.Value("test_total"); got != 0 {
+		t.Errorf("Value = %v, want 0", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.Gauge("test_credits", "Credits.")
+	r.Set("test_credits", 42)
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4" {
+		t.Errorf("Content-Type %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "test_credits 42\n") {
+		t.Errorf("body:\n%s", rec.Body.String())
+	}
+}
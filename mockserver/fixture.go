@@ -0,0 +1,51 @@
+package mockserver
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"io"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+// Fixture describes the universe a Server starts from.
+type Fixture struct {
+	Status    m.Status     `json:"status"`
+	Agent     m.Agent      `json:"agent"`
+	Contracts []m.Contract `json:"contracts"`
+	Ships     []m.Ship     `json:"ships"`
+	Waypoints []m.Waypoint `json:"waypoints"`
+	Markets   []m.Market   `json:"markets"`
+	// Shipyards list ships and prices only to agents with a ship at the waypoint.
+	Shipyards []m.Shipyard `json:"shipyards"`
+	// Yields are the extraction results at each waypoint, returned in order and then repeated.
+	Yields map[string][]Yield `json:"yields"`
+	// CooldownSeconds is the reactor cooldown after an extraction, in simulated seconds.
+	CooldownSeconds int `json:"cooldownSeconds"`
+}
+
+// Yield is a single extraction result.
+type Yield struct {
+	Symbol string `json:"symbol"`
+	Units  int    `json:"units"`
+}
+
+//go:embed fixtures/universe.json
+var universe []byte
+
+// DefaultFixture returns a small single-system universe with a command ship, an excavator,
+// an asteroid field, two marketplaces, and an offered IRON_ORE contract.
+func DefaultFixture() (*Fixture, error) {
+	return LoadFixture(bytes.NewReader(universe))
+}
+
+// LoadFixture decodes a Fixture from JSON.
+func LoadFixture(r io.Reader) (*Fixture, error) {
+	var f Fixture
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
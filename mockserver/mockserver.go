@@ -0,0 +1,929 @@
+package mockserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/clock"
+	"github.com/GeoffreyDick/gogarin/lib"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+// Error codes returned by the Server, matching the SpaceTraders API.
+const (
+	codeCooldown     = 4000
+	codeInTransit    = 4214
+	codeNotInOrbit   = 4236
+	codeNotDocked    = 4244
+	codeNoMarket     = 4602
+	codeNotAvailable = 4603
+	codeCharted      = 4230
+	codeCargoMissing = 4218
+	codeCargoUnits   = 4219
+	codeIncomplete   = 4502
+	codeFulfilled    = 4504
+	codeNotAccepted  = 4505
+	codeDeliverTerms = 4508
+	codeDeliverSite  = 4510
+	codeNotFound     = 404
+)
+
+// Server is an in-process SpaceTraders API implementing the subset the bot uses.
+// Travel and cooldowns follow the game's formulas, divided by the time scale.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	token     string
+	scale     float64
+	clock     clock.Clock
+	status    m.Status
+	agent     m.Agent
+	contracts []m.Contract
+	ships     map[string]*m.Ship
+	order     []string
+	waypoints map[string]m.Waypoint
+	markets   map[string]m.Market
+	shipyards map[string]m.Shipyard
+	yields    map[string][]Yield
+	extracted map[string]int
+	cooldown  time.Duration
+	cooldowns map[string]time.Time
+	requests  map[string]int
+	down      bool
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithTimeScale makes simulated time pass scale times faster than real time.
+func WithTimeScale(scale float64) Option {
+	return func(s *Server) {
+		s.scale = scale
+	}
+}
+
+// WithClock times travel and cooldowns by c instead of the wall clock. A bot sharing a clock.Fake with the
+// Server completes them as soon as the clock is advanced.
+func WithClock(c clock.Clock) Option {
+	return func(s *Server) {
+		s.clock = c
+	}
+}
+
+// WithToken rejects requests that do not carry token as a bearer token.
+func WithToken(token string) Option {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// New starts a Server seeded from f. Callers must Close it.
+func New(f *Fixture, opts ...Option) *Server {
+	s := &Server{
+		scale:     1,
+		clock:     clock.Real,
+		status:    f.Status,
+		agent:     f.Agent,
+		contracts: append([]m.Contract(nil), f.Contracts...),
+		ships:     map[string]*m.Ship{},
+		waypoints: map[string]m.Waypoint{},
+		markets:   map[string]m.Market{},
+		shipyards: map[string]m.Shipyard{},
+		yields:    f.Yields,
+		extracted: map[string]int{},
+		cooldown:  time.Duration(f.CooldownSeconds) * time.Second,
+		cooldowns: map[string]time.Time{},
+		requests:  map[string]int{},
+	}
+
+	for i := range f.Ships {
+		ship := f.Ships[i]
+		s.ships[ship.Symbol] = &ship
+		s.order = append(s.order, ship.Symbol)
+	}
+	for _, waypoint := range f.Waypoints {
+		s.waypoints[waypoint.Symbol] = waypoint
+	}
+	for _, market := range f.Markets {
+		s.markets[market.Symbol] = market
+	}
+	for _, shipyard := range f.Shipyards {
+		s.shipyards[shipyard.Symbol] = shipyard
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+
+	return s
+}
+
+// Requests returns how many requests were made with method to path, e.g. ("GET", "/systems/X1-MK1/waypoints").
+func (s *Server) Requests(method string, path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[method+" "+path]
+}
+
+// SetAvailable simulates a maintenance window: while unavailable, every request gets 503 Service Unavailable.
+func (s *Server) SetAvailable(available bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.down = !available
+}
+
+// Reset simulates a server reset by changing the reported reset date.
+func (s *Server) Reset(resetDate string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status.ResetDate = resetDate
+}
+
+// SetMarket replaces the market at its waypoint, as when trading there moves its prices and supply.
+func (s *Server) SetMarket(market m.Market) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.markets[market.Symbol] = market
+}
+
+// Agent returns the agent's current state.
+func (s *Server) Agent() m.Agent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.agent
+}
+
+// Ship returns a ship's current state.
+func (s *Server) Ship(symbol string) (m.Ship, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ship, ok := s.ships[symbol]
+	if !ok {
+		return m.Ship{}, false
+	}
+	s.settle(ship)
+
+	return *ship, true
+}
+
+// Contracts returns the agent's current contracts.
+func (s *Server) Contracts() []m.Contract {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]m.Contract(nil), s.contracts...)
+}
+
+// scaled converts a simulated duration to real time.
+func (s *Server) scaled(d time.Duration) time.Duration {
+	return time.Duration(float64(d) / s.scale)
+}
+
+// settle completes a ship's transit once its arrival time has passed.
+func (s *Server) settle(ship *m.Ship) {
+	if ship.Nav.Status == "IN_TRANSIT" && !s.clock.Now().Before(ship.Nav.Route.Arrival) {
+		ship.Nav.Status = "IN_ORBIT"
+	}
+}
+
+// apiError is an error response.
+type apiError struct {
+	status  int
+	code    int
+	message string
+}
+
+func errorf(status int, code int, format string, args ...interface{}) *apiError {
+	return &apiError{status: status, code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// cargoShort maps an error removing cargo to the API's error for a missing good or a short unit count.
+func cargoShort(err error) *apiError {
+	var short *m.CargoShortError
+	if errors.As(err, &short) && short.Held == 0 {
+		return errorf(http.StatusBadRequest, codeCargoMissing, "%s", err)
+	}
+
+	return errorf(http.StatusBadRequest, codeCargoUnits, "%s", err)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v2")
+	if path == "" {
+		path = "/"
+	}
+	s.requests[r.Method+" "+path]++
+
+	if s.down {
+		writeError(w, errorf(http.StatusServiceUnavailable, 503, "SpaceTraders is currently undergoing maintenance."))
+		return
+	}
+
+	if s.token != "" && path != "/" && path != "/register" && r.Header.Get("Authorization") != "Bearer "+s.token {
+		writeError(w, errorf(http.StatusUnauthorized, 401, "Failed to parse token. Token reset_date does not match the server."))
+		return
+	}
+
+	data, apiErr := s.route(r, strings.Split(strings.Trim(path, "/"), "/"))
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	status := http.StatusOK
+	if r.Method == http.MethodPost {
+		status = http.StatusCreated
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	// The status endpoint and paginated lists are not wrapped in a data envelope.
+	if _, ok := data.(listing); ok || path == "/" {
+		json.NewEncoder(w).Encode(data)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func writeError(w http.ResponseWriter, e *apiError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.status)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{"message": e.message, "code": e.code},
+	})
+}
+
+// route dispatches a request by method and path segments.
+func (s *Server) route(r *http.Request, parts []string) (interface{}, *apiError) {
+	get := r.Method == http.MethodGet
+	post := r.Method == http.MethodPost
+
+	switch {
+	case get && len(parts) == 1 && parts[0] == "":
+		return s.status, nil
+	case get && match(parts, "my", "agent"):
+		return s.agent, nil
+	case get && match(parts, "my", "agent", "events"):
+		return []m.AgentEvent{}, nil
+	case get && match(parts, "my", "contracts"):
+		return paginate(r, s.contracts)
+	case post && match(parts, "my", "contracts", "*", "accept"):
+		return s.acceptContract(parts[2])
+	case post && match(parts, "my", "contracts", "*", "deliver"):
+		var body struct {
+			ShipSymbol  string `json:"shipSymbol"`
+			TradeSymbol string `json:"tradeSymbol"`
+			Units       int    `json:"units"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return nil, errorf(http.StatusBadRequest, 400, "invalid body: %s", err)
+		}
+		return s.deliverContract(parts[2], body.ShipSymbol, body.TradeSymbol, body.Units)
+	case post && match(parts, "my", "contracts", "*", "fulfill"):
+		return s.fulfillContract(parts[2])
+	case get && match(parts, "my", "ships"):
+		ships := make([]m.Ship, 0, len(s.order))
+		for _, symbol := range s.order {
+			s.settle(s.ships[symbol])
+			ships = append(ships, *s.ships[symbol])
+		}
+		return paginate(r, ships)
+	case post && match(parts, "my", "ships"):
+		var body struct {
+			ShipType       string `json:"shipType"`
+			WaypointSymbol string `json:"waypointSymbol"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return nil, errorf(http.StatusBadRequest, 400, "invalid body: %s", err)
+		}
+		return s.purchaseShip(body.ShipType, body.WaypointSymbol)
+	case get && match(parts, "my", "ships", "*"):
+		ship, err := s.ship(parts[2])
+		if err != nil {
+			return nil, err
+		}
+		return *ship, nil
+	case get && match(parts, "my", "ships", "*", "nav"):
+		ship, err := s.ship(parts[2])
+		if err != nil {
+			return nil, err
+		}
+		return ship.Nav, nil
+	case get && match(parts, "my", "ships", "*", "cooldown"):
+		return s.getCooldown(parts[2])
+	case post && match(parts, "my", "ships", "*", "navigate"):
+		var body struct {
+			WaypointSymbol string `json:"waypointSymbol"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return nil, errorf(http.StatusBadRequest, 400, "invalid body: %s", err)
+		}
+		return s.navigate(parts[2], body.WaypointSymbol)
+	case post && match(parts, "my", "ships", "*", "dock"):
+		return s.setStatus(parts[2], "DOCKED")
+	case post && match(parts, "my", "ships", "*", "orbit"):
+		return s.setStatus(parts[2], "IN_ORBIT")
+	case post && match(parts, "my", "ships", "*", "extract"):
+		return s.extract(parts[2])
+	case post && match(parts, "my", "ships", "*", "sell"):
+		var body struct {
+			Symbol string `json:"symbol"`
+			Units  int    `json:"units"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return nil, errorf(http.StatusBadRequest, 400, "invalid body: %s", err)
+		}
+		return s.sell(parts[2], body.Symbol, body.Units)
+	case post && match(parts, "my", "ships", "*", "jettison"):
+		var body struct {
+			Symbol string `json:"symbol"`
+			Units  int    `json:"units"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return nil, errorf(http.StatusBadRequest, 400, "invalid body: %s", err)
+		}
+		return s.jettison(parts[2], body.Symbol, body.Units)
+	case post && match(parts, "my", "ships", "*", "purchase"):
+		var body struct {
+			Symbol string `json:"symbol"`
+			Units  int    `json:"units"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return nil, errorf(http.StatusBadRequest, 400, "invalid body: %s", err)
+		}
+		return s.purchase(parts[2], body.Symbol, body.Units)
+	case post && match(parts, "my", "ships", "*", "chart"):
+		return s.chart(parts[2])
+	case get && match(parts, "systems", "*"):
+		return s.system(parts[1])
+	case get && match(parts, "systems", "*", "waypoints"):
+		waypoints := []m.Waypoint{}
+		for _, waypoint := range s.waypoints {
+			if waypoint.SystemSymbol == parts[1] {
+				waypoints = append(waypoints, waypoint)
+			}
+		}
+		sort.Slice(waypoints, func(i, j int) bool { return waypoints[i].Symbol < waypoints[j].Symbol })
+		return paginate(r, waypoints)
+	case get && match(parts, "systems", "*", "waypoints", "*"):
+		waypoint, ok := s.waypoints[parts[3]]
+		if !ok {
+			return nil, errorf(http.StatusNotFound, codeNotFound, "Waypoint %s not found.", parts[3])
+		}
+		return waypoint, nil
+	case get && match(parts, "systems", "*", "waypoints", "*", "shipyard"):
+		return s.shipyard(parts[3])
+	case get && match(parts, "systems", "*", "waypoints", "*", "market"):
+		market, ok := s.markets[parts[3]]
+		if !ok {
+			return nil, errorf(http.StatusNotFound, codeNoMarket, "Market not found at %s.", parts[3])
+		}
+		return market, nil
+	}
+
+	return nil, errorf(http.StatusNotFound, codeNotFound, "%s %s is not implemented by the mock server.", r.Method, r.URL.Path)
+}
+
+// system builds the system containing the fixture's waypoints with symbol.
+func (s *Server) system(symbol string) (interface{}, *apiError) {
+	system := m.System{Symbol: symbol, SectorSymbol: strings.Split(symbol, "-")[0], Waypoints: []m.SystemWaypoint{}}
+	for _, waypoint := range s.waypoints {
+		if waypoint.SystemSymbol == symbol {
+			system.Waypoints = append(system.Waypoints, m.SystemWaypoint{Symbol: waypoint.Symbol, Type: waypoint.Type, X: waypoint.X, Y: waypoint.Y})
+		}
+	}
+
+	if len(system.Waypoints) == 0 {
+		return nil, errorf(http.StatusNotFound, codeNotFound, "System %s not found.", symbol)
+	}
+	sort.Slice(system.Waypoints, func(i, j int) bool { return system.Waypoints[i].Symbol < system.Waypoints[j].Symbol })
+
+	return system, nil
+}
+
+// shipyard returns the shipyard at waypointSymbol, hiding its ships and transactions unless a ship is present.
+func (s *Server) shipyard(waypointSymbol string) (interface{}, *apiError) {
+	shipyard, ok := s.shipyards[waypointSymbol]
+	if !ok {
+		return nil, errorf(http.StatusNotFound, codeNotFound, "Shipyard not found at %s.", waypointSymbol)
+	}
+
+	for _, ship := range s.ships {
+		s.settle(ship)
+		if ship.Nav.WaypointSymbol == waypointSymbol && ship.Nav.Status != "IN_TRANSIT" {
+			return shipyard, nil
+		}
+	}
+
+	shipyard.Ships = nil
+	shipyard.Transactions = nil
+
+	return shipyard, nil
+}
+
+// listing is a page of a list endpoint.
+type listing struct {
+	Data interface{} `json:"data"`
+	Meta listingMeta `json:"meta"`
+}
+
+type listingMeta struct {
+	Total int `json:"total"`
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+}
+
+// paginate returns the page of items selected by the request's page and limit query parameters.
+func paginate[T any](r *http.Request, items []T) (interface{}, *apiError) {
+	page, limit := 1, 10
+
+	if v := r.URL.Query().Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, errorf(http.StatusBadRequest, 400, "page must be a positive integer")
+		}
+		page = n
+	}
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 20 {
+			return nil, errorf(http.StatusBadRequest, 400, "limit must be between 1 and 20")
+		}
+		limit = n
+	}
+
+	start := (page - 1) * limit
+	if start > len(items) {
+		start = len(items)
+	}
+	end := start + limit
+	if end > len(items) {
+		end = len(items)
+	}
+
+	return listing{
+		Data: append([]T{}, items[start:end]...),
+		Meta: listingMeta{Total: len(items), Page: page, Limit: limit},
+	}, nil
+}
+
+// match reports whether path segments match a pattern, where "*" matches any single segment.
+func match(parts []string, pattern ...string) bool {
+	if len(parts) != len(pattern) {
+		return false
+	}
+
+	for i := range pattern {
+		if pattern[i] != "*" && pattern[i] != parts[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Server) ship(symbol string) (*m.Ship, *apiError) {
+	ship, ok := s.ships[symbol]
+	if !ok {
+		return nil, errorf(http.StatusNotFound, codeNotFound, "Ship %s not found.", symbol)
+	}
+	s.settle(ship)
+
+	return ship, nil
+}
+
+func (s *Server) acceptContract(id string) (interface{}, *apiError) {
+	for i := range s.contracts {
+		if s.contracts[i].ID != id {
+			continue
+		}
+
+		if s.contracts[i].Accepted {
+			return nil, errorf(http.StatusBadRequest, 4501, "Contract %s has already been accepted.", id)
+		}
+
+		s.contracts[i].Accepted = true
+		s.agent.Credits += m.Credits(s.contracts[i].Terms.Payment.OnAccepted)
+
+		return map[string]interface{}{"agent": s.agent, "contract": s.contracts[i]}, nil
+	}
+
+	return nil, errorf(http.StatusNotFound, codeNotFound, "Contract %s not found.", id)
+}
+
+func (s *Server) contract(id string) (*m.Contract, *apiError) {
+	for i := range s.contracts {
+		if s.contracts[i].ID == id {
+			return &s.contracts[i], nil
+		}
+	}
+
+	return nil, errorf(http.StatusNotFound, codeNotFound, "Contract %s not found.", id)
+}
+
+func (s *Server) deliverContract(id string, symbol string, good string, units int) (interface{}, *apiError) {
+	contract, err := s.contract(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !contract.Accepted {
+		return nil, errorf(http.StatusBadRequest, codeNotAccepted, "Contract %s has not been accepted.", id)
+	}
+
+	ship, err := s.ship(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if ship.Nav.Status != "DOCKED" {
+		return nil, errorf(http.StatusBadRequest, codeNotDocked, "Ship %s must be docked to deliver.", symbol)
+	}
+
+	for i := range contract.Terms.Deliver {
+		term := &contract.Terms.Deliver[i]
+		if term.TradeSymbol != good {
+			continue
+		}
+
+		if term.DestinationSymbol != ship.Nav.WaypointSymbol {
+			return nil, errorf(http.StatusBadRequest, codeDeliverSite, "Contract %s delivers %s to %s.", id, good, term.DestinationSymbol)
+		}
+
+		if units > term.UnitsRequired-term.UnitsFulfilled {
+			return nil, errorf(http.StatusBadRequest, codeDeliverTerms, "Contract %s requires %d more %s.", id, term.UnitsRequired-term.UnitsFulfilled, good)
+		}
+
+		if err := ship.Cargo.Remove(good, units); err != nil {
+			return nil, cargoShort(err)
+		}
+		term.UnitsFulfilled += units
+
+		return map[string]interface{}{"contract": contract, "cargo": ship.Cargo}, nil
+	}
+
+	return nil, errorf(http.StatusBadRequest, codeDeliverTerms, "Contract %s does not require %s.", id, good)
+}
+
+func (s *Server) fulfillContract(id string) (interface{}, *apiError) {
+	contract, err := s.contract(id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case contract.Fulfilled:
+		return nil, errorf(http.StatusBadRequest, codeFulfilled, "Contract %s has already been fulfilled.", id)
+	case !contract.IsDeliverComplete():
+		return nil, errorf(http.StatusBadRequest, codeIncomplete, "Contract %s has deliveries outstanding.", id)
+	}
+
+	contract.Fulfilled = true
+	s.agent.Credits += m.Credits(contract.Terms.Payment.OnFulfilled)
+
+	return map[string]interface{}{"agent": s.agent, "contract": contract}, nil
+}
+
+func (s *Server) getCooldown(symbol string) (interface{}, *apiError) {
+	if _, err := s.ship(symbol); err != nil {
+		return nil, err
+	}
+
+	expiration, ok := s.cooldowns[symbol]
+	if !ok || !s.clock.Now().Before(expiration) {
+		return m.Cooldown{ShipSymbol: symbol}, nil
+	}
+
+	return s.cooldownOf(symbol, expiration), nil
+}
+
+// cooldownOf reports a cooldown in real seconds, so the bot's waits match the scaled clock.
+func (s *Server) cooldownOf(symbol string, expiration time.Time) m.Cooldown {
+	return m.Cooldown{
+		ShipSymbol:       symbol,
+		TotalSeconds:     int(math.Ceil(s.scaled(s.cooldown).Seconds())),
+		RemainingSeconds: int(math.Ceil(expiration.Sub(s.clock.Now()).Seconds())),
+		Expiration:       m.OptionalTime{Time: expiration},
+	}
+}
+
+func (s *Server) navigate(symbol string, destination string) (interface{}, *apiError) {
+	ship, err := s.ship(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ship.Nav.Status {
+	case "IN_TRANSIT":
+		return nil, errorf(http.StatusBadRequest, codeInTransit, "Ship %s is currently in transit.", symbol)
+	case "DOCKED":
+		return nil, errorf(http.StatusBadRequest, codeNotInOrbit, "Ship %s is currently docked. Ships must be in orbit to navigate.", symbol)
+	}
+
+	from, ok := s.waypoints[ship.Nav.WaypointSymbol]
+	if !ok {
+		return nil, errorf(http.StatusNotFound, codeNotFound, "Waypoint %s not found.", ship.Nav.WaypointSymbol)
+	}
+
+	to, ok := s.waypoints[destination]
+	if !ok {
+		return nil, errorf(http.StatusNotFound, codeNotFound, "Waypoint %s not found.", destination)
+	}
+
+	if to.SystemSymbol != from.SystemSymbol {
+		return nil, errorf(http.StatusBadRequest, 4202, "Waypoint %s is not in system %s.", destination, from.SystemSymbol)
+	}
+
+	distance := lib.WaypointDistance(&from, &to)
+	fuel := lib.FuelCost(distance, ship.Nav.FlightMode)
+	if ship.Fuel.Capacity > 0 && fuel > ship.Fuel.Current {
+		return nil, errorf(http.StatusBadRequest, 4203, "Ship %s needs %d fuel but has %d.", symbol, fuel, ship.Fuel.Current)
+	}
+
+	now := s.clock.Now()
+	travel := s.scaled(lib.TravelTime(distance, ship.Engine.Speed, ship.Nav.FlightMode))
+
+	if ship.Fuel.Capacity > 0 {
+		ship.Fuel.Current -= fuel
+		ship.Fuel.Consumed.Amount = fuel
+		ship.Fuel.Consumed.Timestamp = m.OptionalTime{Time: now}
+	}
+
+	ship.Nav.WaypointSymbol = to.Symbol
+	ship.Nav.Status = "IN_TRANSIT"
+	ship.Nav.Route = m.ShipNavRoute{
+		Departure:     m.ShipNavRouteWaypoint{Symbol: from.Symbol, Type: from.Type, SystemSymbol: from.SystemSymbol, X: from.X, Y: from.Y},
+		Destination:   m.ShipNavRouteWaypoint{Symbol: to.Symbol, Type: to.Type, SystemSymbol: to.SystemSymbol, X: to.X, Y: to.Y},
+		DepartureTime: now,
+		Arrival:       now.Add(travel),
+	}
+
+	return map[string]interface{}{"fuel": ship.Fuel, "nav": ship.Nav}, nil
+}
+
+func (s *Server) setStatus(symbol string, status string) (interface{}, *apiError) {
+	ship, err := s.ship(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if ship.Nav.Status == "IN_TRANSIT" {
+		return nil, errorf(http.StatusBadRequest, codeInTransit, "Ship %s is currently in transit.", symbol)
+	}
+
+	ship.Nav.Status = status
+
+	return map[string]interface{}{"nav": ship.Nav}, nil
+}
+
+func (s *Server) extract(symbol string) (interface{}, *apiError) {
+	ship, err := s.ship(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if ship.Nav.Status != "IN_ORBIT" {
+		return nil, errorf(http.StatusBadRequest, codeNotInOrbit, "Ship %s must be in orbit to extract.", symbol)
+	}
+
+	if expiration, ok := s.cooldowns[symbol]; ok && s.clock.Now().Before(expiration) {
+		return nil, errorf(http.StatusConflict, codeCooldown, "Ship action is still on cooldown for %d second(s).", int(math.Ceil(expiration.Sub(s.clock.Now()).Seconds())))
+	}
+
+	yields := s.yields[ship.Nav.WaypointSymbol]
+	if len(yields) == 0 {
+		return nil, errorf(http.StatusBadRequest, 4205, "Waypoint %s has no resources to extract.", ship.Nav.WaypointSymbol)
+	}
+
+	yield := yields[s.extracted[ship.Nav.WaypointSymbol]%len(yields)]
+	s.extracted[ship.Nav.WaypointSymbol]++
+
+	if space := ship.Cargo.SpaceRemaining(); yield.Units > space {
+		yield.Units = space
+	}
+
+	if yield.Units > 0 {
+		ship.Cargo.Add(yield.Symbol, yield.Units)
+	}
+
+	expiration := s.clock.Now().Add(s.scaled(s.cooldown))
+	s.cooldowns[symbol] = expiration
+
+	extraction := m.Extraction{ShipSymbol: symbol}
+	extraction.Yield.Symbol = yield.Symbol
+	extraction.Yield.Units = yield.Units
+
+	return map[string]interface{}{
+		"cooldown":   s.cooldownOf(symbol, expiration),
+		"extraction": extraction,
+		"cargo":      ship.Cargo,
+	}, nil
+}
+
+func (s *Server) sell(symbol string, good string, units int) (interface{}, *apiError) {
+	ship, err := s.ship(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if ship.Nav.Status != "DOCKED" {
+		return nil, errorf(http.StatusBadRequest, codeNotDocked, "Ship %s must be docked to sell.", symbol)
+	}
+
+	market, ok := s.markets[ship.Nav.WaypointSymbol]
+	if !ok {
+		return nil, errorf(http.StatusBadRequest, codeNoMarket, "No market at %s.", ship.Nav.WaypointSymbol)
+	}
+
+	price, ok := market.SellPriceOf(good)
+	if !ok {
+		return nil, errorf(http.StatusBadRequest, codeNotAvailable, "Market %s does not trade %s.", market.Symbol, good)
+	}
+
+	if err := ship.Cargo.Remove(good, units); err != nil {
+		return nil, cargoShort(err)
+	}
+
+	transaction := m.MarketTransaction{
+		WaypointSymbol: market.Symbol,
+		ShipSymbol:     symbol,
+		TradeSymbol:    good,
+		Type:           "SELL",
+		Units:          units,
+		PricePerUnit:   price,
+		TotalPrice:     price * int64(units),
+		Timestamp:      s.clock.Now(),
+	}
+	s.agent.Credits += m.Credits(transaction.TotalPrice)
+
+	return map[string]interface{}{"agent": s.agent, "cargo": ship.Cargo, "transaction": transaction}, nil
+}
+
+func (s *Server) purchase(symbol string, good string, units int) (interface{}, *apiError) {
+	ship, err := s.ship(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if ship.Nav.Status != "DOCKED" {
+		return nil, errorf(http.StatusBadRequest, codeNotDocked, "Ship %s must be docked to purchase.", symbol)
+	}
+
+	market, ok := s.markets[ship.Nav.WaypointSymbol]
+	if !ok {
+		return nil, errorf(http.StatusBadRequest, codeNoMarket, "No market at %s.", ship.Nav.WaypointSymbol)
+	}
+
+	price, ok := market.PurchasePriceOf(good)
+	if !ok || price <= 0 {
+		return nil, errorf(http.StatusBadRequest, codeNotAvailable, "Market %s does not trade %s.", market.Symbol, good)
+	}
+
+	if volume, ok := market.TradeVolumeOf(good); ok && volume > 0 && units > volume {
+		return nil, errorf(http.StatusBadRequest, 4604, "Market %s trades at most %d %s per transaction.", market.Symbol, volume, good)
+	}
+
+	total := price * int64(units)
+	if int64(s.agent.Credits) < total {
+		return nil, errorf(http.StatusBadRequest, 4600, "Agent has insufficient credits: %d needed.", total)
+	}
+
+	if err := ship.Cargo.Add(good, units); err != nil {
+		return nil, errorf(http.StatusBadRequest, 4228, "%s", err)
+	}
+
+	transaction := m.MarketTransaction{
+		WaypointSymbol: market.Symbol,
+		ShipSymbol:     symbol,
+		TradeSymbol:    good,
+		Type:           "PURCHASE",
+		Units:          units,
+		PricePerUnit:   price,
+		TotalPrice:     total,
+		Timestamp:      s.clock.Now(),
+	}
+	s.agent.Credits -= m.Credits(total)
+
+	return map[string]interface{}{"agent": s.agent, "cargo": ship.Cargo, "transaction": transaction}, nil
+}
+
+// purchaseShip buys a ship from the shipyard at waypointSymbol, which one of the agent's ships must be at. The
+// new ship is docked at the shipyard and joins the end of the fleet.
+func (s *Server) purchaseShip(shipType string, waypointSymbol string) (interface{}, *apiError) {
+	shipyard, ok := s.shipyards[waypointSymbol]
+	if !ok {
+		return nil, errorf(http.StatusNotFound, codeNotFound, "Shipyard not found at %s.", waypointSymbol)
+	}
+
+	var present *m.Ship
+	for _, symbol := range s.order {
+		ship := s.ships[symbol]
+		s.settle(ship)
+		if ship.Nav.WaypointSymbol == waypointSymbol && ship.Nav.Status != "IN_TRANSIT" {
+			present = ship
+			break
+		}
+	}
+	if present == nil {
+		return nil, errorf(http.StatusBadRequest, 4601, "Agent has no ship at %s to purchase from the shipyard.", waypointSymbol)
+	}
+
+	var listing *m.ShipyardShip
+	for i := range shipyard.Ships {
+		if shipyard.Ships[i].Type == shipType {
+			listing = &shipyard.Ships[i]
+			break
+		}
+	}
+	if listing == nil {
+		return nil, errorf(http.StatusBadRequest, codeNotAvailable, "Shipyard %s does not sell %s.", waypointSymbol, shipType)
+	}
+
+	if int64(s.agent.Credits) < listing.PurchasePrice {
+		return nil, errorf(http.StatusBadRequest, 4600, "Agent has insufficient credits: %d needed.", listing.PurchasePrice)
+	}
+
+	symbol := fmt.Sprintf("%s-%X", s.agent.Symbol, len(s.order)+1)
+	ship := listing.Build(symbol, present.Registration.FactionSymbol, present.Nav)
+	s.ships[symbol] = &ship
+	s.order = append(s.order, symbol)
+
+	transaction := m.ShipyardTransaction{
+		WaypointSymbol: waypointSymbol,
+		ShipSymbol:     symbol,
+		ShipType:       shipType,
+		Price:          listing.PurchasePrice,
+		AgentSymbol:    s.agent.Symbol,
+		Timestamp:      s.clock.Now(),
+	}
+	shipyard.Transactions = append(shipyard.Transactions, transaction)
+	s.shipyards[waypointSymbol] = shipyard
+	s.agent.Credits -= m.Credits(listing.PurchasePrice)
+
+	return map[string]interface{}{"agent": s.agent, "ship": ship, "transaction": transaction}, nil
+}
+
+func (s *Server) chart(symbol string) (interface{}, *apiError) {
+	ship, err := s.ship(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	waypoint, ok := s.waypoints[ship.Nav.WaypointSymbol]
+	if !ok {
+		return nil, errorf(http.StatusNotFound, codeNotFound, "Waypoint %s not found.", ship.Nav.WaypointSymbol)
+	}
+	if !waypoint.HasTrait("UNCHARTED") {
+		return nil, errorf(http.StatusBadRequest, codeCharted, "Waypoint %s is already charted.", waypoint.Symbol)
+	}
+
+	traits := make([]m.WaypointTrait, 0, len(waypoint.Traits))
+	for _, trait := range waypoint.Traits {
+		if trait.Symbol != "UNCHARTED" {
+			traits = append(traits, trait)
+		}
+	}
+	waypoint.Traits = traits
+	waypoint.Chart = m.Chart{WaypointSymbol: waypoint.Symbol, SubmittedBy: s.agent.Symbol, SubmittedOn: m.OptionalTime{Time: s.clock.Now()}}
+	s.waypoints[waypoint.Symbol] = waypoint
+
+	return map[string]interface{}{"chart": waypoint.Chart, "waypoint": waypoint}, nil
+}
+
+func (s *Server) jettison(symbol string, good string, units int) (interface{}, *apiError) {
+	ship, err := s.ship(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ship.Cargo.Remove(good, units); err != nil {
+		return nil, cargoShort(err)
+	}
+
+	return map[string]interface{}{"cargo": ship.Cargo}, nil
+}
@@ -0,0 +1,365 @@
+package mockserver_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/clock"
+	"github.com/GeoffreyDick/gogarin/mockserver"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+// scale runs simulated time fast enough that travel and cooldowns take milliseconds.
+const scale = 1000
+
+// start boots a Server from the default fixture with a Client pointed at it.
+func start(t *testing.T) (*mockserver.Server, *api.Client) {
+	t.Helper()
+
+	f, err := mockserver.DefaultFixture()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := mockserver.New(f, mockserver.WithTimeScale(scale))
+	t.Cleanup(s.Close)
+
+	return s, api.NewClient("token", api.WithBaseURL(s.URL), api.WithRateLimit(1000))
+}
+
+// startOnClock boots a Server from the default fixture on a fake clock, with a Client pointed at it. The clock
+// moves forward by itself while the client's throttle sleeps.
+func startOnClock(t *testing.T) (*mockserver.Server, *api.Client, *clock.Fake) {
+	t.Helper()
+
+	f, err := mockserver.DefaultFixture()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := clock.NewFake(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)).AutoAdvance()
+	s := mockserver.New(f, mockserver.WithClock(fake))
+	t.Cleanup(s.Close)
+
+	return s, api.NewClient("token", api.WithBaseURL(s.URL), api.WithRateLimit(1000), api.WithClock(fake)), fake
+}
+
+// code returns the SpaceTraders error code of err, or 0 if it is not an API error.
+func code(err error) int {
+	var apiErr *api.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+
+	return 0
+}
+
+func TestNavigateArrivesAfterScaledTravelTime(t *testing.T) {
+	_, c := start(t)
+
+	res, err := c.NavigateShip("MOCK-2", "X1-MK1-B2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Nav.Status != "IN_TRANSIT" {
+		t.Fatalf("status after navigate = %s, want IN_TRANSIT", res.Nav.Status)
+	}
+	if travel := res.Nav.Route.Arrival.Sub(res.Nav.Route.DepartureTime); travel <= 0 || travel > time.Second {
+		t.Fatalf("scaled travel time = %s, want under a second", travel)
+	}
+
+	if _, err := c.ExtractResources("MOCK-2"); err == nil {
+		t.Fatal("extracting in transit succeeded")
+	}
+
+	time.Sleep(time.Until(res.Nav.Route.Arrival))
+	nav, err := c.GetShipNav("MOCK-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nav.Status != "IN_ORBIT" || nav.WaypointSymbol != "X1-MK1-B2" {
+		t.Fatalf("nav after arrival = %s at %s, want IN_ORBIT at X1-MK1-B2", nav.Status, nav.WaypointSymbol)
+	}
+	ship, err := c.GetShip("MOCK-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ship.Nav != *nav {
+		t.Fatalf("ship nav = %+v, want the nav read on its own %+v", ship.Nav, *nav)
+	}
+
+	if _, err := c.NavigateShip("MOCK-1", "X1-MK1-B2"); code(err) != 4236 {
+		t.Fatalf("navigating a docked ship: err = %v, want code 4236", err)
+	}
+}
+
+func TestNavigateArrivesWhenTheClockPassesArrival(t *testing.T) {
+	_, c, fake := startOnClock(t)
+
+	res, err := c.NavigateShip("MOCK-2", "X1-MK1-B2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Nav.Status != "IN_TRANSIT" {
+		t.Fatalf("status after navigate = %s, want IN_TRANSIT", res.Nav.Status)
+	}
+
+	fake.Advance(res.Nav.Route.Arrival.Sub(fake.Now()) - time.Second)
+	if nav, err := c.GetShipNav("MOCK-2"); err != nil || nav.Status != "IN_TRANSIT" {
+		t.Fatalf("nav a second before arrival = %+v, err %v, want IN_TRANSIT", nav, err)
+	}
+
+	fake.Advance(time.Second)
+	nav, err := c.GetShipNav("MOCK-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nav.Status != "IN_ORBIT" || nav.WaypointSymbol != "X1-MK1-B2" {
+		t.Fatalf("nav on arrival = %s at %s, want IN_ORBIT at X1-MK1-B2", nav.Status, nav.WaypointSymbol)
+	}
+
+	extract, err := c.ExtractResources("MOCK-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.Advance(extract.Cooldown.Expiration.Time.Sub(fake.Now()) - time.Second)
+	if _, err := c.ExtractResources("MOCK-2"); code(err) != 4000 {
+		t.Fatalf("extracting a second before the cooldown ends: err = %v, want code 4000", err)
+	}
+	fake.Advance(time.Second)
+	if _, err := c.ExtractResources("MOCK-2"); err != nil {
+		t.Fatalf("extracting once the cooldown ends: %s", err)
+	}
+}
+
+func TestExtractYieldsInOrderAndSells(t *testing.T) {
+	s, c := start(t)
+
+	res, err := c.NavigateShip("MOCK-2", "X1-MK1-B2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Until(res.Nav.Route.Arrival))
+
+	want := []struct {
+		symbol string
+		units  int
+	}{{"IRON_ORE", 7}, {"QUARTZ_SAND", 5}, {"IRON_ORE", 9}, {"IRON_ORE", 7}}
+
+	for i, w := range want {
+		extract, err := c.ExtractResources("MOCK-2")
+		if err != nil {
+			t.Fatalf("extraction %d: %s", i, err)
+		}
+		if y := extract.Extraction.Yield; y.Symbol != w.symbol || y.Units != w.units {
+			t.Fatalf("extraction %d yielded %d %s, want %d %s", i, y.Units, y.Symbol, w.units, w.symbol)
+		}
+
+		if _, err := c.ExtractResources("MOCK-2"); code(err) != 4000 {
+			t.Fatalf("extraction %d: extracting again during the cooldown: err = %v, want code 4000", i, err)
+		}
+		time.Sleep(time.Until(extract.Cooldown.Expiration.Time))
+	}
+
+	res, err = c.NavigateShip("MOCK-2", "X1-MK1-A1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Until(res.Nav.Route.Arrival))
+	if _, err := c.DockShip("MOCK-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	before := s.Agent().Credits
+	sale, err := c.SellCargo("MOCK-2", "IRON_ORE", 23)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sale.Transaction.PricePerUnit != 45 || s.Agent().Credits-before != 23*45 {
+		t.Fatalf("sold at %d for %d credits, want 45 for %d", sale.Transaction.PricePerUnit, s.Agent().Credits-before, 23*45)
+	}
+	if _, err := c.SellCargo("MOCK-2", "QUARTZ_SAND", 5); code(err) != 4603 || !api.IsNotTraded(err) {
+		t.Fatalf("selling a good the market does not trade: err = %v, want code 4603", err)
+	}
+
+	cargo, err := c.JettisonCargo("MOCK-2", m.TradeGood{Symbol: "QUARTZ_SAND"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cargo.UnitsOf("QUARTZ_SAND") != 0 {
+		t.Errorf("cargo after jettisoning = %+v, want no QUARTZ_SAND", cargo)
+	}
+	if _, err := c.JettisonCargo("MOCK-2", m.TradeGood{Symbol: "QUARTZ_SAND"}, 1); code(err) != 4218 || !api.IsCargoShort(err) {
+		t.Errorf("jettisoning cargo not aboard: err = %v, want code 4218", err)
+	}
+}
+
+func TestPurchaseChargesTheAgent(t *testing.T) {
+	s, c := start(t)
+
+	if _, err := c.PurchaseCargo("MOCK-2", "IRON_ORE", 10); code(err) != 4244 {
+		t.Fatalf("purchasing from a ship in orbit: err = %v, want code 4244", err)
+	}
+
+	before := s.Agent().Credits
+	res, err := c.PurchaseCargo("MOCK-1", "IRON_ORE", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Transaction.PricePerUnit != 60 || before-s.Agent().Credits != 600 || res.Cargo.UnitsOf("IRON_ORE") != 10 {
+		t.Fatalf("bought at %d for %d credits with %d aboard, want 60 for 600 with 10 aboard", res.Transaction.PricePerUnit, before-s.Agent().Credits, res.Cargo.UnitsOf("IRON_ORE"))
+	}
+
+	if _, err := c.PurchaseCargo("MOCK-1", "IRON_ORE", 101); code(err) != 4604 {
+		t.Fatalf("buying above the trade volume: err = %v, want code 4604", err)
+	}
+	if _, err := c.PurchaseCargo("MOCK-1", "QUARTZ_SAND", 1); err == nil {
+		t.Fatal("buying a good the market does not trade succeeded")
+	}
+}
+
+func TestTokenIsRequired(t *testing.T) {
+	f, err := mockserver.DefaultFixture()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := mockserver.New(f, mockserver.WithToken("secret"))
+	t.Cleanup(s.Close)
+
+	if _, err := api.NewClient("wrong", api.WithBaseURL(s.URL)).GetMyAgent(); !api.IsUnauthorized(err) {
+		t.Fatalf("wrong token: err = %v, want unauthorized", err)
+	}
+	if _, err := api.NewClient("secret", api.WithBaseURL(s.URL)).GetMyAgent(); err != nil {
+		t.Fatalf("right token: %s", err)
+	}
+	if n := s.Requests("GET", "/my/agent"); n != 2 {
+		t.Fatalf("Requests(GET /my/agent) = %d, want 2", n)
+	}
+}
+
+func TestListsArePaginated(t *testing.T) {
+	s, _ := start(t)
+
+	res, err := http.Get(s.URL + "/systems/X1-MK1/waypoints?page=2&limit=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	var page struct {
+		Data []m.Waypoint `json:"data"`
+		Meta api.Meta     `json:"meta"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Data) != 1 || page.Data[0].Symbol != "X1-MK1-D4" {
+		t.Errorf("page 2 = %+v, want only X1-MK1-D4", page.Data)
+	}
+	if page.Meta != (api.Meta{Total: 4, Page: 2, Limit: 3}) {
+		t.Errorf("meta = %+v, want total 4, page 2, limit 3", page.Meta)
+	}
+
+	res, err = http.Get(s.URL + "/systems/X1-MK1/waypoints?limit=50")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("limit above 20: status = %d, want 400", res.StatusCode)
+	}
+}
+
+func TestUnavailableServerAnswers503(t *testing.T) {
+	s, c := start(t)
+
+	s.SetAvailable(false)
+	var apiErr *api.APIError
+	if _, err := c.GetMyAgent(); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("request during maintenance: err = %v, want 503", err)
+	}
+
+	s.SetAvailable(true)
+	if _, err := c.GetMyAgent(); err != nil {
+		t.Fatalf("request after maintenance: %s", err)
+	}
+
+	s.Reset("2031-01-01")
+	if status, err := c.GetStatus(); err != nil || status.ResetDate != "2031-01-01" {
+		t.Fatalf("status after reset = %+v, %v, want reset date 2031-01-01", status, err)
+	}
+}
+
+func TestShipyardShowsPricesToShipsPresent(t *testing.T) {
+	_, c := start(t)
+
+	shipyard, err := c.GetShipyard("X1-MK1", "X1-MK1-A1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shipyard.Ships) == 0 || shipyard.Ships[0].PurchasePrice != 80000 {
+		t.Fatalf("shipyard with ships present = %+v, want prices", shipyard)
+	}
+
+	if _, err := c.OrbitShip("MOCK-1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, ship := range []string{"MOCK-1", "MOCK-2"} {
+		if _, err := c.NavigateShip(ship, "X1-MK1-D4"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	shipyard, err = c.GetShipyard("X1-MK1", "X1-MK1-A1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shipyard.ShipTypes) != 2 || shipyard.Ships != nil {
+		t.Fatalf("shipyard with no ship present = %+v, want ship types without prices", shipyard)
+	}
+
+	if _, err := c.GetShipyard("X1-MK1", "X1-MK1-B2"); code(err) != 404 {
+		t.Fatalf("shipyard at an asteroid field: %v, want not found", err)
+	}
+}
+
+func TestChartChartsOnlyUnchartedWaypoints(t *testing.T) {
+	f, err := mockserver.DefaultFixture()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range f.Waypoints {
+		if f.Waypoints[i].Symbol == "X1-MK1-A1" {
+			f.Waypoints[i].Traits = append(f.Waypoints[i].Traits, m.WaypointTrait{Symbol: "UNCHARTED"})
+		}
+	}
+	s := mockserver.New(f, mockserver.WithTimeScale(scale))
+	t.Cleanup(s.Close)
+	c := api.NewClient("token", api.WithBaseURL(s.URL), api.WithRateLimit(1000))
+
+	res, err := c.CreateChart("MOCK-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Waypoint.HasTrait("UNCHARTED") || !res.Waypoint.HasTrait("MARKETPLACE") || res.Chart.SubmittedBy != "MOCK" {
+		t.Fatalf("charted = %+v, want the waypoint's traits without UNCHARTED, submitted by MOCK", res)
+	}
+
+	waypoint, err := c.GetWaypoint("X1-MK1", "X1-MK1-A1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if waypoint.HasTrait("UNCHARTED") {
+		t.Error("the server still lists the waypoint as uncharted")
+	}
+
+	if _, err := c.CreateChart("MOCK-1"); code(err) != 4230 {
+		t.Errorf("charting again: %v, want code 4230", err)
+	}
+}
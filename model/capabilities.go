@@ -0,0 +1,50 @@
+package model
+
+import "strings"
+
+// ShipCapabilities are what a ship's modules and mounts let it do.
+type ShipCapabilities struct {
+	// CanMine is whether the ship has a mining laser to extract resources with.
+	CanMine bool `json:"canMine"`
+	// CanSurvey is whether the ship has a surveyor to survey mining targets with.
+	CanSurvey bool `json:"canSurvey"`
+	// CanSiphon is whether the ship has a gas siphon to siphon gas giants with.
+	CanSiphon bool `json:"canSiphon"`
+	// CanRefineMinerals is whether the ship has a mineral processor to refine ores with.
+	CanRefineMinerals bool `json:"canRefineMinerals"`
+	// CanRefineGas is whether the ship has a gas processor to process siphoned gas with.
+	CanRefineGas bool `json:"canRefineGas"`
+	// MiningStrength is the combined strength of the ship's mining lasers, or zero without one.
+	MiningStrength int `json:"miningStrength"`
+	// CargoCapacity is the hold the ship's cargo modules add up to, or its reported cargo capacity when no
+	// cargo module is listed.
+	CargoCapacity int `json:"cargoCapacity"`
+}
+
+// CapabilitiesOf works out what a ship can do from its modules and mounts.
+func CapabilitiesOf(ship Ship) ShipCapabilities {
+	caps := ShipCapabilities{
+		CanMine:           ship.HasMount("MOUNT_MINING_LASER"),
+		CanSurvey:         ship.HasMount("MOUNT_SURVEYOR"),
+		CanSiphon:         ship.HasMount("MOUNT_GAS_SIPHON"),
+		CanRefineMinerals: ship.HasModule("MODULE_MINERAL_PROCESSOR"),
+		CanRefineGas:      ship.HasModule("MODULE_GAS_PROCESSOR"),
+	}
+
+	for _, mount := range ship.Mounts {
+		if strings.HasPrefix(mount.Symbol, "MOUNT_MINING_LASER") {
+			caps.MiningStrength += mount.Strength
+		}
+	}
+
+	for _, module := range ship.Modules {
+		if strings.HasPrefix(module.Symbol, "MODULE_CARGO_HOLD") {
+			caps.CargoCapacity += module.Capacity
+		}
+	}
+	if caps.CargoCapacity == 0 {
+		caps.CargoCapacity = ship.Cargo.Capacity
+	}
+
+	return caps
+}
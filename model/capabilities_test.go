@@ -0,0 +1,57 @@
+package model
+
+import "testing"
+
+func TestCapabilitiesOf(t *testing.T) {
+	tests := []struct {
+		name string
+		ship Ship
+		want ShipCapabilities
+	}{
+		{
+			name: "no modules or mounts",
+			ship: Ship{Cargo: ShipCargo{Capacity: 15}},
+			want: ShipCapabilities{CargoCapacity: 15},
+		},
+		{
+			name: "excavator",
+			ship: Ship{
+				Modules: []ShipModule{{Symbol: "MODULE_CARGO_HOLD_I", Capacity: 15}, {Symbol: "MODULE_CARGO_HOLD_I", Capacity: 15}},
+				Mounts:  []ShipMount{{Symbol: "MOUNT_MINING_LASER_I", Strength: 10}, {Symbol: "MOUNT_MINING_LASER_II", Strength: 25}},
+				Cargo:   ShipCargo{Capacity: 30},
+			},
+			want: ShipCapabilities{CanMine: true, MiningStrength: 35, CargoCapacity: 30},
+		},
+		{
+			name: "surveyor",
+			ship: Ship{Mounts: []ShipMount{{Symbol: "MOUNT_SURVEYOR_I", Strength: 1}}},
+			want: ShipCapabilities{CanSurvey: true},
+		},
+		{
+			name: "refinery",
+			ship: Ship{
+				Modules: []ShipModule{{Symbol: "MODULE_MINERAL_PROCESSOR_I"}, {Symbol: "MODULE_CARGO_HOLD_II", Capacity: 40}},
+				Cargo:   ShipCargo{Capacity: 60},
+			},
+			want: ShipCapabilities{CanRefineMinerals: true, CargoCapacity: 40},
+		},
+		{
+			name: "siphon drone",
+			ship: Ship{
+				Modules: []ShipModule{{Symbol: "MODULE_GAS_PROCESSOR_I"}},
+				Mounts:  []ShipMount{{Symbol: "MOUNT_GAS_SIPHON_I", Strength: 10}},
+				Cargo:   ShipCargo{Capacity: 10},
+			},
+			// A siphon adds nothing to mining strength.
+			want: ShipCapabilities{CanSiphon: true, CanRefineGas: true, CargoCapacity: 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CapabilitiesOf(tt.ship); got != tt.want {
+				t.Errorf("CapabilitiesOf = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
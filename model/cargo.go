@@ -0,0 +1,129 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnitsOf returns the number of units of a given trade symbol held in the cargo.
+func (c *ShipCargo) UnitsOf(symbol string) int {
+	for _, item := range c.Inventory {
+		if item.Symbol == symbol {
+			return item.Units
+		}
+	}
+
+	return 0
+}
+
+// HasItem checks if the cargo holds at least one unit of a given trade symbol.
+func (c *ShipCargo) HasItem(symbol string) bool {
+	return c.UnitsOf(symbol) > 0
+}
+
+// SpaceRemaining returns the number of units that can still be loaded into the cargo.
+func (c *ShipCargo) SpaceRemaining() int {
+	if c.Units >= c.Capacity {
+		return 0
+	}
+
+	return c.Capacity - c.Units
+}
+
+// IsFull checks if the cargo has no space remaining. A ship without a hold, such as a probe, is never full.
+func (c *ShipCargo) IsFull() bool {
+	return c.Capacity > 0 && c.Units >= c.Capacity
+}
+
+// IsEmpty checks if the cargo holds no units.
+func (c *ShipCargo) IsEmpty() bool {
+	return c.Units <= 0
+}
+
+// Add loads units of a trade symbol into the cargo, keeping Units and Inventory consistent.
+// It is meant for local bookkeeping between API refreshes.
+func (c *ShipCargo) Add(symbol string, units int) error {
+	if units <= 0 {
+		return fmt.Errorf("cannot add %d units of %s", units, symbol)
+	}
+
+	if units > c.SpaceRemaining() {
+		return fmt.Errorf("cannot add %d units of %s: only %d units of space remaining", units, symbol, c.SpaceRemaining())
+	}
+
+	c.Units += units
+
+	for i := range c.Inventory {
+		if c.Inventory[i].Symbol == symbol {
+			c.Inventory[i].Units += units
+			return nil
+		}
+	}
+
+	c.Inventory = append(c.Inventory, ShipCargoItem{Symbol: symbol, Units: units})
+
+	return nil
+}
+
+// Remove unloads units of a trade symbol from the cargo, keeping Units and Inventory consistent.
+// Items that reach zero units are dropped from the inventory.
+func (c *ShipCargo) Remove(symbol string, units int) error {
+	if units <= 0 {
+		return fmt.Errorf("cannot remove %d units of %s", units, symbol)
+	}
+
+	for i := range c.Inventory {
+		if c.Inventory[i].Symbol != symbol {
+			continue
+		}
+
+		if c.Inventory[i].Units < units {
+			return &CargoShortError{Symbol: symbol, Units: units, Held: c.Inventory[i].Units}
+		}
+
+		c.Inventory[i].Units -= units
+		c.Units -= units
+
+		if c.Inventory[i].Units == 0 {
+			c.Inventory = append(c.Inventory[:i], c.Inventory[i+1:]...)
+		}
+
+		return nil
+	}
+
+	return &CargoShortError{Symbol: symbol, Units: units}
+}
+
+// CargoShortError is returned when cargo holds fewer units of a trade symbol than asked for, or none at all.
+type CargoShortError struct {
+	Symbol string
+	Units  int
+	// Held is the number of units held, zero if the cargo does not contain the trade symbol.
+	Held int
+}
+
+func (e *CargoShortError) Error() string {
+	if e.Held == 0 {
+		return "cargo does not contain " + e.Symbol
+	}
+
+	return fmt.Sprintf("cannot remove %d units of %s: only %d units held", e.Units, e.Symbol, e.Held)
+}
+
+// String returns a concise, single-line representation of the cargo, e.g. "12/30 [IRON_ORE:10 QUARTZ_SAND:2]".
+func (c ShipCargo) String() string {
+	items := make([]string, 0, len(c.Inventory))
+	for _, item := range c.Inventory {
+		items = append(items, fmt.Sprintf("%s:%d", item.Symbol, item.Units))
+	}
+
+	return fmt.Sprintf("%d/%d [%s]", c.Units, c.Capacity, strings.Join(items, " "))
+}
+
+// LogValues returns key/value pairs describing the cargo, suitable for structured log fields.
+func (c ShipCargo) LogValues() []interface{} {
+	return []interface{}{
+		"cargo", fmt.Sprintf("%d/%d", c.Units, c.Capacity),
+		"inventory", len(c.Inventory),
+	}
+}
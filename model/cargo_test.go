@@ -0,0 +1,140 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+// hold returns a 30-unit hold with iron ore and quartz sand aboard.
+func hold() ShipCargo {
+	return ShipCargo{
+		Capacity: 30,
+		Units:    15,
+		Inventory: []ShipCargoItem{
+			{Symbol: "IRON_ORE", Units: 10},
+			{Symbol: "QUARTZ_SAND", Units: 5},
+		},
+	}
+}
+
+// consistent fails the test if the cargo's units are not the sum of its inventory.
+func consistent(t *testing.T, c ShipCargo) {
+	t.Helper()
+
+	var sum int
+	for _, item := range c.Inventory {
+		if item.Units <= 0 {
+			t.Errorf("inventory holds %d units of %s", item.Units, item.Symbol)
+		}
+		sum += item.Units
+	}
+	if sum != c.Units {
+		t.Errorf("units = %d, inventory sums to %d", c.Units, sum)
+	}
+}
+
+func TestCargoLookups(t *testing.T) {
+	c := hold()
+
+	tests := []struct {
+		symbol string
+		units  int
+	}{
+		{"IRON_ORE", 10},
+		{"QUARTZ_SAND", 5},
+		{"FUEL", 0},
+	}
+	for _, tt := range tests {
+		if got := c.UnitsOf(tt.symbol); got != tt.units {
+			t.Errorf("UnitsOf(%s) = %d, want %d", tt.symbol, got, tt.units)
+		}
+		if got := c.HasItem(tt.symbol); got != (tt.units > 0) {
+			t.Errorf("HasItem(%s) = %t, want %t", tt.symbol, got, tt.units > 0)
+		}
+	}
+
+	if got := c.SpaceRemaining(); got != 15 {
+		t.Errorf("SpaceRemaining = %d, want 15", got)
+	}
+	if c.IsFull() || c.IsEmpty() {
+		t.Errorf("half-full hold reads full %t, empty %t", c.IsFull(), c.IsEmpty())
+	}
+}
+
+func TestCargoCapacityEdges(t *testing.T) {
+	tests := []struct {
+		name  string
+		cargo ShipCargo
+		space int
+		full  bool
+		empty bool
+	}{
+		{"empty", ShipCargo{Capacity: 30}, 30, false, true},
+		{"full", ShipCargo{Capacity: 30, Units: 30}, 0, true, false},
+		{"overfull", ShipCargo{Capacity: 30, Units: 35}, 0, true, false},
+		{"no hold", ShipCargo{}, 0, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cargo.SpaceRemaining(); got != tt.space {
+				t.Errorf("SpaceRemaining = %d, want %d", got, tt.space)
+			}
+			if got := tt.cargo.IsFull(); got != tt.full {
+				t.Errorf("IsFull = %t, want %t", got, tt.full)
+			}
+			if got := tt.cargo.IsEmpty(); got != tt.empty {
+				t.Errorf("IsEmpty = %t, want %t", got, tt.empty)
+			}
+		})
+	}
+}
+
+func TestCargoAddAndRemoveKeepUnitsConsistent(t *testing.T) {
+	c := hold()
+
+	if err := c.Add("IRON_ORE", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Add("ICE_WATER", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Remove("QUARTZ_SAND", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Remove("IRON_ORE", 1); err != nil {
+		t.Fatal(err)
+	}
+	consistent(t, c)
+
+	want := []ShipCargoItem{{Symbol: "IRON_ORE", Units: 14}, {Symbol: "ICE_WATER", Units: 3}}
+	if !reflect.DeepEqual(c.Inventory, want) {
+		t.Fatalf("inventory = %+v, want %+v", c.Inventory, want)
+	}
+	if c.HasItem("QUARTZ_SAND") {
+		t.Fatal("an item removed in full is still held")
+	}
+}
+
+func TestCargoRejectsImpossibleChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *ShipCargo) error
+	}{
+		{"remove more than held", func(c *ShipCargo) error { return c.Remove("IRON_ORE", 11) }},
+		{"remove an item not held", func(c *ShipCargo) error { return c.Remove("FUEL", 1) }},
+		{"remove nothing", func(c *ShipCargo) error { return c.Remove("IRON_ORE", 0) }},
+		{"add beyond capacity", func(c *ShipCargo) error { return c.Add("IRON_ORE", 16) }},
+		{"add a negative amount", func(c *ShipCargo) error { return c.Add("IRON_ORE", -1) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := hold()
+			if err := tt.change(&c); err == nil {
+				t.Fatal("the change was accepted")
+			}
+			if !reflect.DeepEqual(c, hold()) {
+				t.Fatalf("cargo = %+v after a rejected change, want it untouched", c)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package model
+
+import "time"
+
+// Ship components whose condition wears down with use.
+const (
+	ComponentFrame   = "FRAME"
+	ComponentReactor = "REACTOR"
+	ComponentEngine  = "ENGINE"
+)
+
+// Levels of a degraded component's condition.
+const (
+	ConditionWarn     = "WARN"
+	ConditionCritical = "CRITICAL"
+)
+
+// FullCondition is the condition of a component in perfect repair.
+const FullCondition = 100
+
+// ComponentCondition is the condition of one of a ship's components, out of FullCondition.
+type ComponentCondition struct {
+	Component string `json:"component"`
+	Condition int    `json:"condition"`
+}
+
+// Conditions returns the condition of the ship's frame, reactor, and engine. Components the ship was reported
+// without are left out.
+func (s Ship) Conditions() []ComponentCondition {
+	var conditions []ComponentCondition
+	if s.Frame.Symbol != "" {
+		conditions = append(conditions, ComponentCondition{Component: ComponentFrame, Condition: s.Frame.Condition})
+	}
+	if s.Reactor.Symbol != "" {
+		conditions = append(conditions, ComponentCondition{Component: ComponentReactor, Condition: s.Reactor.Condition})
+	}
+	if s.Engine.Symbol != "" {
+		conditions = append(conditions, ComponentCondition{Component: ComponentEngine, Condition: s.Engine.Condition})
+	}
+
+	return conditions
+}
+
+// ConditionDegraded is a ship's component dropping below a condition threshold.
+type ConditionDegraded struct {
+	Ship      string `json:"ship"`
+	Component string `json:"component"`
+	Condition int    `json:"condition"`
+	// Level is the threshold crossed, ConditionWarn or ConditionCritical, and Threshold its value.
+	Level     string `json:"level"`
+	Threshold int    `json:"threshold"`
+}
+
+// RepairTransaction is the payment for repairing a ship at a shipyard.
+type RepairTransaction struct {
+	WaypointSymbol string    `json:"waypointSymbol"`
+	ShipSymbol     string    `json:"shipSymbol"`
+	TotalPrice     int64     `json:"totalPrice"`
+	Timestamp      time.Time `json:"timestamp"`
+}
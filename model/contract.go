@@ -0,0 +1,141 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// IsExpired checks if the contract can no longer be completed at a given time.
+// Unaccepted contracts expire at Expiration; all unfulfilled contracts expire at Terms.Deadline.
+func (c *Contract) IsExpired(now time.Time) bool {
+	if c.Fulfilled {
+		return false
+	}
+
+	if !c.Terms.Deadline.IsZero() && now.After(c.Terms.Deadline) {
+		return true
+	}
+
+	return !c.Accepted && !c.Expiration.IsZero() && now.After(c.Expiration)
+}
+
+// RemainingUnits returns the units still to be delivered, keyed by trade symbol.
+// Deliverables that are already fulfilled are omitted.
+func (c *Contract) RemainingUnits() map[string]int {
+	remaining := make(map[string]int)
+
+	for _, good := range c.Terms.Deliver {
+		if units := good.UnitsRequired - good.UnitsFulfilled; units > 0 {
+			remaining[good.TradeSymbol] += units
+		}
+	}
+
+	return remaining
+}
+
+// TotalPayment returns the sum of the acceptance and fulfillment payments.
+func (c *Contract) TotalPayment() int64 {
+	return c.Terms.Payment.OnAccepted + c.Terms.Payment.OnFulfilled
+}
+
+// NeededGoods returns the trade symbols that still require delivery, in the order of the contract terms.
+func (c *Contract) NeededGoods() []string {
+	var goods []string
+
+	for _, good := range c.Terms.Deliver {
+		if good.UnitsFulfilled >= good.UnitsRequired {
+			continue
+		}
+
+		seen := false
+		for _, g := range goods {
+			if g == good.TradeSymbol {
+				seen = true
+				break
+			}
+		}
+
+		if !seen {
+			goods = append(goods, good.TradeSymbol)
+		}
+	}
+
+	return goods
+}
+
+// IsDeliverComplete checks if every deliverable of the contract has been fulfilled.
+func (c *Contract) IsDeliverComplete() bool {
+	return len(c.RemainingUnits()) == 0
+}
+
+// String returns a concise, single-line representation of the contract.
+func (c Contract) String() string {
+	goods := make([]string, 0, len(c.Terms.Deliver))
+	for _, good := range c.Terms.Deliver {
+		goods = append(goods, fmt.Sprintf("%s %d/%d -> %s", good.TradeSymbol, good.UnitsFulfilled, good.UnitsRequired, good.DestinationSymbol))
+	}
+
+	return fmt.Sprintf("%s %s for %s: %s, %s, due %s",
+		c.ID, c.Type, c.FactionSymbol, strings.Join(goods, ", "), c.Status(), c.Terms.Deadline.Format(time.RFC3339))
+}
+
+// LogValues returns key/value pairs describing the contract, suitable for structured log fields.
+func (c Contract) LogValues() []interface{} {
+	return []interface{}{
+		"contract", c.ID,
+		"type", c.Type,
+		"faction", c.FactionSymbol,
+		"status", c.Status(),
+		"goods", strings.Join(c.NeededGoods(), ","),
+		"payment", c.TotalPayment(),
+		"deadline", c.Terms.Deadline.Format(time.RFC3339),
+	}
+}
+
+// Status returns a single word describing the contract's progress: offered, accepted, or fulfilled.
+func (c *Contract) Status() string {
+	switch {
+	case c.Fulfilled:
+		return "fulfilled"
+	case c.Accepted:
+		return "accepted"
+	default:
+		return "offered"
+	}
+}
+
+// Clone returns a deep copy of the contract, sharing no slices with it.
+func (c Contract) Clone() Contract {
+	c.Terms.Deliver = append([]ContractDeliverGood(nil), c.Terms.Deliver...)
+
+	return c
+}
+
+// Completion returns the share of the contract's required units that have been delivered, from 0 to 1. A
+// contract requiring nothing is complete.
+func (c *Contract) Completion() float64 {
+	var required, fulfilled int
+	for _, good := range c.Terms.Deliver {
+		required += good.UnitsRequired
+		fulfilled += good.UnitsFulfilled
+	}
+
+	if required == 0 {
+		return 1
+	}
+
+	return float64(fulfilled) / float64(required)
+}
+
+// ContractProgress is how an accepted contract stands against its deadline.
+type ContractProgress struct {
+	ContractID string    `json:"contractId"`
+	Deadline   time.Time `json:"deadline"`
+	// Remaining is the time left until the deadline when the progress was taken.
+	Remaining time.Duration `json:"remaining"`
+	// Completion is the share of the required units delivered, from 0 to 1.
+	Completion float64 `json:"completion"`
+	// OnTrack is whether the contract's delivery rate so far finishes it before the deadline.
+	OnTrack bool `json:"onTrack"`
+}
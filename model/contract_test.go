@@ -0,0 +1,78 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// procurement returns an accepted contract delivering iron ore twice over to two destinations, and copper ore.
+func procurement() Contract {
+	return Contract{
+		ID:       "contract-1",
+		Accepted: true,
+		Terms: ContractTerms{
+			Deadline: now.Add(time.Hour),
+			Payment:  ContractPayment{OnAccepted: 10000, OnFulfilled: 50000},
+			Deliver: []ContractDeliverGood{
+				{TradeSymbol: "IRON_ORE", DestinationSymbol: "X1-MK1-A1", UnitsRequired: 100, UnitsFulfilled: 40},
+				{TradeSymbol: "COPPER_ORE", DestinationSymbol: "X1-MK1-A1", UnitsRequired: 50, UnitsFulfilled: 50},
+				{TradeSymbol: "IRON_ORE", DestinationSymbol: "X1-MK1-C3", UnitsRequired: 20},
+			},
+		},
+		Expiration: now.Add(-time.Hour),
+	}
+}
+
+func TestContractRemainingUnitsAndNeededGoods(t *testing.T) {
+	c := procurement()
+
+	if got, want := c.RemainingUnits(), map[string]int{"IRON_ORE": 80}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RemainingUnits = %v, want %v", got, want)
+	}
+	if got, want := c.NeededGoods(), []string{"IRON_ORE"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NeededGoods = %v, want %v", got, want)
+	}
+	if c.IsDeliverComplete() {
+		t.Error("a contract with ore still to deliver reads complete")
+	}
+	if got := c.TotalPayment(); got != 60000 {
+		t.Errorf("TotalPayment = %d, want 60000", got)
+	}
+
+	c.Terms.Deliver[0].UnitsFulfilled = 100
+	c.Terms.Deliver[2].UnitsFulfilled = 20
+	if len(c.RemainingUnits()) != 0 || c.NeededGoods() != nil || !c.IsDeliverComplete() {
+		t.Errorf("fully delivered contract: remaining %v, needed %v", c.RemainingUnits(), c.NeededGoods())
+	}
+}
+
+func TestContractIsExpired(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(c *Contract)
+		at      time.Time
+		expired bool
+	}{
+		{"accepted before the deadline", func(c *Contract) {}, now, false},
+		// An accepted contract's offer expiration no longer applies.
+		{"accepted past its offer expiration", func(c *Contract) {}, now.Add(30 * time.Minute), false},
+		{"accepted past the deadline", func(c *Contract) {}, now.Add(2 * time.Hour), true},
+		{"offer past its expiration", func(c *Contract) { c.Accepted = false }, now, true},
+		{"offer before its expiration", func(c *Contract) { c.Accepted = false; c.Expiration = now.Add(time.Minute) }, now, false},
+		{"fulfilled past the deadline", func(c *Contract) { c.Fulfilled = true }, now.Add(2 * time.Hour), false},
+		{"fulfilled before the deadline", func(c *Contract) { c.Fulfilled = true }, now, false},
+		{"no deadline", func(c *Contract) { c.Terms.Deadline = time.Time{} }, now.Add(24 * time.Hour), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := procurement()
+			tt.edit(&c)
+			if got := c.IsExpired(tt.at); got != tt.expired {
+				t.Errorf("IsExpired = %t, want %t", got, tt.expired)
+			}
+		})
+	}
+}
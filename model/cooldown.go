@@ -0,0 +1,54 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// Remaining returns how long until the cooldown expires at a given time.
+// The server-reported RemainingSeconds is preferred when the cooldown was stamped with FetchedAt on receipt,
+// since it is immune to skew between the local and server clocks. Otherwise Expiration is used.
+func (c *Cooldown) Remaining(now time.Time) time.Duration {
+	var remaining time.Duration
+
+	switch {
+	case c.RemainingSeconds > 0 && !c.FetchedAt.IsZero():
+		elapsed := now.Sub(c.FetchedAt)
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		remaining = time.Duration(c.RemainingSeconds)*time.Second - elapsed
+	case !c.Expiration.IsZero():
+		remaining = c.Expiration.Sub(now)
+	}
+
+	if remaining < 0 {
+		return 0
+	}
+
+	return remaining
+}
+
+// Ready checks if the cooldown has expired at a given time.
+func (c *Cooldown) Ready(now time.Time) bool {
+	return c.Remaining(now) <= 0
+}
+
+// String returns a concise, single-line representation of the cooldown.
+func (c Cooldown) String() string {
+	if c.Expiration.IsZero() {
+		return fmt.Sprintf("%s no cooldown", c.ShipSymbol)
+	}
+
+	return fmt.Sprintf("%s cooldown %ds/%ds until %s", c.ShipSymbol, c.RemainingSeconds, c.TotalSeconds, c.Expiration.Format(time.RFC3339))
+}
+
+// LogValues returns key/value pairs describing the cooldown, suitable for structured log fields.
+func (c Cooldown) LogValues() []interface{} {
+	return []interface{}{
+		"ship", c.ShipSymbol,
+		"remainingSeconds", c.RemainingSeconds,
+		"totalSeconds", c.TotalSeconds,
+		"expiration", c.Expiration.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCooldownRemainingUnderSkew(t *testing.T) {
+	server := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		// skew is how far the local clock is ahead of the server's.
+		skew    time.Duration
+		elapsed time.Duration
+		want    time.Duration
+	}{
+		{"in sync", 0, 10 * time.Second, 50 * time.Second},
+		{"local ahead", 5 * time.Minute, 10 * time.Second, 50 * time.Second},
+		{"local behind", -5 * time.Minute, 10 * time.Second, 50 * time.Second},
+		{"expired", 5 * time.Minute, 2 * time.Minute, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetchedAt := server.Add(tt.skew)
+			c := Cooldown{
+				RemainingSeconds: 60,
+				Expiration:       OptionalTime{Time: server.Add(time.Minute)},
+				FetchedAt:        fetchedAt,
+			}
+
+			if got := c.Remaining(fetchedAt.Add(tt.elapsed)); got != tt.want {
+				t.Errorf("Remaining = %s, want %s", got, tt.want)
+			}
+			if ready := c.Ready(fetchedAt.Add(tt.elapsed)); ready != (tt.want == 0) {
+				t.Errorf("Ready = %t, want %t", ready, tt.want == 0)
+			}
+		})
+	}
+}
+
+func TestCooldownRemainingFallsBackToExpiration(t *testing.T) {
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := Cooldown{RemainingSeconds: 60, Expiration: OptionalTime{Time: now.Add(30 * time.Second)}}
+
+	if got := c.Remaining(now); got != 30*time.Second {
+		t.Errorf("Remaining without FetchedAt = %s, want 30s", got)
+	}
+}
@@ -0,0 +1,25 @@
+package model
+
+// Credits is an amount of agent credits.
+// It is an int64 so that long-lived agents cannot overflow on 32-bit builds.
+type Credits int64
+
+// Afford checks if a cost can be paid while keeping at least reserve credits in the account.
+func (c Credits) Afford(cost int64, reserve int64) bool {
+	if cost < 0 {
+		return false
+	}
+
+	return int64(c)-cost >= reserve
+}
+
+// CreditDrift is how far the credits the bot tracked had drifted from the agent's actual balance.
+type CreditDrift struct {
+	Tracked Credits `json:"tracked"`
+	Actual  Credits `json:"actual"`
+}
+
+// Drift returns the actual balance less the tracked one.
+func (d CreditDrift) Drift() int64 {
+	return int64(d.Actual - d.Tracked)
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestCreditsAfford(t *testing.T) {
+	tests := []struct {
+		name    string
+		credits Credits
+		cost    int64
+		reserve int64
+		want    bool
+	}{
+		{"plenty", 100000, 20000, 0, true},
+		{"exactly", 20000, 20000, 0, true},
+		{"one short", 19999, 20000, 0, false},
+		{"within the reserve", 100000, 90000, 20000, false},
+		{"down to the reserve", 100000, 80000, 20000, true},
+		{"free", 0, 0, 0, true},
+		{"negative cost", 100000, -1, 0, false},
+		{"already overdrawn", -500, 0, 0, false},
+		{"beyond 32 bits", 5000000000, 4000000000, 0, true},
+		{"largest balance", math.MaxInt64, math.MaxInt64, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.credits.Afford(tt.cost, tt.reserve); got != tt.want {
+				t.Errorf("%d.Afford(%d, %d) = %t, want %t", tt.credits, tt.cost, tt.reserve, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonetaryFieldsDecodeBeyond32Bits(t *testing.T) {
+	var agent Agent
+	if err := json.Unmarshal([]byte(`{"symbol":"MOCK","credits":9007199254740993}`), &agent); err != nil {
+		t.Fatal(err)
+	}
+	if agent.Credits != 9007199254740993 {
+		t.Errorf("credits = %d, want 9007199254740993", agent.Credits)
+	}
+
+	var contract Contract
+	if err := json.Unmarshal([]byte(`{"terms":{"payment":{"onAccepted":3000000000,"onFulfilled":4000000000}}}`), &contract); err != nil {
+		t.Fatal(err)
+	}
+	if got := contract.TotalPayment(); got != 7000000000 {
+		t.Errorf("total payment = %d, want 7000000000", got)
+	}
+
+	var transaction MarketTransaction
+	if err := json.Unmarshal([]byte(`{"units":1,"pricePerUnit":2500000000,"totalPrice":2500000000}`), &transaction); err != nil {
+		t.Fatal(err)
+	}
+	if transaction.TotalPrice != 2500000000 {
+		t.Errorf("total price = %d, want 2500000000", transaction.TotalPrice)
+	}
+}
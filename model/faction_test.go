@@ -0,0 +1,29 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFactionDecodes(t *testing.T) {
+	body := `{
+		"symbol": "COSMIC",
+		"name": "Cosmic Engineers",
+		"description": "Pioneers of the stars.",
+		"headquarters": "X1-DF55",
+		"traits": [{"symbol": "INNOVATIVE", "name": "Innovative", "description": "Eager to try new things."}],
+		"isRecruiting": true
+	}`
+
+	var faction Faction
+	if err := json.Unmarshal([]byte(body), &faction); err != nil {
+		t.Fatal(err)
+	}
+
+	if faction.Symbol != "COSMIC" || faction.Headquarters != "X1-DF55" || !faction.IsRecruiting {
+		t.Errorf("faction = %+v", faction)
+	}
+	if len(faction.Traits) != 1 || faction.Traits[0].Symbol != "INNOVATIVE" {
+		t.Errorf("traits = %+v", faction.Traits)
+	}
+}
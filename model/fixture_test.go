@@ -0,0 +1,255 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// decodeFixture decodes the data of an API response recorded in testdata into v.
+func decodeFixture(t *testing.T, name string, v interface{}) json.RawMessage {
+	t.Helper()
+
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(envelope.Data, v); err != nil {
+		t.Fatalf("decoding %s: %s", name, err)
+	}
+
+	return envelope.Data
+}
+
+func TestDecodeShipFixture(t *testing.T) {
+	var ship Ship
+	decodeFixture(t, "ship.json", &ship)
+
+	if err := ship.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if ship.Symbol != "GOGARIN-2" || ship.Registration.Role != "EXCAVATOR" {
+		t.Errorf("ship %s %s, want GOGARIN-2 EXCAVATOR", ship.Symbol, ship.Registration.Role)
+	}
+	if ship.Nav.Status != "IN_ORBIT" || ship.Nav.WaypointSymbol != "X1-DF55-17335A" || ship.Nav.Route.Departure.Symbol != "X1-DF55-20250Z" {
+		t.Errorf("nav = %+v", ship.Nav)
+	}
+	if want := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC); !ship.Nav.Route.Arrival.Equal(want) {
+		t.Errorf("arrival = %s, want %s", ship.Nav.Route.Arrival, want)
+	}
+	if ship.Frame.FuelCapacity != 100 || ship.Reactor.PowerOutput != 15 || ship.Engine.Speed != 2 || ship.Frame.Requirements.Crew != -3 {
+		t.Errorf("frame, reactor, engine = %+v, %+v, %+v", ship.Frame, ship.Reactor, ship.Engine)
+	}
+	if len(ship.Modules) != 1 || ship.Modules[0].Capacity != 30 || len(ship.Mounts) != 1 || ship.Mounts[0].Strength != 10 {
+		t.Errorf("modules %+v, mounts %+v", ship.Modules, ship.Mounts)
+	}
+	if ship.Cargo.UnitsOf("IRON_ORE") != 10 || ship.Cargo.Units != 12 {
+		t.Errorf("cargo = %s", ship.Cargo)
+	}
+	if ship.Fuel.Current != 98 || ship.Fuel.Consumed.Amount != 2 || !ship.Fuel.Consumed.Timestamp.IsSet() {
+		t.Errorf("fuel = %+v", ship.Fuel)
+	}
+}
+
+func TestDecodeWaypointFixture(t *testing.T) {
+	var waypoint Waypoint
+	decodeFixture(t, "waypoint.json", &waypoint)
+
+	if err := waypoint.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if waypoint.Type != "ASTEROID_FIELD" || waypoint.SystemSymbol != "X1-DF55" || waypoint.X != -21 || waypoint.Y != 8 {
+		t.Errorf("waypoint = %s in %s", waypoint, waypoint.SystemSymbol)
+	}
+	if len(waypoint.Orbitals) != 1 || waypoint.Orbitals[0].Symbol != "X1-DF55-17335B" || waypoint.Faction.Symbol != "COSMIC" {
+		t.Errorf("orbitals %+v, faction %+v", waypoint.Orbitals, waypoint.Faction)
+	}
+	if !waypoint.HasTrait("COMMON_METAL_DEPOSITS") || !waypoint.HasTrait("MARKETPLACE") {
+		t.Errorf("traits = %+v", waypoint.Traits)
+	}
+	if waypoint.Chart.SubmittedBy != "COSMIC" || !waypoint.Chart.SubmittedOn.IsSet() {
+		t.Errorf("chart = %+v", waypoint.Chart)
+	}
+}
+
+func TestDecodeContractFixture(t *testing.T) {
+	var contract Contract
+	decodeFixture(t, "contract.json", &contract)
+
+	if err := contract.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if contract.Type != "PROCUREMENT" || !contract.Accepted || contract.Fulfilled {
+		t.Errorf("contract = %s", contract)
+	}
+	if got := contract.TotalPayment(); got != 91260 {
+		t.Errorf("total payment = %d, want 91260", got)
+	}
+	if got, want := contract.RemainingUnits(), map[string]int{"IRON_ORE": 42}; !reflect.DeepEqual(got, want) {
+		t.Errorf("remaining = %v, want %v", got, want)
+	}
+	if want := time.Date(2030, 1, 8, 12, 0, 0, 0, time.UTC); !contract.Terms.Deadline.Equal(want) {
+		t.Errorf("deadline = %s, want %s", contract.Terms.Deadline, want)
+	}
+}
+
+func TestDecodeMarketFixture(t *testing.T) {
+	var market Market
+	decodeFixture(t, "market.json", &market)
+
+	if err := market.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if !market.ImportsGood("IRON_ORE") || !market.ImportsGood("FUEL") || len(market.Exports) != 1 {
+		t.Errorf("imports %+v, exports %+v, exchange %+v", market.Imports, market.Exports, market.Exchange)
+	}
+	if price, ok := market.SellPriceOf("IRON_ORE"); !ok || price != 45 {
+		t.Errorf("iron ore sells for %d, want 45", price)
+	}
+	if len(market.TradeGoods) != 2 || market.TradeGoods[1].Supply != "ABUNDANT" {
+		t.Errorf("trade goods = %+v", market.TradeGoods)
+	}
+	if len(market.Transactions) != 1 || market.Transactions[0].TotalPrice != 1350 || market.Transactions[0].Type != "SELL" {
+		t.Errorf("transactions = %+v", market.Transactions)
+	}
+	for _, good := range market.TradeGoods {
+		if good.Type != "" || good.Activity != "" {
+			t.Errorf("%s: type %q, activity %q decoded from a payload without them", good.Symbol, good.Type, good.Activity)
+		}
+	}
+	for symbol, want := range map[string]string{"IRON": TradeTypeExport, "IRON_ORE": TradeTypeImport, "FUEL": TradeTypeExchange} {
+		if got := market.TradeTypeOf(symbol); got != want {
+			t.Errorf("TradeTypeOf(%s) = %q, want %q from the market's listings", symbol, got, want)
+		}
+	}
+}
+
+func TestDecodeTypedMarketFixture(t *testing.T) {
+	var market Market
+	decodeFixture(t, "market_typed.json", &market)
+
+	if err := market.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	want := []MarketTradeGood{
+		{Symbol: "IRON", Type: TradeTypeExport, TradeVolume: 60, Supply: "HIGH", Activity: "GROWING", PurchasePrice: 110, SellPrice: 98},
+		{Symbol: "IRON_ORE", Type: TradeTypeImport, TradeVolume: 100, Supply: "SCARCE", Activity: "STRONG", PurchasePrice: 52, SellPrice: 45},
+		{Symbol: "FUEL", Type: TradeTypeExchange, TradeVolume: 100, Supply: "ABUNDANT", PurchasePrice: 72, SellPrice: 68},
+	}
+	if !reflect.DeepEqual(market.TradeGoods, want) {
+		t.Errorf("trade goods = %+v, want %+v", market.TradeGoods, want)
+	}
+	if got := market.TradeTypeOf("IRON"); got != TradeTypeExport {
+		t.Errorf("TradeTypeOf(IRON) = %q, want %q", got, TradeTypeExport)
+	}
+	if got := market.TradeTypeOf("GOLD_ORE"); got != "" {
+		t.Errorf("TradeTypeOf(GOLD_ORE) = %q for a good the market does not list", got)
+	}
+}
+
+// subset fails the test if any field of encoded is missing from, or differs in, recorded. A field the model
+// encodes under a misspelled tag is missing from the recorded payload; one it fails to decode differs.
+func subset(t *testing.T, path string, encoded, recorded interface{}) {
+	t.Helper()
+
+	switch e := encoded.(type) {
+	case map[string]interface{}:
+		r, ok := recorded.(map[string]interface{})
+		if !ok {
+			t.Errorf("%s: encoded an object, recorded %v", path, recorded)
+			return
+		}
+		for key, value := range e {
+			if _, ok := r[key]; !ok {
+				t.Errorf("%s.%s: encoded but not in the recorded payload", path, key)
+				continue
+			}
+			subset(t, path+"."+key, value, r[key])
+		}
+	case []interface{}:
+		r, ok := recorded.([]interface{})
+		if !ok || len(r) != len(e) {
+			t.Errorf("%s: encoded %v, recorded %v", path, e, recorded)
+			return
+		}
+		for i := range e {
+			subset(t, fmt.Sprintf("%s[%d]", path, i), e[i], r[i])
+		}
+	default:
+		if !reflect.DeepEqual(encoded, recorded) {
+			t.Errorf("%s: encoded %v, recorded %v", path, encoded, recorded)
+		}
+	}
+}
+
+func TestFixturesRoundTrip(t *testing.T) {
+	tests := []struct {
+		fixture string
+		model   interface{}
+	}{
+		{"ship.json", &Ship{}},
+		{"waypoint.json", &Waypoint{}},
+		{"contract.json", &Contract{}},
+		{"market.json", &Market{}},
+		{"market_typed.json", &Market{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			data := decodeFixture(t, tt.fixture, tt.model)
+
+			encoded, err := json.Marshal(tt.model)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var e, r interface{}
+			if err := json.Unmarshal(encoded, &e); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(data, &r); err != nil {
+				t.Fatal(err)
+			}
+			subset(t, "data", e, r)
+		})
+	}
+}
+
+func TestValidateReportsMissingFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		model   interface{ Validate() error }
+		mutate  func(v interface{})
+	}{
+		{"ship without role", "ship.json", &Ship{}, func(v interface{}) { v.(*Ship).Registration.Role = "" }},
+		{"ship without nav", "ship.json", &Ship{}, func(v interface{}) { v.(*Ship).Nav.WaypointSymbol = "" }},
+		{"ship with cargo units off its inventory", "ship.json", &Ship{}, func(v interface{}) { v.(*Ship).Cargo.Units = 11 }},
+		{"ship with cargo beyond capacity", "ship.json", &Ship{}, func(v interface{}) { v.(*Ship).Cargo.Capacity = 10 }},
+		{"waypoint without type", "waypoint.json", &Waypoint{}, func(v interface{}) { v.(*Waypoint).Type = "" }},
+		{"waypoint without system", "waypoint.json", &Waypoint{}, func(v interface{}) { v.(*Waypoint).SystemSymbol = "" }},
+		{"contract without deadline", "contract.json", &Contract{}, func(v interface{}) { v.(*Contract).Terms.Deadline = time.Time{} }},
+		{"contract without destination", "contract.json", &Contract{}, func(v interface{}) { v.(*Contract).Terms.Deliver[0].DestinationSymbol = "" }},
+		{"market without symbol", "market.json", &Market{}, func(v interface{}) { v.(*Market).Symbol = "" }},
+		{"market with a nameless good", "market.json", &Market{}, func(v interface{}) { v.(*Market).TradeGoods[0].Symbol = "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decodeFixture(t, tt.fixture, tt.model)
+			tt.mutate(tt.model)
+			if err := tt.model.Validate(); err == nil {
+				t.Fatal("Validate accepted it")
+			}
+		})
+	}
+}
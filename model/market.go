@@ -0,0 +1,116 @@
+package model
+
+// How a market trades a good.
+const (
+	TradeTypeExport   = "EXPORT"
+	TradeTypeImport   = "IMPORT"
+	TradeTypeExchange = "EXCHANGE"
+)
+
+// Supply levels of a good at a market, from scarcest to most plentiful.
+const (
+	SupplyScarce   = "SCARCE"
+	SupplyLimited  = "LIMITED"
+	SupplyModerate = "MODERATE"
+	SupplyHigh     = "HIGH"
+	SupplyAbundant = "ABUNDANT"
+)
+
+// ImportsGood checks if the market buys a given trade symbol, either as an import or on the exchange.
+func (mk *Market) ImportsGood(symbol string) bool {
+	for _, good := range mk.Imports {
+		if good.Symbol == symbol {
+			return true
+		}
+	}
+
+	for _, good := range mk.Exchange {
+		if good.Symbol == symbol {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TradeTypeOf returns how the market trades a good: TradeTypeExport, TradeTypeImport, or TradeTypeExchange.
+// Markets observed from older API versions, whose trade goods carry no type, fall back to the market's
+// export, import, and exchange listings. An empty string means the market does not list the good.
+func (mk *Market) TradeTypeOf(symbol string) string {
+	if good, ok := mk.tradeGood(symbol); ok && good.Type != "" {
+		return good.Type
+	}
+
+	listings := []struct {
+		tradeType string
+		goods     []TradeGood
+	}{
+		{TradeTypeExport, mk.Exports},
+		{TradeTypeImport, mk.Imports},
+		{TradeTypeExchange, mk.Exchange},
+	}
+	for _, listing := range listings {
+		for _, good := range listing.goods {
+			if good.Symbol == symbol {
+				return listing.tradeType
+			}
+		}
+	}
+
+	return ""
+}
+
+// SellPriceOf returns the price the market pays per unit of a trade symbol.
+// It reports false when no trade good data is available, e.g. when no ship is present at the market.
+func (mk *Market) SellPriceOf(symbol string) (int64, bool) {
+	good, ok := mk.tradeGood(symbol)
+	if !ok {
+		return 0, false
+	}
+
+	return good.SellPrice, true
+}
+
+// PurchasePriceOf returns the price the market charges per unit of a trade symbol.
+// It reports false when no trade good data is available, e.g. when no ship is present at the market.
+func (mk *Market) PurchasePriceOf(symbol string) (int64, bool) {
+	good, ok := mk.tradeGood(symbol)
+	if !ok {
+		return 0, false
+	}
+
+	return good.PurchasePrice, true
+}
+
+// TradeVolumeOf returns the trade volume of a trade symbol at the market.
+// It reports false when no trade good data is available, e.g. when no ship is present at the market.
+func (mk *Market) TradeVolumeOf(symbol string) (int, bool) {
+	good, ok := mk.tradeGood(symbol)
+	if !ok {
+		return 0, false
+	}
+
+	return good.TradeVolume, true
+}
+
+// SupplyOf returns the supply level of a trade symbol at the market, e.g. SupplyScarce.
+// It reports false when no trade good data is available, e.g. when no ship is present at the market.
+func (mk *Market) SupplyOf(symbol string) (string, bool) {
+	good, ok := mk.tradeGood(symbol)
+	if !ok {
+		return "", false
+	}
+
+	return good.Supply, true
+}
+
+// tradeGood looks up the live trade good data for a trade symbol.
+func (mk *Market) tradeGood(symbol string) (*MarketTradeGood, bool) {
+	for i := range mk.TradeGoods {
+		if mk.TradeGoods[i].Symbol == symbol {
+			return &mk.TradeGoods[i], true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,62 @@
+package model
+
+import "testing"
+
+// ironMarket returns a market importing iron ore and exchanging fuel, as seen with a ship present.
+func ironMarket() Market {
+	return Market{
+		Symbol:   "X1-MK1-A1",
+		Imports:  []TradeGood{{Symbol: "IRON_ORE"}},
+		Exports:  []TradeGood{{Symbol: "IRON"}},
+		Exchange: []TradeGood{{Symbol: "FUEL"}},
+		TradeGoods: []MarketTradeGood{
+			{Symbol: "IRON_ORE", TradeVolume: 60, PurchasePrice: 50, SellPrice: 45},
+			{Symbol: "FUEL", TradeVolume: 100, PurchasePrice: 80, SellPrice: 75},
+		},
+	}
+}
+
+func TestMarketImportsGood(t *testing.T) {
+	mk := ironMarket()
+
+	for symbol, want := range map[string]bool{"IRON_ORE": true, "FUEL": true, "IRON": false, "GOLD_ORE": false} {
+		if got := mk.ImportsGood(symbol); got != want {
+			t.Errorf("ImportsGood(%s) = %t, want %t", symbol, got, want)
+		}
+	}
+}
+
+func TestMarketTradeGoodLookups(t *testing.T) {
+	mk := ironMarket()
+
+	if price, ok := mk.SellPriceOf("IRON_ORE"); !ok || price != 45 {
+		t.Errorf("SellPriceOf(IRON_ORE) = %d, %t, want 45", price, ok)
+	}
+	if price, ok := mk.PurchasePriceOf("FUEL"); !ok || price != 80 {
+		t.Errorf("PurchasePriceOf(FUEL) = %d, %t, want 80", price, ok)
+	}
+	if volume, ok := mk.TradeVolumeOf("FUEL"); !ok || volume != 100 {
+		t.Errorf("TradeVolumeOf(FUEL) = %d, %t, want 100", volume, ok)
+	}
+	if _, ok := mk.SellPriceOf("IRON"); ok {
+		t.Error("a good without trade data has a sell price")
+	}
+}
+
+func TestMarketWithoutShipPresentHasNoPrices(t *testing.T) {
+	mk := ironMarket()
+	mk.TradeGoods = nil
+
+	if !mk.ImportsGood("IRON_ORE") {
+		t.Error("a market seen without a ship present no longer lists its imports")
+	}
+	if price, ok := mk.SellPriceOf("IRON_ORE"); ok {
+		t.Errorf("sell price %d without trade data, want none", price)
+	}
+	if price, ok := mk.PurchasePriceOf("IRON_ORE"); ok {
+		t.Errorf("purchase price %d without trade data, want none", price)
+	}
+	if volume, ok := mk.TradeVolumeOf("IRON_ORE"); ok {
+		t.Errorf("trade volume %d without trade data, want none", volume)
+	}
+}
@@ -3,16 +3,45 @@ package model
 import "time"
 
 type Agent struct {
-	AccountId    string `json:"accountId"`
-	Symbol       string `json:"symbol"`
-	Headquarters string `json:"headquarters"`
-	Credits      int    `json:"credits"`
+	AccountId    string  `json:"accountId"`
+	Symbol       string  `json:"symbol"`
+	Headquarters string  `json:"headquarters"`
+	Credits      Credits `json:"credits"`
+}
+
+type Status struct {
+	Status       string             `json:"status"`
+	Version      string             `json:"version"`
+	ResetDate    string             `json:"resetDate"`
+	Description  string             `json:"description"`
+	Stats        StatusStats        `json:"stats"`
+	ServerResets StatusServerResets `json:"serverResets"`
+}
+
+type StatusStats struct {
+	Agents    int `json:"agents"`
+	Ships     int `json:"ships"`
+	Systems   int `json:"systems"`
+	Waypoints int `json:"waypoints"`
+}
+
+type StatusServerResets struct {
+	Next      time.Time `json:"next"`
+	Frequency string    `json:"frequency"`
+}
+
+type AgentEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data"`
+	CreatedAt time.Time   `json:"createdAt"`
 }
 
 type Chart struct {
-	WaypointSymbol string    `json:"waypointSymbol"`
-	SubmittedBy    string    `json:"submittedBy"`
-	SubmittedOn    time.Time `json:"submittedOn"`
+	WaypointSymbol string       `json:"waypointSymbol"`
+	SubmittedBy    string       `json:"submittedBy"`
+	SubmittedOn    OptionalTime `json:"submittedOn"`
 }
 
 type ConnectedSystem struct {
@@ -43,8 +72,8 @@ type ContractDeliverGood struct {
 }
 
 type ContractPayment struct {
-	OnAccepted  int `json:"onAccepted"`
-	OnFulfilled int `json:"onFulfilled"`
+	OnAccepted  int64 `json:"onAccepted"`
+	OnFulfilled int64 `json:"onFulfilled"`
 }
 
 type ContractTerms struct {
@@ -54,10 +83,11 @@ type ContractTerms struct {
 }
 
 type Cooldown struct {
-	ShipSymbol       string    `json:"shipSymbol"`
-	TotalSeconds     int       `json:"totalSeconds"`
-	RemainingSeconds int       `json:"remainingSeconds"`
-	Expiration       time.Time `json:"expiration"`
+	ShipSymbol       string       `json:"shipSymbol"`
+	TotalSeconds     int          `json:"totalSeconds"`
+	RemainingSeconds int          `json:"remainingSeconds"`
+	Expiration       OptionalTime `json:"expiration"`
+	FetchedAt        time.Time    `json:"-"`
 }
 
 type Extraction struct {
@@ -68,6 +98,21 @@ type Extraction struct {
 	} `json:"yield"`
 }
 
+type Faction struct {
+	Symbol       string         `json:"symbol"`
+	Name         string         `json:"name"`
+	Description  string         `json:"description"`
+	Headquarters string         `json:"headquarters"`
+	Traits       []FactionTrait `json:"traits"`
+	IsRecruiting bool           `json:"isRecruiting"`
+}
+
+type FactionTrait struct {
+	Symbol      string `json:"symbol"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
 type JumpGate struct {
 	JumpRange        int               `json:"jumpRange"`
 	FactionSymbol    string            `json:"factionSymbol"`
@@ -84,11 +129,20 @@ type Market struct {
 }
 
 type MarketTradeGood struct {
-	Symbol        string `json:"symbol"`
-	TradeVolume   int    `json:"tradeVolume"`
-	Supply        string `json:"supply"`
-	PurchasePrice int    `json:"purchasePrice"`
-	SellPrice     int    `json:"sellPrice"`
+	Symbol string `json:"symbol"`
+	// Type is how the market trades the good: EXPORT, IMPORT, or EXCHANGE. Older API versions leave it empty.
+	Type        string `json:"type,omitempty"`
+	TradeVolume int    `json:"tradeVolume"`
+	Supply      string `json:"supply"`
+	// Activity is how strongly the market's trade in the good is growing. Older API versions leave it empty.
+	Activity      string `json:"activity,omitempty"`
+	PurchasePrice int64  `json:"purchasePrice"`
+	SellPrice     int64  `json:"sellPrice"`
+}
+
+// SupplyChain maps each exported good to the goods a market must import to produce it.
+type SupplyChain struct {
+	ExportToImportMap map[string][]string `json:"exportToImportMap"`
 }
 
 type MarketTransaction struct {
@@ -97,8 +151,8 @@ type MarketTransaction struct {
 	TradeSymbol    string    `json:"tradeSymbol"`
 	Type           string    `json:"type"`
 	Units          int       `json:"units"`
-	PricePerUnit   int       `json:"pricePerUnit"`
-	TotalPrice     int       `json:"totalPrice"`
+	PricePerUnit   int64     `json:"pricePerUnit"`
+	TotalPrice     int64     `json:"totalPrice"`
 	Timestamp      time.Time `json:"timestamp"`
 }
 
@@ -151,8 +205,8 @@ type ShipFuel struct {
 	Current  int `json:"current"`
 	Capacity int `json:"capacity"`
 	Consumed struct {
-		Amount    int       `json:"amount"`
-		Timestamp time.Time `json:"timestamp"`
+		Amount    int          `json:"amount"`
+		Timestamp OptionalTime `json:"timestamp"`
 	} `json:"consumed"`
 }
 
@@ -244,7 +298,7 @@ type ShipyardShip struct {
 	Type          string       `json:"type"`
 	Name          string       `json:"name"`
 	Description   string       `json:"description"`
-	PurchasePrice int          `json:"purchasePrice"`
+	PurchasePrice int64        `json:"purchasePrice"`
 	Frame         ShipFrame    `json:"frame"`
 	Reactor       ShipReactor  `json:"reactor"`
 	Engine        ShipEngine   `json:"engine"`
@@ -255,7 +309,8 @@ type ShipyardShip struct {
 type ShipyardTransaction struct {
 	WaypointSymbol string    `json:"waypointSymbol"`
 	ShipSymbol     string    `json:"shipSymbol"`
-	Price          int       `json:"price"`
+	ShipType       string    `json:"shipType"`
+	Price          int64     `json:"price"`
 	AgentSymbol    string    `json:"agentSymbol"`
 	Timestamp      time.Time `json:"timestamp"`
 }
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kinds of manual orders.
+const (
+	OrderNavigate = "NAVIGATE"
+	OrderDock     = "DOCK"
+	OrderOrbit    = "ORBIT"
+	OrderSell     = "SELL"
+	OrderHold     = "HOLD"
+	OrderResume   = "RESUME"
+)
+
+// ManualOrder is an operator's order to a ship. Any order but Resume takes the ship off automatic missions
+// until it is given a Resume order.
+type ManualOrder struct {
+	Kind string `json:"kind"`
+	// Waypoint is where a Navigate order sends the ship.
+	Waypoint string `json:"waypoint,omitempty"`
+	// Good is what a Sell order sells, and Units how many of it. Zero units sells all of the good aboard.
+	Good  string `json:"good,omitempty"`
+	Units int    `json:"units,omitempty"`
+}
+
+// String describes the order, e.g. "navigate to X1-AB12-C3" or "sell 10 IRON_ORE".
+func (o ManualOrder) String() string {
+	kind := strings.ToLower(o.Kind)
+
+	switch o.Kind {
+	case OrderNavigate:
+		return fmt.Sprintf("%s to %s", kind, o.Waypoint)
+	case OrderSell:
+		if o.Units == 0 {
+			return fmt.Sprintf("%s all %s", kind, o.Good)
+		}
+		return fmt.Sprintf("%s %d %s", kind, o.Units, o.Good)
+	default:
+		return kind
+	}
+}
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Plan kinds.
+const (
+	PlanTrade       = "trade"
+	PlanProcurement = "procurement"
+	PlanDelivery    = "delivery"
+	PlanRequisition = "requisition"
+)
+
+// Plan is what a multi-step mission expects to do and earn, worked out before it sets off. The same fields
+// tally what the mission actually did, so the two can be compared when it ends.
+type Plan struct {
+	Kind string `json:"kind"`
+	// Good is the trade good, goods, or ship type the plan deals in.
+	Good  string `json:"good"`
+	Units int    `json:"units"`
+	// Source is where the goods or ship are bought, if the plan buys any.
+	Source string `json:"source,omitempty"`
+	// Destination is where the goods are hauled to, if the plan hauls any.
+	Destination string `json:"destination,omitempty"`
+	// Cost is the purchase price, and Revenue what the sale or the contract pays.
+	Cost    int64 `json:"cost"`
+	Revenue int64 `json:"revenue"`
+	// Fuel is the fuel burned, and FuelCost the price of the fuel.
+	Fuel     int           `json:"fuel"`
+	FuelCost int64         `json:"fuelCost"`
+	Travel   time.Duration `json:"travel"`
+}
+
+// Profit returns the revenue less the purchase and the fuel.
+func (p Plan) Profit() int64 {
+	return p.Revenue - p.Cost - p.FuelCost
+}
+
+// Describe sums the plan up in one line, e.g. "buy 60 IRON_ORE at X1-A1 for ~3,000, haul to X1-B2 (~4 min,
+// 12 fuel), sell for ~5,100, expected profit ~2,000".
+func (p Plan) Describe() string {
+	leg := fmt.Sprintf("(%s, %d fuel)", approxTravel(p.Travel), p.Fuel)
+
+	if p.Kind == PlanRequisition {
+		return fmt.Sprintf("buy %s at %s for %s %s", p.Good, p.Source, approxCredits(p.Cost), leg)
+	}
+
+	var parts []string
+	if p.Source != "" {
+		parts = append(parts, fmt.Sprintf("buy %d %s at %s for %s", p.Units, p.Good, p.Source, approxCredits(p.Cost)))
+		if p.Destination != "" {
+			parts = append(parts, fmt.Sprintf("haul to %s %s", p.Destination, leg))
+		} else {
+			parts[0] += " " + leg
+		}
+	} else {
+		parts = append(parts, fmt.Sprintf("haul %d %s to %s %s", p.Units, p.Good, p.Destination, leg))
+	}
+
+	if p.Kind == PlanTrade {
+		parts = append(parts, "sell for "+approxCredits(p.Revenue))
+	} else {
+		parts = append(parts, "deliver for "+approxCredits(p.Revenue))
+	}
+
+	return strings.Join(append(parts, "expected profit "+approxCredits(p.Profit())), ", ")
+}
+
+// LogValues returns key/value pairs describing the plan, suitable for structured log fields.
+func (p Plan) LogValues() []interface{} {
+	return []interface{}{
+		"kind", p.Kind,
+		"good", p.Good,
+		"units", p.Units,
+		"source", p.Source,
+		"destination", p.Destination,
+		"cost", p.Cost,
+		"revenue", p.Revenue,
+		"fuel", p.Fuel,
+		"travel", p.Travel.Round(time.Second),
+		"profit", p.Profit(),
+	}
+}
+
+// approxCredits formats an estimate of credits with thousands separators, e.g. "~3,000".
+func approxCredits(credits int64) string {
+	sign, digits := "", strconv.FormatInt(credits, 10)
+	if credits < 0 {
+		sign, digits = "-", digits[1:]
+	}
+
+	for i := len(digits) - 3; i > 0; i -= 3 {
+		digits = digits[:i] + "," + digits[i:]
+	}
+
+	return "~" + sign + digits
+}
+
+// approxTravel formats an estimated travel time to the minute, e.g. "~4 min" or "~1h5m".
+func approxTravel(travel time.Duration) string {
+	travel = travel.Round(time.Minute)
+	if travel < time.Hour {
+		return fmt.Sprintf("~%d min", int(travel/time.Minute))
+	}
+
+	return "~" + strings.TrimSuffix(travel.String(), "0s")
+}
+
+// PlanOutcome is a mission's plan and what the mission actually came to.
+type PlanOutcome struct {
+	Planned Plan `json:"planned"`
+	Actual  Plan `json:"actual"`
+}
+
+// Delta returns the actual units, credits, fuel, and travel less the planned.
+func (o PlanOutcome) Delta() Plan {
+	return Plan{
+		Kind:        o.Actual.Kind,
+		Good:        o.Actual.Good,
+		Units:       o.Actual.Units - o.Planned.Units,
+		Source:      o.Actual.Source,
+		Destination: o.Actual.Destination,
+		Cost:        o.Actual.Cost - o.Planned.Cost,
+		Revenue:     o.Actual.Revenue - o.Planned.Revenue,
+		Fuel:        o.Actual.Fuel - o.Planned.Fuel,
+		FuelCost:    o.Actual.FuelCost - o.Planned.FuelCost,
+		Travel:      o.Actual.Travel - o.Planned.Travel,
+	}
+}
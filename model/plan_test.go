@@ -0,0 +1,59 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlanDescribe(t *testing.T) {
+	tests := []struct {
+		name string
+		plan Plan
+		want string
+	}{
+		{
+			name: "trade",
+			plan: Plan{Kind: PlanTrade, Good: "IRON_ORE", Units: 60, Source: "X1-A1", Destination: "X1-B2", Cost: 3000, Revenue: 5100, Fuel: 12, FuelCost: 100, Travel: 4*time.Minute + 10*time.Second},
+			want: "buy 60 IRON_ORE at X1-A1 for ~3,000, haul to X1-B2 (~4 min, 12 fuel), sell for ~5,100, expected profit ~2,000",
+		},
+		{
+			name: "procurement bought at the destination",
+			plan: Plan{Kind: PlanProcurement, Good: "COPPER", Units: 40, Source: "X1-C3", Cost: 2400, Revenue: 64000},
+			want: "buy 40 COPPER at X1-C3 for ~2,400 (~0 min, 0 fuel), deliver for ~64,000, expected profit ~61,600",
+		},
+		{
+			name: "delivery",
+			plan: Plan{Kind: PlanDelivery, Good: "IRON_ORE", Units: 10, Destination: "X1-A1", Revenue: 1200, Fuel: 30, FuelCost: 2100, Travel: 75 * time.Minute},
+			want: "haul 10 IRON_ORE to X1-A1 (~1h15m, 30 fuel), deliver for ~1,200, expected profit ~-900",
+		},
+		{
+			name: "requisition",
+			plan: Plan{Kind: PlanRequisition, Good: "SHIP_MINING_DRONE", Units: 1, Source: "X1-A1", Cost: 80000, Fuel: 5, Travel: 2 * time.Minute},
+			want: "buy SHIP_MINING_DRONE at X1-A1 for ~80,000 (~2 min, 5 fuel)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.plan.Describe(); got != tt.want {
+				t.Errorf("Describe =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanOutcomeDelta(t *testing.T) {
+	o := PlanOutcome{
+		Planned: Plan{Kind: PlanTrade, Good: "IRON_ORE", Units: 60, Source: "X1-A1", Destination: "X1-B2", Cost: 3000, Revenue: 5100, Fuel: 12, FuelCost: 100, Travel: 4 * time.Minute},
+		Actual:  Plan{Kind: PlanTrade, Good: "IRON_ORE", Units: 55, Source: "X1-A1", Destination: "X1-B2", Cost: 2900, Revenue: 4400, Fuel: 14, FuelCost: 120, Travel: 5 * time.Minute},
+	}
+
+	want := Plan{Kind: PlanTrade, Good: "IRON_ORE", Units: -5, Source: "X1-A1", Destination: "X1-B2", Cost: -100, Revenue: -700, Fuel: 2, FuelCost: 20, Travel: time.Minute}
+	delta := o.Delta()
+	if delta != want {
+		t.Fatalf("Delta = %+v, want %+v", delta, want)
+	}
+	if got := delta.Profit(); got != o.Actual.Profit()-o.Planned.Profit() {
+		t.Fatalf("Delta profit = %d, want %d", got, o.Actual.Profit()-o.Planned.Profit())
+	}
+}
@@ -0,0 +1,119 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// String returns a concise, single-line representation of the ship.
+func (s Ship) String() string {
+	return fmt.Sprintf("%s (%s) %s at %s, cargo %d/%d, fuel %d/%d",
+		s.Symbol, s.Registration.Role, s.Nav.Status, s.Nav.WaypointSymbol,
+		s.Cargo.Units, s.Cargo.Capacity, s.Fuel.Current, s.Fuel.Capacity)
+}
+
+// LogValues returns key/value pairs describing the ship, suitable for structured log fields.
+func (s Ship) LogValues() []interface{} {
+	return []interface{}{
+		"ship", s.Symbol,
+		"role", s.Registration.Role,
+		"status", s.Nav.Status,
+		"waypoint", s.Nav.WaypointSymbol,
+		"cargo", fmt.Sprintf("%d/%d", s.Cargo.Units, s.Cargo.Capacity),
+		"fuel", fmt.Sprintf("%d/%d", s.Fuel.Current, s.Fuel.Capacity),
+	}
+}
+
+// HasMount checks if the ship has a mount whose symbol starts with prefix, e.g. MOUNT_MINING_LASER for
+// a mining laser of any grade.
+func (s *Ship) HasMount(prefix string) bool {
+	for _, mount := range s.Mounts {
+		if strings.HasPrefix(mount.Symbol, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasModule checks if the ship has a module whose symbol starts with prefix, e.g. MODULE_CARGO_HOLD for a
+// cargo hold of any size.
+func (s *Ship) HasModule(prefix string) bool {
+	for _, module := range s.Modules {
+		if strings.HasPrefix(module.Symbol, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Clone returns a deep copy of the ship, sharing no slices with it.
+func (s Ship) Clone() Ship {
+	s.Modules = append([]ShipModule(nil), s.Modules...)
+	s.Cargo.Inventory = append([]ShipCargoItem(nil), s.Cargo.Inventory...)
+
+	mounts := make([]ShipMount, len(s.Mounts))
+	for i, mount := range s.Mounts {
+		mount.Deposits = append([]string(nil), mount.Deposits...)
+		mounts[i] = mount
+	}
+	s.Mounts = mounts
+
+	return s
+}
+
+// ShipPurchase is a ship just bought, with the shipyard transaction that paid for it.
+type ShipPurchase struct {
+	Ship        Ship
+	Transaction ShipyardTransaction
+}
+
+// shipTypeRoles are the registration roles of the ship types sold at shipyards.
+var shipTypeRoles = map[string]string{
+	"SHIP_PROBE":              "SATELLITE",
+	"SHIP_MINING_DRONE":       "EXCAVATOR",
+	"SHIP_ORE_HOUND":          "EXCAVATOR",
+	"SHIP_LIGHT_HAULER":       "HAULER",
+	"SHIP_LIGHT_SHUTTLE":      "TRANSPORT",
+	"SHIP_INTERCEPTOR":        "INTERCEPTOR",
+	"SHIP_COMMAND_FRIGATE":    "COMMAND",
+	"SHIP_EXPLORER":           "EXPLORER",
+	"SHIP_REFINING_FREIGHTER": "REFINERY",
+}
+
+// Build returns the ship a shipyard listing delivers, registered to symbol and faction and docked at nav's
+// waypoint, with a full tank and an empty hold sized by its cargo modules.
+func (s ShipyardShip) Build(symbol string, faction string, nav ShipNav) Ship {
+	ship := Ship{
+		Symbol: symbol,
+		Registration: ShipRegistration{
+			Name:          symbol,
+			FactionSymbol: faction,
+			Role:          shipTypeRoles[s.Type],
+		},
+		Nav:     nav,
+		Frame:   s.Frame,
+		Reactor: s.Reactor,
+		Engine:  s.Engine,
+		Modules: s.Modules,
+		Mounts:  s.Mounts,
+		Fuel:    ShipFuel{Current: s.Frame.FuelCapacity, Capacity: s.Frame.FuelCapacity},
+	}
+	ship.Nav.Status = "DOCKED"
+
+	// A bought ship is new, so components the listing gives no condition for are in full condition.
+	for _, condition := range []*int{&ship.Frame.Condition, &ship.Reactor.Condition, &ship.Engine.Condition} {
+		if *condition == 0 {
+			*condition = FullCondition
+		}
+	}
+
+	for _, module := range s.Modules {
+		if strings.HasPrefix(module.Symbol, "MODULE_CARGO_HOLD") {
+			ship.Cargo.Capacity += module.Capacity
+		}
+	}
+
+	return ship.Clone()
+}
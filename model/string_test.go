@@ -0,0 +1,86 @@
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// excavator returns a mining ship in orbit of an asteroid field.
+func excavator() Ship {
+	var s Ship
+	s.Symbol = "MOCK-2"
+	s.Registration.Role = "EXCAVATOR"
+	s.Nav.Status = "IN_ORBIT"
+	s.Nav.WaypointSymbol = "X1-MK1-B2"
+	s.Cargo = ShipCargo{Capacity: 30, Units: 12, Inventory: []ShipCargoItem{{Symbol: "IRON_ORE", Units: 10}, {Symbol: "QUARTZ_SAND", Units: 2}}}
+	s.Fuel.Current = 80
+	s.Fuel.Capacity = 100
+
+	return s
+}
+
+func TestStringFormats(t *testing.T) {
+	contract := procurement()
+	contract.Type = "PROCUREMENT"
+	contract.FactionSymbol = "COSMIC"
+
+	tests := []struct {
+		name  string
+		value fmt.Stringer
+		want  string
+	}{
+		{"ship", excavator(), "MOCK-2 (EXCAVATOR) IN_ORBIT at X1-MK1-B2, cargo 12/30, fuel 80/100"},
+		{"cargo", excavator().Cargo, "12/30 [IRON_ORE:10 QUARTZ_SAND:2]"},
+		{"empty cargo", ShipCargo{Capacity: 30}, "0/30 []"},
+		{"contract", contract, "contract-1 PROCUREMENT for COSMIC: IRON_ORE 40/100 -> X1-MK1-A1, COPPER_ORE 50/50 -> X1-MK1-A1, " +
+			"IRON_ORE 0/20 -> X1-MK1-C3, accepted, due 2030-01-01T13:00:00Z"},
+		{"waypoint", Waypoint{Symbol: "X1-MK1-A1", Type: "PLANET", X: -3, Y: 14}, "X1-MK1-A1 (PLANET) at (-3, 14)"},
+		{"cooldown", Cooldown{ShipSymbol: "MOCK-2", TotalSeconds: 70, RemainingSeconds: 42, Expiration: OptionalTime{Time: now}},
+			"MOCK-2 cooldown 42s/70s until 2030-01-01T12:00:00Z"},
+		{"no cooldown", Cooldown{ShipSymbol: "MOCK-2"}, "MOCK-2 no cooldown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.value.String(); got != tt.want {
+				t.Errorf("String =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogValuesFormats(t *testing.T) {
+	contract := procurement()
+	contract.Type = "PROCUREMENT"
+	contract.FactionSymbol = "COSMIC"
+
+	tests := []struct {
+		name string
+		got  []interface{}
+		want []interface{}
+	}{
+		{"ship", excavator().LogValues(), []interface{}{
+			"ship", "MOCK-2", "role", "EXCAVATOR", "status", "IN_ORBIT", "waypoint", "X1-MK1-B2", "cargo", "12/30", "fuel", "80/100",
+		}},
+		{"cargo", excavator().Cargo.LogValues(), []interface{}{"cargo", "12/30", "inventory", 2}},
+		{"contract", contract.LogValues(), []interface{}{
+			"contract", "contract-1", "type", "PROCUREMENT", "faction", "COSMIC", "status", "accepted", "goods", "IRON_ORE",
+			"payment", int64(60000), "deadline", "2030-01-01T13:00:00Z",
+		}},
+		{"waypoint", Waypoint{Symbol: "X1-MK1-A1", Type: "PLANET", X: -3, Y: 14, Traits: []WaypointTrait{{Symbol: "MARKETPLACE"}, {Symbol: "SHIPYARD"}}}.LogValues(),
+			[]interface{}{"waypoint", "X1-MK1-A1", "type", "PLANET", "x", -3, "y", 14, "traits", "MARKETPLACE,SHIPYARD"}},
+		{"cooldown", Cooldown{ShipSymbol: "MOCK-2", TotalSeconds: 70, RemainingSeconds: 42, Expiration: OptionalTime{Time: now.Add(time.Minute)}}.LogValues(),
+			[]interface{}{"ship", "MOCK-2", "remainingSeconds", 42, "totalSeconds", 70, "expiration", "2030-01-01T12:01:00Z"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("LogValues =\n%v\nwant\n%v", tt.got, tt.want)
+			}
+			if len(tt.got)%2 != 0 {
+				t.Errorf("LogValues has %d elements, want key/value pairs", len(tt.got))
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"bytes"
+	"time"
+)
+
+// OptionalTime is a timestamp the API documents as optional.
+// Absent, null, and empty string values decode to the zero time instead of failing the whole response.
+type OptionalTime struct {
+	time.Time
+}
+
+// UnmarshalJSON decodes an RFC 3339 timestamp, treating null and "" as the zero time.
+func (t *OptionalTime) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) || bytes.Equal(data, []byte(`""`)) {
+		t.Time = time.Time{}
+		return nil
+	}
+
+	return t.Time.UnmarshalJSON(data)
+}
+
+// MarshalJSON encodes the zero time as null and any other time as an RFC 3339 timestamp.
+func (t OptionalTime) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+
+	return t.Time.MarshalJSON()
+}
+
+// IsSet checks if the timestamp was present in the response.
+func (t OptionalTime) IsSet() bool {
+	return !t.IsZero()
+}
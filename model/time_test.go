@@ -0,0 +1,85 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestOptionalTimeDecodesAbsentValues(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want time.Time
+	}{
+		{"absent", `{"shipSymbol":"MOCK-2","totalSeconds":0,"remainingSeconds":0}`, time.Time{}},
+		{"null", `{"shipSymbol":"MOCK-2","expiration":null}`, time.Time{}},
+		{"empty", `{"shipSymbol":"MOCK-2","expiration":""}`, time.Time{}},
+		{"padded null", `{"shipSymbol":"MOCK-2","expiration": null }`, time.Time{}},
+		{"present", `{"shipSymbol":"MOCK-2","expiration":"2030-01-01T12:00:00.000Z"}`, now},
+		{"offset", `{"shipSymbol":"MOCK-2","expiration":"2030-01-01T14:00:00+02:00"}`, now},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Cooldown
+			if err := json.Unmarshal([]byte(tt.json), &c); err != nil {
+				t.Fatal(err)
+			}
+			if !c.Expiration.Equal(tt.want) {
+				t.Errorf("expiration = %s, want %s", c.Expiration, tt.want)
+			}
+			if c.Expiration.IsSet() != !tt.want.IsZero() {
+				t.Errorf("IsSet = %t, want %t", c.Expiration.IsSet(), !tt.want.IsZero())
+			}
+		})
+	}
+}
+
+func TestOptionalTimeRejectsMalformedValues(t *testing.T) {
+	for _, value := range []string{`"yesterday"`, `12`, `"2030-13-01T00:00:00Z"`} {
+		var c Cooldown
+		if err := json.Unmarshal([]byte(`{"expiration":`+value+`}`), &c); err == nil {
+			t.Errorf("expiration %s decoded as %s", value, c.Expiration)
+		}
+	}
+}
+
+func TestOptionalTimeFieldsOfAFreshShip(t *testing.T) {
+	// A ship straight from the shipyard has burned no fuel, and an uncharted waypoint was never submitted.
+	var ship Ship
+	if err := json.Unmarshal([]byte(`{"symbol":"MOCK-3","fuel":{"current":100,"capacity":100,"consumed":{"amount":0,"timestamp":""}}}`), &ship); err != nil {
+		t.Fatal(err)
+	}
+	if ship.Fuel.Consumed.Timestamp.IsSet() {
+		t.Errorf("fresh ship's fuel consumed at %s, want never", ship.Fuel.Consumed.Timestamp)
+	}
+
+	var waypoint Waypoint
+	if err := json.Unmarshal([]byte(`{"symbol":"X1-MK1-D4","chart":{"submittedBy":"","submittedOn":null}}`), &waypoint); err != nil {
+		t.Fatal(err)
+	}
+	if waypoint.Chart.SubmittedOn.IsSet() {
+		t.Errorf("uncharted waypoint submitted on %s, want never", waypoint.Chart.SubmittedOn)
+	}
+}
+
+func TestOptionalTimeRoundTrip(t *testing.T) {
+	for _, c := range []Cooldown{{ShipSymbol: "MOCK-2"}, {ShipSymbol: "MOCK-2", Expiration: OptionalTime{Time: now}}} {
+		data, err := json.Marshal(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded Cooldown
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Expiration.Equal(c.Expiration.Time) {
+			t.Errorf("%s decoded to expiration %s, want %s", data, decoded.Expiration, c.Expiration)
+		}
+	}
+
+	if data, _ := json.Marshal(OptionalTime{}); string(data) != "null" {
+		t.Errorf("zero time encodes as %s, want null", data)
+	}
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Validate checks that the fields the bots rely on are present on a ship.
+func (s *Ship) Validate() error {
+	var errs []error
+
+	if s.Symbol == "" {
+		errs = append(errs, errors.New("ship: missing symbol"))
+	}
+	if s.Registration.Role == "" {
+		errs = append(errs, fmt.Errorf("ship %s: missing registration role", s.Symbol))
+	}
+	if s.Nav.SystemSymbol == "" || s.Nav.WaypointSymbol == "" {
+		errs = append(errs, fmt.Errorf("ship %s: missing nav location", s.Symbol))
+	}
+	if s.Nav.Status == "" {
+		errs = append(errs, fmt.Errorf("ship %s: missing nav status", s.Symbol))
+	}
+	if err := s.Cargo.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("ship %s: %w", s.Symbol, err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks that the cargo's Units agree with its Inventory and Capacity.
+func (c *ShipCargo) Validate() error {
+	units := 0
+	for _, item := range c.Inventory {
+		if item.Symbol == "" {
+			return errors.New("cargo: inventory item missing symbol")
+		}
+		units += item.Units
+	}
+
+	if units != c.Units {
+		return fmt.Errorf("cargo: units %d do not match inventory total %d", c.Units, units)
+	}
+
+	if c.Units > c.Capacity {
+		return fmt.Errorf("cargo: units %d exceed capacity %d", c.Units, c.Capacity)
+	}
+
+	return nil
+}
+
+// Validate checks that the fields the bots rely on are present on a waypoint.
+func (w *Waypoint) Validate() error {
+	var errs []error
+
+	if w.Symbol == "" {
+		errs = append(errs, errors.New("waypoint: missing symbol"))
+	}
+	if w.Type == "" {
+		errs = append(errs, fmt.Errorf("waypoint %s: missing type", w.Symbol))
+	}
+	if w.SystemSymbol == "" {
+		errs = append(errs, fmt.Errorf("waypoint %s: missing system symbol", w.Symbol))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks that the fields the bots rely on are present on a contract.
+func (c *Contract) Validate() error {
+	var errs []error
+
+	if c.ID == "" {
+		errs = append(errs, errors.New("contract: missing id"))
+	}
+	if c.Type == "" {
+		errs = append(errs, fmt.Errorf("contract %s: missing type", c.ID))
+	}
+	if c.Terms.Deadline.IsZero() {
+		errs = append(errs, fmt.Errorf("contract %s: missing deadline", c.ID))
+	}
+	for i, good := range c.Terms.Deliver {
+		if good.TradeSymbol == "" || good.DestinationSymbol == "" {
+			errs = append(errs, fmt.Errorf("contract %s: deliverable %d missing trade or destination symbol", c.ID, i))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks that the fields the bots rely on are present on a market.
+func (mk *Market) Validate() error {
+	var errs []error
+
+	if mk.Symbol == "" {
+		errs = append(errs, errors.New("market: missing symbol"))
+	}
+	for i, good := range mk.TradeGoods {
+		if good.Symbol == "" {
+			errs = append(errs, fmt.Errorf("market %s: trade good %d missing symbol", mk.Symbol, i))
+		}
+	}
+
+	return errors.Join(errs...)
+}
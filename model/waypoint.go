@@ -0,0 +1,114 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MiningTargetTypes are the waypoint types excavators can extract at. Which of them a system has depends
+// on how the map was generated.
+var MiningTargetTypes = []string{"ASTEROID_FIELD", "ASTEROID", "ENGINEERED_ASTEROID", "ASTEROID_BASE"}
+
+// HasTrait checks if the waypoint has a trait with a given symbol.
+func (w *Waypoint) HasTrait(symbol string) bool {
+	for _, trait := range w.Traits {
+		if trait.Symbol == symbol {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasAnyTrait checks if the waypoint has at least one of the given trait symbols.
+func (w *Waypoint) HasAnyTrait(symbols ...string) bool {
+	for _, symbol := range symbols {
+		if w.HasTrait(symbol) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsType checks if the waypoint is of a given type.
+func (w *Waypoint) IsType(t string) bool {
+	return w.Type == t
+}
+
+// IsAnyType checks if the waypoint is of one of the given types.
+func (w *Waypoint) IsAnyType(types ...string) bool {
+	for _, t := range types {
+		if w.IsType(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String returns a concise, single-line representation of the waypoint.
+func (w Waypoint) String() string {
+	return fmt.Sprintf("%s (%s) at (%d, %d)", w.Symbol, w.Type, w.X, w.Y)
+}
+
+// LogValues returns key/value pairs describing the waypoint, suitable for structured log fields.
+func (w Waypoint) LogValues() []interface{} {
+	traits := make([]string, 0, len(w.Traits))
+	for _, trait := range w.Traits {
+		traits = append(traits, trait.Symbol)
+	}
+
+	return []interface{}{
+		"waypoint", w.Symbol,
+		"type", w.Type,
+		"x", w.X,
+		"y", w.Y,
+		"traits", strings.Join(traits, ","),
+	}
+}
+
+// Waypoint converts a system's waypoint listing to a minimal Waypoint, with its symbol, type, and coordinates
+// but no traits, orbitals, or chart.
+func (w SystemWaypoint) Waypoint(systemSymbol string) Waypoint {
+	return Waypoint{
+		Symbol:       w.Symbol,
+		Type:         w.Type,
+		SystemSymbol: systemSymbol,
+		X:            w.X,
+		Y:            w.Y,
+	}
+}
+
+// MinimalWaypoints converts the system's embedded waypoint listing to minimal Waypoints, which carry no traits.
+func (s *System) MinimalWaypoints() []Waypoint {
+	waypoints := make([]Waypoint, 0, len(s.Waypoints))
+	for _, waypoint := range s.Waypoints {
+		waypoints = append(waypoints, waypoint.Waypoint(s.Symbol))
+	}
+
+	return waypoints
+}
+
+// SystemSummary sums up what the waypoints of a system offer, each with its distance from a reference
+// waypoint, usually the agent's headquarters. A waypoint is listed under every kind it is.
+type SystemSummary struct {
+	System string `json:"system"`
+	// From is the waypoint distances are measured from.
+	From         string            `json:"from"`
+	Waypoints    int               `json:"waypoints"`
+	Marketplaces []SummaryWaypoint `json:"marketplaces"`
+	Shipyards    []SummaryWaypoint `json:"shipyards"`
+	Minable      []SummaryWaypoint `json:"minable"`
+	FuelSellers  []SummaryWaypoint `json:"fuelSellers"`
+	JumpGates    []SummaryWaypoint `json:"jumpGates"`
+	// Uncharted waypoints hide their traits until a ship charts them, so the other kinds may be incomplete.
+	Uncharted []SummaryWaypoint `json:"uncharted"`
+}
+
+// SummaryWaypoint is a waypoint in a SystemSummary.
+type SummaryWaypoint struct {
+	Symbol   string  `json:"symbol"`
+	Type     string  `json:"type"`
+	Distance float64 `json:"distance"`
+}
@@ -0,0 +1,38 @@
+package model
+
+import "testing"
+
+func TestWaypointTraitsAndType(t *testing.T) {
+	w := Waypoint{
+		Symbol: "X1-MK1-A1",
+		Type:   "PLANET",
+		Traits: []WaypointTrait{{Symbol: "MARKETPLACE"}, {Symbol: "SHIPYARD"}},
+	}
+
+	tests := []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{"HasTrait(MARKETPLACE)", w.HasTrait("MARKETPLACE"), true},
+		{"HasTrait(MINERAL_DEPOSITS)", w.HasTrait("MINERAL_DEPOSITS"), false},
+		{"HasAnyTrait(MINERAL_DEPOSITS, SHIPYARD)", w.HasAnyTrait("MINERAL_DEPOSITS", "SHIPYARD"), true},
+		{"HasAnyTrait(MINERAL_DEPOSITS)", w.HasAnyTrait("MINERAL_DEPOSITS"), false},
+		{"HasAnyTrait()", w.HasAnyTrait(), false},
+		{"IsType(PLANET)", w.IsType("PLANET"), true},
+		{"IsType(ASTEROID_FIELD)", w.IsType("ASTEROID_FIELD"), false},
+		{"IsAnyType(ASTEROID, PLANET)", w.IsAnyType("ASTEROID", "PLANET"), true},
+		{"IsAnyType(MiningTargetTypes...)", w.IsAnyType(MiningTargetTypes...), false},
+		{"IsAnyType()", w.IsAnyType(), false},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %t, want %t", tt.name, tt.got, tt.want)
+		}
+	}
+
+	var uncharted Waypoint
+	if uncharted.HasTrait("MARKETPLACE") {
+		t.Error("a waypoint without traits has one")
+	}
+}
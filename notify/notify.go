@@ -0,0 +1,282 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/charmbracelet/log"
+)
+
+// Kinds of notification, used as the per-kind enable flags in Options.
+const (
+	KindContractFulfilled   = "contractFulfilled"
+	KindShipPurchased       = "shipPurchased"
+	KindCreditsThreshold    = "creditsThreshold"
+	KindMissionFailed       = "missionFailed"
+	KindDeadlineApproaching = "deadlineApproaching"
+	KindCreditDrift         = "creditDrift"
+	KindConditionDegraded   = "conditionDegraded"
+)
+
+// Notification is a single message sent to every Sink.
+type Notification struct {
+	Kind  string    `json:"kind"`
+	Title string    `json:"title"`
+	Text  string    `json:"text"`
+	Ship  string    `json:"ship,omitempty"`
+	At    time.Time `json:"at"`
+}
+
+// Options configures a Notifier.
+type Options struct {
+	// Enabled maps each notification kind to whether it is sent. Kinds that are absent are not sent.
+	Enabled map[string]bool
+	// CreditThresholds are the credit balances that trigger a notification when crossed upwards.
+	CreditThresholds []int64
+	// FailureThreshold is the number of consecutive failures of a ship's mission that triggers a notification.
+	FailureThreshold int
+	// RateLimit is the maximum number of notifications sent per RateWindow. Excess notifications are dropped.
+	RateLimit int
+	// RateWindow is the window RateLimit applies to.
+	RateWindow time.Duration
+}
+
+// queueSize is the number of notifications waiting for delivery before new ones are dropped.
+const queueSize = 32
+
+/*
+🔔 Notifier
+*/
+
+// Notifier turns significant events into notifications and delivers them to its sinks.
+// Delivery happens on its own goroutine, so a slow or failing sink never blocks bot logic.
+type Notifier struct {
+	opts   Options
+	sinks  []Sink
+	logger *log.Logger
+	queue  chan Notification
+
+	mu       sync.Mutex
+	sent     []time.Time
+	credits  int64
+	failures map[string]int
+}
+
+// New creates a Notifier delivering to sinks.
+func New(opts Options, logger *log.Logger, sinks ...Sink) *Notifier {
+	return &Notifier{
+		opts:     opts,
+		sinks:    sinks,
+		logger:   logger,
+		queue:    make(chan Notification, queueSize),
+		credits:  -1,
+		failures: map[string]int{},
+	}
+}
+
+// Follow notifies for events until the channel is closed, then waits for pending deliveries.
+func (n *Notifier) Follow(events <-chan event.Event) {
+	delivered := make(chan struct{})
+	go func() {
+		n.deliver()
+		close(delivered)
+	}()
+
+	for e := range events {
+		n.Apply(e)
+	}
+
+	close(n.queue)
+	<-delivered
+}
+
+// Apply queues a notification if the event warrants one.
+func (n *Notifier) Apply(e event.Event) {
+	notification, ok := n.evaluate(e)
+	if !ok || !n.opts.Enabled[notification.Kind] {
+		return
+	}
+
+	if !n.allow(e.At) {
+		n.logger.Warn("🔔 Notification rate limit reached. Dropping.", "kind", notification.Kind, "title", notification.Title)
+		return
+	}
+
+	select {
+	case n.queue <- notification:
+	default:
+		n.logger.Warn("🔔 Notification queue full. Dropping.", "kind", notification.Kind, "title", notification.Title)
+	}
+}
+
+// evaluate builds the notification for an event, reporting whether one is warranted.
+func (n *Notifier) evaluate(e event.Event) (Notification, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	switch e.Type {
+	case event.ContractFulfilled:
+		contract, _ := e.Data.(m.Contract)
+		return Notification{
+			Kind:  KindContractFulfilled,
+			Title: "📜 Contract fulfilled",
+			Text:  fmt.Sprintf("Contract %s fulfilled for %d credits.", contract.ID, contract.Terms.Payment.OnFulfilled),
+			At:    e.At,
+		}, true
+	case event.DeadlineApproaching:
+		progress, ok := e.Data.(m.ContractProgress)
+		if !ok {
+			return Notification{}, false
+		}
+
+		return Notification{
+			Kind:  KindDeadlineApproaching,
+			Title: "⏳ Contract deadline approaching",
+			Text: fmt.Sprintf("Contract %s is %.0f%% delivered with %s left.", progress.ContractID, progress.Completion*100,
+				progress.Remaining.Round(time.Minute)),
+			At: e.At,
+		}, true
+	case event.ShipPurchased:
+		return Notification{
+			Kind:  KindShipPurchased,
+			Title: "🚀 Ship purchased",
+			Text:  fmt.Sprintf("%s joined the fleet.", e.Ship),
+			Ship:  e.Ship,
+			At:    e.At,
+		}, true
+	case event.AgentUpdated:
+		agent, ok := e.Data.(m.Agent)
+		if !ok {
+			return Notification{}, false
+		}
+
+		previous := n.credits
+		n.credits = int64(agent.Credits)
+
+		// The first balance seen is the baseline, not a crossing.
+		if previous < 0 {
+			return Notification{}, false
+		}
+
+		for _, threshold := range n.opts.CreditThresholds {
+			if previous < threshold && n.credits >= threshold {
+				return Notification{
+					Kind:  KindCreditsThreshold,
+					Title: "💰 Credits milestone",
+					Text:  fmt.Sprintf("Credits reached %d (threshold %d).", n.credits, threshold),
+					At:    e.At,
+				}, true
+			}
+		}
+	case event.CreditsDrifted:
+		drift, ok := e.Data.(m.CreditDrift)
+		if !ok {
+			return Notification{}, false
+		}
+
+		return Notification{
+			Kind:  KindCreditDrift,
+			Title: "🧾 Credits drifted",
+			Text:  fmt.Sprintf("Tracked credits were %d but the agent has %d (drift %+d). Reset to the agent's balance.", drift.Tracked, drift.Actual, drift.Drift()),
+			At:    e.At,
+		}, true
+	case event.ConditionDegraded:
+		degraded, ok := e.Data.(m.ConditionDegraded)
+		if !ok {
+			return Notification{}, false
+		}
+
+		title := "🔧 Ship condition degraded"
+		if degraded.Level == m.ConditionCritical {
+			title = "🔧 Ship condition critical"
+		}
+
+		return Notification{
+			Kind:  KindConditionDegraded,
+			Title: title,
+			Text:  fmt.Sprintf("%s's %s condition dropped to %d, below %d.", degraded.Ship, strings.ToLower(degraded.Component), degraded.Condition, degraded.Threshold),
+			Ship:  degraded.Ship,
+			At:    e.At,
+		}, true
+	case event.MissionFailed:
+		key := e.Ship + "/" + e.Mission
+		n.failures[key]++
+
+		if n.failures[key] == n.opts.FailureThreshold {
+			return Notification{
+				Kind:  KindMissionFailed,
+				Title: "⚠️ Mission failing",
+				Text:  fmt.Sprintf("%s failed %q %d times in a row: %s", e.Ship, e.Mission, n.failures[key], e.Message),
+				Ship:  e.Ship,
+				At:    e.At,
+			}, true
+		}
+	case event.ResourcesExtracted, event.CargoSold:
+		// Progress resets the ship's failure streaks.
+		for key := range n.failures {
+			if strings.HasPrefix(key, e.Ship+"/") {
+				delete(n.failures, key)
+			}
+		}
+	}
+
+	return Notification{}, false
+}
+
+// allow reports whether another notification fits in the rate limit window ending at now, recording it if so.
+func (n *Notifier) allow(now time.Time) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	cutoff := now.Add(-n.opts.RateWindow)
+	kept := n.sent[:0]
+	for _, at := range n.sent {
+		if at.After(cutoff) {
+			kept = append(kept, at)
+		}
+	}
+	n.sent = kept
+
+	if len(n.sent) >= n.opts.RateLimit {
+		return false
+	}
+
+	n.sent = append(n.sent, now)
+
+	return true
+}
+
+// deliver sends queued notifications to every sink, retrying a failed delivery once.
+func (n *Notifier) deliver() {
+	for notification := range n.queue {
+		for _, sink := range n.sinks {
+			err := n.send(sink, notification)
+			if err == nil {
+				continue
+			}
+
+			n.logger.Warn("🔔 Notification delivery failed. Retrying...", "sink", sink.Name(), "error", err)
+
+			if err := n.send(sink, notification); err != nil {
+				n.logger.Error("🔔 Notification delivery failed.", "sink", sink.Name(), "kind", notification.Kind, "error", err)
+			}
+		}
+	}
+}
+
+// send delivers a notification to a single sink with a timeout.
+func (n *Notifier) send(sink Sink, notification Notification) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return sink.Send(ctx, notification)
+}
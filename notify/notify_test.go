@@ -0,0 +1,175 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/charmbracelet/log"
+)
+
+var at = time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// recordingSink records what it is sent, failing the first failures sends.
+type recordingSink struct {
+	mu       sync.Mutex
+	sent     []Notification
+	attempts int
+	failures int
+}
+
+func (s *recordingSink) Name() string {
+	return "recording"
+}
+
+func (s *recordingSink) Send(_ context.Context, n Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attempts++
+	if s.attempts <= s.failures {
+		return errors.New("unavailable")
+	}
+
+	s.sent = append(s.sent, n)
+
+	return nil
+}
+
+func options() Options {
+	return Options{
+		Enabled: map[string]bool{
+			KindContractFulfilled: true,
+			KindShipPurchased:     true,
+			KindCreditsThreshold:  true,
+			KindMissionFailed:     true,
+		},
+		CreditThresholds: []int64{100000, 1000000},
+		FailureThreshold: 3,
+		RateLimit:        10,
+		RateWindow:       time.Hour,
+	}
+}
+
+// notify runs events through a Notifier with opts, returning what its sink was sent.
+func notify(opts Options, sink *recordingSink, events ...event.Event) []Notification {
+	n := New(opts, log.New(io.Discard), sink)
+
+	ch := make(chan event.Event, len(events))
+	for _, e := range events {
+		if e.At.IsZero() {
+			e.At = at
+		}
+		ch <- e
+	}
+	close(ch)
+
+	n.Follow(ch)
+
+	return sink.sent
+}
+
+func kinds(sent []Notification) []string {
+	var got []string
+	for _, n := range sent {
+		got = append(got, n.Kind)
+	}
+
+	return got
+}
+
+func TestForwardsSignificantEvents(t *testing.T) {
+	var contract m.Contract
+	contract.ID = "c-1"
+	contract.Terms.Payment.OnFulfilled = 40000
+
+	sent := notify(options(), &recordingSink{},
+		event.Event{Type: event.ContractFulfilled, Data: contract},
+		event.Event{Type: event.ShipPurchased, Ship: "GOGARIN-3"},
+		event.Event{Type: event.CargoSold, Ship: "GOGARIN-2"},
+	)
+
+	if len(sent) != 2 {
+		t.Fatalf("sent %v, want the contract and the purchase", kinds(sent))
+	}
+	if sent[0].Kind != KindContractFulfilled || sent[0].Text != "Contract c-1 fulfilled for 40000 credits." || !sent[0].At.Equal(at) {
+		t.Errorf("contract notification = %+v", sent[0])
+	}
+	if sent[1].Kind != KindShipPurchased || sent[1].Ship != "GOGARIN-3" {
+		t.Errorf("purchase notification = %+v", sent[1])
+	}
+}
+
+func TestSkipsDisabledKinds(t *testing.T) {
+	opts := options()
+	opts.Enabled[KindShipPurchased] = false
+
+	if sent := notify(opts, &recordingSink{}, event.Event{Type: event.ShipPurchased, Ship: "GOGARIN-3"}); len(sent) != 0 {
+		t.Errorf("sent %v for a disabled kind", kinds(sent))
+	}
+}
+
+func TestCreditThresholdsNotifyOnUpwardCrossings(t *testing.T) {
+	agent := func(credits int64) event.Event {
+		return event.Event{Type: event.AgentUpdated, Data: m.Agent{Credits: m.Credits(credits)}}
+	}
+
+	sent := notify(options(), &recordingSink{},
+		agent(150000), // baseline, already above the first threshold
+		agent(90000),
+		agent(120000), // crosses 100000
+		agent(110000),
+		agent(1500000), // crosses 1000000
+	)
+
+	if len(sent) != 2 || sent[0].Text != "Credits reached 120000 (threshold 100000)." || sent[1].Text != "Credits reached 1500000 (threshold 1000000)." {
+		t.Errorf("sent %+v", sent)
+	}
+}
+
+func TestMissionFailuresNotifyOncePerStreak(t *testing.T) {
+	failed := event.Event{Type: event.MissionFailed, Ship: "GOGARIN-2", Mission: "mine", Message: "cooldown"}
+
+	sent := notify(options(), &recordingSink{},
+		failed, failed, failed, failed, // notifies at the third
+		event.Event{Type: event.ResourcesExtracted, Ship: "GOGARIN-2"},
+		failed, failed,
+		event.Event{Type: event.MissionFailed, Ship: "GOGARIN-3", Mission: "mine"},
+	)
+
+	if len(sent) != 1 || sent[0].Ship != "GOGARIN-2" || sent[0].Text != `GOGARIN-2 failed "mine" 3 times in a row: cooldown` {
+		t.Errorf("sent %+v", sent)
+	}
+}
+
+func TestRateLimitDropsExcessNotifications(t *testing.T) {
+	opts := options()
+	opts.RateLimit = 2
+
+	purchase := func(offset time.Duration) event.Event {
+		return event.Event{Type: event.ShipPurchased, At: at.Add(offset)}
+	}
+
+	sent := notify(opts, &recordingSink{}, purchase(0), purchase(time.Minute), purchase(2*time.Minute), purchase(61*time.Minute))
+	if len(sent) != 3 || !sent[2].At.Equal(at.Add(61*time.Minute)) {
+		t.Errorf("sent at %v, want the first two and the one after the window", sent)
+	}
+}
+
+func TestRetriesAFailedDeliveryOnce(t *testing.T) {
+	flaky := &recordingSink{failures: 1}
+	if sent := notify(options(), flaky, event.Event{Type: event.ShipPurchased}); len(sent) != 1 {
+		t.Errorf("flaky sink received %d notifications, want 1 after a retry", len(sent))
+	}
+
+	down := &recordingSink{failures: 10}
+	notify(options(), down, event.Event{Type: event.ShipPurchased})
+	if down.attempts != 2 {
+		t.Errorf("failing sink attempted %d times, want 2", down.attempts)
+	}
+}
@@ -0,0 +1,114 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Sink delivers notifications to an external service.
+type Sink interface {
+	// Name identifies the Sink in logs.
+	Name() string
+	// Send delivers a single notification.
+	Send(ctx context.Context, n Notification) error
+}
+
+// postJSON POSTs body as JSON to url, treating any non-2xx response as an error.
+func postJSON(ctx context.Context, client *http.Client, url string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	return nil
+}
+
+/*
+🪝 Webhook
+*/
+
+// WebhookSink POSTs each notification as JSON to a URL.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink creates a WebhookSink posting to url.
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Name identifies the WebhookSink in logs.
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+// Send POSTs the notification as JSON.
+func (s *WebhookSink) Send(ctx context.Context, n Notification) error {
+	return postJSON(ctx, s.client, s.url, n)
+}
+
+/*
+💬 Discord
+*/
+
+// DiscordSink posts each notification to a Discord webhook as an embed.
+type DiscordSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewDiscordSink creates a DiscordSink posting to the Discord webhook url.
+func NewDiscordSink(url string) *DiscordSink {
+	return &DiscordSink{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Name identifies the DiscordSink in logs.
+func (s *DiscordSink) Name() string {
+	return "discord"
+}
+
+// DiscordMessage is the body of a Discord webhook execution.
+type DiscordMessage struct {
+	Username string         `json:"username,omitempty"`
+	Embeds   []DiscordEmbed `json:"embeds"`
+}
+
+// DiscordEmbed is a single Discord message embed.
+type DiscordEmbed struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// Send posts the notification to Discord.
+func (s *DiscordSink) Send(ctx context.Context, n Notification) error {
+	return postJSON(ctx, s.client, s.url, DiscordMessage{
+		Username: "gogarin",
+		Embeds: []DiscordEmbed{{
+			Title:       n.Title,
+			Description: n.Text,
+			Timestamp:   n.At,
+		}},
+	})
+}
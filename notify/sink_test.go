@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// captureServer returns a server recording the body of each request and answering with status.
+func captureServer(t *testing.T, status int) (*httptest.Server, *[][]byte) {
+	t.Helper()
+
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("%s with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, body)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &bodies
+}
+
+func TestWebhookSinkPostsTheNotification(t *testing.T) {
+	server, bodies := captureServer(t, http.StatusNoContent)
+
+	n := Notification{Kind: KindShipPurchased, Title: "🚀 Ship purchased", Text: "GOGARIN-3 joined the fleet.", Ship: "GOGARIN-3", At: at}
+	if err := NewWebhookSink(server.URL).Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+
+	var got Notification
+	if err := json.Unmarshal((*bodies)[0], &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != n {
+		t.Errorf("posted %+v, want %+v", got, n)
+	}
+}
+
+func TestDiscordSinkPostsAnEmbed(t *testing.T) {
+	server, bodies := captureServer(t, http.StatusOK)
+
+	n := Notification{Kind: KindShipPurchased, Title: "🚀 Ship purchased", Text: "GOGARIN-3 joined the fleet.", At: at}
+	if err := NewDiscordSink(server.URL).Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+
+	var got DiscordMessage
+	if err := json.Unmarshal((*bodies)[0], &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Username != "gogarin" || len(got.Embeds) != 1 || got.Embeds[0].Title != n.Title || got.Embeds[0].Description != n.Text || !got.Embeds[0].Timestamp.Equal(at) {
+		t.Errorf("posted %+v", got)
+	}
+}
+
+func TestSinksReportErrorStatuses(t *testing.T) {
+	server, _ := captureServer(t, http.StatusTooManyRequests)
+
+	if err := NewWebhookSink(server.URL).Send(context.Background(), Notification{}); err == nil {
+		t.Error("webhook: no error for 429")
+	}
+	if err := NewDiscordSink(server.URL).Send(context.Background(), Notification{}); err == nil {
+		t.Error("discord: no error for 429")
+	}
+}
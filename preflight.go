@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/config"
+	"github.com/GeoffreyDick/gogarin/lib"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+// maxSafeRateLimit is the sustained request rate the SpaceTraders API allows before responding 429.
+const maxSafeRateLimit = 2
+
+// errPreflight is returned when a hard preflight check fails.
+var errPreflight = errors.New("preflight checks failed; fix the problems above or pass --skip-preflight")
+
+// preflightState carries what earlier checks learned to later ones.
+type preflightState struct {
+	agent *m.Agent
+}
+
+// preflightCheck is one startup requirement.
+type preflightCheck struct {
+	name string
+	// hard checks abort startup when they fail. Soft checks only warn.
+	hard bool
+	// hint tells the user how to fix a failure.
+	hint string
+	run  func(s *preflightState) error
+}
+
+// preflightResult is the outcome of a preflight check. A check is skipped after an earlier hard check fails.
+type preflightResult struct {
+	check   preflightCheck
+	err     error
+	skipped bool
+}
+
+// preflightChecks returns the startup checks for c, authenticated with acfg's token, in the order they run.
+func preflightChecks(c api.ClientAPI, acfg *config.Config) []preflightCheck {
+	return []preflightCheck{
+		{
+			name: "Token present and parseable",
+			hard: true,
+			hint: "set TOKEN in .env, or run `gogarin register --symbol SYMBOL`",
+			run:  func(*preflightState) error { return checkToken(acfg.Token) },
+		},
+		{
+			name: "API reachable",
+			hard: true,
+			hint: "check your network connection and baseURL, and https://status.spacetraders.io",
+			run:  func(*preflightState) error { return checkAPI(c) },
+		},
+		{
+			name: "Token valid",
+			hard: true,
+			hint: "the server may have been reset; run `gogarin register` or pass --auto-register",
+			run: func(s *preflightState) (err error) {
+				s.agent, err = checkAgent(c)
+				return err
+			},
+		},
+		{
+			name: "Fleet has ships",
+			hard: true,
+			hint: "buy a ship, or register a new agent",
+			run:  func(*preflightState) error { return checkFleet(c) },
+		},
+		{
+			name: "Headquarters system resolvable",
+			hard: true,
+			hint: "the agent's headquarters is not a valid waypoint; re-register the agent",
+			run:  func(s *preflightState) error { return checkHeadquarters(c, s.agent) },
+		},
+		{
+			name: "Rate limit",
+			hint: fmt.Sprintf("set rateLimit to %d or less to avoid 429 responses", maxSafeRateLimit),
+			run:  func(*preflightState) error { return checkRateLimit(acfg.RateLimit) },
+		},
+	}
+}
+
+// preflight runs checks in order, stopping at the first hard failure. It returns errPreflight if a hard check failed.
+func preflight(c api.ClientAPI, checks []preflightCheck) ([]preflightResult, error) {
+	var (
+		s       preflightState
+		results []preflightResult
+		failed  bool
+	)
+
+	for _, check := range checks {
+		if failed {
+			results = append(results, preflightResult{check: check, skipped: true})
+			continue
+		}
+
+		err := check.run(&s)
+		results = append(results, preflightResult{check: check, err: err})
+		if err != nil && check.hard {
+			failed = true
+		}
+	}
+
+	if failed {
+		return results, errPreflight
+	}
+
+	return results, nil
+}
+
+// renderPreflight writes a checklist of results to w, with a remediation hint under each failure.
+func renderPreflight(w io.Writer, results []preflightResult) {
+	fmt.Fprintln(w, "Preflight checks:")
+
+	for _, r := range results {
+		switch {
+		case r.skipped:
+			fmt.Fprintf(w, "  [SKIP] %s\n", r.check.name)
+		case r.err == nil:
+			fmt.Fprintf(w, "  [ OK ] %s\n", r.check.name)
+		default:
+			mark := "WARN"
+			if r.check.hard {
+				mark = "FAIL"
+			}
+			fmt.Fprintf(w, "  [%s] %s: %v\n", mark, r.check.name, r.err)
+			fmt.Fprintf(w, "         %s\n", r.check.hint)
+		}
+	}
+}
+
+// checkToken checks that token is set and is a SpaceTraders token.
+func checkToken(token string) error {
+	if token == "" {
+		return errors.New("no token configured")
+	}
+
+	if _, err := api.ParseToken(token); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// checkAPI checks that the server status endpoint responds.
+func checkAPI(c api.ClientAPI) error {
+	_, err := c.GetStatus()
+	return err
+}
+
+// checkAgent checks that the server accepts the token, returning its agent.
+func checkAgent(c api.ClientAPI) (*m.Agent, error) {
+	return c.GetMyAgent()
+}
+
+// checkFleet checks that the agent owns at least one ship.
+func checkFleet(c api.ClientAPI) error {
+	ships, err := c.GetMyShips()
+	if err != nil {
+		return err
+	}
+
+	if len(*ships) == 0 {
+		return errors.New("the agent has no ships")
+	}
+
+	return nil
+}
+
+// checkHeadquarters checks that the system of agent's headquarters can be retrieved.
+func checkHeadquarters(c api.ClientAPI, agent *m.Agent) error {
+	if agent == nil {
+		return errors.New("no agent")
+	}
+
+	systemSymbol, err := lib.SystemSymbolOf(agent.Headquarters)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.GetSystem(systemSymbol)
+	return err
+}
+
+// checkRateLimit checks that limit is positive and within what the API allows.
+func checkRateLimit(limit int) error {
+	if limit <= 0 {
+		return fmt.Errorf("rateLimit must be positive, got %d", limit)
+	}
+
+	if limit > maxSafeRateLimit {
+		return fmt.Errorf("rateLimit %d exceeds the API's %d requests per second", limit, maxSafeRateLimit)
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+// preflightClient is a ClientAPI answering the calls made by the preflight checks.
+type preflightClient struct {
+	api.ClientAPI
+	statusErr error
+	agent     *m.Agent
+	agentErr  error
+	ships     []m.Ship
+	systemErr error
+	systems   []string
+}
+
+func (c *preflightClient) GetStatus() (*m.Status, error) {
+	if c.statusErr != nil {
+		return nil, c.statusErr
+	}
+
+	return &m.Status{Status: "online"}, nil
+}
+
+func (c *preflightClient) GetMyAgent() (*m.Agent, error) {
+	return c.agent, c.agentErr
+}
+
+func (c *preflightClient) GetMyShips() (*[]m.Ship, error) {
+	return &c.ships, nil
+}
+
+func (c *preflightClient) GetSystem(systemSymbol string) (*m.System, error) {
+	c.systems = append(c.systems, systemSymbol)
+	if c.systemErr != nil {
+		return nil, c.systemErr
+	}
+
+	return &m.System{Symbol: systemSymbol}, nil
+}
+
+// healthyClient passes every preflight check.
+func healthyClient() *preflightClient {
+	return &preflightClient{
+		agent: &m.Agent{Symbol: "GOGARIN", Headquarters: "X1-DF55-20250Z"},
+		ships: []m.Ship{{Symbol: "GOGARIN-1"}},
+	}
+}
+
+func TestCheckToken(t *testing.T) {
+	if err := checkToken(""); err == nil {
+		t.Error("accepted a missing token")
+	}
+	if err := checkToken("not-a-token"); err == nil {
+		t.Error("accepted an unparseable token")
+	}
+	if err := checkToken(jwt("2030-01-01")); err != nil {
+		t.Errorf("rejected a valid token: %s", err)
+	}
+}
+
+func TestCheckFleet(t *testing.T) {
+	c := healthyClient()
+	if err := checkFleet(c); err != nil {
+		t.Errorf("rejected a fleet with a ship: %s", err)
+	}
+
+	c.ships = nil
+	if err := checkFleet(c); err == nil {
+		t.Error("accepted an empty fleet")
+	}
+}
+
+func TestCheckHeadquarters(t *testing.T) {
+	c := healthyClient()
+	if err := checkHeadquarters(c, c.agent); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.systems) != 1 || c.systems[0] != "X1-DF55" {
+		t.Errorf("looked up systems %v, want [X1-DF55]", c.systems)
+	}
+
+	if err := checkHeadquarters(c, &m.Agent{Headquarters: "X1"}); err == nil {
+		t.Error("accepted a malformed headquarters")
+	}
+	if err := checkHeadquarters(c, nil); err == nil {
+		t.Error("accepted a missing agent")
+	}
+
+	c.systemErr = errors.New("not found")
+	if err := checkHeadquarters(c, c.agent); err == nil {
+		t.Error("accepted an unresolvable system")
+	}
+}
+
+func TestCheckRateLimit(t *testing.T) {
+	for limit, ok := range map[int]bool{-1: false, 0: false, 1: true, 2: true, 3: false} {
+		if err := checkRateLimit(limit); (err == nil) != ok {
+			t.Errorf("checkRateLimit(%d) = %v, want ok %t", limit, err, ok)
+		}
+	}
+}
+
+func TestPreflightPassesAHealthySetup(t *testing.T) {
+	withConfig(t, jwt("2030-01-01"))
+
+	results, err := preflight(healthyClient(), preflightChecks(healthyClient(), cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	renderPreflight(&out, results)
+	if strings.Count(out.String(), "[ OK ]") != 6 {
+		t.Errorf("checklist:\n%s\nwant six passes", out.String())
+	}
+}
+
+func TestPreflightStopsAtTheFirstHardFailure(t *testing.T) {
+	withConfig(t, jwt("2030-01-01"))
+
+	c := healthyClient()
+	c.agentErr = unauthorized
+	results, err := preflight(c, preflightChecks(c, cfg))
+	if !errors.Is(err, errPreflight) {
+		t.Fatalf("err = %v, want %v", err, errPreflight)
+	}
+
+	var out bytes.Buffer
+	renderPreflight(&out, results)
+	assertLines(t, out.String(),
+		"Preflight checks:",
+		"[ OK ] Token present and parseable",
+		"[ OK ] API reachable",
+		"[FAIL] Token valid: Token is invalid.",
+		"the server may have been reset; run `gogarin register` or pass --auto-register",
+		"[SKIP] Fleet has ships",
+		"[SKIP] Headquarters system resolvable",
+		"[SKIP] Rate limit",
+	)
+	if len(c.systems) != 0 {
+		t.Error("ran a check after a hard failure")
+	}
+}
+
+func TestPreflightOnlyWarnsAboutTheRateLimit(t *testing.T) {
+	withConfig(t, jwt("2030-01-01"))
+	cfg.RateLimit = 5
+
+	results, err := preflight(healthyClient(), preflightChecks(healthyClient(), cfg))
+	if err != nil {
+		t.Fatalf("err = %v, want startup to continue", err)
+	}
+
+	var out bytes.Buffer
+	renderPreflight(&out, results)
+	if !strings.Contains(out.String(), "[WARN] Rate limit: rateLimit 5 exceeds the API's 2 requests per second") {
+		t.Errorf("checklist:\n%s\nwant a rate limit warning", out.String())
+	}
+}
+
+func TestPreflightAgainstTheMockServer(t *testing.T) {
+	withConfig(t, jwt("2030-01-01"))
+	_, c := startMock(t, 0)
+
+	results, err := preflight(c, preflightChecks(c, cfg))
+	if err != nil {
+		var out bytes.Buffer
+		renderPreflight(&out, results)
+		t.Fatalf("%s\n%s", err, out.String())
+	}
+}
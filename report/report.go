@@ -0,0 +1,354 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/boterr"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/ledger"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+// topErrors is how many of the most frequent mission errors a Report lists.
+const topErrors = 5
+
+// Report sums up what a run did, from its start until it ended.
+type Report struct {
+	Started  time.Time     `json:"started"`
+	Ended    time.Time     `json:"ended"`
+	Duration time.Duration `json:"duration"`
+	// Outcome is why the run ended: "stopped", or the error it ended with.
+	Outcome string  `json:"outcome"`
+	Credits Credits `json:"credits"`
+	// Ledger is the income, spend, and net of the run's credit movements.
+	Ledger    ledger.Totals `json:"ledger"`
+	Mined     []GoodTally   `json:"mined"`
+	Sold      []GoodTally   `json:"sold"`
+	Delivered []GoodTally   `json:"delivered"`
+	Contracts Contracts     `json:"contracts"`
+	// ShipsPurchased are the ship types bought, and ShipSpend what they cost.
+	ShipsPurchased []string `json:"shipsPurchased"`
+	ShipSpend      int64    `json:"shipSpend"`
+	FuelSpend      int64    `json:"fuelSpend"`
+	// Requests are the API requests each subsystem sent, when the client reports its usage.
+	Requests []api.SubsystemUsage `json:"requests,omitempty"`
+	// Errors are the most frequent reasons missions failed, most frequent first.
+	Errors []ErrorTally `json:"errors"`
+}
+
+// Credits are the agent's credits when a run started and ended.
+type Credits struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	Delta int64 `json:"delta"`
+}
+
+// GoodTally is how many units of a good were handled, and for how many credits when they were sold.
+type GoodTally struct {
+	Symbol  string `json:"symbol"`
+	Units   int    `json:"units"`
+	Credits int64  `json:"credits,omitempty"`
+}
+
+// Contracts counts the contracts accepted, delivered to, and fulfilled during a run.
+type Contracts struct {
+	Accepted   int `json:"accepted"`
+	Progressed int `json:"progressed"`
+	Fulfilled  int `json:"fulfilled"`
+}
+
+// ErrorTally is how many missions failed for a reason.
+type ErrorTally struct {
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+}
+
+/*
+📋 Session
+*/
+
+// Session tallies what a run does from the event bus, for the Report at its end.
+type Session struct {
+	mu      sync.Mutex
+	started time.Time
+	// startCredits and endCredits are the first and latest credits reported, once any are.
+	startCredits *int64
+	endCredits   int64
+	mined        map[string]*GoodTally
+	sold         map[string]*GoodTally
+	delivered    map[string]*GoodTally
+	contracts    Contracts
+	// firstFulfilled and lastFulfilled are the units first and last seen fulfilled of each contract, so a
+	// contract delivered to during the run can be told apart.
+	firstFulfilled map[string]int
+	lastFulfilled  map[string]int
+	ships          []string
+	shipSpend      int64
+	fuelSpend      int64
+	errors         map[string]int
+}
+
+// NewSession creates a Session for a run started at started.
+func NewSession(started time.Time) *Session {
+	return &Session{
+		started:        started,
+		mined:          make(map[string]*GoodTally),
+		sold:           make(map[string]*GoodTally),
+		delivered:      make(map[string]*GoodTally),
+		firstFulfilled: make(map[string]int),
+		lastFulfilled:  make(map[string]int),
+		errors:         make(map[string]int),
+	}
+}
+
+// Follow tallies events until the channel is closed.
+func (s *Session) Follow(events <-chan event.Event) {
+	for e := range events {
+		s.Apply(e)
+	}
+}
+
+// Apply tallies a single event.
+func (s *Session) Apply(e event.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch data := e.Data.(type) {
+	case m.Agent:
+		if e.Type != event.AgentUpdated {
+			return
+		}
+		if s.startCredits == nil {
+			start := int64(data.Credits)
+			s.startCredits = &start
+		}
+		s.endCredits = int64(data.Credits)
+	case m.Extraction:
+		tally(s.mined, data.Yield.Symbol, data.Yield.Units, 0)
+	case m.MarketTransaction:
+		switch e.Type {
+		case event.CargoSold:
+			tally(s.sold, data.TradeSymbol, data.Units, data.TotalPrice)
+		case event.ShipRefueled:
+			s.fuelSpend += data.TotalPrice
+		}
+	case m.ShipCargoItem:
+		if e.Type == event.CargoDelivered {
+			tally(s.delivered, data.Symbol, data.Units, 0)
+		}
+	case m.Contract:
+		switch e.Type {
+		case event.ContractAccepted:
+			s.contracts.Accepted++
+		case event.ContractFulfilled:
+			s.contracts.Fulfilled++
+		}
+	case []m.Contract:
+		for _, contract := range data {
+			var fulfilled int
+			for _, good := range contract.Terms.Deliver {
+				fulfilled += good.UnitsFulfilled
+			}
+
+			if _, ok := s.firstFulfilled[contract.ID]; !ok {
+				s.firstFulfilled[contract.ID] = fulfilled
+			}
+			s.lastFulfilled[contract.ID] = fulfilled
+		}
+	case m.ShipPurchase:
+		s.ships = append(s.ships, data.Transaction.ShipType)
+		s.shipSpend += data.Transaction.Price
+	case error:
+		if e.Type == event.MissionFailed {
+			s.errors[failureReason(data)]++
+		}
+	}
+}
+
+// tally adds units, and credits, of a good to tallies.
+func tally(tallies map[string]*GoodTally, symbol string, units int, credits int64) {
+	t, ok := tallies[symbol]
+	if !ok {
+		t = &GoodTally{Symbol: symbol}
+		tallies[symbol] = t
+	}
+	t.Units += units
+	t.Credits += credits
+}
+
+// failureReason is the message of the error a mission failed with, without the context fields wrapped around
+// it, so failures of different ships and missions for the same reason are counted together.
+func failureReason(err error) string {
+	for {
+		var wrapped *boterr.Error
+		if !errors.As(err, &wrapped) {
+			return err.Error()
+		}
+		err = wrapped.Err
+	}
+}
+
+// Report sums up the run as of ended. l gives the run's credit movements, and usage, if not nil, the API
+// requests sent. outcome is why the run ended.
+func (s *Session) Report(ended time.Time, outcome string, l *ledger.Ledger, usage func() []api.SubsystemUsage) Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := Report{
+		Started:        s.started,
+		Ended:          ended,
+		Duration:       ended.Sub(s.started).Round(time.Second),
+		Outcome:        outcome,
+		Ledger:         l.Totals(),
+		Mined:          sorted(s.mined),
+		Sold:           sorted(s.sold),
+		Delivered:      sorted(s.delivered),
+		Contracts:      s.contracts,
+		ShipsPurchased: append([]string{}, s.ships...),
+		ShipSpend:      s.shipSpend,
+		FuelSpend:      s.fuelSpend,
+		Errors:         []ErrorTally{},
+	}
+
+	if s.startCredits != nil {
+		r.Credits = Credits{Start: *s.startCredits, End: s.endCredits, Delta: s.endCredits - *s.startCredits}
+	}
+
+	for id, last := range s.lastFulfilled {
+		if last > s.firstFulfilled[id] {
+			r.Contracts.Progressed++
+		}
+	}
+
+	if usage != nil {
+		r.Requests = usage()
+	}
+
+	for message, count := range s.errors {
+		r.Errors = append(r.Errors, ErrorTally{Message: message, Count: count})
+	}
+	sort.Slice(r.Errors, func(i, j int) bool {
+		if r.Errors[i].Count != r.Errors[j].Count {
+			return r.Errors[i].Count > r.Errors[j].Count
+		}
+		return r.Errors[i].Message < r.Errors[j].Message
+	})
+	if len(r.Errors) > topErrors {
+		r.Errors = r.Errors[:topErrors]
+	}
+
+	return r
+}
+
+// sorted returns the tallies of goods in symbol order.
+func sorted(tallies map[string]*GoodTally) []GoodTally {
+	goods := make([]GoodTally, 0, len(tallies))
+	for _, t := range tallies {
+		goods = append(goods, *t)
+	}
+	sort.Slice(goods, func(i, j int) bool { return goods[i].Symbol < goods[j].Symbol })
+
+	return goods
+}
+
+// TotalRequests returns the API requests sent by every subsystem.
+func (r Report) TotalRequests() uint64 {
+	var total uint64
+	for _, u := range r.Requests {
+		total += u.Total
+	}
+
+	return total
+}
+
+// Render formats the Report as tables for the log.
+func (r Report) Render() string {
+	var b strings.Builder
+
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Duration\t%s\t(%s to %s)\n", r.Duration, r.Started.Format(time.RFC3339), r.Ended.Format(time.RFC3339))
+	fmt.Fprintf(tw, "Outcome\t%s\n", r.Outcome)
+	fmt.Fprintf(tw, "Credits\t%d → %d\t(%+d)\n", r.Credits.Start, r.Credits.End, r.Credits.Delta)
+	fmt.Fprintf(tw, "Ledger\tincome %d\tspend %d\tnet %+d\n", r.Ledger.Income, r.Ledger.Spend, r.Ledger.Net)
+	fmt.Fprintf(tw, "Contracts\taccepted %d\tprogressed %d\tfulfilled %d\n", r.Contracts.Accepted, r.Contracts.Progressed, r.Contracts.Fulfilled)
+	if len(r.ShipsPurchased) > 0 {
+		fmt.Fprintf(tw, "Ships purchased\t%d\tfor %d\t(%s)\n", len(r.ShipsPurchased), r.ShipSpend, strings.Join(r.ShipsPurchased, ", "))
+	} else {
+		fmt.Fprintln(tw, "Ships purchased\t0")
+	}
+	fmt.Fprintf(tw, "Fuel spend\t%d\n", r.FuelSpend)
+	if r.Requests != nil {
+		fmt.Fprintf(tw, "API requests\t%d\n", r.TotalRequests())
+	}
+	tw.Flush()
+
+	if len(r.Mined)+len(r.Sold)+len(r.Delivered) > 0 {
+		b.WriteString("\n")
+		tw = tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "GOOD\tMINED\tSOLD\tCREDITS\tDELIVERED")
+		for _, symbol := range goods(r.Mined, r.Sold, r.Delivered) {
+			mined, sold, delivered := find(r.Mined, symbol), find(r.Sold, symbol), find(r.Delivered, symbol)
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\n", symbol, mined.Units, sold.Units, sold.Credits, delivered.Units)
+		}
+		tw.Flush()
+	}
+
+	if len(r.Errors) > 0 {
+		b.WriteString("\n")
+		tw = tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "FAILURES\tERROR")
+		for _, e := range r.Errors {
+			fmt.Fprintf(tw, "%d\t%s\n", e.Count, e.Message)
+		}
+		tw.Flush()
+	}
+
+	return b.String()
+}
+
+// goods returns the symbols of every good among tallies, in order.
+func goods(tallies ...[]GoodTally) []string {
+	seen := make(map[string]bool)
+	var symbols []string
+	for _, goods := range tallies {
+		for _, t := range goods {
+			if !seen[t.Symbol] {
+				seen[t.Symbol] = true
+				symbols = append(symbols, t.Symbol)
+			}
+		}
+	}
+	sort.Strings(symbols)
+
+	return symbols
+}
+
+// find returns the tally of a good, or an empty one.
+func find(tallies []GoodTally, symbol string) GoodTally {
+	for _, t := range tallies {
+		if t.Symbol == symbol {
+			return t
+		}
+	}
+
+	return GoodTally{Symbol: symbol}
+}
+
+// WriteFile writes the Report to path as indented JSON.
+func (r Report) WriteFile(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,119 @@
+package report
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/boterr"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/ledger"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the current output")
+
+var epoch = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// golden compares got with the golden file testdata/name, rewriting it instead with -update.
+func golden(t *testing.T, name string, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Fatalf("output differs from %s (rerun with -update to accept it):\n--- got\n%s--- want\n%s", path, got, want)
+	}
+}
+
+// session plays a run of two hours through a Session and a Ledger, as the bus would, and reports on it.
+func session(t *testing.T) Report {
+	t.Helper()
+
+	s := NewSession(epoch)
+	l := ledger.New(0)
+	at := epoch
+	publish := func(e event.Event) {
+		at = at.Add(time.Minute)
+		e.At = at
+		s.Apply(e)
+		l.Apply(e)
+	}
+
+	contract := func(fulfilled int) m.Contract {
+		var c m.Contract
+		c.ID = "CONTRACT-1"
+		c.Terms.Payment = m.ContractPayment{OnAccepted: 1500, OnFulfilled: 9000}
+		c.Terms.Deliver = []m.ContractDeliverGood{{TradeSymbol: "COPPER_ORE", DestinationSymbol: "X1-MK1-A1", UnitsRequired: 40, UnitsFulfilled: fulfilled}}
+		return c
+	}
+	extraction := func(symbol string, units int) m.Extraction {
+		var x m.Extraction
+		x.ShipSymbol = "MOCK-2"
+		x.Yield.Symbol = symbol
+		x.Yield.Units = units
+		return x
+	}
+
+	publish(event.Event{Type: event.AgentUpdated, Data: m.Agent{Credits: 150000}})
+	publish(event.Event{Type: event.ContractsUpdated, Data: []m.Contract{contract(0)}})
+	publish(event.Event{Type: event.ContractAccepted, Data: contract(0)})
+	publish(event.Event{Type: event.ResourcesExtracted, Ship: "MOCK-2", Data: extraction("IRON_ORE", 7)})
+	publish(event.Event{Type: event.ResourcesExtracted, Ship: "MOCK-2", Data: extraction("COPPER_ORE", 12)})
+	publish(event.Event{Type: event.ResourcesExtracted, Ship: "MOCK-2", Data: extraction("IRON_ORE", 9)})
+	publish(event.Event{Type: event.ShipRefueled, Ship: "MOCK-2", Data: m.MarketTransaction{TradeSymbol: "FUEL", Units: 100, TotalPrice: 7200}})
+	publish(event.Event{Type: event.CargoSold, Ship: "MOCK-2", Data: m.MarketTransaction{TradeSymbol: "IRON_ORE", Units: 16, TotalPrice: 720}})
+	publish(event.Event{Type: event.CargoDelivered, Ship: "MOCK-2", Data: m.ShipCargoItem{Symbol: "COPPER_ORE", Units: 12}})
+	publish(event.Event{Type: event.ContractsUpdated, Data: []m.Contract{contract(12)}})
+	publish(event.Event{Type: event.ShipPurchased, Data: m.ShipPurchase{
+		Ship:        m.Ship{Symbol: "MOCK-3"},
+		Transaction: m.ShipyardTransaction{ShipType: "SHIP_MINING_DRONE", Price: 85000},
+	}})
+	timeout := errors.New("mission timed out")
+	publish(event.Event{Type: event.MissionFailed, Ship: "MOCK-2", Data: boterr.Wrap(timeout, "ship", "MOCK-2", "mission", "Extract")})
+	publish(event.Event{Type: event.MissionFailed, Ship: "MOCK-3", Data: boterr.Wrap(timeout, "ship", "MOCK-3", "mission", "Sell")})
+	publish(event.Event{Type: event.MissionFailed, Ship: "MOCK-3", Data: errors.New("no market buys QUARTZ_SAND")})
+	publish(event.Event{Type: event.AgentUpdated, Data: m.Agent{Credits: 59020}})
+
+	usage := func() []api.SubsystemUsage {
+		return []api.SubsystemUsage{{Subsystem: "fleet", Total: 212}, {Subsystem: "market", Total: 40}}
+	}
+
+	return s.Report(epoch.Add(2*time.Hour), "stopped", l, usage)
+}
+
+func TestRenderReport(t *testing.T) {
+	golden(t, "report.golden", session(t).Render())
+}
+
+func TestRenderEmptyReport(t *testing.T) {
+	r := NewSession(epoch).Report(epoch.Add(time.Minute), "interrupt", ledger.New(0), nil)
+
+	golden(t, "empty.golden", r.Render())
+}
+
+func TestWriteReportFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := session(t).WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "report.json.golden", string(data))
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+)
+
+/*
+👥 Agents
+*/
+
+// Agents serves the Servers of several agents run by one process from one address. Each agent's routes are
+// served under /agents/NAME/, e.g. /agents/NAME/api/status, and /api/agents lists the agents' names.
+type Agents struct {
+	names []string
+	http  *http.Server
+}
+
+// NewAgents creates an Agents listening on addr, serving each Server's routes under its agent's name.
+func NewAgents(addr string, servers map[string]*Server) *Agents {
+	a := &Agents{}
+
+	mux := http.NewServeMux()
+	for name, s := range servers {
+		a.names = append(a.names, name)
+		prefix := "/agents/" + name
+		mux.Handle(prefix+"/", http.StripPrefix(prefix, s.Handler()))
+	}
+	sort.Strings(a.names)
+	mux.HandleFunc("/api/agents", a.handleAgents)
+
+	a.http = &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	return a
+}
+
+// ListenAndServe serves until Shutdown is called.
+func (a *Agents) ListenAndServe() error {
+	if err := a.http.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// Shutdown stops the Agents, waiting for in-flight requests until ctx is done.
+func (a *Agents) Shutdown(ctx context.Context) error {
+	return a.http.Shutdown(ctx)
+}
+
+// handleAgents lists the names of the agents served, sorted.
+func (a *Agents) handleAgents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, a.names)
+}
@@ -0,0 +1,452 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/ledger"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/status"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+// Server exposes the fleet's status as JSON.
+// Every response is rendered from in-memory snapshots; no read triggers an API call.
+// The writable routes are /api/strategy, /api/pause and /api/resume, and /api/ships/SHIP/order, each only when
+// the Server is created with the option serving it.
+type Server struct {
+	board      *status.Board
+	ledger     *ledger.Ledger
+	fleetPlan  func() map[string]int
+	metrics    http.Handler
+	strategies StrategySwitcher
+	pause      PauseControl
+	orders     OrderTaker
+	usage      func() []api.SubsystemUsage
+	markets    *store.MarketStore
+	http       *http.Server
+}
+
+// StrategySwitcher reports and switches the strategy ships follow.
+type StrategySwitcher interface {
+	// Strategies returns the strategy of ships without a role override, and the overrides by role.
+	Strategies() (string, map[string]string)
+	// SetStrategy switches the strategy of a role, or of ships without a role override when role is empty.
+	SetStrategy(role string, name string) error
+}
+
+// PauseControl pauses and resumes the fleet.
+type PauseControl interface {
+	Pause()
+	Resume()
+	Paused() bool
+}
+
+// OrderTaker gives ships manual orders.
+type OrderTaker interface {
+	Order(shipSymbol string, order m.ManualOrder) error
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithMetrics serves handler at /metrics.
+func WithMetrics(handler http.Handler) Option {
+	return func(s *Server) {
+		s.metrics = handler
+	}
+}
+
+// WithStrategies serves and switches the fleet's strategies at /api/strategy.
+// A switch takes effect the next time each ship is dispatched.
+func WithStrategies(strategies StrategySwitcher) Option {
+	return func(s *Server) {
+		s.strategies = strategies
+	}
+}
+
+// WithPauseControl pauses and resumes the fleet at /api/pause and /api/resume.
+func WithPauseControl(pause PauseControl) Option {
+	return func(s *Server) {
+		s.pause = pause
+	}
+}
+
+// WithOrders gives ships manual orders at /api/ships/SHIP/order.
+func WithOrders(orders OrderTaker) Option {
+	return func(s *Server) {
+		s.orders = orders
+	}
+}
+
+// WithRequestUsage reports the API request budget usage of each subsystem, read from usage, at /api/status.
+func WithRequestUsage(usage func() []api.SubsystemUsage) Option {
+	return func(s *Server) {
+		s.usage = usage
+	}
+}
+
+// WithMarkets serves the latest stored observation of each market at /api/markets/WAYPOINT, so it can be
+// compared with the live market.
+func WithMarkets(markets *store.MarketStore) Option {
+	return func(s *Server) {
+		s.markets = markets
+	}
+}
+
+// New creates a Server listening on addr. fleetPlan is called for the target number of ships per role on
+// each status request, so a reloaded plan is reported.
+func New(addr string, board *status.Board, l *ledger.Ledger, fleetPlan func() map[string]int, opts ...Option) *Server {
+	s := &Server{
+		board:     board,
+		ledger:    l,
+		fleetPlan: fleetPlan,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.http = &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	return s
+}
+
+// Handler returns the Server's routes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/status", s.handleStatus)
+	mux.HandleFunc("/api/ships", s.handleShips)
+	mux.HandleFunc("/api/contracts", s.handleContracts)
+	mux.HandleFunc("/api/ledger", s.handleLedger)
+
+	if s.metrics != nil {
+		mux.Handle("/metrics", s.metrics)
+	}
+
+	if s.strategies != nil {
+		mux.HandleFunc("/api/strategy", s.handleStrategy)
+	}
+
+	if s.markets != nil {
+		mux.HandleFunc("/api/markets/", s.handleMarket)
+	}
+
+	if s.pause != nil {
+		mux.HandleFunc("/api/pause", s.handlePause(s.pause.Pause))
+		mux.HandleFunc("/api/resume", s.handlePause(s.pause.Resume))
+	}
+
+	if s.orders != nil {
+		mux.HandleFunc("/api/ships/", s.handleOrder)
+	}
+
+	return mux
+}
+
+// ListenAndServe serves until Shutdown is called.
+func (s *Server) ListenAndServe() error {
+	if err := s.http.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// Shutdown stops the Server, waiting for in-flight requests until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
+}
+
+// PlanProgress is the current and target number of ships for a role.
+type PlanProgress struct {
+	Current int `json:"current"`
+	Target  int `json:"target"`
+}
+
+// StatusResponse is the body of GET /api/status.
+type StatusResponse struct {
+	Agent     m.Agent                 `json:"agent"`
+	Ships     int                     `json:"ships"`
+	FleetPlan map[string]PlanProgress `json:"fleetPlan"`
+	Ledger    ledger.Totals           `json:"ledger"`
+	// PausedAt is when the fleet was paused, or nil while it runs.
+	PausedAt *time.Time `json:"pausedAt,omitempty"`
+	// Requests is the request budget usage of each subsystem, when the Server is created WithRequestUsage.
+	Requests []api.SubsystemUsage `json:"requests,omitempty"`
+	// HomeSystem sums up the home system, or is nil before it is summarized.
+	HomeSystem *m.SystemSummary `json:"homeSystem,omitempty"`
+	// Strategies are what the ships following each strategy earned per hour within the earnings window.
+	Strategies []ledger.StrategyRate `json:"strategies,omitempty"`
+	TakenAt    time.Time             `json:"takenAt"`
+}
+
+// ShipResponse is an element of the body of GET /api/ships.
+type ShipResponse struct {
+	Symbol   string      `json:"symbol"`
+	Role     string      `json:"role"`
+	Mission  string      `json:"mission"`
+	Status   string      `json:"status"`
+	Waypoint string      `json:"waypoint"`
+	Cargo    m.ShipCargo `json:"cargo"`
+	Fuel     m.ShipFuel  `json:"fuel"`
+	ETA      *time.Time  `json:"eta,omitempty"`
+	// CreditsPerHour is what the ship earned per hour of active time within the earnings window, or nil
+	// until it has been active for a minute.
+	CreditsPerHour *float64 `json:"creditsPerHour,omitempty"`
+	// Conditions are the condition of the ship's frame, reactor, and engine, and Degraded the thresholds they
+	// last dropped below, until the ship is repaired.
+	Conditions []m.ComponentCondition `json:"conditions,omitempty"`
+	Degraded   []m.ConditionDegraded  `json:"degraded,omitempty"`
+	UpdatedAt  time.Time              `json:"updatedAt"`
+}
+
+// ContractResponse is an element of the body of GET /api/contracts.
+type ContractResponse struct {
+	m.Contract
+	// Remaining is the time left until the contract's deadline, as of the response.
+	Remaining time.Duration `json:"remaining"`
+	// OnTrack is whether the contract's delivery rate finishes it before the deadline, as of the last
+	// reconcile. It is nil for contracts that are not being delivered.
+	OnTrack *bool `json:"onTrack,omitempty"`
+}
+
+// LedgerResponse is the body of GET /api/ledger.
+type LedgerResponse struct {
+	Totals  ledger.Totals  `json:"totals"`
+	Entries []ledger.Entry `json:"entries"`
+	// Accuracy compares the profit of settled mission plans with what they realized, keyed by plan kind.
+	Accuracy map[string]ledger.Accuracy `json:"accuracy"`
+}
+
+// StrategyResponse is the body of GET /api/strategy.
+type StrategyResponse struct {
+	Default string            `json:"default"`
+	Roles   map[string]string `json:"roles"`
+}
+
+// PauseResponse is the body of POST /api/pause and POST /api/resume.
+type PauseResponse struct {
+	Paused bool `json:"paused"`
+}
+
+// OrderResponse is the body of POST /api/ships/SHIP/order, whose request body is the m.ManualOrder.
+type OrderResponse struct {
+	Ship  string        `json:"ship"`
+	Order m.ManualOrder `json:"order"`
+}
+
+// StrategyRequest is the body of PUT /api/strategy. An empty Role switches the default strategy.
+type StrategyRequest struct {
+	Role     string `json:"role"`
+	Strategy string `json:"strategy"`
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	snapshot := s.board.Snapshot()
+
+	fleetPlan := s.fleetPlan()
+
+	plan := make(map[string]PlanProgress, len(fleetPlan))
+	for role, target := range fleetPlan {
+		plan[role] = PlanProgress{Target: target}
+	}
+	for _, ship := range snapshot.Ships {
+		progress := plan[ship.Ship.Registration.Role]
+		progress.Current++
+		plan[ship.Ship.Registration.Role] = progress
+	}
+
+	response := StatusResponse{
+		Agent:      snapshot.Agent,
+		Ships:      len(snapshot.Ships),
+		FleetPlan:  plan,
+		Ledger:     s.ledger.Totals(),
+		PausedAt:   snapshot.PausedAt,
+		HomeSystem: snapshot.HomeSystem,
+		Strategies: snapshot.Strategies,
+		TakenAt:    snapshot.TakenAt,
+	}
+	if s.usage != nil {
+		response.Requests = s.usage()
+	}
+
+	writeJSON(w, response)
+}
+
+func (s *Server) handleShips(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	snapshot := s.board.Snapshot()
+
+	ships := make([]ShipResponse, 0, len(snapshot.Ships))
+	for _, ss := range snapshot.Ships {
+		ship := ShipResponse{
+			Symbol:         ss.Ship.Symbol,
+			Role:           ss.Ship.Registration.Role,
+			Mission:        ss.Mission,
+			Status:         ss.Ship.Nav.Status,
+			Waypoint:       ss.Ship.Nav.WaypointSymbol,
+			Cargo:          ss.Ship.Cargo,
+			Fuel:           ss.Ship.Fuel,
+			CreditsPerHour: ss.CreditsPerHour,
+			Conditions:     ss.Ship.Conditions(),
+			Degraded:       ss.Degraded,
+			UpdatedAt:      ss.UpdatedAt,
+		}
+
+		if arrival := ss.Ship.Nav.Route.Arrival; ss.Ship.Nav.Status == "IN_TRANSIT" && arrival.After(snapshot.TakenAt) {
+			ship.ETA = &arrival
+		}
+
+		ships = append(ships, ship)
+	}
+
+	writeJSON(w, ships)
+}
+
+func (s *Server) handleContracts(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	snapshot := s.board.Snapshot()
+
+	contracts := make([]ContractResponse, 0, len(snapshot.Contracts))
+	for _, contract := range snapshot.Contracts {
+		res := ContractResponse{Contract: contract, Remaining: contract.Terms.Deadline.Sub(snapshot.TakenAt)}
+		if progress, ok := snapshot.Deadlines[contract.ID]; ok {
+			onTrack := progress.OnTrack
+			res.OnTrack = &onTrack
+		}
+		contracts = append(contracts, res)
+	}
+
+	writeJSON(w, contracts)
+}
+
+func (s *Server) handleLedger(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	writeJSON(w, LedgerResponse{
+		Totals:   s.ledger.Totals(),
+		Entries:  s.ledger.Entries(),
+		Accuracy: s.ledger.Accuracy(),
+	})
+}
+
+func (s *Server) handleMarket(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	waypoint := strings.TrimPrefix(r.URL.Path, "/api/markets/")
+	observation, ok := s.markets.Get(waypoint)
+	if !ok {
+		http.Error(w, "market "+waypoint+" has not been observed", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, observation)
+}
+
+func (s *Server) handleStrategy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut, http.MethodPost:
+		var req StrategyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.strategies.SetStrategy(req.Role, req.Strategy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fallback, roles := s.strategies.Strategies()
+	writeJSON(w, StrategyResponse{Default: fallback, Roles: roles})
+}
+
+func (s *Server) handleOrder(w http.ResponseWriter, r *http.Request) {
+	symbol, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/ships/"), "/order")
+	if !ok || symbol == "" || strings.Contains(symbol, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var order m.ManualOrder
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.orders.Order(symbol, order); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, OrderResponse{Ship: symbol, Order: order})
+}
+
+// handlePause returns a handler calling action on POST and reporting whether the fleet is paused.
+func (s *Server) handlePause(action func()) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		action()
+		writeJSON(w, PauseResponse{Paused: s.pause.Paused()})
+	}
+}
+
+// allowGet rejects requests that are not GET or HEAD, reporting whether the request may proceed.
+func allowGet(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+
+	return true
+}
+
+// writeJSON writes v as a JSON response body.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
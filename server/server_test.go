@@ -0,0 +1,301 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/api"
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/ledger"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/status"
+)
+
+// newTestServer returns a Server over a board holding one docked command ship and one excavator in transit.
+func newTestServer() *Server {
+	board := status.NewBoard()
+	board.Apply(event.Event{Type: event.AgentUpdated, Data: m.Agent{Symbol: "GOGARIN", Credits: 175000}})
+
+	command := m.Ship{Symbol: "GOGARIN-1"}
+	command.Registration.Role = "COMMAND"
+	command.Nav.Status = "DOCKED"
+	command.Nav.WaypointSymbol = "X1-DF55-20250Z"
+	board.Apply(event.Event{Type: event.ShipReported, Ship: command.Symbol, Data: command})
+
+	excavator := m.Ship{Symbol: "GOGARIN-2"}
+	excavator.Registration.Role = "EXCAVATOR"
+	excavator.Nav.Status = "IN_TRANSIT"
+	excavator.Nav.Route.Arrival = time.Now().Add(time.Hour)
+	board.Apply(event.Event{Type: event.ShipReported, Ship: excavator.Symbol, Data: excavator})
+	board.Apply(event.Event{Type: event.MissionStarted, Ship: excavator.Symbol, Mission: "mine IRON_ORE"})
+
+	board.Apply(event.Event{Type: event.ContractsUpdated, Data: []m.Contract{{ID: "c-1"}}})
+
+	l := ledger.New(10)
+	l.Record(ledger.Entry{Kind: ledger.KindSale, Amount: 450})
+
+	return New("", board, l, func() map[string]int { return map[string]int{"EXCAVATOR": 3, "SURVEYOR": 1} })
+}
+
+// get serves a GET of path and decodes the JSON response into v.
+func get(t *testing.T, s *Server, path string, v interface{}) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s: status %d", path, rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("GET %s: Content-Type %q", path, ct)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("GET %s: %s\n%s", path, err, rec.Body.String())
+	}
+}
+
+func TestStatus(t *testing.T) {
+	var res StatusResponse
+	get(t, newTestServer(), "/api/status", &res)
+
+	if res.Agent.Symbol != "GOGARIN" || res.Agent.Credits != 175000 || res.Ships != 2 {
+		t.Errorf("status = %+v", res)
+	}
+	want := map[string]PlanProgress{
+		"EXCAVATOR": {Current: 1, Target: 3},
+		"SURVEYOR":  {Current: 0, Target: 1},
+		"COMMAND":   {Current: 1, Target: 0},
+	}
+	for role, progress := range want {
+		if res.FleetPlan[role] != progress {
+			t.Errorf("fleet plan %s = %+v, want %+v", role, res.FleetPlan[role], progress)
+		}
+	}
+	if res.Ledger.Income != 450 || res.Ledger.Net != 450 {
+		t.Errorf("ledger totals = %+v", res.Ledger)
+	}
+}
+
+func TestStatusReportsAReloadedFleetPlan(t *testing.T) {
+	plan := map[string]int{"EXCAVATOR": 3}
+	s := New("", status.NewBoard(), ledger.New(0), func() map[string]int { return plan })
+
+	var res StatusResponse
+	get(t, s, "/api/status", &res)
+	if res.FleetPlan["EXCAVATOR"].Target != 3 {
+		t.Fatalf("fleet plan = %+v, want 3 excavators", res.FleetPlan)
+	}
+
+	plan = map[string]int{"EXCAVATOR": 5, "HAULER": 1}
+	get(t, s, "/api/status", &res)
+	if res.FleetPlan["EXCAVATOR"].Target != 5 || res.FleetPlan["HAULER"].Target != 1 {
+		t.Errorf("fleet plan after reload = %+v, want 5 excavators and a hauler", res.FleetPlan)
+	}
+}
+
+func TestStatusReportsRequestUsage(t *testing.T) {
+	var res StatusResponse
+	get(t, newTestServer(), "/api/status", &res)
+	if res.Requests != nil {
+		t.Errorf("requests = %+v without usage", res.Requests)
+	}
+
+	usage := []api.SubsystemUsage{{Subsystem: api.SubsystemMarketPoll, Requests: 600, Share: 0.1, Cap: 0.2, Total: 900}}
+	s := newTestServer()
+	WithRequestUsage(func() []api.SubsystemUsage { return usage })(s)
+
+	get(t, s, "/api/status", &res)
+	if len(res.Requests) != 1 || res.Requests[0] != usage[0] {
+		t.Errorf("requests = %+v, want %+v", res.Requests, usage)
+	}
+}
+
+func TestShips(t *testing.T) {
+	var ships []ShipResponse
+	get(t, newTestServer(), "/api/ships", &ships)
+
+	if len(ships) != 2 {
+		t.Fatalf("ships = %+v", ships)
+	}
+	if docked := ships[0]; docked.Symbol != "GOGARIN-1" || docked.Status != "DOCKED" || docked.Waypoint != "X1-DF55-20250Z" || docked.ETA != nil {
+		t.Errorf("docked ship = %+v", docked)
+	}
+	if moving := ships[1]; moving.Mission != "mine IRON_ORE" || moving.ETA == nil {
+		t.Errorf("ship in transit = %+v", moving)
+	}
+}
+
+func TestContractsAndLedger(t *testing.T) {
+	s := newTestServer()
+
+	var contracts []m.Contract
+	get(t, s, "/api/contracts", &contracts)
+	if len(contracts) != 1 || contracts[0].ID != "c-1" {
+		t.Errorf("contracts = %+v", contracts)
+	}
+
+	var res LedgerResponse
+	get(t, s, "/api/ledger", &res)
+	if len(res.Entries) != 1 || res.Entries[0].Kind != ledger.KindSale || res.Totals.Income != 450 {
+		t.Errorf("ledger = %+v", res)
+	}
+}
+
+func TestRejectsWrites(t *testing.T) {
+	s := newTestServer()
+
+	for _, path := range []string{"/api/status", "/api/ships", "/api/contracts", "/api/ledger"} {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+
+		if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, HEAD" {
+			t.Errorf("POST %s: status %d, Allow %q", path, rec.Code, rec.Header().Get("Allow"))
+		}
+	}
+}
+
+func TestMetricsAreServedOnlyWhenEnabled(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestServer().Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("without metrics: status %d, want 404", rec.Code)
+	}
+
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("gogarin_credits 1\n"))
+	})
+	s := New("", status.NewBoard(), ledger.New(0), nil, WithMetrics(metrics))
+
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "gogarin_credits 1\n" {
+		t.Errorf("with metrics: status %d, body %q", rec.Code, rec.Body.String())
+	}
+}
+
+// strategies is a StrategySwitcher accepting only the strategies it starts with.
+type strategies struct {
+	fallback string
+	byRole   map[string]string
+}
+
+func (s *strategies) Strategies() (string, map[string]string) {
+	return s.fallback, s.byRole
+}
+
+func (s *strategies) SetStrategy(role string, name string) error {
+	if name != "mining" && name != "trading" {
+		return fmt.Errorf("unknown strategy %q", name)
+	}
+	if role == "" {
+		s.fallback = name
+	} else {
+		s.byRole[role] = name
+	}
+	return nil
+}
+
+func TestStrategyIsServedAndSwitched(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestServer().Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/strategy", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("without strategies: status %d, want 404", rec.Code)
+	}
+
+	switcher := &strategies{fallback: "mining", byRole: map[string]string{}}
+	s := New("", status.NewBoard(), ledger.New(0), nil, WithStrategies(switcher))
+
+	put := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/strategy", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := put(`{"role":"EXCAVATOR","strategy":"trading"}`); rec.Code != http.StatusOK {
+		t.Fatalf("switching EXCAVATOR: status %d, %s", rec.Code, rec.Body)
+	}
+
+	var got StrategyResponse
+	get(t, s, "/api/strategy", &got)
+	if got.Default != "mining" || got.Roles["EXCAVATOR"] != "trading" {
+		t.Errorf("strategies = %+v, want mining with EXCAVATOR trading", got)
+	}
+
+	for _, body := range []string{`{"strategy":"gambling"}`, `{"strategy":`} {
+		if rec := put(body); rec.Code != http.StatusBadRequest {
+			t.Errorf("PUT %s: status %d, want 400", body, rec.Code)
+		}
+	}
+	if switcher.fallback != "mining" {
+		t.Errorf("default switched to %q by a rejected request", switcher.fallback)
+	}
+
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/strategy", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE: status %d, want 405", rec.Code)
+	}
+}
+
+// pauser is a PauseControl recording whether it is paused.
+type pauser struct {
+	paused bool
+}
+
+func (p *pauser) Pause()       { p.paused = true }
+func (p *pauser) Resume()      { p.paused = false }
+func (p *pauser) Paused() bool { return p.paused }
+
+func TestPauseAndResume(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestServer().Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/pause", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("without pause control: status %d, want 404", rec.Code)
+	}
+
+	board := status.NewBoard()
+	control := &pauser{}
+	s := New("", board, ledger.New(0), func() map[string]int { return nil }, WithPauseControl(control))
+
+	post := func(path string) PauseResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("POST %s: status %d, %s", path, rec.Code, rec.Body)
+		}
+		var res PauseResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	if res := post("/api/pause"); !res.Paused || !control.paused {
+		t.Errorf("after POST /api/pause: response %+v, paused %t", res, control.paused)
+	}
+
+	pausedAt := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	board.Apply(event.Event{Type: event.FleetPaused, At: pausedAt})
+	var res StatusResponse
+	get(t, s, "/api/status", &res)
+	if res.PausedAt == nil || !res.PausedAt.Equal(pausedAt) {
+		t.Errorf("status paused at %v, want %s", res.PausedAt, pausedAt)
+	}
+
+	if res := post("/api/resume"); res.Paused || control.paused {
+		t.Errorf("after POST /api/resume: response %+v, paused %t", res, control.paused)
+	}
+
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/pause", nil))
+	if rec.Code != http.StatusMethodNotAllowed || control.paused {
+		t.Errorf("GET /api/pause: status %d, paused %t, want 405 and no pause", rec.Code, control.paused)
+	}
+}
@@ -0,0 +1,234 @@
+package status
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/ledger"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+// maxRecentEvents is the number of recent events kept on the Board.
+const maxRecentEvents = 50
+
+// ShipStatus is the latest known state of a ship and what it is doing.
+type ShipStatus struct {
+	Ship m.Ship `json:"ship"`
+	// Capabilities are what the ship's modules and mounts let it do.
+	Capabilities m.ShipCapabilities `json:"capabilities"`
+	// Nickname and Notes are what the operator has noted about the ship locally, if anything.
+	Nickname string `json:"nickname,omitempty"`
+	Notes    string `json:"notes,omitempty"`
+	Mission  string `json:"mission"`
+	// CreditsPerHour is what the ship earned per hour of active time within the earnings window, or nil
+	// until it has been active for a minute.
+	CreditsPerHour *float64 `json:"creditsPerHour,omitempty"`
+	// Degraded are the latest condition thresholds each of the ship's components dropped below, until the ship
+	// is repaired.
+	Degraded  []m.ConditionDegraded `json:"degraded,omitempty"`
+	UpdatedAt time.Time             `json:"updatedAt"`
+}
+
+// Snapshot is a point-in-time copy of the Board.
+type Snapshot struct {
+	Agent     m.Agent       `json:"agent"`
+	Ships     []ShipStatus  `json:"ships"`
+	Contracts []m.Contract  `json:"contracts"`
+	Events    []event.Event `json:"events"`
+	// Deadlines are how the accepted contracts stood against their deadlines at the last reconcile, keyed
+	// by contract ID.
+	Deadlines map[string]m.ContractProgress `json:"deadlines,omitempty"`
+	// PausedAt is when the fleet was paused, or nil while it runs.
+	PausedAt *time.Time `json:"pausedAt,omitempty"`
+	// HomeSystem sums up the home system, or is nil before it is summarized.
+	HomeSystem *m.SystemSummary `json:"homeSystem,omitempty"`
+	// Strategies are what the ships following each strategy earned per hour within the earnings window.
+	Strategies []ledger.StrategyRate `json:"strategies,omitempty"`
+	TakenAt    time.Time             `json:"takenAt"`
+}
+
+/*
+📋 Board
+*/
+
+// Board maintains the fleet status from the event bus, for the TUI and other read-only views.
+type Board struct {
+	mu        sync.RWMutex
+	agent     m.Agent
+	ships     map[string]*ShipStatus
+	contracts []m.Contract
+	deadlines map[string]m.ContractProgress
+	events    []event.Event
+	pausedAt  *time.Time
+	home      *m.SystemSummary
+	// metadata names ships in snapshots. A nil store names none.
+	metadata *store.MetadataStore
+	// earnings rates ships and strategies in snapshots. A nil Earnings rates none.
+	earnings *ledger.Earnings
+}
+
+// NewBoard creates a new, empty Board.
+func NewBoard() *Board {
+	return &Board{
+		ships: make(map[string]*ShipStatus),
+	}
+}
+
+// SetMetadata names ships in snapshots by their nicknames in metadata.
+func (b *Board) SetMetadata(metadata *store.MetadataStore) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.metadata = metadata
+}
+
+// SetEarnings rates ships and strategies in snapshots by what they earn per hour in earnings.
+func (b *Board) SetEarnings(earnings *ledger.Earnings) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.earnings = earnings
+}
+
+// Follow applies events to the Board until the channel is closed.
+func (b *Board) Follow(events <-chan event.Event) {
+	for e := range events {
+		b.Apply(e)
+	}
+}
+
+// Apply updates the Board with a single event.
+func (b *Board) Apply(e event.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch data := e.Data.(type) {
+	case m.Agent:
+		b.agent = data
+	case m.Ship:
+		b.ship(data.Symbol).Ship = data
+	case m.ShipPurchase:
+		b.ship(data.Ship.Symbol).Ship = data.Ship
+	case []m.Contract:
+		b.contracts = append([]m.Contract(nil), data...)
+	case m.Contract:
+		b.updateContract(data)
+	case []m.ContractProgress:
+		b.deadlines = make(map[string]m.ContractProgress, len(data))
+		for _, progress := range data {
+			b.deadlines[progress.ContractID] = progress
+		}
+	case m.SystemSummary:
+		b.home = &data
+	case m.ConditionDegraded:
+		b.ship(data.Ship).degrade(data)
+	}
+
+	switch e.Type {
+	case event.FleetPaused:
+		at := e.At
+		b.pausedAt = &at
+	case event.FleetResumed:
+		b.pausedAt = nil
+	case event.ShipRepaired:
+		b.ship(e.Ship).Degraded = nil
+	}
+
+	if e.Ship != "" {
+		s := b.ship(e.Ship)
+		s.UpdatedAt = e.At
+		if e.Type == event.MissionStarted {
+			s.Mission = e.Mission
+		}
+	}
+
+	if e.Type != event.ShipReported && e.Type != event.AgentUpdated && e.Type != event.ContractsUpdated &&
+		e.Type != event.ContractProgressUpdated {
+		b.events = append(b.events, e)
+		if len(b.events) > maxRecentEvents {
+			b.events = b.events[len(b.events)-maxRecentEvents:]
+		}
+	}
+}
+
+// Snapshot returns a copy of the Board, with ships sorted by symbol and events oldest first.
+func (b *Board) Snapshot() Snapshot {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	snapshot := Snapshot{
+		Agent:      b.agent,
+		Ships:      make([]ShipStatus, 0, len(b.ships)),
+		Contracts:  append([]m.Contract(nil), b.contracts...),
+		Events:     append([]event.Event(nil), b.events...),
+		Deadlines:  make(map[string]m.ContractProgress, len(b.deadlines)),
+		PausedAt:   b.pausedAt,
+		HomeSystem: b.home,
+		TakenAt:    time.Now(),
+	}
+
+	for id, progress := range b.deadlines {
+		snapshot.Deadlines[id] = progress
+	}
+
+	rates := b.earnings.Rates(snapshot.TakenAt)
+	snapshot.Strategies = rates.Strategies
+
+	for _, s := range b.ships {
+		ship := *s
+		metadata, _ := b.metadata.Get(ship.Ship.Symbol)
+		ship.Nickname, ship.Notes = metadata.Nickname, metadata.Notes
+		ship.Capabilities = m.CapabilitiesOf(ship.Ship)
+		if rate, ok := rates.Ship(ship.Ship.Symbol); ok && rate.Rated() {
+			perHour := rate.PerHour
+			ship.CreditsPerHour = &perHour
+		}
+		snapshot.Ships = append(snapshot.Ships, ship)
+	}
+
+	sort.Slice(snapshot.Ships, func(i, j int) bool {
+		return snapshot.Ships[i].Ship.Symbol < snapshot.Ships[j].Ship.Symbol
+	})
+
+	return snapshot
+}
+
+// degrade replaces the degradation of a component with a newer one. The slice is replaced rather than changed
+// in place, since snapshots share it.
+func (s *ShipStatus) degrade(degraded m.ConditionDegraded) {
+	updated := []m.ConditionDegraded{degraded}
+	for _, d := range s.Degraded {
+		if d.Component != degraded.Component {
+			updated = append(updated, d)
+		}
+	}
+	sort.Slice(updated, func(i, j int) bool { return updated[i].Component < updated[j].Component })
+
+	s.Degraded = updated
+}
+
+// ship returns the status entry for a ship symbol, creating it if needed.
+func (b *Board) ship(symbol string) *ShipStatus {
+	s, ok := b.ships[symbol]
+	if !ok {
+		s = &ShipStatus{Ship: m.Ship{Symbol: symbol}}
+		b.ships[symbol] = s
+	}
+
+	return s
+}
+
+// updateContract replaces or adds a contract by ID.
+func (b *Board) updateContract(contract m.Contract) {
+	for i := range b.contracts {
+		if b.contracts[i].ID == contract.ID {
+			b.contracts[i] = contract
+			return
+		}
+	}
+
+	b.contracts = append(b.contracts, contract)
+}
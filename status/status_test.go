@@ -0,0 +1,139 @@
+package status
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/store"
+)
+
+var at = time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestApplyTracksShipsAndMissions(t *testing.T) {
+	board := NewBoard()
+
+	ship := m.Ship{Symbol: "GOGARIN-2"}
+	ship.Nav.Status = "IN_ORBIT"
+	board.Apply(event.Event{Type: event.ShipReported, At: at, Ship: "GOGARIN-2", Data: ship})
+	board.Apply(event.Event{Type: event.MissionStarted, At: at.Add(time.Second), Ship: "GOGARIN-2", Mission: "mine IRON_ORE"})
+	board.Apply(event.Event{Type: event.ShipReported, At: at, Ship: "GOGARIN-1", Data: m.Ship{Symbol: "GOGARIN-1"}})
+
+	snapshot := board.Snapshot()
+	if len(snapshot.Ships) != 2 || snapshot.Ships[0].Ship.Symbol != "GOGARIN-1" {
+		t.Fatalf("ships = %+v, want both sorted by symbol", snapshot.Ships)
+	}
+	s := snapshot.Ships[1]
+	if s.Ship.Nav.Status != "IN_ORBIT" || s.Mission != "mine IRON_ORE" || !s.UpdatedAt.Equal(at.Add(time.Second)) {
+		t.Errorf("GOGARIN-2 = %+v", s)
+	}
+}
+
+func TestSnapshotNamesShipsFromMetadata(t *testing.T) {
+	metadata, err := store.OpenMetadataStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	board := NewBoard()
+	board.SetMetadata(metadata)
+	board.Apply(event.Event{Type: event.ShipReported, At: at, Ship: "GOGARIN-2", Data: m.Ship{Symbol: "GOGARIN-2"}})
+
+	if s := board.Snapshot().Ships[0]; s.Nickname != "" || s.Notes != "" {
+		t.Errorf("unnamed ship = %+v", s)
+	}
+
+	// Renames show in the next snapshot, without the ship reporting again.
+	if err := metadata.Rename("GOGARIN-2", "Rockhound"); err != nil {
+		t.Fatal(err)
+	}
+	if err := metadata.Annotate("GOGARIN-2", "Slow laser"); err != nil {
+		t.Fatal(err)
+	}
+	if s := board.Snapshot().Ships[0]; s.Nickname != "Rockhound" || s.Notes != "Slow laser" || s.Ship.Symbol != "GOGARIN-2" {
+		t.Errorf("named ship = %+v", s)
+	}
+}
+
+func TestApplyTracksAgentAndContracts(t *testing.T) {
+	board := NewBoard()
+
+	board.Apply(event.Event{Type: event.AgentUpdated, Data: m.Agent{Symbol: "GOGARIN", Credits: 1000}})
+	board.Apply(event.Event{Type: event.ContractsUpdated, Data: []m.Contract{{ID: "c-1"}, {ID: "c-2"}}})
+	board.Apply(event.Event{Type: event.ContractAccepted, Data: m.Contract{ID: "c-2", Accepted: true}})
+	board.Apply(event.Event{Type: event.ContractAccepted, Data: m.Contract{ID: "c-3", Accepted: true}})
+
+	snapshot := board.Snapshot()
+	if snapshot.Agent.Credits != 1000 {
+		t.Errorf("agent = %+v", snapshot.Agent)
+	}
+	if len(snapshot.Contracts) != 3 || !snapshot.Contracts[1].Accepted || snapshot.Contracts[2].ID != "c-3" {
+		t.Errorf("contracts = %+v", snapshot.Contracts)
+	}
+}
+
+func TestApplyKeepsRecentNotableEvents(t *testing.T) {
+	board := NewBoard()
+
+	board.Apply(event.Event{Type: event.ShipReported, Ship: "GOGARIN-1"})
+	board.Apply(event.Event{Type: event.AgentUpdated})
+	board.Apply(event.Event{Type: event.ContractsUpdated})
+	for i := 0; i < maxRecentEvents+5; i++ {
+		board.Apply(event.Event{Type: event.CargoSold, Message: fmt.Sprint(i)})
+	}
+
+	events := board.Snapshot().Events
+	if len(events) != maxRecentEvents {
+		t.Fatalf("kept %d events, want %d", len(events), maxRecentEvents)
+	}
+	if events[0].Message != "5" || events[len(events)-1].Message != fmt.Sprint(maxRecentEvents+4) {
+		t.Errorf("kept events %s to %s, want the newest", events[0].Message, events[len(events)-1].Message)
+	}
+}
+
+func TestApplyTracksWhenTheFleetIsPaused(t *testing.T) {
+	board := NewBoard()
+	if paused := board.Snapshot().PausedAt; paused != nil {
+		t.Fatalf("paused at %s before any pause", paused)
+	}
+
+	board.Apply(event.Event{Type: event.FleetPaused, At: at})
+	if paused := board.Snapshot().PausedAt; paused == nil || !paused.Equal(at) {
+		t.Errorf("paused at %v, want %s", paused, at)
+	}
+
+	board.Apply(event.Event{Type: event.FleetResumed, At: at.Add(time.Minute)})
+	if paused := board.Snapshot().PausedAt; paused != nil {
+		t.Errorf("paused at %s after resuming", paused)
+	}
+}
+
+func TestSnapshotIsACopy(t *testing.T) {
+	board := NewBoard()
+	board.Apply(event.Event{Type: event.ContractsUpdated, Data: []m.Contract{{ID: "c-1"}}})
+	board.Apply(event.Event{Type: event.CargoSold, Message: "sold"})
+
+	snapshot := board.Snapshot()
+	snapshot.Contracts[0].ID = "changed"
+	snapshot.Events[0].Message = "changed"
+
+	again := board.Snapshot()
+	if again.Contracts[0].ID != "c-1" || again.Events[0].Message != "sold" {
+		t.Errorf("changing a snapshot changed the board: %+v", again)
+	}
+}
+
+func TestFollowAppliesUntilClosed(t *testing.T) {
+	bus := event.NewBus()
+	board := NewBoard()
+	ch := bus.Subscribe(4)
+
+	bus.Publish(event.Event{Type: event.AgentUpdated, Data: m.Agent{Symbol: "GOGARIN"}})
+	bus.Close()
+	board.Follow(ch)
+
+	if board.Snapshot().Agent.Symbol != "GOGARIN" {
+		t.Error("followed event not applied")
+	}
+}
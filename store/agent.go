@@ -0,0 +1,266 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/clock"
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+// ErrInsufficientCredits is returned when a reservation would leave fewer credits than the floor.
+var ErrInsufficientCredits = errors.New("insufficient credits")
+
+// AgentGetter fetches the agent. It is satisfied by api.ClientAPI.
+type AgentGetter interface {
+	GetMyAgent() (*m.Agent, error)
+}
+
+/*
+💰 AgentState
+*/
+
+// AgentState is the latest known state of the agent, shared by every bot. Spending goes through
+// ReserveCredits, so concurrent missions cannot both spend the same credits.
+//
+// With a source, reads of an agent older than the TTL are served from memory while a single refresh
+// runs in the background. Transaction responses carry the agent, so Update keeps it fresh without fetching.
+type AgentState struct {
+	mu         sync.Mutex
+	agent      m.Agent
+	floor      int64
+	reserved   int64
+	updatedAt  time.Time
+	refreshing bool
+	// updates counts the updates to the agent, so Audit can tell when one raced its fetch.
+	updates int
+
+	source AgentGetter
+	ttl    time.Duration
+	clock  clock.Clock
+	// fetchMu serializes fetches, so concurrent refreshes make a single call.
+	fetchMu sync.Mutex
+}
+
+// AgentOption configures an AgentState.
+type AgentOption func(*AgentState)
+
+// WithAgentSource refreshes the agent from source once it is older than ttl.
+func WithAgentSource(source AgentGetter, ttl time.Duration) AgentOption {
+	return func(s *AgentState) {
+		s.source = source
+		s.ttl = ttl
+	}
+}
+
+// NewAgentState creates an AgentState for agent that refuses to reserve credits below floor.
+func NewAgentState(agent m.Agent, floor int64, opts ...AgentOption) *AgentState {
+	s := &AgentState{agent: agent, floor: floor, updatedAt: time.Now(), clock: clock.Real}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Agent returns a copy of the agent.
+func (s *AgentState) Agent() m.Agent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refreshIfStale()
+
+	return s.agent
+}
+
+// Credits returns the agent's credits, including those reserved.
+func (s *AgentState) Credits() m.Credits {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refreshIfStale()
+
+	return s.agent.Credits
+}
+
+// Available returns the credits that can still be reserved.
+func (s *AgentState) Available() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refreshIfStale()
+
+	return int64(s.unreserved()) - s.floor
+}
+
+// SetFloor changes the balance reservations never take the agent below. Outstanding reservations are kept.
+func (s *AgentState) SetFloor(floor int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.floor = floor
+}
+
+// SetClock ages the agent by c instead of the wall clock, as if it was just updated.
+func (s *AgentState) SetClock(c clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clock = c
+	s.updatedAt = c.Now()
+}
+
+// Update replaces the agent with fresh data from the API. Outstanding reservations are kept.
+func (s *AgentState) Update(agent m.Agent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.agent = agent
+	s.updatedAt = s.clock.Now()
+	s.updates++
+}
+
+// Refresh fetches the agent from the source if it is older than the TTL, or always when force is set.
+// Concurrent refreshes make a single call. Without a source, Refresh does nothing.
+func (s *AgentState) Refresh(force bool) error {
+	if s.source == nil {
+		return nil
+	}
+
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+
+	// Another refresh may have fetched the agent while this one waited.
+	if !force && s.fresh() {
+		return nil
+	}
+
+	agent, err := s.source.GetMyAgent()
+	if err != nil {
+		return err
+	}
+	s.Update(*agent)
+
+	return nil
+}
+
+// Audit fetches the agent from the source and replaces the tracked agent with it, returning how far the
+// tracked credits had drifted from the actual balance. If a transaction updated the agent while it was being
+// fetched, the fetched agent may predate it, so nothing is compared or replaced and ok is false. Without a
+// source, Audit does nothing.
+func (s *AgentState) Audit() (drift m.CreditDrift, ok bool, err error) {
+	if s.source == nil {
+		return m.CreditDrift{}, false, nil
+	}
+
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+
+	s.mu.Lock()
+	updates := s.updates
+	s.mu.Unlock()
+
+	agent, err := s.source.GetMyAgent()
+	if err != nil {
+		return m.CreditDrift{}, false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.updates != updates {
+		return m.CreditDrift{}, false, nil
+	}
+
+	drift = m.CreditDrift{Tracked: s.agent.Credits, Actual: agent.Credits}
+	s.agent = *agent
+	s.updatedAt = s.clock.Now()
+	s.updates++
+
+	return drift, true, nil
+}
+
+// fresh checks if the agent is younger than the TTL.
+func (s *AgentState) fresh() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.clock.Now().Sub(s.updatedAt) < s.ttl
+}
+
+// refreshIfStale starts a background refresh when the agent is older than the TTL and none is running.
+// It must be called with mu held.
+func (s *AgentState) refreshIfStale() {
+	if s.source == nil || s.refreshing || s.clock.Now().Sub(s.updatedAt) < s.ttl {
+		return
+	}
+	s.refreshing = true
+
+	go func() {
+		// A failed refresh leaves the agent stale, so the next read tries again.
+		_ = s.Refresh(false)
+
+		s.mu.Lock()
+		s.refreshing = false
+		s.mu.Unlock()
+	}()
+}
+
+// CanAfford checks if cost can be reserved now without going below the floor.
+func (s *AgentState) CanAfford(cost int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refreshIfStale()
+
+	return s.unreserved().Afford(cost, s.floor)
+}
+
+// unreserved returns the credits not held by a reservation. Callers must hold mu.
+func (s *AgentState) unreserved() m.Credits {
+	return s.agent.Credits - m.Credits(s.reserved)
+}
+
+// ReserveCredits holds amount credits until release is called, which must happen once the transaction
+// they pay for has succeeded or failed. It returns ErrInsufficientCredits, and reserves nothing, if the
+// reservation would leave fewer credits than the floor. Calling release more than once has no effect.
+func (s *AgentState) ReserveCredits(amount int64) (release func(), err error) {
+	if amount < 0 {
+		return nil, fmt.Errorf("cannot reserve %d credits", amount)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.unreserved().Afford(amount, s.floor) {
+		return nil, fmt.Errorf("%w: reserving %d of %d available", ErrInsufficientCredits, amount, int64(s.unreserved())-s.floor)
+	}
+	s.reserved += amount
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			s.reserved -= amount
+		})
+	}, nil
+}
+
+// Follow updates the agent from events until the channel is closed.
+func (s *AgentState) Follow(events <-chan event.Event) {
+	for e := range events {
+		if e.Type != event.AgentUpdated {
+			continue
+		}
+
+		if agent, ok := e.Data.(m.Agent); ok {
+			s.Update(agent)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package store
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+func TestReserveCreditsConcurrentlyAgainstBarelySufficientBalance(t *testing.T) {
+	for run := 0; run < 100; run++ {
+		// Either purchase fits above the floor, but not both.
+		s := NewAgentState(m.Agent{Credits: 1500}, 500)
+
+		var (
+			wg        sync.WaitGroup
+			start     = make(chan struct{})
+			successes = make(chan func(), 2)
+			failures  = make(chan error, 2)
+		)
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+
+				release, err := s.ReserveCredits(600)
+				if err != nil {
+					failures <- err
+					return
+				}
+				successes <- release
+			}()
+		}
+		close(start)
+		wg.Wait()
+		close(successes)
+		close(failures)
+
+		if len(successes) != 1 {
+			t.Fatalf("run %d: %d reservations succeeded, want exactly 1", run, len(successes))
+		}
+		if err := <-failures; !errors.Is(err, ErrInsufficientCredits) {
+			t.Fatalf("run %d: refused reservation err = %v, want %v", run, err, ErrInsufficientCredits)
+		}
+
+		release := <-successes
+		release()
+		release()
+		if available := s.Available(); available != 1000 {
+			t.Fatalf("run %d: %d available after releasing twice, want 1000", run, available)
+		}
+	}
+}
+
+func TestReserveCreditsRefusesNegativeAmounts(t *testing.T) {
+	s := NewAgentState(m.Agent{Credits: 1000}, 0)
+
+	if _, err := s.ReserveCredits(-100); err == nil {
+		t.Fatal("reserving a negative amount succeeded")
+	}
+	if available := s.Available(); available != 1000 {
+		t.Fatalf("%d available, want 1000", available)
+	}
+}
+
+func TestReserveCreditsKeepsTheFloor(t *testing.T) {
+	tests := []struct {
+		name     string
+		credits  m.Credits
+		floor    int64
+		held     int64
+		amount   int64
+		want     bool
+		wantLeft int64
+	}{
+		{"plenty", 10000, 0, 0, 2000, true, 8000},
+		{"down to the floor", 10000, 2000, 0, 8000, true, 0},
+		{"into the floor", 10000, 2000, 0, 8001, false, 8000},
+		{"already held", 10000, 2000, 5000, 3001, false, 3000},
+		{"rest of what is free", 10000, 2000, 5000, 3000, true, 0},
+		{"nothing", 0, 0, 0, 0, true, 0},
+		{"overdrawn", -500, 0, 0, 0, false, -500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewAgentState(m.Agent{Credits: tt.credits}, tt.floor)
+			if tt.held > 0 {
+				if _, err := s.ReserveCredits(tt.held); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := s.CanAfford(tt.amount); got != tt.want {
+				t.Errorf("CanAfford(%d) = %t, want %t", tt.amount, got, tt.want)
+			}
+
+			_, err := s.ReserveCredits(tt.amount)
+			if (err == nil) != tt.want {
+				t.Errorf("ReserveCredits(%d) err = %v, want success %t", tt.amount, err, tt.want)
+			}
+			if !tt.want && !errors.Is(err, ErrInsufficientCredits) {
+				t.Errorf("ReserveCredits(%d) err = %v, want %v", tt.amount, err, ErrInsufficientCredits)
+			}
+			if left := s.Available(); left != tt.wantLeft {
+				t.Errorf("%d available after reserving, want %d", left, tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestUpdateKeepsReservations(t *testing.T) {
+	s := NewAgentState(m.Agent{Credits: 1000}, 0)
+
+	release, err := s.ReserveCredits(400)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Update(m.Agent{Credits: 2000})
+	if available := s.Available(); available != 1600 {
+		t.Fatalf("%d available after an update, want 1600", available)
+	}
+
+	release()
+	if available, credits := s.Available(), s.Credits(); available != 2000 || credits != 2000 {
+		t.Fatalf("after release: %d available of %d credits, want 2000 of 2000", available, credits)
+	}
+}
+
+// agentSource is an AgentGetter that counts its calls and answers with credits after a short delay.
+type agentSource struct {
+	calls   atomic.Int32
+	credits m.Credits
+}
+
+func (a *agentSource) GetMyAgent() (*m.Agent, error) {
+	a.calls.Add(1)
+	time.Sleep(10 * time.Millisecond)
+
+	return &m.Agent{Symbol: "GOGARIN", Credits: a.credits}, nil
+}
+
+// stale returns an AgentState refreshed from source whose agent is already older than its TTL.
+func stale(source *agentSource) *AgentState {
+	s := NewAgentState(m.Agent{Symbol: "GOGARIN", Credits: 1000}, 0, WithAgentSource(source, time.Minute))
+	s.updatedAt = time.Now().Add(-time.Hour)
+
+	return s
+}
+
+// settled waits for the background refresh of s to finish.
+func settled(t *testing.T, s *AgentState) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		s.mu.Lock()
+		refreshing := s.refreshing
+		s.mu.Unlock()
+		if !refreshing {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("refresh did not finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestABurstOfStaleReadsFetchesTheAgentOnce(t *testing.T) {
+	source := &agentSource{credits: 2000}
+	s := stale(source)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			switch i % 4 {
+			case 0:
+				s.Credits()
+			case 1:
+				s.Available()
+			case 2:
+				s.Agent()
+			default:
+				s.CanAfford(100)
+			}
+		}(i)
+	}
+	wg.Wait()
+	settled(t, s)
+
+	if calls := source.calls.Load(); calls != 1 {
+		t.Fatalf("%d GetMyAgent calls for a burst of reads, want 1", calls)
+	}
+	if credits := s.Credits(); credits != 2000 {
+		t.Errorf("credits = %d after the refresh, want the fetched 2000", credits)
+	}
+
+	// Reads within the TTL are served from memory.
+	for i := 0; i < 10; i++ {
+		s.Available()
+	}
+	settled(t, s)
+	if calls := source.calls.Load(); calls != 1 {
+		t.Errorf("%d GetMyAgent calls after reads of a fresh agent, want still 1", calls)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	source := &agentSource{credits: 2000}
+	s := stale(source)
+
+	// Concurrent refreshes of a stale agent make a single call.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.Refresh(false); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls := source.calls.Load(); calls != 1 {
+		t.Fatalf("%d GetMyAgent calls for concurrent refreshes, want 1", calls)
+	}
+
+	if err := s.Refresh(false); err != nil || source.calls.Load() != 1 {
+		t.Errorf("refreshing a fresh agent: err = %v, %d calls, want no fetch", err, source.calls.Load())
+	}
+	if err := s.Refresh(true); err != nil || source.calls.Load() != 2 {
+		t.Errorf("forced refresh: err = %v, %d calls, want a fetch", err, source.calls.Load())
+	}
+
+	// Without a source there is nothing to fetch from.
+	if err := NewAgentState(m.Agent{}, 0).Refresh(true); err != nil {
+		t.Errorf("refreshing without a source: %s", err)
+	}
+}
+
+func TestUpdateFromATransactionSavesAFetch(t *testing.T) {
+	source := &agentSource{credits: 2000}
+	s := stale(source)
+
+	// A sale returned the agent, with its new balance.
+	s.Update(m.Agent{Symbol: "GOGARIN", Credits: 1500})
+
+	if credits := s.Credits(); credits != 1500 {
+		t.Errorf("credits = %d, want the transaction's 1500", credits)
+	}
+	settled(t, s)
+	if calls := source.calls.Load(); calls != 0 {
+		t.Errorf("%d GetMyAgent calls after an update, want none", calls)
+	}
+}
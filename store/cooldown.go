@@ -0,0 +1,83 @@
+package store
+
+import (
+	"time"
+)
+
+// cooldownWindow is the number of a ship's latest cooldowns its rolling average is taken over.
+const cooldownWindow = 10
+
+/*
+⚛ CooldownStats
+*/
+
+// CooldownStats holds the lengths of the reactor cooldowns each ship's extractions trigger, which scale with
+// its mounts, so throughput can be planned from a rolling average. Methods are safe to call on a nil
+// CooldownStats, which records nothing.
+type CooldownStats struct {
+	// lengths are each ship's latest cooldowns, oldest first.
+	lengths *Map[string, []time.Duration]
+}
+
+// NewCooldownStats creates an empty CooldownStats.
+func NewCooldownStats() *CooldownStats {
+	return &CooldownStats{lengths: NewMap[string, []time.Duration]()}
+}
+
+// Record adds the length of a cooldown a ship's extraction triggered. Cooldowns without a length are ignored.
+func (s *CooldownStats) Record(shipSymbol string, totalSeconds int) {
+	if s == nil || totalSeconds <= 0 {
+		return
+	}
+
+	s.lengths.Update(shipSymbol, func(lengths []time.Duration, _ bool) ([]time.Duration, bool) {
+		// The lengths are copied, so a slice already read is never changed in place.
+		lengths = append(append(make([]time.Duration, 0, len(lengths)+1), lengths...), time.Duration(totalSeconds)*time.Second)
+		if len(lengths) > cooldownWindow {
+			lengths = lengths[len(lengths)-cooldownWindow:]
+		}
+		return lengths, true
+	})
+}
+
+// Average returns the rolling average of a ship's cooldowns, reporting false if none is recorded.
+func (s *CooldownStats) Average(shipSymbol string) (time.Duration, bool) {
+	if s == nil {
+		return 0, false
+	}
+
+	lengths, _ := s.lengths.Get(shipSymbol)
+
+	return meanCooldown(lengths)
+}
+
+// Averages returns the rolling average cooldown of every ship with one recorded, keyed by ship symbol.
+func (s *CooldownStats) Averages() map[string]time.Duration {
+	if s == nil {
+		return nil
+	}
+
+	averages := make(map[string]time.Duration, s.lengths.Len())
+	s.lengths.Range(func(ship string, lengths []time.Duration, _ time.Time) bool {
+		if avg, ok := meanCooldown(lengths); ok {
+			averages[ship] = avg
+		}
+		return true
+	})
+
+	return averages
+}
+
+// meanCooldown returns the mean of lengths, reporting false if there are none.
+func meanCooldown(lengths []time.Duration) (time.Duration, bool) {
+	if len(lengths) == 0 {
+		return 0, false
+	}
+
+	var sum time.Duration
+	for _, length := range lengths {
+		sum += length
+	}
+
+	return sum / time.Duration(len(lengths)), true
+}
@@ -0,0 +1,38 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCooldownStatsAveragesTheLatestWindow(t *testing.T) {
+	s := NewCooldownStats()
+
+	// The first two cooldowns fall out of the window of ten.
+	for _, seconds := range []int{1000, 1000, 60, 60, 60, 60, 60, 80, 80, 80, 80, 80} {
+		s.Record("MOCK-2", seconds)
+	}
+	s.Record("MOCK-2", 0)
+	s.Record("MOCK-3", 30)
+
+	if avg, ok := s.Average("MOCK-2"); !ok || avg != 70*time.Second {
+		t.Fatalf("MOCK-2 averages %s, want 1m10s", avg)
+	}
+	if _, ok := s.Average("MOCK-1"); ok {
+		t.Fatal("a ship without cooldowns has an average")
+	}
+
+	averages := s.Averages()
+	if len(averages) != 2 || averages["MOCK-3"] != 30*time.Second {
+		t.Fatalf("averages = %v, want MOCK-2 and MOCK-3 at 30s", averages)
+	}
+}
+
+func TestNilCooldownStatsRecordsNothing(t *testing.T) {
+	var s *CooldownStats
+
+	s.Record("MOCK-2", 60)
+	if _, ok := s.Average("MOCK-2"); ok || s.Averages() != nil {
+		t.Fatal("a nil CooldownStats reports an average")
+	}
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+/*
+⛽ FuelStations
+*/
+
+// FuelStations finds the waypoints of a system where ships can buy fuel: fuel stations, and waypoints whose
+// recorded market trades FUEL. It reads the shared SystemKnowledge and MarketStore, so it learns of new
+// fuel sellers as markets are recorded.
+type FuelStations struct {
+	systems *SystemKnowledge
+	markets *MarketStore
+}
+
+// NewFuelStations creates a FuelStations reading systems and markets.
+func NewFuelStations(systems *SystemKnowledge, markets *MarketStore) *FuelStations {
+	return &FuelStations{systems: systems, markets: markets}
+}
+
+// In returns the waypoints of a system that sell fuel.
+func (f *FuelStations) In(systemSymbol string) ([]m.Waypoint, error) {
+	waypoints, err := f.systems.Layout(systemSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	var stations []m.Waypoint
+	for _, waypoint := range waypoints {
+		if f.SellsFuel(waypoint) {
+			stations = append(stations, waypoint)
+		}
+	}
+
+	return stations, nil
+}
+
+// SellsFuel checks if a waypoint is a fuel station or its recorded market trades FUEL.
+func (f *FuelStations) SellsFuel(waypoint m.Waypoint) bool {
+	if waypoint.IsType("FUEL_STATION") {
+		return true
+	}
+
+	observation, ok := f.markets.Get(waypoint.Symbol)
+
+	return ok && observation.Market.TradeTypeOf("FUEL") != ""
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+func TestFuelStations(t *testing.T) {
+	lister := &countingLister{waypoints: []m.Waypoint{
+		{Symbol: "X1-MK1-A1", SystemSymbol: "X1-MK1", Type: "PLANET"},
+		{Symbol: "X1-MK1-B2", SystemSymbol: "X1-MK1", Type: "ASTEROID_FIELD"},
+		{Symbol: "X1-MK1-C3", SystemSymbol: "X1-MK1", Type: "FUEL_STATION"},
+		{Symbol: "X1-MK1-D4", SystemSymbol: "X1-MK1", Type: "MOON"},
+	}}
+	markets := NewMarketStore()
+	markets.Record(m.Market{Symbol: "X1-MK1-A1", Exchange: []m.TradeGood{{Symbol: "FUEL"}}}, time.Now())
+	markets.Record(m.Market{Symbol: "X1-MK1-D4", Exports: []m.TradeGood{{Symbol: "IRON"}}}, time.Now())
+	stations := NewFuelStations(NewSystemKnowledge(lister), markets)
+
+	got, err := stations.In("X1-MK1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var symbols []string
+	for _, waypoint := range got {
+		symbols = append(symbols, waypoint.Symbol)
+	}
+	if len(symbols) != 2 || symbols[0] != "X1-MK1-A1" || symbols[1] != "X1-MK1-C3" {
+		t.Errorf("stations = %v, want the market trading FUEL and the fuel station", symbols)
+	}
+
+	// A market recorded later is found without refetching the system.
+	markets.Record(m.Market{Symbol: "X1-MK1-D4", Imports: []m.TradeGood{{Symbol: "FUEL"}}}, time.Now())
+	if got, _ := stations.In("X1-MK1"); len(got) != 3 {
+		t.Errorf("stations after recording X1-MK1-D4 = %+v, want it among them", got)
+	}
+}
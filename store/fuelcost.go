@@ -0,0 +1,214 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/lib"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+const (
+	// fuelCostObservations is the number of navigations of a route before its learned cost replaces the
+	// formula's estimate.
+	fuelCostObservations = 3
+	// fuelCostWindow is the number of a route's latest navigations its learned cost is averaged over.
+	fuelCostWindow = 10
+)
+
+// FuelRoute is the fuel ships actually burned flying from one waypoint to another in a flight mode.
+type FuelRoute struct {
+	Origin      string `json:"origin"`
+	Destination string `json:"destination"`
+	FlightMode  string `json:"flightMode"`
+	// Consumed are the latest navigations' fuel, oldest first.
+	Consumed []int `json:"consumed"`
+}
+
+// fuelRouteKey identifies a route flown in a flight mode.
+type fuelRouteKey struct {
+	origin      string
+	destination string
+	flightMode  string
+}
+
+// fuelCostFile is the layout of the fuel cost file.
+type fuelCostFile struct {
+	// ResetDate is the server reset the routes were learned in.
+	ResetDate string      `json:"resetDate"`
+	Routes    []FuelRoute `json:"routes"`
+}
+
+/*
+⛽ FuelCosts
+*/
+
+// FuelCosts learns the fuel each route actually burns, which drifts from the formula with engine condition and
+// rounding. Once a route has been flown often enough in a flight mode, the average of its latest navigations
+// replaces the formula's estimate. Routes are learned per server reset, and every change is written to the fuel
+// cost file, when there is one. Methods are safe to call on a nil FuelCosts, which learns nothing and estimates
+// by the formula.
+type FuelCosts struct {
+	// mu serializes learning, so the file is written with each navigation in turn.
+	mu        sync.Mutex
+	path      string
+	resetDate string
+	routes    *Map[fuelRouteKey, []int]
+}
+
+// OpenFuelCosts loads the routes learned at path, starting empty if the file does not exist or was learned in
+// a reset other than resetDate. An empty resetDate keeps whatever the file holds. An empty path keeps the
+// routes in memory only.
+func OpenFuelCosts(path string, resetDate string) (*FuelCosts, error) {
+	c := &FuelCosts{path: path, resetDate: resetDate, routes: NewMap[fuelRouteKey, []int]()}
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return c, nil
+	case err != nil:
+		return nil, err
+	}
+
+	var file fuelCostFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	if resetDate != "" && file.ResetDate != resetDate {
+		return c, nil
+	}
+
+	c.resetDate = file.ResetDate
+	for _, route := range file.Routes {
+		c.routes.Set(fuelRouteKey{route.Origin, route.Destination, route.FlightMode}, route.Consumed)
+	}
+
+	return c, nil
+}
+
+// Record adds the fuel a navigation from origin to destination in a flight mode burned. Navigations that go
+// nowhere are ignored.
+func (c *FuelCosts) Record(origin string, destination string, flightMode string, consumed int) error {
+	if c == nil || origin == destination || consumed < 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.routes.Update(fuelRouteKey{origin, destination, flightMode}, func(consumptions []int, _ bool) ([]int, bool) {
+		// The consumptions are copied, so a slice already read is never changed in place.
+		consumptions = append(append(make([]int, 0, len(consumptions)+1), consumptions...), consumed)
+		if len(consumptions) > fuelCostWindow {
+			consumptions = consumptions[len(consumptions)-fuelCostWindow:]
+		}
+		return consumptions, true
+	})
+
+	return c.save()
+}
+
+// Learned returns the fuel a route burns in a flight mode, reporting false until it has been flown often enough
+// to trust the average. The average is rounded up, so estimates never fall short.
+func (c *FuelCosts) Learned(origin string, destination string, flightMode string) (int, bool) {
+	if c == nil {
+		return 0, false
+	}
+
+	consumptions, _ := c.routes.Get(fuelRouteKey{origin, destination, flightMode})
+	if len(consumptions) < fuelCostObservations {
+		return 0, false
+	}
+
+	var sum int
+	for _, consumed := range consumptions {
+		sum += consumed
+	}
+
+	return int(math.Ceil(float64(sum) / float64(len(consumptions)))), true
+}
+
+// Estimate returns the fuel flying from origin to destination in a flight mode burns: the learned cost once
+// the route is learned, and the formula's estimate from their distance until then.
+func (c *FuelCosts) Estimate(origin *m.Waypoint, destination *m.Waypoint, flightMode string) int {
+	if fuel, ok := c.Learned(origin.Symbol, destination.Symbol, flightMode); ok {
+		return fuel
+	}
+
+	return lib.FuelCost(lib.WaypointDistance(origin, destination), flightMode)
+}
+
+// Routes returns every route flown, sorted by origin, destination, and flight mode.
+func (c *FuelCosts) Routes() []FuelRoute {
+	if c == nil {
+		return nil
+	}
+
+	return c.sorted()
+}
+
+// sorted returns the routes sorted by origin, destination, and flight mode.
+func (c *FuelCosts) sorted() []FuelRoute {
+	routes := make([]FuelRoute, 0, c.routes.Len())
+	c.routes.Range(func(key fuelRouteKey, consumptions []int, _ time.Time) bool {
+		routes = append(routes, FuelRoute{
+			Origin:      key.origin,
+			Destination: key.destination,
+			FlightMode:  key.flightMode,
+			Consumed:    append([]int(nil), consumptions...),
+		})
+		return true
+	})
+	sort.Slice(routes, func(i, j int) bool {
+		a, b := routes[i], routes[j]
+		if a.Origin != b.Origin {
+			return a.Origin < b.Origin
+		}
+		if a.Destination != b.Destination {
+			return a.Destination < b.Destination
+		}
+		return a.FlightMode < b.FlightMode
+	})
+
+	return routes
+}
+
+// save writes the routes to a temporary file and renames it over the fuel cost file, so a crash mid-write
+// never leaves a truncated file. It must be called with mu held.
+func (c *FuelCosts) save() error {
+	if c.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(fuelCostFile{ResetDate: c.resetDate, Routes: c.sorted()}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path)
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+func TestFuelCostsSwitchFromFormulaToLearned(t *testing.T) {
+	c, err := OpenFuelCosts("", "2030-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a1 := &m.Waypoint{Symbol: "X1-MK1-A1"}
+	b2 := &m.Waypoint{Symbol: "X1-MK1-B2", X: 6, Y: 8}
+
+	// The formula burns the distance of 10 cruising; the worn engine burns more.
+	for i, consumed := range []int{13, 12, 14, 15} {
+		want := 10
+		if i >= 3 {
+			// Averaged and rounded up: (13 + 12 + 14) / 3 = 13.
+			want = 13
+		}
+		if got := c.Estimate(a1, b2, "CRUISE"); got != want {
+			t.Fatalf("after %d navigations estimate = %d, want %d", i, got, want)
+		}
+
+		if err := c.Record("X1-MK1-A1", "X1-MK1-B2", "CRUISE", consumed); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := c.Estimate(a1, b2, "CRUISE"); got != 14 {
+		t.Fatalf("estimate = %d, want the ceiling of the four navigations' 13.5", got)
+	}
+
+	// Other flight modes and the way back are separate routes, still estimated by the formula.
+	if got := c.Estimate(a1, b2, "BURN"); got != 20 {
+		t.Fatalf("BURN estimate = %d, want the formula's 20", got)
+	}
+	if got := c.Estimate(b2, a1, "CRUISE"); got != 10 {
+		t.Fatalf("return estimate = %d, want the formula's 10", got)
+	}
+}
+
+func TestFuelCostsKeepOnlyLatestNavigations(t *testing.T) {
+	c, _ := OpenFuelCosts("", "")
+
+	for i := 0; i < fuelCostWindow; i++ {
+		c.Record("A", "B", "CRUISE", 100)
+	}
+	for i := 0; i < fuelCostWindow; i++ {
+		c.Record("A", "B", "CRUISE", 10)
+	}
+
+	if fuel, ok := c.Learned("A", "B", "CRUISE"); !ok || fuel != 10 {
+		t.Fatalf("Learned = %d, %t, want only the latest %d navigations' 10", fuel, ok, fuelCostWindow)
+	}
+}
+
+func TestFuelCostsPersistPerReset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fuel.json")
+
+	c, err := OpenFuelCosts(path, "2030-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, consumed := range []int{5, 5, 6} {
+		if err := c.Record("A", "B", "DRIFT", consumed); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reopened, err := OpenFuelCosts(path, "2030-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fuel, ok := reopened.Learned("A", "B", "DRIFT"); !ok || fuel != 6 {
+		t.Fatalf("after reopening Learned = %d, %t, want 6, true", fuel, ok)
+	}
+
+	reset, err := OpenFuelCosts(path, "2030-01-15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if routes := reset.Routes(); len(routes) != 0 {
+		t.Fatalf("%d routes after a server reset, want none", len(routes))
+	}
+}
@@ -0,0 +1,221 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/clock"
+)
+
+// JournalEntry is the recorded intent and progress of a ship's multi-step mission.
+type JournalEntry struct {
+	Ship      string `json:"ship"`
+	Kind      string `json:"kind"`
+	MissionID string `json:"missionId"`
+	// Step is the last step the mission reached.
+	Step string `json:"step"`
+	// ContractID is the contract the mission works on, if any. The entry is invalid once the contract is gone.
+	ContractID string `json:"contractId,omitempty"`
+	// Intent is the mission's own description of what it set out to do, enough to resume it.
+	Intent    json.RawMessage `json:"intent"`
+	StartedAt time.Time       `json:"startedAt"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+// journalFile is the layout of the journal file.
+type journalFile struct {
+	// ResetDate is the server reset the missions were started in.
+	ResetDate string         `json:"resetDate"`
+	Entries   []JournalEntry `json:"entries"`
+}
+
+/*
+📓 Journal
+*/
+
+// Journal holds the unfinished multi-step mission of each ship, so missions interrupted by a restart can resume.
+// Missions are journalled per server reset, and every change is written to the journal file, when there is one.
+// Methods are safe to call on a nil Journal, which records nothing.
+type Journal struct {
+	// mu serializes changes, so the file is written with each change in turn.
+	mu        sync.Mutex
+	path      string
+	resetDate string
+	entries   *Map[string, JournalEntry]
+	clock     clock.Clock
+}
+
+// OpenJournal loads the journal at path, starting empty if the file does not exist or was written in a reset
+// other than resetDate. An empty resetDate keeps whatever the file holds. An empty path keeps the journal in
+// memory only.
+func OpenJournal(path string, resetDate string) (*Journal, error) {
+	j := &Journal{path: path, resetDate: resetDate, entries: NewMap[string, JournalEntry](), clock: clock.Real}
+	if path == "" {
+		return j, nil
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return j, nil
+	case err != nil:
+		return nil, err
+	}
+
+	var file journalFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	// Ships and contracts do not survive a reset, so neither do the missions working on them.
+	if resetDate != "" && file.ResetDate != resetDate {
+		return j, nil
+	}
+
+	j.resetDate = file.ResetDate
+	for _, entry := range file.Entries {
+		j.entries.Set(entry.Ship, entry)
+	}
+
+	return j, nil
+}
+
+// SetClock timestamps entries by c instead of the wall clock.
+func (j *Journal) SetClock(c clock.Clock) {
+	if j == nil {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.clock = c
+}
+
+// Begin records that a ship started a multi-step mission, replacing any entry it had.
+func (j *Journal) Begin(entry JournalEntry) error {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := j.clock.Now()
+	entry.StartedAt, entry.UpdatedAt = now, now
+	j.entries.Set(entry.Ship, entry)
+
+	return j.save()
+}
+
+// Step records that a ship's mission reached a step. A nil intent keeps the recorded one.
+func (j *Journal) Step(ship string, step string, intent json.RawMessage) error {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := j.clock.Now()
+	stepped := j.entries.Update(ship, func(entry JournalEntry, ok bool) (JournalEntry, bool) {
+		if !ok {
+			return entry, false
+		}
+
+		entry.Step = step
+		if intent != nil {
+			entry.Intent = intent
+		}
+		entry.UpdatedAt = now
+		return entry, true
+	})
+	if !stepped {
+		return nil
+	}
+
+	return j.save()
+}
+
+// End clears a ship's entry once its mission completes or is abandoned.
+func (j *Journal) End(ship string) error {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, ok := j.entries.Get(ship); !ok {
+		return nil
+	}
+	j.entries.Delete(ship)
+
+	return j.save()
+}
+
+// Get returns a ship's unfinished mission.
+func (j *Journal) Get(ship string) (JournalEntry, bool) {
+	if j == nil {
+		return JournalEntry{}, false
+	}
+
+	return j.entries.Get(ship)
+}
+
+// Entries returns every unfinished mission, sorted by ship.
+func (j *Journal) Entries() []JournalEntry {
+	if j == nil {
+		return nil
+	}
+
+	return j.sorted()
+}
+
+// sorted returns the entries sorted by ship.
+func (j *Journal) sorted() []JournalEntry {
+	entries := make([]JournalEntry, 0, j.entries.Len())
+	j.entries.Range(func(_ string, entry JournalEntry, _ time.Time) bool {
+		entries = append(entries, entry)
+		return true
+	})
+
+	sort.Slice(entries, func(a, b int) bool { return entries[a].Ship < entries[b].Ship })
+
+	return entries
+}
+
+// save writes the journal to a temporary file and renames it over the journal file, so a crash mid-write
+// never leaves a truncated journal. It must be called with mu held.
+func (j *Journal) save() error {
+	if j.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(journalFile{ResetDate: j.resetDate, Entries: j.sorted()}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), filepath.Base(j.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), j.path)
+}
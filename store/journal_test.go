@@ -0,0 +1,159 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournalSurvivesReopening(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.json")
+
+	j, err := OpenJournal(path, "2030-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Begin(JournalEntry{Ship: "GOGARIN-2", Kind: "trade", Step: "buying", Intent: json.RawMessage(`{"good":"IRON_ORE"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Begin(JournalEntry{Ship: "GOGARIN-1", Kind: "deliver", Step: "delivering", ContractID: "c-1", Intent: json.RawMessage(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Step("GOGARIN-2", "hauling", json.RawMessage(`{"good":"IRON_ORE","units":30}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Step("GOGARIN-3", "hauling", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenJournal(path, "2030-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := reopened.Entries()
+	if len(entries) != 2 || entries[0].Ship != "GOGARIN-1" || entries[1].Ship != "GOGARIN-2" {
+		t.Fatalf("entries = %+v, want GOGARIN-1 and GOGARIN-2", entries)
+	}
+	var intent struct {
+		Good  string `json:"good"`
+		Units int    `json:"units"`
+	}
+	hauling := entries[1]
+	if err := json.Unmarshal(hauling.Intent, &intent); err != nil || intent.Good != "IRON_ORE" || intent.Units != 30 {
+		t.Errorf("stepped intent = %s, want the hauling intent", hauling.Intent)
+	}
+	if hauling.Step != "hauling" || hauling.StartedAt.After(hauling.UpdatedAt) {
+		t.Errorf("stepped entry = %+v", hauling)
+	}
+
+	if err := reopened.End("GOGARIN-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reopened.Get("GOGARIN-1"); ok {
+		t.Error("ended entry still journalled")
+	}
+
+	again, err := OpenJournal(path, "2030-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries := again.Entries(); len(entries) != 1 || entries[0].Ship != "GOGARIN-2" {
+		t.Errorf("entries after End = %+v, want only GOGARIN-2", entries)
+	}
+}
+
+func TestJournalIsKeptPerReset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.json")
+
+	j, err := OpenJournal(path, "2030-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Begin(JournalEntry{Ship: "GOGARIN-1", Kind: "deliver", Intent: json.RawMessage(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		resetDate string
+		want      int
+	}{
+		{"same reset", "2030-01-01", 1},
+		{"reset date unknown", "", 1},
+		{"after a reset", "2030-01-15", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reopened, err := OpenJournal(path, tt.resetDate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(reopened.Entries()); got != tt.want {
+				t.Errorf("%d entries, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// A journal opened without a reset date keeps the file's, so it is still discarded after a reset.
+	unknown, err := OpenJournal(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := unknown.Step("GOGARIN-1", "fulfilling", nil); err != nil {
+		t.Fatal(err)
+	}
+	if after, err := OpenJournal(path, "2030-01-15"); err != nil || len(after.Entries()) != 0 {
+		t.Errorf("after a reset: entries = %v, err = %v, want none", after.Entries(), err)
+	}
+
+	// The first change after a reset replaces the stale file.
+	fresh, err := OpenJournal(path, "2030-01-15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fresh.Begin(JournalEntry{Ship: "GOGARIN-2", Kind: "trade", Intent: json.RawMessage(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if stale, err := OpenJournal(path, "2030-01-01"); err != nil || len(stale.Entries()) != 0 {
+		t.Errorf("opened with the old reset date: entries = %v, err = %v, want none", stale.Entries(), err)
+	}
+}
+
+func TestJournalInMemoryAndNil(t *testing.T) {
+	j, err := OpenJournal("", "2030-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Begin(JournalEntry{Ship: "GOGARIN-1", Kind: "deliver"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := j.Get("GOGARIN-1"); !ok {
+		t.Error("in-memory journal lost its entry")
+	}
+
+	var none *Journal
+	if err := none.Begin(JournalEntry{Ship: "GOGARIN-1"}); err != nil {
+		t.Error(err)
+	}
+	if err := none.Step("GOGARIN-1", "hauling", nil); err != nil {
+		t.Error(err)
+	}
+	if err := none.End("GOGARIN-1"); err != nil {
+		t.Error(err)
+	}
+	if _, ok := none.Get("GOGARIN-1"); ok || none.Entries() != nil {
+		t.Error("nil journal recorded an entry")
+	}
+}
+
+func TestOpenJournalRejectsACorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.json")
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenJournal(path, ""); err == nil {
+		t.Error("opened a corrupt journal")
+	}
+}
@@ -0,0 +1,198 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/clock"
+)
+
+// mapEntry is a value in a Map and when it was set.
+type mapEntry[V any] struct {
+	value V
+	at    time.Time
+}
+
+// mapRecord is the JSON layout of a Map entry.
+type mapRecord[K comparable, V any] struct {
+	Key   K         `json:"key"`
+	Value V         `json:"value"`
+	At    time.Time `json:"at"`
+}
+
+/*
+🗃️ Map
+*/
+
+// Map is a map safe for concurrent use that stamps each entry with when it was set, so entries older than a
+// TTL can be swept away. It marshals to and from JSON as a list of entries, for the stores that persist it.
+// The zero Map is not usable; create one with NewMap.
+type Map[K comparable, V any] struct {
+	mu      sync.RWMutex
+	entries map[K]mapEntry[V]
+	clock   clock.Clock
+}
+
+// NewMap creates an empty Map stamping entries by the wall clock.
+func NewMap[K comparable, V any]() *Map[K, V] {
+	return &Map[K, V]{entries: make(map[K]mapEntry[V]), clock: clock.Real}
+}
+
+// SetClock stamps entries, and times sweeps, by c instead of the wall clock.
+func (s *Map[K, V]) SetClock(c clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clock = clock.Or(c)
+}
+
+// Get returns the value of a key.
+func (s *Map[K, V]) Get(key K) (V, bool) {
+	value, _, ok := s.Entry(key)
+
+	return value, ok
+}
+
+// Entry returns the value of a key and when it was set.
+func (s *Map[K, V]) Entry(key K) (V, time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.entries[key]
+
+	return entry.value, entry.at, ok
+}
+
+// Set sets the value of a key, stamped now.
+func (s *Map[K, V]) Set(key K, value V) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = mapEntry[V]{value: value, at: s.clock.Now()}
+}
+
+// Update sets the value of a key to what change returns for its current value, unless change reports false.
+// change runs with the Map locked, so it sees no other write and must not call the Map. Update reports whether
+// the value was set.
+func (s *Map[K, V]) Update(key K, change func(value V, ok bool) (V, bool)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	value, set := change(entry.value, ok)
+	if set {
+		s.entries[key] = mapEntry[V]{value: value, at: s.clock.Now()}
+	}
+
+	return set
+}
+
+// Delete removes a key.
+func (s *Map[K, V]) Delete(key K) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+}
+
+// Len returns the number of keys.
+func (s *Map[K, V]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.entries)
+}
+
+// Range calls f for each entry, in no particular order, until f returns false. It ranges over a copy, so f may
+// call the Map.
+func (s *Map[K, V]) Range(f func(key K, value V, at time.Time) bool) {
+	for _, record := range s.records() {
+		if !f(record.Key, record.Value, record.At) {
+			return
+		}
+	}
+}
+
+// Sweep removes the entries set longer than ttl ago, returning how many it removed.
+func (s *Map[K, V]) Sweep(ttl time.Duration) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+
+	var swept int
+	for key, entry := range s.entries {
+		if now.Sub(entry.at) > ttl {
+			delete(s.entries, key)
+			swept++
+		}
+	}
+
+	return swept
+}
+
+// SweepEvery sweeps entries set longer than ttl ago every interval, until ctx is done. It blocks, so run it on
+// its own goroutine.
+func (s *Map[K, V]) SweepEvery(ctx context.Context, interval time.Duration, ttl time.Duration) {
+	s.mu.RLock()
+	ticker := s.clock.NewTicker(interval)
+	s.mu.RUnlock()
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			s.Sweep(ttl)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// MarshalJSON writes the entries as a list, sorted by key so the same entries always marshal alike.
+func (s *Map[K, V]) MarshalJSON() ([]byte, error) {
+	records := s.records()
+	sort.Slice(records, func(i, j int) bool { return fmt.Sprint(records[i].Key) < fmt.Sprint(records[j].Key) })
+
+	return json.Marshal(records)
+}
+
+// UnmarshalJSON replaces the entries with a list written by MarshalJSON, keeping each entry's stamp.
+func (s *Map[K, V]) UnmarshalJSON(data []byte) error {
+	var records []mapRecord[K, V]
+	if err := json.Unmarshal(data, &records); err != nil {
+		return err
+	}
+
+	entries := make(map[K]mapEntry[V], len(records))
+	for _, record := range records {
+		entries[record.Key] = mapEntry[V]{value: record.Value, at: record.At}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = entries
+	if s.clock == nil {
+		s.clock = clock.Real
+	}
+
+	return nil
+}
+
+// records returns a copy of the entries.
+func (s *Map[K, V]) records() []mapRecord[K, V] {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := make([]mapRecord[K, V], 0, len(s.entries))
+	for key, entry := range s.entries {
+		records = append(records, mapRecord[K, V]{Key: key, Value: entry.value, At: entry.at})
+	}
+
+	return records
+}
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/clock"
+)
+
+func TestMapIsSafeForConcurrentUse(t *testing.T) {
+	s := NewMap[int, int]()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				key := i % 16
+				switch (w + i) % 8 {
+				case 0:
+					s.Set(key, i)
+				case 1:
+					s.Update(key, func(v int, _ bool) (int, bool) { return v + 1, true })
+				case 2:
+					s.Delete(key)
+				case 3:
+					s.Get(key)
+				case 4:
+					s.Range(func(int, int, time.Time) bool { return true })
+				case 5:
+					s.Len()
+				case 6:
+					s.Sweep(time.Hour)
+				case 7:
+					if _, err := s.MarshalJSON(); err != nil {
+						t.Error(err)
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+func TestMapRangeMayCallTheMap(t *testing.T) {
+	s := NewMap[string, int]()
+	s.Set("a", 1)
+	s.Set("b", 2)
+
+	s.Range(func(key string, value int, _ time.Time) bool {
+		s.Set(key+key, value*10)
+		s.Delete(key)
+		return true
+	})
+
+	if got, _ := s.Get("aa"); got != 10 {
+		t.Fatalf("aa = %d, want 10", got)
+	}
+	if _, ok := s.Get("a"); ok || s.Len() != 2 {
+		t.Fatalf("%d keys, want only the two set while ranging", s.Len())
+	}
+}
+
+func TestMapUpdateCanDecline(t *testing.T) {
+	fake := clock.NewFake(epoch)
+	s := NewMap[string, int]()
+	s.SetClock(fake)
+	s.Set("a", 1)
+
+	fake.Advance(time.Minute)
+	if s.Update("a", func(v int, _ bool) (int, bool) { return v + 1, false }) {
+		t.Fatal("a declined update reported the value was set")
+	}
+	if value, at, _ := s.Entry("a"); value != 1 || !at.Equal(epoch) {
+		t.Fatalf("a = %d at %s after a declined update, want 1 at %s", value, at, epoch)
+	}
+
+	if !s.Update("b", func(v int, ok bool) (int, bool) { return 2, !ok }) {
+		t.Fatal("update of a new key reported the value was not set")
+	}
+	if value, at, _ := s.Entry("b"); value != 2 || !at.Equal(epoch.Add(time.Minute)) {
+		t.Fatalf("b = %d at %s, want 2 stamped when it was set", value, at)
+	}
+}
+
+func TestMapSweepRemovesOnlyExpiredEntries(t *testing.T) {
+	fake := clock.NewFake(epoch)
+	s := NewMap[string, int]()
+	s.SetClock(fake)
+
+	s.Set("old", 1)
+	fake.Advance(30 * time.Minute)
+	s.Set("recent", 2)
+	fake.Advance(45 * time.Minute)
+
+	if n := s.Sweep(time.Hour); n != 1 {
+		t.Fatalf("swept %d entries, want 1", n)
+	}
+	if _, ok := s.Get("old"); ok {
+		t.Fatal("the entry set 75 minutes ago survived an hour's sweep")
+	}
+	if _, ok := s.Get("recent"); !ok {
+		t.Fatal("the entry set 45 minutes ago was swept")
+	}
+}
+
+func TestMapSweepEverySweepsUntilCancelled(t *testing.T) {
+	fake := clock.NewFake(epoch)
+	s := NewMap[string, int]()
+	s.SetClock(fake)
+	s.Set("a", 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.SweepEvery(ctx, 10*time.Minute, 15*time.Minute)
+	}()
+
+	for fake.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The first sweep finds the entry ten minutes old, the second twenty.
+	fake.Advance(10 * time.Minute)
+	time.Sleep(20 * time.Millisecond)
+	if s.Len() != 1 {
+		t.Fatal("the entry was swept before it expired")
+	}
+	fake.Advance(10 * time.Minute)
+	for deadline := time.Now().Add(5 * time.Second); s.Len() != 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the expired entry was not swept")
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SweepEvery did not return once cancelled")
+	}
+	if fake.Waiters() != 0 {
+		t.Fatal("SweepEvery left its ticker running")
+	}
+}
+
+func TestMapJSONRoundTrip(t *testing.T) {
+	fake := clock.NewFake(epoch)
+	s := NewMap[string, []int]()
+	s.SetClock(fake)
+	for i, key := range []string{"c", "a", "b"} {
+		s.Set(key, []int{i})
+		fake.Advance(time.Minute)
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []mapRecord[string, []int]
+	if err := json.Unmarshal(data, &records); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, record := range records {
+		keys = append(keys, record.Key)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("marshalled keys in order %v, want %v", keys, want)
+	}
+
+	restored := NewMap[string, []int]()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range []string{"c", "a", "b"} {
+		value, at, ok := restored.Entry(key)
+		if !ok || len(value) != 1 || value[0] != i || !at.Equal(epoch.Add(time.Duration(i)*time.Minute)) {
+			t.Fatalf("%s = %v at %s after the round trip, want [%d] at %s", key, value, at, i, epoch.Add(time.Duration(i)*time.Minute))
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+// MarketObservation is a market as seen at a point in time.
+type MarketObservation struct {
+	Market     m.Market  `json:"market"`
+	ObservedAt time.Time `json:"observedAt"`
+}
+
+/*
+📈 MarketStore
+*/
+
+// MarketStore holds the latest observation of each market, keyed by waypoint symbol.
+type MarketStore struct {
+	markets *Map[string, MarketObservation]
+}
+
+// NewMarketStore creates a new, empty MarketStore.
+func NewMarketStore() *MarketStore {
+	return &MarketStore{
+		markets: NewMap[string, MarketObservation](),
+	}
+}
+
+// Record stores an observation of a market.
+// A market without trade good data (no ship present) does not replace an observation that has prices.
+func (s *MarketStore) Record(market m.Market, at time.Time) {
+	s.markets.Update(market.Symbol, func(existing MarketObservation, ok bool) (MarketObservation, bool) {
+		if ok && len(market.TradeGoods) == 0 && len(existing.Market.TradeGoods) > 0 {
+			return existing, false
+		}
+
+		return MarketObservation{Market: market, ObservedAt: at}, true
+	})
+}
+
+// Get returns the latest observation of the market at a waypoint.
+func (s *MarketStore) Get(waypointSymbol string) (MarketObservation, bool) {
+	return s.markets.Get(waypointSymbol)
+}
+
+// Supply returns the supply level of a good at the market at a waypoint, as last observed.
+func (s *MarketStore) Supply(waypointSymbol string, symbol string) (string, bool) {
+	observation, ok := s.Get(waypointSymbol)
+	if !ok {
+		return "", false
+	}
+
+	return observation.Market.SupplyOf(symbol)
+}
+
+// Fresh checks if the market at a waypoint was observed with prices within maxAge of now.
+func (s *MarketStore) Fresh(waypointSymbol string, maxAge time.Duration, now time.Time) bool {
+	observation, ok := s.Get(waypointSymbol)
+
+	return ok && len(observation.Market.TradeGoods) > 0 && now.Sub(observation.ObservedAt) < maxAge
+}
+
+// All returns every observation, sorted by waypoint symbol.
+func (s *MarketStore) All() []MarketObservation {
+	observations := make([]MarketObservation, 0, s.markets.Len())
+	s.markets.Range(func(_ string, observation MarketObservation, _ time.Time) bool {
+		observations = append(observations, observation)
+		return true
+	})
+
+	sort.Slice(observations, func(i, j int) bool {
+		return observations[i].Market.Symbol < observations[j].Market.Symbol
+	})
+
+	return observations
+}
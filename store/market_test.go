@@ -0,0 +1,67 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+var at = time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestMarketStoreKeepsPricesOverAnUnpricedObservation(t *testing.T) {
+	s := NewMarketStore()
+
+	priced := m.Market{Symbol: "X1-DF55-B", TradeGoods: []m.MarketTradeGood{{Symbol: "IRON_ORE", SellPrice: 45}}}
+	s.Record(priced, at)
+	s.Record(m.Market{Symbol: "X1-DF55-B"}, at.Add(time.Minute))
+
+	observation, ok := s.Get("X1-DF55-B")
+	if !ok || len(observation.Market.TradeGoods) != 1 || !observation.ObservedAt.Equal(at) {
+		t.Fatalf("observation = %+v, want the priced one", observation)
+	}
+
+	priced.TradeGoods[0].SellPrice = 50
+	s.Record(priced, at.Add(2*time.Minute))
+	if observation, _ := s.Get("X1-DF55-B"); observation.Market.TradeGoods[0].SellPrice != 50 {
+		t.Errorf("newer prices not recorded: %+v", observation)
+	}
+}
+
+func TestMarketStoreAll(t *testing.T) {
+	s := NewMarketStore()
+	if _, ok := s.Get("X1-DF55-A"); ok {
+		t.Error("empty store has a market")
+	}
+
+	s.Record(m.Market{Symbol: "X1-DF55-C"}, at)
+	s.Record(m.Market{Symbol: "X1-DF55-A"}, at)
+
+	all := s.All()
+	if len(all) != 2 || all[0].Market.Symbol != "X1-DF55-A" || all[1].Market.Symbol != "X1-DF55-C" {
+		t.Errorf("All = %+v, want both sorted by symbol", all)
+	}
+}
+
+func TestMarketStoreFresh(t *testing.T) {
+	s := NewMarketStore()
+	s.Record(m.Market{Symbol: "X1-DF55-A", TradeGoods: []m.MarketTradeGood{{Symbol: "IRON_ORE", SellPrice: 45}}}, at)
+	s.Record(m.Market{Symbol: "X1-DF55-B"}, at)
+
+	tests := []struct {
+		waypoint string
+		now      time.Time
+		want     bool
+	}{
+		{"X1-DF55-A", at, true},
+		{"X1-DF55-A", at.Add(4 * time.Minute), true},
+		{"X1-DF55-A", at.Add(5 * time.Minute), false},
+		{"X1-DF55-B", at, false},
+		{"X1-DF55-C", at, false},
+	}
+	for _, tt := range tests {
+		if got := s.Fresh(tt.waypoint, 5*time.Minute, tt.now); got != tt.want {
+			t.Errorf("Fresh(%s) %s after recording = %t, want %t", tt.waypoint, tt.now.Sub(at), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ShipMetadata is what the operator has noted about a ship. It is local only and never sent to the API.
+type ShipMetadata struct {
+	Nickname string `json:"nickname,omitempty"`
+	Notes    string `json:"notes,omitempty"`
+}
+
+/*
+🏷️ MetadataStore
+*/
+
+// MetadataStore holds the nickname and notes of each ship, written to the metadata file on every change when
+// there is one. Methods are safe to call on a nil MetadataStore, which knows no ship.
+type MetadataStore struct {
+	// mu serializes changes, so the file is written with each change in turn.
+	mu    sync.Mutex
+	path  string
+	ships *Map[string, ShipMetadata]
+}
+
+// OpenMetadataStore loads the metadata at path, starting empty if the file does not exist.
+// An empty path keeps the metadata in memory only.
+func OpenMetadataStore(path string) (*MetadataStore, error) {
+	s := &MetadataStore{path: path, ships: NewMap[string, ShipMetadata]()}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return s, nil
+	case err != nil:
+		return nil, err
+	}
+
+	var ships map[string]ShipMetadata
+	if err := json.Unmarshal(data, &ships); err != nil {
+		return nil, err
+	}
+
+	for symbol, metadata := range ships {
+		s.ships.Set(symbol, metadata)
+	}
+
+	return s, nil
+}
+
+// Get returns a ship's metadata.
+func (s *MetadataStore) Get(shipSymbol string) (ShipMetadata, bool) {
+	if s == nil {
+		return ShipMetadata{}, false
+	}
+
+	return s.ships.Get(shipSymbol)
+}
+
+// Nickname returns a ship's nickname, or an empty string if it has none.
+func (s *MetadataStore) Nickname(shipSymbol string) string {
+	metadata, _ := s.Get(shipSymbol)
+
+	return metadata.Nickname
+}
+
+// Label returns a ship's symbol followed by its quoted nickname, e.g. AGENT-3 "Rockhound", or just its symbol
+// if it has no nickname.
+func (s *MetadataStore) Label(shipSymbol string) string {
+	if nickname := s.Nickname(shipSymbol); nickname != "" {
+		return fmt.Sprintf("%s %q", shipSymbol, nickname)
+	}
+
+	return shipSymbol
+}
+
+// Rename sets a ship's nickname. An empty nickname clears it.
+func (s *MetadataStore) Rename(shipSymbol string, nickname string) error {
+	return s.update(shipSymbol, func(metadata *ShipMetadata) { metadata.Nickname = nickname })
+}
+
+// Annotate sets a ship's notes. Empty notes clear them.
+func (s *MetadataStore) Annotate(shipSymbol string, notes string) error {
+	return s.update(shipSymbol, func(metadata *ShipMetadata) { metadata.Notes = notes })
+}
+
+// update changes a ship's metadata and saves the store, forgetting ships left with no metadata.
+func (s *MetadataStore) update(shipSymbol string, change func(metadata *ShipMetadata)) error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	metadata, _ := s.ships.Get(shipSymbol)
+	change(&metadata)
+	if metadata == (ShipMetadata{}) {
+		s.ships.Delete(shipSymbol)
+	} else {
+		s.ships.Set(shipSymbol, metadata)
+	}
+
+	return s.save()
+}
+
+// save writes the store to a temporary file and renames it over the metadata file, so a crash mid-write
+// never leaves a truncated file. It must be called with mu held.
+func (s *MetadataStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	ships := make(map[string]ShipMetadata, s.ships.Len())
+	s.ships.Range(func(symbol string, metadata ShipMetadata, _ time.Time) bool {
+		ships[symbol] = metadata
+		return true
+	})
+
+	data, err := json.MarshalIndent(ships, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMetadataSurvivesReopening(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ships.json")
+
+	s, err := OpenMetadataStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Rename("GOGARIN-3", "Rockhound"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Annotate("GOGARIN-3", "Slow laser, keep near the field."); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Annotate("GOGARIN-4", "Spare hauler"); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenMetadataStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := reopened.Get("GOGARIN-3"); !ok || got != (ShipMetadata{Nickname: "Rockhound", Notes: "Slow laser, keep near the field."}) {
+		t.Errorf("GOGARIN-3 = %+v, %t", got, ok)
+	}
+	if got := reopened.Label("GOGARIN-3"); got != `GOGARIN-3 "Rockhound"` {
+		t.Errorf("label = %s", got)
+	}
+	if got := reopened.Label("GOGARIN-4"); got != "GOGARIN-4" {
+		t.Errorf("label without a nickname = %s", got)
+	}
+
+	// Clearing everything about a ship forgets it.
+	if err := reopened.Annotate("GOGARIN-4", ""); err != nil {
+		t.Fatal(err)
+	}
+	again, err := OpenMetadataStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := again.Get("GOGARIN-4"); ok {
+		t.Error("a ship with no metadata left is still stored")
+	}
+	if again.Nickname("GOGARIN-3") != "Rockhound" {
+		t.Error("clearing one ship lost another's nickname")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n  \"GOGARIN-3\": {\n    \"nickname\": \"Rockhound\",\n    \"notes\": \"Slow laser, keep near the field.\"\n  }\n}"; string(data) != want {
+		t.Errorf("metadata file =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestMetadataInMemoryAndNil(t *testing.T) {
+	s, err := OpenMetadataStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Rename("GOGARIN-1", "Flagship"); err != nil || s.Nickname("GOGARIN-1") != "Flagship" {
+		t.Errorf("in memory: nickname %q, err %v", s.Nickname("GOGARIN-1"), err)
+	}
+
+	var none *MetadataStore
+	if err := none.Rename("GOGARIN-1", "Flagship"); err != nil {
+		t.Errorf("renaming on a nil store: %s", err)
+	}
+	if none.Label("GOGARIN-1") != "GOGARIN-1" {
+		t.Error("a nil store names a ship")
+	}
+}
+
+func TestOpenMetadataStoreRejectsACorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ships.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenMetadataStore(path); err == nil {
+		t.Error("opened a corrupt metadata file")
+	}
+}
@@ -0,0 +1,257 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/clock"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+// ShipyardPrice is a shipyard's price for a ship type at a point in time.
+type ShipyardPrice struct {
+	Waypoint   string    `json:"waypoint"`
+	ShipType   string    `json:"shipType"`
+	Price      int64     `json:"price"`
+	ObservedAt time.Time `json:"observedAt"`
+}
+
+// shipyardFile is the layout of the shipyard file.
+type shipyardFile struct {
+	// ResetDate is the server reset the prices were observed in.
+	ResetDate string          `json:"resetDate"`
+	Prices    []ShipyardPrice `json:"prices"`
+}
+
+// shipyardKey identifies the prices of a ship type at a shipyard.
+type shipyardKey struct {
+	waypoint string
+	shipType string
+}
+
+/*
+🏭 ShipyardStore
+*/
+
+// ShipyardStore holds the prices of ship types observed at shipyards, so the latest price can be compared
+// with its rolling average. Prices expire once older than the TTL. Prices are kept per server reset, and every
+// change is written to the shipyard file, when there is one. Methods are safe to call on a nil ShipyardStore,
+// which records nothing.
+type ShipyardStore struct {
+	// mu serializes recording, so the file is written with each observation in turn.
+	mu        sync.Mutex
+	path      string
+	resetDate string
+	ttl       time.Duration
+	// histories are the prices of each ship type at each shipyard, oldest first.
+	histories *Map[shipyardKey, []ShipyardPrice]
+}
+
+// OpenShipyardStore loads the prices at path, starting empty if the file does not exist or was written in a
+// reset other than resetDate. An empty resetDate keeps whatever the file holds. Prices older than ttl are
+// dropped as new ones are recorded; zero keeps them forever. An empty path keeps the prices in memory only.
+func OpenShipyardStore(path string, resetDate string, ttl time.Duration) (*ShipyardStore, error) {
+	s := &ShipyardStore{path: path, resetDate: resetDate, ttl: ttl, histories: NewMap[shipyardKey, []ShipyardPrice]()}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return s, nil
+	case err != nil:
+		return nil, err
+	}
+
+	var file shipyardFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	// Shipyards are regenerated by a reset, so prices from before one say nothing about prices after it.
+	if resetDate != "" && file.ResetDate != resetDate {
+		return s, nil
+	}
+	s.resetDate = file.ResetDate
+
+	histories := make(map[shipyardKey][]ShipyardPrice)
+	for _, price := range file.Prices {
+		key := shipyardKey{price.Waypoint, price.ShipType}
+		histories[key] = append(histories[key], price)
+	}
+	for key, history := range histories {
+		sortPrices(history)
+		s.histories.Set(key, history)
+	}
+
+	return s, nil
+}
+
+// SetClock times sweeps by c instead of the wall clock.
+func (s *ShipyardStore) SetClock(c clock.Clock) {
+	if s == nil {
+		return
+	}
+
+	s.histories.SetClock(c)
+}
+
+// SweepEvery forgets, every interval until ctx is done, the ship types no shipyard has listed within the TTL,
+// so prices that will never be read again do not pile up between observations. The file drops them when it
+// is next written. It blocks, so run it on its own goroutine; with no TTL, it returns at once.
+func (s *ShipyardStore) SweepEvery(ctx context.Context, interval time.Duration) {
+	if s == nil || s.ttl <= 0 {
+		return
+	}
+
+	s.histories.SweepEvery(ctx, interval, s.ttl)
+}
+
+// Record stores the prices a shipyard lists at, dropping expired prices. A shipyard without prices, seen
+// without a ship present, records nothing.
+func (s *ShipyardStore) Record(shipyard m.Shipyard, at time.Time) error {
+	if s == nil || len(shipyard.Ships) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, ship := range shipyard.Ships {
+		price := ShipyardPrice{Waypoint: shipyard.Symbol, ShipType: ship.Type, Price: ship.PurchasePrice, ObservedAt: at}
+		s.histories.Update(shipyardKey{shipyard.Symbol, ship.Type}, func(history []ShipyardPrice, _ bool) ([]ShipyardPrice, bool) {
+			// The history is copied, so a slice already read is never changed in place.
+			kept := make([]ShipyardPrice, 0, len(history)+1)
+			for _, p := range history {
+				if !s.expired(p, at) {
+					kept = append(kept, p)
+				}
+			}
+			kept = append(kept, price)
+			sortPrices(kept)
+			return kept, true
+		})
+	}
+
+	return s.save(at)
+}
+
+// History returns the unexpired prices of a ship type at a shipyard, oldest first.
+func (s *ShipyardStore) History(waypointSymbol string, shipType string, now time.Time) []ShipyardPrice {
+	if s == nil {
+		return nil
+	}
+
+	prices, _ := s.histories.Get(shipyardKey{waypointSymbol, shipType})
+
+	var history []ShipyardPrice
+	for _, price := range prices {
+		if !s.expired(price, now) {
+			history = append(history, price)
+		}
+	}
+
+	return history
+}
+
+// Fresh checks if a shipyard's prices were recorded less than maxAge ago.
+func (s *ShipyardStore) Fresh(waypointSymbol string, maxAge time.Duration, now time.Time) bool {
+	if s == nil {
+		return false
+	}
+
+	var latest time.Time
+	s.histories.Range(func(key shipyardKey, history []ShipyardPrice, _ time.Time) bool {
+		if n := len(history); key.waypoint == waypointSymbol && n > 0 && history[n-1].ObservedAt.After(latest) {
+			latest = history[n-1].ObservedAt
+		}
+		return true
+	})
+
+	return !latest.IsZero() && now.Sub(latest) < maxAge
+}
+
+// Shipyards returns the shipyards with unexpired prices for a ship type, sorted by waypoint symbol.
+func (s *ShipyardStore) Shipyards(shipType string, now time.Time) []string {
+	if s == nil {
+		return nil
+	}
+
+	var shipyards []string
+	s.histories.Range(func(key shipyardKey, history []ShipyardPrice, _ time.Time) bool {
+		if n := len(history); key.shipType == shipType && n > 0 && !s.expired(history[n-1], now) {
+			shipyards = append(shipyards, key.waypoint)
+		}
+		return true
+	})
+	sort.Strings(shipyards)
+
+	return shipyards
+}
+
+// expired checks if a price is older than the TTL.
+func (s *ShipyardStore) expired(price ShipyardPrice, now time.Time) bool {
+	return s.ttl > 0 && now.Sub(price.ObservedAt) > s.ttl
+}
+
+// sortPrices sorts prices by observation time, oldest first.
+func sortPrices(prices []ShipyardPrice) {
+	sort.SliceStable(prices, func(i, j int) bool { return prices[i].ObservedAt.Before(prices[j].ObservedAt) })
+}
+
+// save writes the prices unexpired at now to a temporary file and renames it over the shipyard file, so a crash
+// mid-write never leaves a truncated file. It must be called with mu held.
+func (s *ShipyardStore) save(now time.Time) error {
+	if s.path == "" {
+		return nil
+	}
+
+	var prices []ShipyardPrice
+	s.histories.Range(func(_ shipyardKey, history []ShipyardPrice, _ time.Time) bool {
+		for _, price := range history {
+			if !s.expired(price, now) {
+				prices = append(prices, price)
+			}
+		}
+		return true
+	})
+	sort.Slice(prices, func(i, j int) bool {
+		a, b := prices[i], prices[j]
+		if !a.ObservedAt.Equal(b.ObservedAt) {
+			return a.ObservedAt.Before(b.ObservedAt)
+		}
+		if a.Waypoint != b.Waypoint {
+			return a.Waypoint < b.Waypoint
+		}
+		return a.ShipType < b.ShipType
+	})
+
+	data, err := json.MarshalIndent(shipyardFile{ResetDate: s.resetDate, Prices: prices}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,182 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/clock"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+var epoch = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func shipyard(symbol string, price int64) m.Shipyard {
+	return m.Shipyard{Symbol: symbol, Ships: []m.ShipyardShip{{Type: "SHIP_MINING_DRONE", PurchasePrice: price}}}
+}
+
+func prices(history []ShipyardPrice) []int64 {
+	var p []int64
+	for _, price := range history {
+		p = append(p, price.Price)
+	}
+	return p
+}
+
+func TestShipyardStoreExpiresPrices(t *testing.T) {
+	s, err := OpenShipyardStore("", "", 2*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, price := range []int64{80000, 82000, 79000} {
+		if err := s.Record(shipyard("X1-MK1-A1", price), epoch.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Seen without a ship present, the shipyard lists no prices.
+	if err := s.Record(m.Shipyard{Symbol: "X1-MK1-C3"}, epoch); err != nil {
+		t.Fatal(err)
+	}
+
+	now := epoch.Add(3 * time.Hour)
+	if got, want := prices(s.History("X1-MK1-A1", "SHIP_MINING_DRONE", now)), []int64{82000, 79000}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("history three hours in = %v, want %v", got, want)
+	}
+	if got, want := s.Shipyards("SHIP_MINING_DRONE", now), []string{"X1-MK1-A1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("shipyards = %v, want %v", got, want)
+	}
+	if !s.Fresh("X1-MK1-A1", 90*time.Minute, now) || s.Fresh("X1-MK1-A1", time.Hour, now) {
+		t.Fatal("the prices recorded an hour before are not fresh within 90 minutes only")
+	}
+	if got := s.Shipyards("SHIP_MINING_DRONE", epoch.Add(5*time.Hour)); got != nil {
+		t.Fatalf("shipyards once every price expired = %v, want none", got)
+	}
+}
+
+func TestShipyardStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shipyards.json")
+
+	s, err := OpenShipyardStore(path, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Recorded out of order, as by a satellite whose clock lags a survey's.
+	for _, at := range []time.Duration{2 * time.Hour, 0, time.Hour} {
+		if err := s.Record(shipyard("X1-MK1-A1", 80000+int64(at/time.Hour)), epoch.Add(at)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reopened, err := OpenShipyardStore(path, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := prices(reopened.History("X1-MK1-A1", "SHIP_MINING_DRONE", epoch)), []int64{80000, 80001, 80002}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("reopened history = %v, want %v", got, want)
+	}
+}
+
+func TestShipyardStoreIsKeptPerReset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shipyards.json")
+
+	s, err := OpenShipyardStore(path, "2030-01-01", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Record(shipyard("X1-MK1-A1", 80000), epoch); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		resetDate string
+		want      int
+	}{
+		{"same reset", "2030-01-01", 1},
+		{"reset date unknown", "", 1},
+		{"after a reset", "2030-01-15", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reopened, err := OpenShipyardStore(path, tt.resetDate, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(reopened.History("X1-MK1-A1", "SHIP_MINING_DRONE", epoch)); got != tt.want {
+				t.Errorf("%d prices, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// The first price recorded after a reset replaces the stale file.
+	fresh, err := OpenShipyardStore(path, "2030-01-15", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fresh.Record(shipyard("X1-MK1-B2", 90000), epoch); err != nil {
+		t.Fatal(err)
+	}
+	stale, err := OpenShipyardStore(path, "2030-01-01", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stale.Shipyards("SHIP_MINING_DRONE", epoch); got != nil {
+		t.Errorf("opened with the old reset date: shipyards = %v, want none", got)
+	}
+}
+
+func TestShipyardStoreSweepsUnlistedShipTypes(t *testing.T) {
+	fake := clock.NewFake(epoch)
+	s, err := OpenShipyardStore("", "", 2*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetClock(fake)
+
+	if err := s.Record(shipyard("X1-MK1-A1", 80000), fake.Now()); err != nil {
+		t.Fatal(err)
+	}
+	fake.Advance(90 * time.Minute)
+	if err := s.Record(shipyard("X1-MK1-C3", 81000), fake.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.SweepEvery(ctx, time.Hour)
+	for fake.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A1 was last listed two and a half hours before the sweep, C3 an hour before.
+	fake.Advance(time.Hour)
+	for deadline := time.Now().Add(5 * time.Second); s.histories.Len() != 1; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d histories after the sweep, want only C3's", s.histories.Len())
+		}
+	}
+	if _, ok := s.histories.Get(shipyardKey{"X1-MK1-C3", "SHIP_MINING_DRONE"}); !ok {
+		t.Fatal("the sweep dropped the history listed within the TTL")
+	}
+}
+
+func TestShipyardStoreWithoutTTLIsNeverSwept(t *testing.T) {
+	s, err := OpenShipyardStore("", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.SweepEvery(context.Background(), time.Hour)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SweepEvery ran for a store that keeps its prices forever")
+	}
+}
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/ledger"
+)
+
+// Snapshot is the state a run leaves behind in its state file.
+type Snapshot struct {
+	// Clean marks a run that shut down cleanly. A run marks its state file unclean as it starts, so the mark is
+	// missing after a crash.
+	Clean     bool      `json:"clean"`
+	StartedAt time.Time `json:"startedAt"`
+	// At is when the snapshot was taken, zero while the run is still going.
+	At time.Time `json:"at,omitempty"`
+	// Outcome is why the run ended: "stopped", or the error it ended with.
+	Outcome string `json:"outcome,omitempty"`
+	// Journal are the unfinished missions at the end of the run.
+	Journal []JournalEntry `json:"journal,omitempty"`
+	// Ledger are the latest credit movements of the run, and LedgerTotals the sum of all of them.
+	Ledger       []ledger.Entry `json:"ledger,omitempty"`
+	LedgerTotals ledger.Totals  `json:"ledgerTotals"`
+}
+
+/*
+🧷 StateFile
+*/
+
+// StateFile records whether the previous run shut down cleanly, and keeps a final snapshot of each run. Methods
+// are safe to call on a nil StateFile, which keeps nothing and reports every previous run as unclean.
+type StateFile struct {
+	path string
+	// started is when the run marked itself running.
+	started time.Time
+	// previous is the snapshot the previous run left, and found whether it left one.
+	previous Snapshot
+	found    bool
+}
+
+// OpenStateFile loads the snapshot the previous run left at path. An empty path keeps no snapshots.
+func OpenStateFile(path string) (*StateFile, error) {
+	s := &StateFile{path: path}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return s, nil
+	case err != nil:
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &s.previous); err != nil {
+		return nil, err
+	}
+	s.found = true
+
+	return s, nil
+}
+
+// Clean reports whether the previous run shut down cleanly. It is false when there was no previous run to
+// vouch for the journal, as well as after a crash.
+func (s *StateFile) Clean() bool {
+	return s != nil && s.found && s.previous.Clean
+}
+
+// Previous returns the snapshot the previous run left, reporting false if it left none.
+func (s *StateFile) Previous() (Snapshot, bool) {
+	if s == nil {
+		return Snapshot{}, false
+	}
+
+	return s.previous, s.found
+}
+
+// MarkRunning clears the clean-shutdown mark for a run started at startedAt, so a crash before Close leaves
+// the state file unclean.
+func (s *StateFile) MarkRunning(startedAt time.Time) error {
+	if s == nil {
+		return nil
+	}
+
+	s.started = startedAt
+
+	return s.save(Snapshot{StartedAt: startedAt})
+}
+
+// Close writes the run's final snapshot, started when the run marked itself running. Only a snapshot marked
+// clean vouches for the state it leaves; a run ending badly writes its snapshot unmarked, so the next run checks
+// before trusting it.
+func (s *StateFile) Close(snapshot Snapshot) error {
+	if s == nil {
+		return nil
+	}
+
+	snapshot.StartedAt = s.started
+
+	return s.save(snapshot)
+}
+
+// save writes a snapshot to a temporary file and renames it over the state file, so a crash mid-write never
+// leaves a truncated file.
+func (s *StateFile) save(snapshot Snapshot) error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	// The snapshot is synced before the rename, so the clean mark never lands ahead of its data.
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStateFileIsCleanOnlyAfterACleanClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	first, err := OpenStateFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Clean() {
+		t.Fatal("the first run vouches for a journal no run left")
+	}
+	if err := first.MarkRunning(epoch); err != nil {
+		t.Fatal(err)
+	}
+
+	// The run crashes without closing its state file.
+	crashed, err := OpenStateFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crashed.Clean() {
+		t.Fatal("a run that crashed left its state file clean")
+	}
+	if previous, ok := crashed.Previous(); !ok || !previous.StartedAt.Equal(epoch) || !previous.At.IsZero() {
+		t.Fatalf("previous snapshot = %+v, want the crashed run's start with no final snapshot", previous)
+	}
+
+	if err := crashed.MarkRunning(epoch.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	entries := []JournalEntry{{Ship: "MOCK-2", Kind: "trade", Step: "hauling"}}
+	if err := crashed.Close(Snapshot{Clean: true, At: epoch.Add(2 * time.Hour), Outcome: "stopped", Journal: entries}); err != nil {
+		t.Fatal(err)
+	}
+
+	stopped, err := OpenStateFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stopped.Clean() {
+		t.Fatal("a run that shut down cleanly left its state file unclean")
+	}
+	previous, _ := stopped.Previous()
+	if !previous.StartedAt.Equal(epoch.Add(time.Hour)) || previous.Outcome != "stopped" || len(previous.Journal) != 1 {
+		t.Fatalf("previous snapshot = %+v, want the stopped run's", previous)
+	}
+
+	// A run ending in a recovered panic writes its snapshot without the clean mark.
+	if err := stopped.MarkRunning(epoch.Add(3 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := stopped.Close(Snapshot{At: epoch.Add(4 * time.Hour), Outcome: "panic"}); err != nil {
+		t.Fatal(err)
+	}
+	panicked, err := OpenStateFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if panicked.Clean() {
+		t.Fatal("a run that panicked left its state file clean")
+	}
+}
+
+func TestNilStateFileIsNeverClean(t *testing.T) {
+	var s *StateFile
+
+	if err := s.MarkRunning(epoch); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(Snapshot{Clean: true}); err != nil {
+		t.Fatal(err)
+	}
+	if s.Clean() {
+		t.Fatal("a nil StateFile vouches for the journal")
+	}
+}
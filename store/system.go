@@ -0,0 +1,202 @@
+package store
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/lib"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+// WaypointLister lists the waypoints of a system. It is satisfied by api.ClientAPI.
+type WaypointLister interface {
+	ListWaypoints(systemSymbol string) (*[]m.Waypoint, error)
+}
+
+// SystemGetter gets a system, whose embedded waypoint listing has every waypoint's type and coordinates
+// but no traits. It is satisfied by api.ClientAPI.
+type SystemGetter interface {
+	GetSystem(systemSymbol string) (*m.System, error)
+}
+
+/*
+🗺️ SystemKnowledge
+*/
+
+// SystemKnowledge holds the waypoints of each system the fleet has visited, so ships share one
+// ListWaypoints call per system instead of making their own.
+type SystemKnowledge struct {
+	lister WaypointLister
+
+	systems *Map[string, []m.Waypoint]
+	// layouts are the minimal waypoints of systems read from GetSystem, for lookups that need no traits.
+	layouts *Map[string, []m.Waypoint]
+
+	// loadMu serializes fetches, so concurrent first reads of a system make a single call.
+	loadMu sync.Mutex
+}
+
+// NewSystemKnowledge creates an empty SystemKnowledge that fetches waypoints with lister.
+// If lister is also a SystemGetter, Layout reads systems with it.
+func NewSystemKnowledge(lister WaypointLister) *SystemKnowledge {
+	return &SystemKnowledge{
+		lister:  lister,
+		systems: NewMap[string, []m.Waypoint](),
+		layouts: NewMap[string, []m.Waypoint](),
+	}
+}
+
+// Waypoints returns the waypoints of a system, fetching them the first time the system is read.
+// The returned slice is a copy.
+func (k *SystemKnowledge) Waypoints(systemSymbol string) ([]m.Waypoint, error) {
+	if waypoints, ok := k.cached(systemSymbol); ok {
+		return waypoints, nil
+	}
+
+	k.loadMu.Lock()
+	defer k.loadMu.Unlock()
+
+	// Another reader may have fetched the system while this one waited.
+	if waypoints, ok := k.cached(systemSymbol); ok {
+		return waypoints, nil
+	}
+
+	if err := k.fetch(systemSymbol); err != nil {
+		return nil, err
+	}
+
+	waypoints, _ := k.cached(systemSymbol)
+
+	return waypoints, nil
+}
+
+// Layout returns the waypoints of a system for lookups by type or position, which need no traits.
+// Fully known waypoints are returned if the system has been listed; otherwise the minimal waypoints from
+// GetSystem are fetched once and cached, so type-based navigation never lists waypoints.
+// The returned slice is a copy.
+func (k *SystemKnowledge) Layout(systemSymbol string) ([]m.Waypoint, error) {
+	if waypoints, ok := k.cached(systemSymbol); ok {
+		return waypoints, nil
+	}
+
+	getter, ok := k.lister.(SystemGetter)
+	if !ok {
+		return k.Waypoints(systemSymbol)
+	}
+
+	if waypoints, ok := k.cachedLayout(systemSymbol); ok {
+		return waypoints, nil
+	}
+
+	k.loadMu.Lock()
+	defer k.loadMu.Unlock()
+
+	if waypoints, ok := k.cachedLayout(systemSymbol); ok {
+		return waypoints, nil
+	}
+
+	system, err := getter.GetSystem(systemSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	waypoints := system.MinimalWaypoints()
+
+	k.layouts.Set(systemSymbol, waypoints)
+
+	return append([]m.Waypoint(nil), waypoints...), nil
+}
+
+// Waypoint returns a single waypoint, fetching its system the first time the system is read.
+func (k *SystemKnowledge) Waypoint(waypointSymbol string) (*m.Waypoint, error) {
+	systemSymbol, err := lib.SystemSymbolOf(waypointSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	waypoints, err := k.Waypoints(systemSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range waypoints {
+		if waypoints[i].Symbol == waypointSymbol {
+			return &waypoints[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("waypoint %s not found in system %s", waypointSymbol, systemSymbol)
+}
+
+// Refresh fetches the waypoints of a system again, replacing what is known about it.
+func (k *SystemKnowledge) Refresh(systemSymbol string) error {
+	k.loadMu.Lock()
+	defer k.loadMu.Unlock()
+
+	return k.fetch(systemSymbol)
+}
+
+// Put replaces a single known waypoint, e.g. after it is charted. Waypoints of unknown systems are ignored,
+// since the whole system is fetched on first read.
+func (k *SystemKnowledge) Put(waypoint m.Waypoint) {
+	k.systems.Update(waypoint.SystemSymbol, func(waypoints []m.Waypoint, ok bool) ([]m.Waypoint, bool) {
+		if !ok {
+			return nil, false
+		}
+
+		for i := range waypoints {
+			if waypoints[i].Symbol == waypoint.Symbol {
+				waypoints[i] = waypoint
+				return waypoints, true
+			}
+		}
+
+		return append(waypoints, waypoint), true
+	})
+}
+
+// Follow updates charted waypoints from events until the channel is closed.
+func (k *SystemKnowledge) Follow(events <-chan event.Event) {
+	for e := range events {
+		if e.Type != event.WaypointCharted {
+			continue
+		}
+
+		if waypoint, ok := e.Data.(m.Waypoint); ok {
+			k.Put(waypoint)
+		}
+	}
+}
+
+// cached returns a copy of the known waypoints of a system.
+func (k *SystemKnowledge) cached(systemSymbol string) ([]m.Waypoint, bool) {
+	waypoints, ok := k.systems.Get(systemSymbol)
+	if !ok {
+		return nil, false
+	}
+
+	return append([]m.Waypoint(nil), waypoints...), true
+}
+
+// cachedLayout returns a copy of the minimal waypoints of a system.
+func (k *SystemKnowledge) cachedLayout(systemSymbol string) ([]m.Waypoint, bool) {
+	waypoints, ok := k.layouts.Get(systemSymbol)
+	if !ok {
+		return nil, false
+	}
+
+	return append([]m.Waypoint(nil), waypoints...), true
+}
+
+// fetch lists the waypoints of a system and stores them. Callers must hold loadMu.
+func (k *SystemKnowledge) fetch(systemSymbol string) error {
+	waypoints, err := k.lister.ListWaypoints(systemSymbol)
+	if err != nil {
+		return err
+	}
+
+	k.systems.Set(systemSymbol, append([]m.Waypoint(nil), (*waypoints)...))
+
+	return nil
+}
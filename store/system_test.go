@@ -0,0 +1,176 @@
+package store
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+// countingLister returns fixed waypoints and counts its calls.
+type countingLister struct {
+	calls     int32
+	waypoints []m.Waypoint
+	err       error
+}
+
+func (l *countingLister) ListWaypoints(systemSymbol string) (*[]m.Waypoint, error) {
+	atomic.AddInt32(&l.calls, 1)
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	waypoints := append([]m.Waypoint(nil), l.waypoints...)
+
+	return &waypoints, nil
+}
+
+func testWaypoints() []m.Waypoint {
+	return []m.Waypoint{
+		{Symbol: "X1-MK1-A1", SystemSymbol: "X1-MK1", Type: "PLANET"},
+		{Symbol: "X1-MK1-B2", SystemSymbol: "X1-MK1", Type: "ASTEROID_FIELD"},
+	}
+}
+
+// systemLister is a countingLister that also gets the system, whose listing has no traits.
+type systemLister struct {
+	countingLister
+	systemCalls int32
+}
+
+func (l *systemLister) GetSystem(systemSymbol string) (*m.System, error) {
+	atomic.AddInt32(&l.systemCalls, 1)
+
+	system := m.System{Symbol: systemSymbol}
+	for _, waypoint := range l.waypoints {
+		system.Waypoints = append(system.Waypoints, m.SystemWaypoint{Symbol: waypoint.Symbol, Type: waypoint.Type, X: waypoint.X, Y: waypoint.Y})
+	}
+
+	return &system, nil
+}
+
+func TestSystemKnowledgeLayout(t *testing.T) {
+	waypoints := testWaypoints()
+	waypoints[0].Traits = []m.WaypointTrait{{Symbol: "MARKETPLACE"}}
+	lister := &systemLister{countingLister: countingLister{waypoints: waypoints}}
+	k := NewSystemKnowledge(lister)
+
+	for i := 0; i < 3; i++ {
+		layout, err := k.Layout("X1-MK1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(layout) != 2 || layout[1].Type != "ASTEROID_FIELD" || layout[0].SystemSymbol != "X1-MK1" || len(layout[0].Traits) != 0 {
+			t.Fatalf("layout = %+v, want the minimal waypoints", layout)
+		}
+		layout[0].Type = "MOON"
+	}
+	if lister.systemCalls != 1 || lister.calls != 0 {
+		t.Errorf("GetSystem called %d times and ListWaypoints %d, want 1 and 0", lister.systemCalls, lister.calls)
+	}
+
+	// Once the system is listed, the fully known waypoints are returned.
+	if _, err := k.Waypoints("X1-MK1"); err != nil {
+		t.Fatal(err)
+	}
+	if layout, err := k.Layout("X1-MK1"); err != nil || !layout[0].HasTrait("MARKETPLACE") {
+		t.Errorf("layout after listing = %+v, %v, want the listed waypoints", layout, err)
+	}
+
+	// Without GetSystem, the layout is the listing.
+	plain := &countingLister{waypoints: testWaypoints()}
+	if layout, err := NewSystemKnowledge(plain).Layout("X1-MK1"); err != nil || len(layout) != 2 || plain.calls != 1 {
+		t.Errorf("layout = %d waypoints, %v with %d listings, want 2 from one listing", len(layout), err, plain.calls)
+	}
+}
+
+func TestSystemKnowledgeFetchesEachSystemOnce(t *testing.T) {
+	lister := &countingLister{waypoints: testWaypoints()}
+	k := NewSystemKnowledge(lister)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if waypoints, err := k.Waypoints("X1-MK1"); err != nil || len(waypoints) != 2 {
+				t.Errorf("Waypoints = %d, %v, want 2 waypoints", len(waypoints), err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := k.Waypoint("X1-MK1-B2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Waypoint("X1-MK1-Z9"); err == nil {
+		t.Error("Waypoint found an unknown waypoint")
+	}
+	if lister.calls != 1 {
+		t.Errorf("ListWaypoints called %d times, want 1", lister.calls)
+	}
+
+	if err := k.Refresh("X1-MK1"); err != nil {
+		t.Fatal(err)
+	}
+	if lister.calls != 2 {
+		t.Errorf("ListWaypoints called %d times after Refresh, want 2", lister.calls)
+	}
+}
+
+func TestSystemKnowledgeReturnsCopies(t *testing.T) {
+	k := NewSystemKnowledge(&countingLister{waypoints: testWaypoints()})
+
+	waypoints, err := k.Waypoints("X1-MK1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waypoints[0].Type = "MOON"
+
+	if w, _ := k.Waypoint("X1-MK1-A1"); w.Type != "PLANET" {
+		t.Errorf("mutating a returned slice changed the store: type = %s", w.Type)
+	}
+}
+
+func TestSystemKnowledgeDoesNotCacheErrors(t *testing.T) {
+	lister := &countingLister{err: errors.New("boom")}
+	k := NewSystemKnowledge(lister)
+
+	if _, err := k.Waypoints("X1-MK1"); err == nil {
+		t.Fatal("Waypoints succeeded with a failing lister")
+	}
+
+	lister.err = nil
+	lister.waypoints = testWaypoints()
+	if waypoints, err := k.Waypoints("X1-MK1"); err != nil || len(waypoints) != 2 {
+		t.Fatalf("Waypoints after recovery = %d, %v, want 2 waypoints", len(waypoints), err)
+	}
+}
+
+func TestSystemKnowledgeFollowsChartEvents(t *testing.T) {
+	k := NewSystemKnowledge(&countingLister{waypoints: testWaypoints()})
+	if _, err := k.Waypoints("X1-MK1"); err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan event.Event, 3)
+	events <- event.Event{Type: event.WaypointCharted, Data: m.Waypoint{Symbol: "X1-MK1-B2", SystemSymbol: "X1-MK1", Type: "ENGINEERED_ASTEROID"}}
+	events <- event.Event{Type: event.WaypointCharted, Data: m.Waypoint{Symbol: "X1-MK1-C3", SystemSymbol: "X1-MK1", Type: "MOON"}}
+	events <- event.Event{Type: event.WaypointCharted, Data: m.Waypoint{Symbol: "X1-ZZ9-A1", SystemSymbol: "X1-ZZ9", Type: "PLANET"}}
+	close(events)
+	k.Follow(events)
+
+	waypoints, _ := k.Waypoints("X1-MK1")
+	if len(waypoints) != 3 {
+		t.Fatalf("known waypoints = %d, want 3", len(waypoints))
+	}
+	if w, _ := k.Waypoint("X1-MK1-B2"); w.Type != "ENGINEERED_ASTEROID" {
+		t.Errorf("charted waypoint not replaced: type = %s", w.Type)
+	}
+	if _, ok := k.cached("X1-ZZ9"); ok {
+		t.Error("a charted waypoint of an unknown system was stored")
+	}
+}
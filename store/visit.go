@@ -0,0 +1,227 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Actions a ship performs on a visit, as recorded in a VisitLog.
+const (
+	ActionMarket   = "MARKET"
+	ActionChart    = "CHART"
+	ActionShipyard = "SHIPYARD"
+)
+
+// Outcomes of an action performed on a visit.
+const (
+	OutcomeDone   = "DONE"
+	OutcomeFailed = "FAILED"
+)
+
+// Visit is what ships did at a waypoint: when one last visited it, and the latest outcome of each action
+// performed there.
+type Visit struct {
+	Waypoint string    `json:"waypoint"`
+	At       time.Time `json:"at"`
+	// Actions are the latest of each action performed at the waypoint, sorted by action.
+	Actions []VisitAction `json:"actions,omitempty"`
+}
+
+// VisitAction is the latest outcome of an action performed at a waypoint, and when it was last done.
+type VisitAction struct {
+	Action  string    `json:"action"`
+	Outcome string    `json:"outcome"`
+	At      time.Time `json:"at"`
+	// DoneAt is when the action last succeeded, zero if it never has.
+	DoneAt time.Time `json:"doneAt"`
+}
+
+// visitLogFile is the layout of the visit log file.
+type visitLogFile struct {
+	// ResetDate is the server reset the visits were made in.
+	ResetDate string  `json:"resetDate"`
+	Visits    []Visit `json:"visits"`
+}
+
+/*
+🧭 VisitLog
+*/
+
+// VisitLog remembers the waypoints ships have visited and what they did there, so a ship arriving somewhere
+// recently handled skips re-recording its market, re-charting it, or re-checking its shipyard. Visits are kept
+// per server reset, and every change is written to the visit file, when there is one. Methods are safe to call
+// on a nil VisitLog, which remembers nothing.
+type VisitLog struct {
+	// mu serializes recording, so the file is written with each visit in turn.
+	mu        sync.Mutex
+	path      string
+	resetDate string
+	visits    *Map[string, Visit]
+}
+
+// OpenVisitLog loads the visits at path, starting empty if the file does not exist or was written in a reset
+// other than resetDate. An empty resetDate keeps whatever the file holds. An empty path keeps the visits in
+// memory only.
+func OpenVisitLog(path string, resetDate string) (*VisitLog, error) {
+	l := &VisitLog{path: path, resetDate: resetDate, visits: NewMap[string, Visit]()}
+	if path == "" {
+		return l, nil
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return l, nil
+	case err != nil:
+		return nil, err
+	}
+
+	var file visitLogFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	if resetDate != "" && file.ResetDate != resetDate {
+		return l, nil
+	}
+
+	l.resetDate = file.ResetDate
+	for _, visit := range file.Visits {
+		l.visits.Set(visit.Waypoint, visit)
+	}
+
+	return l, nil
+}
+
+// Record stores a visit to a waypoint at at, with the outcome of each action performed on it.
+func (l *VisitLog) Record(waypointSymbol string, at time.Time, outcomes map[string]string) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.visits.Update(waypointSymbol, func(visit Visit, _ bool) (Visit, bool) {
+		// The actions are copied, so the recorded visit is never changed in place.
+		visit = copyVisit(visit)
+		visit.Waypoint, visit.At = waypointSymbol, at
+
+		for action, outcome := range outcomes {
+			i := sort.Search(len(visit.Actions), func(i int) bool { return visit.Actions[i].Action >= action })
+			if i == len(visit.Actions) || visit.Actions[i].Action != action {
+				visit.Actions = append(visit.Actions, VisitAction{})
+				copy(visit.Actions[i+1:], visit.Actions[i:])
+				visit.Actions[i] = VisitAction{Action: action}
+			}
+
+			visit.Actions[i].Outcome = outcome
+			visit.Actions[i].At = at
+			if outcome == OutcomeDone {
+				visit.Actions[i].DoneAt = at
+			}
+		}
+
+		return visit, true
+	})
+
+	return l.save()
+}
+
+// LastVisited returns when a ship last visited a waypoint, reporting false if none has.
+func (l *VisitLog) LastVisited(waypointSymbol string) (time.Time, bool) {
+	visit, ok := l.Get(waypointSymbol)
+
+	return visit.At, ok
+}
+
+// Done checks if an action last succeeded at a waypoint within maxAge of now. Zero maxAge accepts it however
+// long ago it succeeded.
+func (l *VisitLog) Done(waypointSymbol string, action string, maxAge time.Duration, now time.Time) bool {
+	visit, ok := l.Get(waypointSymbol)
+	if !ok {
+		return false
+	}
+
+	for _, a := range visit.Actions {
+		if a.Action == action {
+			return !a.DoneAt.IsZero() && (maxAge == 0 || now.Sub(a.DoneAt) < maxAge)
+		}
+	}
+
+	return false
+}
+
+// Get returns what ships did at a waypoint, reporting false if no ship has visited it.
+func (l *VisitLog) Get(waypointSymbol string) (Visit, bool) {
+	if l == nil {
+		return Visit{}, false
+	}
+
+	visit, ok := l.visits.Get(waypointSymbol)
+
+	return copyVisit(visit), ok
+}
+
+// Visits returns every visited waypoint, sorted by waypoint symbol.
+func (l *VisitLog) Visits() []Visit {
+	if l == nil {
+		return nil
+	}
+
+	return l.sorted()
+}
+
+// copyVisit returns a copy of a visit that shares no actions with it.
+func copyVisit(visit Visit) Visit {
+	visit.Actions = append([]VisitAction(nil), visit.Actions...)
+
+	return visit
+}
+
+// sorted returns the visits sorted by waypoint symbol.
+func (l *VisitLog) sorted() []Visit {
+	visits := make([]Visit, 0, l.visits.Len())
+	l.visits.Range(func(_ string, visit Visit, _ time.Time) bool {
+		visits = append(visits, copyVisit(visit))
+		return true
+	})
+	sort.Slice(visits, func(i, j int) bool { return visits[i].Waypoint < visits[j].Waypoint })
+
+	return visits
+}
+
+// save writes the visits to a temporary file and renames it over the visit file, so a crash mid-write never
+// leaves a truncated file. It must be called with mu held.
+func (l *VisitLog) save() error {
+	if l.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(visitLogFile{ResetDate: l.resetDate, Visits: l.sorted()}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), l.path)
+}
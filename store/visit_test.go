@@ -0,0 +1,113 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVisitLogGatesActionsOnWhenTheyLastSucceeded(t *testing.T) {
+	l, err := OpenVisitLog("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	window := 5 * time.Minute
+
+	if _, ok := l.LastVisited("X1-MK1-A1"); ok {
+		t.Fatal("an unvisited waypoint reports a visit")
+	}
+
+	if err := l.Record("X1-MK1-A1", epoch, map[string]string{ActionMarket: OutcomeDone, ActionShipyard: OutcomeFailed}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second visit within the window finds the market recorded; the failed shipyard check counts for nothing.
+	now := epoch.Add(2 * time.Minute)
+	if !l.Done("X1-MK1-A1", ActionMarket, window, now) {
+		t.Fatal("market recorded two minutes ago is not done within five")
+	}
+	if l.Done("X1-MK1-A1", ActionShipyard, window, now) {
+		t.Fatal("failed shipyard check counts as done")
+	}
+	if l.Done("X1-MK1-A1", ActionChart, 0, now) {
+		t.Fatal("chart never performed counts as done")
+	}
+
+	// A visit that performs nothing moves the last visit, not when the market was last recorded.
+	if err := l.Record("X1-MK1-A1", now, nil); err != nil {
+		t.Fatal(err)
+	}
+	if at, _ := l.LastVisited("X1-MK1-A1"); !at.Equal(now) {
+		t.Fatalf("last visited %s, want %s", at, now)
+	}
+
+	// Beyond the window the market is due again, but with no maxAge it stays done however old.
+	later := epoch.Add(window)
+	if l.Done("X1-MK1-A1", ActionMarket, window, later) {
+		t.Fatal("market recorded five minutes ago is still done within five")
+	}
+	if !l.Done("X1-MK1-A1", ActionMarket, 0, later) {
+		t.Fatal("market ever recorded is not done without a maxAge")
+	}
+
+	// A later failure keeps when the action last succeeded.
+	if err := l.Record("X1-MK1-A1", later, map[string]string{ActionMarket: OutcomeFailed}); err != nil {
+		t.Fatal(err)
+	}
+	visit, _ := l.Get("X1-MK1-A1")
+	if len(visit.Actions) != 2 || visit.Actions[0].Action != ActionMarket || visit.Actions[1].Action != ActionShipyard {
+		t.Fatalf("actions %+v, want MARKET then SHIPYARD", visit.Actions)
+	}
+	if market := visit.Actions[0]; market.Outcome != OutcomeFailed || !market.At.Equal(later) || !market.DoneAt.Equal(epoch) {
+		t.Fatalf("market %+v, want failed now and last done at %s", market, epoch)
+	}
+}
+
+func TestVisitLogPersistsPerReset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "visits.json")
+
+	l, err := OpenVisitLog(path, "2030-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Record("X1-MK1-C3", epoch, map[string]string{ActionMarket: OutcomeDone}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Record("X1-MK1-A1", epoch, map[string]string{ActionChart: OutcomeDone}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenVisitLog(path, "2030-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	visits := reopened.Visits()
+	if len(visits) != 2 || visits[0].Waypoint != "X1-MK1-A1" || visits[1].Waypoint != "X1-MK1-C3" {
+		t.Fatalf("reopened visits %+v, want X1-MK1-A1 and X1-MK1-C3", visits)
+	}
+	if !reopened.Done("X1-MK1-C3", ActionMarket, time.Minute, epoch) {
+		t.Fatal("reopened log lost the market recorded at X1-MK1-C3")
+	}
+
+	reset, err := OpenVisitLog(path, "2030-01-15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if visits := reset.Visits(); len(visits) != 0 {
+		t.Fatalf("%d visits after a server reset, want none", len(visits))
+	}
+}
+
+func TestNilVisitLogRemembersNothing(t *testing.T) {
+	var l *VisitLog
+
+	if err := l.Record("X1-MK1-A1", epoch, map[string]string{ActionMarket: OutcomeDone}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := l.LastVisited("X1-MK1-A1"); ok {
+		t.Fatal("nil log reports a visit")
+	}
+	if l.Done("X1-MK1-A1", ActionMarket, 0, epoch) {
+		t.Fatal("nil log reports an action done")
+	}
+}
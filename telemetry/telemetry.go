@@ -0,0 +1,227 @@
+package telemetry
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/event"
+)
+
+// Record is a single line of a telemetry file.
+type Record struct {
+	At        time.Time       `json:"at"`
+	Type      event.Type      `json:"type"`
+	Ship      string          `json:"ship,omitempty"`
+	Mission   string          `json:"mission,omitempty"`
+	MissionID string          `json:"missionId,omitempty"`
+	Message   string          `json:"message,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// recorded is the set of event types written to telemetry.
+var recorded = map[event.Type]bool{
+	event.MissionStarted:     true,
+	event.MissionCompleted:   true,
+	event.MissionFailed:      true,
+	event.ShipNavigated:      true,
+	event.Arrived:            true,
+	event.ResourcesExtracted: true,
+	event.CargoSold:          true,
+	event.CargoPurchased:     true,
+	event.ContractAccepted:   true,
+	event.ContractFulfilled:  true,
+	event.ShipPurchased:      true,
+	event.ConditionDegraded:  true,
+	event.ShipRepaired:       true,
+}
+
+// backups is the number of rotated files kept alongside the active one.
+const backups = 3
+
+/*
+📼 Writer
+*/
+
+// Writer appends one JSON line per significant event to a file, rotating it at a size threshold.
+// Rotated files are renamed path.1 (newest) through path.3 (oldest).
+type Writer struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	file     *os.File
+	size     int64
+}
+
+// Open opens the telemetry file at path for appending. A maxBytes of zero disables rotation.
+func Open(path string, maxBytes int64) (*Writer, error) {
+	w := &Writer{path: path, maxBytes: maxBytes}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// open opens the active file, picking up its current size.
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	w.file = f
+	w.size = info.Size()
+
+	return nil
+}
+
+// Follow writes events until the channel is closed.
+func (w *Writer) Follow(events <-chan event.Event) error {
+	var firstErr error
+	for e := range events {
+		if err := w.Write(e); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Write appends the event as a JSON line if its type is recorded.
+func (w *Writer) Write(e event.Event) error {
+	if !recorded[e.Type] {
+		return nil
+	}
+
+	line, err := Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return os.ErrClosed
+	}
+
+	if w.maxBytes > 0 && w.size > 0 && w.size+int64(len(line)) > w.maxBytes {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := w.file.Write(line)
+	w.size += int64(n)
+
+	return err
+}
+
+// rotate shifts the rotated files up by one and starts a new active file.
+func (w *Writer) rotate() error {
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+
+	for i := backups - 1; i >= 1; i-- {
+		if err := os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := os.Rename(w.path, w.path+".1"); err != nil {
+		return err
+	}
+
+	return w.open()
+}
+
+// Close flushes the file to disk and closes it.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+
+	syncErr := w.file.Sync()
+	closeErr := w.file.Close()
+	w.file = nil
+
+	if syncErr != nil {
+		return syncErr
+	}
+
+	return closeErr
+}
+
+// Marshal encodes an event as a newline-terminated telemetry line.
+func Marshal(e event.Event) ([]byte, error) {
+	record := Record{
+		At:        e.At.UTC(),
+		Type:      e.Type,
+		Ship:      e.Ship,
+		Mission:   e.Mission,
+		MissionID: e.MissionID,
+		Message:   e.Message,
+	}
+
+	if e.Data != nil {
+		data := e.Data
+		// Errors marshal to an empty object, so record their message instead.
+		if err, ok := data.(error); ok {
+			data = err.Error()
+		}
+
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		record.Data = raw
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(line, '\n'), nil
+}
+
+// Read decodes every record from a telemetry file.
+func Read(r io.Reader) ([]Record, error) {
+	var records []Record
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
@@ -0,0 +1,154 @@
+package telemetry
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+)
+
+var at = time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func readFile(t *testing.T, path string) []Record {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	records, err := Read(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return records
+}
+
+func TestWriteRecordsSignificantEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry.jsonl")
+	w, err := Open(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sale := m.MarketTransaction{TradeSymbol: "IRON_ORE", Units: 10, TotalPrice: 450}
+	for _, e := range []event.Event{
+		{Type: event.ShipReported, At: at, Ship: "GOGARIN-2"},
+		{Type: event.CargoSold, At: at, Ship: "GOGARIN-2", Mission: "sell", MissionID: "a1b2c3", Data: sale},
+		{Type: event.MissionFailed, At: at.Add(time.Second), Ship: "GOGARIN-2", Mission: "mine", Data: errors.New("cooldown")},
+	} {
+		if err := w.Write(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records := readFile(t, path)
+	if len(records) != 2 {
+		t.Fatalf("records = %+v, want the sale and the failure", records)
+	}
+	if r := records[0]; r.Type != event.CargoSold || r.Ship != "GOGARIN-2" || r.Mission != "sell" || r.MissionID != "a1b2c3" || !r.At.Equal(at) ||
+		!strings.Contains(string(r.Data), `"tradeSymbol":"IRON_ORE"`) {
+		t.Errorf("sale record = %+v (data %s)", r, r.Data)
+	}
+	if r := records[1]; string(r.Data) != `"cooldown"` {
+		t.Errorf("failure recorded data %s, want the error's message", r.Data)
+	}
+}
+
+func TestWriteAfterCloseFails(t *testing.T) {
+	w, err := Open(filepath.Join(t.TempDir(), "telemetry.jsonl"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("second Close: %s", err)
+	}
+
+	if err := w.Write(event.Event{Type: event.CargoSold}); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("err = %v, want os.ErrClosed", err)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry.jsonl")
+	line, err := Marshal(event.Event{Type: event.ShipPurchased, At: at, Ship: "GOGARIN-3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two lines fit in a file, so six lines fill the active file and two rotated ones, and ten push the oldest out.
+	w, err := Open(path, int64(2*len(line)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := w.Write(event.Event{Type: event.ShipPurchased, At: at.Add(time.Duration(i) * time.Second), Ship: "GOGARIN-3"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, want := range map[string]int{path: 2, path + ".1": 2, path + ".2": 2, path + ".3": 2} {
+		if got := len(readFile(t, file)); got != want {
+			t.Errorf("%s holds %d records, want %d", filepath.Base(file), got, want)
+		}
+	}
+	if _, err := os.Stat(path + ".4"); !os.IsNotExist(err) {
+		t.Errorf("kept a fourth rotated file: %v", err)
+	}
+	if newest := readFile(t, path); !newest[1].At.Equal(at.Add(9 * time.Second)) {
+		t.Errorf("active file ends at %s, want the last event", newest[1].At)
+	}
+}
+
+func TestReopenAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry.jsonl")
+
+	for i := 0; i < 2; i++ {
+		w, err := Open(path, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(event.Event{Type: event.ShipPurchased, At: at}); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+	}
+
+	if got := len(readFile(t, path)); got != 2 {
+		t.Errorf("%d records after reopening, want 2", got)
+	}
+}
+
+func TestReadReportsTheBadLine(t *testing.T) {
+	_, err := Read(strings.NewReader("{\"type\":\"CARGO_SOLD\"}\n\n{broken\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 3:") {
+		t.Errorf("err = %v, want one naming line 3", err)
+	}
+}
+
+func TestMarshalOmitsAMissingMissionID(t *testing.T) {
+	line, err := Marshal(event.Event{Type: event.ShipPurchased, At: at, Ship: "GOGARIN-3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(line), "missionId") {
+		t.Errorf("line = %s, want no missionId", line)
+	}
+}
@@ -0,0 +1,244 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	"github.com/GeoffreyDick/gogarin/status"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// refreshInterval is how often the dashboard re-reads the status Board.
+const refreshInterval = 1 * time.Second
+
+// recentEvents is the number of events shown in the events pane.
+const recentEvents = 8
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+	headerStyle   = lipgloss.NewStyle().Bold(true).Underline(true)
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	paneStyle     = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
+	helpStyle     = lipgloss.NewStyle().Faint(true)
+)
+
+type tickMsg time.Time
+
+// Model is the bubbletea model of the fleet dashboard.
+type Model struct {
+	board    *status.Board
+	snapshot status.Snapshot
+	selected int
+	detail   bool
+}
+
+// New creates a fleet dashboard reading from a status Board.
+func New(board *status.Board) Model {
+	return Model{
+		board:    board,
+		snapshot: board.Snapshot(),
+	}
+}
+
+// Run shows the fleet dashboard until the user quits.
+func Run(board *status.Board) error {
+	_, err := tea.NewProgram(New(board), tea.WithAltScreen()).Run()
+	return err
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(refreshInterval, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
+
+// Init starts the refresh ticker.
+func (m Model) Init() tea.Cmd {
+	return tick()
+}
+
+// Update handles refresh ticks and keyboard controls.
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tickMsg:
+		m.snapshot = m.board.Snapshot()
+		if m.selected >= len(m.snapshot.Ships) {
+			m.selected = len(m.snapshot.Ships) - 1
+		}
+		if m.selected < 0 {
+			m.selected = 0
+		}
+		return m, tick()
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c":
+			return m, tea.Quit
+		case "up", "k":
+			if m.selected > 0 {
+				m.selected--
+			}
+		case "down", "j":
+			if m.selected < len(m.snapshot.Ships)-1 {
+				m.selected++
+			}
+		case "enter":
+			m.detail = !m.detail
+		case "esc":
+			m.detail = false
+		}
+	}
+
+	return m, nil
+}
+
+// View renders the dashboard.
+func (m Model) View() string {
+	now := m.snapshot.TakenAt
+
+	var b strings.Builder
+	b.WriteString(RenderHeader(m.snapshot))
+	b.WriteString("\n\n")
+	b.WriteString(RenderShipTable(m.snapshot.Ships, m.selected, now))
+	b.WriteString("\n")
+
+	if m.detail && m.selected < len(m.snapshot.Ships) {
+		b.WriteString(paneStyle.Render(RenderShipDetail(m.snapshot.Ships[m.selected], now)))
+		b.WriteString("\n")
+	}
+
+	b.WriteString(paneStyle.Render(RenderEvents(m.snapshot.Events, recentEvents)))
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render("↑/↓ select • enter detail • q quit"))
+
+	return b.String()
+}
+
+// RenderHeader renders the agent symbol and credits, and whether the fleet is paused.
+func RenderHeader(s status.Snapshot) string {
+	header := fmt.Sprintf("👽 %s  💰 %d credits  🚀 %d ships", s.Agent.Symbol, s.Agent.Credits, len(s.Ships))
+	if s.PausedAt != nil {
+		header += fmt.Sprintf("  ⏸️ PAUSED since %s", s.PausedAt.Format(time.TimeOnly))
+	}
+
+	return titleStyle.Render(header)
+}
+
+// RenderShipTable renders one row per ship, highlighting the selected row.
+func RenderShipTable(ships []status.ShipStatus, selected int, now time.Time) string {
+	var b strings.Builder
+	b.WriteString(headerStyle.Render(fmt.Sprintf("%-14s %-14s %-10s %-34s %-18s %-18s %-8s %s", "SHIP", "NAME", "ROLE", "MISSION", "CARGO", "FUEL", "CR/H", "ETA")))
+	b.WriteString("\n")
+
+	for i, s := range ships {
+		perHour := "-"
+		if s.CreditsPerHour != nil {
+			perHour = fmt.Sprintf("%.0f", *s.CreditsPerHour)
+		}
+
+		row := fmt.Sprintf("%-14s %-14s %-10s %-34s %-18s %-18s %-8s %s",
+			s.Ship.Symbol,
+			truncate(s.Nickname, 14),
+			s.Ship.Registration.Role,
+			truncate(s.Mission, 34),
+			Bar(s.Ship.Cargo.Units, s.Ship.Cargo.Capacity, 10),
+			Bar(s.Ship.Fuel.Current, s.Ship.Fuel.Capacity, 10),
+			perHour,
+			ETA(s, now))
+
+		if i == selected {
+			row = selectedStyle.Render(row)
+		}
+
+		b.WriteString(row)
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// RenderShipDetail renders the details of a single ship.
+func RenderShipDetail(s status.ShipStatus, now time.Time) string {
+	ship := s.Ship
+
+	title := ship.Symbol
+	if s.Nickname != "" {
+		title = fmt.Sprintf("%s %q", ship.Symbol, s.Nickname)
+	}
+
+	lines := []string{
+		titleStyle.Render(title),
+		fmt.Sprintf("Role:     %s", ship.Registration.Role),
+		fmt.Sprintf("Mission:  %s", s.Mission),
+		fmt.Sprintf("Nav:      %s at %s (%s)", ship.Nav.Status, ship.Nav.WaypointSymbol, ship.Nav.FlightMode),
+		fmt.Sprintf("ETA:      %s", ETA(s, now)),
+		fmt.Sprintf("Cargo:    %s", ship.Cargo),
+		fmt.Sprintf("Fuel:     %d/%d", ship.Fuel.Current, ship.Fuel.Capacity),
+		fmt.Sprintf("Updated:  %s ago", now.Sub(s.UpdatedAt).Round(time.Second)),
+	}
+	if s.Notes != "" {
+		lines = append(lines, fmt.Sprintf("Notes:    %s", s.Notes))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// RenderEvents renders the most recent events, newest last.
+func RenderEvents(events []event.Event, n int) string {
+	if len(events) > n {
+		events = events[len(events)-n:]
+	}
+
+	lines := []string{titleStyle.Render("Recent events")}
+	for _, e := range events {
+		line := fmt.Sprintf("%s %-20s %s", e.At.Format("15:04:05"), e.Type, e.Ship)
+		if e.Mission != "" {
+			line += " " + e.Mission
+		}
+		if e.Message != "" {
+			line += " " + e.Message
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Bar renders a fill bar such as "[####------] 12/30".
+// Zero-capacity values render as an empty bar.
+func Bar(current, capacity, width int) string {
+	filled := 0
+	if capacity > 0 {
+		filled = current * width / capacity
+	}
+	if filled > width {
+		filled = width
+	}
+	if filled < 0 {
+		filled = 0
+	}
+
+	return fmt.Sprintf("[%s%s] %d/%d", strings.Repeat("#", filled), strings.Repeat("-", width-filled), current, capacity)
+}
+
+// ETA renders the time remaining until a ship in transit arrives.
+func ETA(s status.ShipStatus, now time.Time) string {
+	arrival := s.Ship.Nav.Route.Arrival
+	if s.Ship.Nav.Status != "IN_TRANSIT" || !arrival.After(now) {
+		return "-"
+	}
+
+	return arrival.Sub(now).Round(time.Second).String()
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+
+	return string(r[:n-1]) + "…"
+}
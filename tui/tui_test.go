@@ -0,0 +1,135 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GeoffreyDick/gogarin/event"
+	m "github.com/GeoffreyDick/gogarin/model"
+	"github.com/GeoffreyDick/gogarin/status"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var now = time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestBar(t *testing.T) {
+	tests := []struct {
+		current, capacity int
+		want              string
+	}{
+		{12, 30, "[####------] 12/30"},
+		{0, 30, "[----------] 0/30"},
+		{30, 30, "[##########] 30/30"},
+		{40, 30, "[##########] 40/30"},
+		{-5, 30, "[----------] -5/30"},
+		{0, 0, "[----------] 0/0"},
+	}
+	for _, tt := range tests {
+		if got := Bar(tt.current, tt.capacity, 10); got != tt.want {
+			t.Errorf("Bar(%d, %d) = %q, want %q", tt.current, tt.capacity, got, tt.want)
+		}
+	}
+}
+
+func TestETA(t *testing.T) {
+	var s status.ShipStatus
+	s.Ship.Nav.Status = "IN_TRANSIT"
+	s.Ship.Nav.Route.Arrival = now.Add(90 * time.Second)
+
+	if got := ETA(s, now); got != "1m30s" {
+		t.Errorf("in transit: ETA = %q, want 1m30s", got)
+	}
+	if got := ETA(s, now.Add(2*time.Minute)); got != "-" {
+		t.Errorf("overdue: ETA = %q, want -", got)
+	}
+	s.Ship.Nav.Status = "IN_ORBIT"
+	if got := ETA(s, now); got != "-" {
+		t.Errorf("in orbit: ETA = %q, want -", got)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("mine IRON_ORE", 20); got != "mine IRON_ORE" {
+		t.Errorf("short string truncated to %q", got)
+	}
+	if got := truncate("mine IRON_ORE", 6); got != "mine …" {
+		t.Errorf("truncate = %q, want %q", got, "mine …")
+	}
+}
+
+func TestRenderEventsShowsTheNewest(t *testing.T) {
+	events := []event.Event{
+		{Type: event.CargoSold, At: now, Ship: "GOGARIN-1", Message: "first"},
+		{Type: event.MissionStarted, At: now, Ship: "GOGARIN-2", Mission: "mine", Message: "second"},
+		{Type: event.CargoSold, At: now, Ship: "GOGARIN-3", Message: "third"},
+	}
+
+	out := RenderEvents(events, 2)
+	if strings.Contains(out, "first") || !strings.Contains(out, "GOGARIN-2") || !strings.Contains(out, "mine second") || !strings.Contains(out, "third") {
+		t.Errorf("RenderEvents =\n%s", out)
+	}
+}
+
+func TestRenderShipTableAndDetail(t *testing.T) {
+	ship := m.Ship{Symbol: "GOGARIN-2"}
+	ship.Registration.Role = "EXCAVATOR"
+	ship.Cargo.Units, ship.Cargo.Capacity = 15, 30
+	s := status.ShipStatus{Ship: ship, Mission: "mine IRON_ORE", UpdatedAt: now.Add(-5 * time.Second)}
+
+	table := RenderShipTable([]status.ShipStatus{s}, 0, now)
+	for _, want := range []string{"GOGARIN-2", "EXCAVATOR", "mine IRON_ORE", "[#####-----] 15/30"} {
+		if !strings.Contains(table, want) {
+			t.Errorf("table missing %q:\n%s", want, table)
+		}
+	}
+
+	if detail := RenderShipDetail(s, now); !strings.Contains(detail, "Updated:  5s ago") || strings.Contains(detail, "Notes:") {
+		t.Errorf("detail =\n%s", detail)
+	}
+
+	s.Nickname, s.Notes = "Rockhound", "Slow laser"
+	if table := RenderShipTable([]status.ShipStatus{s}, 0, now); !strings.Contains(table, "GOGARIN-2      Rockhound") {
+		t.Errorf("named table =\n%s", table)
+	}
+	detail := RenderShipDetail(s, now)
+	for _, want := range []string{`GOGARIN-2 "Rockhound"`, "Notes:    Slow laser"} {
+		if !strings.Contains(detail, want) {
+			t.Errorf("named detail missing %q:\n%s", want, detail)
+		}
+	}
+}
+
+func TestUpdateMovesSelectionWithinTheFleet(t *testing.T) {
+	board := status.NewBoard()
+	for _, symbol := range []string{"GOGARIN-1", "GOGARIN-2"} {
+		board.Apply(event.Event{Type: event.ShipReported, Ship: symbol, Data: m.Ship{Symbol: symbol}})
+	}
+	model := New(board)
+
+	key := func(k string) {
+		next, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		model = next.(Model)
+	}
+
+	key("j")
+	key("j")
+	if model.selected != 1 {
+		t.Errorf("selected %d after moving past the last ship, want 1", model.selected)
+	}
+	key("k")
+	key("k")
+	if model.selected != 0 {
+		t.Errorf("selected %d after moving past the first ship, want 0", model.selected)
+	}
+
+	next, _ := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = next.(Model)
+	if !model.detail || !strings.Contains(model.View(), "Role:") {
+		t.Error("enter did not open the ship detail")
+	}
+
+	if _, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")}); cmd == nil {
+		t.Error("q did not quit")
+	}
+}